@@ -0,0 +1,212 @@
+// Package audit reports on the keyspace for capacity planning and leak hunting
+// It scans key prefixes and reports key counts, estimated memory, the TTL distribution,
+// and keys that never expire
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// TTLBucket counts keys whose TTL is at most Max and above the previous bucket's Max
+// The last bucket of a report has Max 0 and counts keys beyond every bound
+type TTLBucket struct {
+	Max   time.Duration `json:"max"`
+	Count int64         `json:"count"`
+}
+
+// PrefixReport is the audit result of one prefix
+type PrefixReport struct {
+	// Prefix is the audited key prefix; empty means the whole keyspace
+	Prefix string `json:"prefix"`
+
+	// Keys is the number of keys found
+	Keys int64 `json:"keys"`
+
+	// KeysWithoutTTL is the number of keys that never expire
+	KeysWithoutTTL int64 `json:"keys_without_ttl"`
+
+	// NoTTLExamples lists some of the keys without a TTL
+	NoTTLExamples []string `json:"no_ttl_examples,omitempty"`
+
+	// TTLBuckets is the distribution of the remaining TTL of keys that expire
+	TTLBuckets []TTLBucket `json:"ttl_buckets"`
+
+	// SampledKeys is the number of keys measured with MEMORY USAGE
+	SampledKeys int64 `json:"sampled_keys"`
+
+	// SampledBytes is the memory used by the sampled keys
+	SampledBytes int64 `json:"sampled_bytes"`
+
+	// EstimatedBytes extrapolates SampledBytes to all keys of the prefix
+	EstimatedBytes int64 `json:"estimated_bytes"`
+}
+
+// Report is the result of an audit
+type Report struct {
+	// Prefixes holds one report per audited prefix, in the configured order
+	Prefixes []PrefixReport `json:"prefixes"`
+
+	// StartedAt is the time the audit started
+	StartedAt time.Time `json:"started_at"`
+
+	// Duration is how long the audit took
+	Duration time.Duration `json:"duration"`
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to encode audit report: %w", err)
+	}
+	return nil
+}
+
+// Run audits the prefixes of cfg; zero config values fall back to defaults
+// Keys are read with SCAN and pipelined PTTL and MEMORY USAGE calls, so the audit is safe
+// to run against a live server, though it touches every matching key once
+func Run(ctx context.Context, client redis.Cmdable, cfg Config) (*Report, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	cfg = withDefaults(cfg)
+
+	report := &Report{StartedAt: time.Now()}
+	for _, prefix := range cfg.Prefixes {
+		pr, err := auditPrefix(ctx, client, cfg, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to audit prefix %q: %w", prefix, err)
+		}
+		report.Prefixes = append(report.Prefixes, pr)
+	}
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
+
+func withDefaults(cfg Config) Config {
+	if len(cfg.Prefixes) == 0 {
+		cfg.Prefixes = []string{""}
+	}
+	if cfg.ScanCount <= 0 {
+		cfg.ScanCount = utils.DefaultScanCount
+	}
+	if cfg.MemorySamples == 0 {
+		cfg.MemorySamples = 100
+	}
+	if len(cfg.TTLBuckets) == 0 {
+		cfg.TTLBuckets = DefaultTTLBuckets
+	}
+	cfg.TTLBuckets = slices.Sorted(slices.Values(cfg.TTLBuckets))
+	if cfg.NoTTLExamples == 0 {
+		cfg.NoTTLExamples = 10
+	}
+	return cfg
+}
+
+func auditPrefix(ctx context.Context, client redis.Cmdable, cfg Config, prefix string) (PrefixReport, error) {
+	pr := PrefixReport{
+		Prefix:     prefix,
+		TTLBuckets: make([]TTLBucket, len(cfg.TTLBuckets)+1),
+	}
+	for i, bound := range cfg.TTLBuckets {
+		pr.TTLBuckets[i].Max = bound
+	}
+
+	batch := make([]string, 0, cfg.ScanCount)
+	for key, err := range utils.ScanKeys(ctx, client, utils.EscapeGlob(prefix)+"*", cfg.ScanCount) {
+		if err != nil {
+			return pr, err
+		}
+		batch = append(batch, key)
+		if int64(len(batch)) < cfg.ScanCount {
+			continue
+		}
+		if err := inspect(ctx, client, cfg, &pr, batch); err != nil {
+			return pr, err
+		}
+		batch = batch[:0]
+	}
+	if err := inspect(ctx, client, cfg, &pr, batch); err != nil {
+		return pr, err
+	}
+
+	if pr.SampledKeys > 0 {
+		pr.EstimatedBytes = pr.SampledBytes * pr.Keys / pr.SampledKeys
+	}
+	return pr, nil
+}
+
+// inspect reads the TTL of every key in batch, and the memory of as many as still need sampling
+func inspect(ctx context.Context, client redis.Cmdable, cfg Config, pr *PrefixReport, batch []string) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	sample := 0
+	if cfg.MemorySamples > 0 {
+		sample = min(len(batch), cfg.MemorySamples-int(pr.SampledKeys))
+	}
+
+	ttls, err := utils.TTLs(ctx, client, batch)
+	if err != nil {
+		return err
+	}
+	mems := make([]*redis.IntCmd, sample)
+	if sample > 0 {
+		pipe := client.Pipeline()
+		for i := range mems {
+			if cfg.MemoryUsageSamples > 0 {
+				mems[i] = pipe.MemoryUsage(ctx, batch[i], cfg.MemoryUsageSamples)
+			} else {
+				mems[i] = pipe.MemoryUsage(ctx, batch[i])
+			}
+		}
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+	}
+
+	for i, key := range batch {
+		ttl := ttls[key]
+		switch {
+		case ttl == utils.TTLKeyNotFound:
+			// Deleted or expired since SCAN returned it
+			continue
+		case ttl == utils.TTLNoExpiry:
+			pr.KeysWithoutTTL++
+			if len(pr.NoTTLExamples) < cfg.NoTTLExamples {
+				pr.NoTTLExamples = append(pr.NoTTLExamples, batch[i])
+			}
+		default:
+			pr.TTLBuckets[bucketIndex(cfg.TTLBuckets, ttl)].Count++
+		}
+		pr.Keys++
+	}
+	for _, cmd := range mems {
+		if n, err := cmd.Result(); err == nil {
+			pr.SampledKeys++
+			pr.SampledBytes += n
+		}
+	}
+	return nil
+}
+
+// bucketIndex returns the first bucket whose bound is at least ttl, or the overflow bucket
+func bucketIndex(bounds []time.Duration, ttl time.Duration) int {
+	for i, bound := range bounds {
+		if ttl <= bound {
+			return i
+		}
+	}
+	return len(bounds)
+}
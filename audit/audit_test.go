@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestRun(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	for i := 0; i < 25; i++ {
+		_ = client.Set(ctx, fmt.Sprintf("session:%02d", i), "data", 30*time.Minute).Err()
+	}
+	_ = client.Set(ctx, "session:long", "data", 48*time.Hour).Err()
+	_ = client.Set(ctx, "session:forever", "data", 0).Err()
+	_ = client.HSet(ctx, "cache:h", "f", "v").Err()
+	_ = client.Set(ctx, "cache*literal", "v", 0).Err()
+
+	cfg := DefaultConfig("session:", "cache:").
+		WithScanCount(10).
+		WithMemorySamples(5, 0).
+		WithTTLBuckets(time.Minute, time.Hour, 24*time.Hour)
+	report, err := Run(ctx, client, cfg)
+	if err != nil {
+		t.Fatalf("Run() error = %v, want nil", err)
+	}
+	if len(report.Prefixes) != 2 || report.StartedAt.IsZero() {
+		t.Fatalf("Run() = %+v, want two prefix reports", report)
+	}
+
+	s := report.Prefixes[0]
+	if s.Prefix != "session:" || s.Keys != 27 || s.KeysWithoutTTL != 1 {
+		t.Errorf("session report = %+v, want 27 keys, 1 without TTL", s)
+	}
+	if len(s.NoTTLExamples) != 1 || s.NoTTLExamples[0] != "session:forever" {
+		t.Errorf("NoTTLExamples = %v, want [session:forever]", s.NoTTLExamples)
+	}
+	want := []int64{0, 25, 0, 1}
+	for i, b := range s.TTLBuckets {
+		if b.Count != want[i] {
+			t.Errorf("TTLBuckets[%d] = %+v, want count %d", i, b, want[i])
+		}
+	}
+	if s.TTLBuckets[3].Max != 0 || s.TTLBuckets[1].Max != time.Hour {
+		t.Errorf("TTLBuckets bounds = %+v, want 1m, 1h, 24h, overflow", s.TTLBuckets)
+	}
+	if s.SampledKeys != 5 || s.SampledBytes <= 0 || s.EstimatedBytes < s.SampledBytes*5 {
+		t.Errorf("memory = %d keys/%d bytes, estimated %d, want 5 samples extrapolated", s.SampledKeys, s.SampledBytes, s.EstimatedBytes)
+	}
+
+	c := report.Prefixes[1]
+	if c.Keys != 1 || c.KeysWithoutTTL != 1 {
+		t.Errorf("cache report = %+v, want only cache:h, without matching the glob in cache*literal", c)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v, want nil", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded.Prefixes[0].Keys != 27 {
+		t.Errorf("WriteJSON() = %s (%v), want the report round-tripped", buf.String(), err)
+	}
+}
+
+func TestRun_WholeKeyspace(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	_ = client.Set(ctx, "a", "1", 0).Err()
+	_ = client.Set(ctx, "b", "2", time.Hour).Err()
+
+	report, err := Run(ctx, client, Config{MemorySamples: -1})
+	if err != nil {
+		t.Fatalf("Run() error = %v, want nil", err)
+	}
+	pr := report.Prefixes[0]
+	if pr.Prefix != "" || pr.Keys != 2 || pr.KeysWithoutTTL != 1 {
+		t.Errorf("Run() = %+v, want 2 keys, 1 without TTL", pr)
+	}
+	if pr.SampledKeys != 0 || pr.EstimatedBytes != 0 {
+		t.Errorf("Run() memory = %d/%d, want sampling disabled", pr.SampledKeys, pr.EstimatedBytes)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := Run(ctx, nil, DefaultConfig()); err == nil {
+		t.Error("Run() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	mock.SetShouldFail(true)
+
+	if _, err := Run(ctx, client, DefaultConfig("x:")); err == nil {
+		t.Error("Run() with failing Redis error = nil, want error")
+	}
+}
@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// DefaultTTLBuckets are the upper bounds of the default TTL histogram
+var DefaultTTLBuckets = []time.Duration{time.Minute, time.Hour, 24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour}
+
+// Config represents what an audit scans and measures
+type Config struct {
+	// Prefixes are the key prefixes reported on, each scanned separately (default: the whole keyspace)
+	// A key matching several prefixes is counted under each of them
+	Prefixes []string
+
+	// ScanCount is the COUNT hint passed to SCAN, and the number of keys per pipeline (default: 100)
+	ScanCount int64
+
+	// MemorySamples is how many keys per prefix are measured with MEMORY USAGE; the prefix total is
+	// extrapolated from them (default: 100, negative disables memory sampling)
+	MemorySamples int
+
+	// MemoryUsageSamples is the SAMPLES argument of MEMORY USAGE for nested values (default: 0, the server default)
+	MemoryUsageSamples int
+
+	// TTLBuckets are the ascending upper bounds of the TTL histogram (default: DefaultTTLBuckets)
+	TTLBuckets []time.Duration
+
+	// NoTTLExamples is how many keys without a TTL are listed per prefix (default: 10)
+	NoTTLExamples int
+}
+
+// DefaultConfig returns a Config with default values that audits the given prefixes
+func DefaultConfig(prefixes ...string) Config {
+	return Config{
+		Prefixes:      prefixes,
+		ScanCount:     utils.DefaultScanCount,
+		MemorySamples: 100,
+		TTLBuckets:    DefaultTTLBuckets,
+		NoTTLExamples: 10,
+	}
+}
+
+// WithScanCount sets the SCAN COUNT hint and pipeline size
+func (c Config) WithScanCount(count int64) Config {
+	c.ScanCount = count
+	return c
+}
+
+// WithMemorySamples sets how many keys per prefix are measured, and the SAMPLES argument used for each
+func (c Config) WithMemorySamples(keys, samples int) Config {
+	c.MemorySamples = keys
+	c.MemoryUsageSamples = samples
+	return c
+}
+
+// WithTTLBuckets sets the upper bounds of the TTL histogram
+func (c Config) WithTTLBuckets(buckets ...time.Duration) Config {
+	c.TTLBuckets = buckets
+	return c
+}
+
+// WithNoTTLExamples sets how many keys without a TTL are listed per prefix
+func (c Config) WithNoTTLExamples(n int) Config {
+	c.NoTTLExamples = n
+	return c
+}
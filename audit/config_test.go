@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig("session:", "cache:")
+
+	if len(cfg.Prefixes) != 2 || cfg.Prefixes[0] != "session:" {
+		t.Errorf("DefaultConfig().Prefixes = %v, want [session: cache:]", cfg.Prefixes)
+	}
+	if cfg.ScanCount != 100 || cfg.MemorySamples != 100 || cfg.NoTTLExamples != 10 {
+		t.Errorf("DefaultConfig() = %+v, want scan count 100, 100 memory samples, 10 examples", cfg)
+	}
+	if len(cfg.TTLBuckets) != len(DefaultTTLBuckets) {
+		t.Errorf("DefaultConfig().TTLBuckets = %v, want %v", cfg.TTLBuckets, DefaultTTLBuckets)
+	}
+}
+
+func TestConfig_With(t *testing.T) {
+	cfg := DefaultConfig().
+		WithScanCount(5).
+		WithMemorySamples(-1, 3).
+		WithTTLBuckets(time.Hour, time.Minute).
+		WithNoTTLExamples(2)
+
+	if cfg.ScanCount != 5 || cfg.MemorySamples != -1 || cfg.MemoryUsageSamples != 3 || cfg.NoTTLExamples != 2 {
+		t.Errorf("With*() = %+v", cfg)
+	}
+
+	cfg = withDefaults(cfg)
+	if len(cfg.Prefixes) != 1 || cfg.Prefixes[0] != "" {
+		t.Errorf("withDefaults().Prefixes = %q, want the whole keyspace", cfg.Prefixes)
+	}
+	if cfg.TTLBuckets[0] != time.Minute || cfg.TTLBuckets[1] != time.Hour {
+		t.Errorf("withDefaults().TTLBuckets = %v, want them sorted", cfg.TTLBuckets)
+	}
+	if cfg.MemorySamples != -1 {
+		t.Errorf("withDefaults().MemorySamples = %d, want -1 kept", cfg.MemorySamples)
+	}
+}
@@ -0,0 +1,253 @@
+// Package auditlog keeps an append-only trail of who did what to which resource
+// Entries are appended to a capped Redis stream, whose IDs order them in time, and can be
+// read back by time range, newest first, or followed as they are written
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for audit log stream keys
+	DefaultKeyPrefix = "auditlog:"
+
+	// DefaultMaxLen is the default approximate number of entries kept
+	DefaultMaxLen = 100000
+)
+
+// followBlock is how long Follow waits for new entries before checking for cancellation
+const followBlock = time.Second
+
+// Stream fields of an entry
+const (
+	fieldActor    = "actor"
+	fieldAction   = "action"
+	fieldTarget   = "target"
+	fieldMetadata = "metadata"
+)
+
+// Entry is one audited action
+type Entry struct {
+	// ID is the stream ID of the entry, set when it is appended
+	ID string
+
+	// Time is when the entry was appended, taken from its ID
+	Time time.Time
+
+	// Actor is who performed the action, e.g. a user ID or "system"
+	Actor string
+
+	// Action is what was done, e.g. "user.login" or "invoice.delete"
+	Action string
+
+	// Target is the resource acted on (optional)
+	Target string
+
+	// Metadata holds further details such as the IP address or changed fields (optional)
+	Metadata map[string]string
+}
+
+// Log is a named audit trail
+type Log struct {
+	client    *redis.Client
+	name      string
+	keyPrefix string
+	maxLen    int64
+}
+
+// NewLog creates a handle to the audit log name (e.g. "admin")
+func NewLog(client *redis.Client, name string, opts ...Option) *Log {
+	l := &Log{
+		client:    client,
+		name:      name,
+		keyPrefix: DefaultKeyPrefix,
+		maxLen:    DefaultMaxLen,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Key returns the Redis key of the stream
+func (l *Log) Key() string {
+	return l.keyPrefix + l.name
+}
+
+// Append records entry and returns its ID; the entry's ID and Time are ignored
+func (l *Log) Append(ctx context.Context, entry Entry) (string, error) {
+	if l.client == nil {
+		return "", fmt.Errorf("redis client is nil")
+	}
+	if entry.Actor == "" || entry.Action == "" {
+		return "", ErrInvalidEntry
+	}
+
+	values := []interface{}{fieldActor, entry.Actor, fieldAction, entry.Action}
+	if entry.Target != "" {
+		values = append(values, fieldTarget, entry.Target)
+	}
+	if len(entry.Metadata) > 0 {
+		data, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode audit metadata: %w", err)
+		}
+		values = append(values, fieldMetadata, data)
+	}
+
+	args := &redis.XAddArgs{Stream: l.Key(), Values: values}
+	if l.maxLen > 0 {
+		args.MaxLen = l.maxLen
+		args.Approx = true
+	}
+	id, err := l.client.XAdd(ctx, args).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	return id, nil
+}
+
+// Range returns up to limit entries appended between from and to, both inclusive, oldest
+// first; a limit of zero or less returns them all
+func (l *Log) Range(ctx context.Context, from, to time.Time, limit int64) ([]Entry, error) {
+	if l.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	start := strconv.FormatInt(from.UnixMilli(), 10)
+	end := strconv.FormatInt(to.UnixMilli(), 10)
+	var msgs []redis.XMessage
+	var err error
+	if limit > 0 {
+		msgs, err = l.client.XRangeN(ctx, l.Key(), start, end, limit).Result()
+	} else {
+		msgs, err = l.client.XRange(ctx, l.Key(), start, end).Result()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit entries: %w", err)
+	}
+	return decodeEntries(msgs)
+}
+
+// Latest returns the n most recent entries, newest first
+func (l *Log) Latest(ctx context.Context, n int64) ([]Entry, error) {
+	if l.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if n <= 0 {
+		return []Entry{}, nil
+	}
+
+	msgs, err := l.client.XRevRangeN(ctx, l.Key(), "+", "-", n).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit entries: %w", err)
+	}
+	return decodeEntries(msgs)
+}
+
+// Len returns the number of entries kept
+func (l *Log) Len(ctx context.Context) (int64, error) {
+	if l.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	n, err := l.client.XLen(ctx, l.Key()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get audit log length: %w", err)
+	}
+	return n, nil
+}
+
+// Follow passes entries appended after the entry afterID to fn, in order, until ctx is
+// cancelled or fn returns an error. An empty afterID follows only entries appended from now
+// on; "0" replays the whole log first. Follow returns nil on cancellation and fn's error otherwise
+func (l *Log) Follow(ctx context.Context, afterID string, fn func(Entry) error) error {
+	if l.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if fn == nil {
+		return fmt.Errorf("follow callback is nil")
+	}
+
+	// "$" is resolved once up front, as passing it on every read would skip entries
+	// appended between reads
+	if afterID == "" {
+		last, err := l.client.XRevRangeN(ctx, l.Key(), "+", "-", 1).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read audit log tail: %w", err)
+		}
+		afterID = "0-0"
+		if len(last) == 1 {
+			afterID = last[0].ID
+		}
+	}
+
+	for ctx.Err() == nil {
+		streams, err := l.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{l.Key(), afterID},
+			Block:   followBlock,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to follow audit log: %w", err)
+		}
+		for _, stream := range streams {
+			entries, err := decodeEntries(stream.Messages)
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				if err := fn(entry); err != nil {
+					return err
+				}
+				afterID = entry.ID
+			}
+		}
+	}
+	return nil
+}
+
+func decodeEntries(msgs []redis.XMessage) ([]Entry, error) {
+	entries := make([]Entry, len(msgs))
+	for i, msg := range msgs {
+		entry, err := decodeEntry(msg)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+func decodeEntry(msg redis.XMessage) (Entry, error) {
+	entry := Entry{ID: msg.ID}
+	ms, _, _ := strings.Cut(msg.ID, "-")
+	if n, err := strconv.ParseInt(ms, 10, 64); err == nil {
+		entry.Time = time.UnixMilli(n)
+	}
+	entry.Actor, _ = msg.Values[fieldActor].(string)
+	entry.Action, _ = msg.Values[fieldAction].(string)
+	entry.Target, _ = msg.Values[fieldTarget].(string)
+	if raw, ok := msg.Values[fieldMetadata].(string); ok {
+		if err := json.Unmarshal([]byte(raw), &entry.Metadata); err != nil {
+			return Entry{}, fmt.Errorf("failed to decode metadata of audit entry %s: %w", msg.ID, err)
+		}
+	}
+	return entry, nil
+}
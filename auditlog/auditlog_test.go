@@ -0,0 +1,210 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestNewLog(t *testing.T) {
+	l := NewLog(nil, "admin")
+	if l.Key() != "auditlog:admin" || l.maxLen != DefaultMaxLen {
+		t.Errorf("NewLog() = %q/%d, want defaults", l.Key(), l.maxLen)
+	}
+
+	l = NewLog(nil, "admin", WithKeyPrefix("trail:"), WithMaxLen(0))
+	if l.Key() != "trail:admin" || l.maxLen != 0 {
+		t.Errorf("NewLog() with options = %q/%d, want trail:admin/0", l.Key(), l.maxLen)
+	}
+	if l = NewLog(nil, "admin", WithMaxLen(-1)); l.maxLen != DefaultMaxLen {
+		t.Errorf("NewLog() with negative max length = %d, want default", l.maxLen)
+	}
+}
+
+func TestLog_AppendAndRange(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	l := NewLog(client, "admin")
+
+	start := time.Now().Add(-time.Millisecond)
+	id, err := l.Append(ctx, Entry{
+		Actor:    "alice",
+		Action:   "user.delete",
+		Target:   "user:42",
+		Metadata: map[string]string{"ip": "10.0.0.1"},
+	})
+	if err != nil || id == "" {
+		t.Fatalf("Append() = %q, %v, want an ID", id, err)
+	}
+	_, _ = l.Append(ctx, Entry{Actor: "bob", Action: "user.login"})
+	_, _ = l.Append(ctx, Entry{Actor: "system", Action: "backup.run"})
+
+	entries, err := l.Range(ctx, start, time.Now(), 0)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("Range() = %v, %v, want 3 entries", entries, err)
+	}
+	e := entries[0]
+	if e.ID != id || e.Actor != "alice" || e.Action != "user.delete" || e.Target != "user:42" || e.Metadata["ip"] != "10.0.0.1" {
+		t.Errorf("Range()[0] = %+v, want alice's entry", e)
+	}
+	if e.Time.Before(start.Truncate(time.Millisecond)) || e.Time.After(time.Now()) {
+		t.Errorf("Range()[0].Time = %v, want the append time", e.Time)
+	}
+	if entries[1].Target != "" || entries[1].Metadata != nil {
+		t.Errorf("Range()[1] = %+v, want no target or metadata", entries[1])
+	}
+
+	if entries, _ := l.Range(ctx, start, time.Now(), 2); len(entries) != 2 || entries[1].Actor != "bob" {
+		t.Errorf("Range() with limit = %v, want the 2 oldest", entries)
+	}
+	if entries, _ := l.Range(ctx, start.Add(-time.Hour), start.Add(-time.Minute), 0); len(entries) != 0 {
+		t.Errorf("Range() before the first entry = %v, want none", entries)
+	}
+
+	latest, err := l.Latest(ctx, 2)
+	if err != nil || len(latest) != 2 || latest[0].Actor != "system" || latest[1].Actor != "bob" {
+		t.Errorf("Latest() = %v, %v, want system then bob", latest, err)
+	}
+	if latest, _ := l.Latest(ctx, 0); len(latest) != 0 {
+		t.Errorf("Latest(0) = %v, want none", latest)
+	}
+	if n, err := l.Len(ctx); err != nil || n != 3 {
+		t.Errorf("Len() = %d, %v, want 3", n, err)
+	}
+}
+
+func TestLog_MaxLen(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	l := NewLog(client, "admin", WithMaxLen(3))
+	for i := 0; i < 5; i++ {
+		_, _ = l.Append(ctx, Entry{Actor: "alice", Action: "a"})
+	}
+	if n, _ := l.Len(ctx); n != 3 {
+		t.Errorf("Len() = %d, want 3", n)
+	}
+}
+
+func TestLog_Follow(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l := NewLog(client, "admin")
+	_, _ = l.Append(ctx, Entry{Actor: "alice", Action: "old"})
+
+	got := make(chan Entry, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Follow(ctx, "", func(e Entry) error {
+			got <- e
+			return nil
+		})
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	_, _ = l.Append(ctx, Entry{Actor: "bob", Action: "new-1"})
+	_, _ = l.Append(ctx, Entry{Actor: "bob", Action: "new-2"})
+	for _, want := range []string{"new-1", "new-2"} {
+		select {
+		case e := <-got:
+			if e.Action != want {
+				t.Errorf("Follow() delivered %q, want %q", e.Action, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Follow() did not deliver %q", want)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Follow() error = %v, want nil on cancellation", err)
+	}
+}
+
+func TestLog_FollowReplay(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	l := NewLog(client, "admin")
+	for _, action := range []string{"a", "b", "c"} {
+		_, _ = l.Append(ctx, Entry{Actor: "alice", Action: action})
+	}
+
+	stop := errors.New("stop")
+	var actions []string
+	err := l.Follow(ctx, "0", func(e Entry) error {
+		actions = append(actions, e.Action)
+		if len(actions) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || len(actions) != 2 || actions[0] != "a" || actions[1] != "b" {
+		t.Errorf("Follow() = %v, %v, want a, b then the callback error", actions, err)
+	}
+}
+
+func TestLog_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	l := NewLog(nil, "admin")
+	if _, err := l.Append(ctx, Entry{Actor: "a", Action: "b"}); err == nil {
+		t.Error("Append() with nil client error = nil, want error")
+	}
+	if _, err := l.Range(ctx, time.Now(), time.Now(), 0); err == nil {
+		t.Error("Range() with nil client error = nil, want error")
+	}
+	if _, err := l.Latest(ctx, 1); err == nil {
+		t.Error("Latest() with nil client error = nil, want error")
+	}
+	if _, err := l.Len(ctx); err == nil {
+		t.Error("Len() with nil client error = nil, want error")
+	}
+	if err := l.Follow(ctx, "", func(Entry) error { return nil }); err == nil {
+		t.Error("Follow() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	l = NewLog(client, "admin")
+	if _, err := l.Append(ctx, Entry{Action: "b"}); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("Append() without actor error = %v, want ErrInvalidEntry", err)
+	}
+	if _, err := l.Append(ctx, Entry{Actor: "a"}); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("Append() without action error = %v, want ErrInvalidEntry", err)
+	}
+	if err := l.Follow(ctx, "", nil); err == nil {
+		t.Error("Follow() with nil callback error = nil, want error")
+	}
+
+	_ = client.XAdd(ctx, &redis.XAddArgs{Stream: l.Key(), Values: []string{"actor", "a", "metadata", "not-json"}}).Err()
+	if _, err := l.Latest(ctx, 1); err == nil {
+		t.Error("Latest() with malformed metadata error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if _, err := l.Append(ctx, Entry{Actor: "a", Action: "b"}); err == nil {
+		t.Error("Append() error = nil, want error")
+	}
+	if _, err := l.Range(ctx, time.Now(), time.Now(), 0); err == nil {
+		t.Error("Range() error = nil, want error")
+	}
+	if _, err := l.Len(ctx); err == nil {
+		t.Error("Len() error = nil, want error")
+	}
+	if err := l.Follow(ctx, "0", func(Entry) error { return nil }); err == nil {
+		t.Error("Follow() error = nil, want error")
+	}
+}
@@ -0,0 +1,8 @@
+package auditlog
+
+import "errors"
+
+var (
+	// ErrInvalidEntry indicates an entry without an actor or an action.
+	ErrInvalidEntry = errors.New("audit entry needs an actor and an action")
+)
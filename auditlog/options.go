@@ -0,0 +1,21 @@
+package auditlog
+
+// Option configures a Log
+type Option func(*Log)
+
+// WithKeyPrefix sets the prefix of the stream key (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(l *Log) {
+		l.keyPrefix = prefix
+	}
+}
+
+// WithMaxLen caps the stream at about n entries, dropping the oldest first
+// (default: DefaultMaxLen; 0 keeps every entry)
+func WithMaxLen(n int64) Option {
+	return func(l *Log) {
+		if n >= 0 {
+			l.maxLen = n
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Package bloomfilter provides probabilistic set membership on Redis
+// It uses RedisBloom when available and falls back to a bitmap with k hash functions
+package bloomfilter
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for filter keys
+	DefaultKeyPrefix = "bloom:"
+
+	// DefaultCapacity is the capacity used when none is given
+	DefaultCapacity = 10000
+
+	// DefaultErrorRate is the false positive rate used when none is given
+	DefaultErrorRate = 0.01
+)
+
+// Filter is a Bloom filter: Exists never misses an added item but may report
+// items that were never added, at roughly the configured error rate
+type Filter struct {
+	client    *redis.Client
+	name      string
+	keyPrefix string
+	capacity  uint64
+	errorRate float64
+	bits      uint64
+	hashes    uint
+	mode      atomic.Int32
+}
+
+// NewFilter creates a filter sized for capacity items at the given false positive rate
+// Zero or out-of-range values fall back to DefaultCapacity and DefaultErrorRate
+func NewFilter(client *redis.Client, name string, capacity uint64, errorRate float64, opts ...Option) *Filter {
+	if capacity == 0 {
+		capacity = DefaultCapacity
+	}
+	errorRate = clampErrorRate(errorRate)
+	bits := OptimalBits(capacity, errorRate)
+	f := &Filter{
+		client:    client,
+		name:      name,
+		keyPrefix: DefaultKeyPrefix,
+		capacity:  capacity,
+		errorRate: errorRate,
+		bits:      bits,
+		hashes:    OptimalHashes(bits, capacity),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Key returns the Redis key of the filter
+func (f *Filter) Key() string {
+	return f.keyPrefix + f.name
+}
+
+// Mode returns the implementation in use; ModeAuto until the first command resolves it
+func (f *Filter) Mode() Mode {
+	return Mode(f.mode.Load())
+}
+
+// Bits returns the bitmap size used by the fallback implementation
+func (f *Filter) Bits() uint64 {
+	return f.bits
+}
+
+// Hashes returns the number of hash functions used by the fallback implementation
+func (f *Filter) Hashes() uint {
+	return f.hashes
+}
+
+// Add adds item to the filter
+// Returns true if the item was not present before (subject to false positives)
+func (f *Filter) Add(ctx context.Context, item string) (bool, error) {
+	added, err := f.AddMulti(ctx, item)
+	if err != nil {
+		return false, err
+	}
+	return added[0], nil
+}
+
+// AddMulti adds items to the filter and reports for each whether it was new
+func (f *Filter) AddMulti(ctx context.Context, items ...string) ([]bool, error) {
+	if f.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if len(items) == 0 {
+		return []bool{}, nil
+	}
+
+	var added []bool
+	err := f.dispatch(ctx,
+		func() (err error) {
+			added, err = f.client.BFInsert(ctx, f.Key(), &redis.BFInsertOptions{
+				Capacity: int64(f.capacity),
+				Error:    f.errorRate,
+			}, toArgs(items)...).Result()
+			return err
+		},
+		func() (err error) {
+			added, err = f.bitmapAdd(ctx, items)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add to bloom filter: %w", err)
+	}
+	return added, nil
+}
+
+// Exists reports whether item may have been added
+func (f *Filter) Exists(ctx context.Context, item string) (bool, error) {
+	found, err := f.ExistsMulti(ctx, item)
+	if err != nil {
+		return false, err
+	}
+	return found[0], nil
+}
+
+// ExistsMulti reports for each item whether it may have been added
+func (f *Filter) ExistsMulti(ctx context.Context, items ...string) ([]bool, error) {
+	if f.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if len(items) == 0 {
+		return []bool{}, nil
+	}
+
+	var found []bool
+	err := f.dispatch(ctx,
+		func() (err error) {
+			found, err = f.client.BFMExists(ctx, f.Key(), toArgs(items)...).Result()
+			return err
+		},
+		func() (err error) {
+			found, err = f.bitmapExists(ctx, items)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bloom filter: %w", err)
+	}
+	return found, nil
+}
+
+// Clear deletes the filter
+func (f *Filter) Clear(ctx context.Context) error {
+	if f.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := f.client.Del(ctx, f.Key()).Err(); err != nil {
+		return fmt.Errorf("failed to clear bloom filter: %w", err)
+	}
+	return nil
+}
+
+// dispatch runs the RedisBloom or bitmap variant of a command
+// In ModeAuto the first "unknown command" reply switches the filter to the bitmap for good
+func (f *Filter) dispatch(ctx context.Context, bloom, bitmap func() error) error {
+	switch f.Mode() {
+	case ModeRedisBloom:
+		return bloom()
+	case ModeBitmap:
+		return bitmap()
+	}
+
+	err := bloom()
+	if err == nil {
+		f.mode.CompareAndSwap(int32(ModeAuto), int32(ModeRedisBloom))
+		return nil
+	}
+	if !isUnknownCommand(err) || ctx.Err() != nil {
+		return err
+	}
+	f.mode.CompareAndSwap(int32(ModeAuto), int32(ModeBitmap))
+	return bitmap()
+}
+
+func isUnknownCommand(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "unknown command")
+}
+
+// locations returns the k bit offsets of item using double hashing over a 128-bit FNV-1a hash
+func (f *Filter) locations(item string) []int64 {
+	h := fnv.New128a()
+	_, _ = h.Write([]byte(item))
+	sum := h.Sum(nil)
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:])
+
+	locs := make([]int64, f.hashes)
+	for i := range locs {
+		locs[i] = int64((h1 + uint64(i)*h2) % f.bits)
+	}
+	return locs
+}
+
+// bitmapAdd sets the bits of every item in one pipeline
+// An item counts as new when any of its bits was unset; concurrent adds of the same
+// item may both report it as new
+func (f *Filter) bitmapAdd(ctx context.Context, items []string) ([]bool, error) {
+	key := f.Key()
+	pipe := f.client.Pipeline()
+	cmds := make([][]*redis.IntCmd, len(items))
+	for i, item := range items {
+		for _, loc := range f.locations(item) {
+			cmds[i] = append(cmds[i], pipe.SetBit(ctx, key, loc, 1))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	added := make([]bool, len(items))
+	for i := range items {
+		for _, cmd := range cmds[i] {
+			if cmd.Val() == 0 {
+				added[i] = true
+				break
+			}
+		}
+	}
+	return added, nil
+}
+
+// bitmapExists reads the bits of every item in one pipeline
+func (f *Filter) bitmapExists(ctx context.Context, items []string) ([]bool, error) {
+	key := f.Key()
+	pipe := f.client.Pipeline()
+	cmds := make([][]*redis.IntCmd, len(items))
+	for i, item := range items {
+		for _, loc := range f.locations(item) {
+			cmds[i] = append(cmds[i], pipe.GetBit(ctx, key, loc))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	found := make([]bool, len(items))
+	for i := range items {
+		found[i] = true
+		for _, cmd := range cmds[i] {
+			if cmd.Val() == 0 {
+				found[i] = false
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+func toArgs(items []string) []interface{} {
+	args := make([]interface{}, len(items))
+	for i, item := range items {
+		args[i] = item
+	}
+	return args
+}
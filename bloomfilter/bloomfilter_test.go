@@ -0,0 +1,137 @@
+package bloomfilter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestNewFilter(t *testing.T) {
+	f := NewFilter(nil, "emails", 0, 0)
+	if f.Key() != "bloom:emails" {
+		t.Errorf("Key() = %q, want %q", f.Key(), "bloom:emails")
+	}
+	if f.capacity != DefaultCapacity || f.errorRate != DefaultErrorRate {
+		t.Errorf("NewFilter() sizing = %d/%v, want defaults", f.capacity, f.errorRate)
+	}
+	if f.Bits() != OptimalBits(DefaultCapacity, DefaultErrorRate) || f.Hashes() != 7 {
+		t.Errorf("NewFilter() bits/hashes = %d/%d", f.Bits(), f.Hashes())
+	}
+	if f.Mode() != ModeAuto {
+		t.Errorf("Mode() = %s, want auto", f.Mode())
+	}
+
+	f = NewFilter(nil, "emails", 100, 0.001, WithKeyPrefix("bf:"), WithMode(ModeBitmap))
+	if f.Key() != "bf:emails" || f.Mode() != ModeBitmap {
+		t.Errorf("NewFilter() with options = %q/%s, want bf:emails/bitmap", f.Key(), f.Mode())
+	}
+}
+
+func TestFilter_AutoFallsBackToBitmap(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	f := NewFilter(client, "emails", 1000, 0.01)
+
+	// The mock has no RedisBloom module, so the first command switches to the bitmap
+	added, err := f.Add(ctx, "a@example.com")
+	if err != nil || !added {
+		t.Fatalf("Add() = %v, %v, want true", added, err)
+	}
+	if f.Mode() != ModeBitmap {
+		t.Errorf("Mode() = %s, want bitmap", f.Mode())
+	}
+	if added, _ := f.Add(ctx, "a@example.com"); added {
+		t.Error("Add() of an existing item = true, want false")
+	}
+	if ok, err := f.Exists(ctx, "a@example.com"); err != nil || !ok {
+		t.Errorf("Exists() = %v, %v, want true", ok, err)
+	}
+	if typ, _ := client.Type(ctx, f.Key()).Result(); typ != "string" {
+		t.Errorf("Type() of the filter key = %q, want string", typ)
+	}
+}
+
+func TestFilter_Bitmap(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	f := NewFilter(client, "ids", 1000, 0.01, WithMode(ModeBitmap))
+
+	items := make([]string, 500)
+	for i := range items {
+		items[i] = fmt.Sprintf("item-%d", i)
+	}
+	added, err := f.AddMulti(ctx, items...)
+	if err != nil || len(added) != len(items) {
+		t.Fatalf("AddMulti() = %d results, %v, want %d", len(added), err, len(items))
+	}
+
+	found, err := f.ExistsMulti(ctx, items...)
+	if err != nil {
+		t.Fatalf("ExistsMulti() error = %v, want nil", err)
+	}
+	for i, ok := range found {
+		if !ok {
+			t.Fatalf("ExistsMulti()[%d] = false, added items must always be found", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		if ok, _ := f.Exists(ctx, fmt.Sprintf("other-%d", i)); ok {
+			falsePositives++
+		}
+	}
+	if falsePositives > 30 {
+		t.Errorf("false positives = %d/1000, want about 1%% or less", falsePositives)
+	}
+
+	if res, err := f.AddMulti(ctx); err != nil || len(res) != 0 {
+		t.Errorf("AddMulti() with no items = %v, %v, want empty", res, err)
+	}
+	if err := f.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error = %v, want nil", err)
+	}
+	if ok, _ := f.Exists(ctx, items[0]); ok {
+		t.Error("Exists() after Clear = true, want false")
+	}
+}
+
+func TestFilter_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	f := NewFilter(nil, "x", 10, 0.1)
+	if _, err := f.Add(ctx, "a"); err == nil {
+		t.Error("Add() with nil client error = nil, want error")
+	}
+	if _, err := f.Exists(ctx, "a"); err == nil {
+		t.Error("Exists() with nil client error = nil, want error")
+	}
+	if err := f.Clear(ctx); err == nil {
+		t.Error("Clear() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	// Forcing RedisBloom against a server without the module surfaces the error
+	f = NewFilter(client, "x", 10, 0.1, WithMode(ModeRedisBloom))
+	if _, err := f.Add(ctx, "a"); err == nil {
+		t.Error("Add() in RedisBloom mode without the module error = nil, want error")
+	}
+
+	// Other failures do not trigger the fallback
+	mock.SetShouldFail(true)
+	f = NewFilter(client, "x", 10, 0.1)
+	if _, err := f.Exists(ctx, "a"); err == nil {
+		t.Error("Exists() error = nil, want error")
+	}
+	if f.Mode() != ModeAuto {
+		t.Errorf("Mode() after a failure = %s, want auto", f.Mode())
+	}
+}
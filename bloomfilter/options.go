@@ -0,0 +1,42 @@
+package bloomfilter
+
+// Mode selects the filter implementation
+type Mode int
+
+const (
+	// ModeAuto uses RedisBloom when the server provides it and the bitmap fallback otherwise
+	ModeAuto Mode = iota
+	// ModeRedisBloom always uses the RedisBloom BF.* commands
+	ModeRedisBloom
+	// ModeBitmap always uses a plain bitmap with SETBIT/GETBIT
+	ModeBitmap
+)
+
+// String returns the name of the mode
+func (m Mode) String() string {
+	switch m {
+	case ModeRedisBloom:
+		return "redisbloom"
+	case ModeBitmap:
+		return "bitmap"
+	default:
+		return "auto"
+	}
+}
+
+// Option configures a Filter
+type Option func(*Filter)
+
+// WithKeyPrefix sets the prefix of the filter key (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(f *Filter) {
+		f.keyPrefix = prefix
+	}
+}
+
+// WithMode selects the implementation (default: ModeAuto)
+func WithMode(mode Mode) Option {
+	return func(f *Filter) {
+		f.mode.Store(int32(mode))
+	}
+}
@@ -0,0 +1,47 @@
+package bloomfilter
+
+import "math"
+
+// MaxBits is the largest bitmap the fallback implementation uses (512 MB, the Redis string limit)
+const MaxBits = 1 << 32
+
+// OptimalBits returns the bitmap size needed to hold capacity items at the given false positive rate
+func OptimalBits(capacity uint64, errorRate float64) uint64 {
+	if capacity == 0 {
+		capacity = 1
+	}
+	errorRate = clampErrorRate(errorRate)
+	bits := math.Ceil(-float64(capacity) * math.Log(errorRate) / (math.Ln2 * math.Ln2))
+	if bits > MaxBits {
+		return MaxBits
+	}
+	return uint64(bits)
+}
+
+// OptimalHashes returns the number of hash functions minimizing false positives for the given sizing
+func OptimalHashes(bits, capacity uint64) uint {
+	if capacity == 0 {
+		capacity = 1
+	}
+	k := math.Round(float64(bits) / float64(capacity) * math.Ln2)
+	if k < 1 {
+		return 1
+	}
+	return uint(k)
+}
+
+// EstimatedErrorRate returns the expected false positive rate after inserting n items
+func EstimatedErrorRate(bits uint64, hashes uint, n uint64) float64 {
+	if bits == 0 {
+		return 1
+	}
+	k := float64(hashes)
+	return math.Pow(1-math.Exp(-k*float64(n)/float64(bits)), k)
+}
+
+func clampErrorRate(errorRate float64) float64 {
+	if errorRate <= 0 || errorRate >= 1 || math.IsNaN(errorRate) {
+		return DefaultErrorRate
+	}
+	return errorRate
+}
@@ -0,0 +1,41 @@
+package bloomfilter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOptimalBits(t *testing.T) {
+	// 1M items at 1% need about 9.59M bits and 7 hash functions
+	bits := OptimalBits(1000000, 0.01)
+	if bits < 9585000 || bits > 9586000 {
+		t.Errorf("OptimalBits(1e6, 0.01) = %d, want ~9585059", bits)
+	}
+	if k := OptimalHashes(bits, 1000000); k != 7 {
+		t.Errorf("OptimalHashes() = %d, want 7", k)
+	}
+
+	if OptimalBits(0, 0.01) == 0 {
+		t.Error("OptimalBits() with zero capacity = 0, want > 0")
+	}
+	if OptimalBits(100, 0) != OptimalBits(100, DefaultErrorRate) {
+		t.Error("OptimalBits() with invalid error rate should use DefaultErrorRate")
+	}
+	if OptimalBits(math.MaxUint64, 0.0001) != MaxBits {
+		t.Errorf("OptimalBits() huge capacity = %d, want MaxBits", OptimalBits(math.MaxUint64, 0.0001))
+	}
+	if k := OptimalHashes(1, 1000); k != 1 {
+		t.Errorf("OptimalHashes() for a tiny bitmap = %d, want 1", k)
+	}
+}
+
+func TestEstimatedErrorRate(t *testing.T) {
+	bits := OptimalBits(10000, 0.01)
+	rate := EstimatedErrorRate(bits, OptimalHashes(bits, 10000), 10000)
+	if rate < 0.009 || rate > 0.011 {
+		t.Errorf("EstimatedErrorRate() at capacity = %v, want ~0.01", rate)
+	}
+	if EstimatedErrorRate(0, 1, 1) != 1 {
+		t.Error("EstimatedErrorRate() with no bits should be 1")
+	}
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SetBytes stores data as is with the given TTL, bypassing the cache's codec, for payloads
+// that are already serialized, e.g. protobuf blobs or rendered HTML. A codec.Sealer codec,
+// e.g. codec.EncryptedCodec, still seals it
+func (c *RedisCache) SetBytes(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("set") {
+		return nil
+	}
+	data, err := c.seal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	return c.setRaw(ctx, "set", key, data, ttl)
+}
+
+// GetBytes returns the bytes stored at key as is, bypassing the cache's codec, or an error
+// wrapping ErrNotFound when the key is missing
+func (c *RedisCache) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.getRaw(ctx, "get", key)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = c.open(data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+	return data, nil
+}
+
+// SetBytes stores data as is, as RedisCache.SetBytes does, marks it most recently used, and
+// evicts beyond the cap
+func (c *LRUCache) SetBytes(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("set") {
+		return nil
+	}
+	data, err := c.seal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	return c.setRaw(ctx, "set", key, data, ttl)
+}
+
+// GetBytes returns the bytes stored at key as is and marks it most recently used
+func (c *LRUCache) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.getRaw(ctx, "get", key)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = c.open(data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+	return data, nil
+}
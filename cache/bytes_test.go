@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+func TestSetBytes(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	lru := NewLRUCache(client, "{bytes-lru}:", 10)
+	for name, c := range map[string]Cache{
+		"redis": NewCache(client, "bytes:"),
+		"lru":   lru,
+	} {
+		blob := []byte{0x08, 0x96, 0x01, '"'}
+		if err := c.SetBytes(ctx, "blob", blob, time.Minute); err != nil {
+			t.Fatalf("%s: SetBytes() error = %v, want nil", name, err)
+		}
+		got, err := c.GetBytes(ctx, "blob")
+		if err != nil || string(got) != string(blob) {
+			t.Errorf("%s: GetBytes() = %q, %v, want %q", name, got, err, blob)
+		}
+		if _, err := c.GetBytes(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: GetBytes() of a missing key error = %v, want ErrNotFound", name, err)
+		}
+	}
+	if n, _ := lru.Len(ctx); n != 1 {
+		t.Errorf("LRU Len() after SetBytes = %d, want 1", n)
+	}
+
+	mock.SetShouldFail(true)
+	if err := NewCache(client, "bytes:").SetBytes(ctx, "k", nil, 0); err == nil {
+		t.Error("SetBytes() with failing Redis error = nil, want error")
+	}
+	if _, err := NewCache(nil, "bytes:").GetBytes(ctx, "k"); err == nil {
+		t.Error("GetBytes() with nil client error = nil, want error")
+	}
+}
+
+func TestRedisCache_RawValues(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	for name, c := range map[string]*RedisCache{
+		"json":    NewCache(client, "raw-json:"),
+		"msgpack": NewCache(client, "raw-mp:", WithCodec(codec.Msgpack)),
+	} {
+		_ = c.Set(ctx, "html", "<p>hi</p>", 0)
+		_ = c.Set(ctx, "blob", []byte("\x00\x01"), 0)
+
+		// Strings and byte slices are stored without encoding
+		if v, _ := client.Get(ctx, c.Key("html")).Result(); v != "<p>hi</p>" {
+			t.Errorf("%s: stored string = %q, want it as is", name, v)
+		}
+		var s string
+		if err := c.Get(ctx, "html", &s); err != nil || s != "<p>hi</p>" {
+			t.Errorf("%s: Get(*string) = %q, %v, want the string", name, s, err)
+		}
+		var b []byte
+		if err := c.Get(ctx, "blob", &b); err != nil || string(b) != "\x00\x01" {
+			t.Errorf("%s: Get(*[]byte) = %q, %v, want the bytes", name, b, err)
+		}
+		if got, _ := c.GetBytes(ctx, "html"); string(got) != "<p>hi</p>" {
+			t.Errorf("%s: GetBytes() of a string = %q, want it as is", name, got)
+		}
+
+		// Other values still go through the codec
+		_ = c.Set(ctx, "n", 42, 0)
+		var n int
+		if err := c.Get(ctx, "n", &n); err != nil || n != 42 {
+			t.Errorf("%s: Get(*int) = %d, %v, want 42", name, n, err)
+		}
+	}
+}
+
+func TestRedisCache_EncryptedRaw(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	enc, err := codec.Encrypted(codec.JSON, "k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+	if err != nil {
+		t.Fatalf("Encrypted() error = %v, want nil", err)
+	}
+	for name, c := range map[string]interface {
+		Cache
+		Key(key string) string
+	}{
+		"redis": NewCache(client, "enc:", WithCodec(enc)),
+		"lru":   NewLRUCache(client, "{enc-lru}:", 10, WithCodec(enc)),
+	} {
+		// Strings and bytes skip the codec's encoding but are still encrypted
+		_ = c.Set(ctx, "email", "alice@example.com", 0)
+		_ = c.SetBytes(ctx, "blob", []byte("ssn"), 0)
+		for _, k := range []string{"email", "blob"} {
+			if v, _ := client.Get(ctx, c.Key(k)).Result(); strings.Contains(v, "alice") || strings.Contains(v, "ssn") {
+				t.Errorf("%s: stored %s = %q, want it encrypted", name, k, v)
+			}
+		}
+		var s string
+		if err := c.Get(ctx, "email", &s); err != nil || s != "alice@example.com" {
+			t.Errorf("%s: Get(*string) = %q, %v, want the string", name, s, err)
+		}
+		if b, err := c.GetBytes(ctx, "blob"); err != nil || string(b) != "ssn" {
+			t.Errorf("%s: GetBytes() = %q, %v, want the bytes", name, b, err)
+		}
+
+		// Plaintext written by another cache does not decrypt
+		_ = client.Set(ctx, c.Key("plain"), "leak", 0).Err()
+		if _, err := c.GetBytes(ctx, "plain"); !errors.Is(err, codec.ErrDecrypt) {
+			t.Errorf("%s: GetBytes() of plaintext error = %v, want codec.ErrDecrypt", name, err)
+		}
+	}
+}
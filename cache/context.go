@@ -0,0 +1,20 @@
+package cache
+
+import "context"
+
+type prefixKey struct{}
+
+// ContextWithPrefix returns a copy of ctx under which cache operations scope their keys with
+// prefix, e.g. "tenant42:", placed after the cache's own key prefix: a key "user:1" of a
+// cache prefixed "app:" becomes "app:tenant42:user:1". One cache can then serve many tenants
+// or requests without a cache per tenant. DelPrefix, Flush, ScanKeys, Keys, and tags stay
+// within the scope; an empty prefix lifts a scope set earlier
+func ContextWithPrefix(ctx context.Context, prefix string) context.Context {
+	return context.WithValue(ctx, prefixKey{}, prefix)
+}
+
+// PrefixFromContext returns the key scope attached by ContextWithPrefix, or "" if there is none
+func PrefixFromContext(ctx context.Context) string {
+	prefix, _ := ctx.Value(prefixKey{}).(string)
+	return prefix
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestContextWithPrefix(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+	acme := ContextWithPrefix(ctx, "acme:")
+	globex := ContextWithPrefix(ctx, "globex:")
+
+	if got := PrefixFromContext(acme); got != "acme:" {
+		t.Errorf("PrefixFromContext() = %q, want acme:", got)
+	}
+	if got := PrefixFromContext(ContextWithPrefix(acme, "")); got != "" {
+		t.Errorf("PrefixFromContext() after lifting the scope = %q, want none", got)
+	}
+
+	for name, c := range map[string]Cache{
+		"redis": NewCache(client, "app:"),
+		"lru":   NewLRUCache(client, "{app-lru}:", 10),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_ = c.Set(acme, "user:1", "alice", 0)
+			_ = c.Set(globex, "user:1", "bob", 0)
+			var got string
+			if err := c.Get(acme, "user:1", &got); err != nil || got != "alice" {
+				t.Errorf("Get() under acme = %q, %v, want alice", got, err)
+			}
+			if err := c.Get(globex, "user:1", &got); err != nil || got != "bob" {
+				t.Errorf("Get() under globex = %q, %v, want bob", got, err)
+			}
+			if err := c.Get(ctx, "user:1", &got); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() without a scope error = %v, want ErrNotFound", err)
+			}
+			if n, err := c.Incr(acme, "visits"); err != nil || n != 1 {
+				t.Errorf("Incr() under acme = %d, %v, want 1", n, err)
+			}
+		})
+	}
+
+	c := NewCache(client, "app:")
+	if exists, _ := client.Exists(ctx, "app:acme:user:1").Result(); exists != 1 {
+		t.Error("scoped entry is not keyed app:acme:user:1")
+	}
+	if keys, err := c.Keys(acme, "user:*"); err != nil || !slices.Equal(keys, []string{"user:1"}) {
+		t.Errorf("Keys() under acme = %v, %v, want [user:1]", keys, err)
+	}
+
+	// Tags and deletes stay within the scope
+	_ = c.SetWithTags(acme, "page:1", "a", time.Minute, "pages")
+	_ = c.SetWithTags(globex, "page:1", "g", time.Minute, "pages")
+	if n, err := c.InvalidateTag(acme, "pages"); err != nil || n != 1 {
+		t.Errorf("InvalidateTag() under acme = %d, %v, want 1", n, err)
+	}
+	if exists, _ := c.Exists(globex, "page:1"); !exists {
+		t.Error("InvalidateTag() under acme deleted globex's entry")
+	}
+	if n, err := c.Flush(acme); err != nil || n != 2 {
+		t.Errorf("Flush() under acme = %d, %v, want its 2 remaining entries", n, err)
+	}
+	if exists, _ := c.Exists(globex, "user:1"); !exists {
+		t.Error("Flush() under acme deleted globex's entry")
+	}
+}
+
+func TestContextWithPrefix_Versioned(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+	acme := ContextWithPrefix(ctx, "acme:")
+	globex := ContextWithPrefix(ctx, "globex:")
+
+	v := NewVersionedCache(client, "users:")
+	_ = v.Set(acme, "1", "alice", 0)
+	_ = v.Set(globex, "1", "bob", 0)
+	if exists, _ := client.Exists(ctx, "users:v0:acme:1").Result(); exists != 1 {
+		t.Error("scoped entry is not keyed users:v0:acme:1")
+	}
+	if n, err := v.DelVersion(acme, 0); err != nil || n != 1 {
+		t.Errorf("DelVersion() under acme = %d, %v, want 1", n, err)
+	}
+	if exists, _ := v.Exists(globex, "1"); !exists {
+		t.Error("DelVersion() under acme deleted globex's entry")
+	}
+}
+
+func TestContextWithPrefix_GetOrSet(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	names := NewTyped[string](client, "names:")
+	for _, tenant := range []string{"acme:", "globex:"} {
+		ctx := ContextWithPrefix(context.Background(), tenant)
+		got, err := names.GetOrSet(ctx, "1", 0, func(ctx context.Context) (string, error) {
+			return PrefixFromContext(ctx), nil
+		})
+		if err != nil || got != tenant {
+			t.Errorf("GetOrSet() under %s = %q, %v, want its own load", tenant, got, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestRedisCache_Counters(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	rec := testutil.NewRecorder()
+	c := NewCache(client, "hits:", WithRecorder(rec))
+	for _, step := range []struct {
+		name string
+		call func() (int64, error)
+		want int64
+	}{
+		{"Incr", func() (int64, error) { return c.Incr(ctx, "home") }, 1},
+		{"IncrBy", func() (int64, error) { return c.IncrBy(ctx, "home", 10) }, 11},
+		{"IncrBy negative", func() (int64, error) { return c.IncrBy(ctx, "home", -5) }, 6},
+		{"Decr", func() (int64, error) { return c.Decr(ctx, "home") }, 5},
+	} {
+		if got, err := step.call(); err != nil || got != step.want {
+			t.Errorf("%s() = %d, %v, want %d", step.name, got, err, step.want)
+		}
+	}
+	if v, err := client.Get(ctx, "hits:home").Result(); err != nil || v != "5" {
+		t.Errorf("stored counter = %q, %v, want 5 under the prefix", v, err)
+	}
+	var got int64
+	if err := c.Get(ctx, "home", &got); err != nil || got != 5 {
+		t.Errorf("Get() of a counter = %d, %v, want 5", got, err)
+	}
+	if n := rec.Count(metrics.SubsystemCache, "incr_by", metrics.OutcomeSuccess); n != 2 {
+		t.Errorf("Count(incr_by) = %d, want 2", n)
+	}
+
+	_ = c.Set(ctx, "name", "alice", 0)
+	if _, err := c.Incr(ctx, "name"); err == nil {
+		t.Error("Incr() of a non-integer value error = nil, want error")
+	}
+	mock.SetShouldFail(true)
+	if _, err := c.Incr(ctx, "home"); err == nil {
+		t.Error("Incr() with failing Redis error = nil, want error")
+	}
+	if _, err := NewCache(nil, "x:").Decr(ctx, "k"); err == nil {
+		t.Error("Decr() with nil client error = nil, want error")
+	}
+}
+
+func TestRedisCache_IncrWithTTL(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewCache(client, "window:")
+	if n, err := c.IncrWithTTL(ctx, "k", 3, time.Minute); err != nil || n != 3 {
+		t.Fatalf("IncrWithTTL() = %d, %v, want 3", n, err)
+	}
+	_ = c.Expire(ctx, "k", 2*time.Minute)
+
+	// The first expiry is kept
+	if n, err := c.IncrWithTTL(ctx, "k", 2, time.Minute); err != nil || n != 5 {
+		t.Errorf("IncrWithTTL() = %d, %v, want 5", n, err)
+	}
+	if ttl, _ := c.TTL(ctx, "k"); ttl <= time.Minute {
+		t.Errorf("TTL() = %v, want the existing expiry kept", ttl)
+	}
+
+	if _, err := c.IncrWithTTL(ctx, "forever", 1, 0); err != nil {
+		t.Fatalf("IncrWithTTL() without ttl error = %v, want nil", err)
+	}
+	if ttl, _ := c.TTL(ctx, "forever"); ttl != -1 {
+		t.Errorf("TTL() without ttl = %v, want no expiry", ttl)
+	}
+
+	degraded := NewCache(&degradedClient{UniversalClient: client, degraded: true}, "window:")
+	if n, err := degraded.IncrWithTTL(ctx, "k", 1, time.Minute); err != nil || n != 0 {
+		t.Errorf("IncrWithTTL() while degraded = %d, %v, want 0", n, err)
+	}
+	if n, _ := c.IncrBy(ctx, "k", 0); n != 5 {
+		t.Errorf("counter after degraded IncrWithTTL = %d, want 5", n)
+	}
+}
@@ -0,0 +1,19 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound indicates the key does not exist in the cache.
+	ErrNotFound = errors.New("key not found")
+	// ErrLoaderPanic indicates the GetOrSet loader panicked while other callers waited for it.
+	ErrLoaderPanic = errors.New("cache loader panicked")
+	// ErrNegativeHit indicates the key is cached as known to be missing; it also matches ErrNotFound.
+	ErrNegativeHit = fmt.Errorf("%w: cached as missing", ErrNotFound)
+	// ErrTombstoned indicates the key was deleted with SetTombstone; it also matches ErrNotFound.
+	ErrTombstoned = fmt.Errorf("%w: tombstoned", ErrNotFound)
+	// ErrValueTooLarge indicates an encoded value exceeds the cache's WithMaxValueSize.
+	ErrValueTooLarge = errors.New("value too large")
+)
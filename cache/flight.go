@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// flight is a load in progress, shared by the callers asking for the same key
+type flight[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// flightGroup coalesces concurrent loads of the same key into one
+type flightGroup[T any] struct {
+	mu      sync.Mutex
+	flights map[string]*flight[T]
+}
+
+// do runs load for key unless a load of key is already in progress, in which case it waits for
+// that load's result, or until ctx is done. When load panics, the panic goes on in the caller
+// that ran it and the waiting callers get ErrLoaderPanic
+func (g *flightGroup[T]) do(ctx context.Context, key string, load func() (T, error)) (T, error) {
+	g.mu.Lock()
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.value, f.err
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+	if g.flights == nil {
+		g.flights = make(map[string]*flight[T])
+	}
+	f := &flight[T]{done: make(chan struct{}), err: ErrLoaderPanic}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	f.value, f.err = load()
+	return f.value, f.err
+}
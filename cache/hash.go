@@ -0,0 +1,422 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+)
+
+// hashSetScript replaces KEYS[1] with a hash of the field and value pairs in ARGV[2:] and
+// expires it in ARGV[1] milliseconds when positive, so no field of an older object is kept
+const hashSetScript = `
+-- redis-kit:cache-hash-set
+redis.call("del", KEYS[1])
+redis.call("hset", KEYS[1], unpack(ARGV, 2))
+if tonumber(ARGV[1]) > 0 then
+	redis.call("pexpire", KEYS[1], ARGV[1])
+end
+return 1
+`
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// hashField is a struct field stored as a hash field
+type hashField struct {
+	name  string
+	index []int
+	typ   reflect.Type
+}
+
+// HashCache is a cache of structs of type T stored as Redis hashes, one hash field per struct
+// field, so single fields can be read, written, and incremented without rewriting the whole
+// object. A field is named by its `redis` tag, as go-redis's HSet and Scan name it, or else by
+// its Go name; `redis:"-"` and unexported fields are skipped. Strings, numbers, bools, and
+// times are stored in their text form, so numbers can be incremented in place, and other
+// fields are encoded with the cache's codec. Use Cache for Del, Exists, TTL, and Expire
+type HashCache[T any] struct {
+	cache  *RedisCache
+	fields []hashField
+	byName map[string]hashField
+	err    error // why T cannot be stored, if it cannot
+}
+
+// NewHashCache creates a hash cache of structs of type T with the given client, key prefix,
+// and options, as NewCache does; methods fail if T is not a struct with a stored field
+func NewHashCache[T any](client redis.UniversalClient, keyPrefix string, opts ...Option) *HashCache[T] {
+	h := &HashCache[T]{cache: NewCache(client, keyPrefix, opts...)}
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		h.err = fmt.Errorf("hash cache needs a struct type, got %s", t)
+		return h
+	}
+	h.byName = make(map[string]hashField)
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || len(f.Index) > 1 {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("redis"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		field := hashField{name: name, index: f.Index, typ: f.Type}
+		h.fields = append(h.fields, field)
+		h.byName[name] = field
+	}
+	if len(h.fields) == 0 {
+		h.err = fmt.Errorf("hash cache needs a struct with exported fields, got %s", t)
+	}
+	return h
+}
+
+// Cache returns the underlying cache, e.g. to delete or expire an object
+func (h *HashCache[T]) Cache() *RedisCache {
+	return h.cache
+}
+
+// Set stores every field of value at key, replacing the whole object, with the given TTL,
+// varied by WithTTLJitter if set
+func (h *HashCache[T]) Set(ctx context.Context, key string, value T, ttl time.Duration) error {
+	if skip, err := h.check("hash_set"); skip || err != nil {
+		return err
+	}
+	args := []interface{}{h.cache.jitter(ttl).Milliseconds()}
+	v := reflect.ValueOf(&value).Elem()
+	for _, f := range h.fields {
+		data, err := h.encodeField(v.FieldByIndex(f.index))
+		if err != nil {
+			return fmt.Errorf("failed to marshal field %s: %w", f.name, err)
+		}
+		args = append(args, f.name, data)
+	}
+
+	fullKey := h.cache.buildKey(ctx, key)
+	start := time.Now()
+	err := h.cache.do(ctx, func(ctx context.Context) error {
+		return h.cache.client.Eval(ctx, hashSetScript, []string{fullKey}, args...).Err()
+	})
+	h.cache.observe("hash_set", metrics.Outcome(err), start)
+	if err != nil {
+		return fmt.Errorf("failed to set cache: %w", err)
+	}
+	return nil
+}
+
+// Get returns the object stored at key, or the zero value and an error wrapping ErrNotFound
+// when the key is missing; fields missing from the hash are left zero
+func (h *HashCache[T]) Get(ctx context.Context, key string) (T, error) {
+	var value T
+	if skip, err := h.check("hash_get"); err != nil {
+		return value, err
+	} else if skip {
+		return value, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	fullKey := h.cache.buildKey(ctx, key)
+	start := time.Now()
+	var stored map[string]string
+	err := h.cache.read(ctx, func(ctx context.Context, client redis.Cmdable) error {
+		var getErr error
+		stored, getErr = client.HGetAll(ctx, fullKey).Result()
+		return getErr
+	})
+	if err == nil && len(stored) == 0 {
+		err = redis.Nil
+	}
+	h.observeRead("hash_get", err, start)
+	if err == redis.Nil {
+		return value, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return value, fmt.Errorf("failed to get cache: %w", err)
+	}
+
+	v := reflect.ValueOf(&value).Elem()
+	for _, f := range h.fields {
+		data, ok := stored[f.name]
+		if !ok {
+			continue
+		}
+		if err := h.decodeField(data, v.FieldByIndex(f.index)); err != nil {
+			var zero T
+			return zero, fmt.Errorf("failed to unmarshal field %s: %w", f.name, err)
+		}
+	}
+	return value, nil
+}
+
+// SetFields stores only the named fields of value at key, leaving the others and the TTL as
+// they are. On a missing key it creates an object holding just those fields, without a TTL
+func (h *HashCache[T]) SetFields(ctx context.Context, key string, value T, fields ...string) error {
+	if skip, err := h.check("hash_set_fields"); skip || err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 0, 2*len(fields))
+	v := reflect.ValueOf(&value).Elem()
+	for _, name := range fields {
+		f, err := h.field(name)
+		if err != nil {
+			return err
+		}
+		data, err := h.encodeField(v.FieldByIndex(f.index))
+		if err != nil {
+			return fmt.Errorf("failed to marshal field %s: %w", f.name, err)
+		}
+		args = append(args, f.name, data)
+	}
+
+	fullKey := h.cache.buildKey(ctx, key)
+	start := time.Now()
+	err := h.cache.do(ctx, func(ctx context.Context) error {
+		return h.cache.client.HSet(ctx, fullKey, args...).Err()
+	})
+	h.cache.observe("hash_set_fields", metrics.Outcome(err), start)
+	if err != nil {
+		return fmt.Errorf("failed to set cache fields: %w", err)
+	}
+	return nil
+}
+
+// GetFields returns an object holding only the named fields stored at key, the others left
+// zero, or an error wrapping ErrNotFound when none of them is stored
+func (h *HashCache[T]) GetFields(ctx context.Context, key string, fields ...string) (T, error) {
+	var value T
+	if skip, err := h.check("hash_get_fields"); err != nil {
+		return value, err
+	} else if skip {
+		return value, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	selected := make([]hashField, len(fields))
+	for i, name := range fields {
+		f, err := h.field(name)
+		if err != nil {
+			return value, err
+		}
+		selected[i] = f
+	}
+	if len(selected) == 0 {
+		return value, nil
+	}
+
+	fullKey := h.cache.buildKey(ctx, key)
+	start := time.Now()
+	var stored []interface{}
+	err := h.cache.read(ctx, func(ctx context.Context, client redis.Cmdable) error {
+		var getErr error
+		stored, getErr = client.HMGet(ctx, fullKey, fields...).Result()
+		return getErr
+	})
+	if err == nil && !slices.ContainsFunc(stored, func(v interface{}) bool { return v != nil }) {
+		err = redis.Nil
+	}
+	h.observeRead("hash_get_fields", err, start)
+	if err == redis.Nil {
+		return value, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return value, fmt.Errorf("failed to get cache fields: %w", err)
+	}
+
+	v := reflect.ValueOf(&value).Elem()
+	for i, f := range selected {
+		data, ok := stored[i].(string)
+		if !ok {
+			continue
+		}
+		if err := h.decodeField(data, v.FieldByIndex(f.index)); err != nil {
+			var zero T
+			return zero, fmt.Errorf("failed to unmarshal field %s: %w", f.name, err)
+		}
+	}
+	return value, nil
+}
+
+// IncrField adds delta to the integer field of the object at key with HINCRBY and returns its
+// new value. On a missing key it creates an object holding just that field, without a TTL;
+// while Redis is degraded the field is left unchanged and read as 0
+func (h *HashCache[T]) IncrField(ctx context.Context, key, field string, delta int64) (int64, error) {
+	f, err := h.numericField("hash_incr", field, reflect.Int, reflect.Uint)
+	if f == nil || err != nil {
+		return 0, err
+	}
+	var n int64
+	err = h.incr(ctx, "hash_incr", key, func(ctx context.Context, fullKey string) error {
+		var incrErr error
+		n, incrErr = h.cache.client.HIncrBy(ctx, fullKey, f.name, delta).Result()
+		return incrErr
+	})
+	return n, err
+}
+
+// IncrFieldFloat adds delta to the float field of the object at key with HINCRBYFLOAT and
+// returns its new value, as IncrField does for integer fields
+func (h *HashCache[T]) IncrFieldFloat(ctx context.Context, key, field string, delta float64) (float64, error) {
+	f, err := h.numericField("hash_incr_float", field, reflect.Float64)
+	if f == nil || err != nil {
+		return 0, err
+	}
+	var n float64
+	err = h.incr(ctx, "hash_incr_float", key, func(ctx context.Context, fullKey string) error {
+		var incrErr error
+		n, incrErr = h.cache.client.HIncrByFloat(ctx, fullKey, f.name, delta).Result()
+		return incrErr
+	})
+	return n, err
+}
+
+// check reports an error if the cache cannot run operation, or whether to skip Redis because
+// the client reports it as degraded
+func (h *HashCache[T]) check(operation string) (bool, error) {
+	if h.cache.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+	if h.err != nil {
+		return false, h.err
+	}
+	return h.cache.degraded(operation), nil
+}
+
+// field returns the stored field named name
+func (h *HashCache[T]) field(name string) (hashField, error) {
+	f, ok := h.byName[name]
+	if !ok {
+		return hashField{}, fmt.Errorf("hash cache field %q is not stored for %s", name, reflect.TypeFor[T]())
+	}
+	return f, nil
+}
+
+// numericField returns the field named name if its kind is in the family of one of kinds, or
+// nil when Redis is degraded
+func (h *HashCache[T]) numericField(operation, name string, kinds ...reflect.Kind) (*hashField, error) {
+	if skip, err := h.check(operation); skip || err != nil {
+		return nil, err
+	}
+	f, err := h.field(name)
+	if err != nil {
+		return nil, err
+	}
+	family := kindFamily(f.typ.Kind())
+	for _, kind := range kinds {
+		if family == kind {
+			return &f, nil
+		}
+	}
+	return nil, fmt.Errorf("hash cache field %q is a %s, which cannot be incremented this way", name, f.typ)
+}
+
+// incr runs an increment command on key, reported as operation
+func (h *HashCache[T]) incr(ctx context.Context, operation, key string, fn func(ctx context.Context, fullKey string) error) error {
+	fullKey := h.cache.buildKey(ctx, key)
+	start := time.Now()
+	err := h.cache.do(ctx, func(ctx context.Context) error {
+		return fn(ctx, fullKey)
+	})
+	h.cache.observe(operation, metrics.Outcome(err), start)
+	if err != nil {
+		return fmt.Errorf("failed to update field: %w", err)
+	}
+	return nil
+}
+
+// observeRead reports a read, a hit when err is nil
+func (h *HashCache[T]) observeRead(operation string, err error, start time.Time) {
+	if err == nil {
+		h.cache.observe(operation, metrics.OutcomeHit, start)
+	} else {
+		h.cache.observe(operation, metrics.Outcome(err), start)
+	}
+}
+
+// encodeField returns the stored form of a field value
+func (h *HashCache[T]) encodeField(v reflect.Value) (interface{}, error) {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	}
+	switch kindFamily(v.Kind()) {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	case reflect.Int:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return h.cache.encode(v.Interface())
+}
+
+// decodeField sets a field value from its stored form
+func (h *HashCache[T]) decodeField(data string, v reflect.Value) error {
+	if v.Type() == timeType {
+		t, err := time.Parse(time.RFC3339Nano, data)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch kindFamily(v.Kind()) {
+	case reflect.String:
+		v.SetString(data)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(data)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int:
+		n, err := strconv.ParseInt(data, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+		return nil
+	case reflect.Uint:
+		n, err := strconv.ParseUint(data, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+		return nil
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(data, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+		return nil
+	}
+	return h.cache.decode([]byte(data), v.Addr().Interface())
+}
+
+// kindFamily groups sized kinds under Int, Uint, and Float64; other kinds are returned as is
+func kindFamily(kind reflect.Kind) reflect.Kind {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.Int
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return reflect.Uint
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	}
+	return kind
+}
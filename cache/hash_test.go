@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+type hashProfile struct {
+	Name     string    `redis:"name"`
+	Visits   int64     `redis:"visits"`
+	Score    float64   `redis:"score"`
+	Verified bool      `redis:"verified"`
+	Joined   time.Time `redis:"joined"`
+	Tags     []string  `redis:"tags"`
+	Plan     string
+	Secret   string `redis:"-"`
+	internal int
+}
+
+func TestHashCache(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	profiles := NewHashCache[hashProfile](client, "profile:")
+	joined := time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)
+	want := hashProfile{Name: "Alice", Visits: 3, Score: 1.5, Verified: true, Joined: joined,
+		Tags: []string{"admin"}, Plan: "pro", Secret: "s", internal: 1}
+	if err := profiles.Set(ctx, "1", want, time.Minute); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+
+	// Fields are stored in their text form under their tag names
+	stored, _ := client.HGetAll(ctx, "profile:1").Result()
+	if stored["name"] != "Alice" || stored["visits"] != "3" || stored["verified"] != "1" ||
+		stored["Plan"] != "pro" || stored["tags"] != `["admin"]` || len(stored) != 7 {
+		t.Errorf("stored hash = %v, want one field per stored struct field", stored)
+	}
+	if ttl, _ := profiles.Cache().TTL(ctx, "1"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("TTL() = %v, want up to a minute", ttl)
+	}
+
+	got, err := profiles.Get(ctx, "1")
+	if err != nil {
+		t.Fatalf("Get() error = %v, want nil", err)
+	}
+	if got.Name != "Alice" || got.Visits != 3 || got.Score != 1.5 || !got.Verified || !got.Joined.Equal(joined) ||
+		len(got.Tags) != 1 || got.Plan != "pro" || got.Secret != "" || got.internal != 0 {
+		t.Errorf("Get() = %+v, want the stored fields", got)
+	}
+
+	// Single fields are updated in place
+	if err := profiles.SetFields(ctx, "1", hashProfile{Name: "Alicia", Plan: "ignored"}, "name"); err != nil {
+		t.Fatalf("SetFields() error = %v, want nil", err)
+	}
+	if n, err := profiles.IncrField(ctx, "1", "visits", 2); err != nil || n != 5 {
+		t.Errorf("IncrField() = %d, %v, want 5", n, err)
+	}
+	if f, err := profiles.IncrFieldFloat(ctx, "1", "score", 0.25); err != nil || f != 1.75 {
+		t.Errorf("IncrFieldFloat() = %v, %v, want 1.75", f, err)
+	}
+	got, err = profiles.GetFields(ctx, "1", "name", "visits")
+	if err != nil || got.Name != "Alicia" || got.Visits != 5 || got.Plan != "" {
+		t.Errorf("GetFields() = %+v, %v, want only name and visits", got, err)
+	}
+	if ttl, _ := profiles.Cache().TTL(ctx, "1"); ttl <= 0 {
+		t.Errorf("TTL() after SetFields = %v, want it kept", ttl)
+	}
+
+	// Set replaces the whole object
+	if err := profiles.Set(ctx, "1", hashProfile{Name: "Bob"}, 0); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	if got, _ := profiles.Get(ctx, "1"); got.Visits != 0 || got.Plan != "" {
+		t.Errorf("Get() after replacing = %+v, want the new object only", got)
+	}
+	if ttl, _ := profiles.Cache().TTL(ctx, "1"); ttl != -1 {
+		t.Errorf("TTL() after Set without TTL = %v, want none", ttl)
+	}
+
+	if _, err := profiles.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a missing key error = %v, want ErrNotFound", err)
+	}
+	if _, err := profiles.GetFields(ctx, "missing", "name"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetFields() of a missing key error = %v, want ErrNotFound", err)
+	}
+	if err := profiles.Cache().Del(ctx, "1"); err != nil {
+		t.Fatalf("Del() error = %v, want nil", err)
+	}
+	if exists, _ := profiles.Cache().Exists(ctx, "1"); exists {
+		t.Error("Exists() after Del = true, want false")
+	}
+}
+
+func TestHashCache_Errors(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	profiles := NewHashCache[hashProfile](client, "profile:")
+	if err := profiles.SetFields(ctx, "1", hashProfile{}, "unknown"); err == nil {
+		t.Error("SetFields() of an unknown field error = nil, want error")
+	}
+	if _, err := profiles.GetFields(ctx, "1", "Secret"); err == nil {
+		t.Error("GetFields() of a skipped field error = nil, want error")
+	}
+	if _, err := profiles.IncrField(ctx, "1", "name", 1); err == nil {
+		t.Error("IncrField() of a string field error = nil, want error")
+	}
+	if _, err := profiles.IncrFieldFloat(ctx, "1", "visits", 1); err == nil {
+		t.Error("IncrFieldFloat() of an integer field error = nil, want error")
+	}
+
+	_ = client.HSet(ctx, "profile:bad", "visits", "many").Err()
+	if _, err := profiles.Get(ctx, "bad"); err == nil {
+		t.Error("Get() of a malformed field error = nil, want error")
+	}
+
+	if err := NewHashCache[int](client, "n:").Set(ctx, "k", 1, 0); err == nil {
+		t.Error("Set() of a non-struct type error = nil, want error")
+	}
+	if _, err := NewHashCache[struct{ x int }](client, "n:").Get(ctx, "k"); err == nil {
+		t.Error("Get() of a struct without exported fields error = nil, want error")
+	}
+	if err := NewHashCache[hashProfile](nil, "n:").Set(ctx, "k", hashProfile{}, 0); err == nil {
+		t.Error("Set() with nil client error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if err := profiles.Set(ctx, "1", hashProfile{}, 0); err == nil {
+		t.Error("Set() with failing Redis error = nil, want error")
+	}
+	if _, err := profiles.Get(ctx, "1"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() with failing Redis error = %v, want a Redis error", err)
+	}
+	if _, err := profiles.IncrField(ctx, "1", "visits", 1); err == nil {
+		t.Error("IncrField() with failing Redis error = nil, want error")
+	}
+}
+
+func TestHashCache_Degraded(t *testing.T) {
+	mockClient, _ := testutil.NewMockRedisClient()
+	defer func() { _ = mockClient.Close() }()
+	client := &degradedClient{UniversalClient: mockClient, degraded: true}
+	ctx := context.Background()
+
+	profiles := NewHashCache[hashProfile](client, "profile:")
+	if err := profiles.Set(ctx, "1", hashProfile{Name: "Alice"}, 0); err != nil {
+		t.Errorf("Set() while degraded error = %v, want nil", err)
+	}
+	if _, err := profiles.Get(ctx, "1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() while degraded error = %v, want ErrNotFound", err)
+	}
+	if n, err := profiles.IncrField(ctx, "1", "visits", 1); n != 0 || err != nil {
+		t.Errorf("IncrField() while degraded = %d, %v, want 0", n, err)
+	}
+	if exists, _ := mockClient.Exists(ctx, "profile:1").Result(); exists != 0 {
+		t.Error("writes while degraded reached Redis")
+	}
+}
@@ -0,0 +1,332 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHTTPMaxBody is the largest response body, in bytes, HTTPMiddleware caches by default
+const DefaultHTTPMaxBody = 1 << 20
+
+// HTTPCacheHeader is the response header HTTPMiddleware sets to HIT, STALE, or MISS
+const HTTPCacheHeader = "X-Cache"
+
+// httpEntry is a cached HTTP response
+type httpEntry struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// Stored is when the response was cached, in Unix milliseconds
+	Stored int64
+}
+
+// httpSettings holds the configuration of HTTPMiddleware
+type httpSettings struct {
+	vary    []string
+	stale   time.Duration
+	maxBody int
+	onError func(r *http.Request, err error)
+}
+
+// HTTPOption configures HTTPMiddleware
+type HTTPOption func(*httpSettings)
+
+// WithHTTPVary caches a response per value of the given request headers, e.g. Accept-Encoding
+// or Accept-Language, on top of the key of keyFn
+func WithHTTPVary(headers ...string) HTTPOption {
+	return func(s *httpSettings) {
+		for _, header := range headers {
+			s.vary = append(s.vary, http.CanonicalHeaderKey(header))
+		}
+	}
+}
+
+// WithHTTPStale keeps serving a response for d after it expired while it is refreshed in the
+// background, so a popular page going stale does not make its readers wait for the handler.
+// If the refresh is not cacheable, e.g. a 500, the stale response goes on being served until
+// d is over (default: 0, expired responses are not served)
+func WithHTTPStale(d time.Duration) HTTPOption {
+	return func(s *httpSettings) {
+		if d > 0 {
+			s.stale = d
+		}
+	}
+}
+
+// WithHTTPMaxBody sets the largest response body cached; larger responses are still sent but
+// not cached (default: DefaultHTTPMaxBody)
+func WithHTTPMaxBody(n int) HTTPOption {
+	return func(s *httpSettings) {
+		if n > 0 {
+			s.maxBody = n
+		}
+	}
+}
+
+// WithHTTPOnError sets a callback for cache reads and writes that fail; the request is served
+// by the handler either way (optional)
+func WithHTTPOnError(fn func(r *http.Request, err error)) HTTPOption {
+	return func(s *httpSettings) {
+		s.onError = fn
+	}
+}
+
+// DefaultHTTPKey keys a response by the request host and URI, query included
+func DefaultHTTPKey(r *http.Request) string {
+	return r.Host + r.URL.RequestURI()
+}
+
+// httpCache is the state of one HTTPMiddleware
+type httpCache struct {
+	cache      *Typed[httpEntry]
+	keyFn      func(r *http.Request) string
+	ttl        time.Duration
+	settings   httpSettings
+	refreshing sync.Map
+}
+
+// HTTPMiddleware caches whole responses to GET requests, status, headers, and body, in c for
+// ttl, keyed by keyFn (default: DefaultHTTPKey); keyFn returning "" skips the cache for that
+// request, and it must cover whatever else a response depends on, e.g. the user. Other
+// methods always reach the handler. A request with Cache-Control no-cache or max-age=0
+// skips the lookup but refreshes the entry, and one with no-store bypasses the cache. Only
+// responses with a status cacheable by default per RFC 9110, e.g. 200 or 404, are stored,
+// and not when they set a cookie, have Cache-Control no-store, no-cache, or private, or Vary
+// by every header. Cached responses carry an Age header, and every response an X-Cache one
+func HTTPMiddleware(c Cache, keyFn func(r *http.Request) string, ttl time.Duration, opts ...HTTPOption) func(http.Handler) http.Handler {
+	m := &httpCache{
+		cache:    TypedOf[httpEntry](c),
+		keyFn:    keyFn,
+		ttl:      ttl,
+		settings: httpSettings{maxBody: DefaultHTTPMaxBody},
+	}
+	if m.keyFn == nil {
+		m.keyFn = DefaultHTTPKey
+	}
+	for _, opt := range opts {
+		opt(&m.settings)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.serve(next, w, r)
+		})
+	}
+}
+
+// serve answers r from the cache, or with next, caching its response
+func (m *httpCache) serve(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		next.ServeHTTP(w, r)
+		return
+	}
+	key := m.key(r)
+	directives := cacheDirectives(r.Header)
+	if _, noStore := directives["no-store"]; key == "" || noStore {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if _, noCache := directives["no-cache"]; !noCache && directives["max-age"] != "0" {
+		entry, err := m.cache.Get(r.Context(), key)
+		switch {
+		case err == nil:
+			age := time.Since(time.UnixMilli(entry.Stored))
+			if m.ttl <= 0 || age < m.ttl {
+				m.write(w, entry, "HIT", age)
+				return
+			}
+			if age < m.ttl+m.settings.stale {
+				m.write(w, entry, "STALE", age)
+				m.refresh(next, r, key)
+				return
+			}
+		case !errors.Is(err, ErrNotFound):
+			m.report(r, err)
+		}
+	}
+
+	cw := &captureWriter{w: w, max: m.settings.maxBody}
+	w.Header().Set(HTTPCacheHeader, "MISS")
+	next.ServeHTTP(cw, r)
+	m.store(r, key, cw)
+}
+
+// key returns the cache key of r, with a hash of its vary headers
+func (m *httpCache) key(r *http.Request) string {
+	key := m.keyFn(r)
+	if key == "" || len(m.settings.vary) == 0 {
+		return key
+	}
+	h := sha256.New()
+	for _, name := range m.settings.vary {
+		h.Write([]byte(strings.Join(r.Header.Values(name), ",")))
+		h.Write([]byte{0})
+	}
+	return key + "#" + hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// write sends a cached response
+func (m *httpCache) write(w http.ResponseWriter, entry httpEntry, outcome string, age time.Duration) {
+	header := w.Header()
+	for name, values := range entry.Header {
+		header[name] = slices.Clone(values)
+	}
+	header.Set("Age", strconv.Itoa(int(age.Seconds())))
+	header.Set(HTTPCacheHeader, outcome)
+	w.WriteHeader(entry.Status)
+	if len(entry.Body) > 0 {
+		_, _ = w.Write(entry.Body)
+	}
+}
+
+// refresh runs next for r in the background and caches its response, unless a refresh of key
+// is already running
+func (m *httpCache) refresh(next http.Handler, r *http.Request, key string) {
+	if _, running := m.refreshing.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+	r = r.Clone(context.WithoutCancel(r.Context()))
+	go func() {
+		defer m.refreshing.Delete(key)
+		cw := &captureWriter{max: m.settings.maxBody}
+		next.ServeHTTP(cw, r)
+		m.store(r, key, cw)
+	}()
+}
+
+// store caches the response captured by cw if it is cacheable
+func (m *httpCache) store(r *http.Request, key string, cw *captureWriter) {
+	if cw.status == 0 {
+		cw.capture(http.StatusOK)
+	}
+	if cw.overflow || !cacheableStatus(cw.status) || !cacheableHeader(cw.header) {
+		return
+	}
+	cw.header.Del(HTTPCacheHeader)
+	cw.header.Del("Age")
+	entry := httpEntry{Status: cw.status, Header: cw.header, Body: cw.body.Bytes(), Stored: time.Now().UnixMilli()}
+	ttl := m.ttl
+	if ttl > 0 {
+		ttl += m.settings.stale
+	}
+	if err := m.cache.Set(context.WithoutCancel(r.Context()), key, entry, ttl); err != nil {
+		m.report(r, err)
+	}
+}
+
+// report passes err to the error callback, if any
+func (m *httpCache) report(r *http.Request, err error) {
+	if m.settings.onError != nil {
+		m.settings.onError(r, err)
+	}
+}
+
+// cacheableStatus reports whether responses with status are cacheable by default per RFC 9110
+func cacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent, http.StatusMultipleChoices,
+		http.StatusMovedPermanently, http.StatusPermanentRedirect, http.StatusNotFound, http.StatusMethodNotAllowed,
+		http.StatusGone, http.StatusRequestURITooLong, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// cacheableHeader reports whether a response with header may be stored in a shared cache
+func cacheableHeader(header http.Header) bool {
+	directives := cacheDirectives(header)
+	for _, name := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[name]; ok {
+			return false
+		}
+	}
+	return header.Get("Set-Cookie") == "" && !slices.Contains(header.Values("Vary"), "*")
+}
+
+// cacheDirectives parses the Cache-Control directives of header, mapping each name, in lower
+// case, to its value
+func cacheDirectives(header http.Header) map[string]string {
+	directives := map[string]string{}
+	for _, value := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+	return directives
+}
+
+// captureWriter records the response a handler writes, up to max bytes of body, passing it on
+// to w unless w is nil, as when refreshing in the background
+type captureWriter struct {
+	w        http.ResponseWriter
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	max      int
+	overflow bool
+}
+
+// capture records status and a copy of the headers as they are sent
+func (cw *captureWriter) capture(status int) {
+	cw.status = status
+	cw.header = cw.Header().Clone()
+	if cw.header == nil {
+		cw.header = http.Header{}
+	}
+}
+
+func (cw *captureWriter) Header() http.Header {
+	if cw.w != nil {
+		return cw.w.Header()
+	}
+	if cw.header == nil {
+		cw.header = http.Header{}
+	}
+	return cw.header
+}
+
+func (cw *captureWriter) WriteHeader(code int) {
+	// 1xx responses are informational and may precede the final one
+	if cw.status == 0 && code >= http.StatusOK {
+		cw.capture(code)
+	}
+	if cw.w != nil {
+		cw.w.WriteHeader(code)
+	}
+}
+
+func (cw *captureWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.overflow {
+		if cw.body.Len()+len(p) > cw.max {
+			cw.overflow = true
+			cw.body = bytes.Buffer{}
+		} else {
+			cw.body.Write(p)
+		}
+	}
+	if cw.w != nil {
+		return cw.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (cw *captureWriter) Unwrap() http.ResponseWriter {
+	return cw.w
+}
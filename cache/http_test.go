@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	var calls atomic.Int32
+	handler := HTTPMiddleware(NewCache(client, "http:"), nil, time.Hour, WithHTTPVary("Accept-Language"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := calls.Add(1)
+			switch r.URL.Path {
+			case "/private":
+				w.Header().Set("Cache-Control", "private")
+			case "/cookie":
+				http.SetCookie(w, &http.Cookie{Name: "id", Value: "1"})
+			case "/error":
+				w.WriteHeader(http.StatusInternalServerError)
+			case "/missing":
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = fmt.Fprintf(w, "%s %s #%d", r.URL.Path, r.Header.Get("Accept-Language"), n)
+		}))
+	serve := func(method, target string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := serve(http.MethodGet, "/page?q=1")
+	if first.Header().Get(HTTPCacheHeader) != "MISS" || first.Body.String() != "/page  #1" {
+		t.Fatalf("first response = %s %q, want a MISS from the handler", first.Header().Get(HTTPCacheHeader), first.Body.String())
+	}
+	second := serve(http.MethodGet, "/page?q=1")
+	if second.Header().Get(HTTPCacheHeader) != "HIT" || second.Body.String() != "/page  #1" ||
+		second.Header().Get("Content-Type") != "text/plain" || second.Header().Get("Age") == "" {
+		t.Errorf("second response = %v %q, want a HIT with the cached headers and body", second.Header(), second.Body.String())
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		header []string
+		want   string
+	}{
+		{"another query", http.MethodGet, "/page?q=2", nil, "MISS"},
+		{"another vary header", http.MethodGet, "/page?q=1", []string{"Accept-Language", "fr"}, "MISS"},
+		{"cached vary header", http.MethodGet, "/page?q=1", []string{"Accept-Language", "fr"}, "HIT"},
+		{"request no-cache", http.MethodGet, "/page?q=1", []string{"Cache-Control", "no-cache"}, "MISS"},
+		{"refreshed by no-cache", http.MethodGet, "/page?q=1", nil, "HIT"},
+		{"request no-store", http.MethodGet, "/page?q=1", []string{"Cache-Control", "no-store"}, ""},
+		{"POST", http.MethodPost, "/post", nil, ""},
+		{"POST again", http.MethodPost, "/post", nil, ""},
+		{"private", http.MethodGet, "/private", nil, "MISS"},
+		{"private again", http.MethodGet, "/private", nil, "MISS"},
+		{"cookie", http.MethodGet, "/cookie", nil, "MISS"},
+		{"cookie again", http.MethodGet, "/cookie", nil, "MISS"},
+		{"error", http.MethodGet, "/error", nil, "MISS"},
+		{"error again", http.MethodGet, "/error", nil, "MISS"},
+		{"not found", http.MethodGet, "/missing", nil, "MISS"},
+		{"not found again", http.MethodGet, "/missing", nil, "HIT"},
+	}
+	for _, tt := range tests {
+		if got := serve(tt.method, tt.target, tt.header...).Header().Get(HTTPCacheHeader); got != tt.want {
+			t.Errorf("%s: %s = %q, want %q", tt.name, HTTPCacheHeader, got, tt.want)
+		}
+	}
+	if rec := serve(http.MethodGet, "/page?q=1"); rec.Body.String() != "/page  #4" {
+		t.Errorf("response after no-cache = %q, want the refreshed one", rec.Body.String())
+	}
+	if rec := serve(http.MethodGet, "/missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("cached status = %d, want 404", rec.Code)
+	}
+}
+
+func TestHTTPMiddleware_Stale(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	var calls atomic.Int32
+	handler := HTTPMiddleware(NewCache(client, "http-stale:"), nil, 20*time.Millisecond, WithHTTPStale(time.Hour))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "#%d", calls.Add(1))
+		}))
+	serve := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+
+	serve()
+	time.Sleep(30 * time.Millisecond)
+	if rec := serve(); rec.Header().Get(HTTPCacheHeader) != "STALE" || rec.Body.String() != "#1" {
+		t.Fatalf("expired response = %s %q, want the stale #1", rec.Header().Get(HTTPCacheHeader), rec.Body.String())
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		rec := serve()
+		if rec.Header().Get(HTTPCacheHeader) == "HIT" && rec.Body.String() == "#2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("response after the refresh = %s %q, want a HIT of #2", rec.Header().Get(HTTPCacheHeader), rec.Body.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want once more in the background", n)
+	}
+}
+
+func TestHTTPMiddleware_Errors(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	var errs atomic.Int32
+	keyFn := func(r *http.Request) string {
+		if r.URL.Query().Has("nocache") {
+			return ""
+		}
+		return r.URL.Path
+	}
+	handler := HTTPMiddleware(NewCache(client, "http-err:"), keyFn, time.Hour,
+		WithHTTPMaxBody(4),
+		WithHTTPOnError(func(r *http.Request, err error) { errs.Add(1) }))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(strings.TrimPrefix(r.URL.Path, "/")))
+		}))
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// Bodies over the limit are sent in full but not cached
+	serve("/too-long")
+	if rec := serve("/too-long"); rec.Header().Get(HTTPCacheHeader) != "MISS" || rec.Body.String() != "too-long" {
+		t.Errorf("oversized response = %s %q, want an uncached MISS", rec.Header().Get(HTTPCacheHeader), rec.Body.String())
+	}
+	serve("/ok?nocache")
+	if rec := serve("/ok?nocache"); rec.Header().Get(HTTPCacheHeader) != "" {
+		t.Errorf("%s with an empty key = %q, want none", HTTPCacheHeader, rec.Header().Get(HTTPCacheHeader))
+	}
+
+	// A failing Redis is reported and the handler serves the request
+	mock.SetShouldFail(true)
+	if rec := serve("/ok"); rec.Body.String() != "ok" {
+		t.Errorf("response with failing Redis = %q, want the handler's", rec.Body.String())
+	}
+	mock.SetShouldFail(false)
+	if n := errs.Load(); n != 2 {
+		t.Errorf("errors reported = %d, want the failed read and write", n)
+	}
+}
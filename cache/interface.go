@@ -3,17 +3,39 @@ package cache
 import (
 	"context"
 	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
 )
 
+// Codec encodes cached values; codec.JSON, codec.Msgpack, and codec.Gob are provided
+type Codec = codec.Codec
+
 // Cache provides a generic caching interface
 type Cache interface {
-	// Set stores a value in the cache with the given TTL
+	// Set stores a value in the cache with the given TTL; []byte and string values are stored
+	// as is rather than encoded with the codec
 	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
 
 	// Get retrieves a value from the cache
 	// The dest parameter should be a pointer to the type you want to unmarshal into
 	Get(ctx context.Context, key string, dest interface{}) error
 
+	// SetBytes stores already serialized data as is, bypassing the codec
+	SetBytes(ctx context.Context, key string, data []byte, ttl time.Duration) error
+
+	// GetBytes returns the bytes stored at key as is, bypassing the codec
+	GetBytes(ctx context.Context, key string) ([]byte, error)
+
+	// Add stores a value only if key does not exist and reports whether it was written
+	Add(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+
+	// SetNegative caches key as known to be missing, so Get fails with ErrNegativeHit
+	SetNegative(ctx context.Context, key string, ttl time.Duration) error
+
+	// SetTombstone marks key as deleted, so Get fails with ErrTombstoned and loaded values are
+	// not stored over it
+	SetTombstone(ctx context.Context, key string, ttl time.Duration) error
+
 	// Del deletes a key from the cache
 	Del(ctx context.Context, key string) error
 
@@ -25,4 +47,22 @@ type Cache interface {
 
 	// Expire sets the expiration time for a key
 	Expire(ctx context.Context, key string, ttl time.Duration) error
+
+	// Persist removes the TTL of a key
+	Persist(ctx context.Context, key string) error
+
+	// Touch sets the TTL of every key in one round trip and returns the number that exist
+	Touch(ctx context.Context, ttl time.Duration, keys ...string) (int64, error)
+
+	// Incr increments the counter at key and returns its new value
+	Incr(ctx context.Context, key string) (int64, error)
+
+	// IncrBy adds delta to the counter at key and returns its new value
+	IncrBy(ctx context.Context, key string, delta int64) (int64, error)
+
+	// Decr decrements the counter at key and returns its new value
+	Decr(ctx context.Context, key string) (int64, error)
+
+	// IncrWithTTL adds delta to the counter at key, expiring it after ttl when it has no TTL yet
+	IncrWithTTL(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
 }
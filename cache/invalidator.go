@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// Invalidation tells the processes keeping local copies of cached values which to drop
+type Invalidation struct {
+	// Keys are the keys to drop
+	Keys []string `json:"keys,omitempty"`
+	// Prefixes drop every key starting with one of them
+	Prefixes []string `json:"prefixes,omitempty"`
+	// All drops every key; Watch also sends it on each (re)subscription, as messages published
+	// while disconnected are lost
+	All bool `json:"all,omitempty"`
+}
+
+// Matches reports whether inv drops key
+func (inv Invalidation) Matches(key string) bool {
+	if inv.All || slices.Contains(inv.Keys, key) {
+		return true
+	}
+	for _, prefix := range inv.Prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidatorSettings holds the configuration of an Invalidator
+type invalidatorSettings struct {
+	onError func(error)
+}
+
+// InvalidatorOption configures an Invalidator
+type InvalidatorOption func(*invalidatorSettings)
+
+// WithInvalidatorOnError sets a callback for errors in Watch, which keeps running after them,
+// such as a lost subscription or a message that cannot be decoded
+func WithInvalidatorOnError(fn func(error)) InvalidatorOption {
+	return func(s *invalidatorSettings) {
+		s.onError = fn
+	}
+}
+
+// Invalidator broadcasts invalidations over a Redis pub/sub channel, so every instance of an
+// app keeping local copies of cached values, e.g. in memory in front of a cache, drops them
+// when any instance changes the source of truth. Delivery is at most once: an instance that
+// is not subscribed when a message is published misses it, which Watch makes up for by
+// dropping everything when it (re)subscribes
+type Invalidator struct {
+	client   redis.UniversalClient
+	channel  string
+	settings invalidatorSettings
+
+	mu          sync.Mutex
+	subscribers []invalidationSubscriber
+	nextID      int
+}
+
+// invalidationSubscriber is a callback registered with Invalidator.Subscribe
+type invalidationSubscriber struct {
+	id int
+	fn func(Invalidation)
+}
+
+// NewInvalidator creates an invalidator publishing and listening on channel
+func NewInvalidator(client redis.UniversalClient, channel string, opts ...InvalidatorOption) *Invalidator {
+	inv := &Invalidator{client: client, channel: channel}
+	for _, opt := range opts {
+		opt(&inv.settings)
+	}
+	return inv
+}
+
+// Channel returns the pub/sub channel of the invalidator
+func (i *Invalidator) Channel() string {
+	return i.channel
+}
+
+// Invalidate tells every watching instance to drop keys
+func (i *Invalidator) Invalidate(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return i.Publish(ctx, Invalidation{Keys: keys})
+}
+
+// InvalidatePrefix tells every watching instance to drop the keys starting with one of prefixes
+func (i *Invalidator) InvalidatePrefix(ctx context.Context, prefixes ...string) error {
+	if len(prefixes) == 0 {
+		return nil
+	}
+	return i.Publish(ctx, Invalidation{Prefixes: prefixes})
+}
+
+// InvalidateAll tells every watching instance to drop every key
+func (i *Invalidator) InvalidateAll(ctx context.Context) error {
+	return i.Publish(ctx, Invalidation{All: true})
+}
+
+// Publish broadcasts inv to every watching instance, this one included
+func (i *Invalidator) Publish(ctx context.Context, inv Invalidation) error {
+	if i.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	payload, err := json.Marshal(inv)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invalidation: %w", err)
+	}
+	if err := i.client.Publish(ctx, i.channel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish invalidation: %w", err)
+	}
+	return nil
+}
+
+// Subscribe registers fn to be called with every invalidation Watch receives and returns a
+// function removing it. Callbacks run one at a time on the Watch goroutine, in the order
+// they were registered
+func (i *Invalidator) Subscribe(fn func(Invalidation)) (unsubscribe func()) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	id := i.nextID
+	i.nextID++
+	i.subscribers = append(i.subscribers, invalidationSubscriber{id: id, fn: fn})
+	return func() {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		i.subscribers = slices.DeleteFunc(slices.Clone(i.subscribers), func(s invalidationSubscriber) bool { return s.id == id })
+	}
+}
+
+// Watch delivers the invalidations published on the channel to the subscribers until ctx is
+// cancelled. Each time the subscription is (re)established the subscribers get an
+// Invalidation with All set, so changes made while disconnected are not missed. Watch
+// returns nil on cancellation
+func (i *Invalidator) Watch(ctx context.Context) error {
+	if i.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	return utils.WatchChannel(ctx, i.client, i.channel, utils.ChannelHandler{
+		OnSubscribe: func() { i.dispatch(Invalidation{All: true}) },
+		OnMessage: func(payload string) {
+			var inv Invalidation
+			if err := json.Unmarshal([]byte(payload), &inv); err != nil {
+				i.reportError(fmt.Errorf("failed to unmarshal invalidation: %w", err))
+				return
+			}
+			i.dispatch(inv)
+		},
+		OnError: func(err error) {
+			i.reportError(fmt.Errorf("invalidation subscription failed: %w", err))
+		},
+	})
+}
+
+// dispatch calls the subscribers with inv
+func (i *Invalidator) dispatch(inv Invalidation) {
+	i.mu.Lock()
+	subscribers := i.subscribers
+	i.mu.Unlock()
+
+	for _, s := range subscribers {
+		s.fn(inv)
+	}
+}
+
+// reportError passes err to the error callback, if any
+func (i *Invalidator) reportError(err error) {
+	if i.settings.onError != nil && err != nil && !errors.Is(err, context.Canceled) {
+		i.settings.onError(err)
+	}
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestInvalidator(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		mu       sync.Mutex
+		received []Invalidation
+		second   int
+	)
+	watcher := NewInvalidator(client, "cache:invalidate")
+	watcher.Subscribe(func(inv Invalidation) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, inv)
+	})
+	unsubscribe := watcher.Subscribe(func(Invalidation) {
+		mu.Lock()
+		defer mu.Unlock()
+		second++
+	})
+	waitFor := func(n int) []Invalidation {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			got := append([]Invalidation(nil), received...)
+			mu.Unlock()
+			if len(got) >= n {
+				return got
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("received %d invalidations, want %d", len(received), n)
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- watcher.Watch(ctx) }()
+
+	// Subscribing drops everything, as messages may have been missed
+	if got := waitFor(1); !got[0].All {
+		t.Errorf("first invalidation = %+v, want All", got[0])
+	}
+	unsubscribe()
+
+	publisher := NewInvalidator(client, "cache:invalidate")
+	_ = publisher.Invalidate(ctx, "user:1", "user:2")
+	_ = publisher.InvalidatePrefix(ctx, "product:")
+	_ = publisher.InvalidateAll(ctx)
+	want := []Invalidation{
+		{All: true},
+		{Keys: []string{"user:1", "user:2"}},
+		{Prefixes: []string{"product:"}},
+		{All: true},
+	}
+	if got := waitFor(4); !reflect.DeepEqual(got, want) {
+		t.Errorf("invalidations = %+v, want %+v", got, want)
+	}
+	mu.Lock()
+	if second != 1 {
+		t.Errorf("unsubscribed callback ran %d times, want 1", second)
+	}
+	mu.Unlock()
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch() error = %v, want nil on cancellation", err)
+	}
+}
+
+func TestInvalidation_Matches(t *testing.T) {
+	tests := []struct {
+		inv  Invalidation
+		key  string
+		want bool
+	}{
+		{Invalidation{Keys: []string{"a", "b"}}, "b", true},
+		{Invalidation{Keys: []string{"a", "b"}}, "ab", false},
+		{Invalidation{Prefixes: []string{"user:"}}, "user:1", true},
+		{Invalidation{Prefixes: []string{"user:"}}, "users", false},
+		{Invalidation{All: true}, "anything", true},
+		{Invalidation{}, "a", false},
+	}
+	for _, tt := range tests {
+		if got := tt.inv.Matches(tt.key); got != tt.want {
+			t.Errorf("%+v.Matches(%q) = %v, want %v", tt.inv, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidator_Errors(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var errs []error
+	watcher := NewInvalidator(client, "cache:invalidate-err", WithInvalidatorOnError(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}))
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	subscribed := make(chan struct{}, 1)
+	watcher.Subscribe(func(inv Invalidation) {
+		if inv.All {
+			subscribed <- struct{}{}
+		}
+	})
+	go func() { _ = watcher.Watch(watchCtx) }()
+	<-subscribed
+
+	// Malformed messages are reported and skipped
+	_ = client.Publish(ctx, watcher.Channel(), "not json").Err()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(errs)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("malformed message was not reported")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := watcher.Invalidate(ctx); err != nil {
+		t.Errorf("Invalidate() without keys error = %v, want nil", err)
+	}
+	mock.SetShouldFail(true)
+	if err := watcher.Invalidate(ctx, "a"); err == nil {
+		t.Error("Invalidate() with failing Redis error = nil, want error")
+	}
+	mock.SetShouldFail(false)
+
+	nilInv := NewInvalidator(nil, "x")
+	if err := nilInv.InvalidatePrefix(ctx, "a"); err == nil {
+		t.Error("InvalidatePrefix() with nil client error = nil, want error")
+	}
+	if err := nilInv.Watch(ctx); err == nil {
+		t.Error("Watch() with nil client error = nil, want error")
+	}
+}
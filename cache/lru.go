@@ -0,0 +1,272 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+)
+
+// DefaultLRUIndexKey is appended to the key prefix to name the recency index of an LRUCache
+const DefaultLRUIndexKey = "__lru_index"
+
+// lruTouch scores KEYS[1] in the index KEYS[2] with the server time in microseconds,
+// kept strictly increasing so recency stays ordered if the clock stalls or steps back;
+// scores are formatted explicitly, as Lua would print them with too few digits
+const lruTouch = `
+local t = redis.call("time")
+local now = t[1] * 1000000 + t[2]
+local last = redis.call("zrevrange", KEYS[2], 0, 0, "withscores")
+if last[2] and now <= tonumber(last[2]) then
+	now = tonumber(last[2]) + 1
+end
+`
+
+// lruWrite is how the LRU set script treats a key that already exists
+type lruWrite string
+
+const (
+	// lruSet replaces the key
+	lruSet lruWrite = "0"
+	// lruAdd keeps the key
+	lruAdd lruWrite = "1"
+	// lruFill replaces the key unless it holds a tombstone
+	lruFill lruWrite = "2"
+)
+
+const lruSetScript = `
+-- redis-kit:lru-set
+if ARGV[4] == "1" and redis.call("exists", KEYS[1]) == 1 then
+	return -1
+end
+if ARGV[4] == "2" and redis.call("type", KEYS[1]).ok == "string" and redis.call("get", KEYS[1]) == ARGV[5] then
+	return -1
+end
+` + lruTouch + `
+if tonumber(ARGV[2]) > 0 then
+	redis.call("set", KEYS[1], ARGV[1], "px", ARGV[2])
+else
+	redis.call("set", KEYS[1], ARGV[1])
+end
+redis.call("zadd", KEYS[2], string.format("%.0f", now), KEYS[1])
+local evicted = 0
+local excess = redis.call("zcard", KEYS[2]) - tonumber(ARGV[3])
+if excess > 0 then
+	for _, key in ipairs(redis.call("zrange", KEYS[2], 0, excess - 1)) do
+		evicted = evicted + redis.call("del", key)
+		redis.call("zrem", KEYS[2], key)
+	end
+end
+return evicted
+`
+
+const lruGetScript = `
+-- redis-kit:lru-get
+local value = redis.call("get", KEYS[1])
+if not value then
+	redis.call("zrem", KEYS[2], KEYS[1])
+	return false
+end
+` + lruTouch + `
+redis.call("zadd", KEYS[2], "xx", string.format("%.0f", now), KEYS[1])
+return value
+`
+
+// LRUCache is a RedisCache holding at most a fixed number of entries
+// It tracks when each entry was last written or read in a sorted set and, on Set, evicts
+// the least recently used entries beyond the cap. Use it when the Redis is shared and
+// instance-wide maxmemory eviction cannot be relied on
+//
+// Evicted keys are deleted from the Set script without being declared, so on Redis Cluster
+// the prefix must contain a {hash tag} that keeps the entries and the index in one slot
+type LRUCache struct {
+	*RedisCache
+	maxEntries int64
+	indexKey   string
+}
+
+// NewLRUCache creates a cache keeping at most maxEntries entries under keyPrefix
+// A maxEntries below 1 is treated as 1
+func NewLRUCache(client redis.UniversalClient, keyPrefix string, maxEntries int64, opts ...Option) *LRUCache {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	base := NewCache(client, keyPrefix, opts...)
+	base.lruIndex = base.Key(DefaultLRUIndexKey)
+	return &LRUCache{
+		RedisCache: base,
+		maxEntries: maxEntries,
+		indexKey:   base.lruIndex,
+	}
+}
+
+// MaxEntries returns the entry cap
+func (c *LRUCache) MaxEntries() int64 {
+	return c.maxEntries
+}
+
+// IndexKey returns the Redis key of the recency index
+func (c *LRUCache) IndexKey() string {
+	return c.indexKey
+}
+
+// Set stores a value, marks it most recently used, and evicts the least recently used
+// entries beyond the cap
+func (c *LRUCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("set") {
+		return nil
+	}
+
+	data, err := c.encode(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	return c.setRaw(ctx, "set", key, data, ttl)
+}
+
+// Add stores a value only if key does not exist, as RedisCache.Add does, and when written
+// marks it most recently used and evicts beyond the cap
+func (c *LRUCache) Add(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if c.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("add") {
+		return true, nil
+	}
+
+	data, err := c.encode(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	data, ok, sizeErr := c.fit("add", data)
+	if !ok {
+		return false, sizeErr
+	}
+	added, err := c.store(ctx, "add", key, data, ttl, lruAdd)
+	if err != nil {
+		return false, fmt.Errorf("failed to add to cache: %w", err)
+	}
+	if added {
+		c.stats.sets.Add(1)
+	}
+	return added, sizeErr
+}
+
+// setRaw stores encoded data, marks it most recently used, and evicts beyond the cap
+func (c *LRUCache) setRaw(ctx context.Context, operation, key string, data []byte, ttl time.Duration) error {
+	data, ok, sizeErr := c.fit(operation, data)
+	if !ok {
+		return sizeErr
+	}
+	if _, err := c.store(ctx, operation, key, data, ttl, lruSet); err != nil {
+		return fmt.Errorf("failed to set cache: %w", err)
+	}
+	return sizeErr
+}
+
+// store runs the LRU set script, treating an existing key as mode says, and reports whether
+// it wrote
+func (c *LRUCache) store(ctx context.Context, operation, key string, data []byte, ttl time.Duration, mode lruWrite) (bool, error) {
+	fullKey := c.buildKey(ctx, key)
+	args := []interface{}{data, c.jitter(ttl).Milliseconds(), c.maxEntries}
+	switch mode {
+	case lruAdd:
+		args = append(args, string(mode))
+	case lruFill:
+		args = append(args, string(mode), tombstoneValue)
+	}
+	start := time.Now()
+	var result int64
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = c.client.Eval(ctx, lruSetScript, []string{fullKey, c.indexKey}, args...).Int64()
+		return err
+	})
+	c.observe(operation, metrics.Outcome(err), start)
+	return err == nil && result >= 0, err
+}
+
+// Get retrieves a value and marks it most recently used
+func (c *LRUCache) Get(ctx context.Context, key string, dest interface{}) error {
+	data, err := c.getRaw(ctx, "get", key)
+	if err != nil {
+		return err
+	}
+	if err := c.decode(data, dest); err != nil {
+		return fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+	return nil
+}
+
+// getRaw returns the encoded value stored at key and marks it most recently used
+func (c *LRUCache) getRaw(ctx context.Context, operation, key string) ([]byte, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if c.degraded(operation) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	fullKey := c.buildKey(ctx, key)
+	start := time.Now()
+	var data string
+	err := c.do(ctx, func(ctx context.Context) error {
+		var getErr error
+		data, getErr = c.client.Eval(ctx, lruGetScript, []string{fullKey, c.indexKey}).Text()
+		return getErr
+	})
+	c.observe(operation, readOutcome([]byte(data), err), start)
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache: %w", err)
+	}
+	if err := markerErr(key, []byte(data)); err != nil {
+		return nil, err
+	}
+	payload, _ := splitCost([]byte(data))
+	return payload, nil
+}
+
+// Del deletes a key and its recency entry
+func (c *LRUCache) Del(ctx context.Context, key string) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("del") {
+		return nil
+	}
+
+	fullKey := c.buildKey(ctx, key)
+	start := time.Now()
+	err := c.do(ctx, func(ctx context.Context) error {
+		pipe := c.client.Pipeline()
+		pipe.Del(ctx, fullKey)
+		pipe.ZRem(ctx, c.indexKey, fullKey)
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+	c.observe("del", metrics.Outcome(err), start)
+	return err
+}
+
+// Len returns the number of tracked entries, which may include entries that expired
+// since they were last written or read
+func (c *LRUCache) Len(ctx context.Context) (int64, error) {
+	if c.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	n, err := c.client.ZCard(ctx, c.indexKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cache length: %w", err)
+	}
+	return n, nil
+}
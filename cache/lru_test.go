@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestNewLRUCache(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	c := NewLRUCache(client, "lru:", 0)
+	if c.MaxEntries() != 1 {
+		t.Errorf("NewLRUCache() MaxEntries = %d, want 1", c.MaxEntries())
+	}
+	if c.IndexKey() != "lru:__lru_index" {
+		t.Errorf("IndexKey() = %q, want lru:__lru_index", c.IndexKey())
+	}
+
+	var _ Cache = c
+}
+
+func TestLRUCache_Evicts(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	c := NewLRUCache(client, "lru:", 3)
+	for _, key := range []string{"a", "b", "c"} {
+		if err := c.Set(ctx, key, key+"-value", time.Minute); err != nil {
+			t.Fatalf("Set(%s) error = %v, want nil", key, err)
+		}
+	}
+
+	// Reading a makes b the least recently used
+	var got string
+	if err := c.Get(ctx, "a", &got); err != nil || got != "a-value" {
+		t.Fatalf("Get(a) = %q, %v, want a-value", got, err)
+	}
+	if err := c.Set(ctx, "d", "d-value", time.Minute); err != nil {
+		t.Fatalf("Set(d) error = %v, want nil", err)
+	}
+
+	if err := c.Get(ctx, "b", &got); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(b) error = %v, want ErrNotFound after eviction", err)
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if exists, _ := c.Exists(ctx, key); !exists {
+			t.Errorf("Exists(%s) = false, want true", key)
+		}
+	}
+	if n, err := c.Len(ctx); err != nil || n != 3 {
+		t.Errorf("Len() = %d, %v, want 3", n, err)
+	}
+
+	// Overwriting an entry does not grow the cache
+	_ = c.Set(ctx, "c", "c-new", time.Minute)
+	if n, _ := c.Len(ctx); n != 3 {
+		t.Errorf("Len() after overwrite = %d, want 3", n)
+	}
+	if ttl, _ := c.TTL(ctx, "c"); ttl <= 0 {
+		t.Errorf("TTL(c) = %v, want > 0", ttl)
+	}
+}
+
+func TestLRUCache_Del(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	c := NewLRUCache(client, "lru:", 2)
+	_ = c.Set(ctx, "a", 1, 0)
+	_ = c.Set(ctx, "b", 2, 0)
+
+	if err := c.Del(ctx, "a"); err != nil {
+		t.Fatalf("Del() error = %v, want nil", err)
+	}
+	if n, _ := c.Len(ctx); n != 1 {
+		t.Errorf("Len() after Del = %d, want 1", n)
+	}
+
+	// The freed slot is reused without evicting b
+	_ = c.Set(ctx, "c", 3, 0)
+	var got int
+	if err := c.Get(ctx, "b", &got); err != nil || got != 2 {
+		t.Errorf("Get(b) = %d, %v, want 2", got, err)
+	}
+	if ttl := client.PTTL(ctx, c.Key("b")).Val(); ttl >= 0 {
+		t.Errorf("PTTL(b) = %v, want no expiry", ttl)
+	}
+}
+
+func TestLRUCache_ExpiredEntries(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	c := NewLRUCache(client, "lru:", 2)
+	_ = c.Set(ctx, "short", "v", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	var got string
+	if err := c.Get(ctx, "short", &got); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() expired error = %v, want ErrNotFound", err)
+	}
+	if n, _ := c.Len(ctx); n != 0 {
+		t.Errorf("Len() after reading an expired entry = %d, want 0", n)
+	}
+}
+
+func TestLRUCache_Metrics(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	rec := testutil.NewRecorder()
+	ctx := context.Background()
+	c := NewLRUCache(client, "lru:", 2, WithRecorder(rec))
+	_ = c.Set(ctx, "a", 1, 0)
+	var got int
+	_ = c.Get(ctx, "a", &got)
+	_ = c.Get(ctx, "missing", &got)
+	_ = c.Del(ctx, "a")
+
+	if rec.Count(metrics.SubsystemCache, "set", metrics.OutcomeSuccess) != 1 ||
+		rec.Count(metrics.SubsystemCache, "get", metrics.OutcomeHit) != 1 ||
+		rec.Count(metrics.SubsystemCache, "get", metrics.OutcomeMiss) != 1 ||
+		rec.Count(metrics.SubsystemCache, "del", metrics.OutcomeSuccess) != 1 {
+		t.Errorf("recorded %d observations, want set/hit/miss/del", rec.Total())
+	}
+}
+
+func TestLRUCache_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	c := NewLRUCache(nil, "lru:", 2)
+	if err := c.Set(ctx, "a", 1, 0); err == nil {
+		t.Error("Set() with nil client error = nil, want error")
+	}
+	var got int
+	if err := c.Get(ctx, "a", &got); err == nil {
+		t.Error("Get() with nil client error = nil, want error")
+	}
+	if err := c.Del(ctx, "a"); err == nil {
+		t.Error("Del() with nil client error = nil, want error")
+	}
+	if _, err := c.Len(ctx); err == nil {
+		t.Error("Len() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	c = NewLRUCache(client, "lru:", 2)
+	if err := c.Set(ctx, "bad", make(chan int), 0); err == nil {
+		t.Error("Set() unmarshalable value error = nil, want error")
+	}
+	_ = c.Set(ctx, "s", "text", 0)
+	if err := c.Get(ctx, "s", &got); err == nil {
+		t.Error("Get() into wrong type error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if err := c.Set(ctx, "a", 1, 0); err == nil {
+		t.Error("Set() error = nil, want error")
+	}
+	if err := c.Get(ctx, "a", &got); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want a non-ErrNotFound error", err)
+	}
+	if err := c.Del(ctx, "a"); err == nil {
+		t.Error("Del() error = nil, want error")
+	}
+	if _, err := c.Len(ctx); err == nil {
+		t.Error("Len() error = nil, want error")
+	}
+}
+
+func TestLRUCache_DelPrefix(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewLRUCache(client, "lru:", 10)
+	for _, key := range []string{"a:1", "a:2", "b:1"} {
+		_ = c.Set(ctx, key, 1, 0)
+	}
+	if n, err := c.DelPrefix(ctx, "a:"); err != nil || n != 2 {
+		t.Fatalf("DelPrefix(a:) = %d, %v, want 2", n, err)
+	}
+	if n, _ := c.Len(ctx); n != 1 {
+		t.Errorf("Len() after DelPrefix = %d, want 1", n)
+	}
+
+	// The recency index is emptied, not counted as an entry
+	if n, err := c.Flush(ctx); err != nil || n != 1 {
+		t.Errorf("Flush() = %d, %v, want 1", n, err)
+	}
+	if n, _ := c.Len(ctx); n != 0 {
+		t.Errorf("Len() after Flush = %d, want 0", n)
+	}
+}
@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// MultiResult is the outcome of a multi-key read, key by key, so a caller can load only the
+// keys the cache could not serve
+type MultiResult[T any] struct {
+	// Hits maps the keys found to their values
+	Hits map[string]T
+	// Misses lists the keys not in the cache, in the order requested
+	Misses []string
+	// Errors maps the keys that could not be read or decoded to why, including ErrNegativeHit
+	// for keys cached as missing and ErrTombstoned for tombstoned keys
+	Errors map[string]error
+
+	keys []string
+}
+
+// Missing returns the keys to load from the source of truth, in the order requested: the
+// misses and the keys that failed, but not those cached as missing or tombstoned
+func (r MultiResult[T]) Missing() []string {
+	var missing []string
+	for _, key := range r.keys {
+		if _, ok := r.Hits[key]; ok {
+			continue
+		}
+		if err, ok := r.Errors[key]; ok && (errors.Is(err, ErrNegativeHit) || errors.Is(err, ErrTombstoned)) {
+			continue
+		}
+		missing = append(missing, key)
+	}
+	return missing
+}
+
+// add records the outcome of reading key
+func (r *MultiResult[T]) add(key string, value T, err error) {
+	switch {
+	case err == nil:
+		r.Hits[key] = value
+	case errors.Is(err, ErrNotFound) && !errors.Is(err, ErrNegativeHit) && !errors.Is(err, ErrTombstoned):
+		r.Misses = append(r.Misses, key)
+	default:
+		r.Errors[key] = err
+	}
+}
+
+// multiEntry is the encoded value of one key of a multi-key read, or why there is none
+type multiEntry struct {
+	data []byte
+	err  error
+}
+
+// multiGetter is a cache of this package that reads many keys in one round trip
+type multiGetter interface {
+	getMulti(ctx context.Context, keys []string) ([]multiEntry, error)
+	decode(data []byte, dest interface{}) error
+}
+
+// getMulti reads keys with one pipeline of GETs, which, unlike MGET, works across cluster
+// slots. It fails as a whole only when the round trip does; errors Redis replies for single
+// keys are reported in their entries. With a local tier, keys are read one by one through it
+func (c *RedisCache) getMulti(ctx context.Context, keys []string) ([]multiEntry, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	entries := make([]multiEntry, len(keys))
+	if c.local != nil {
+		for i, key := range keys {
+			entries[i].data, entries[i].err = c.getRaw(ctx, "get", key)
+		}
+		return entries, nil
+	}
+	if len(keys) == 0 || c.degradedMulti(keys, entries) {
+		return entries, nil
+	}
+
+	start := time.Now()
+	var cmds []*redis.StringCmd
+	err := c.read(ctx, func(ctx context.Context, client redis.Cmdable) error {
+		pipe := client.Pipeline()
+		cmds = make([]*redis.StringCmd, len(keys))
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, c.buildKey(ctx, key))
+		}
+		_, err := pipe.Exec(ctx)
+		return multiErr(err)
+	})
+	if err != nil {
+		c.observe("get", metrics.OutcomeError, start)
+		return nil, fmt.Errorf("failed to get cache: %w", err)
+	}
+	for i, cmd := range cmds {
+		data, err := cmd.Result()
+		entries[i] = c.multiEntry(keys[i], data, err, start)
+	}
+	return entries, nil
+}
+
+// getMulti reads keys with one pipeline of the LRU get script, marking each entry found
+// most recently used
+func (c *LRUCache) getMulti(ctx context.Context, keys []string) ([]multiEntry, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	entries := make([]multiEntry, len(keys))
+	if len(keys) == 0 || c.degradedMulti(keys, entries) {
+		return entries, nil
+	}
+
+	start := time.Now()
+	var cmds []*redis.Cmd
+	err := c.do(ctx, func(ctx context.Context) error {
+		pipe := c.client.Pipeline()
+		cmds = make([]*redis.Cmd, len(keys))
+		for i, key := range keys {
+			cmds[i] = pipe.Eval(ctx, lruGetScript, []string{c.buildKey(ctx, key), c.indexKey})
+		}
+		_, err := pipe.Exec(ctx)
+		return multiErr(err)
+	})
+	if err != nil {
+		c.observe("get", metrics.OutcomeError, start)
+		return nil, fmt.Errorf("failed to get cache: %w", err)
+	}
+	for i, cmd := range cmds {
+		data, err := cmd.Text()
+		entries[i] = c.multiEntry(keys[i], data, err, start)
+	}
+	return entries, nil
+}
+
+// degradedMulti fills entries with misses and reports true while the client reports Redis
+// as degraded
+func (c *RedisCache) degradedMulti(keys []string, entries []multiEntry) bool {
+	if !utils.Degraded(c.client) {
+		return false
+	}
+	for i, key := range keys {
+		c.observe("get", metrics.OutcomeDegraded, time.Now())
+		entries[i].err = fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return true
+}
+
+// multiEntry turns the reply for key into its entry, reporting it as a get
+func (c *RedisCache) multiEntry(key, data string, err error, start time.Time) multiEntry {
+	switch {
+	case errors.Is(err, redis.Nil):
+		c.observe("get", metrics.OutcomeMiss, start)
+		return multiEntry{err: fmt.Errorf("%w: %s", ErrNotFound, key)}
+	case err != nil:
+		c.observe("get", metrics.OutcomeError, start)
+		return multiEntry{err: fmt.Errorf("failed to get cache: %w", err)}
+	}
+	c.observe("get", readOutcome([]byte(data), nil), start)
+	if err := markerErr(key, []byte(data)); err != nil {
+		return multiEntry{err: err}
+	}
+	payload, _ := splitCost([]byte(data))
+	return multiEntry{data: payload}
+}
+
+// multiErr returns the error of a pipeline of reads unless it is one Redis replied for a
+// single key, such as redis.Nil or WRONGTYPE, which its command reports instead
+func multiErr(err error) error {
+	var replyErr redis.Error
+	if err == nil || errors.As(err, &replyErr) {
+		return nil
+	}
+	return err
+}
+
+// getMulti reads keys under the current version
+func (v *VersionedCache) getMulti(ctx context.Context, keys []string) ([]multiEntry, error) {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.getMulti(ctx, keys)
+}
+
+// decode decodes a value read from the cache
+func (v *VersionedCache) decode(data []byte, dest interface{}) error {
+	return v.base.decode(data, dest)
+}
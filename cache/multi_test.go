@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestTyped_GetMulti(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	for name, c := range map[string]Cache{
+		"redis":     NewCache(client, "multi:"),
+		"lru":       NewLRUCache(client, "{multi-lru}:", 10),
+		"versioned": NewVersionedCache(client, "multi-v:"),
+		"other":     struct{ Cache }{NewCache(client, "multi-o:")},
+	} {
+		counts := TypedOf[int](c)
+		_ = counts.Set(ctx, "a", 1, time.Minute)
+		_ = counts.Set(ctx, "b", 2, time.Minute)
+		_ = c.Set(ctx, "bad", "not a number", time.Minute)
+		_ = c.SetNegative(ctx, "gone", time.Minute)
+
+		result, err := counts.GetMulti(ctx, []string{"a", "missing", "bad", "b", "gone"})
+		if err != nil {
+			t.Fatalf("%s: GetMulti() error = %v, want nil", name, err)
+		}
+		if len(result.Hits) != 2 || result.Hits["a"] != 1 || result.Hits["b"] != 2 {
+			t.Errorf("%s: Hits = %v, want a and b", name, result.Hits)
+		}
+		if !slices.Equal(result.Misses, []string{"missing"}) {
+			t.Errorf("%s: Misses = %v, want [missing]", name, result.Misses)
+		}
+		if len(result.Errors) != 2 || result.Errors["bad"] == nil || !errors.Is(result.Errors["gone"], ErrNegativeHit) {
+			t.Errorf("%s: Errors = %v, want a decode error for bad and ErrNegativeHit for gone", name, result.Errors)
+		}
+		if got := result.Missing(); !slices.Equal(got, []string{"missing", "bad"}) {
+			t.Errorf("%s: Missing() = %v, want [missing bad]", name, got)
+		}
+
+		if result, err := counts.GetMulti(ctx, nil); err != nil || len(result.Hits) != 0 || result.Missing() != nil {
+			t.Errorf("%s: GetMulti() without keys = %+v, %v, want an empty result", name, result, err)
+		}
+	}
+
+	// Keys holding another type fail alone
+	counts := NewTyped[int](client, "multi:")
+	_ = client.HSet(ctx, "multi:hash", "f", "v").Err()
+	result, err := counts.GetMulti(ctx, []string{"a", "hash"})
+	if err != nil || result.Hits["a"] != 1 || result.Errors["hash"] == nil {
+		t.Errorf("GetMulti() with a hash key = %+v, %v, want a hit and an error for the hash", result, err)
+	}
+
+	// Per-key errors of a failing Redis leave every key to load
+	mock.SetShouldFail(true)
+	result, err = counts.GetMulti(ctx, []string{"a", "b"})
+	if err == nil && !slices.Equal(result.Missing(), []string{"a", "b"}) {
+		t.Errorf("GetMulti() with failing Redis Missing() = %v, want [a b]", result.Missing())
+	}
+	mock.SetShouldFail(false)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := counts.GetMulti(canceled, []string{"a"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetMulti() with done context error = %v, want context.Canceled", err)
+	}
+	if _, err := TypedOf[int](nil).GetMulti(ctx, []string{"a"}); err == nil {
+		t.Error("GetMulti() with nil cache error = nil, want error")
+	}
+}
+
+func TestTyped_GetMultiStats(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewCache(client, "multi-stats:")
+	_ = c.Set(ctx, "a", "1", 0)
+	if _, err := TypedOf[string](c).GetMulti(ctx, []string{"a", "b", "c"}); err != nil {
+		t.Fatalf("GetMulti() error = %v, want nil", err)
+	}
+	if st := c.Stats(); st.Hits != 1 || st.Misses != 2 {
+		t.Errorf("Stats() after GetMulti = %+v, want one hit and two misses", st)
+	}
+
+	// The LRU index marks the entries read
+	lru := NewLRUCache(client, "{multi-stats-lru}:", 10)
+	_ = lru.Set(ctx, "old", "1", 0)
+	_ = lru.Set(ctx, "new", "2", 0)
+	if _, err := TypedOf[string](lru).GetMulti(ctx, []string{"old"}); err != nil {
+		t.Fatalf("GetMulti() error = %v, want nil", err)
+	}
+	members, _ := client.ZRange(ctx, lru.IndexKey(), 0, -1).Result()
+	if len(members) != 2 || members[1] != lru.Key("old") {
+		t.Errorf("index after GetMulti = %v, want old most recently used", members)
+	}
+}
+
+func TestTyped_GetMultiDegraded(t *testing.T) {
+	mockClient, _ := testutil.NewMockRedisClient()
+	defer func() { _ = mockClient.Close() }()
+	client := &degradedClient{UniversalClient: mockClient}
+	ctx := context.Background()
+
+	c := NewCache(client, "multi-dg:")
+	_ = c.Set(ctx, "a", "1", 0)
+	client.degraded = true
+	result, err := TypedOf[string](c).GetMulti(ctx, []string{"a", "b"})
+	if err != nil || len(result.Hits) != 0 || !slices.Equal(result.Misses, []string{"a", "b"}) {
+		t.Errorf("GetMulti() while degraded = %+v, %v, want every key missing", result, err)
+	}
+}
+
+func TestTyped_GetOrSetMulti(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	counts := TypedOf[int](NewCache(client, "multi-load:", WithNegativeTTL(time.Minute)))
+	_ = counts.Set(ctx, "a", 1, time.Minute)
+	_ = counts.Cache().Set(ctx, "bad", "not a number", time.Minute)
+
+	var calls [][]string
+	loader := func(ctx context.Context, missing []string) (map[string]int, error) {
+		calls = append(calls, missing)
+		loaded := map[string]int{}
+		for _, key := range missing {
+			if key != "gone" {
+				loaded[key] = len(key)
+			}
+		}
+		return loaded, nil
+	}
+
+	got, err := counts.GetOrSetMulti(ctx, []string{"a", "bb", "bad", "bb", "gone"}, time.Minute, loader)
+	if err != nil {
+		t.Fatalf("GetOrSetMulti() error = %v, want nil", err)
+	}
+	want := map[string]int{"a": 1, "bb": 2, "bad": 3}
+	if !maps.Equal(got, want) {
+		t.Errorf("GetOrSetMulti() = %v, want %v", got, want)
+	}
+	if len(calls) != 1 || !slices.Equal(calls[0], []string{"bb", "bad", "gone"}) {
+		t.Errorf("loader calls = %v, want one with [bb bad gone]", calls)
+	}
+
+	// Loaded keys are now cached, and gone is cached as missing
+	got, err = counts.GetOrSetMulti(ctx, []string{"bb", "bad", "gone"}, time.Minute, loader)
+	if err != nil || !maps.Equal(got, map[string]int{"bb": 2, "bad": 3}) || len(calls) != 1 {
+		t.Errorf("GetOrSetMulti() of cached keys = %v, %v with %d loader calls, want no load", got, err, len(calls))
+	}
+
+	// Loader errors are returned with the hits
+	errLoad := errors.New("database down")
+	got, err = counts.GetOrSetMulti(ctx, []string{"a", "c"}, time.Minute, func(ctx context.Context, missing []string) (map[string]int, error) {
+		return nil, errLoad
+	})
+	if !errors.Is(err, errLoad) || !maps.Equal(got, map[string]int{"a": 1}) {
+		t.Errorf("GetOrSetMulti() with failing loader = %v, %v, want the hits and %v", got, err, errLoad)
+	}
+
+	// A failing Redis loads every key
+	mock.SetShouldFail(true)
+	calls = nil
+	got, err = counts.GetOrSetMulti(ctx, []string{"a", "bb"}, time.Minute, loader)
+	if err != nil || !maps.Equal(got, map[string]int{"a": 1, "bb": 2}) || len(calls) != 1 {
+		t.Errorf("GetOrSetMulti() with failing Redis = %v, %v, want both keys loaded at once", got, err)
+	}
+	mock.SetShouldFail(false)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := counts.GetOrSetMulti(canceled, []string{"x"}, 0, loader); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetOrSetMulti() with done context error = %v, want context.Canceled", err)
+	}
+	if _, err := TypedOf[int](nil).GetOrSetMulti(ctx, []string{"a"}, 0, loader); err == nil {
+		t.Error("GetOrSetMulti() with nil cache error = nil, want error")
+	}
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// negativeValue is stored for keys cached as missing; no codec encodes a value to it, as
+// neither JSON, msgpack, nor gob output starts with a NUL byte followed by text
+const negativeValue = "\x00redis-kit:negative"
+
+// NegativeTTL returns how long Typed.GetOrSet caches a miss of its loader, 0 when it does not
+func (c *RedisCache) NegativeTTL() time.Duration {
+	return c.negativeTTL
+}
+
+// SetNegative caches key as known to be missing for ttl: Get then fails with ErrNegativeHit,
+// which also matches ErrNotFound, without the caller reaching the backing store. Exists and
+// TTL see the entry like any other, and Set replaces it
+func (c *RedisCache) SetNegative(ctx context.Context, key string, ttl time.Duration) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("set_negative") {
+		return nil
+	}
+	return c.setRaw(ctx, "set_negative", key, []byte(negativeValue), ttl)
+}
+
+// SetNegative caches key as known to be missing for ttl, as RedisCache.SetNegative does; the
+// entry counts toward the cap
+func (c *LRUCache) SetNegative(ctx context.Context, key string, ttl time.Duration) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("set_negative") {
+		return nil
+	}
+	return c.setRaw(ctx, "set_negative", key, []byte(negativeValue), ttl)
+}
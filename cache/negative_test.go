@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestSetNegative(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	caches := map[string]Cache{
+		"redis": NewCache(client, "neg:"),
+		"lru":   NewLRUCache(client, "{neg-lru}:", 10),
+	}
+	for name, c := range caches {
+		t.Run(name, func(t *testing.T) {
+			if err := c.SetNegative(ctx, "user:404", time.Minute); err != nil {
+				t.Fatalf("SetNegative() error = %v, want nil", err)
+			}
+			var got string
+			err := c.Get(ctx, "user:404", &got)
+			if !errors.Is(err, ErrNegativeHit) || !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() of a negative entry error = %v, want ErrNegativeHit matching ErrNotFound", err)
+			}
+			if ttl, _ := c.TTL(ctx, "user:404"); ttl <= 0 || ttl > time.Minute {
+				t.Errorf("TTL() of a negative entry = %v, want up to a minute", ttl)
+			}
+
+			// Set replaces the entry
+			_ = c.Set(ctx, "user:404", "found", 0)
+			if err := c.Get(ctx, "user:404", &got); err != nil || got != "found" {
+				t.Errorf("Get() after Set = %q, %v, want %q", got, err, "found")
+			}
+		})
+	}
+
+	mock.SetShouldFail(true)
+	if err := NewCache(client, "neg:").SetNegative(ctx, "k", time.Minute); err == nil {
+		t.Error("SetNegative() with failing Redis error = nil, want error")
+	}
+	if err := NewCache(nil, "neg:").SetNegative(ctx, "k", time.Minute); err == nil {
+		t.Error("SetNegative() with nil client error = nil, want error")
+	}
+}
+
+func TestTyped_GetOrSetNegative(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	loads := 0
+	loader := func(ctx context.Context) (int, error) {
+		loads++
+		return 0, fmt.Errorf("user 404: %w", ErrNotFound)
+	}
+
+	users := NewTyped[int](client, "users:", WithNegativeTTL(time.Minute))
+	if _, err := users.GetOrSet(ctx, "404", time.Hour, loader); !errors.Is(err, ErrNotFound) || errors.Is(err, ErrNegativeHit) {
+		t.Errorf("GetOrSet() with a missing record error = %v, want the loader's ErrNotFound", err)
+	}
+	if _, err := users.GetOrSet(ctx, "404", time.Hour, loader); !errors.Is(err, ErrNegativeHit) {
+		t.Errorf("GetOrSet() after a cached miss error = %v, want ErrNegativeHit", err)
+	}
+	if loads != 1 {
+		t.Errorf("loader ran %d times, want 1", loads)
+	}
+	if ttl, _ := users.Cache().TTL(ctx, "404"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("negative entry TTL = %v, want the negative TTL", ttl)
+	}
+
+	// Without a negative TTL misses are not cached
+	loads = 0
+	plain := NewTyped[int](client, "plain:")
+	_, _ = plain.GetOrSet(ctx, "404", time.Hour, loader)
+	_, _ = plain.GetOrSet(ctx, "404", time.Hour, loader)
+	if loads != 2 {
+		t.Errorf("loader ran %d times without a negative TTL, want 2", loads)
+	}
+}
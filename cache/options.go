@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/utils"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// Option configures a RedisCache
+type Option func(*RedisCache)
+
+// WithRetryPolicy sets the retry policy applied to cache operations (default: no retry)
+func WithRetryPolicy(policy utils.RetryPolicy) Option {
+	return func(c *RedisCache) {
+		c.retryPolicy = policy
+	}
+}
+
+// ReadRouter runs reads against a replica, such as client.ReadWriteClient does
+type ReadRouter interface {
+	Read(ctx context.Context, fn func(redis.Cmdable) error) error
+}
+
+// WithReadRouter sends Get, Exists, and TTL through r, so they can be served by replicas;
+// writes still go to the cache's client (default: reads use the cache's client)
+func WithReadRouter(r ReadRouter) Option {
+	return func(c *RedisCache) {
+		c.reader = r
+	}
+}
+
+// LocalTier is an in-process cache of raw values in front of Redis that keeps itself coherent
+// with it, such as client.TrackingCache
+type LocalTier interface {
+	// Get returns the value stored at key, or redis.Nil if there is none
+	Get(ctx context.Context, key string) (string, error)
+
+	// Invalidate drops keys after the cache wrote them
+	Invalidate(keys ...string)
+}
+
+// WithLocalTier serves Get through local, which answers repeated reads from memory; Set and
+// Del invalidate the keys they write (default: every Get reads Redis)
+// LRUCache reads always go to Redis, so that they update the recency index
+func WithLocalTier(local LocalTier) Option {
+	return func(c *RedisCache) {
+		c.local = local
+	}
+}
+
+// WithRecorder sets the recorder cache operations are reported to (default: metrics.Default())
+func WithRecorder(r metrics.Recorder) Option {
+	return func(c *RedisCache) {
+		c.recorder = r
+	}
+}
+
+// WithCodec sets the codec values are encoded with (default: codec.Default(), JSON)
+// Every reader of the cache's keys must use the same codec; a nil cd keeps the default
+func WithCodec(cd codec.Codec) Option {
+	return func(c *RedisCache) {
+		c.codec = cd
+	}
+}
+
+// WithTTLJitter makes Set randomly shorten or extend each positive TTL by up to fraction of it
+// (0-1, e.g. 0.1 for ±10%), so keys written together do not all expire in the same instant and
+// send their reloads to the backing store at once (default: 0, TTLs are used as given)
+func WithTTLJitter(fraction float64) Option {
+	return func(c *RedisCache) {
+		c.ttlJitter = math.Min(math.Max(fraction, 0), 1)
+	}
+}
+
+// WithNegativeTTL makes Typed.GetOrSet cache a loader's ErrNotFound for ttl, so lookups of
+// missing keys, e.g. made-up IDs, stop reaching the backing store; they then fail with
+// ErrNegativeHit until it expires (default: 0, misses are not cached)
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(c *RedisCache) {
+		c.negativeTTL = ttl
+	}
+}
+
+// WithEarlyRefresh makes Typed.GetOrSet refresh entries before they expire, with a
+// probability that grows as expiry nears and with how long the loader took, following the
+// XFetch algorithm; beta scales how early, 1 being the usual choice and larger values
+// refreshing earlier. One caller reloads while the rest keep getting the cached value, so an
+// expensive entry under heavy reads never expires into a burst of loads. Entries written by
+// GetOrSet then carry the loader's duration, which Get strips (default: 0, no early refresh)
+// LRUCache does not refresh early
+func WithEarlyRefresh(beta float64) Option {
+	return func(c *RedisCache) {
+		c.earlyRefresh = max(beta, 0)
+	}
+}
+
+// WithVersionRefresh makes a VersionedCache reuse the namespace version it last read for
+// interval before reading it from Redis again, saving a round trip per operation; other
+// processes then see a BumpVersion up to interval late (default: 0, read on every operation)
+func WithVersionRefresh(interval time.Duration) Option {
+	return func(c *RedisCache) {
+		c.versionRefresh = max(interval, 0)
+	}
+}
+
+// OversizePolicy is what a cache does with a value larger than its WithMaxValueSize
+type OversizePolicy int
+
+const (
+	// OversizeReject fails the write with ErrValueTooLarge and stores nothing
+	OversizeReject OversizePolicy = iota
+	// OversizeTruncate stores the first bytes of the value up to the limit and fails the
+	// write with ErrValueTooLarge. Only strings and []byte read back usefully: a truncated
+	// codec or encrypted value no longer decodes
+	OversizeTruncate
+	// OversizeSkip drops the write without an error, as if the value were not worth caching
+	OversizeSkip
+)
+
+// WithMaxValueSize limits encoded values to bytes, so one huge object cannot blow up Redis
+// memory or pipeline buffers; larger values are handled by WithOversizePolicy and reported
+// with the outcome metrics.OutcomeTooLarge (default: 0, no limit). The limit applies to
+// Set, Add, and SetBytes, but not to counters or the fields of a HashCache
+func WithMaxValueSize(bytes int) Option {
+	return func(c *RedisCache) {
+		c.maxValueSize = max(bytes, 0)
+	}
+}
+
+// WithOversizePolicy sets what writes do with values above WithMaxValueSize
+// (default: OversizeReject)
+func WithOversizePolicy(policy OversizePolicy) Option {
+	return func(c *RedisCache) {
+		c.oversize = policy
+	}
+}
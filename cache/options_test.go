@@ -0,0 +1,379 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+func TestWithRetryPolicy(t *testing.T) {
+	t.Run("default is no retry", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		c := NewCache(client, "test:")
+		if c.retryPolicy.MaxAttempts != 1 {
+			t.Errorf("NewCache() retryPolicy.MaxAttempts = %d, want 1", c.retryPolicy.MaxAttempts)
+		}
+	})
+
+	t.Run("retries transient failures", func(t *testing.T) {
+		client, mock := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		calls := 0
+		policy := utils.NoRetry().
+			WithMaxAttempts(2).
+			WithBackoff(time.Millisecond, time.Millisecond).
+			WithClassifier(func(error) bool {
+				calls++
+				mock.SetShouldFail(false)
+				return true
+			})
+		c := NewCache(client, "test:", WithRetryPolicy(policy))
+		ctx := context.Background()
+
+		mock.SetShouldFail(true)
+		if err := c.Set(ctx, "key", "value", time.Minute); err != nil {
+			t.Fatalf("Set() error = %v, want nil", err)
+		}
+
+		mock.SetShouldFail(true)
+		var got string
+		if err := c.Get(ctx, "key", &got); err != nil {
+			t.Fatalf("Get() error = %v, want nil", err)
+		}
+		if got != "value" {
+			t.Errorf("Get() = %q, want %q", got, "value")
+		}
+
+		mock.SetShouldFail(true)
+		if exists, err := c.Exists(ctx, "key"); err != nil || !exists {
+			t.Errorf("Exists() = (%v, %v), want (true, nil)", exists, err)
+		}
+
+		mock.SetShouldFail(true)
+		if _, err := c.TTL(ctx, "key"); err != nil {
+			t.Errorf("TTL() error = %v, want nil", err)
+		}
+
+		mock.SetShouldFail(true)
+		if err := c.Expire(ctx, "key", time.Minute); err != nil {
+			t.Errorf("Expire() error = %v, want nil", err)
+		}
+
+		mock.SetShouldFail(true)
+		if err := c.Del(ctx, "key"); err != nil {
+			t.Errorf("Del() error = %v, want nil", err)
+		}
+
+		if calls != 6 {
+			t.Errorf("classifier calls = %d, want 6", calls)
+		}
+	})
+}
+
+func TestWithRecorder(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	rec := testutil.NewRecorder()
+	c := NewCache(client, "test:", WithRecorder(rec))
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "key", "value", time.Minute)
+	var got string
+	_ = c.Get(ctx, "key", &got)
+	_ = c.Get(ctx, "missing", &got)
+	_, _ = c.Exists(ctx, "key")
+	_, _ = c.TTL(ctx, "key")
+	_ = c.Expire(ctx, "key", time.Minute)
+	_ = c.Del(ctx, "key")
+	mock.SetShouldFail(true)
+	_ = c.Get(ctx, "key", &got)
+
+	for _, tt := range []struct{ operation, outcome string }{
+		{"set", metrics.OutcomeSuccess},
+		{"get", metrics.OutcomeHit},
+		{"get", metrics.OutcomeMiss},
+		{"get", metrics.OutcomeError},
+		{"exists", metrics.OutcomeSuccess},
+		{"ttl", metrics.OutcomeSuccess},
+		{"expire", metrics.OutcomeSuccess},
+		{"del", metrics.OutcomeSuccess},
+	} {
+		if n := rec.Count(metrics.SubsystemCache, tt.operation, tt.outcome); n != 1 {
+			t.Errorf("Count(%s, %s) = %d, want 1", tt.operation, tt.outcome, n)
+		}
+	}
+	if n := rec.Total(); n != 8 {
+		t.Errorf("Total() = %d, want 8", n)
+	}
+}
+
+// replicaRouter reads from a separate client standing in for a replica
+type replicaRouter struct {
+	replica redis.Cmdable
+	reads   int
+}
+
+func (r *replicaRouter) Read(_ context.Context, fn func(redis.Cmdable) error) error {
+	r.reads++
+	return fn(r.replica)
+}
+
+func TestWithReadRouter(t *testing.T) {
+	primary, _ := testutil.NewMockRedisClient()
+	defer func() { _ = primary.Close() }()
+	replica, _ := testutil.NewMockRedisClient()
+	defer func() { _ = replica.Close() }()
+
+	ctx := context.Background()
+	router := &replicaRouter{replica: replica}
+	c := NewCache(primary, "test:", WithReadRouter(router))
+
+	// Writes go to the primary, which the replica has not caught up with
+	if err := c.Set(ctx, "k", "primary", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	var got string
+	if err := c.Get(ctx, "k", &got); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() before replication error = %v, want ErrNotFound from the replica", err)
+	}
+
+	_ = replica.Set(ctx, c.Key("k"), "replica", time.Minute).Err()
+	if err := c.Get(ctx, "k", &got); err != nil || got != "replica" {
+		t.Errorf("Get() = %q, %v, want the replica's value", got, err)
+	}
+	if ok, err := c.Exists(ctx, "k"); err != nil || !ok {
+		t.Errorf("Exists() = %v, %v, want true", ok, err)
+	}
+	if ttl, err := c.TTL(ctx, "k"); err != nil || ttl <= 0 {
+		t.Errorf("TTL() = %v, %v, want positive", ttl, err)
+	}
+	if router.reads != 4 {
+		t.Errorf("router reads = %d, want 4", router.reads)
+	}
+}
+
+// mapTier is a local tier over a map that falls back to a Redis client
+type mapTier struct {
+	client      redis.Cmdable
+	values      map[string]string
+	invalidated []string
+}
+
+func (m *mapTier) Get(ctx context.Context, key string) (string, error) {
+	if v, ok := m.values[key]; ok {
+		return v, nil
+	}
+	return m.client.Get(ctx, key).Result()
+}
+
+func (m *mapTier) Invalidate(keys ...string) {
+	m.invalidated = append(m.invalidated, keys...)
+	for _, key := range keys {
+		delete(m.values, key)
+	}
+}
+
+func TestWithLocalTier(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	tier := &mapTier{client: client, values: map[string]string{"test:local": "memory"}}
+	c := NewCache(client, "test:", WithLocalTier(tier))
+
+	var got string
+	if err := c.Get(ctx, "local", &got); err != nil || got != "memory" {
+		t.Errorf("Get() = %q, %v, want the local value", got, err)
+	}
+	if err := c.Get(ctx, "missing", &got); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() missing error = %v, want ErrNotFound", err)
+	}
+
+	if err := c.Set(ctx, "local", "redis", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	if err := c.Get(ctx, "local", &got); err != nil || got != "redis" {
+		t.Errorf("Get() after Set = %q, %v, want the written value", got, err)
+	}
+	_ = c.Del(ctx, "local")
+	if len(tier.invalidated) != 2 || tier.invalidated[0] != "test:local" {
+		t.Errorf("invalidated = %v, want the key after Set and Del", tier.invalidated)
+	}
+}
+
+func TestWithCodec(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	if c := NewCache(client, "json:"); c.Codec() != codec.JSON {
+		t.Errorf("NewCache() codec = %s, want json", c.Codec().Name())
+	}
+	if c := NewCache(client, "json:", WithCodec(nil)); c.Codec() != codec.JSON {
+		t.Errorf("WithCodec(nil) codec = %v, want json", c.Codec())
+	}
+
+	type record struct {
+		ID    string
+		Count int
+		Tags  []string
+	}
+	in := record{ID: "42", Count: 7, Tags: []string{"a", "b"}}
+	caches := map[string]Cache{
+		"msgpack":     NewCacheWithCodec(client, "mp:", codec.Msgpack),
+		"gob":         NewCache(client, "gob:", WithCodec(codec.Gob)),
+		"msgpack lru": NewLRUCache(client, "lru:", 10, WithCodec(codec.Msgpack)),
+	}
+	for name, c := range caches {
+		t.Run(name, func(t *testing.T) {
+			if err := c.Set(ctx, "k", in, time.Minute); err != nil {
+				t.Fatalf("Set() error = %v, want nil", err)
+			}
+			var out record
+			if err := c.Get(ctx, "k", &out); err != nil {
+				t.Fatalf("Get() error = %v, want nil", err)
+			}
+			if out.ID != in.ID || out.Count != in.Count || len(out.Tags) != 2 {
+				t.Errorf("Get() = %+v, want %+v", out, in)
+			}
+		})
+	}
+
+	// Values are stored in the codec's encoding
+	data, err := client.Get(ctx, "mp:k").Bytes()
+	if err != nil {
+		t.Fatalf("raw Get() error = %v, want nil", err)
+	}
+	var out record
+	if err := codec.Msgpack.Unmarshal(data, &out); err != nil || out.ID != "42" {
+		t.Errorf("stored value decoded with msgpack = %+v, %v, want the record", out, err)
+	}
+	if err := NewCache(client, "mp:").Get(ctx, "k", &out); err == nil {
+		t.Error("Get() of a msgpack value with the json codec error = nil, want error")
+	}
+}
+
+func TestWithTTLJitter(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	if c := NewCache(client, "x:", WithTTLJitter(2)); c.ttlJitter != 1 {
+		t.Errorf("WithTTLJitter(2) = %v, want 1", c.ttlJitter)
+	}
+	if c := NewCache(client, "x:", WithTTLJitter(-1)); c.jitter(time.Minute) != time.Minute {
+		t.Error("WithTTLJitter(-1) varied the TTL, want it used as given")
+	}
+
+	const ttl = 100 * time.Second
+	caches := map[string]Cache{
+		"redis": NewCache(client, "jitter:", WithTTLJitter(0.1)),
+		"lru":   NewLRUCache(client, "{jitter-lru}:", 100, WithTTLJitter(0.1)),
+	}
+	for name, c := range caches {
+		t.Run(name, func(t *testing.T) {
+			seen := make(map[time.Duration]bool)
+			for i := range 20 {
+				key := fmt.Sprintf("k%d", i)
+				if err := c.Set(ctx, key, i, ttl); err != nil {
+					t.Fatalf("Set() error = %v, want nil", err)
+				}
+				got, _ := c.TTL(ctx, key)
+				if got < 90*time.Second || got > 110*time.Second {
+					t.Errorf("TTL() = %v, want within 10%% of %v", got, ttl)
+				}
+				seen[got.Round(time.Second)] = true
+			}
+			if len(seen) < 2 {
+				t.Errorf("TTLs = %v, want them spread out", seen)
+			}
+
+			// Entries without expiry keep none
+			if err := c.Set(ctx, "forever", 1, 0); err != nil {
+				t.Fatalf("Set() error = %v, want nil", err)
+			}
+			if got, _ := c.TTL(ctx, "forever"); got != -1 {
+				t.Errorf("TTL() without expiry = %v, want -1", got)
+			}
+		})
+	}
+
+	// A tag set outlives the entries it names
+	c := NewCache(client, "jitter-tag:", WithTTLJitter(0.5))
+	_ = c.SetWithTags(ctx, "k", 1, ttl, "t")
+	if got := client.PTTL(ctx, c.TagKey("t")).Val(); got < 149*time.Second {
+		t.Errorf("tag set TTL = %v, want the longest jittered TTL", got)
+	}
+}
+
+func TestWithMaxValueSize(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	for _, lru := range []bool{false, true} {
+		newCache := func(prefix string, opts ...Option) Cache {
+			if lru {
+				return NewLRUCache(client, "{"+prefix+"}:", 10, opts...)
+			}
+			return NewCache(client, prefix+":", opts...)
+		}
+		rec := testutil.NewRecorder()
+
+		// Reject stores nothing and fails
+		c := newCache("size-reject", WithMaxValueSize(4), WithRecorder(rec))
+		if err := c.Set(ctx, "small", "abcd", 0); err != nil {
+			t.Errorf("lru=%v: Set() within the limit error = %v, want nil", lru, err)
+		}
+		if err := c.Set(ctx, "big", "abcdef", 0); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("lru=%v: Set() above the limit error = %v, want ErrValueTooLarge", lru, err)
+		}
+		if err := c.SetBytes(ctx, "big", []byte("abcdef"), 0); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("lru=%v: SetBytes() above the limit error = %v, want ErrValueTooLarge", lru, err)
+		}
+		if added, err := c.Add(ctx, "big", "abcdef", 0); added || !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("lru=%v: Add() above the limit = %v, %v, want ErrValueTooLarge", lru, added, err)
+		}
+		if exists, _ := c.Exists(ctx, "big"); exists {
+			t.Errorf("lru=%v: a rejected value was stored", lru)
+		}
+		if n := rec.Count(metrics.SubsystemCache, "set", metrics.OutcomeTooLarge); n != 2 {
+			t.Errorf("lru=%v: too_large observations of set = %d, want 2", lru, n)
+		}
+
+		// Truncate stores the head of the value and fails
+		c = newCache("size-truncate", WithMaxValueSize(4), WithOversizePolicy(OversizeTruncate))
+		if err := c.Set(ctx, "big", "abcdef", 0); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("lru=%v: truncating Set() error = %v, want ErrValueTooLarge", lru, err)
+		}
+		var got string
+		if err := c.Get(ctx, "big", &got); err != nil || got != "abcd" {
+			t.Errorf("lru=%v: Get() of a truncated value = %q, %v, want abcd", lru, got, err)
+		}
+
+		// Skip drops the write silently
+		c = newCache("size-skip", WithMaxValueSize(4), WithOversizePolicy(OversizeSkip))
+		if err := c.Set(ctx, "big", map[string]string{"k": "value"}, 0); err != nil {
+			t.Errorf("lru=%v: skipping Set() error = %v, want nil", lru, err)
+		}
+		if exists, _ := c.Exists(ctx, "big"); exists {
+			t.Errorf("lru=%v: a skipped value was stored", lru)
+		}
+	}
+
+	if c := NewCache(client, "x:", WithMaxValueSize(-1)); c.maxValueSize != 0 {
+		t.Errorf("WithMaxValueSize(-1) = %d, want no limit", c.maxValueSize)
+	}
+}
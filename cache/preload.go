@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultPreloadConcurrency is the number of keys a Preloader loads in parallel by default
+const DefaultPreloadConcurrency = 8
+
+// PreloadProgress reports how far a Preloader run got
+type PreloadProgress struct {
+	// Total is the number of keys to preload
+	Total int
+	// Loaded are the keys loaded and stored
+	Loaded int
+	// Skipped are the keys already cached, with WithPreloadSkipCached, tombstoned, or that the
+	// loader reported missing with ErrNotFound
+	Skipped int
+	// Failed are the keys whose load or write failed
+	Failed int
+}
+
+// Done returns the number of keys handled so far
+func (p PreloadProgress) Done() int {
+	return p.Loaded + p.Skipped + p.Failed
+}
+
+// preloadSettings holds the configuration of a Preloader
+type preloadSettings struct {
+	concurrency int
+	progress    func(PreloadProgress)
+	skipCached  bool
+}
+
+// PreloadOption configures a Preloader
+type PreloadOption func(*preloadSettings)
+
+// WithPreloadConcurrency sets how many keys are loaded in parallel, bounding the load put on
+// the backing store (default: DefaultPreloadConcurrency)
+func WithPreloadConcurrency(n int) PreloadOption {
+	return func(s *preloadSettings) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// WithPreloadProgress calls fn after each key is handled, one call at a time, e.g. to log
+// or export how far a warm-up got
+func WithPreloadProgress(fn func(PreloadProgress)) PreloadOption {
+	return func(s *preloadSettings) {
+		s.progress = fn
+	}
+}
+
+// WithPreloadSkipCached skips keys already in the cache instead of reloading them, so a
+// scheduled warm-up only fills the gaps (default: every key is reloaded)
+func WithPreloadSkipCached() PreloadOption {
+	return func(s *preloadSettings) {
+		s.skipCached = true
+	}
+}
+
+// Preloader fills a cache ahead of reads, e.g. at deploy time or on a schedule, so the first
+// requests after a start or a flush do not all miss and hit the backing store at once
+type Preloader[T any] struct {
+	cache    *Typed[T]
+	ttl      time.Duration
+	loader   func(ctx context.Context, key string) (T, error)
+	settings preloadSettings
+}
+
+// NewPreloader creates a preloader storing the values loader returns in c with the given TTL
+func NewPreloader[T any](c Cache, ttl time.Duration, loader func(ctx context.Context, key string) (T, error), opts ...PreloadOption) *Preloader[T] {
+	p := &Preloader[T]{
+		cache:    TypedOf[T](c),
+		ttl:      ttl,
+		loader:   loader,
+		settings: preloadSettings{concurrency: DefaultPreloadConcurrency},
+	}
+	for _, opt := range opts {
+		opt(&p.settings)
+	}
+	return p
+}
+
+// Run loads and stores keys with bounded parallelism and returns the final progress. A key
+// that fails does not stop the others; the returned error joins the failures, each naming
+// its key. When ctx is done, keys not started yet are left out and ctx's error is returned
+// with the failures
+func (p *Preloader[T]) Run(ctx context.Context, keys []string) (PreloadProgress, error) {
+	progress := PreloadProgress{Total: len(keys)}
+	if p.cache.cache == nil {
+		return progress, fmt.Errorf("redis client is nil")
+	}
+	if p.loader == nil {
+		return progress, fmt.Errorf("loader is nil")
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	report := func(update func(*PreloadProgress), err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		update(&progress)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if p.settings.progress != nil {
+			p.settings.progress(progress)
+		}
+	}
+
+	work := make(chan string)
+	for range min(p.settings.concurrency, len(keys)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range work {
+				loaded, err := p.preload(ctx, key)
+				switch {
+				case err != nil:
+					report(func(pp *PreloadProgress) { pp.Failed++ }, fmt.Errorf("failed to preload %q: %w", key, err))
+				case loaded:
+					report(func(pp *PreloadProgress) { pp.Loaded++ }, nil)
+				default:
+					report(func(pp *PreloadProgress) { pp.Skipped++ }, nil)
+				}
+			}
+		}()
+	}
+	dispatched := 0
+dispatch:
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case work <- key:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if dispatched < len(keys) {
+		errs = append(errs, ctx.Err())
+	}
+	return progress, errors.Join(errs...)
+}
+
+// preload loads and stores key, reporting whether it did
+func (p *Preloader[T]) preload(ctx context.Context, key string) (bool, error) {
+	if p.settings.skipCached {
+		exists, err := p.cache.Exists(ctx, key)
+		if err != nil {
+			return false, err
+		}
+		if exists {
+			return false, nil
+		}
+	}
+	value, err := p.loader(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return p.cache.fill(ctx, key, value, p.ttl, 0)
+}
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestPreloader(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewCache(client, "warm:")
+	var running, peak atomic.Int32
+	loader := func(ctx context.Context, key string) (int, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		switch key {
+		case "missing":
+			return 0, ErrNotFound
+		case "broken":
+			return 0, errors.New("database down")
+		}
+		return len(key), nil
+	}
+
+	keys := []string{"a", "bb", "ccc", "dddd", "missing", "broken"}
+	var reports []PreloadProgress
+	p := NewPreloader(c, time.Hour, loader,
+		WithPreloadConcurrency(2),
+		WithPreloadProgress(func(pp PreloadProgress) { reports = append(reports, pp) }))
+	progress, err := p.Run(ctx, keys)
+	if err == nil || !strings.Contains(err.Error(), `"broken"`) {
+		t.Errorf("Run() error = %v, want the failure of broken", err)
+	}
+	want := PreloadProgress{Total: 6, Loaded: 4, Skipped: 1, Failed: 1}
+	if progress != want || progress.Done() != 6 {
+		t.Errorf("Run() = %+v, want %+v", progress, want)
+	}
+	if n := peak.Load(); n > 2 {
+		t.Errorf("loader ran %d at once, want at most 2", n)
+	}
+	if len(reports) != 6 || reports[5] != want {
+		t.Errorf("progress reports = %+v, want one per key ending with %+v", reports, want)
+	}
+	counts := TypedOf[int](c)
+	if got, err := counts.Get(ctx, "ccc"); err != nil || got != 3 {
+		t.Errorf("Get() of a preloaded key = %d, %v, want 3", got, err)
+	}
+	if ttl, _ := c.TTL(ctx, "ccc"); ttl <= 0 {
+		t.Errorf("TTL() of a preloaded key = %v, want the preloader's TTL", ttl)
+	}
+}
+
+func TestPreloader_SkipCached(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewCache(client, "warm-skip:")
+	_ = c.Set(ctx, "a", "cached", 0)
+	var loads atomic.Int32
+	p := NewPreloader(c, 0, func(ctx context.Context, key string) (string, error) {
+		loads.Add(1)
+		return "loaded", nil
+	}, WithPreloadSkipCached())
+	progress, err := p.Run(ctx, []string{"a", "b"})
+	if err != nil || progress.Loaded != 1 || progress.Skipped != 1 {
+		t.Errorf("Run() = %+v, %v, want b loaded and a skipped", progress, err)
+	}
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loader ran %d times, want 1", n)
+	}
+	var got string
+	if err := c.Get(ctx, "a", &got); err != nil || got != "cached" {
+		t.Errorf("Get() of a skipped key = %q, %v, want it untouched", got, err)
+	}
+}
+
+func TestPreloader_Errors(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	loader := func(ctx context.Context, key string) (string, error) { return key, nil }
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = fmt.Sprint(i)
+	}
+
+	// Failed writes count as failures
+	mock.SetShouldFail(true)
+	progress, err := NewPreloader(NewCache(client, "warm-err:"), 0, loader).Run(ctx, keys[:3])
+	if err == nil || progress.Failed != 3 {
+		t.Errorf("Run() with failing Redis = %+v, %v, want 3 failures", progress, err)
+	}
+	mock.SetShouldFail(false)
+
+	// A done context stops dispatching
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	progress, err = NewPreloader(NewCache(client, "warm-err:"), 0, loader, WithPreloadConcurrency(1)).Run(canceled, keys)
+	if !errors.Is(err, context.Canceled) || progress.Done() == len(keys) {
+		t.Errorf("Run() with done context = %+v, %v, want context.Canceled before every key", progress, err)
+	}
+
+	if _, err := NewPreloader[string](nil, 0, loader).Run(ctx, keys); err == nil {
+		t.Error("Run() with nil cache error = nil, want error")
+	}
+	if _, err := NewPreloader[string](NewCache(client, "warm-err:"), 0, nil).Run(ctx, keys); err == nil {
+		t.Error("Run() with nil loader error = nil, want error")
+	}
+	if progress, err := NewPreloader(NewCache(client, "warm-err:"), 0, loader).Run(ctx, nil); err != nil || progress.Total != 0 {
+		t.Errorf("Run() without keys = %+v, %v, want nothing done", progress, err)
+	}
+}
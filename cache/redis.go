@@ -2,80 +2,352 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/utils"
+	"github.com/soulteary/redis-kit/utils/codec"
 )
 
 // RedisCache provides a Redis-based cache implementation
 type RedisCache struct {
-	client    *redis.Client
-	keyPrefix string
+	client         redis.UniversalClient
+	keyPrefix      string
+	retryPolicy    utils.RetryPolicy
+	recorder       metrics.Recorder
+	reader         ReadRouter
+	local          LocalTier
+	codec          codec.Codec
+	lruIndex       string  // recency index of an LRUCache, kept in step by DelPrefix
+	ttlJitter      float64 // fraction by which Set varies TTLs
+	negativeTTL    time.Duration
+	earlyRefresh   float64       // XFetch beta of Typed.GetOrSet
+	versionRefresh time.Duration // how long a VersionedCache reuses the version it read
+	maxValueSize   int           // limit of encoded values in bytes, 0 for none
+	oversize       OversizePolicy
+	stats          cacheStats
 }
 
 // NewCache creates a new Redis cache with the given client and key prefix
-func NewCache(client *redis.Client, keyPrefix string) *RedisCache {
-	return &RedisCache{
-		client:    client,
-		keyPrefix: keyPrefix,
+// client may be a single-node, cluster, sentinel, or ring client; while a
+// client.ResilientClient reports Redis as degraded, reads miss and writes are dropped
+func NewCache(client redis.UniversalClient, keyPrefix string, opts ...Option) *RedisCache {
+	c := &RedisCache{
+		client:      utils.NormalizeClient(client),
+		keyPrefix:   keyPrefix,
+		retryPolicy: utils.NoRetry(),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.codec == nil {
+		c.codec = codec.Default()
+	}
+	return c
 }
 
-// buildKey constructs the full key with prefix
-func (c *RedisCache) buildKey(key string) string {
-	if c.keyPrefix == "" {
-		return key
-	}
+// NewCacheWithCodec creates a new Redis cache encoding values with cd, e.g. codec.Msgpack for
+// large structs or codec.Gob for Go types JSON cannot round-trip; see WithCodec
+func NewCacheWithCodec(client redis.UniversalClient, keyPrefix string, cd Codec, opts ...Option) *RedisCache {
+	return NewCache(client, keyPrefix, append(opts, WithCodec(cd))...)
+}
+
+// Client returns the underlying Redis client, for operations the Cache interface does not cover
+func (c *RedisCache) Client() redis.UniversalClient {
+	return c.client
+}
+
+// Codec returns the codec values are encoded with
+func (c *RedisCache) Codec() Codec {
+	return c.codec
+}
+
+// KeyPrefix returns the prefix of the cache's Redis keys
+func (c *RedisCache) KeyPrefix() string {
+	return c.keyPrefix
+}
+
+// Key returns the Redis key used for key, including the cache's prefix but no scope of
+// ContextWithPrefix
+func (c *RedisCache) Key(key string) string {
 	return c.keyPrefix + key
 }
 
-// Set stores a value in Redis with the given TTL
+// do runs a Redis operation under the cache's retry policy
+func (c *RedisCache) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return utils.Retry(ctx, c.retryPolicy, fn)
+}
+
+// read runs a read operation under the cache's retry policy, through its read router if set
+func (c *RedisCache) read(ctx context.Context, fn func(ctx context.Context, client redis.Cmdable) error) error {
+	return c.do(ctx, func(ctx context.Context) error {
+		if c.reader != nil {
+			return c.reader.Read(ctx, func(client redis.Cmdable) error { return fn(ctx, client) })
+		}
+		return fn(ctx, c.client)
+	})
+}
+
+// degraded reports whether the client reports Redis as unavailable; the cache then misses on
+// reads and drops writes without calling Redis
+func (c *RedisCache) degraded(operation string) bool {
+	if !utils.Degraded(c.client) {
+		return false
+	}
+	c.observe(operation, metrics.OutcomeDegraded, time.Now())
+	return true
+}
+
+// invalidate drops written keys from the local tier, if any
+func (c *RedisCache) invalidate(keys ...string) {
+	if c.local != nil {
+		c.local.Invalidate(keys...)
+	}
+}
+
+// observe reports an operation to the cache's recorder, or the default one, and counts it
+// in the cache's Stats
+func (c *RedisCache) observe(operation, outcome string, start time.Time) {
+	c.stats.count(operation, outcome)
+	metrics.Observe(c.recorder, metrics.SubsystemCache, operation, outcome, start)
+}
+
+// jitter randomly varies a positive ttl by the cache's TTL jitter
+func (c *RedisCache) jitter(ttl time.Duration) time.Duration {
+	return utils.JitterTTL(ttl, c.ttlJitter)
+}
+
+// maxTTL returns the longest TTL jitter can turn ttl into
+func (c *RedisCache) maxTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(float64(ttl)*c.ttlJitter)
+}
+
+// fit applies WithMaxValueSize to data about to be written by operation, returning the data
+// to store, whether to store it, and the error to return for it
+func (c *RedisCache) fit(operation string, data []byte) ([]byte, bool, error) {
+	if c.maxValueSize <= 0 || len(data) <= c.maxValueSize {
+		return data, true, nil
+	}
+	c.observe(operation, metrics.OutcomeTooLarge, time.Now())
+	err := fmt.Errorf("%w: %d bytes, limit %d", ErrValueTooLarge, len(data), c.maxValueSize)
+	switch c.oversize {
+	case OversizeTruncate:
+		return data[:c.maxValueSize], true, err
+	case OversizeSkip:
+		return nil, false, nil
+	}
+	return nil, false, err
+}
+
+// encode stores []byte and string values as is, only sealed by a codec.Sealer codec, and the
+// rest with the cache's codec
+func (c *RedisCache) encode(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return c.seal(v)
+	case string:
+		return c.seal([]byte(v))
+	}
+	return c.codec.Marshal(value)
+}
+
+// seal seals raw data with the cache's codec if it is a codec.Sealer, e.g. encrypts it
+func (c *RedisCache) seal(data []byte) ([]byte, error) {
+	if s, ok := c.codec.(codec.Sealer); ok {
+		return s.Seal(data)
+	}
+	return data, nil
+}
+
+// open reverses seal
+func (c *RedisCache) open(data []byte) ([]byte, error) {
+	if s, ok := c.codec.(codec.Sealer); ok {
+		return s.Open(data)
+	}
+	return data, nil
+}
+
+// Decode decodes a value read from one of the cache's keys directly, e.g. with MGET, into dest
+// as Get would
+func (c *RedisCache) Decode(data []byte, dest interface{}) error {
+	data, _ = splitCost(data)
+	return c.decode(data, dest)
+}
+
+// decode reads data into *[]byte and *string destinations as is, as encode stored them, and
+// into the rest with the cache's codec
+func (c *RedisCache) decode(data []byte, dest interface{}) error {
+	switch d := dest.(type) {
+	case *[]byte:
+		if d != nil {
+			raw, err := c.open(data)
+			if err != nil {
+				return err
+			}
+			*d = raw
+			return nil
+		}
+	case *string:
+		if d != nil {
+			raw, err := c.open(data)
+			if err != nil {
+				return err
+			}
+			*d = string(raw)
+			return nil
+		}
+	}
+	return c.codec.Unmarshal(data, dest)
+}
+
+// buildKey constructs the full key of an entry with the cache's prefix and the scope of ctx,
+// if any (see ContextWithPrefix)
+func (c *RedisCache) buildKey(ctx context.Context, key string) string {
+	return c.keyPrefix + PrefixFromContext(ctx) + key
+}
+
+// Set stores a value in Redis with the given TTL, varied by WithTTLJitter if set; []byte and
+// string values are stored as is, and the rest encoded with the cache's codec
 func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
 	if c.client == nil {
 		return fmt.Errorf("redis client is nil")
 	}
+	if c.degraded("set") {
+		return nil
+	}
 
-	fullKey := c.buildKey(key)
-
-	// Serialize value to JSON
-	data, err := json.Marshal(value)
+	data, err := c.encode(value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal value: %w", err)
 	}
+	return c.setRaw(ctx, "set", key, data, ttl)
+}
+
+// Add stores a value with the given TTL only if key does not exist (SET NX) and reports
+// whether it was written, e.g. to initialize a key once or drop duplicate work. While Redis
+// is degraded the cache holds nothing, so Add reports the write and drops it, as Set does
+func (c *RedisCache) Add(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if c.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("add") {
+		return true, nil
+	}
+
+	data, err := c.encode(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	data, ok, sizeErr := c.fit("add", data)
+	if !ok {
+		return false, sizeErr
+	}
+
+	fullKey := c.buildKey(ctx, key)
+	start := time.Now()
+	var added bool
+	err = c.do(ctx, func(ctx context.Context) error {
+		err := c.client.SetArgs(ctx, fullKey, data, redis.SetArgs{Mode: "NX", TTL: c.jitter(ttl)}).Err()
+		added = err == nil
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return err
+	})
+	if added {
+		c.invalidate(fullKey)
+		c.stats.sets.Add(1)
+	}
+	c.observe("add", metrics.Outcome(err), start)
+	if err != nil {
+		return false, fmt.Errorf("failed to add to cache: %w", err)
+	}
+	return added, sizeErr
+}
+
+// setRaw stores encoded data with the given TTL, varied by WithTTLJitter if set
+func (c *RedisCache) setRaw(ctx context.Context, operation, key string, data []byte, ttl time.Duration) error {
+	data, ok, sizeErr := c.fit(operation, data)
+	if !ok {
+		return sizeErr
+	}
+	fullKey := c.buildKey(ctx, key)
 
 	// Store in Redis with TTL
-	if err := c.client.Set(ctx, fullKey, data, ttl).Err(); err != nil {
+	start := time.Now()
+	err := c.do(ctx, func(ctx context.Context) error {
+		return c.client.Set(ctx, fullKey, data, c.jitter(ttl)).Err()
+	})
+	c.invalidate(fullKey)
+	c.observe(operation, metrics.Outcome(err), start)
+	if err != nil {
 		return fmt.Errorf("failed to set cache: %w", err)
 	}
 
-	return nil
+	return sizeErr
 }
 
 // Get retrieves a value from Redis
 func (c *RedisCache) Get(ctx context.Context, key string, dest interface{}) error {
+	data, err := c.getRaw(ctx, "get", key)
+	if err != nil {
+		return err
+	}
+	if err := c.decode(data, dest); err != nil {
+		return fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+
+	return nil
+}
+
+// getRaw returns the encoded value stored at key
+func (c *RedisCache) getRaw(ctx context.Context, operation, key string) ([]byte, error) {
 	if c.client == nil {
-		return fmt.Errorf("redis client is nil")
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if c.degraded(operation) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
 	}
 
-	fullKey := c.buildKey(key)
+	fullKey := c.buildKey(ctx, key)
 
 	// Get from Redis
-	data, err := c.client.Get(ctx, fullKey).Bytes()
+	start := time.Now()
+	var data []byte
+	var err error
+	if c.local != nil {
+		err = c.do(ctx, func(ctx context.Context) error {
+			value, getErr := c.local.Get(ctx, fullKey)
+			data = []byte(value)
+			return getErr
+		})
+	} else {
+		err = c.read(ctx, func(ctx context.Context, client redis.Cmdable) error {
+			var getErr error
+			data, getErr = client.Get(ctx, fullKey).Bytes()
+			return getErr
+		})
+	}
+	c.observe(operation, readOutcome(data, err), start)
 	if err == redis.Nil {
-		return fmt.Errorf("key not found: %s", key)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
 	}
 	if err != nil {
-		return fmt.Errorf("failed to get cache: %w", err)
+		return nil, fmt.Errorf("failed to get cache: %w", err)
 	}
-
-	// Deserialize from JSON
-	if err := json.Unmarshal(data, dest); err != nil {
-		return fmt.Errorf("failed to unmarshal value: %w", err)
+	if err := markerErr(key, data); err != nil {
+		return nil, err
 	}
-
-	return nil
+	data, _ = splitCost(data)
+	return data, nil
 }
 
 // Del deletes a key from Redis
@@ -83,9 +355,18 @@ func (c *RedisCache) Del(ctx context.Context, key string) error {
 	if c.client == nil {
 		return fmt.Errorf("redis client is nil")
 	}
+	if c.degraded("del") {
+		return nil
+	}
 
-	fullKey := c.buildKey(key)
-	return c.client.Del(ctx, fullKey).Err()
+	fullKey := c.buildKey(ctx, key)
+	start := time.Now()
+	err := c.do(ctx, func(ctx context.Context) error {
+		return c.client.Del(ctx, fullKey).Err()
+	})
+	c.invalidate(fullKey)
+	c.observe("del", metrics.Outcome(err), start)
+	return err
 }
 
 // Exists checks if a key exists in Redis
@@ -93,9 +374,19 @@ func (c *RedisCache) Exists(ctx context.Context, key string) (bool, error) {
 	if c.client == nil {
 		return false, fmt.Errorf("redis client is nil")
 	}
+	if c.degraded("exists") {
+		return false, nil
+	}
 
-	fullKey := c.buildKey(key)
-	count, err := c.client.Exists(ctx, fullKey).Result()
+	fullKey := c.buildKey(ctx, key)
+	start := time.Now()
+	var count int64
+	err := c.read(ctx, func(ctx context.Context, client redis.Cmdable) error {
+		var existsErr error
+		count, existsErr = client.Exists(ctx, fullKey).Result()
+		return existsErr
+	})
+	c.observe("exists", metrics.Outcome(err), start)
 	if err != nil {
 		return false, fmt.Errorf("failed to check existence: %w", err)
 	}
@@ -108,9 +399,19 @@ func (c *RedisCache) TTL(ctx context.Context, key string) (time.Duration, error)
 	if c.client == nil {
 		return 0, fmt.Errorf("redis client is nil")
 	}
+	if c.degraded("ttl") {
+		return utils.TTLKeyNotFound, nil
+	}
 
-	fullKey := c.buildKey(key)
-	ttl, err := c.client.TTL(ctx, fullKey).Result()
+	fullKey := c.buildKey(ctx, key)
+	start := time.Now()
+	var ttl time.Duration
+	err := c.read(ctx, func(ctx context.Context, client redis.Cmdable) error {
+		var ttlErr error
+		ttl, ttlErr = client.TTL(ctx, fullKey).Result()
+		return ttlErr
+	})
+	c.observe("ttl", metrics.Outcome(err), start)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get TTL: %w", err)
 	}
@@ -123,7 +424,234 @@ func (c *RedisCache) Expire(ctx context.Context, key string, ttl time.Duration)
 	if c.client == nil {
 		return fmt.Errorf("redis client is nil")
 	}
+	if c.degraded("expire") {
+		return nil
+	}
+
+	fullKey := c.buildKey(ctx, key)
+	start := time.Now()
+	err := c.do(ctx, func(ctx context.Context) error {
+		return c.client.Expire(ctx, fullKey, ttl).Err()
+	})
+	c.observe("expire", metrics.Outcome(err), start)
+	return err
+}
+
+// Persist removes the TTL of key, so a temporary entry is kept until deleted
+func (c *RedisCache) Persist(ctx context.Context, key string) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("persist") {
+		return nil
+	}
+
+	fullKey := c.buildKey(ctx, key)
+	start := time.Now()
+	err := c.do(ctx, func(ctx context.Context) error {
+		return c.client.Persist(ctx, fullKey).Err()
+	})
+	c.observe("persist", metrics.Outcome(err), start)
+	return err
+}
+
+// Touch sets the TTL of every key to ttl in one round trip, e.g. to extend a group of entries
+// that are in use, and returns the number of keys that exist. Missing keys are skipped rather
+// than created
+func (c *RedisCache) Touch(ctx context.Context, ttl time.Duration, keys ...string) (int64, error) {
+	if c.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+	if len(keys) == 0 || c.degraded("touch") {
+		return 0, nil
+	}
+
+	start := time.Now()
+	var touched int64
+	// One PEXPIRE per key, as the keys may live in different cluster slots
+	err := c.do(ctx, func(ctx context.Context) error {
+		cmds := make([]*redis.BoolCmd, 0, len(keys))
+		err := utils.PipelineEach(ctx, c.client, keys, utils.DefaultChunkSize, func(pipe redis.Pipeliner, key string) {
+			cmds = append(cmds, pipe.PExpire(ctx, c.buildKey(ctx, key), ttl))
+		})
+		if err != nil {
+			return err
+		}
+		touched = 0
+		for _, cmd := range cmds {
+			if cmd.Val() {
+				touched++
+			}
+		}
+		return nil
+	})
+	c.observe("touch", metrics.Outcome(err), start)
+	if err != nil {
+		return 0, fmt.Errorf("failed to touch keys: %w", err)
+	}
+	return touched, nil
+}
+
+// DelPrefix deletes every entry whose key starts with prefix, relative to the cache's key
+// prefix, so a namespace can be invalidated without FLUSHDB or KEYS. Keys are found with SCAN
+// and removed with UNLINK in batches, one round trip each; on Redis Cluster every master is
+// scanned. It returns the number of keys deleted, partial on error. The full prefix may not be
+// empty, as that would match every key of the database
+func (c *RedisCache) DelPrefix(ctx context.Context, prefix string) (int64, error) {
+	return c.delPrefix(ctx, "del_prefix", prefix)
+}
+
+// Flush deletes every entry of the cache, as DelPrefix does; the cache needs a key prefix
+func (c *RedisCache) Flush(ctx context.Context) (int64, error) {
+	return c.delPrefix(ctx, "flush", "")
+}
+
+// delPrefix deletes the keys under prefix, reported as operation
+func (c *RedisCache) delPrefix(ctx context.Context, operation, prefix string) (int64, error) {
+	if c.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+	fullPrefix := c.buildKey(ctx, prefix)
+	if fullPrefix == "" {
+		return 0, fmt.Errorf("key prefix is required")
+	}
+	if c.degraded(operation) {
+		return 0, nil
+	}
+
+	start := time.Now()
+	var deleted int64
+	batch := make([]string, 0, utils.DefaultDeleteBatchSize)
+	flush := func() error {
+		n, err := c.unlink(ctx, batch, nil)
+		deleted += n
+		batch = batch[:0]
+		return err
+	}
+
+	var err error
+	for key, scanErr := range utils.ScanKeys(ctx, c.client, utils.EscapeGlob(fullPrefix)+"*", utils.DefaultScanCount) {
+		if scanErr != nil {
+			err = scanErr
+			break
+		}
+		if key == c.lruIndex {
+			continue
+		}
+		batch = append(batch, key)
+		if len(batch) >= utils.DefaultDeleteBatchSize {
+			if err = flush(); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = flush()
+	}
+	c.observe(operation, metrics.Outcome(err), start)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete keys under prefix: %w", err)
+	}
+	return deleted, nil
+}
 
-	fullKey := c.buildKey(key)
-	return c.client.Expire(ctx, fullKey, ttl).Err()
+// unlink deletes the full keys in one round trip and drops them from the LRU index, if any,
+// running queue for each key on the same pipeline; it returns the number of keys deleted
+func (c *RedisCache) unlink(ctx context.Context, keys []string, queue func(pipe redis.Pipeliner, key string)) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	var deleted int64
+	// One UNLINK per key, as the keys may live in different cluster slots
+	err := c.do(ctx, func(ctx context.Context) error {
+		cmds := make([]*redis.IntCmd, 0, len(keys))
+		err := utils.PipelineEach(ctx, c.client, keys, utils.DefaultDeleteBatchSize, func(pipe redis.Pipeliner, key string) {
+			cmds = append(cmds, pipe.Unlink(ctx, key))
+			if c.lruIndex != "" {
+				pipe.ZRem(ctx, c.lruIndex, key)
+			}
+			if queue != nil {
+				queue(pipe, key)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		deleted = 0
+		for _, cmd := range cmds {
+			deleted += cmd.Val()
+		}
+		return nil
+	})
+	c.invalidate(keys...)
+	return deleted, err
+}
+
+// ScanKeys returns an iterator over the keys of the cache's entries matching pattern, a SCAN
+// MATCH glob such as "user:*" applied inside the cache's key prefix; an empty pattern matches
+// every entry. Keys are yielded without the prefix, ready for Get. On Redis Cluster every
+// master is scanned, as DelPrefix does. As with SCAN itself, a key may be yielded more than
+// once, and the iteration stops after yielding an error
+func (c *RedisCache) ScanKeys(ctx context.Context, pattern string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		if c.client == nil {
+			yield("", fmt.Errorf("redis client is nil"))
+			return
+		}
+		if utils.Degraded(c.client) {
+			return
+		}
+		if pattern == "" {
+			pattern = "*"
+		}
+		prefix := c.buildKey(ctx, "")
+		for fullKey, err := range utils.ScanKeys(ctx, c.client, utils.EscapeGlob(prefix)+pattern, utils.DefaultScanCount) {
+			if err != nil {
+				yield("", err)
+				return
+			}
+			key, parseErr := utils.ParseKey(prefix, fullKey)
+			if parseErr != nil {
+				continue
+			}
+			if c.lruIndex != "" && key == DefaultLRUIndexKey || strings.HasPrefix(key, DefaultTagKeyPrefix) {
+				continue
+			}
+			if !yield(key, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns the keys of the cache's entries matching pattern, as ScanKeys yields them but
+// without duplicates; prefer ScanKeys for large caches
+func (c *RedisCache) Keys(ctx context.Context, pattern string) ([]string, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("keys") {
+		return nil, nil
+	}
+
+	start := time.Now()
+	seen := make(map[string]struct{})
+	var keys []string
+	var err error
+	for key, scanErr := range c.ScanKeys(ctx, pattern) {
+		if scanErr != nil {
+			err = scanErr
+			break
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+	c.observe("keys", metrics.Outcome(err), start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	return keys, nil
 }
@@ -2,10 +2,16 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils"
 )
 
 func TestNewCache(t *testing.T) {
@@ -24,17 +30,51 @@ func TestNewCache(t *testing.T) {
 	}
 }
 
+func TestNewCache_UniversalClient(t *testing.T) {
+	ring, _ := testutil.NewMockRingClient()
+	defer func() { _ = ring.Close() }()
+
+	ctx := context.Background()
+	c := NewCache(ring, "test:")
+	if err := c.Set(ctx, "k", "v", time.Minute); err != nil {
+		t.Fatalf("Set() through ring error = %v, want nil", err)
+	}
+	var got string
+	if err := c.Get(ctx, "k", &got); err != nil || got != "v" {
+		t.Errorf("Get() through ring = %q, %v, want v", got, err)
+	}
+
+	lru := NewLRUCache(ring, "lru:", 1)
+	_ = lru.Set(ctx, "a", 1, 0)
+	_ = lru.Set(ctx, "b", 2, 0)
+	if n, err := lru.Len(ctx); err != nil || n != 1 {
+		t.Errorf("LRU Len() through ring = %d, %v, want 1", n, err)
+	}
+
+	// A nil *redis.Client is reported like a missing client instead of panicking
+	var client *redis.Client
+	if err := NewCache(client, "test:").Set(ctx, "k", "v", 0); err == nil {
+		t.Error("Set() with nil *redis.Client error = nil, want error")
+	}
+}
+
 func TestRedisCache_buildKey(t *testing.T) {
 	t.Run("with prefix", func(t *testing.T) {
 		client, _ := testutil.NewMockRedisClient()
 		defer func() { _ = client.Close() }()
 
 		c := NewCache(client, "test:")
-		key := c.buildKey("mykey")
+		key := c.buildKey(context.Background(), "mykey")
 		expected := "test:mykey"
 		if key != expected {
 			t.Errorf("buildKey() = %q, want %q", key, expected)
 		}
+		if c.Key("mykey") != expected {
+			t.Errorf("Key() = %q, want %q", c.Key("mykey"), expected)
+		}
+		if c.Client() != client {
+			t.Error("Client() did not return the cache's client")
+		}
 	})
 
 	t.Run("without prefix", func(t *testing.T) {
@@ -42,7 +82,7 @@ func TestRedisCache_buildKey(t *testing.T) {
 		defer func() { _ = client.Close() }()
 
 		c := NewCache(client, "")
-		key := c.buildKey("mykey")
+		key := c.buildKey(context.Background(), "mykey")
 		expected := "mykey"
 		if key != expected {
 			t.Errorf("buildKey() = %q, want %q", key, expected)
@@ -173,6 +213,9 @@ func TestRedisCache_Get(t *testing.T) {
 		if err.Error() != "key not found: nonexistent" {
 			t.Errorf("Get() error = %q, want %q", err.Error(), "key not found: nonexistent")
 		}
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get() error = %v, want ErrNotFound", err)
+		}
 	})
 
 	t.Run("nil client error", func(t *testing.T) {
@@ -439,6 +482,105 @@ func TestRedisCache_Expire(t *testing.T) {
 	})
 }
 
+// pipelineSizes records the number of commands in each pipeline
+type pipelineSizes struct {
+	mu    sync.Mutex
+	sizes []int
+}
+
+func (h *pipelineSizes) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *pipelineSizes) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+
+func (h *pipelineSizes) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		h.mu.Lock()
+		h.sizes = append(h.sizes, len(cmds))
+		h.mu.Unlock()
+		return next(ctx, cmds)
+	}
+}
+
+func TestRedisCache_TouchChunks(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+	c := NewCache(client, "chunks:")
+
+	keys := make([]string, utils.DefaultChunkSize+1)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%d", i)
+		_ = c.Set(ctx, keys[i], i, time.Minute)
+	}
+
+	hook := &pipelineSizes{}
+	client.AddHook(hook)
+	if n, err := c.Touch(ctx, time.Hour, keys...); err != nil || n != int64(len(keys)) {
+		t.Fatalf("Touch() = %d, %v, want %d", n, err, len(keys))
+	}
+	if !slices.Equal(hook.sizes, []int{utils.DefaultChunkSize, 1}) {
+		t.Errorf("Touch() pipeline sizes = %v, want chunks of at most %d", hook.sizes, utils.DefaultChunkSize)
+	}
+}
+
+func TestRedisCache_PersistTouch(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	for name, c := range map[string]Cache{
+		"redis": NewCache(client, "pt:"),
+		"lru":   NewLRUCache(client, "{pt-lru}:", 10),
+	} {
+		_ = c.Set(ctx, "a", "1", time.Minute)
+		_ = c.Set(ctx, "b", "2", time.Minute)
+
+		if err := c.Persist(ctx, "a"); err != nil {
+			t.Fatalf("%s: Persist() error = %v, want nil", name, err)
+		}
+		if ttl, _ := c.TTL(ctx, "a"); ttl != -1 {
+			t.Errorf("%s: TTL() after Persist = %v, want -1", name, ttl)
+		}
+		if err := c.Persist(ctx, "missing"); err != nil {
+			t.Errorf("%s: Persist() of a missing key error = %v, want nil", name, err)
+		}
+
+		n, err := c.Touch(ctx, time.Hour, "a", "b", "missing")
+		if err != nil || n != 2 {
+			t.Errorf("%s: Touch() = %d, %v, want 2", name, n, err)
+		}
+		for _, key := range []string{"a", "b"} {
+			if ttl, _ := c.TTL(ctx, key); ttl <= time.Minute {
+				t.Errorf("%s: TTL(%s) after Touch = %v, want about an hour", name, key, ttl)
+			}
+		}
+		if exists, _ := c.Exists(ctx, "missing"); exists {
+			t.Errorf("%s: Touch() created a missing key", name)
+		}
+		if n, err := c.Touch(ctx, time.Hour); n != 0 || err != nil {
+			t.Errorf("%s: Touch() without keys = %d, %v, want 0", name, n, err)
+		}
+	}
+
+	c := NewCache(client, "pt:")
+	mock.SetShouldFail(true)
+	if err := c.Persist(ctx, "a"); err == nil {
+		t.Error("Persist() with failing Redis error = nil, want error")
+	}
+	if _, err := c.Touch(ctx, time.Hour, "a"); err == nil {
+		t.Error("Touch() with failing Redis error = nil, want error")
+	}
+	mock.SetShouldFail(false)
+
+	nilCache := NewCache(nil, "pt:")
+	if err := nilCache.Persist(ctx, "a"); err == nil {
+		t.Error("Persist() with nil client error = nil, want error")
+	}
+	if _, err := nilCache.Touch(ctx, time.Hour, "a"); err == nil {
+		t.Error("Touch() with nil client error = nil, want error")
+	}
+}
+
 func TestRedisCache_KeyPrefix(t *testing.T) {
 	t.Run("prefix is applied", func(t *testing.T) {
 		client, _ := testutil.NewMockRedisClient()
@@ -608,7 +750,7 @@ func TestRedisCache_KeyPrefix(t *testing.T) {
 
 		for _, tc := range testCases {
 			c := NewCache(client, tc.prefix)
-			got := c.buildKey(tc.key)
+			got := c.buildKey(context.Background(), tc.key)
 			if got != tc.want {
 				t.Errorf("buildKey(prefix=%q, key=%q) = %q, want %q", tc.prefix, tc.key, got, tc.want)
 			}
@@ -817,3 +959,277 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+// degradedClient reports a settable degraded state, as client.ResilientClient does
+type degradedClient struct {
+	redis.UniversalClient
+	degraded bool
+}
+
+func (c *degradedClient) Degraded() bool { return c.degraded }
+
+func (c *degradedClient) Unwrap() redis.UniversalClient { return c.UniversalClient }
+
+func TestRedisCache_Add(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	lru := NewLRUCache(client, "{add-lru}:", 10)
+	for name, c := range map[string]Cache{
+		"redis": NewCache(client, "add:"),
+		"lru":   lru,
+	} {
+		if added, err := c.Add(ctx, "job:1", "first", time.Minute); !added || err != nil {
+			t.Fatalf("%s: Add() of a new key = %v, %v, want true", name, added, err)
+		}
+		if added, err := c.Add(ctx, "job:1", "second", time.Minute); added || err != nil {
+			t.Errorf("%s: Add() of an existing key = %v, %v, want false", name, added, err)
+		}
+		var got string
+		if err := c.Get(ctx, "job:1", &got); err != nil || got != "first" {
+			t.Errorf("%s: Get() after Add = %q, %v, want first", name, got, err)
+		}
+		if ttl, _ := c.TTL(ctx, "job:1"); ttl <= 0 || ttl > time.Minute {
+			t.Errorf("%s: TTL() after Add = %v, want up to a minute", name, ttl)
+		}
+		if added, err := c.Add(ctx, "forever", 1, 0); !added || err != nil {
+			t.Errorf("%s: Add() without TTL = %v, %v, want true", name, added, err)
+		}
+	}
+	if n, _ := lru.Len(ctx); n != 2 {
+		t.Errorf("LRU Len() after Add = %d, want only written keys tracked", n)
+	}
+
+	if _, err := NewCache(client, "add:").Add(ctx, "k", make(chan int), 0); err == nil {
+		t.Error("Add() of an unmarshalable value error = nil, want error")
+	}
+	mock.SetShouldFail(true)
+	if added, err := NewCache(client, "add:").Add(ctx, "new", 1, 0); added || err == nil {
+		t.Errorf("Add() with failing Redis = %v, %v, want false and an error", added, err)
+	}
+	if added, err := lru.Add(ctx, "new", 1, 0); added || err == nil {
+		t.Errorf("LRU Add() with failing Redis = %v, %v, want false and an error", added, err)
+	}
+	if _, err := NewCache(nil, "add:").Add(ctx, "k", 1, 0); err == nil {
+		t.Error("Add() with nil client error = nil, want error")
+	}
+}
+
+func TestRedisCache_Degraded(t *testing.T) {
+	mockClient, _ := testutil.NewMockRedisClient()
+	defer func() { _ = mockClient.Close() }()
+	client := &degradedClient{UniversalClient: mockClient}
+	ctx := context.Background()
+
+	for name, c := range map[string]Cache{
+		"redis": NewCache(client, "c:"),
+		"lru":   NewLRUCache(client, "lru:", 10),
+	} {
+		client.degraded = false
+		if err := c.Set(ctx, "k", "before", time.Minute); err != nil {
+			t.Fatalf("%s: Set() error = %v, want nil", name, err)
+		}
+
+		client.degraded = true
+		var got string
+		if err := c.Get(ctx, "k", &got); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: Get() while degraded error = %v, want ErrNotFound", name, err)
+		}
+		if err := c.Set(ctx, "k", "during", time.Minute); err != nil {
+			t.Errorf("%s: Set() while degraded error = %v, want nil", name, err)
+		}
+		if err := c.Del(ctx, "k"); err != nil {
+			t.Errorf("%s: Del() while degraded error = %v, want nil", name, err)
+		}
+		if added, err := c.Add(ctx, "k", "during", time.Minute); !added || err != nil {
+			t.Errorf("%s: Add() while degraded = %v, %v, want true", name, added, err)
+		}
+
+		// Writes while degraded were dropped
+		client.degraded = false
+		if err := c.Get(ctx, "k", &got); err != nil || got != "before" {
+			t.Errorf("%s: Get() after recovery = %q, %v, want before", name, got, err)
+		}
+	}
+
+	rc := NewCache(client, "c:")
+	client.degraded = true
+	if ok, err := rc.Exists(ctx, "k"); ok || err != nil {
+		t.Errorf("Exists() while degraded = %v, %v, want false", ok, err)
+	}
+	if ttl, err := rc.TTL(ctx, "k"); ttl != -2 || err != nil {
+		t.Errorf("TTL() while degraded = %v, %v, want -2", ttl, err)
+	}
+	if err := rc.Expire(ctx, "k", time.Second); err != nil {
+		t.Errorf("Expire() while degraded error = %v, want nil", err)
+	}
+	if err := rc.Persist(ctx, "k"); err != nil {
+		t.Errorf("Persist() while degraded error = %v, want nil", err)
+	}
+	if n, err := rc.Touch(ctx, time.Second, "k"); n != 0 || err != nil {
+		t.Errorf("Touch() while degraded = %d, %v, want 0", n, err)
+	}
+	if n, err := rc.Flush(ctx); n != 0 || err != nil {
+		t.Errorf("Flush() while degraded = %d, %v, want 0", n, err)
+	}
+	client.degraded = false
+	if ok, _ := rc.Exists(ctx, "k"); !ok {
+		t.Error("Flush() while degraded deleted keys")
+	}
+}
+
+func TestRedisCache_DelPrefix(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewCache(client, "app:")
+	for i := range 250 {
+		_ = c.Set(ctx, fmt.Sprintf("users:%d", i), i, 0)
+	}
+	_ = c.Set(ctx, "orders:1", 1, 0)
+	_ = c.Set(ctx, "u*:1", 1, 0)
+	_ = client.Set(ctx, "other:users:1", 1, 0).Err()
+
+	n, err := c.DelPrefix(ctx, "users:")
+	if err != nil || n != 250 {
+		t.Fatalf("DelPrefix(users:) = %d, %v, want 250", n, err)
+	}
+	if ok, _ := c.Exists(ctx, "users:7"); ok {
+		t.Error("DelPrefix() left users:7")
+	}
+
+	// Glob characters in the prefix are matched literally
+	if n, err := c.DelPrefix(ctx, "u*"); err != nil || n != 1 {
+		t.Errorf("DelPrefix(u*) = %d, %v, want 1", n, err)
+	}
+	if ok, _ := c.Exists(ctx, "orders:1"); !ok {
+		t.Error("DelPrefix() deleted a key outside the prefix")
+	}
+
+	if n, err := c.Flush(ctx); err != nil || n != 1 {
+		t.Errorf("Flush() = %d, %v, want 1", n, err)
+	}
+	if v, err := client.Get(ctx, "other:users:1").Result(); err != nil || v != "1" {
+		t.Errorf("Flush() touched a key of another prefix: %q, %v", v, err)
+	}
+
+	// An empty full prefix would match the whole database
+	if _, err := NewCache(client, "").Flush(ctx); err == nil {
+		t.Error("Flush() without key prefix error = nil, want error")
+	}
+	if _, err := NewCache(nil, "app:").DelPrefix(ctx, "x"); err == nil {
+		t.Error("DelPrefix() with nil client error = nil, want error")
+	}
+	mock.SetShouldFail(true)
+	if _, err := c.Flush(ctx); err == nil {
+		t.Error("Flush() with failing Redis error = nil, want error")
+	}
+}
+
+func TestRedisCache_DelPrefix_Cluster(t *testing.T) {
+	cluster, _ := testutil.NewMockClusterClient(3)
+	defer func() { _ = cluster.Close() }()
+	ctx := context.Background()
+
+	// Directly and behind a wrapper such as client.ResilientClient
+	for name, client := range map[string]redis.UniversalClient{
+		"cluster": cluster,
+		"wrapped": &degradedClient{UniversalClient: cluster},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewCache(client, "app:")
+			for i := range 30 {
+				_ = c.Set(ctx, fmt.Sprintf("users:%d", i), i, 0)
+				_ = c.Set(ctx, fmt.Sprintf("orders:%d", i), i, 0)
+			}
+			if n, err := c.DelPrefix(ctx, "users:"); err != nil || n != 30 {
+				t.Errorf("DelPrefix() on a cluster = %d, %v, want 30", n, err)
+			}
+			if n, err := c.Flush(ctx); err != nil || n != 30 {
+				t.Errorf("Flush() on a cluster = %d, %v, want 30", n, err)
+			}
+			for i := range 30 {
+				for _, key := range []string{fmt.Sprintf("users:%d", i), fmt.Sprintf("orders:%d", i)} {
+					if n, _ := cluster.Exists(ctx, c.Key(key)).Result(); n != 0 {
+						t.Errorf("Flush() on a cluster kept %s, want none", key)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRedisCache_Keys(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewCache(client, "a[1]:")
+	for _, key := range []string{"user:1", "user:2", "order:1"} {
+		_ = c.Set(ctx, key, 1, 0)
+	}
+	_ = client.Set(ctx, "a1:user:3", 1, 0).Err()
+
+	keys, err := c.Keys(ctx, "user:*")
+	slices.Sort(keys)
+	if err != nil || !slices.Equal(keys, []string{"user:1", "user:2"}) {
+		t.Errorf("Keys(user:*) = %v, %v, want user:1, user:2", keys, err)
+	}
+	if keys, err := c.Keys(ctx, ""); err != nil || len(keys) != 3 {
+		t.Errorf("Keys() = %v, %v, want every entry", keys, err)
+	}
+
+	// The iterator can be stopped early
+	var n int
+	for key, err := range c.ScanKeys(ctx, "") {
+		if err != nil {
+			t.Fatalf("ScanKeys() error = %v, want nil", err)
+		}
+		var v int
+		if err := c.Get(ctx, key, &v); err != nil {
+			t.Errorf("Get(%s) of a scanned key error = %v, want nil", key, err)
+		}
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("ScanKeys() yielded %d keys before break, want 1", n)
+	}
+
+	// Every master of a cluster is listed
+	cluster, _ := testutil.NewMockClusterClient(3)
+	defer func() { _ = cluster.Close() }()
+	onCluster := NewCache(cluster, "app:")
+	for i := range 30 {
+		_ = onCluster.Set(ctx, fmt.Sprintf("user:%d", i), i, 0)
+	}
+	if keys, err := onCluster.Keys(ctx, "user:*"); err != nil || len(keys) != 30 {
+		t.Errorf("Keys() on a cluster = %d keys, %v, want 30", len(keys), err)
+	}
+
+	// The recency index of an LRU cache is not an entry
+	lru := NewLRUCache(client, "lru:", 10)
+	_ = lru.Set(ctx, "x", 1, 0)
+	if keys, err := lru.Keys(ctx, ""); err != nil || !slices.Equal(keys, []string{"x"}) {
+		t.Errorf("LRU Keys() = %v, %v, want x", keys, err)
+	}
+
+	degraded := NewCache(&degradedClient{UniversalClient: client, degraded: true}, "a[1]:")
+	if keys, err := degraded.Keys(ctx, ""); err != nil || keys != nil {
+		t.Errorf("Keys() while degraded = %v, %v, want none", keys, err)
+	}
+	if _, err := NewCache(nil, "x:").Keys(ctx, ""); err == nil {
+		t.Error("Keys() with nil client error = nil, want error")
+	}
+	for _, err := range NewCache(nil, "x:").ScanKeys(ctx, "") {
+		if err == nil {
+			t.Error("ScanKeys() with nil client error = nil, want error")
+		}
+	}
+	mock.SetShouldFail(true)
+	if _, err := c.Keys(ctx, ""); err == nil {
+		t.Error("Keys() with failing Redis error = nil, want error")
+	}
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+)
+
+// renameScript renames KEYS[1] to KEYS[2], replacing it, and moves the entry's recency in
+// the LRU index KEYS[3], if given
+const renameScript = `
+-- redis-kit:cache-rename
+if redis.call("exists", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("rename", KEYS[1], KEYS[2])
+if KEYS[3] then
+	local score = redis.call("zscore", KEYS[3], KEYS[1])
+	redis.call("zrem", KEYS[3], KEYS[1])
+	if score then
+		redis.call("zadd", KEYS[3], score, KEYS[2])
+	end
+end
+return 1
+`
+
+// copyScript copies KEYS[1] to KEYS[2], replacing it, then expires the copy in ARGV[1]
+// milliseconds, persists it for 0, or keeps the source's TTL for -1; the copy takes the
+// source's recency in the LRU index KEYS[3], if given
+const copyScript = `
+-- redis-kit:cache-copy
+if redis.call("copy", KEYS[1], KEYS[2], "replace") == 0 then
+	return 0
+end
+local ttl = tonumber(ARGV[1])
+if ttl > 0 then
+	redis.call("pexpire", KEYS[2], ttl)
+elseif ttl == 0 then
+	redis.call("persist", KEYS[2])
+end
+if KEYS[3] then
+	local score = redis.call("zscore", KEYS[3], KEYS[1])
+	if score then
+		redis.call("zadd", KEYS[3], score, KEYS[2])
+	end
+end
+return 1
+`
+
+// Rename atomically moves the entry at oldKey to newKey, both relative to the cache's key
+// prefix, replacing any entry there and keeping its TTL, e.g. to swap in a dataset built
+// under a temporary key. It fails with an error wrapping ErrNotFound when oldKey is missing.
+// On Redis Cluster, both keys must hash to the same slot, e.g. through a {hash tag} in the
+// prefix. An LRUCache moves the entry's recency along
+func (c *RedisCache) Rename(ctx context.Context, oldKey, newKey string) error {
+	return c.move(ctx, "rename", renameScript, oldKey, newKey)
+}
+
+// Copy copies the entry at src to dst, both relative to the cache's key prefix, replacing
+// any entry there. The copy expires after ttl, or never for 0; redis.KeepTTL keeps the TTL of
+// src. It fails with an error wrapping ErrNotFound when src is missing. Copy needs Redis 6.2
+// or later and, on Redis Cluster, both keys in the same slot. In an LRUCache the copy
+// counts toward the cap from the next write
+func (c *RedisCache) Copy(ctx context.Context, src, dst string, ttl time.Duration) error {
+	var ms int64
+	switch {
+	case ttl == redis.KeepTTL:
+		ms = -1
+	case ttl > 0:
+		ms = max(c.jitter(ttl).Milliseconds(), 1)
+	}
+	return c.move(ctx, "copy", copyScript, src, dst, ms)
+}
+
+// move runs the rename or copy script from src to dst, reported as operation
+func (c *RedisCache) move(ctx context.Context, operation, script, src, dst string, args ...interface{}) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if c.degraded(operation) {
+		return nil
+	}
+
+	keys := []string{c.buildKey(ctx, src), c.buildKey(ctx, dst)}
+	if c.lruIndex != "" {
+		keys = append(keys, c.lruIndex)
+	}
+	start := time.Now()
+	var moved int64
+	err := c.do(ctx, func(ctx context.Context) error {
+		var evalErr error
+		moved, evalErr = c.client.Eval(ctx, script, keys, args...).Int64()
+		return evalErr
+	})
+	c.invalidate(keys[:2]...)
+	c.observe(operation, metrics.Outcome(err), start)
+	if err != nil {
+		return fmt.Errorf("failed to %s key: %w", operation, err)
+	}
+	if moved == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, src)
+	}
+	return nil
+}
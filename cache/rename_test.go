@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestRedisCache_Rename(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewCache(client, "{ds}:")
+	_ = c.Set(ctx, "live", "old", 0)
+	_ = c.Set(ctx, "building", "new", time.Hour)
+
+	// The fresh dataset replaces the live one, keeping its TTL
+	if err := c.Rename(ctx, "building", "live"); err != nil {
+		t.Fatalf("Rename() error = %v, want nil", err)
+	}
+	var got string
+	if err := c.Get(ctx, "live", &got); err != nil || got != "new" {
+		t.Errorf("Get() after Rename = %q, %v, want new", got, err)
+	}
+	if ttl, _ := c.TTL(ctx, "live"); ttl <= 0 {
+		t.Errorf("TTL() after Rename = %v, want the source's TTL", ttl)
+	}
+	if exists, _ := c.Exists(ctx, "building"); exists {
+		t.Error("Rename() left the old key")
+	}
+	if err := c.Rename(ctx, "building", "live"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Rename() of a missing key error = %v, want ErrNotFound", err)
+	}
+
+	mock.SetShouldFail(true)
+	if err := c.Rename(ctx, "live", "other"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Rename() with failing Redis error = %v, want a Redis error", err)
+	}
+	mock.SetShouldFail(false)
+	if err := NewCache(nil, "{ds}:").Rename(ctx, "a", "b"); err == nil {
+		t.Error("Rename() with nil client error = nil, want error")
+	}
+}
+
+func TestRedisCache_Copy(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewCache(client, "{cp}:")
+	_ = c.Set(ctx, "src", "v", time.Hour)
+
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		wantTTL func(ttl time.Duration) bool
+	}{
+		{"new TTL", time.Minute, func(ttl time.Duration) bool { return ttl > 0 && ttl <= time.Minute }},
+		{"no expiry", 0, func(ttl time.Duration) bool { return ttl == -1 }},
+		{"keep TTL", redis.KeepTTL, func(ttl time.Duration) bool { return ttl > time.Minute }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.Copy(ctx, "src", "dst", tt.ttl); err != nil {
+				t.Fatalf("Copy() error = %v, want nil", err)
+			}
+			var got string
+			if err := c.Get(ctx, "dst", &got); err != nil || got != "v" {
+				t.Errorf("Get() of the copy = %q, %v, want v", got, err)
+			}
+			if ttl, _ := c.TTL(ctx, "dst"); !tt.wantTTL(ttl) {
+				t.Errorf("TTL() of the copy = %v", ttl)
+			}
+		})
+	}
+	if exists, _ := c.Exists(ctx, "src"); !exists {
+		t.Error("Copy() removed the source")
+	}
+	if err := c.Copy(ctx, "missing", "dst", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Copy() of a missing key error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLRUCache_RenameCopy(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewLRUCache(client, "{lru-mv}:", 2)
+	_ = c.Set(ctx, "a", "1", 0)
+	_ = c.Set(ctx, "b", "2", 0)
+
+	// The recency entry moves with the key
+	if err := c.Rename(ctx, "a", "c"); err != nil {
+		t.Fatalf("Rename() error = %v, want nil", err)
+	}
+	members, _ := client.ZRange(ctx, c.IndexKey(), 0, -1).Result()
+	if len(members) != 2 || members[0] != c.Key("c") || members[1] != c.Key("b") {
+		t.Errorf("index after Rename = %v, want [c b] in recency order", members)
+	}
+
+	// A copy is tracked and counts toward the cap on the next write
+	if err := c.Copy(ctx, "b", "d", 0); err != nil {
+		t.Fatalf("Copy() error = %v, want nil", err)
+	}
+	if n, _ := c.Len(ctx); n != 3 {
+		t.Errorf("Len() after Copy = %d, want 3", n)
+	}
+	_ = c.Set(ctx, "e", "5", 0)
+	if n, _ := c.Len(ctx); n != 2 {
+		t.Errorf("Len() after the next Set = %d, want 2", n)
+	}
+}
+
+func TestRedisCache_RenameDegraded(t *testing.T) {
+	mockClient, _ := testutil.NewMockRedisClient()
+	defer func() { _ = mockClient.Close() }()
+	client := &degradedClient{UniversalClient: mockClient}
+	ctx := context.Background()
+
+	c := NewCache(client, "{dg}:")
+	_ = c.Set(ctx, "a", "1", 0)
+	client.degraded = true
+	if err := c.Rename(ctx, "a", "b"); err != nil {
+		t.Errorf("Rename() while degraded error = %v, want nil", err)
+	}
+	if err := c.Copy(ctx, "a", "b", 0); err != nil {
+		t.Errorf("Copy() while degraded error = %v, want nil", err)
+	}
+	client.degraded = false
+	if exists, _ := c.Exists(ctx, "b"); exists {
+		t.Error("Rename() or Copy() while degraded wrote the destination")
+	}
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/soulteary/redis-kit/metrics"
+)
+
+// Operations counted by Stats; the rest, e.g. counters and TTL reads, only count errors, and
+// Add counts its writes itself
+var (
+	statsReads   = map[string]bool{"get": true, "hash_get": true, "hash_get_fields": true}
+	statsWrites  = map[string]bool{"set": true, "set_negative": true, "set_tombstone": true, "hash_set": true, "hash_set_fields": true}
+	statsDeletes = map[string]bool{"del": true, "del_prefix": true, "flush": true, "invalidate_tag": true}
+)
+
+// Stats counts a cache's operations since it was created
+type Stats struct {
+	// Hits are reads that found their key
+	Hits int64
+	// Misses are reads that did not, including those skipped while Redis was degraded
+	Misses int64
+	// Sets are successful writes of entries, counting Add only when it wrote
+	Sets int64
+	// Deletes are successful Del, DelPrefix, Flush, and InvalidateTag calls
+	Deletes int64
+	// Errors are operations of any kind that failed
+	Errors int64
+}
+
+// HitRatio returns the share of reads that hit, or 0 before the first read
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// cacheStats holds the live counters behind Stats
+type cacheStats struct {
+	hits, misses, sets, deletes, errors atomic.Int64
+}
+
+// count adds an observed operation to the counters
+func (s *cacheStats) count(operation, outcome string) {
+	switch {
+	case outcome == metrics.OutcomeError:
+		s.errors.Add(1)
+	case statsReads[operation] && outcome == metrics.OutcomeHit:
+		s.hits.Add(1)
+	case statsReads[operation]:
+		s.misses.Add(1)
+	case outcome == metrics.OutcomeDegraded, outcome == metrics.OutcomeTooLarge:
+	case statsWrites[operation]:
+		s.sets.Add(1)
+	case statsDeletes[operation]:
+		s.deletes.Add(1)
+	}
+}
+
+// Stats returns the cache's operation counts, e.g. to compute its hit ratio; they are kept
+// whatever recorder the cache reports to. Caches wrapping it, such as Typed and HashCache,
+// add to its counts
+func (c *RedisCache) Stats() Stats {
+	return Stats{
+		Hits:    c.stats.hits.Load(),
+		Misses:  c.stats.misses.Load(),
+		Sets:    c.stats.sets.Load(),
+		Deletes: c.stats.deletes.Load(),
+		Errors:  c.stats.errors.Load(),
+	}
+}
+
+// StatsSource is a cache whose Stats a StatsCollector exports, such as a RedisCache or LRUCache
+type StatsSource interface {
+	KeyPrefix() string
+	Stats() Stats
+}
+
+// StatsCollector serves the Stats of caches in the Prometheus text exposition format, labelled
+// by key prefix, as metrics.PrometheusRecorder serves operation latencies. Caches sharing a
+// prefix are summed
+type StatsCollector struct {
+	namespace string
+
+	mu      sync.Mutex
+	sources []StatsSource
+}
+
+// NewStatsCollector creates a collector exporting the stats of sources under namespace, as
+// <namespace>_cache_hits_total and so on; an empty namespace uses metrics.DefaultNamespace
+func NewStatsCollector(namespace string, sources ...StatsSource) *StatsCollector {
+	if namespace == "" {
+		namespace = metrics.DefaultNamespace
+	}
+	s := &StatsCollector{namespace: namespace}
+	s.Add(sources...)
+	return s
+}
+
+// Add exports the stats of more caches
+func (s *StatsCollector) Add(sources ...StatsSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, source := range sources {
+		if source != nil {
+			s.sources = append(s.sources, source)
+		}
+	}
+}
+
+// WriteTo writes the counters of every cache in the Prometheus text exposition format
+func (s *StatsCollector) WriteTo(w io.Writer) (int64, error) {
+	s.mu.Lock()
+	byPrefix := make(map[string]Stats, len(s.sources))
+	for _, source := range s.sources {
+		st, prefix := source.Stats(), source.KeyPrefix()
+		sum := byPrefix[prefix]
+		sum.Hits += st.Hits
+		sum.Misses += st.Misses
+		sum.Sets += st.Sets
+		sum.Deletes += st.Deletes
+		sum.Errors += st.Errors
+		byPrefix[prefix] = sum
+	}
+	s.mu.Unlock()
+
+	prefixes := make([]string, 0, len(byPrefix))
+	for prefix := range byPrefix {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	var b strings.Builder
+	counters := []struct {
+		name, help string
+		value      func(Stats) int64
+	}{
+		{"hits", "Cache reads that found their key.", func(st Stats) int64 { return st.Hits }},
+		{"misses", "Cache reads that did not find their key.", func(st Stats) int64 { return st.Misses }},
+		{"sets", "Cache entries written.", func(st Stats) int64 { return st.Sets }},
+		{"deletes", "Cache deletions.", func(st Stats) int64 { return st.Deletes }},
+		{"errors", "Cache operations that failed.", func(st Stats) int64 { return st.Errors }},
+	}
+	for _, counter := range counters {
+		name := fmt.Sprintf("%s_cache_%s_total", s.namespace, counter.name)
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, counter.help)
+		fmt.Fprintf(&b, "# TYPE %s counter\n", name)
+		for _, prefix := range prefixes {
+			fmt.Fprintf(&b, "%s{prefix=\"%s\"} %d\n", name, labelEscaper.Replace(prefix), counter.value(byPrefix[prefix]))
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the counters, so the collector can be mounted at /metrics next to a
+// metrics.PrometheusRecorder
+func (s *StatsCollector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = s.WriteTo(w)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestRedisCache_Stats(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewCache(client, "stats:")
+	var got string
+	_ = c.Set(ctx, "a", "1", time.Minute)
+	_ = c.Get(ctx, "a", &got)
+	_ = c.Get(ctx, "a", &got)
+	_ = c.Get(ctx, "missing", &got)
+	_, _ = c.Add(ctx, "a", "2", 0)
+	_, _ = c.Add(ctx, "b", "2", 0)
+	_ = c.Del(ctx, "a")
+	_, _ = c.Incr(ctx, "n")
+	mock.SetShouldFail(true)
+	_ = c.Get(ctx, "a", &got)
+	mock.SetShouldFail(false)
+
+	want := Stats{Hits: 2, Misses: 1, Sets: 2, Deletes: 1, Errors: 1}
+	if st := c.Stats(); st != want {
+		t.Errorf("Stats() = %+v, want %+v", st, want)
+	}
+	if r := c.Stats().HitRatio(); r < 0.66 || r > 0.67 {
+		t.Errorf("HitRatio() = %v, want 2/3", r)
+	}
+	if r := (Stats{}).HitRatio(); r != 0 {
+		t.Errorf("HitRatio() without reads = %v, want 0", r)
+	}
+
+	// Wrappers count on the cache they wrap
+	lru := NewLRUCache(client, "{stats-lru}:", 10)
+	_ = TypedOf[int](lru).Set(ctx, "k", 1, 0)
+	_, _ = TypedOf[int](lru).Get(ctx, "k")
+	if st := lru.Stats(); st.Sets != 1 || st.Hits != 1 {
+		t.Errorf("LRU Stats() through Typed = %+v, want one set and one hit", st)
+	}
+	profiles := NewHashCache[hashProfile](client, "stats-hash:")
+	_, _ = profiles.Get(ctx, "missing")
+	if st := profiles.Cache().Stats(); st.Misses != 1 {
+		t.Errorf("HashCache Stats() = %+v, want one miss", st)
+	}
+}
+
+func TestRedisCache_StatsDegraded(t *testing.T) {
+	mockClient, _ := testutil.NewMockRedisClient()
+	defer func() { _ = mockClient.Close() }()
+	client := &degradedClient{UniversalClient: mockClient, degraded: true}
+	ctx := context.Background()
+
+	c := NewCache(client, "stats:")
+	var got string
+	_ = c.Set(ctx, "a", "1", 0)
+	_ = c.Get(ctx, "a", &got)
+	if st := c.Stats(); st != (Stats{Misses: 1}) {
+		t.Errorf("Stats() while degraded = %+v, want only a miss", st)
+	}
+}
+
+func TestStatsCollector(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	users := NewCache(client, "users:")
+	usersLRU := NewLRUCache(client, "users:", 10)
+	sessions := NewCache(client, `se"ss:`)
+	var got string
+	_ = users.Set(ctx, "a", "1", 0)
+	_ = users.Get(ctx, "a", &got)
+	_ = usersLRU.Get(ctx, "a", &got)
+	_ = sessions.Get(ctx, "missing", &got)
+
+	collector := NewStatsCollector("", users, usersLRU)
+	collector.Add(sessions, nil)
+	rec := httptest.NewRecorder()
+	collector.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE redis_kit_cache_hits_total counter",
+		`redis_kit_cache_hits_total{prefix="users:"} 2`,
+		`redis_kit_cache_misses_total{prefix="se\"ss:"} 1`,
+		`redis_kit_cache_sets_total{prefix="users:"} 1`,
+		`redis_kit_cache_errors_total{prefix="users:"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("collector output lacks %q:\n%s", line, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+
+	var b strings.Builder
+	if n, err := NewStatsCollector("app").WriteTo(&b); err != nil || n != int64(b.Len()) || !strings.Contains(b.String(), "app_cache_hits_total") {
+		t.Errorf("WriteTo() = %d, %v, %q, want the counters under the namespace", n, err, b.String())
+	}
+}
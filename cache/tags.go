@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// DefaultTagKeyPrefix is appended to the key prefix to name the sets of tagged keys; cache keys
+// must not start with it
+const DefaultTagKeyPrefix = "__tag:"
+
+// tagAddScript adds ARGV[1] to the tag set KEYS[1], keeping the set at least as long as the
+// entry's TTL ARGV[2] in milliseconds, or without expiry when the entry has none
+const tagAddScript = `
+-- redis-kit:cache-tag-add
+local existed = redis.call("exists", KEYS[1]) == 1
+local ttl = redis.call("pttl", KEYS[1])
+redis.call("sadd", KEYS[1], ARGV[1])
+local want = tonumber(ARGV[2])
+if want <= 0 then
+	redis.call("persist", KEYS[1])
+elseif not existed or (ttl >= 0 and ttl < want) then
+	redis.call("pexpire", KEYS[1], want)
+end
+return 1
+`
+
+// TagKey returns the Redis key of the set holding the keys tagged with tag
+func (c *RedisCache) TagKey(tag string) string {
+	return c.Key(DefaultTagKeyPrefix + tag)
+}
+
+// tagKey returns TagKey of tag within the scope of ctx, if any; the scope follows the tag
+// prefix, so tag sets stay out of ScanKeys and of a scoped DelPrefix
+func (c *RedisCache) tagKey(ctx context.Context, tag string) string {
+	return c.TagKey(PrefixFromContext(ctx) + tag)
+}
+
+// SetWithTags stores a value as Set does and tags it, so that InvalidateTag of any of tags
+// deletes it, e.g. every entry derived from a user tagged "user:123". The tags are recorded
+// before the value is written, so a concurrent InvalidateTag never misses the entry. Tags are
+// only dropped by InvalidateTag: an entry overwritten with Set keeps its earlier tags
+func (c *RedisCache) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error {
+	if err := c.tag(ctx, key, ttl, tags); err != nil {
+		return err
+	}
+	return c.Set(ctx, key, value, ttl)
+}
+
+// SetWithTags stores a value as Set does and tags it, as RedisCache.SetWithTags does
+func (c *LRUCache) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error {
+	if err := c.tag(ctx, key, ttl, tags); err != nil {
+		return err
+	}
+	return c.Set(ctx, key, value, ttl)
+}
+
+// tag adds key to the sets of tags, kept for the longest TTL jitter can give the entry; each
+// tag set is written on its own, as tags may live in different cluster slots
+func (c *RedisCache) tag(ctx context.Context, key string, ttl time.Duration, tags []string) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	// The write that follows reports a degraded cache
+	if len(tags) == 0 || utils.Degraded(c.client) {
+		return nil
+	}
+
+	fullKey := c.buildKey(ctx, key)
+	start := time.Now()
+	err := c.do(ctx, func(ctx context.Context) error {
+		pipe := c.client.Pipeline()
+		for _, tag := range tags {
+			pipe.Eval(ctx, tagAddScript, []string{c.tagKey(ctx, tag)}, fullKey, c.maxTTL(ttl).Milliseconds())
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+	c.observe("tag", metrics.Outcome(err), start)
+	if err != nil {
+		return fmt.Errorf("failed to tag cache entry: %w", err)
+	}
+	return nil
+}
+
+// InvalidateTag deletes every entry tagged with tag and returns the number of entries deleted,
+// partial on error. Entries are deleted in batches, each removed from the tag set with them,
+// so entries tagged while it runs are kept for the next invalidation
+func (c *RedisCache) InvalidateTag(ctx context.Context, tag string) (int64, error) {
+	if c.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("invalidate_tag") {
+		return 0, nil
+	}
+
+	tagKey := c.tagKey(ctx, tag)
+	start := time.Now()
+	var keys []string
+	err := c.do(ctx, func(ctx context.Context) error {
+		var membersErr error
+		keys, membersErr = c.client.SMembers(ctx, tagKey).Result()
+		return membersErr
+	})
+
+	var deleted int64
+	if err == nil {
+		for _, batch := range utils.Chunk(keys, utils.DefaultDeleteBatchSize) {
+			var n int64
+			n, err = c.unlink(ctx, batch, func(pipe redis.Pipeliner, key string) {
+				pipe.SRem(ctx, tagKey, key)
+			})
+			deleted += n
+			if err != nil {
+				break
+			}
+		}
+	}
+	c.observe("invalidate_tag", metrics.Outcome(err), start)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to invalidate tag: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestRedisCache_Tags(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewCache(client, "app:")
+	_ = c.SetWithTags(ctx, "profile:1", "p", time.Minute, "user:1", "team:x")
+	_ = c.SetWithTags(ctx, "avatar:1", "a", time.Minute, "user:1")
+	_ = c.SetWithTags(ctx, "roster:x", "r", time.Minute, "team:x")
+	_ = c.Set(ctx, "plain", "v", time.Minute)
+
+	if members, _ := client.SMembers(ctx, c.TagKey("user:1")).Result(); len(members) != 2 {
+		t.Errorf("tag set members = %v, want 2 full keys", members)
+	}
+	keys, _ := c.Keys(ctx, "")
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"avatar:1", "plain", "profile:1", "roster:x"}) {
+		t.Errorf("Keys() = %v, want the entries without tag sets", keys)
+	}
+
+	if n, err := c.InvalidateTag(ctx, "user:1"); err != nil || n != 2 {
+		t.Fatalf("InvalidateTag(user:1) = %d, %v, want 2", n, err)
+	}
+	for key, want := range map[string]bool{"profile:1": false, "avatar:1": false, "roster:x": true, "plain": true} {
+		if ok, _ := c.Exists(ctx, key); ok != want {
+			t.Errorf("Exists(%s) after InvalidateTag = %v, want %v", key, ok, want)
+		}
+	}
+	if ok, _ := client.Exists(ctx, c.TagKey("user:1")).Result(); ok != 0 {
+		t.Error("InvalidateTag() left the emptied tag set")
+	}
+
+	// Only entries still present are counted
+	if n, err := c.InvalidateTag(ctx, "team:x"); err != nil || n != 1 {
+		t.Errorf("InvalidateTag(team:x) = %d, %v, want 1", n, err)
+	}
+	if n, err := c.InvalidateTag(ctx, "unknown"); err != nil || n != 0 {
+		t.Errorf("InvalidateTag(unknown) = %d, %v, want 0", n, err)
+	}
+
+	mock.SetShouldFail(true)
+	if err := c.SetWithTags(ctx, "k", "v", 0, "t"); err == nil {
+		t.Error("SetWithTags() with failing Redis error = nil, want error")
+	}
+	if _, err := c.InvalidateTag(ctx, "t"); err == nil {
+		t.Error("InvalidateTag() with failing Redis error = nil, want error")
+	}
+	if _, err := NewCache(nil, "x:").InvalidateTag(ctx, "t"); err == nil {
+		t.Error("InvalidateTag() with nil client error = nil, want error")
+	}
+}
+
+func TestRedisCache_TagTTL(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewCache(client, "app:")
+	tagTTL := func() time.Duration {
+		return client.PTTL(ctx, c.TagKey("t")).Val()
+	}
+
+	_ = c.SetWithTags(ctx, "long", "v", 5*time.Minute, "t")
+	_ = c.SetWithTags(ctx, "short", "v", time.Minute, "t")
+	if ttl := tagTTL(); ttl <= 4*time.Minute {
+		t.Errorf("tag set TTL = %v, want the longest entry TTL kept", ttl)
+	}
+	_ = c.SetWithTags(ctx, "forever", "v", 0, "t")
+	if ttl := tagTTL(); ttl != -1 {
+		t.Errorf("tag set TTL with a persistent entry = %v, want no expiry", ttl)
+	}
+}
+
+func TestLRUCache_Tags(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewLRUCache(client, "{lru}:", 10)
+	_ = c.SetWithTags(ctx, "a", 1, 0, "t")
+	_ = c.SetWithTags(ctx, "b", 2, 0, "t")
+	if n, _ := c.Len(ctx); n != 2 {
+		t.Errorf("Len() after SetWithTags = %d, want the entries tracked", n)
+	}
+	if n, err := c.InvalidateTag(ctx, "t"); err != nil || n != 2 {
+		t.Fatalf("InvalidateTag() = %d, %v, want 2", n, err)
+	}
+	if n, _ := c.Len(ctx); n != 0 {
+		t.Errorf("Len() after InvalidateTag = %d, want 0", n)
+	}
+}
+
+func TestRedisCache_TagsDegraded(t *testing.T) {
+	mockClient, _ := testutil.NewMockRedisClient()
+	defer func() { _ = mockClient.Close() }()
+	client := &degradedClient{UniversalClient: mockClient, degraded: true}
+	ctx := context.Background()
+
+	c := NewCache(client, "app:")
+	if err := c.SetWithTags(ctx, "k", "v", 0, "t"); err != nil {
+		t.Errorf("SetWithTags() while degraded error = %v, want nil", err)
+	}
+	if ok, _ := mockClient.Exists(ctx, c.TagKey("t")).Result(); ok != 0 {
+		t.Error("SetWithTags() while degraded wrote the tag set")
+	}
+	if n, err := c.InvalidateTag(ctx, "t"); err != nil || n != 0 {
+		t.Errorf("InvalidateTag() while degraded = %d, %v, want 0", n, err)
+	}
+}
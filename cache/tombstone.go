@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/soulteary/redis-kit/metrics"
+)
+
+// tombstoneValue is stored for keys deleted ahead of the source of truth; as with
+// negativeValue, no codec encodes a value to it
+const tombstoneValue = "\x00redis-kit:tombstone"
+
+// fillScript stores a loaded value unless the key holds a tombstone, which must outlive the
+// loads racing with the delete it stands for
+// KEYS[1] = key; ARGV[1] = value, ARGV[2] = TTL in milliseconds (0: none), ARGV[3] = tombstone
+// Returns 1 when the value was stored, 0 when the key is tombstoned
+const fillScript = `
+-- redis-kit:cache-fill
+if redis.call("type", KEYS[1]).ok == "string" and redis.call("get", KEYS[1]) == ARGV[3] then
+	return 0
+end
+if tonumber(ARGV[2]) > 0 then
+	redis.call("set", KEYS[1], ARGV[1], "px", ARGV[2])
+else
+	redis.call("set", KEYS[1], ARGV[1])
+end
+return 1
+`
+
+// SetTombstone marks key as deleted for ttl: Get then fails with ErrTombstoned, which also
+// matches ErrNotFound, and values loaded by GetOrSet, GetOrSetMulti, or a Preloader are not
+// stored over it, so a load that read the source of truth before a delete cannot bring the
+// deleted object back. Make ttl longer than the source of truth takes to apply the delete.
+// Set replaces the tombstone, as an explicit write wins
+func (c *RedisCache) SetTombstone(ctx context.Context, key string, ttl time.Duration) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("set_tombstone") {
+		return nil
+	}
+	return c.setRaw(ctx, "set_tombstone", key, []byte(tombstoneValue), ttl)
+}
+
+// SetTombstone marks key as deleted for ttl, as RedisCache.SetTombstone does; the entry counts
+// toward the cap
+func (c *LRUCache) SetTombstone(ctx context.Context, key string, ttl time.Duration) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("set_tombstone") {
+		return nil
+	}
+	return c.setRaw(ctx, "set_tombstone", key, []byte(tombstoneValue), ttl)
+}
+
+// markerErr returns the error a read of key reports when data is a marker instead of a
+// value, a negative entry or a tombstone, and nil otherwise
+func markerErr(key string, data []byte) error {
+	switch string(data) {
+	case negativeValue:
+		return fmt.Errorf("%w: %s", ErrNegativeHit, key)
+	case tombstoneValue:
+		return fmt.Errorf("%w: %s", ErrTombstoned, key)
+	}
+	return nil
+}
+
+// readOutcome returns the outcome recorded for a read that got data or failed with err; a
+// marker is a miss, as the caller gets an error matching ErrNotFound for it
+func readOutcome(data []byte, err error) string {
+	if err != nil {
+		return metrics.Outcome(err)
+	}
+	if s := string(data); s == negativeValue || s == tombstoneValue {
+		return metrics.OutcomeMiss
+	}
+	return metrics.OutcomeHit
+}
+
+// filler is a cache of this package that stores loaded values without replacing tombstones
+type filler interface {
+	fill(ctx context.Context, key string, value interface{}, ttl, cost time.Duration) (bool, error)
+}
+
+// fill stores a value loaded from the source of truth unless key is tombstoned, along with
+// the loader's duration when cost is positive, and reports whether it did
+func (c *RedisCache) fill(ctx context.Context, key string, value interface{}, ttl, cost time.Duration) (bool, error) {
+	if c.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("set") {
+		return false, nil
+	}
+	data, err := c.encode(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	if cost > 0 {
+		data = withCost(data, cost)
+	}
+	data, ok, sizeErr := c.fit("set", data)
+	if !ok {
+		return false, sizeErr
+	}
+
+	fullKey := c.buildKey(ctx, key)
+	start := time.Now()
+	var stored bool
+	err = c.do(ctx, func(ctx context.Context) error {
+		n, err := c.client.Eval(ctx, fillScript, []string{fullKey}, data, c.jitter(ttl).Milliseconds(), tombstoneValue).Int64()
+		stored = n == 1
+		return err
+	})
+	if stored {
+		c.invalidate(fullKey)
+	}
+	c.observe("set", metrics.Outcome(err), start)
+	if err != nil {
+		return false, fmt.Errorf("failed to set cache: %w", err)
+	}
+	return stored, sizeErr
+}
+
+// fill stores a loaded value unless key is tombstoned, marking it most recently used
+func (c *LRUCache) fill(ctx context.Context, key string, value interface{}, ttl, cost time.Duration) (bool, error) {
+	if c.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("set") {
+		return false, nil
+	}
+	data, err := c.encode(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	if cost > 0 {
+		data = withCost(data, cost)
+	}
+	data, ok, sizeErr := c.fit("set", data)
+	if !ok {
+		return false, sizeErr
+	}
+	stored, err := c.store(ctx, "set", key, data, ttl, lruFill)
+	if err != nil {
+		return false, fmt.Errorf("failed to set cache: %w", err)
+	}
+	return stored, sizeErr
+}
+
+// fill stores a loaded value under the current version unless key is tombstoned there
+func (v *VersionedCache) fill(ctx context.Context, key string, value interface{}, ttl, cost time.Duration) (bool, error) {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return false, err
+	}
+	return c.fill(ctx, key, value, ttl, cost)
+}
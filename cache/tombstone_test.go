@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestSetTombstone(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	caches := map[string]Cache{
+		"redis":     NewCache(client, "tomb:"),
+		"lru":       NewLRUCache(client, "{tomb-lru}:", 10),
+		"versioned": NewVersionedCache(client, "tomb-v:"),
+	}
+	for name, c := range caches {
+		t.Run(name, func(t *testing.T) {
+			_ = c.Set(ctx, "user:1", "alice", 0)
+			if err := c.SetTombstone(ctx, "user:1", time.Minute); err != nil {
+				t.Fatalf("SetTombstone() error = %v, want nil", err)
+			}
+			var got string
+			err := c.Get(ctx, "user:1", &got)
+			if !errors.Is(err, ErrTombstoned) || !errors.Is(err, ErrNotFound) || errors.Is(err, ErrNegativeHit) {
+				t.Errorf("Get() of a tombstone error = %v, want ErrTombstoned matching ErrNotFound", err)
+			}
+			if ttl, _ := c.TTL(ctx, "user:1"); ttl <= 0 || ttl > time.Minute {
+				t.Errorf("TTL() of a tombstone = %v, want up to a minute", ttl)
+			}
+
+			// A load does not resurrect the key, and is not even run
+			users := TypedOf[string](c)
+			loads := 0
+			if _, err := users.GetOrSet(ctx, "user:1", 0, func(ctx context.Context) (string, error) {
+				loads++
+				return "alice", nil
+			}); !errors.Is(err, ErrTombstoned) || loads != 0 {
+				t.Errorf("GetOrSet() of a tombstone = %v with %d loads, want ErrTombstoned without loading", err, loads)
+			}
+			if stored, err := users.fill(ctx, "user:1", "alice", 0, 0); err != nil || stored {
+				t.Errorf("fill() over a tombstone = %v, %v, want nothing stored", stored, err)
+			}
+			if err := c.Get(ctx, "user:1", &got); !errors.Is(err, ErrTombstoned) {
+				t.Errorf("Get() after a racing load error = %v, want ErrTombstoned", err)
+			}
+			if stored, err := users.fill(ctx, "user:2", "bob", 0, 0); err != nil || !stored {
+				t.Errorf("fill() of a missing key = %v, %v, want it stored", stored, err)
+			}
+
+			// Set replaces the tombstone
+			_ = c.Set(ctx, "user:1", "alicia", 0)
+			if err := c.Get(ctx, "user:1", &got); err != nil || got != "alicia" {
+				t.Errorf("Get() after Set = %q, %v, want alicia", got, err)
+			}
+		})
+	}
+
+	mock.SetShouldFail(true)
+	if err := NewCache(client, "tomb:").SetTombstone(ctx, "k", time.Minute); err == nil {
+		t.Error("SetTombstone() with failing Redis error = nil, want error")
+	}
+	mock.SetShouldFail(false)
+	if err := NewCache(nil, "tomb:").SetTombstone(ctx, "k", time.Minute); err == nil {
+		t.Error("SetTombstone() with nil client error = nil, want error")
+	}
+}
+
+func TestSetTombstone_Stats(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	// Markers are misses, as the caller gets ErrNotFound for them
+	caches := map[string]Cache{
+		"redis":  NewCache(client, "tomb-stats:"),
+		"lru":    NewLRUCache(client, "{tomb-stats-lru}:", 10),
+		"xfetch": NewCache(client, "tomb-stats-x:", WithEarlyRefresh(1)),
+	}
+	for name, c := range caches {
+		t.Run(name, func(t *testing.T) {
+			_ = c.SetTombstone(ctx, "gone", time.Minute)
+			_ = c.SetNegative(ctx, "absent", time.Minute)
+			_ = c.Set(ctx, "here", 1, 0)
+			counts := TypedOf[int](c)
+			for _, key := range []string{"gone", "absent", "here"} {
+				_, _ = counts.GetOrSet(ctx, key, 0, func(ctx context.Context) (int, error) { return 1, nil })
+			}
+			_, _ = counts.GetMulti(ctx, []string{"gone", "absent", "here"})
+			if st := c.(StatsSource).Stats(); st.Hits != 2 || st.Misses != 4 {
+				t.Errorf("Stats() after reading markers = %+v, want 2 hits and 4 misses", st)
+			}
+		})
+	}
+}
+
+func TestSetTombstone_Multi(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewCache(client, "tomb-multi:")
+	counts := TypedOf[int](c)
+	_ = counts.Set(ctx, "a", 1, 0)
+	_ = c.SetTombstone(ctx, "b", time.Minute)
+
+	result, err := counts.GetMulti(ctx, []string{"a", "b", "c"})
+	if err != nil || !errors.Is(result.Errors["b"], ErrTombstoned) || len(result.Missing()) != 1 {
+		t.Errorf("GetMulti() with a tombstone = %+v, %v, want b tombstoned and only c missing", result, err)
+	}
+	var loaded []string
+	got, err := counts.GetOrSetMulti(ctx, []string{"a", "b", "c"}, 0, func(ctx context.Context, missing []string) (map[string]int, error) {
+		loaded = missing
+		return map[string]int{"b": 2, "c": 3}, nil
+	})
+	if err != nil || !maps.Equal(got, map[string]int{"a": 1, "c": 3}) || len(loaded) != 1 {
+		t.Errorf("GetOrSetMulti() with a tombstone = %v, %v loading %v, want b left out", got, err, loaded)
+	}
+
+	// Preloads skip tombstoned keys
+	progress, err := NewPreloader(c, 0, func(ctx context.Context, key string) (int, error) {
+		return 9, nil
+	}).Run(ctx, []string{"b", "d"})
+	if err != nil || progress.Loaded != 1 || progress.Skipped != 1 {
+		t.Errorf("Run() with a tombstone = %+v, %v, want d loaded and b skipped", progress, err)
+	}
+	if _, err := counts.Get(ctx, "b"); !errors.Is(err, ErrTombstoned) {
+		t.Errorf("Get() after preloading error = %v, want ErrTombstoned", err)
+	}
+}
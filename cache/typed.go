@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Typed is a cache of values of type T: Get returns the decoded value instead of filling a
+// destination pointer, and Set only accepts a T, so a key cannot be read back as another type
+// by mistake. Values are encoded with the codec of the Cache it wraps
+type Typed[T any] struct {
+	cache   Cache
+	flights flightGroup[T]
+}
+
+// NewTyped creates a Redis cache of values of type T with the given client, key prefix, and
+// options, as NewCache does
+func NewTyped[T any](client redis.UniversalClient, keyPrefix string, opts ...Option) *Typed[T] {
+	return &Typed[T]{cache: NewCache(client, keyPrefix, opts...)}
+}
+
+// TypedOf returns a typed view of c, e.g. of an LRUCache; it shares c's keys
+func TypedOf[T any](c Cache) *Typed[T] {
+	return &Typed[T]{cache: c}
+}
+
+// Cache returns the untyped cache, for operations Typed does not cover, e.g. TTL and Expire
+func (t *Typed[T]) Cache() Cache {
+	return t.cache
+}
+
+// Set stores value with the given TTL
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, ttl time.Duration) error {
+	if t.cache == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	return t.cache.Set(ctx, key, value, ttl)
+}
+
+// Get returns the value stored at key, or the zero value and an error wrapping ErrNotFound
+// when the key is missing
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, error) {
+	var value T
+	if t.cache == nil {
+		return value, fmt.Errorf("redis client is nil")
+	}
+	if err := t.cache.Get(ctx, key, &value); err != nil {
+		var zero T
+		return zero, err
+	}
+	return value, nil
+}
+
+// Del deletes key
+func (t *Typed[T]) Del(ctx context.Context, key string) error {
+	if t.cache == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	return t.cache.Del(ctx, key)
+}
+
+// Exists checks if key exists
+func (t *Typed[T]) Exists(ctx context.Context, key string) (bool, error) {
+	if t.cache == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+	return t.cache.Exists(ctx, key)
+}
+
+// GetMulti reads keys in one round trip and reports, key by key, the values found, the keys
+// missing, and the keys that could not be read or decoded, so one bad entry does not fail the
+// rest; Missing lists the keys to load from the source of truth. It fails as a whole only
+// when the round trip does. Caches of other packages are read key by key
+func (t *Typed[T]) GetMulti(ctx context.Context, keys []string) (MultiResult[T], error) {
+	result := MultiResult[T]{Hits: make(map[string]T, len(keys)), Errors: map[string]error{}, keys: keys}
+	if t.cache == nil {
+		return result, fmt.Errorf("redis client is nil")
+	}
+	mg, ok := t.cache.(multiGetter)
+	if !ok {
+		for _, key := range keys {
+			value, err := t.Get(ctx, key)
+			result.add(key, value, err)
+		}
+		return result, nil
+	}
+	entries, err := mg.getMulti(ctx, keys)
+	if err != nil {
+		return result, err
+	}
+	for i, entry := range entries {
+		var value T
+		if entry.err == nil {
+			if err := mg.decode(entry.data, &value); err != nil {
+				entry.err = fmt.Errorf("failed to unmarshal value: %w", err)
+			}
+		}
+		result.add(keys[i], value, entry.err)
+	}
+	return result, nil
+}
+
+// GetOrSet returns the value stored at key, or loads it with loader and stores it with the
+// given TTL when the key is missing. Concurrent misses of a key coalesce into one load per
+// Typed, whose result every caller gets, so a popular key expiring does not send a stampede
+// to the backing store; a caller whose ctx is done stops waiting. The cache is best effort
+// here: when reading it fails, the value is loaded, and a failed write is dropped. Errors of
+// loader are returned as is and nothing is stored, except that an error matching ErrNotFound
+// is cached for the cache's WithNegativeTTL, if set, and the key then fails with ErrNegativeHit
+// without calling loader. A tombstoned key fails with ErrTombstoned without calling loader,
+// and a value loaded while the key was tombstoned is returned but not stored. With
+// WithEarlyRefresh, a hit may instead reload the value before it expires; if that reload
+// fails, the cached value is returned
+func (t *Typed[T]) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	if t.cache == nil {
+		var zero T
+		return zero, fmt.Errorf("redis client is nil")
+	}
+	value, refresh, err := t.lookup(ctx, key)
+	if (err == nil && !refresh) || errors.Is(err, ErrNegativeHit) || errors.Is(err, ErrTombstoned) {
+		return value, err
+	}
+	if err := ctx.Err(); err != nil {
+		return value, err
+	}
+	// The same key under different scopes of ContextWithPrefix names different entries
+	loaded, err := t.flights.do(ctx, PrefixFromContext(ctx)+key, func() (T, error) {
+		start := time.Now()
+		value, err := loader(ctx)
+		if errors.Is(err, ErrNotFound) {
+			if nc, ok := t.cache.(interface{ NegativeTTL() time.Duration }); ok && nc.NegativeTTL() > 0 {
+				_ = t.cache.SetNegative(ctx, key, nc.NegativeTTL())
+			}
+		}
+		if err != nil {
+			return value, err
+		}
+		var cost time.Duration
+		if rc, ok := t.cache.(*RedisCache); ok && rc.earlyRefresh > 0 {
+			cost = time.Since(start)
+		}
+		_, _ = t.fill(ctx, key, value, ttl, cost)
+		return value, nil
+	})
+	if err != nil && refresh && !errors.Is(err, ErrNotFound) {
+		return value, nil
+	}
+	return loaded, err
+}
+
+// GetOrSetMulti returns the values stored at keys, reading them with one GetMulti, and loads
+// the keys missing or unreadable, in the order requested, with a single call of batchLoader,
+// storing what it returns with the given TTL, so a page of N misses costs one query instead
+// of N. Keys batchLoader leaves out of its map are not in the result either; they are cached
+// as missing for the cache's WithNegativeTTL, if set, and keys cached as missing or
+// tombstoned are left out without being loaded. As with GetOrSet, the cache is best effort: when the read fails
+// every key is loaded, and failed writes are dropped. An error of batchLoader is returned
+// with the values found in the cache. Unlike GetOrSet, loads are not coalesced with
+// concurrent callers and WithEarlyRefresh does not apply
+func (t *Typed[T]) GetOrSetMulti(ctx context.Context, keys []string, ttl time.Duration, batchLoader func(ctx context.Context, missing []string) (map[string]T, error)) (map[string]T, error) {
+	if t.cache == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	result, err := t.GetMulti(ctx, keys)
+	if err != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result = MultiResult[T]{Hits: make(map[string]T, len(keys)), keys: keys}
+	}
+	var missing []string
+	for _, key := range result.Missing() {
+		if !slices.Contains(missing, key) {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return result.Hits, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return result.Hits, err
+	}
+
+	loaded, err := batchLoader(ctx, missing)
+	if err != nil {
+		return result.Hits, err
+	}
+	var negativeTTL time.Duration
+	if nc, ok := t.cache.(interface{ NegativeTTL() time.Duration }); ok {
+		negativeTTL = nc.NegativeTTL()
+	}
+	for _, key := range missing {
+		value, ok := loaded[key]
+		switch {
+		case ok:
+			result.Hits[key] = value
+			_, _ = t.fill(ctx, key, value, ttl, 0)
+		case negativeTTL > 0:
+			_ = t.cache.SetNegative(ctx, key, negativeTTL)
+		}
+	}
+	return result.Hits, nil
+}
+
+// fill stores a value loaded from the source of truth and reports whether it did; caches of
+// this package keep tombstones, those of other packages are written with Set
+func (t *Typed[T]) fill(ctx context.Context, key string, value T, ttl, cost time.Duration) (bool, error) {
+	if f, ok := t.cache.(filler); ok {
+		return f.fill(ctx, key, value, ttl, cost)
+	}
+	if err := t.cache.Set(ctx, key, value, ttl); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// lookup returns the value stored at key and, for a RedisCache with early refresh, whether
+// GetOrSet should reload it now
+func (t *Typed[T]) lookup(ctx context.Context, key string) (T, bool, error) {
+	rc, ok := t.cache.(*RedisCache)
+	if !ok || rc.earlyRefresh <= 0 {
+		value, err := t.Get(ctx, key)
+		return value, false, err
+	}
+	var value T
+	data, cost, ttl, err := rc.getEntry(ctx, key)
+	if err != nil {
+		return value, false, err
+	}
+	if err := rc.decode(data, &value); err != nil {
+		var zero T
+		return zero, false, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+	return value, rc.refreshEarly(cost, ttl), nil
+}
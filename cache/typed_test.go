@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+type typedUser struct {
+	ID   string
+	Name string
+	Tags []string
+}
+
+func TestTyped(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	users := NewTyped[typedUser](client, "users:")
+
+	want := typedUser{ID: "1", Name: "Alice", Tags: []string{"admin"}}
+	if err := users.Set(ctx, "1", want, time.Minute); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	got, err := users.Get(ctx, "1")
+	if err != nil {
+		t.Fatalf("Get() error = %v, want nil", err)
+	}
+	if got.ID != want.ID || got.Name != want.Name || len(got.Tags) != 1 || got.Tags[0] != "admin" {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+	if exists, err := users.Exists(ctx, "1"); err != nil || !exists {
+		t.Errorf("Exists() = %v, %v, want true", exists, err)
+	}
+
+	// The untyped cache shares the keys
+	var raw typedUser
+	if err := users.Cache().Get(ctx, "1", &raw); err != nil || raw.Name != "Alice" {
+		t.Errorf("Cache().Get() = %+v, %v, want the stored user", raw, err)
+	}
+
+	if err := users.Del(ctx, "1"); err != nil {
+		t.Fatalf("Del() error = %v, want nil", err)
+	}
+	got, err = users.Get(ctx, "1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Del error = %v, want ErrNotFound", err)
+	}
+	if got.ID != "" || got.Tags != nil {
+		t.Errorf("Get() after Del = %+v, want the zero value", got)
+	}
+}
+
+func TestTyped_DecodeError(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if err := NewCache(client, "n:").Set(ctx, "k", "not a number", 0); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	got, err := NewTyped[int](client, "n:").Get(ctx, "k")
+	if err == nil || got != 0 {
+		t.Errorf("Get() of a string as int = %d, %v, want 0 and an error", got, err)
+	}
+}
+
+func TestTypedOf(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	counts := TypedOf[int](NewLRUCache(client, "lru:", 10))
+	if err := counts.Set(ctx, "a", 42, 0); err != nil {
+		t.Fatalf("Set() through LRUCache error = %v, want nil", err)
+	}
+	if got, err := counts.Get(ctx, "a"); err != nil || got != 42 {
+		t.Errorf("Get() through LRUCache = %d, %v, want 42", got, err)
+	}
+
+	nilCache := TypedOf[int](nil)
+	if err := nilCache.Set(ctx, "a", 1, 0); err == nil {
+		t.Error("Set() with nil cache error = nil, want error")
+	}
+	if _, err := nilCache.Get(ctx, "a"); err == nil {
+		t.Error("Get() with nil cache error = nil, want error")
+	}
+	if err := nilCache.Del(ctx, "a"); err == nil {
+		t.Error("Del() with nil cache error = nil, want error")
+	}
+	if _, err := nilCache.Exists(ctx, "a"); err == nil {
+		t.Error("Exists() with nil cache error = nil, want error")
+	}
+}
+
+func TestTyped_GetOrSet(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	users := NewTyped[typedUser](client, "users:")
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (typedUser, error) {
+		loads.Add(1)
+		<-release
+		return typedUser{ID: "1", Name: "Alice"}, nil
+	}
+
+	// Concurrent misses share one load
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make(chan typedUser, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := users.GetOrSet(ctx, "1", time.Minute, loader)
+			if err != nil {
+				t.Errorf("GetOrSet() error = %v, want nil", err)
+			}
+			results <- u
+		}()
+	}
+	for loads.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+	for u := range results {
+		if u.Name != "Alice" {
+			t.Errorf("GetOrSet() = %+v, want the loaded user", u)
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loader ran %d times, want 1", n)
+	}
+
+	// The loaded value is stored, so the next call hits
+	u, err := users.GetOrSet(ctx, "1", time.Minute, func(ctx context.Context) (typedUser, error) {
+		t.Error("loader ran on a hit")
+		return typedUser{}, nil
+	})
+	if err != nil || u.Name != "Alice" {
+		t.Errorf("GetOrSet() on a hit = %+v, %v, want the stored user", u, err)
+	}
+}
+
+func TestTyped_GetOrSetErrors(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	counts := NewTyped[int](client, "n:")
+
+	// Loader errors are returned and nothing is stored
+	errLoad := errors.New("database down")
+	if _, err := counts.GetOrSet(ctx, "k", 0, func(ctx context.Context) (int, error) { return 0, errLoad }); !errors.Is(err, errLoad) {
+		t.Errorf("GetOrSet() with failing loader error = %v, want %v", err, errLoad)
+	}
+	if exists, _ := counts.Exists(ctx, "k"); exists {
+		t.Error("GetOrSet() stored a value the loader failed to produce")
+	}
+
+	// A failing cache does not fail the call
+	mock.SetShouldFail(true)
+	if got, err := counts.GetOrSet(ctx, "k", 0, func(ctx context.Context) (int, error) { return 7, nil }); err != nil || got != 7 {
+		t.Errorf("GetOrSet() with failing cache = %d, %v, want 7", got, err)
+	}
+	mock.SetShouldFail(false)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := counts.GetOrSet(canceled, "k", 0, func(ctx context.Context) (int, error) {
+		t.Error("loader ran with a done context")
+		return 0, nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetOrSet() with done context error = %v, want context.Canceled", err)
+	}
+
+	if _, err := TypedOf[int](nil).GetOrSet(ctx, "k", 0, nil); err == nil {
+		t.Error("GetOrSet() with nil cache error = nil, want error")
+	}
+}
+
+func TestFlightGroup(t *testing.T) {
+	var g flightGroup[int]
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	leader := make(chan any, 1)
+	go func() {
+		defer func() { leader <- recover() }()
+		_, _ = g.do(ctx, "k", func() (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	// A waiter gives up when its context is done
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := g.do(waitCtx, "k", func() (int, error) { return 1, nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("do() waiting past its deadline error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// A waiter gets ErrLoaderPanic, and the panic goes on in the leader
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := g.do(ctx, "k", func() (int, error) { return 1, nil })
+		waiter <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if err := <-waiter; !errors.Is(err, ErrLoaderPanic) {
+		t.Errorf("do() waiting on a panicking load error = %v, want ErrLoaderPanic", err)
+	}
+	if r := <-leader; r != "boom" {
+		t.Errorf("leader recovered %v, want the panic", r)
+	}
+
+	// The key is free again
+	if v, err := g.do(ctx, "k", func() (int, error) { return 2, nil }); err != nil || v != 2 {
+		t.Errorf("do() after the panic = %d, %v, want 2", v, err)
+	}
+}
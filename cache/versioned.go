@@ -0,0 +1,315 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+)
+
+// DefaultVersionKey is the key, relative to the key prefix, holding a VersionedCache's version
+const DefaultVersionKey = "__version"
+
+// VersionedCache is a cache whose keys carry a namespace version stored in Redis, e.g.
+// "users:v3:42" under the prefix "users:", so BumpVersion invalidates every entry at once in a
+// single INCR instead of scanning for them. Entries of older versions are no longer read and
+// go away as their TTL runs out, or with DelVersion. Each operation first reads the version,
+// one extra round trip unless WithVersionRefresh lets it reuse the last one read
+type VersionedCache struct {
+	base    *RedisCache // holds the version key and the options of every version
+	opts    []Option
+	current atomic.Pointer[versionState]
+}
+
+// versionState is the version last read and the cache of its keys
+type versionState struct {
+	version int64
+	cache   *RedisCache
+	read    time.Time
+}
+
+// NewVersionedCache creates a versioned cache with the given client, key prefix, and options,
+// which apply to the keys of every version as they do for NewCache
+func NewVersionedCache(client redis.UniversalClient, keyPrefix string, opts ...Option) *VersionedCache {
+	return &VersionedCache{base: NewCache(client, keyPrefix, opts...), opts: opts}
+}
+
+// VersionKey returns the Redis key holding the version
+func (v *VersionedCache) VersionKey() string {
+	return v.base.Key(DefaultVersionKey)
+}
+
+// NegativeTTL returns how long Typed.GetOrSet caches a miss of its loader, 0 when it does not
+func (v *VersionedCache) NegativeTTL() time.Duration {
+	return v.base.negativeTTL
+}
+
+// Version returns the current version, 0 until BumpVersion is first called
+func (v *VersionedCache) Version(ctx context.Context) (int64, error) {
+	state, err := v.load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return state.version, nil
+}
+
+// Cache returns the cache of the current version's keys, for operations VersionedCache does
+// not cover, e.g. DelPrefix within the version; it is not switched by a later BumpVersion
+func (v *VersionedCache) Cache(ctx context.Context) (*RedisCache, error) {
+	return v.cache(ctx)
+}
+
+// BumpVersion increments the version, invalidating every entry of the cache, and returns
+// the new version. While the client reports Redis as degraded, it fails rather than leave
+// stale entries readable
+func (v *VersionedCache) BumpVersion(ctx context.Context) (int64, error) {
+	c := v.base
+	if c.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("bump_version") {
+		return 0, fmt.Errorf("failed to bump version: redis is degraded")
+	}
+	start := time.Now()
+	var version int64
+	err := c.do(ctx, func(ctx context.Context) error {
+		var incrErr error
+		version, incrErr = c.client.Incr(ctx, v.VersionKey()).Result()
+		return incrErr
+	})
+	c.observe("bump_version", metrics.Outcome(err), start)
+	if err != nil {
+		return 0, fmt.Errorf("failed to bump version: %w", err)
+	}
+	v.use(version)
+	return version, nil
+}
+
+// DelVersion deletes the entries of version, e.g. an old one whose keys have no TTL, and
+// returns the number of keys deleted; under a scope of ContextWithPrefix, only the scope's
+// entries are deleted, as their keys put the scope after the version
+func (v *VersionedCache) DelVersion(ctx context.Context, version int64) (int64, error) {
+	return v.base.DelPrefix(ContextWithPrefix(ctx, versionPrefix(version)+PrefixFromContext(ctx)), "")
+}
+
+// versionPrefix returns the part of the key prefix naming version
+func versionPrefix(version int64) string {
+	return "v" + strconv.FormatInt(version, 10) + ":"
+}
+
+// cache returns the cache of the current version
+func (v *VersionedCache) cache(ctx context.Context) (*RedisCache, error) {
+	state, err := v.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return state.cache, nil
+}
+
+// load returns the current version and its cache, reading the version from Redis unless the
+// last one read is recent enough. While the client reports Redis as degraded, the last
+// version read is used, as the cache then neither reads nor writes
+func (v *VersionedCache) load(ctx context.Context) (*versionState, error) {
+	c := v.base
+	if c.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	state := v.current.Load()
+	if state != nil && c.versionRefresh > 0 && time.Since(state.read) < c.versionRefresh {
+		return state, nil
+	}
+	if c.degraded("version") {
+		if state != nil {
+			return state, nil
+		}
+		return v.use(0), nil
+	}
+	start := time.Now()
+	var version int64
+	err := c.read(ctx, func(ctx context.Context, client redis.Cmdable) error {
+		var getErr error
+		version, getErr = client.Get(ctx, v.VersionKey()).Int64()
+		if errors.Is(getErr, redis.Nil) {
+			version, getErr = 0, nil
+		}
+		return getErr
+	})
+	c.observe("version", metrics.Outcome(err), start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version: %w", err)
+	}
+	return v.use(version), nil
+}
+
+// use makes version current and returns its state
+func (v *VersionedCache) use(version int64) *versionState {
+	state := v.current.Load()
+	if state == nil || state.version != version {
+		prefix := v.base.keyPrefix + versionPrefix(version)
+		state = &versionState{version: version, cache: NewCache(v.base.client, prefix, v.opts...)}
+	} else {
+		state = &versionState{version: version, cache: state.cache}
+	}
+	state.read = time.Now()
+	v.current.Store(state)
+	return state
+}
+
+// Set stores a value under the current version
+func (v *VersionedCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, key, value, ttl)
+}
+
+// Get retrieves a value stored under the current version
+func (v *VersionedCache) Get(ctx context.Context, key string, dest interface{}) error {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Get(ctx, key, dest)
+}
+
+// SetBytes stores already serialized data as is under the current version
+func (v *VersionedCache) SetBytes(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return err
+	}
+	return c.SetBytes(ctx, key, data, ttl)
+}
+
+// GetBytes returns the bytes stored at key under the current version as is
+func (v *VersionedCache) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetBytes(ctx, key)
+}
+
+// Add stores a value under the current version only if key does not exist there
+func (v *VersionedCache) Add(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return false, err
+	}
+	return c.Add(ctx, key, value, ttl)
+}
+
+// SetNegative caches key as known to be missing under the current version
+func (v *VersionedCache) SetNegative(ctx context.Context, key string, ttl time.Duration) error {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return err
+	}
+	return c.SetNegative(ctx, key, ttl)
+}
+
+// SetTombstone marks key as deleted under the current version
+func (v *VersionedCache) SetTombstone(ctx context.Context, key string, ttl time.Duration) error {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return err
+	}
+	return c.SetTombstone(ctx, key, ttl)
+}
+
+// Del deletes key under the current version
+func (v *VersionedCache) Del(ctx context.Context, key string) error {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Del(ctx, key)
+}
+
+// Exists checks if key exists under the current version
+func (v *VersionedCache) Exists(ctx context.Context, key string) (bool, error) {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return false, err
+	}
+	return c.Exists(ctx, key)
+}
+
+// TTL returns the remaining time-to-live of key under the current version
+func (v *VersionedCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c.TTL(ctx, key)
+}
+
+// Expire sets the expiration time of key under the current version
+func (v *VersionedCache) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Expire(ctx, key, ttl)
+}
+
+// Persist removes the TTL of key under the current version
+func (v *VersionedCache) Persist(ctx context.Context, key string) error {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Persist(ctx, key)
+}
+
+// Touch sets the TTL of every key under the current version in one round trip
+func (v *VersionedCache) Touch(ctx context.Context, ttl time.Duration, keys ...string) (int64, error) {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c.Touch(ctx, ttl, keys...)
+}
+
+// Incr increments the counter at key under the current version and returns its new value
+func (v *VersionedCache) Incr(ctx context.Context, key string) (int64, error) {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c.Incr(ctx, key)
+}
+
+// IncrBy adds delta to the counter at key under the current version and returns its new value
+func (v *VersionedCache) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c.IncrBy(ctx, key, delta)
+}
+
+// Decr decrements the counter at key under the current version and returns its new value
+func (v *VersionedCache) Decr(ctx context.Context, key string) (int64, error) {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c.Decr(ctx, key)
+}
+
+// IncrWithTTL adds delta to the counter at key under the current version, expiring it after
+// ttl when it has no TTL yet
+func (v *VersionedCache) IncrWithTTL(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	c, err := v.cache(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c.IncrWithTTL(ctx, key, delta, ttl)
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestVersionedCache(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewVersionedCache(client, "users:")
+	var _ Cache = c
+	if v, err := c.Version(ctx); err != nil || v != 0 {
+		t.Errorf("Version() before any bump = %d, %v, want 0", v, err)
+	}
+	if err := c.Set(ctx, "1", "alice", 0); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	if v, err := client.Get(ctx, "users:v0:1").Result(); err != nil || v != "alice" {
+		t.Errorf("raw key users:v0:1 = %q, %v, want alice", v, err)
+	}
+
+	// Another process sharing the prefix sees the bump on its next operation
+	other := NewVersionedCache(client, "users:")
+	if v, err := c.BumpVersion(ctx); err != nil || v != 1 {
+		t.Fatalf("BumpVersion() = %d, %v, want 1", v, err)
+	}
+	var got string
+	if err := other.Get(ctx, "1", &got); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after BumpVersion error = %v, want ErrNotFound", err)
+	}
+	if err := other.Set(ctx, "1", "bob", 0); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	if err := c.Get(ctx, "1", &got); err != nil || got != "bob" {
+		t.Errorf("Get() of the new version = %q, %v, want bob", got, err)
+	}
+	if v, _ := other.Version(ctx); v != 1 {
+		t.Errorf("Version() = %d, want 1", v)
+	}
+
+	// Old versions are left for their TTL or DelVersion
+	if n, err := c.DelVersion(ctx, 0); err != nil || n != 1 {
+		t.Errorf("DelVersion(0) = %d, %v, want 1", n, err)
+	}
+	if exists, _ := c.Exists(ctx, "1"); !exists {
+		t.Error("DelVersion(0) deleted an entry of the current version")
+	}
+
+	mock.SetShouldFail(true)
+	if err := c.Set(ctx, "1", "carol", 0); err == nil {
+		t.Error("Set() with failing Redis error = nil, want error")
+	}
+	if _, err := c.BumpVersion(ctx); err == nil {
+		t.Error("BumpVersion() with failing Redis error = nil, want error")
+	}
+	mock.SetShouldFail(false)
+
+	nilCache := NewVersionedCache(nil, "users:")
+	if err := nilCache.Set(ctx, "1", "x", 0); err == nil {
+		t.Error("Set() with nil client error = nil, want error")
+	}
+	if _, err := nilCache.BumpVersion(ctx); err == nil {
+		t.Error("BumpVersion() with nil client error = nil, want error")
+	}
+}
+
+func TestVersionedCache_Refresh(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewVersionedCache(client, "{v}:", WithVersionRefresh(time.Hour))
+	other := NewVersionedCache(client, "{v}:")
+	if err := c.Set(ctx, "k", "old", 0); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	if _, err := other.BumpVersion(ctx); err != nil {
+		t.Fatalf("BumpVersion() error = %v, want nil", err)
+	}
+
+	// The version read is reused until the refresh interval passes
+	var got string
+	if err := c.Get(ctx, "k", &got); err != nil || got != "old" {
+		t.Errorf("Get() within the refresh interval = %q, %v, want old", got, err)
+	}
+	mock.SetShouldFail(true)
+	if v, err := c.Version(ctx); err != nil || v != 0 {
+		t.Errorf("Version() within the refresh interval = %d, %v, want the cached 0", v, err)
+	}
+	mock.SetShouldFail(false)
+
+	// A local bump takes effect at once
+	if v, err := c.BumpVersion(ctx); err != nil || v != 2 {
+		t.Fatalf("BumpVersion() = %d, %v, want 2", v, err)
+	}
+	if err := c.Get(ctx, "k", &got); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after local BumpVersion error = %v, want ErrNotFound", err)
+	}
+	if rc, err := c.Cache(ctx); err != nil || rc.KeyPrefix() != "{v}:v2:" {
+		t.Errorf("Cache() = %v, %v, want the cache of v2", rc, err)
+	}
+}
+
+func TestVersionedCache_Degraded(t *testing.T) {
+	mockClient, _ := testutil.NewMockRedisClient()
+	defer func() { _ = mockClient.Close() }()
+	client := &degradedClient{UniversalClient: mockClient, degraded: true}
+	ctx := context.Background()
+
+	c := NewVersionedCache(client, "users:")
+	if err := c.Set(ctx, "1", "alice", 0); err != nil {
+		t.Errorf("Set() while degraded error = %v, want nil", err)
+	}
+	if v, err := c.Version(ctx); err != nil || v != 0 {
+		t.Errorf("Version() while degraded = %d, %v, want 0", v, err)
+	}
+	if _, err := c.BumpVersion(ctx); err == nil {
+		t.Error("BumpVersion() while degraded error = nil, want error")
+	}
+	if n, _ := mockClient.Exists(ctx, "users:v0:1", c.VersionKey()).Result(); n != 0 {
+		t.Errorf("Exists() after degraded writes = %d, want 0", n)
+	}
+}
+
+func TestVersionedCache_Typed(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	c := NewVersionedCache(client, "users:", WithNegativeTTL(time.Minute))
+	users := TypedOf[typedUser](c)
+	if _, err := users.GetOrSet(ctx, "404", time.Minute, func(ctx context.Context) (typedUser, error) {
+		return typedUser{}, ErrNotFound
+	}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetOrSet() of a missing user error = %v, want ErrNotFound", err)
+	}
+	if _, err := users.Get(ctx, "404"); !errors.Is(err, ErrNegativeHit) {
+		t.Errorf("Get() of a cached miss error = %v, want ErrNegativeHit", err)
+	}
+	if _, err := c.BumpVersion(ctx); err != nil {
+		t.Fatalf("BumpVersion() error = %v, want nil", err)
+	}
+	u, err := users.GetOrSet(ctx, "404", time.Minute, func(ctx context.Context) (typedUser, error) {
+		return typedUser{ID: "404", Name: "Found"}, nil
+	})
+	if err != nil || u.Name != "Found" {
+		t.Errorf("GetOrSet() after BumpVersion = %+v, %v, want the loaded user", u, err)
+	}
+}
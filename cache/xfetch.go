@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// costPrefix starts values stored with their loader's duration in microseconds, followed by
+// a NUL byte and the encoded value; as with negativeValue, no codec output starts with it
+const costPrefix = "\x00redis-kit:cost="
+
+// withCost prepends the loader's duration to encoded data
+func withCost(data []byte, cost time.Duration) []byte {
+	out := make([]byte, 0, len(costPrefix)+24+len(data))
+	out = append(out, costPrefix...)
+	out = strconv.AppendInt(out, cost.Microseconds(), 10)
+	out = append(out, 0)
+	return append(out, data...)
+}
+
+// splitCost returns the encoded value of data and the loader duration stored with it, if any
+func splitCost(data []byte) ([]byte, time.Duration) {
+	rest, ok := bytes.CutPrefix(data, []byte(costPrefix))
+	if !ok {
+		return data, 0
+	}
+	cost, value, ok := bytes.Cut(rest, []byte{0})
+	if !ok {
+		return data, 0
+	}
+	us, err := strconv.ParseInt(string(cost), 10, 64)
+	if err != nil {
+		return data, 0
+	}
+	return value, time.Duration(us) * time.Microsecond
+}
+
+// getEntry returns the encoded value stored at key, the loader duration stored with it, and
+// its remaining TTL, in one round trip
+func (c *RedisCache) getEntry(ctx context.Context, key string) ([]byte, time.Duration, time.Duration, error) {
+	if c.client == nil {
+		return nil, 0, 0, fmt.Errorf("redis client is nil")
+	}
+	if c.degraded("get") {
+		return nil, 0, 0, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	fullKey := c.buildKey(ctx, key)
+	start := time.Now()
+	var data []byte
+	var ttl time.Duration
+	err := c.read(ctx, func(ctx context.Context, client redis.Cmdable) error {
+		var get *redis.StringCmd
+		var pttl *redis.DurationCmd
+		// Errors are read from the commands, as a missing key fails the pipeline with redis.Nil
+		_, _ = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			get = pipe.Get(ctx, fullKey)
+			pttl = pipe.PTTL(ctx, fullKey)
+			return nil
+		})
+		var getErr error
+		if data, getErr = get.Bytes(); getErr != nil {
+			return getErr
+		}
+		ttl = pttl.Val()
+		return pttl.Err()
+	})
+	c.observe("get", readOutcome(data, err), start)
+	if err == redis.Nil {
+		return nil, 0, 0, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to get cache: %w", err)
+	}
+	if err := markerErr(key, data); err != nil {
+		return nil, 0, 0, err
+	}
+	data, cost := splitCost(data)
+	return data, cost, ttl, nil
+}
+
+// refreshEarly decides, per XFetch, whether an entry whose loader took cost and that expires
+// in ttl is reloaded now: with probability exp(-ttl / (cost * beta))
+func (c *RedisCache) refreshEarly(cost, ttl time.Duration) bool {
+	if c.earlyRefresh <= 0 || cost <= 0 || ttl <= 0 {
+		return false
+	}
+	gap := -float64(cost) * c.earlyRefresh * math.Log(1-rand.Float64())
+	return gap >= float64(ttl)
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestSplitCost(t *testing.T) {
+	data, cost := splitCost(withCost([]byte(`{"a":1}`), 1500*time.Microsecond))
+	if string(data) != `{"a":1}` || cost != 1500*time.Microsecond {
+		t.Errorf("splitCost(withCost()) = %q, %v, want the value and 1.5ms", data, cost)
+	}
+	for _, plain := range []string{`{"a":1}`, "", costPrefix + "x\x00v", costPrefix + "12"} {
+		if data, cost := splitCost([]byte(plain)); string(data) != plain || cost != 0 {
+			t.Errorf("splitCost(%q) = %q, %v, want it unchanged", plain, data, cost)
+		}
+	}
+}
+
+func TestRedisCache_refreshEarly(t *testing.T) {
+	c := NewCache(nil, "x:", WithEarlyRefresh(1))
+	if !c.refreshEarly(time.Hour, time.Millisecond) {
+		t.Error("refreshEarly() for an expensive entry about to expire = false, want true")
+	}
+	if c.refreshEarly(time.Millisecond, time.Hour) {
+		t.Error("refreshEarly() for a cheap entry far from expiry = true, want false")
+	}
+	if c.refreshEarly(0, time.Millisecond) || c.refreshEarly(time.Hour, -1) {
+		t.Error("refreshEarly() without a cost or TTL = true, want false")
+	}
+	if NewCache(nil, "x:", WithEarlyRefresh(-1)).refreshEarly(time.Hour, time.Millisecond) {
+		t.Error("refreshEarly() without early refresh = true, want false")
+	}
+}
+
+func TestTyped_GetOrSetEarlyRefresh(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	counts := NewTyped[int](client, "xf:", WithEarlyRefresh(1))
+	loads := 0
+	loader := func(ctx context.Context) (int, error) {
+		loads++
+		return 10 * loads, nil
+	}
+
+	// The loader's duration is stored with the value, and Get strips it
+	if n, err := counts.GetOrSet(ctx, "k", time.Hour, loader); err != nil || n != 10 {
+		t.Fatalf("GetOrSet() on a miss = %d, %v, want 10", n, err)
+	}
+	if raw, _ := client.Get(ctx, "xf:k").Result(); !strings.HasPrefix(raw, costPrefix) {
+		t.Errorf("stored value = %q, want it to carry the loader duration", raw)
+	}
+	if n, err := counts.Get(ctx, "k"); err != nil || n != 10 {
+		t.Errorf("Get() of an entry with a cost = %d, %v, want 10", n, err)
+	}
+	if n, _ := counts.GetOrSet(ctx, "k", time.Hour, loader); n != 10 || loads != 1 {
+		t.Errorf("GetOrSet() far from expiry = %d after %d loads, want the cached 10", n, loads)
+	}
+
+	// An expensive entry about to expire is reloaded
+	_ = client.Set(ctx, "xf:k", withCost([]byte("5"), time.Hour), time.Second).Err()
+	if n, err := counts.GetOrSet(ctx, "k", time.Hour, loader); err != nil || n != 20 {
+		t.Errorf("GetOrSet() near expiry = %d, %v, want the reloaded 20", n, err)
+	}
+	if ttl, _ := counts.Cache().TTL(ctx, "k"); ttl <= time.Minute {
+		t.Errorf("TTL() after the early refresh = %v, want the new TTL", ttl)
+	}
+
+	// A failed early refresh keeps serving the cached value
+	_ = client.Set(ctx, "xf:k", withCost([]byte("5"), time.Hour), time.Second).Err()
+	failing := func(ctx context.Context) (int, error) { return 0, errors.New("database down") }
+	if n, err := counts.GetOrSet(ctx, "k", time.Hour, failing); err != nil || n != 5 {
+		t.Errorf("GetOrSet() with a failed early refresh = %d, %v, want the cached 5", n, err)
+	}
+	gone := func(ctx context.Context) (int, error) { return 0, fmt.Errorf("k: %w", ErrNotFound) }
+	if _, err := counts.GetOrSet(ctx, "k", time.Hour, gone); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetOrSet() refreshing a deleted record error = %v, want ErrNotFound", err)
+	}
+
+	// LRU caches do not refresh early
+	lru := TypedOf[int](NewLRUCache(client, "{xf-lru}:", 10, WithEarlyRefresh(1)))
+	if n, err := lru.GetOrSet(ctx, "k", time.Hour, loader); err != nil || n != 30 {
+		t.Errorf("LRU GetOrSet() = %d, %v, want 30", n, err)
+	}
+	if raw, _ := client.Get(ctx, "{xf-lru}:k").Result(); raw != "30" {
+		t.Errorf("LRU stored value = %q, want it without a cost", raw)
+	}
+}
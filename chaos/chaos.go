@@ -0,0 +1,305 @@
+// Package chaos injects faults into a go-redis client for resilience tests
+// An Injector is installed as a client hook: it fails a share of the commands with
+// ErrInjected, delays another share with latency spikes, and drops every connection on
+// demand or on a schedule, optionally refusing to reconnect for a while. Combined with the
+// in-memory mock it lets CI check how cache, lock, and rate limit code behaves when Redis
+// misbehaves, without external tooling
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+// handshakeCommands are sent by go-redis when it sets up a connection; they are never failed
+// or delayed, so that injected faults stay on the commands under test
+var handshakeCommands = map[string]struct{}{
+	"hello":  {},
+	"auth":   {},
+	"select": {},
+	"client": {},
+}
+
+// Stats counts the faults an Injector has introduced
+type Stats struct {
+	// Commands is the number of commands and pipelines eligible for faults
+	Commands int64
+
+	// Errors is the number of commands and pipelines failed with ErrInjected
+	Errors int64
+
+	// Delays is the number of latency spikes
+	Delays int64
+
+	// Disconnects is the number of times every connection was dropped
+	Disconnects int64
+
+	// RefusedDials is the number of dials failed during downtime
+	RefusedDials int64
+}
+
+// Injector introduces faults into the commands and connections of a client
+type Injector struct {
+	cfg     Config
+	enabled atomic.Bool
+
+	mu        sync.Mutex
+	rng       *rand.Rand
+	conns     map[*trackedConn]struct{}
+	downUntil time.Time
+
+	commands     atomic.Int64
+	errors       atomic.Int64
+	delays       atomic.Int64
+	disconnects  atomic.Int64
+	refusedDials atomic.Int64
+}
+
+// New creates an enabled Injector; install it with client.AddHook, or use Inject
+// Zero config values fall back to defaults
+func New(cfg Config) *Injector {
+	cfg = withDefaults(cfg)
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	i := &Injector{
+		cfg:   cfg,
+		rng:   rand.New(rand.NewPCG(seed, seed)),
+		conns: make(map[*trackedConn]struct{}),
+	}
+	i.enabled.Store(true)
+	return i
+}
+
+// Inject creates an Injector and installs it on client
+// Connections opened before the call are not tracked, and so not dropped by Disconnect
+func Inject(client *redis.Client, cfg Config) *Injector {
+	i := New(cfg)
+	if client != nil {
+		client.AddHook(i)
+	}
+	return i
+}
+
+// NewMockClient returns a client of a new in-memory mock with an Injector installed
+func NewMockClient(cfg Config) (*redis.Client, *testutil.MockRedis, *Injector) {
+	client, mock := testutil.NewMockRedisClient()
+	return client, mock, Inject(client, cfg)
+}
+
+// Config returns the injector configuration
+func (i *Injector) Config() Config {
+	return i.cfg
+}
+
+// SetEnabled turns fault injection on or off, for example around a test's setup
+// Connections are still tracked while disabled
+func (i *Injector) SetEnabled(enabled bool) {
+	i.enabled.Store(enabled)
+}
+
+// Enabled reports whether faults are injected
+func (i *Injector) Enabled() bool {
+	return i.enabled.Load()
+}
+
+// Stats returns the faults introduced so far
+func (i *Injector) Stats() Stats {
+	return Stats{
+		Commands:     i.commands.Load(),
+		Errors:       i.errors.Load(),
+		Delays:       i.delays.Load(),
+		Disconnects:  i.disconnects.Load(),
+		RefusedDials: i.refusedDials.Load(),
+	}
+}
+
+// Disconnect closes every open connection of the client and starts the configured downtime,
+// returning the number of connections closed. Commands in flight on them fail, and later
+// commands reconnect once the downtime is over
+func (i *Injector) Disconnect() int {
+	i.mu.Lock()
+	conns := make([]*trackedConn, 0, len(i.conns))
+	for c := range i.conns {
+		conns = append(conns, c)
+	}
+	clear(i.conns)
+	i.downUntil = time.Now().Add(i.cfg.Downtime)
+	i.mu.Unlock()
+
+	for _, c := range conns {
+		c.dropped.Store(true)
+		_ = c.Conn.Close()
+	}
+	i.disconnects.Add(1)
+	return len(conns)
+}
+
+// Run calls Disconnect every DisconnectInterval until ctx is cancelled, then returns nil
+// Disconnects are skipped while the injector is disabled
+func (i *Injector) Run(ctx context.Context) error {
+	if i.cfg.DisconnectInterval <= 0 {
+		return fmt.Errorf("disconnect interval is not set")
+	}
+
+	ticker := time.NewTicker(i.cfg.DisconnectInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if i.Enabled() {
+				i.Disconnect()
+			}
+		}
+	}
+}
+
+// DialHook refuses dials during downtime and tracks the connections opened
+func (i *Injector) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if i.Enabled() && i.down() {
+			i.refusedDials.Add(1)
+			return nil, ErrInjected
+		}
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		tc := &trackedConn{Conn: conn, injector: i}
+		i.mu.Lock()
+		i.conns[tc] = struct{}{}
+		i.mu.Unlock()
+		return tc, nil
+	}
+}
+
+// ProcessHook injects faults into single commands
+func (i *Injector) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := i.inject(ctx, cmd); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+// ProcessPipelineHook injects faults into whole pipelines and transactions
+func (i *Injector) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if err := i.inject(ctx, cmds...); err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		return next(ctx, cmds)
+	}
+}
+
+// inject delays and fails cmds according to the configured rates
+func (i *Injector) inject(ctx context.Context, cmds ...redis.Cmder) error {
+	if !i.Enabled() || !i.eligible(cmds) {
+		return nil
+	}
+	i.commands.Add(1)
+
+	i.mu.Lock()
+	delay := i.rng.Float64() < i.cfg.LatencyRate
+	fail := i.rng.Float64() < i.cfg.ErrorRate
+	i.mu.Unlock()
+
+	if delay {
+		i.delays.Add(1)
+		timer := time.NewTimer(i.cfg.Latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if fail {
+		i.errors.Add(1)
+		return ErrInjected
+	}
+	return nil
+}
+
+// eligible reports whether any of cmds may receive faults
+func (i *Injector) eligible(cmds []redis.Cmder) bool {
+	for _, cmd := range cmds {
+		name := cmd.Name()
+		if _, ok := handshakeCommands[name]; ok {
+			continue
+		}
+		if len(i.cfg.Commands) == 0 || slices.Contains(i.cfg.Commands, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// down reports whether dials are refused
+func (i *Injector) down() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return time.Now().Before(i.downUntil)
+}
+
+// trackedConn removes itself from the injector's connections when go-redis closes it
+// Once dropped by Disconnect, its calls fail with net.ErrClosed, as on a closed TCP socket,
+// whatever the underlying connection reports
+type trackedConn struct {
+	net.Conn
+	injector *Injector
+	dropped  atomic.Bool
+}
+
+func (c *trackedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	return n, c.wrap("read", err)
+}
+
+func (c *trackedConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	return n, c.wrap("write", err)
+}
+
+func (c *trackedConn) SetDeadline(t time.Time) error {
+	return c.wrap("set deadline", c.Conn.SetDeadline(t))
+}
+
+func (c *trackedConn) SetReadDeadline(t time.Time) error {
+	return c.wrap("set read deadline", c.Conn.SetReadDeadline(t))
+}
+
+func (c *trackedConn) SetWriteDeadline(t time.Time) error {
+	return c.wrap("set write deadline", c.Conn.SetWriteDeadline(t))
+}
+
+func (c *trackedConn) wrap(op string, err error) error {
+	if err == nil || !c.dropped.Load() {
+		return err
+	}
+	return &net.OpError{Op: op, Net: c.LocalAddr().Network(), Addr: c.RemoteAddr(), Err: net.ErrClosed}
+}
+
+func (c *trackedConn) Close() error {
+	c.injector.mu.Lock()
+	delete(c.injector.conns, c)
+	c.injector.mu.Unlock()
+	return c.Conn.Close()
+}
@@ -0,0 +1,174 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+func TestInjector_Errors(t *testing.T) {
+	client, _, inj := NewMockClient(DefaultConfig().WithErrorRate(1))
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	err := client.Set(ctx, "k", "v", 0).Err()
+	if !errors.Is(err, ErrInjected) {
+		t.Fatalf("Set() error = %v, want ErrInjected", err)
+	}
+	if !utils.IsRetryableError(err) {
+		t.Error("IsRetryableError(ErrInjected) = false, want true")
+	}
+
+	_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "a", "1", 0)
+		pipe.Set(ctx, "b", "2", 0)
+		return nil
+	})
+	if !errors.Is(err, ErrInjected) {
+		t.Errorf("Pipelined() error = %v, want ErrInjected", err)
+	}
+
+	inj.SetEnabled(false)
+	if n := client.Exists(ctx, "k", "a", "b").Val(); n != 0 {
+		t.Errorf("Exists() = %d, want failed commands never sent", n)
+	}
+	if err := client.Set(ctx, "k", "v", 0).Err(); err != nil {
+		t.Errorf("Set() while disabled error = %v, want nil", err)
+	}
+
+	if s := inj.Stats(); s.Commands != 2 || s.Errors != 2 {
+		t.Errorf("Stats() = %+v, want 2 commands failed", s)
+	}
+}
+
+func TestInjector_Rates(t *testing.T) {
+	client, _, inj := NewMockClient(DefaultConfig().WithErrorRate(0.3).WithSeed(7))
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	failed := 0
+	for i := 0; i < 500; i++ {
+		if err := client.Incr(ctx, "n").Err(); err != nil {
+			failed++
+		}
+	}
+	if failed < 100 || failed > 200 {
+		t.Errorf("failed %d of 500 commands, want about 150", failed)
+	}
+	inj.SetEnabled(false)
+	if n, _ := client.Get(ctx, "n").Int(); n+failed != 500 {
+		t.Errorf("counter = %d with %d failures, want 500 in total", n, failed)
+	}
+}
+
+func TestInjector_Commands(t *testing.T) {
+	client, _, _ := NewMockClient(DefaultConfig().WithErrorRate(1).WithCommands("GET"))
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if err := client.Set(ctx, "k", "v", 0).Err(); err != nil {
+		t.Errorf("Set() error = %v, want nil for a command not listed", err)
+	}
+	if err := client.Get(ctx, "k").Err(); !errors.Is(err, ErrInjected) {
+		t.Errorf("Get() error = %v, want ErrInjected", err)
+	}
+}
+
+func TestInjector_Latency(t *testing.T) {
+	client, _, inj := NewMockClient(DefaultConfig().WithLatency(1, 50*time.Millisecond))
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	start := time.Now()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Fatalf("Ping() error = %v, want nil", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Ping() took %v, want at least 50ms", elapsed)
+	}
+
+	// A spike longer than the caller's deadline surfaces as a timeout
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := client.Ping(short).Err(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Ping() with short deadline error = %v, want DeadlineExceeded", err)
+	}
+	if s := inj.Stats(); s.Delays != 2 || s.Errors != 0 {
+		t.Errorf("Stats() = %+v, want 2 delays", s)
+	}
+}
+
+func TestInjector_Disconnect(t *testing.T) {
+	// Without go-redis retries, each step of the outage is visible to the caller
+	mock := testutil.NewMockRedis()
+	client := redis.NewClient(&redis.Options{Addr: "mock", Dialer: mock.Dialer(), MaxRetries: -1, DialerRetries: 1})
+	defer func() { _ = client.Close() }()
+	inj := Inject(client, DefaultConfig().WithDisconnects(time.Hour, 300*time.Millisecond))
+
+	ctx := context.Background()
+	if err := client.Set(ctx, "k", "v", 0).Err(); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	if n := inj.Disconnect(); n != 1 {
+		t.Errorf("Disconnect() = %d, want 1 connection closed", n)
+	}
+
+	// The dropped connection fails, then reconnecting fails during the downtime
+	if err := client.Get(ctx, "k").Err(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Get() on the dropped connection error = %v, want net.ErrClosed", err)
+	}
+	if err := client.Get(ctx, "k").Err(); !errors.Is(err, ErrInjected) {
+		t.Errorf("Get() during downtime error = %v, want ErrInjected", err)
+	}
+	time.Sleep(350 * time.Millisecond)
+	if v, err := client.Get(ctx, "k").Result(); err != nil || v != "v" {
+		t.Errorf("Get() after downtime = %q, %v, want v", v, err)
+	}
+	if s := inj.Stats(); s.Disconnects != 1 || s.RefusedDials == 0 {
+		t.Errorf("Stats() = %+v, want 1 disconnect and refused dials", s)
+	}
+}
+
+func TestInjector_Run(t *testing.T) {
+	client, _, inj := NewMockClient(DefaultConfig().WithDisconnects(20*time.Millisecond, 0))
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- inj.Run(ctx) }()
+
+	// A cache with a retry policy rides out the dropped connections
+	c := cache.NewCache(client, "app:", cache.WithRetryPolicy(utils.DefaultRetryPolicy().WithBackoff(time.Millisecond, 5*time.Millisecond)))
+	for ctx.Err() == nil {
+		if err := c.Set(context.Background(), "k", "v", 0); err != nil {
+			t.Errorf("Set() error = %v, want retries to recover", err)
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v, want nil on cancellation", err)
+	}
+	if s := inj.Stats(); s.Disconnects < 3 {
+		t.Errorf("Stats().Disconnects = %d, want several", s.Disconnects)
+	}
+
+	if err := New(DefaultConfig()).Run(context.Background()); err == nil {
+		t.Error("Run() without interval error = nil, want error")
+	}
+}
+
+func TestInject_NilClient(t *testing.T) {
+	if inj := Inject(nil, DefaultConfig()); inj == nil || !inj.Enabled() {
+		t.Error("Inject(nil) = disabled injector, want an enabled one")
+	}
+}
@@ -0,0 +1,89 @@
+package chaos
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultLatency is the default delay of a latency spike
+const DefaultLatency = 100 * time.Millisecond
+
+// Config represents the faults an Injector introduces
+type Config struct {
+	// ErrorRate is the probability that a command fails with ErrInjected without reaching
+	// the server (default: 0)
+	ErrorRate float64
+
+	// LatencyRate is the probability that a command is delayed by Latency (default: 0)
+	LatencyRate float64
+
+	// Latency is the delay of a latency spike (default: 100ms)
+	Latency time.Duration
+
+	// DisconnectInterval is how often Run closes every connection of the client (default: 0, disabled)
+	DisconnectInterval time.Duration
+
+	// Downtime is how long dials fail with ErrInjected after each disconnect (default: 0)
+	Downtime time.Duration
+
+	// Commands limits injected errors and latency to these commands, matched case-insensitively
+	// (default: every command)
+	Commands []string
+
+	// Seed seeds the random source so a failing run can be replayed; 0 picks a random seed
+	Seed uint64
+}
+
+// DefaultConfig returns a Config with default values, which injects no faults until rates are set
+func DefaultConfig() Config {
+	return Config{Latency: DefaultLatency}
+}
+
+// WithErrorRate sets the probability that a command fails
+func (c Config) WithErrorRate(rate float64) Config {
+	c.ErrorRate = rate
+	return c
+}
+
+// WithLatency sets the probability and the delay of latency spikes
+func (c Config) WithLatency(rate float64, latency time.Duration) Config {
+	c.LatencyRate = rate
+	c.Latency = latency
+	return c
+}
+
+// WithDisconnects sets how often Run drops every connection and how long reconnecting fails afterwards
+func (c Config) WithDisconnects(interval, downtime time.Duration) Config {
+	c.DisconnectInterval = interval
+	c.Downtime = downtime
+	return c
+}
+
+// WithCommands limits injected errors and latency to the given commands
+func (c Config) WithCommands(commands ...string) Config {
+	c.Commands = commands
+	return c
+}
+
+// WithSeed sets the seed of the random source
+func (c Config) WithSeed(seed uint64) Config {
+	c.Seed = seed
+	return c
+}
+
+func withDefaults(cfg Config) Config {
+	cfg.ErrorRate = min(max(cfg.ErrorRate, 0), 1)
+	cfg.LatencyRate = min(max(cfg.LatencyRate, 0), 1)
+	if cfg.Latency <= 0 {
+		cfg.Latency = DefaultLatency
+	}
+	if cfg.Downtime < 0 {
+		cfg.Downtime = 0
+	}
+	commands := make([]string, len(cfg.Commands))
+	for i, name := range cfg.Commands {
+		commands[i] = strings.ToLower(name)
+	}
+	cfg.Commands = commands
+	return cfg
+}
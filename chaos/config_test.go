@@ -0,0 +1,35 @@
+package chaos
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.ErrorRate != 0 || cfg.LatencyRate != 0 || cfg.Latency != DefaultLatency || cfg.DisconnectInterval != 0 {
+		t.Errorf("DefaultConfig() = %+v, want no faults", cfg)
+	}
+
+	cfg = cfg.WithErrorRate(0.5).
+		WithLatency(0.1, time.Second).
+		WithDisconnects(time.Minute, time.Second).
+		WithCommands("GET", "set").
+		WithSeed(42)
+	if cfg.ErrorRate != 0.5 || cfg.LatencyRate != 0.1 || cfg.Latency != time.Second ||
+		cfg.DisconnectInterval != time.Minute || cfg.Downtime != time.Second || cfg.Seed != 42 {
+		t.Errorf("With*() = %+v", cfg)
+	}
+
+	cfg = withDefaults(cfg)
+	if cfg.Commands[0] != "get" || cfg.Commands[1] != "set" {
+		t.Errorf("withDefaults().Commands = %v, want lower case", cfg.Commands)
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	cfg := withDefaults(Config{ErrorRate: 2, LatencyRate: -1, Latency: -time.Second, Downtime: -time.Second})
+	if cfg.ErrorRate != 1 || cfg.LatencyRate != 0 || cfg.Latency != DefaultLatency || cfg.Downtime != 0 {
+		t.Errorf("withDefaults() = %+v, want clamped rates and default latency", cfg)
+	}
+}
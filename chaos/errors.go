@@ -0,0 +1,13 @@
+package chaos
+
+var (
+	// ErrInjected is returned by commands and dials failed by an Injector. It is a
+	// net.Error, so retry policies treat it like a dropped connection.
+	ErrInjected error = injectedError{}
+)
+
+type injectedError struct{}
+
+func (injectedError) Error() string   { return "chaos: injected failure" }
+func (injectedError) Timeout() bool   { return false }
+func (injectedError) Temporary() bool { return true }
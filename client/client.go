@@ -3,34 +3,102 @@ package client
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 // NewClient creates a new Redis client with the given configuration
+// It pings Redis and fails if it is unreachable, unless cfg.LazyConnect is set. With several
+// cfg.Addrs, the ping succeeds once any of them is reachable
 func NewClient(cfg Config) (*redis.Client, error) {
+	if len(cfg.Addrs) > 0 {
+		if slices.Contains(cfg.Addrs, "") {
+			return nil, fmt.Errorf("redis seed addresses must not be empty")
+		}
+		cfg.Addr = cfg.Addrs[0]
+	}
 	if cfg.Addr == "" {
 		return nil, fmt.Errorf("redis address is required")
 	}
+	switch cfg.Network {
+	case "", "tcp", "tcp4", "tcp6", "unix":
+	default:
+		return nil, fmt.Errorf("unsupported redis network: %q", cfg.Network)
+	}
+
+	tlsConfig, err := buildTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := protocol(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dial, err := buildDialer(cfg, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
 
 	opts := &redis.Options{
-		Addr:         cfg.Addr,
-		Password:     cfg.Password,
-		DB:           cfg.DB,
-		PoolSize:     cfg.PoolSize,
-		MinIdleConns: cfg.MinIdleConns,
-		DialTimeout:  cfg.DialTimeout,
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
-		MaxRetries:   cfg.MaxRetries,
-		PoolTimeout:  cfg.PoolTimeout,
+		Network:         cfg.Network,
+		Addr:            cfg.Addr,
+		Username:        cfg.Username,
+		Password:        cfg.Password,
+		DB:              cfg.DB,
+		Protocol:        resp,
+		PoolSize:        cfg.PoolSize,
+		MinIdleConns:    cfg.MinIdleConns,
+		DialTimeout:     cfg.DialTimeout,
+		ReadTimeout:     cfg.ReadTimeout,
+		WriteTimeout:    cfg.WriteTimeout,
+		MaxRetries:      maxRetries(cfg),
+		MinRetryBackoff: cfg.MinRetryBackoff,
+		MaxRetryBackoff: cfg.MaxRetryBackoff,
+		PoolTimeout:     cfg.PoolTimeout,
+		TLSConfig:       tlsConfig,
+		ClientName:      cfg.ClientName,
+		OnConnect:       cfg.OnConnect,
 	}
-	if cfg.Dialer != nil {
-		opts.Dialer = cfg.Dialer
+	opts.ContextTimeoutEnabled = cfg.ContextTimeout
+	if dial != nil {
+		opts.Dialer = dial
+	}
+
+	return connect(redis.NewClient(opts), cfg)
+}
+
+// protocol returns the RESP version for cfg; RESP3 is opt-in, so zero means RESP2
+func protocol(cfg Config) (int, error) {
+	switch cfg.Protocol {
+	case 0, 2:
+		return 2, nil
+	case 3:
+		return 3, nil
+	default:
+		return 0, fmt.Errorf("unsupported redis protocol: %d", cfg.Protocol)
 	}
+}
 
-	client := redis.NewClient(opts)
+// connect instruments a new client, installs its guard and retry hooks, and tests its
+// connection and server version unless cfg.LazyConnect is set, closing it on failure
+func connect(client *redis.Client, cfg Config) (*redis.Client, error) {
+	if cfg.MinServerVersion != "" {
+		if _, err := parseServerVersion(cfg.MinServerVersion); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+	}
+	Instrument(client, cfg.Recorder)
+	LogCommands(client, cfg.Logger, LogOptions{SampleRate: cfg.LogSampleRate})
+	GuardCommands(client, cfg.DeniedCommands...)
+	if cfg.RetryPredicate != nil {
+		client.AddHook(retryHook{policy: cfg.RetryPolicy()})
+	}
+	if cfg.LazyConnect {
+		return client, nil
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.DialTimeout)
@@ -40,6 +108,20 @@ func NewClient(cfg Config) (*redis.Client, error) {
 		_ = client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
+	if cfg.MinServerVersion != "" {
+		if err := CheckServerVersion(ctx, client, cfg.MinServerVersion); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+	}
+	if cfg.Warmup > 0 {
+		warmupCtx, cancel := context.WithTimeout(context.Background(), cfg.DialTimeout)
+		defer cancel()
+		if err := Warmup(warmupCtx, client, cfg.Warmup); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+	}
 
 	return client, nil
 }
@@ -2,6 +2,13 @@ package client
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -9,6 +16,25 @@ import (
 	"github.com/soulteary/redis-kit/testutil"
 )
 
+// serveMock relays the connections accepted on ln to mock until ln is closed
+func serveMock(ln net.Listener, mock *testutil.MockRedis) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		backend, _ := mock.Dialer()(context.Background(), "tcp", "")
+		go func() {
+			_, _ = io.Copy(backend, conn)
+			_ = backend.Close()
+		}()
+		go func() {
+			_, _ = io.Copy(conn, backend)
+			_ = conn.Close()
+		}()
+	}
+}
+
 func TestNewClient(t *testing.T) {
 	t.Run("successful creation with mock", func(t *testing.T) {
 		mockClient, _ := testutil.NewMockRedisClient()
@@ -64,6 +90,210 @@ func TestNewClient(t *testing.T) {
 	})
 }
 
+func TestNewClient_LazyConnect(t *testing.T) {
+	mock := testutil.NewMockRedis()
+	mock.SetShouldFail(true)
+	cfg := DefaultConfig().WithAddr("mock").WithDialTimeout(time.Second).WithLazyConnect(true)
+	cfg.Dialer = mock.Dialer()
+
+	// Redis is failing at startup, yet construction succeeds
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() lazy error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+	if _, err := NewClient(cfg.WithLazyConnect(false)); err == nil {
+		t.Error("NewClient() eager with failing Redis error = nil, want error")
+	}
+
+	// The first command after recovery connects
+	mock.SetShouldFail(false)
+	ctx := context.Background()
+	if err := client.Set(ctx, "k", "v", 0).Err(); err != nil {
+		t.Errorf("Set() after recovery error = %v, want nil", err)
+	}
+
+	// Lazy clients still validate their configuration
+	if _, err := NewClient(DefaultConfig().WithAddr("").WithLazyConnect(true)); err == nil {
+		t.Error("NewClient() lazy without address error = nil, want error")
+	}
+	failover, err := NewFailoverClient(DefaultConfig().WithLazyConnect(true), SentinelConfig{
+		MasterName:    "mymaster",
+		SentinelAddrs: []string{"invalid:26379"},
+	})
+	if err != nil {
+		t.Errorf("NewFailoverClient() lazy error = %v, want nil", err)
+	} else {
+		_ = failover.Close()
+	}
+}
+
+func TestNewClient_Username(t *testing.T) {
+	cfg := DefaultConfig().WithUsername("app").WithPassword("secret").WithLazyConnect(true)
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+	if opts := client.Options(); opts.Username != "app" || opts.Password != "secret" {
+		t.Errorf("NewClient() credentials = %q/%q, want app/secret", opts.Username, opts.Password)
+	}
+}
+
+func TestNewClient_RetryPredicate(t *testing.T) {
+	mock := testutil.NewMockRedis()
+	var calls atomic.Int32
+	cfg := DefaultConfig().WithAddr("mock").WithMaxRetries(2).WithRetryBackoff(time.Millisecond, 2*time.Millisecond).
+		WithRetryPredicate(func(err error) bool {
+			// The failure clears up after the first attempt
+			calls.Add(1)
+			mock.SetShouldFail(false)
+			return strings.Contains(err.Error(), "mock redis failure")
+		})
+	cfg.Dialer = mock.Dialer()
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+	// go-redis's own retries are disabled, which it reports as 0
+	if opts := client.Options(); opts.MaxRetries != 0 || opts.MinRetryBackoff != time.Millisecond {
+		t.Errorf("NewClient() MaxRetries = %d, MinRetryBackoff = %v, want 0 and 1ms", opts.MaxRetries, opts.MinRetryBackoff)
+	}
+
+	ctx := context.Background()
+	mock.SetShouldFail(true)
+	if err := client.Set(ctx, "k", "v", 0).Err(); err != nil || calls.Load() != 1 {
+		t.Errorf("Set() error = %v after %d predicate calls, want success after a retry", err, calls.Load())
+	}
+
+	mock.SetShouldFail(true)
+	pipe := client.Pipeline()
+	get := pipe.Get(ctx, "k")
+	if _, err := pipe.Exec(ctx); err != nil || get.Val() != "v" {
+		t.Errorf("pipeline Get() = %q, %v, want v after a retry", get.Val(), err)
+	}
+
+	// Errors the predicate rejects are returned after one attempt
+	calls.Store(0)
+	if err := client.Get(ctx, "missing").Err(); err == nil || calls.Load() != 1 {
+		t.Errorf("Get(missing) error = %v after %d predicate calls, want redis.Nil unretried", err, calls.Load())
+	}
+
+	// Attempts stop at MaxRetries
+	mock.SetShouldFail(true)
+	calls.Store(0)
+	client2, _ := NewClient(cfg.WithLazyConnect(true).WithRetryPredicate(func(error) bool {
+		calls.Add(1)
+		return true
+	}))
+	defer func() { _ = client2.Close() }()
+	if err := client2.Get(ctx, "k").Err(); err == nil || calls.Load() != 2 {
+		t.Errorf("Get() error = %v after %d predicate calls, want an error after 2 retries", err, calls.Load())
+	}
+}
+
+func TestNewClient_OnConnect(t *testing.T) {
+	mock := testutil.NewMockRedis()
+	var connects atomic.Int32
+	cfg := DefaultConfig().WithAddr("mock").WithMinIdleConns(0).WithClientName("billing-worker").
+		WithOnConnect(func(ctx context.Context, cn *redis.Conn) error {
+			connects.Add(1)
+			return cn.Ping(ctx).Err()
+		})
+	cfg.Dialer = mock.Dialer()
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	name, err := client.ClientGetName(ctx).Result()
+	if err != nil || name != "billing-worker" {
+		t.Errorf("ClientGetName() = %q, %v, want billing-worker", name, err)
+	}
+	list, err := client.ClientList(ctx).Result()
+	if err != nil || !strings.Contains(list, "name=billing-worker") {
+		t.Errorf("ClientList() = %q, %v, want the named connection", list, err)
+	}
+	if connects.Load() != 1 {
+		t.Errorf("OnConnect ran %d times, want 1", connects.Load())
+	}
+
+	// An OnConnect error fails the connection
+	cfg = cfg.WithOnConnect(func(context.Context, *redis.Conn) error {
+		return errors.New("setup failed")
+	})
+	if _, err := NewClient(cfg); err == nil || !strings.Contains(err.Error(), "setup failed") {
+		t.Errorf("NewClient() with failing OnConnect error = %v, want setup failed", err)
+	}
+}
+
+func TestNewClient_UnixSocket(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, which t.TempDir may exceed
+	dir, err := os.MkdirTemp("", "redis-kit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "redis.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+	go serveMock(ln, testutil.NewMockRedis())
+
+	cfg, err := ParseURL("unix://" + path + "?dial_timeout=2s")
+	if err != nil {
+		t.Fatalf("ParseURL() error = %v, want nil", err)
+	}
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() over unix socket error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+	if err := client.Set(context.Background(), "k", "v", 0).Err(); err != nil {
+		t.Errorf("Set() over unix socket error = %v, want nil", err)
+	}
+
+	if _, err := NewClient(DefaultConfig().WithNetwork("udp").WithLazyConnect(true)); err == nil {
+		t.Error("NewClient() with udp network error = nil, want error")
+	}
+}
+
+func TestNewClient_Protocol(t *testing.T) {
+	mock := testutil.NewMockRedis()
+	cfg := DefaultConfig().WithAddr("mock").WithLazyConnect(true)
+	cfg.Dialer = mock.Dialer()
+	for _, tt := range []struct{ set, want int }{{0, 2}, {2, 2}, {3, 3}} {
+		cfg.Protocol = tt.set
+		client, err := NewClient(cfg)
+		if err != nil {
+			t.Fatalf("NewClient() with protocol %d error = %v, want nil", tt.set, err)
+		}
+		if got := client.Options().Protocol; got != tt.want {
+			t.Errorf("NewClient() with protocol %d uses %d, want %d", tt.set, got, tt.want)
+		}
+		_ = client.Close()
+	}
+
+	// The mock does not speak RESP3, so RESP3 clients fall back to RESP2
+	client, err := NewClient(cfg.WithProtocol(3).WithLazyConnect(false))
+	if err != nil {
+		t.Fatalf("NewClient() RESP3 error = %v, want nil", err)
+	}
+	_ = client.Close()
+
+	if _, err := NewClient(cfg.WithProtocol(4)); err == nil {
+		t.Error("NewClient() with protocol 4 error = nil, want error")
+	}
+	if _, err := NewFailoverClient(cfg.WithProtocol(1), SentinelConfig{MasterName: "m", SentinelAddrs: []string{"s:26379"}}); err == nil {
+		t.Error("NewFailoverClient() with protocol 1 error = nil, want error")
+	}
+}
+
 func TestNewClientWithDefaults(t *testing.T) {
 	t.Run("creates client with default config", func(t *testing.T) {
 		// This will fail without real Redis, but we can test the function exists
@@ -1,8 +1,15 @@
 package client
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
 	"testing"
 	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -35,6 +42,9 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.MaxRetries != 3 {
 		t.Errorf("DefaultConfig().MaxRetries = %d, want 3", cfg.MaxRetries)
 	}
+	if cfg.MinRetryBackoff != DefaultMinRetryBackoff || cfg.MaxRetryBackoff != DefaultMaxRetryBackoff {
+		t.Errorf("DefaultConfig() retry backoff = %v-%v, want %v-%v", cfg.MinRetryBackoff, cfg.MaxRetryBackoff, DefaultMinRetryBackoff, DefaultMaxRetryBackoff)
+	}
 	if cfg.PoolTimeout != 4*time.Second {
 		t.Errorf("DefaultConfig().PoolTimeout = %v, want %v", cfg.PoolTimeout, 4*time.Second)
 	}
@@ -56,6 +66,31 @@ func TestWithAddr(t *testing.T) {
 	}
 }
 
+func TestWithAddrs(t *testing.T) {
+	addrs := []string{"10.0.0.1:6379", "10.0.0.2:6379"}
+	cfg := DefaultConfig().WithAddrs(addrs...)
+	addrs[0] = "changed"
+	if len(cfg.Addrs) != 2 || cfg.Addrs[0] != "10.0.0.1:6379" {
+		t.Errorf("WithAddrs() = %v, want a copy of the addresses", cfg.Addrs)
+	}
+	if got := cfg.WithAddr("10.0.0.3:6379").Addrs; got != nil {
+		t.Errorf("WithAddr() left Addrs = %v, want nil", got)
+	}
+	if got := cfg.WithUnixSocket("/tmp/redis.sock").Addrs; got != nil {
+		t.Errorf("WithUnixSocket() left Addrs = %v, want nil", got)
+	}
+}
+
+func TestWithUsername(t *testing.T) {
+	if DefaultConfig().Username != "" {
+		t.Errorf("DefaultConfig().Username = %q, want empty", DefaultConfig().Username)
+	}
+	cfg := DefaultConfig().WithUsername("app")
+	if cfg.Username != "app" {
+		t.Errorf("WithUsername() = %q, want %q", cfg.Username, "app")
+	}
+}
+
 func TestWithPassword(t *testing.T) {
 	cfg := DefaultConfig().WithPassword("mypassword")
 	if cfg.Password != "mypassword" {
@@ -208,6 +243,51 @@ func TestWithPoolTimeout(t *testing.T) {
 	}
 }
 
+func TestWithTLS(t *testing.T) {
+	tlsConfig := &tls.Config{ServerName: "redis.example.com"}
+	cfg := DefaultConfig().
+		WithTLS(tlsConfig).
+		WithTLSCertFiles("client.crt", "client.key").
+		WithTLSCAFile("ca.crt").
+		WithTLSInsecureSkipVerify(true)
+
+	if cfg.TLSConfig != tlsConfig {
+		t.Error("WithTLS() did not set TLSConfig")
+	}
+	if cfg.TLSCertFile != "client.crt" || cfg.TLSKeyFile != "client.key" {
+		t.Errorf("WithTLSCertFiles() = %q, %q, want client.crt, client.key", cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	if cfg.TLSCAFile != "ca.crt" {
+		t.Errorf("WithTLSCAFile() = %q, want ca.crt", cfg.TLSCAFile)
+	}
+	if !cfg.TLSInsecureSkipVerify {
+		t.Error("WithTLSInsecureSkipVerify(true) = false, want true")
+	}
+	if DefaultConfig().tlsEnabled() || !cfg.tlsEnabled() {
+		t.Error("tlsEnabled() should be false by default and true once TLS is configured")
+	}
+}
+
+func TestWithLazyConnect(t *testing.T) {
+	if DefaultConfig().LazyConnect {
+		t.Error("DefaultConfig().LazyConnect = true, want false")
+	}
+	cfg := DefaultConfig().WithLazyConnect(true)
+	if !cfg.LazyConnect {
+		t.Error("WithLazyConnect(true) = false, want true")
+	}
+}
+
+func TestWithContextTimeout(t *testing.T) {
+	if DefaultConfig().ContextTimeout {
+		t.Error("DefaultConfig().ContextTimeout = true, want false")
+	}
+	cfg := DefaultConfig().WithContextTimeout(true)
+	if !cfg.ContextTimeout {
+		t.Error("WithContextTimeout(true) = false, want true")
+	}
+}
+
 func TestConfigChaining(t *testing.T) {
 	cfg := DefaultConfig().
 		WithAddr("127.0.0.1:6379").
@@ -252,3 +332,127 @@ func TestConfigChaining(t *testing.T) {
 		t.Errorf("chained WithPoolTimeout() = %v, want %v", cfg.PoolTimeout, 8*time.Second)
 	}
 }
+
+func TestWithRetryBackoff(t *testing.T) {
+	cfg := DefaultConfig().WithRetryBackoff(10*time.Millisecond, time.Second)
+	if cfg.MinRetryBackoff != 10*time.Millisecond || cfg.MaxRetryBackoff != time.Second {
+		t.Errorf("WithRetryBackoff() = %v-%v, want 10ms-1s", cfg.MinRetryBackoff, cfg.MaxRetryBackoff)
+	}
+}
+
+func TestWithRetryPredicate(t *testing.T) {
+	if DefaultConfig().RetryPredicate != nil {
+		t.Error("DefaultConfig().RetryPredicate != nil, want nil")
+	}
+	cfg := DefaultConfig().WithRetryPredicate(func(error) bool { return true })
+	if cfg.RetryPredicate == nil || !cfg.RetryPredicate(errors.New("x")) {
+		t.Error("WithRetryPredicate() did not set the predicate")
+	}
+}
+
+func TestConfig_RetryPolicy(t *testing.T) {
+	policy := DefaultConfig().RetryPolicy()
+	if policy.MaxAttempts != 4 || policy.InitialBackoff != DefaultMinRetryBackoff || policy.MaxBackoff != DefaultMaxRetryBackoff {
+		t.Errorf("RetryPolicy() = %+v, want 4 attempts with the default backoff", policy)
+	}
+	if policy.Classifier == nil || !policy.Classifier(io.EOF) || policy.Classifier(redis.Nil) {
+		t.Error("RetryPolicy() classifier should default to utils.IsRetryableError")
+	}
+
+	// Zero values fall back to the go-redis defaults
+	policy = Config{}.RetryPolicy()
+	if policy.MaxAttempts != 4 || policy.InitialBackoff != DefaultMinRetryBackoff || policy.MaxBackoff != DefaultMaxRetryBackoff {
+		t.Errorf("Config{}.RetryPolicy() = %+v, want the defaults", policy)
+	}
+
+	policy = DefaultConfig().WithMaxRetries(-1).WithRetryBackoff(-1, -1).
+		WithRetryPredicate(func(error) bool { return false }).RetryPolicy()
+	if policy.MaxAttempts != 1 || policy.InitialBackoff != 0 || policy.Classifier(io.EOF) {
+		t.Errorf("RetryPolicy() with retries disabled = %+v, want 1 attempt without backoff", policy)
+	}
+	if d := policy.Backoff(1); d != 0 {
+		t.Errorf("Backoff(1) with backoff disabled = %v, want 0", d)
+	}
+}
+
+func TestWithClientName(t *testing.T) {
+	if DefaultConfig().ClientName != "" {
+		t.Errorf("DefaultConfig().ClientName = %q, want empty", DefaultConfig().ClientName)
+	}
+	cfg := DefaultConfig().WithClientName("api")
+	if cfg.ClientName != "api" {
+		t.Errorf("WithClientName() = %q, want %q", cfg.ClientName, "api")
+	}
+}
+
+func TestWithOnConnect(t *testing.T) {
+	if DefaultConfig().OnConnect != nil {
+		t.Error("DefaultConfig().OnConnect != nil, want nil")
+	}
+	cfg := DefaultConfig().WithOnConnect(func(context.Context, *redis.Conn) error { return nil })
+	if cfg.OnConnect == nil {
+		t.Error("WithOnConnect() did not set the function")
+	}
+}
+
+func TestWithUnixSocket(t *testing.T) {
+	if DefaultConfig().Network != "tcp" {
+		t.Errorf("DefaultConfig().Network = %q, want tcp", DefaultConfig().Network)
+	}
+	cfg := DefaultConfig().WithUnixSocket("/tmp/redis.sock")
+	if cfg.Network != "unix" || cfg.Addr != "/tmp/redis.sock" {
+		t.Errorf("WithUnixSocket() = %s %s, want unix /tmp/redis.sock", cfg.Network, cfg.Addr)
+	}
+	if cfg := DefaultConfig().WithNetwork("tcp6"); cfg.Network != "tcp6" {
+		t.Errorf("WithNetwork() = %q, want tcp6", cfg.Network)
+	}
+}
+
+func TestWithProtocol(t *testing.T) {
+	if DefaultConfig().Protocol != 2 {
+		t.Errorf("DefaultConfig().Protocol = %d, want 2", DefaultConfig().Protocol)
+	}
+	if cfg := DefaultConfig().WithProtocol(3); cfg.Protocol != 3 {
+		t.Errorf("WithProtocol() = %d, want 3", cfg.Protocol)
+	}
+}
+
+func TestWithMinServerVersion(t *testing.T) {
+	if DefaultConfig().MinServerVersion != "" {
+		t.Errorf("DefaultConfig().MinServerVersion = %q, want empty", DefaultConfig().MinServerVersion)
+	}
+	if cfg := DefaultConfig().WithMinServerVersion("6.2"); cfg.MinServerVersion != "6.2" {
+		t.Errorf("WithMinServerVersion() = %q, want 6.2", cfg.MinServerVersion)
+	}
+}
+
+func TestWithWarmup(t *testing.T) {
+	if DefaultConfig().Warmup != 0 {
+		t.Errorf("DefaultConfig().Warmup = %d, want 0", DefaultConfig().Warmup)
+	}
+	if cfg := DefaultConfig().WithWarmup(8); cfg.Warmup != 8 {
+		t.Errorf("WithWarmup() = %d, want 8", cfg.Warmup)
+	}
+}
+
+func TestWithDNSRefresh(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.DNSRefresh != 0 || cfg.DNSRecycle {
+		t.Errorf("DefaultConfig() DNSRefresh = %v, DNSRecycle = %v, want disabled", cfg.DNSRefresh, cfg.DNSRecycle)
+	}
+	cfg = cfg.WithDNSRefresh(30*time.Second, true)
+	if cfg.DNSRefresh != 30*time.Second || !cfg.DNSRecycle {
+		t.Errorf("WithDNSRefresh() = %v, %v, want 30s, true", cfg.DNSRefresh, cfg.DNSRecycle)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	if cfg := DefaultConfig(); cfg.Logger != nil || cfg.LogSampleRate != 0 {
+		t.Error("DefaultConfig() logs commands, want no logger")
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := DefaultConfig().WithLogger(logger).WithLogSampleRate(0.1)
+	if cfg.Logger != logger || cfg.LogSampleRate != 0.1 {
+		t.Errorf("WithLogger() = %v, %v, want the logger at 0.1", cfg.Logger, cfg.LogSampleRate)
+	}
+}
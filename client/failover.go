@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+)
+
+// failoverDialer dials the first reachable of several seed addresses, starting from the one that
+// last accepted a connection. It moves on to the next address only when a dial fails, so after a
+// failover it stays on the standby, even once the former primary is reachable again, until the
+// standby fails in turn
+type failoverDialer struct {
+	forward   Dialer      // dials a seed address
+	tlsConfig *tls.Config // applied to the connection, nil when forward applies it
+	addrs     []string
+	current   atomic.Int64 // index of the address that last accepted a connection
+}
+
+func newFailoverDialer(forward Dialer, tlsConfig *tls.Config, addrs []string) *failoverDialer {
+	return &failoverDialer{forward: forward, tlsConfig: tlsConfig, addrs: addrs}
+}
+
+// dial connects to the current address, or failing that to the following ones in order; the
+// address go-redis asks for is ignored
+func (d *failoverDialer) dial(ctx context.Context, network, _ string) (net.Conn, error) {
+	start := d.current.Load()
+	var errs []error
+	for i := range d.addrs {
+		n := (start + int64(i)) % int64(len(d.addrs))
+		addr := d.addrs[n]
+		conn, err := d.forward(ctx, network, addr)
+		if err == nil {
+			if n != start {
+				d.current.CompareAndSwap(start, n)
+			}
+			host, _, _ := net.SplitHostPort(addr)
+			return tlsClient(conn, d.tlsConfig, host), nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", addr, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("failed to dial any redis address: %w", errors.Join(errs...))
+}
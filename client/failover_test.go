@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// seedDialer records the addresses dialed, failing those marked down
+type seedDialer struct {
+	mu     sync.Mutex
+	down   map[string]bool
+	dialed []string
+}
+
+func (d *seedDialer) setDown(addr string, down bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.down[addr] = down
+}
+
+func (d *seedDialer) dial(_ context.Context, _, addr string) (net.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dialed = append(d.dialed, addr)
+	if d.down[addr] {
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	_ = server.Close()
+	return client, nil
+}
+
+// next dials once and returns the addresses dialed
+func (d *seedDialer) next(t *testing.T, fd *failoverDialer) ([]string, error) {
+	t.Helper()
+	d.mu.Lock()
+	d.dialed = nil
+	d.mu.Unlock()
+	conn, err := fd.dial(context.Background(), "tcp", "ignored:6379")
+	if conn != nil {
+		_ = conn.Close()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.dialed, err
+}
+
+func TestFailoverDialer(t *testing.T) {
+	seeds := &seedDialer{down: make(map[string]bool)}
+	fd := newFailoverDialer(seeds.dial, nil, []string{"a:6379", "b:6379", "c:6379"})
+
+	steps := []struct {
+		name   string
+		down   []string
+		up     []string
+		dialed string
+	}{
+		{"primary first", nil, nil, "a:6379"},
+		{"fail over to standby", []string{"a:6379"}, nil, "a:6379,b:6379"},
+		{"stay on standby", nil, nil, "b:6379"},
+		{"stay after primary recovers", nil, []string{"a:6379"}, "b:6379"},
+		{"wrap around", []string{"b:6379", "c:6379"}, nil, "b:6379,c:6379,a:6379"},
+	}
+	for _, step := range steps {
+		for _, addr := range step.down {
+			seeds.setDown(addr, true)
+		}
+		for _, addr := range step.up {
+			seeds.setDown(addr, false)
+		}
+		dialed, err := seeds.next(t, fd)
+		if err != nil {
+			t.Fatalf("%s: dial() error = %v, want nil", step.name, err)
+		}
+		if got := strings.Join(dialed, ","); got != step.dialed {
+			t.Errorf("%s: dialed %s, want %s", step.name, got, step.dialed)
+		}
+	}
+
+	seeds.setDown("a:6379", true)
+	_, err := seeds.next(t, fd)
+	if err == nil || !strings.Contains(err.Error(), "c:6379") {
+		t.Errorf("dial() with every address down error = %v, want one naming each address", err)
+	}
+}
+
+func TestFailoverDialer_Canceled(t *testing.T) {
+	seeds := &seedDialer{down: map[string]bool{"a:6379": true}}
+	fd := newFailoverDialer(seeds.dial, nil, []string{"a:6379", "b:6379"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fd.dial(ctx, "tcp", "ignored:6379"); err == nil {
+		t.Error("dial() with a done context error = nil, want error")
+	}
+	if len(seeds.dialed) != 1 {
+		t.Errorf("dialed %v after the context was done, want only the first address", seeds.dialed)
+	}
+}
+
+func TestNewClient_Addrs(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on localhost: %v", err)
+	}
+	down := ln.Addr().String()
+	_ = ln.Close()
+	standby, _ := listenMock(t)
+
+	cfg := DefaultConfig().WithAddrs(down, standby).WithDialTimeout(2 * time.Second)
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() with a reachable standby error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+	if err := client.Set(context.Background(), "k", "v", 0).Err(); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	direct, err := NewClient(DefaultConfig().WithAddr(standby))
+	if err != nil {
+		t.Fatalf("NewClient() for the standby error = %v, want nil", err)
+	}
+	defer func() { _ = direct.Close() }()
+	if v, err := direct.Get(context.Background(), "k").Result(); err != nil || v != "v" {
+		t.Errorf("standby value = %q, %v, want %q", v, err, "v")
+	}
+
+	if _, err := NewClient(cfg.WithAddrs(down)); err == nil {
+		t.Error("NewClient() with every address down error = nil, want error")
+	}
+	if _, err := NewClient(cfg.WithAddrs(standby, "").WithLazyConnect(true)); err == nil {
+		t.Error("NewClient() with an empty seed address error = nil, want error")
+	}
+}
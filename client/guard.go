@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// ErrCommandDenied is returned for commands blocked by GuardCommands
+var ErrCommandDenied = errors.New("redis command denied")
+
+// DefaultDeniedCommands are the commands Config.WithCommandGuard blocks when given none: those
+// that wipe, stall, or reconfigure a whole server shared by other applications
+var DefaultDeniedCommands = []string{"FLUSHALL", "FLUSHDB", "KEYS", "CONFIG", "SHUTDOWN", "DEBUG"}
+
+// setupCommands are the commands go-redis sends to set up a connection; they are never blocked
+var setupCommands = map[string]struct{}{
+	"hello":                      {},
+	"auth":                       {},
+	"select":                     {},
+	"readonly":                   {},
+	"client setname":             {},
+	"client setinfo":             {},
+	"client maint_notifications": {},
+}
+
+// GuardCommands makes client fail the denied commands with ErrCommandDenied instead of sending
+// them. An entry is a command name, e.g. "KEYS", or a command and its subcommand, e.g.
+// "CONFIG SET" to still allow CONFIG GET; case does not matter. A pipeline holding a denied
+// command is not sent at all. Commands run by scripts are not inspected, and those go-redis
+// sends to set up a connection are always allowed. NewClient calls it when
+// Config.DeniedCommands is set
+func GuardCommands(client redis.UniversalClient, denied ...string) {
+	client = utils.NormalizeClient(client)
+	if client == nil || len(denied) == 0 {
+		return
+	}
+	h := guardHook{denied: make(map[string]struct{}, len(denied))}
+	for _, entry := range denied {
+		if entry = strings.Join(strings.Fields(strings.ToLower(entry)), " "); entry != "" {
+			h.denied[entry] = struct{}{}
+		}
+	}
+	client.AddHook(h)
+}
+
+// guardHook blocks denied commands before they reach the connection
+type guardHook struct {
+	denied map[string]struct{} // lowercase "name" or "name subcommand"
+}
+
+// check returns the error for cmd if it is denied
+func (h guardHook) check(cmd redis.Cmder) error {
+	name := cmd.Name()
+	var sub string
+	if args := cmd.Args(); len(args) > 1 {
+		if s, ok := args[1].(string); ok {
+			sub = name + " " + strings.ToLower(s)
+		}
+	}
+	if _, ok := setupCommands[name]; ok {
+		return nil
+	}
+	if _, ok := setupCommands[sub]; ok {
+		return nil
+	}
+	if _, ok := h.denied[name]; ok {
+		return fmt.Errorf("%w: %s is blocked by the command guard", ErrCommandDenied, strings.ToUpper(name))
+	}
+	if _, ok := h.denied[sub]; ok {
+		return fmt.Errorf("%w: %s is blocked by the command guard", ErrCommandDenied, strings.ToUpper(sub))
+	}
+	return nil
+}
+
+func (h guardHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h guardHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := h.check(cmd); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h guardHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if err := h.check(cmd); err != nil {
+				for _, cmd := range cmds {
+					cmd.SetErr(err)
+				}
+				return err
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
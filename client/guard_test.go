@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNewClient_CommandGuard(t *testing.T) {
+	addr, _ := listenMock(t)
+	ctx := context.Background()
+	cfg := DefaultConfig().WithAddr(addr).WithDialTimeout(2 * time.Second)
+
+	client, err := NewClient(cfg.WithCommandGuard())
+	if err != nil {
+		t.Fatalf("NewClient() with command guard error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if err := client.Set(ctx, "k", "v", 0).Err(); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	err = client.FlushDB(ctx).Err()
+	if !errors.Is(err, ErrCommandDenied) || !strings.Contains(err.Error(), "FLUSHDB") {
+		t.Errorf("FlushDB() error = %v, want ErrCommandDenied naming FLUSHDB", err)
+	}
+	if err := client.Keys(ctx, "*").Err(); !errors.Is(err, ErrCommandDenied) {
+		t.Errorf("Keys() error = %v, want ErrCommandDenied", err)
+	}
+	if v, err := client.Get(ctx, "k").Result(); err != nil || v != "v" {
+		t.Errorf("Get() after denied FLUSHDB = %q, %v, want %q", v, err, "v")
+	}
+
+	// A pipeline holding a denied command is not sent
+	_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "piped", "v", 0)
+		pipe.FlushDB(ctx)
+		return nil
+	})
+	if !errors.Is(err, ErrCommandDenied) {
+		t.Errorf("Pipelined() error = %v, want ErrCommandDenied", err)
+	}
+	if err := client.Get(ctx, "piped").Err(); !errors.Is(err, redis.Nil) {
+		t.Errorf("Get() after denied pipeline error = %v, want redis.Nil", err)
+	}
+}
+
+func TestNewClient_CommandGuardSubcommands(t *testing.T) {
+	addr, mock := listenMock(t)
+	ctx := context.Background()
+	cfg := DefaultConfig().WithAddr(addr).WithDialTimeout(2 * time.Second).WithClientName("guarded")
+
+	// Connection setup still sends CLIENT SETNAME
+	client, err := NewClient(cfg.WithCommandGuard("config set", "CLIENT"))
+	if err != nil {
+		t.Fatalf("NewClient() with CLIENT denied error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+	if names := mock.ClientNames(); !slices.Contains(names, "guarded") {
+		t.Errorf("client names = %v, want the connection named", names)
+	}
+
+	if err := client.ConfigSet(ctx, "maxmemory", "0").Err(); !errors.Is(err, ErrCommandDenied) {
+		t.Errorf("ConfigSet() error = %v, want ErrCommandDenied", err)
+	}
+	if err := client.ConfigGet(ctx, "maxmemory").Err(); errors.Is(err, ErrCommandDenied) {
+		t.Errorf("ConfigGet() error = %v, want CONFIG GET allowed", err)
+	}
+	if err := client.ClientList(ctx).Err(); !errors.Is(err, ErrCommandDenied) {
+		t.Errorf("ClientList() error = %v, want ErrCommandDenied", err)
+	}
+}
+
+func TestWithCommandGuard(t *testing.T) {
+	if got := DefaultConfig().DeniedCommands; got != nil {
+		t.Errorf("DefaultConfig().DeniedCommands = %v, want nil", got)
+	}
+	cfg := DefaultConfig().WithCommandGuard()
+	if len(cfg.DeniedCommands) != len(DefaultDeniedCommands) {
+		t.Errorf("WithCommandGuard() = %v, want %v", cfg.DeniedCommands, DefaultDeniedCommands)
+	}
+	cfg.DeniedCommands[0] = "changed"
+	if DefaultDeniedCommands[0] == "changed" {
+		t.Error("WithCommandGuard() shares DefaultDeniedCommands")
+	}
+	if got := DefaultConfig().WithCommandGuard("KEYS").DeniedCommands; len(got) != 1 || got[0] != "KEYS" {
+		t.Errorf("WithCommandGuard(KEYS) = %v, want [KEYS]", got)
+	}
+}
@@ -2,7 +2,11 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -44,3 +48,132 @@ func CheckHealth(ctx context.Context, client *redis.Client) HealthStatus {
 
 	return status
 }
+
+// healthResponse is the JSON form of a HealthStatus served by HealthHandler
+type healthResponse struct {
+	Healthy   bool          `json:"healthy"`
+	Latency   time.Duration `json:"latency"`
+	Error     string        `json:"error,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// HealthHandler returns an http.Handler that runs CheckHealth and writes the status as JSON,
+// for mounting as a readiness or liveness endpoint such as /readyz
+// The status code is 200 if Redis answered the ping and 503 otherwise
+func HealthHandler(client *redis.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		status := CheckHealth(req.Context(), client)
+		resp := healthResponse{Healthy: status.Healthy, Latency: status.Latency, Timestamp: status.Timestamp}
+		if status.Error != nil {
+			resp.Error = status.Error.Error()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if status.Healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// DetailedHealthStatus is a HealthStatus with server details read from INFO
+type DetailedHealthStatus struct {
+	HealthStatus
+
+	// InfoError is set when the ping succeeded but INFO could not be read, e.g. because an
+	// ACL forbids it; the detail fields are then zero
+	InfoError error
+
+	RedisVersion     string
+	Role             string // "master" or "slave"
+	Uptime           time.Duration
+	UsedMemory       int64 // bytes
+	ConnectedClients int64
+
+	// ConnectedReplicas is the number of replicas attached to a master
+	ConnectedReplicas int64
+
+	// ReplicationLag is, on a master, the largest lag reported for its replicas and, on a
+	// replica, the time since it last heard from its master
+	ReplicationLag time.Duration
+
+	// MasterLinkUp reports whether a replica is connected to its master; false on a master
+	MasterLinkUp bool
+
+	// Info holds every field INFO returned, keyed by field name
+	Info map[string]string
+}
+
+// CheckHealthDetailed performs CheckHealth and, if Redis is reachable, reads the server,
+// clients, memory, and replication sections of INFO
+func CheckHealthDetailed(ctx context.Context, client *redis.Client) DetailedHealthStatus {
+	status := DetailedHealthStatus{HealthStatus: CheckHealth(ctx, client)}
+	if !status.Healthy {
+		return status
+	}
+
+	infoCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	raw, err := client.Info(infoCtx, "server", "clients", "memory", "replication").Result()
+	if err != nil {
+		status.InfoError = fmt.Errorf("failed to read redis info: %w", err)
+		return status
+	}
+
+	info := parseInfo(raw)
+	status.Info = info
+	status.RedisVersion = info["redis_version"]
+	status.Role = info["role"]
+	status.Uptime = time.Duration(infoInt(info, "uptime_in_seconds")) * time.Second
+	status.UsedMemory = infoInt(info, "used_memory")
+	status.ConnectedClients = infoInt(info, "connected_clients")
+	status.ConnectedReplicas = infoInt(info, "connected_slaves")
+
+	if status.Role == "master" {
+		for i := int64(0); i < status.ConnectedReplicas; i++ {
+			replica := parseInfoList(info["slave"+strconv.FormatInt(i, 10)])
+			lag, _ := strconv.ParseInt(replica["lag"], 10, 64)
+			status.ReplicationLag = max(status.ReplicationLag, time.Duration(lag)*time.Second)
+		}
+	} else {
+		status.MasterLinkUp = info["master_link_status"] == "up"
+		status.ReplicationLag = time.Duration(infoInt(info, "master_last_io_seconds_ago")) * time.Second
+	}
+	return status
+}
+
+// parseInfo parses the field:value lines of an INFO reply, skipping section headers
+func parseInfo(raw string) map[string]string {
+	info := make(map[string]string)
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if field, value, ok := strings.Cut(line, ":"); ok {
+			info[field] = value
+		}
+	}
+	return info
+}
+
+// parseInfoList parses a comma-separated key=value INFO value, such as a replica's line
+func parseInfoList(value string) map[string]string {
+	fields := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// infoInt returns an integer INFO field, or 0 if it is missing or malformed
+func infoInt(info map[string]string, field string) int64 {
+	n, _ := strconv.ParseInt(info[field], 10, 64)
+	return n
+}
@@ -2,6 +2,10 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -105,3 +109,115 @@ func TestCheckHealth(t *testing.T) {
 		}
 	})
 }
+
+func TestHealthHandler(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	serve := func(h http.Handler) (*httptest.ResponseRecorder, map[string]any) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("HealthHandler() body %q is not JSON: %v", rec.Body.String(), err)
+		}
+		return rec, body
+	}
+
+	h := HealthHandler(client)
+	rec, body := serve(h)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("HealthHandler() = %d %q, want 200 application/json", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if body["healthy"] != true || body["timestamp"] == nil || body["error"] != nil {
+		t.Errorf("HealthHandler() body = %v, want a healthy status", body)
+	}
+
+	mock.SetShouldFail(true)
+	rec, body = serve(h)
+	if rec.Code != http.StatusServiceUnavailable || body["healthy"] != false || body["error"] == "" || body["error"] == nil {
+		t.Errorf("HealthHandler() failing = %d %v, want 503 with the error", rec.Code, body)
+	}
+
+	if rec, body := serve(HealthHandler(nil)); rec.Code != http.StatusServiceUnavailable || body["error"] != "redis client is nil" {
+		t.Errorf("HealthHandler(nil) = %d %v, want 503", rec.Code, body)
+	}
+}
+
+func TestCheckHealthDetailed(t *testing.T) {
+	t.Run("master", func(t *testing.T) {
+		client, mock := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		mock.SetInfo("replication", "connected_slaves", "2")
+		mock.SetInfo("replication", "slave0", "ip=10.0.0.2,port=6379,state=online,offset=100,lag=0")
+		mock.SetInfo("replication", "slave1", "ip=10.0.0.3,port=6379,state=online,offset=90,lag=3")
+
+		status := CheckHealthDetailed(context.Background(), client)
+		if !status.Healthy || status.InfoError != nil {
+			t.Fatalf("CheckHealthDetailed() = %+v, want healthy", status)
+		}
+		if status.RedisVersion == "" || status.Role != "master" || status.UsedMemory <= 0 || status.ConnectedClients < 1 {
+			t.Errorf("CheckHealthDetailed() details = %+v", status)
+		}
+		if status.ConnectedReplicas != 2 || status.ReplicationLag != 3*time.Second || status.MasterLinkUp {
+			t.Errorf("CheckHealthDetailed() replication = %d replicas, lag %v, link %v, want 2, 3s, false",
+				status.ConnectedReplicas, status.ReplicationLag, status.MasterLinkUp)
+		}
+		if status.Info["redis_mode"] != "standalone" {
+			t.Errorf("CheckHealthDetailed() Info = %v, want all fields", status.Info)
+		}
+	})
+
+	t.Run("replica", func(t *testing.T) {
+		client, mock := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		mock.SetInfo("server", "uptime_in_seconds", "3600")
+		mock.SetInfo("replication", "role", "slave")
+		mock.SetInfo("replication", "master_link_status", "up")
+		mock.SetInfo("replication", "master_last_io_seconds_ago", "2")
+
+		status := CheckHealthDetailed(context.Background(), client)
+		if status.Role != "slave" || !status.MasterLinkUp || status.ReplicationLag != 2*time.Second || status.Uptime != time.Hour {
+			t.Errorf("CheckHealthDetailed() replica = %+v", status)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		status := CheckHealthDetailed(context.Background(), nil)
+		if status.Healthy || status.Error == nil || status.Info != nil {
+			t.Errorf("CheckHealthDetailed() with nil client = %+v, want unhealthy without info", status)
+		}
+	})
+
+	t.Run("info failure", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+		client.AddHook(failInfoHook{})
+
+		status := CheckHealthDetailed(context.Background(), client)
+		if !status.Healthy || status.InfoError == nil {
+			t.Errorf("CheckHealthDetailed() with INFO denied = %+v, want healthy with InfoError", status)
+		}
+	})
+}
+
+// failInfoHook fails INFO commands, as an ACL denying them would
+type failInfoHook struct{}
+
+func (failInfoHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (failInfoHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "info" {
+			cmd.SetErr(errors.New("NOPERM this user has no permissions to run the 'info' command"))
+			return cmd.Err()
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (failInfoHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
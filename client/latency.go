@@ -0,0 +1,319 @@
+package client
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// Defaults for LatencyOptions
+const (
+	DefaultSlowThreshold = 10 * time.Millisecond
+	DefaultSlowLogSize   = 128
+)
+
+// scriptMarker matches the "-- redis-kit:<name>" line the kit's Lua scripts start with
+var scriptMarker = regexp.MustCompile(`--\s*redis-kit:([\w.-]+)`)
+
+// LatencyOptions configures a LatencyMonitor
+type LatencyOptions struct {
+	// SlowThreshold is the duration from which a command is captured in the slow log
+	// (default: DefaultSlowThreshold)
+	SlowThreshold time.Duration
+
+	// SlowLogSize is the number of slow commands kept, dropping the oldest beyond it
+	// (default: DefaultSlowLogSize)
+	SlowLogSize int
+
+	// Buckets are the upper bounds of the latency histograms in increasing order
+	// (default: metrics.DefaultBuckets)
+	Buckets []time.Duration
+
+	// RedactValues replaces the arguments after the key with "?" in captured statements
+	RedactValues bool
+}
+
+// SlowCommand is a command that took at least the slow threshold
+type SlowCommand struct {
+	Time      time.Time     // when the command started
+	Duration  time.Duration // how long it took, including retries by inner hooks
+	Command   string        // the command name, or "pipeline"
+	Operation string        // the kit script name for scripts marked "-- redis-kit:<name>", else Command
+	KeyPrefix string        // the prefix of the first key, which names the kit primitive (e.g. "lock:")
+	Statement string        // the command text, with scripts shortened; pipelines list one command per line
+	Err       error         // the error the command returned, if any
+}
+
+// LatencyHistogram is the latency distribution of one command
+type LatencyHistogram struct {
+	Command string
+	Bounds  []time.Duration // bucket upper bounds
+	Counts  []uint64        // commands per bucket; the last one counts those above every bound
+	Count   uint64          // total commands
+	Sum     time.Duration   // total duration
+	Max     time.Duration   // longest duration
+}
+
+// Mean returns the average duration
+func (h LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns an upper bound of the q-quantile (e.g. 0.99): the bound of the bucket it
+// falls in, or Max when it falls above every bound
+func (h LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(h.Count) + 0.5)
+	rank = min(max(rank, 1), h.Count)
+	var seen uint64
+	for i, n := range h.Counts {
+		seen += n
+		if seen >= rank && i < len(h.Bounds) {
+			return min(h.Bounds[i], h.Max)
+		}
+	}
+	return h.Max
+}
+
+// LatencyMonitor records the latency of every command of a client in per-command histograms
+// and keeps the slowest ones in a bounded log
+type LatencyMonitor struct {
+	opts LatencyOptions
+
+	mu         sync.Mutex
+	histograms map[string]*LatencyHistogram
+	slow       []SlowCommand // ring buffer
+	next       int           // where the next slow command goes
+	full       bool
+}
+
+// EnableLatencyMonitor adds a hook to client that feeds the returned monitor, covering the
+// commands issued by the kit's cache, lock, and rate limiter on top of it
+// Add it before other hooks to measure the time they take too; a nil client returns a monitor
+// that records nothing
+func EnableLatencyMonitor(client redis.UniversalClient, opts LatencyOptions) *LatencyMonitor {
+	m := NewLatencyMonitor(opts)
+	if client = utils.NormalizeClient(client); client != nil {
+		client.AddHook(latencyHook{monitor: m})
+	}
+	return m
+}
+
+// NewLatencyMonitor creates a monitor that is fed through Observe
+func NewLatencyMonitor(opts LatencyOptions) *LatencyMonitor {
+	if opts.SlowThreshold <= 0 {
+		opts.SlowThreshold = DefaultSlowThreshold
+	}
+	if opts.SlowLogSize <= 0 {
+		opts.SlowLogSize = DefaultSlowLogSize
+	}
+	if len(opts.Buckets) == 0 {
+		opts.Buckets = make([]time.Duration, len(metrics.DefaultBuckets))
+		for i, seconds := range metrics.DefaultBuckets {
+			opts.Buckets[i] = time.Duration(seconds * float64(time.Second))
+		}
+	} else {
+		opts.Buckets = append([]time.Duration(nil), opts.Buckets...)
+		sort.Slice(opts.Buckets, func(i, j int) bool { return opts.Buckets[i] < opts.Buckets[j] })
+	}
+	return &LatencyMonitor{
+		opts:       opts,
+		histograms: make(map[string]*LatencyHistogram),
+		slow:       make([]SlowCommand, opts.SlowLogSize),
+	}
+}
+
+// SlowThreshold returns the duration from which commands are captured in the slow log
+func (m *LatencyMonitor) SlowThreshold() time.Duration {
+	return m.opts.SlowThreshold
+}
+
+// Observe records a command that started at start; cmds holds one command, or the commands
+// of a pipeline
+func (m *LatencyMonitor) Observe(cmds []redis.Cmder, start time.Time, err error) {
+	if len(cmds) == 0 {
+		return
+	}
+	duration := time.Since(start)
+	name := "pipeline"
+	if len(cmds) == 1 {
+		name = cmds[0].Name()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.histograms[name]
+	if !ok {
+		h = &LatencyHistogram{
+			Command: name,
+			Bounds:  m.opts.Buckets,
+			Counts:  make([]uint64, len(m.opts.Buckets)+1),
+		}
+		m.histograms[name] = h
+	}
+	h.Counts[sort.Search(len(h.Bounds), func(i int) bool { return duration <= h.Bounds[i] })]++
+	h.Count++
+	h.Sum += duration
+	h.Max = max(h.Max, duration)
+
+	if duration < m.opts.SlowThreshold {
+		return
+	}
+	m.slow[m.next] = m.slowCommand(name, cmds, start, duration, err)
+	m.next = (m.next + 1) % len(m.slow)
+	if m.next == 0 {
+		m.full = true
+	}
+}
+
+// slowCommand describes cmds for the slow log
+func (m *LatencyMonitor) slowCommand(name string, cmds []redis.Cmder, start time.Time, duration time.Duration, err error) SlowCommand {
+	entry := SlowCommand{Time: start, Duration: duration, Command: name, Operation: name, Err: err}
+	statements := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		args := cmd.Args()
+		statements[i] = statement(shortenScript(args), m.opts.RedactValues)
+		if entry.KeyPrefix == "" {
+			entry.KeyPrefix = keyPrefix(commandKey(args))
+		}
+	}
+	entry.Statement = strings.Join(statements, "\n")
+	if len(cmds) == 1 {
+		if script := scriptName(cmds[0].Args()); script != "" {
+			entry.Operation = script
+		}
+	}
+	return entry
+}
+
+// scriptName returns the name in the redis-kit marker of an EVAL script, or ""
+func scriptName(args []interface{}) string {
+	if len(args) < 2 {
+		return ""
+	}
+	switch strings.ToLower(formatArg(args[0])) {
+	case "eval", "eval_ro":
+	default:
+		return ""
+	}
+	if match := scriptMarker.FindStringSubmatch(formatArg(args[1])); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// shortenScript replaces the body of an EVAL script with its redis-kit name, or "<script>"
+func shortenScript(args []interface{}) []interface{} {
+	if len(args) < 2 {
+		return args
+	}
+	switch strings.ToLower(formatArg(args[0])) {
+	case "eval", "eval_ro":
+	default:
+		return args
+	}
+	short := append([]interface{}(nil), args...)
+	short[1] = "<script>"
+	if name := scriptName(args); name != "" {
+		short[1] = "<redis-kit:" + name + ">"
+	}
+	return short
+}
+
+// SlowLog returns the captured slow commands, newest first
+func (m *LatencyMonitor) SlowLog() []SlowCommand {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := m.next
+	if m.full {
+		n = len(m.slow)
+	}
+	entries := make([]SlowCommand, 0, n)
+	for i := 1; i <= n; i++ {
+		entries = append(entries, m.slow[(m.next-i+len(m.slow))%len(m.slow)])
+	}
+	return entries
+}
+
+// Histograms returns the latency histogram of every command seen, sorted by command
+func (m *LatencyMonitor) Histograms() []LatencyHistogram {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	histograms := make([]LatencyHistogram, 0, len(m.histograms))
+	for _, h := range m.histograms {
+		c := *h
+		c.Counts = append([]uint64(nil), h.Counts...)
+		histograms = append(histograms, c)
+	}
+	sort.Slice(histograms, func(i, j int) bool { return histograms[i].Command < histograms[j].Command })
+	return histograms
+}
+
+// Histogram returns the latency histogram of command, and false if it was not seen
+func (m *LatencyMonitor) Histogram(command string) (LatencyHistogram, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.histograms[strings.ToLower(command)]
+	if !ok {
+		return LatencyHistogram{}, false
+	}
+	c := *h
+	c.Counts = append([]uint64(nil), h.Counts...)
+	return c, true
+}
+
+// Reset drops the histograms and the slow log
+func (m *LatencyMonitor) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.histograms = make(map[string]*LatencyHistogram)
+	m.slow = make([]SlowCommand, len(m.slow))
+	m.next = 0
+	m.full = false
+}
+
+// latencyHook feeds every command and pipeline of a client to a LatencyMonitor, leaving out
+// the commands that set up connections
+type latencyHook struct {
+	monitor *LatencyMonitor
+}
+
+func (h latencyHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h latencyHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if handshake(cmd) {
+			return next(ctx, cmd)
+		}
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.monitor.Observe([]redis.Cmder{cmd}, start, err)
+		return err
+	}
+}
+
+func (h latencyHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if handshake(cmds...) {
+			return next(ctx, cmds)
+		}
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.monitor.Observe(cmds, start, err)
+		return err
+	}
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/lock"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestEnableLatencyMonitor(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	// Every command is slow with a 1ns threshold
+	m := EnableLatencyMonitor(client, LatencyOptions{SlowThreshold: time.Nanosecond, RedactValues: true})
+	ctx := context.Background()
+
+	c := cache.NewLRUCache(client, "cache:user:", 10)
+	if err := c.Set(ctx, "1", "alice", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	locker := lock.NewRedisLocker(client, lock.WithKeyPrefix("lock:"))
+	if ok, err := locker.Lock("job"); err != nil || !ok {
+		t.Fatalf("Lock() = %v, %v, want true", ok, err)
+	}
+	_ = client.Get(ctx, "missing").Err()
+	pipe := client.Pipeline()
+	pipe.Incr(ctx, "counter:a")
+	pipe.Incr(ctx, "counter:b")
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatalf("pipeline error = %v, want nil", err)
+	}
+
+	log := m.SlowLog()
+	if len(log) != 4 {
+		t.Fatalf("SlowLog() has %d entries, want 4: %+v", len(log), log)
+	}
+
+	// Newest first
+	if log[0].Command != "pipeline" || log[0].KeyPrefix != "counter:" ||
+		log[0].Statement != "incr counter:a\nincr counter:b" {
+		t.Errorf("SlowLog()[0] = %+v, want the pipeline", log[0])
+	}
+	if log[1].Command != "get" || !errors.Is(log[1].Err, redis.Nil) {
+		t.Errorf("SlowLog()[1] = %+v, want the missing get", log[1])
+	}
+	if log[2].KeyPrefix != "lock:" || log[2].Statement != "set lock:job ? ? ? ?" {
+		t.Errorf("SlowLog()[2] = %+v, want the lock", log[2])
+	}
+	if log[3].Command != "eval" || log[3].Operation != "lru-set" || log[3].KeyPrefix != "cache:user:" ||
+		log[3].Statement != "eval <redis-kit:lru-set> 2 cache:user:1 ? ? ? ?" {
+		t.Errorf("SlowLog()[3] = %+v, want the redacted lru-set script", log[3])
+	}
+	for _, entry := range log {
+		if entry.Duration <= 0 || entry.Time.IsZero() {
+			t.Errorf("entry %+v has no timing", entry)
+		}
+	}
+
+	histograms := m.Histograms()
+	var names []string
+	for _, h := range histograms {
+		names = append(names, h.Command)
+	}
+	if got := strings.Join(names, ","); got != "eval,get,pipeline,set" {
+		t.Errorf("Histograms() commands = %s, want eval,get,pipeline,set", got)
+	}
+	h, ok := m.Histogram("GET")
+	if !ok || h.Count != 1 || h.Sum <= 0 || h.Max != h.Sum || len(h.Counts) != len(h.Bounds)+1 {
+		t.Errorf("Histogram(GET) = %+v, %v, want one observation", h, ok)
+	}
+	if _, ok := m.Histogram("del"); ok {
+		t.Error("Histogram(del) found, want false")
+	}
+
+	m.Reset()
+	if len(m.SlowLog()) != 0 || len(m.Histograms()) != 0 {
+		t.Error("Reset() kept observations")
+	}
+}
+
+func TestLatencyMonitor_Threshold(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	m := EnableLatencyMonitor(client, LatencyOptions{SlowThreshold: time.Hour})
+	if m.SlowThreshold() != time.Hour {
+		t.Errorf("SlowThreshold() = %v, want 1h", m.SlowThreshold())
+	}
+	for i := 0; i < 3; i++ {
+		_ = client.Set(context.Background(), "k", "v", 0).Err()
+	}
+	if log := m.SlowLog(); len(log) != 0 {
+		t.Errorf("SlowLog() = %+v, want empty below the threshold", log)
+	}
+	if h, _ := m.Histogram("set"); h.Count != 3 {
+		t.Errorf("Histogram(set).Count = %d, want 3", h.Count)
+	}
+
+	if m := NewLatencyMonitor(LatencyOptions{}); m.SlowThreshold() != DefaultSlowThreshold {
+		t.Errorf("default SlowThreshold() = %v, want %v", m.SlowThreshold(), DefaultSlowThreshold)
+	}
+}
+
+func TestLatencyMonitor_RingBuffer(t *testing.T) {
+	m := NewLatencyMonitor(LatencyOptions{SlowThreshold: time.Nanosecond, SlowLogSize: 3})
+	ctx := context.Background()
+	start := time.Now().Add(-time.Millisecond)
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		m.Observe([]redis.Cmder{redis.NewStringCmd(ctx, "get", key)}, start, nil)
+	}
+	m.Observe(nil, start, nil)
+
+	var got []string
+	for _, entry := range m.SlowLog() {
+		got = append(got, entry.Statement)
+	}
+	if strings.Join(got, ",") != "get e,get d,get c" {
+		t.Errorf("SlowLog() = %v, want the 3 newest entries", got)
+	}
+
+	m.Observe([]redis.Cmder{redis.NewCmd(ctx, "eval", "return 1", 0)}, start, nil)
+	if log := m.SlowLog(); log[0].Statement != "eval <script> 0" || log[0].Operation != "eval" {
+		t.Errorf("unmarked script entry = %+v, want a shortened statement", log[0])
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	m := NewLatencyMonitor(LatencyOptions{Buckets: []time.Duration{10 * time.Millisecond, time.Millisecond}})
+	ctx := context.Background()
+	cmd := []redis.Cmder{redis.NewStatusCmd(ctx, "ping")}
+	for i := 0; i < 8; i++ {
+		m.Observe(cmd, time.Now(), nil)
+	}
+	m.Observe(cmd, time.Now().Add(-5*time.Millisecond), nil)
+	m.Observe(cmd, time.Now().Add(-50*time.Millisecond), nil)
+
+	h, _ := m.Histogram("ping")
+	if h.Bounds[0] != time.Millisecond || h.Counts[0] != 8 || h.Counts[1] != 1 || h.Counts[2] != 1 {
+		t.Fatalf("Histogram(ping) = %+v, want 8/1/1 over sorted bounds", h)
+	}
+	if q := h.Quantile(0.5); q != time.Millisecond {
+		t.Errorf("Quantile(0.5) = %v, want 1ms", q)
+	}
+	if q := h.Quantile(0.9); q != 10*time.Millisecond {
+		t.Errorf("Quantile(0.9) = %v, want 10ms", q)
+	}
+	if q := h.Quantile(1); q != h.Max || q < 50*time.Millisecond {
+		t.Errorf("Quantile(1) = %v, want Max %v", q, h.Max)
+	}
+	if h.Mean() <= 0 {
+		t.Errorf("Mean() = %v, want > 0", h.Mean())
+	}
+	if (LatencyHistogram{}).Quantile(0.5) != 0 || (LatencyHistogram{}).Mean() != 0 {
+		t.Error("empty histogram Quantile()/Mean() != 0")
+	}
+}
+
+func TestEnableLatencyMonitor_NilClient(t *testing.T) {
+	m := EnableLatencyMonitor(nil, LatencyOptions{})
+	if m == nil || len(m.SlowLog()) != 0 {
+		t.Error("EnableLatencyMonitor(nil) should return an empty monitor")
+	}
+}
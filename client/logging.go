@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// LogOptions configures the command log installed by LogCommands
+type LogOptions struct {
+	// SampleRate is the fraction of successful commands logged, in (0, 1]; failures are always
+	// logged (default: 1, every command)
+	SampleRate float64
+}
+
+// attemptsKey carries the attempt counter a logHook shares with the retryHook inside it
+type attemptsKey struct{}
+
+// countAttempt counts an attempt of the command ctx belongs to, if it is logged
+func countAttempt(ctx context.Context) {
+	if attempts, ok := ctx.Value(attemptsKey{}).(*int); ok {
+		*attempts++
+	}
+}
+
+// LogCommands logs every command and pipeline of client to logger with its name, duration,
+// key prefix, retries, and error. Successful commands are logged at debug level, error
+// replies and context errors at warn, and connection failures at error, so the handler's
+// level picks what is kept; a missing key is a success. NewClient calls it when
+// Config.Logger is set, installing it outside the retry hook so retries are counted; go-redis's
+// own retries are not visible to hooks
+func LogCommands(client redis.UniversalClient, logger *slog.Logger, opts LogOptions) {
+	client = utils.NormalizeClient(client)
+	if client == nil || logger == nil {
+		return
+	}
+	if opts.SampleRate <= 0 || opts.SampleRate > 1 {
+		opts.SampleRate = 1
+	}
+	client.AddHook(logHook{logger: logger, sampleRate: opts.SampleRate})
+}
+
+// logHook logs commands and pipelines as they complete
+type logHook struct {
+	logger     *slog.Logger
+	sampleRate float64
+}
+
+// level returns the level a command completing with err is logged at
+func (h logHook) level(err error) slog.Level {
+	switch {
+	case err == nil, errors.Is(err, redis.Nil):
+		return slog.LevelDebug
+	case isServerReply(err), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// log logs a command unless it succeeded and is not sampled
+func (h logHook) log(ctx context.Context, command string, err error, attempts int, start time.Time, attrs ...slog.Attr) {
+	level := h.level(err)
+	if level == slog.LevelDebug && h.sampleRate < 1 && rand.Float64() >= h.sampleRate {
+		return
+	}
+	if !h.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs = append(attrs,
+		slog.String("command", command),
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("retries", max(attempts-1, 0)),
+	)
+	msg := "redis-kit command"
+	if level > slog.LevelDebug {
+		msg = "redis-kit command failed"
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	h.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+func (h logHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h logHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if handshake(cmd) {
+			return next(ctx, cmd)
+		}
+		var attempts int
+		start := time.Now()
+		err := next(context.WithValue(ctx, attemptsKey{}, &attempts), cmd)
+		var attrs []slog.Attr
+		if prefix := keyPrefix(commandKey(cmd.Args())); prefix != "" {
+			attrs = append(attrs, slog.String("key_prefix", prefix))
+		}
+		h.log(ctx, cmd.Name(), err, attempts, start, attrs...)
+		return err
+	}
+}
+
+func (h logHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if handshake(cmds...) {
+			return next(ctx, cmds)
+		}
+		var attempts int
+		start := time.Now()
+		err := next(context.WithValue(ctx, attemptsKey{}, &attempts), cmds)
+		h.log(ctx, "pipeline", err, attempts, start, slog.Int("commands", len(cmds)))
+		return err
+	}
+}
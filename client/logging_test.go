@@ -0,0 +1,160 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+// logBuffer collects JSON log entries
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) logger() *slog.Logger {
+	return slog.New(slog.NewJSONHandler(b, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func (b *logBuffer) entries(t *testing.T) []map[string]any {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	b.buf.Reset()
+	return entries
+}
+
+func TestLogCommands(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	var logs logBuffer
+	LogCommands(client, logs.logger(), LogOptions{})
+	ctx := context.Background()
+
+	_ = client.Set(ctx, "user:1", "v", 0).Err()
+	_ = client.Get(ctx, "missing").Err()
+	pipe := client.Pipeline()
+	pipe.Get(ctx, "a")
+	pipe.Get(ctx, "b")
+	_, _ = pipe.Exec(ctx)
+	mock.SetShouldFail(true)
+	_ = client.Get(ctx, "user:2").Err()
+
+	entries := logs.entries(t)
+	if len(entries) != 4 {
+		t.Fatalf("logged %d entries, want 4: %v", len(entries), entries)
+	}
+	tests := []struct {
+		level, msg, command string
+	}{
+		{"DEBUG", "redis-kit command", "set"},
+		{"DEBUG", "redis-kit command", "get"},
+		{"DEBUG", "redis-kit command", "pipeline"},
+		{"WARN", "redis-kit command failed", "get"},
+	}
+	for i, tt := range tests {
+		e := entries[i]
+		if e["level"] != tt.level || e["msg"] != tt.msg || e["command"] != tt.command {
+			t.Errorf("entry %d = %v, want %s %q for %s", i, e, tt.level, tt.msg, tt.command)
+		}
+		if _, ok := e["duration"]; !ok || e["retries"] != float64(0) {
+			t.Errorf("entry %d = %v, want a duration and no retries", i, e)
+		}
+	}
+	if entries[0]["key_prefix"] != "user:" || entries[2]["commands"] != float64(2) || entries[3]["error"] == nil {
+		t.Errorf("entries = %v, want the key prefix, pipeline size, and error", entries)
+	}
+
+	LogCommands(nil, logs.logger(), LogOptions{})
+	LogCommands(client, nil, LogOptions{})
+}
+
+func TestLogCommands_Sampling(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	var logs logBuffer
+	LogCommands(client, logs.logger(), LogOptions{SampleRate: 1e-12})
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		_ = client.Set(ctx, "k", "v", 0).Err()
+	}
+	mock.SetShouldFail(true)
+	_ = client.Get(ctx, "k").Err()
+
+	entries := logs.entries(t)
+	if len(entries) != 1 || entries[0]["level"] != "WARN" {
+		t.Errorf("entries = %v, want only the failure", entries)
+	}
+}
+
+func TestNewClient_Logger(t *testing.T) {
+	addr, mock := listenMock(t)
+	var logs logBuffer
+	cfg := DefaultConfig().WithAddr(addr).
+		WithLogger(logs.logger()).
+		WithMaxRetries(2).
+		WithRetryBackoff(time.Millisecond, time.Millisecond).
+		WithRetryPredicate(func(error) bool { return true })
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	mock.SetShouldFail(true)
+	_ = client.Get(context.Background(), "k").Err()
+
+	entries := logs.entries(t)
+	last := entries[len(entries)-1]
+	if last["command"] != "get" || last["retries"] != float64(2) {
+		t.Errorf("last entry = %v, want get with 2 retries", last)
+	}
+}
+
+func TestLogHook_Level(t *testing.T) {
+	tests := []struct {
+		err  error
+		want slog.Level
+	}{
+		{nil, slog.LevelDebug},
+		{redis.Nil, slog.LevelDebug},
+		{redis.ErrClosed, slog.LevelError},
+		{io.EOF, slog.LevelError},
+		{context.DeadlineExceeded, slog.LevelWarn},
+		{errors.New("plain"), slog.LevelError},
+	}
+	for _, tt := range tests {
+		if got := (logHook{}).level(tt.err); got != tt.want {
+			t.Errorf("level(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
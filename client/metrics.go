@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+)
+
+// Instrument reports every command, pipeline, and dial of client to r
+// A nil r reports to metrics.Default(). NewClient instruments its clients already
+func Instrument(client *redis.Client, r metrics.Recorder) {
+	if client == nil {
+		return
+	}
+	client.AddHook(metricsHook{recorder: r})
+}
+
+// metricsHook labels commands by name, pipelines as "pipeline", and new connections as "dial"
+type metricsHook struct {
+	recorder metrics.Recorder
+}
+
+func (h metricsHook) observe(operation string, err error, start time.Time) {
+	metrics.Observe(h.recorder, metrics.SubsystemClient, operation, metrics.Outcome(err), start)
+}
+
+func (h metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		start := time.Now()
+		conn, err := next(ctx, network, addr)
+		h.observe("dial", err, start)
+		return conn, err
+	}
+}
+
+func (h metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.observe(cmd.Name(), err, start)
+		return err
+	}
+}
+
+func (h metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.observe("pipeline", err, start)
+		return err
+	}
+}
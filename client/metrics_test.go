@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestInstrument(t *testing.T) {
+	_, mock := testutil.NewMockRedisClient()
+	rec := testutil.NewRecorder()
+
+	cfg := DefaultConfig().
+		WithAddr("mock").
+		WithDialTimeout(2 * time.Second).
+		WithRecorder(rec)
+	cfg.Dialer = mock.Dialer()
+
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	_ = client.Set(ctx, "k", "v", 0).Err()
+	_ = client.Get(ctx, "missing").Err()
+	pipe := client.Pipeline()
+	pipe.Get(ctx, "k")
+	_, _ = pipe.Exec(ctx)
+
+	for _, tt := range []struct{ operation, outcome string }{
+		{"dial", metrics.OutcomeSuccess},
+		{"ping", metrics.OutcomeSuccess},
+		{"set", metrics.OutcomeSuccess},
+		{"get", metrics.OutcomeMiss},
+		{"pipeline", metrics.OutcomeSuccess},
+	} {
+		if n := rec.Count(metrics.SubsystemClient, tt.operation, tt.outcome); n < 1 {
+			t.Errorf("Count(%s, %s) = %d, want at least 1", tt.operation, tt.outcome, n)
+		}
+	}
+
+	// Instrumenting a nil client is a no-op
+	Instrument(nil, rec)
+}
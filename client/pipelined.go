@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// Batch queues commands for Pipelined, executing them in pipelines of bounded size
+type Batch struct {
+	ctx       context.Context
+	client    redis.UniversalClient
+	chunkSize int
+
+	pipe   redis.Pipeliner
+	chunks int
+	cmds   []redis.Cmder
+	errs   []error
+}
+
+// Queue runs queue to add commands to the current pipeline, and executes the pipeline once it
+// holds at least the chunk size. A closure's commands are never split across pipelines, so a
+// chunk may exceed the size by the commands of its last closure
+func (b *Batch) Queue(queue func(pipe redis.Pipeliner)) {
+	queue(b.pipe)
+	if b.pipe.Len() >= b.chunkSize {
+		b.flush()
+	}
+}
+
+// flush executes the current pipeline and starts a new one; after the context is done, the
+// queued commands fail with its error instead
+func (b *Batch) flush() {
+	if b.pipe.Len() == 0 {
+		return
+	}
+	chunk := b.chunks
+	b.chunks++
+
+	var cmds []redis.Cmder
+	if err := b.ctx.Err(); err != nil {
+		cmds = b.pipe.Cmds()
+		for _, cmd := range cmds {
+			cmd.SetErr(err)
+		}
+		b.pipe.Discard()
+		b.errs = append(b.errs, fmt.Errorf("pipeline chunk %d failed: %w", chunk, err))
+	} else {
+		var err error
+		cmds, err = b.pipe.Exec(b.ctx)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			b.errs = append(b.errs, fmt.Errorf("pipeline chunk %d failed: %w", chunk, err))
+		}
+	}
+	b.cmds = append(b.cmds, cmds...)
+	b.pipe = b.client.Pipeline()
+}
+
+// Pipelined runs fn, which queues commands on a Batch, and executes them in pipelines of at
+// most chunkSize commands, each sent as soon as it fills, so thousands of operations issued in a
+// loop cost a round trip per chunk with bounded memory. A chunkSize <= 0 uses
+// utils.DefaultChunkSize
+// It returns every executed command in queue order and the errors of all failed chunks joined;
+// a failed chunk does not stop the following ones, and redis.Nil replies are not failures. If
+// fn returns an error, the commands not yet sent are discarded and the error is returned with
+// the commands already executed
+func Pipelined(ctx context.Context, client redis.UniversalClient, chunkSize int, fn func(b *Batch) error) ([]redis.Cmder, error) {
+	client = utils.NormalizeClient(client)
+	if client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if chunkSize <= 0 {
+		chunkSize = utils.DefaultChunkSize
+	}
+
+	b := &Batch{ctx: ctx, client: client, chunkSize: chunkSize, pipe: client.Pipeline()}
+	if err := fn(b); err != nil {
+		b.pipe.Discard()
+		return b.cmds, err
+	}
+	b.flush()
+	return b.cmds, errors.Join(b.errs...)
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+// pipelineCounter counts the pipelines a client sends
+type pipelineCounter struct {
+	sizes []int
+}
+
+func (p *pipelineCounter) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (p *pipelineCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+
+func (p *pipelineCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !handshake(cmds...) {
+			p.sizes = append(p.sizes, len(cmds))
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func TestPipelined(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	counter := &pipelineCounter{}
+	client.AddHook(counter)
+	ctx := context.Background()
+
+	cmds, err := Pipelined(ctx, client, 10, func(b *Batch) error {
+		for i := 0; i < 25; i++ {
+			b.Queue(func(pipe redis.Pipeliner) {
+				pipe.Set(ctx, fmt.Sprintf("k%d", i), i, 0)
+			})
+		}
+		b.Queue(func(pipe redis.Pipeliner) { pipe.Get(ctx, "missing") })
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Pipelined() error = %v, want nil", err)
+	}
+	if len(cmds) != 26 || fmt.Sprint(counter.sizes) != "[10 10 6]" {
+		t.Fatalf("Pipelined() ran %d commands in pipelines %v, want 26 in [10 10 6]", len(cmds), counter.sizes)
+	}
+	if cmds[24].Args()[1] != "k24" {
+		t.Errorf("cmds[24] = %v, want the commands in queue order", cmds[24].Args())
+	}
+	if v, _ := client.Get(ctx, "k24").Result(); v != "24" {
+		t.Errorf("k24 = %q, want 24", v)
+	}
+
+	// A closure's commands stay in one pipeline
+	counter.sizes = nil
+	_, _ = Pipelined(ctx, client, 2, func(b *Batch) error {
+		for i := 0; i < 2; i++ {
+			b.Queue(func(pipe redis.Pipeliner) {
+				pipe.Incr(ctx, "a")
+				pipe.Incr(ctx, "b")
+				pipe.Incr(ctx, "c")
+			})
+		}
+		return nil
+	})
+	if fmt.Sprint(counter.sizes) != "[3 3]" {
+		t.Errorf("pipelines = %v, want [3 3]", counter.sizes)
+	}
+}
+
+func TestPipelined_Errors(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	// A failed chunk is reported and the following ones still run
+	cmds, err := Pipelined(ctx, client, 2, func(b *Batch) error {
+		for i := 0; i < 6; i++ {
+			b.Queue(func(pipe redis.Pipeliner) {
+				if i == 3 {
+					pipe.Do(ctx, "nosuchcommand")
+					return
+				}
+				pipe.Set(ctx, fmt.Sprintf("e%d", i), i, 0)
+			})
+		}
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "pipeline chunk 1 failed") || strings.Contains(err.Error(), "chunk 2") {
+		t.Errorf("Pipelined() error = %v, want chunk 1 to fail", err)
+	}
+	if len(cmds) != 6 || cmds[5].Err() != nil {
+		t.Errorf("Pipelined() = %d commands, last error %v, want all 6 run", len(cmds), cmds[5].Err())
+	}
+
+	// Chunks queued after the context is done are not sent
+	cctx, cancel := context.WithCancel(ctx)
+	cmds, err = Pipelined(cctx, client, 1, func(b *Batch) error {
+		b.Queue(func(pipe redis.Pipeliner) { pipe.Set(cctx, "c1", 1, 0) })
+		cancel()
+		b.Queue(func(pipe redis.Pipeliner) { pipe.Set(cctx, "c2", 1, 0) })
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || len(cmds) != 2 || !errors.Is(cmds[1].Err(), context.Canceled) {
+		t.Errorf("Pipelined() after cancel = %v, %v, want the second chunk canceled", cmds, err)
+	}
+	if client.Exists(ctx, "c2").Val() != 0 {
+		t.Error("Pipelined() sent a chunk after the context was canceled")
+	}
+
+	// An error from fn discards the unsent commands
+	fnErr := errors.New("stop")
+	cmds, err = Pipelined(ctx, client, 10, func(b *Batch) error {
+		b.Queue(func(pipe redis.Pipeliner) { pipe.Set(ctx, "discarded", 1, 0) })
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) || len(cmds) != 0 || client.Exists(ctx, "discarded").Val() != 0 {
+		t.Errorf("Pipelined() with fn error = %v, %v, want nothing sent", cmds, err)
+	}
+
+	if _, err := Pipelined(ctx, nil, 10, func(*Batch) error { return nil }); err == nil {
+		t.Error("Pipelined(nil) error = nil, want error")
+	}
+}
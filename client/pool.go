@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultPoolLogInterval is how often LogPoolStats logs when given no interval
+const DefaultPoolLogInterval = time.Minute
+
+// PoolStatus is a snapshot of a client's connection pool
+// The counters are cumulative since the client was created
+type PoolStatus struct {
+	TotalConns uint32 // connections in the pool, idle or in use
+	IdleConns  uint32 // connections waiting to be used
+	StaleConns uint32 // connections removed from the pool as stale
+
+	Hits     uint32 // times an idle connection was available
+	Misses   uint32 // times a new connection had to be dialed
+	Timeouts uint32 // times no connection became free within PoolTimeout
+
+	WaitCount    uint32        // times a command waited for a free connection
+	WaitDuration time.Duration // total time commands waited for a free connection
+}
+
+// InUseConns returns the number of connections running commands
+func (s PoolStatus) InUseConns() uint32 {
+	if s.IdleConns > s.TotalConns {
+		return 0
+	}
+	return s.TotalConns - s.IdleConns
+}
+
+// PoolStats returns the pool statistics of client; for cluster and ring clients they are
+// summed over the nodes. A nil client returns a zero PoolStatus
+func PoolStats(client redis.UniversalClient) PoolStatus {
+	if client == nil {
+		return PoolStatus{}
+	}
+	stats := client.PoolStats()
+	if stats == nil {
+		return PoolStatus{}
+	}
+	return PoolStatus{
+		TotalConns:   stats.TotalConns,
+		IdleConns:    stats.IdleConns,
+		StaleConns:   stats.StaleConns,
+		Hits:         stats.Hits,
+		Misses:       stats.Misses,
+		Timeouts:     stats.Timeouts,
+		WaitCount:    stats.WaitCount,
+		WaitDuration: time.Duration(stats.WaitDurationNs),
+	}
+}
+
+// LogPoolStats logs the pool statistics of client every interval until ctx is cancelled
+// Statistics are logged at info level, or at warn level when pool timeouts occurred since the
+// previous log, which means commands failed because the pool was exhausted; a nil logger uses
+// slog.Default() and an interval <= 0 uses DefaultPoolLogInterval
+func LogPoolStats(ctx context.Context, client redis.UniversalClient, interval time.Duration, logger *slog.Logger) {
+	if client == nil {
+		return
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if interval <= 0 {
+		interval = DefaultPoolLogInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	previous := PoolStats(client)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stats := PoolStats(client)
+		attrs := []any{
+			"total", stats.TotalConns,
+			"idle", stats.IdleConns,
+			"in_use", stats.InUseConns(),
+			"stale", stats.StaleConns,
+			"hits", stats.Hits,
+			"misses", stats.Misses,
+			"timeouts", stats.Timeouts,
+			"wait_count", stats.WaitCount,
+			"wait_duration", stats.WaitDuration,
+		}
+		if stats.Timeouts > previous.Timeouts {
+			logger.Warn("redis-kit connection pool exhausted", append(attrs, "new_timeouts", stats.Timeouts-previous.Timeouts)...)
+		} else {
+			logger.Info("redis-kit connection pool", attrs...)
+		}
+		previous = stats
+	}
+}
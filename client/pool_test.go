@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestPoolStats(t *testing.T) {
+	if stats := PoolStats(nil); stats != (PoolStatus{}) {
+		t.Errorf("PoolStats(nil) = %+v, want zero", stats)
+	}
+
+	mock := testutil.NewMockRedis()
+	cfg := DefaultConfig().WithAddr("mock").WithPoolSize(1).WithMinIdleConns(0).WithPoolTimeout(20 * time.Millisecond).WithMaxRetries(-1)
+	cfg.Dialer = mock.Dialer()
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	_ = client.Set(ctx, "k", "v", 0)
+	stats := PoolStats(client)
+	if stats.TotalConns != 1 || stats.IdleConns != 1 || stats.InUseConns() != 0 || stats.Hits == 0 || stats.Misses == 0 {
+		t.Errorf("PoolStats() = %+v, want one idle connection reused", stats)
+	}
+
+	// Holding the only connection exhausts the pool
+	conn := client.Conn()
+	defer func() { _ = conn.Close() }()
+	_ = conn.Ping(ctx)
+	if stats := PoolStats(client); stats.InUseConns() != 1 {
+		t.Errorf("InUseConns() = %d, want 1", stats.InUseConns())
+	}
+	if err := client.Get(ctx, "k").Err(); err == nil {
+		t.Error("Get() with the pool exhausted error = nil, want pool timeout")
+	}
+	if stats := PoolStats(client); stats.Timeouts != 1 {
+		t.Errorf("PoolStats() = %+v, want a timeout", stats)
+	}
+
+	ring, _ := testutil.NewMockRingClient()
+	defer func() { _ = ring.Close() }()
+	_ = ring.Set(ctx, "k", "v", 0)
+	if stats := PoolStats(ring); stats.TotalConns == 0 {
+		t.Errorf("PoolStats(ring) = %+v, want connections", stats)
+	}
+}
+
+func TestLogPoolStats(t *testing.T) {
+	mock := testutil.NewMockRedis()
+	cfg := DefaultConfig().WithAddr("mock").WithPoolSize(1).WithMinIdleConns(0).WithPoolTimeout(20 * time.Millisecond).WithMaxRetries(-1)
+	cfg.Dialer = mock.Dialer()
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		LogPoolStats(ctx, client, 30*time.Millisecond, logger)
+	}()
+
+	conn := client.Conn()
+	_ = conn.Ping(ctx)
+	_ = client.Get(ctx, "k").Err()
+	_ = conn.Close()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-done
+
+	out := buf.String()
+	if !strings.Contains(out, "connection pool exhausted") || !strings.Contains(out, "new_timeouts=1") {
+		t.Errorf("LogPoolStats() log = %q, want a pool exhaustion warning", out)
+	}
+	if !strings.Contains(out, "level=INFO") || !strings.Contains(out, "total=1") {
+		t.Errorf("LogPoolStats() log = %q, want periodic statistics", out)
+	}
+
+	// A nil client returns immediately
+	LogPoolStats(context.Background(), nil, time.Millisecond, logger)
+}
@@ -0,0 +1,332 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// SOCKS5 protocol constants (RFC 1928, RFC 1929)
+const (
+	socksVersion        = 0x05
+	socksAuthNone       = 0x00
+	socksAuthPassword   = 0x02
+	socksAuthNoMethod   = 0xff
+	socksPasswordVer    = 0x01
+	socksCmdConnect     = 0x01
+	socksAddrIPv4       = 0x01
+	socksAddrDomain     = 0x03
+	socksAddrIPv6       = 0x04
+	socksReplySucceeded = 0x00
+)
+
+// socksReplies describes the SOCKS5 reply codes
+var socksReplies = map[byte]string{
+	0x01: "general failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// proxyDialer tunnels connections through a SOCKS5 or HTTP CONNECT proxy
+type proxyDialer struct {
+	proxy     *url.URL
+	forward   Dialer      // dials the proxy
+	tlsConfig *tls.Config // applied to the tunnel, as go-redis does not apply it to custom dialers
+	timeout   time.Duration
+}
+
+// buildDialer returns the dialer for cfg: the address dialer, wrapped in a failover dialer when
+// cfg.Addrs lists several addresses
+func buildDialer(cfg Config, tlsConfig *tls.Config) (Dialer, error) {
+	dial, err := buildAddrDialer(cfg, tlsConfig)
+	if err != nil || len(cfg.Addrs) < 2 {
+		return dial, err
+	}
+	if dial == nil {
+		// go-redis's dialer would apply TLS, so the failover dialer does in its place
+		return newFailoverDialer(netDialer(cfg), tlsConfig, cfg.Addrs).dial, nil
+	}
+	return newFailoverDialer(dial, nil, cfg.Addrs).dial, nil
+}
+
+// buildAddrDialer returns the dialer of one address for cfg: a proxy dialer when cfg.Proxy is
+// set, wrapped in a resolving dialer when cfg.DNSRefresh is set, else cfg.Dialer, which may be
+// nil for go-redis's own
+func buildAddrDialer(cfg Config, tlsConfig *tls.Config) (Dialer, error) {
+	if cfg.Proxy == "" && cfg.DNSRefresh <= 0 {
+		return cfg.Dialer, nil
+	}
+
+	forward := cfg.Dialer
+	if forward == nil {
+		forward = netDialer(cfg)
+	}
+	if cfg.DNSRefresh <= 0 {
+		return newProxyDialer(cfg, forward, tlsConfig)
+	}
+
+	if cfg.Network == "unix" {
+		return nil, fmt.Errorf("redis dns refresh is not supported over unix sockets")
+	}
+	if cfg.Proxy != "" {
+		// The proxy connects to the resolved addresses; the resolving dialer applies TLS
+		proxy, err := newProxyDialer(cfg, forward, nil)
+		if err != nil {
+			return nil, err
+		}
+		forward = proxy
+	}
+	return newResolvingDialer(forward, tlsConfig, cfg.DNSRefresh, cfg.DNSRecycle).dial, nil
+}
+
+// netDialer returns a TCP dialer configured like go-redis's own
+func netDialer(cfg Config) Dialer {
+	d := &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 5 * time.Minute}
+	return d.DialContext
+}
+
+// newProxyDialer returns a dialer tunneling through cfg.Proxy, dialing the proxy with forward
+func newProxyDialer(cfg Config, forward Dialer, tlsConfig *tls.Config) (Dialer, error) {
+	if cfg.Network == "unix" {
+		return nil, fmt.Errorf("redis proxy is not supported over unix sockets")
+	}
+
+	proxy, err := url.Parse(cfg.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis proxy url: %w", err)
+	}
+	switch proxy.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, fmt.Errorf("unsupported redis proxy scheme: %q", proxy.Scheme)
+	}
+	if proxy.Hostname() == "" {
+		return nil, fmt.Errorf("redis proxy host is required")
+	}
+
+	d := &proxyDialer{proxy: proxy, forward: forward, tlsConfig: tlsConfig, timeout: cfg.DialTimeout}
+	return d.dial, nil
+}
+
+// proxyAddr returns the proxy address with the scheme's default port
+func (d *proxyDialer) proxyAddr() string {
+	port := d.proxy.Port()
+	if port == "" {
+		port = "1080"
+		if d.proxy.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(d.proxy.Hostname(), port)
+}
+
+// dial connects to addr through the proxy
+func (d *proxyDialer) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := d.forward(ctx, "tcp", d.proxyAddr())
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial redis proxy: %w", err)
+	}
+
+	// Bound the handshake by the context and the dial timeout
+	deadline, ok := ctx.Deadline()
+	if d.timeout > 0 && (!ok || time.Until(deadline) > d.timeout) {
+		deadline, ok = time.Now().Add(d.timeout), true
+	}
+	if ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if d.proxy.Scheme == "http" {
+		conn, err = d.connectHTTP(conn, addr)
+	} else {
+		err = d.connectSOCKS(ctx, conn, addr)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	host, _, _ := net.SplitHostPort(addr)
+	return tlsClient(conn, d.tlsConfig, host), nil
+}
+
+// connectHTTP asks an HTTP proxy to open a tunnel to addr with CONNECT
+func (d *proxyDialer) connectHTTP(conn net.Conn, addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := d.proxy.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to send proxy connect request: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proxy connect response: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("redis proxy refused connection to %s: %s", addr, resp.Status)
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn reads the bytes a proxy sent after its response before the connection's own
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// connectSOCKS asks a SOCKS5 proxy to connect to addr; socks5 resolves host names locally and
+// socks5h lets the proxy resolve them
+func (d *proxyDialer) connectSOCKS(ctx context.Context, conn net.Conn, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid redis address %q: %w", addr, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid redis port %q", portStr)
+	}
+
+	// Greeting, offering username/password authentication when credentials are set
+	methods := []byte{socksAuthNone}
+	if d.proxy.User != nil {
+		methods = append(methods, socksAuthPassword)
+	}
+	if _, err := conn.Write(append([]byte{socksVersion, byte(len(methods))}, methods...)); err != nil {
+		return fmt.Errorf("failed to send socks greeting: %w", err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("failed to read socks greeting: %w", err)
+	}
+	if reply[0] != socksVersion {
+		return fmt.Errorf("redis proxy is not a socks5 proxy")
+	}
+	switch reply[1] {
+	case socksAuthNone:
+	case socksAuthPassword:
+		if err := d.authenticateSOCKS(conn); err != nil {
+			return err
+		}
+	case socksAuthNoMethod:
+		return fmt.Errorf("redis proxy accepts none of the offered socks authentication methods")
+	default:
+		return fmt.Errorf("redis proxy chose unsupported socks authentication method %d", reply[1])
+	}
+
+	req := []byte{socksVersion, socksCmdConnect, 0x00}
+	ip := net.ParseIP(host)
+	if ip == nil && d.proxy.Scheme == "socks5" {
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+		if err != nil {
+			return fmt.Errorf("failed to resolve redis host: %w", err)
+		}
+		ip = ips[0]
+	}
+	switch {
+	case ip == nil:
+		if len(host) > 255 {
+			return fmt.Errorf("redis host name too long for socks: %q", host)
+		}
+		req = append(req, socksAddrDomain, byte(len(host)))
+		req = append(req, host...)
+	case ip.To4() != nil:
+		req = append(req, socksAddrIPv4)
+		req = append(req, ip.To4()...)
+	default:
+		req = append(req, socksAddrIPv6)
+		req = append(req, ip.To16()...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("failed to send socks connect request: %w", err)
+	}
+
+	// Reply: version, status, reserved, then the bound address, which is discarded
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("failed to read socks connect reply: %w", err)
+	}
+	if header[1] != socksReplySucceeded {
+		reason, ok := socksReplies[header[1]]
+		if !ok {
+			reason = fmt.Sprintf("error %d", header[1])
+		}
+		return fmt.Errorf("redis proxy refused connection to %s: %s", addr, reason)
+	}
+	var skip int
+	switch header[3] {
+	case socksAddrIPv4:
+		skip = net.IPv4len
+	case socksAddrIPv6:
+		skip = net.IPv6len
+	case socksAddrDomain:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return fmt.Errorf("failed to read socks connect reply: %w", err)
+		}
+		skip = int(n[0])
+	default:
+		return fmt.Errorf("redis proxy replied with unknown socks address type %d", header[3])
+	}
+	if _, err := io.ReadFull(conn, make([]byte, skip+2)); err != nil {
+		return fmt.Errorf("failed to read socks connect reply: %w", err)
+	}
+	return nil
+}
+
+// authenticateSOCKS sends the proxy URL's credentials
+func (d *proxyDialer) authenticateSOCKS(conn net.Conn) error {
+	user := d.proxy.User.Username()
+	password, _ := d.proxy.User.Password()
+	if len(user) > 255 || len(password) > 255 {
+		return fmt.Errorf("socks credentials too long")
+	}
+	req := []byte{socksPasswordVer, byte(len(user))}
+	req = append(req, user...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("failed to send socks credentials: %w", err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("failed to read socks authentication reply: %w", err)
+	}
+	if reply[1] != 0x00 {
+		return fmt.Errorf("redis proxy rejected the socks credentials")
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+// listenMock serves a mock Redis on a local TCP port until the test ends
+func listenMock(t *testing.T) (string, *testutil.MockRedis) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on localhost: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	mock := testutil.NewMockRedis()
+	go serveMock(ln, mock)
+	return ln.Addr().String(), mock
+}
+
+// testProxy is a SOCKS5 or HTTP CONNECT proxy recording the targets it tunnels to
+type testProxy struct {
+	addr string
+	auth string // "user:pass" required from clients, or empty
+
+	mu      sync.Mutex
+	targets []string
+}
+
+func (p *testProxy) record(target string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.targets = append(p.targets, target)
+}
+
+func (p *testProxy) seen() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.targets...)
+}
+
+// startProxy runs a proxy speaking scheme ("socks5" or "http") until the test ends
+func startProxy(t *testing.T, scheme, auth string) *testProxy {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on localhost: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	p := &testProxy{addr: ln.Addr().String(), auth: auth}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if scheme == "http" {
+				go p.serveHTTP(conn)
+			} else {
+				go p.serveSOCKS(conn)
+			}
+		}
+	}()
+	return p
+}
+
+// tunnel copies between the client and target until either side closes
+func tunnel(client net.Conn, r io.Reader, target string) {
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		_ = client.Close()
+		return
+	}
+	go func() {
+		_, _ = io.Copy(upstream, r)
+		_ = upstream.Close()
+	}()
+	_, _ = io.Copy(client, upstream)
+	_ = client.Close()
+}
+
+func (p *testProxy) serveHTTP(conn net.Conn) {
+	br := bufio.NewReader(conn)
+	req, err := http.ReadRequest(br)
+	if err != nil || req.Method != http.MethodConnect {
+		_ = conn.Close()
+		return
+	}
+	if p.auth != "" && req.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte(p.auth)) {
+		_, _ = io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+		_ = conn.Close()
+		return
+	}
+	p.record(req.Host)
+	_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	tunnel(conn, br, req.Host)
+}
+
+func (p *testProxy) serveSOCKS(conn net.Conn) {
+	fail := func() { _ = conn.Close() }
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		fail()
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		fail()
+		return
+	}
+	method := byte(socksAuthNone)
+	if p.auth != "" {
+		method = socksAuthNoMethod
+		for _, m := range methods {
+			if m == socksAuthPassword {
+				method = socksAuthPassword
+			}
+		}
+	}
+	_, _ = conn.Write([]byte{socksVersion, method})
+	switch method {
+	case socksAuthNoMethod:
+		fail()
+		return
+	case socksAuthPassword:
+		// version, user, password
+		buf := make([]byte, 2)
+		_, _ = io.ReadFull(conn, buf)
+		user := make([]byte, buf[1])
+		_, _ = io.ReadFull(conn, user)
+		_, _ = io.ReadFull(conn, buf[:1])
+		password := make([]byte, buf[0])
+		_, _ = io.ReadFull(conn, password)
+		if string(user)+":"+string(password) != p.auth {
+			_, _ = conn.Write([]byte{socksPasswordVer, 0x01})
+			fail()
+			return
+		}
+		_, _ = conn.Write([]byte{socksPasswordVer, 0x00})
+	}
+
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		fail()
+		return
+	}
+	var host string
+	switch req[3] {
+	case socksAddrIPv4, socksAddrIPv6:
+		ip := make([]byte, net.IPv4len)
+		if req[3] == socksAddrIPv6 {
+			ip = make([]byte, net.IPv6len)
+		}
+		_, _ = io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case socksAddrDomain:
+		n := make([]byte, 1)
+		_, _ = io.ReadFull(conn, n)
+		name := make([]byte, n[0])
+		_, _ = io.ReadFull(conn, name)
+		host = string(name)
+	}
+	port := make([]byte, 2)
+	_, _ = io.ReadFull(conn, port)
+	target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	p.record(target)
+
+	if strings.HasPrefix(host, "blocked") {
+		_, _ = conn.Write([]byte{socksVersion, 0x02, 0x00, socksAddrIPv4, 0, 0, 0, 0, 0, 0})
+		fail()
+		return
+	}
+	_, _ = conn.Write([]byte{socksVersion, socksReplySucceeded, 0x00, socksAddrDomain, 5, 'p', 'r', 'o', 'x', 'y', 0, 0})
+	tunnel(conn, conn, target)
+}
+
+func TestNewClient_Proxy(t *testing.T) {
+	target, _ := listenMock(t)
+	_, port, _ := net.SplitHostPort(target)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		scheme string
+		auth   string
+		url    func(p *testProxy) string
+		addr   string
+		want   string // target seen by the proxy
+	}{
+		{"socks5", "socks5", "", func(p *testProxy) string { return "socks5://" + p.addr }, target, target},
+		{"socks5 with auth", "socks5", "alice:secret", func(p *testProxy) string { return "socks5://alice:secret@" + p.addr }, target, target},
+		{"socks5h remote resolution", "socks5", "", func(p *testProxy) string { return "socks5h://" + p.addr }, "localhost:" + port, "localhost:" + port},
+		{"socks5 local resolution", "socks5", "", func(p *testProxy) string { return "socks5://" + p.addr }, "localhost:" + port, ""},
+		{"http connect", "http", "", func(p *testProxy) string { return "http://" + p.addr }, target, target},
+		{"http connect with auth", "http", "bob:pw", func(p *testProxy) string { return "http://bob:pw@" + p.addr }, target, target},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := startProxy(t, tt.scheme, tt.auth)
+			client, err := NewClient(DefaultConfig().WithAddr(tt.addr).WithDialTimeout(2 * time.Second).WithProxy(tt.url(proxy)))
+			if err != nil {
+				t.Fatalf("NewClient() through proxy error = %v, want nil", err)
+			}
+			defer func() { _ = client.Close() }()
+
+			if err := client.Set(ctx, "k", "v", 0).Err(); err != nil {
+				t.Fatalf("Set() through proxy error = %v, want nil", err)
+			}
+			if v, err := client.Get(ctx, "k").Result(); err != nil || v != "v" {
+				t.Errorf("Get() through proxy = %q, %v, want v", v, err)
+			}
+			seen := proxy.seen()
+			if len(seen) == 0 {
+				t.Fatal("proxy tunneled no connection")
+			}
+			if tt.want != "" && seen[0] != tt.want {
+				t.Errorf("proxy target = %s, want %s", seen[0], tt.want)
+			}
+			if tt.want == "" && strings.HasPrefix(seen[0], "localhost") {
+				t.Errorf("proxy target = %s, want a locally resolved address", seen[0])
+			}
+		})
+	}
+}
+
+func TestNewClient_ProxyErrors(t *testing.T) {
+	target, _ := listenMock(t)
+	cfg := DefaultConfig().WithAddr(target).WithDialTimeout(time.Second).WithMaxRetries(-1)
+
+	socks := startProxy(t, "socks5", "alice:secret")
+	httpProxy := startProxy(t, "http", "bob:pw")
+	refused := []string{
+		"socks5://" + socks.addr,             // no credentials
+		"socks5://alice:wrong@" + socks.addr, // bad credentials
+		"http://" + httpProxy.addr,           // no credentials
+		"socks5h://127.0.0.1:1",              // no proxy listening
+	}
+	for _, proxyURL := range refused {
+		if _, err := NewClient(cfg.WithProxy(proxyURL)); err == nil {
+			t.Errorf("NewClient() through %s error = nil, want error", proxyURL)
+		}
+	}
+
+	blocked := startProxy(t, "socks5", "")
+	_, err := NewClient(cfg.WithAddr("blocked.internal:6379").WithProxy("socks5h://" + blocked.addr))
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("NewClient() to a blocked target error = %v, want the socks reply", err)
+	}
+
+	invalid := []Config{
+		cfg.WithProxy("ftp://proxy:21"),
+		cfg.WithProxy("socks5://"),
+		cfg.WithProxy("://bad"),
+		cfg.WithUnixSocket("/tmp/redis.sock").WithProxy("socks5://proxy:1080"),
+	}
+	for _, c := range invalid {
+		if _, err := NewClient(c.WithLazyConnect(true)); err == nil {
+			t.Errorf("NewClient() with proxy %q error = nil, want error", c.Proxy)
+		}
+	}
+	if _, err := NewFailoverClient(cfg.WithProxy("ftp://proxy:21").WithLazyConnect(true), SentinelConfig{
+		MasterName:    "mymaster",
+		SentinelAddrs: []string{"sentinel:26379"},
+	}); err == nil {
+		t.Error("NewFailoverClient() with an invalid proxy error = nil, want error")
+	}
+}
+
+func TestBuildDialer(t *testing.T) {
+	if dial, err := buildDialer(DefaultConfig(), nil); err != nil || dial != nil {
+		t.Errorf("buildDialer() without proxy = %v, %v, want go-redis's dialer", dial != nil, err)
+	}
+
+	for proxyURL, want := range map[string]string{
+		"socks5://proxy":       "proxy:1080",
+		"http://proxy":         "proxy:80",
+		"http://proxy:3128":    "proxy:3128",
+		"socks5h://[::1]:1081": "[::1]:1081",
+	} {
+		if dial, err := buildDialer(DefaultConfig().WithProxy(proxyURL), nil); err != nil || dial == nil {
+			t.Fatalf("buildDialer(%s) error = %v", proxyURL, err)
+		}
+		proxy, _ := url.Parse(proxyURL)
+		if got := (&proxyDialer{proxy: proxy}).proxyAddr(); got != want {
+			t.Errorf("proxyAddr(%s) = %s, want %s", proxyURL, got, want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// DefaultReplicaRetryInterval is how long a replica that failed is skipped before reads are
+// sent to it again
+const DefaultReplicaRetryInterval = 5 * time.Second
+
+// ReadWriteClient sends commands to a primary and reads to replicas
+// It is the primary's *redis.Client, so commands issued on it directly, and everything built on
+// it, go to the primary. Reads go to replicas through Read or Reader, round-robin over the
+// replicas that are up; a replica whose connection or server fails is skipped for the retry
+// interval, and reads fall back to the primary while no replica is up
+//
+// Replicas apply writes asynchronously, so a read may not see a write that just succeeded
+type ReadWriteClient struct {
+	*redis.Client
+
+	replicas      []*replica
+	next          atomic.Uint64
+	retryInterval time.Duration
+}
+
+// replica is a read replica and when it may be used again after a failure
+type replica struct {
+	client    *redis.Client
+	addr      string
+	downUntil atomic.Int64 // Unix nanoseconds
+}
+
+func (r *replica) up(now time.Time) bool {
+	return now.UnixNano() >= r.downUntil.Load()
+}
+
+// NewReadWriteClient creates a client for the primary at cfg.Addr, or cfg.Addrs, with read
+// replicas at replicaAddrs, which share the rest of cfg
+// The primary is pinged like NewClient does; replicas are connected lazily, so a replica that
+// is down at startup only makes reads fall back to the primary
+func NewReadWriteClient(cfg Config, replicaAddrs ...string) (*ReadWriteClient, error) {
+	primary, err := NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ReadWriteClient{Client: primary, retryInterval: DefaultReplicaRetryInterval}
+	for _, addr := range replicaAddrs {
+		client, err := NewClient(cfg.WithAddr(addr).WithLazyConnect(true))
+		if err != nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("failed to create replica client for %s: %w", addr, err)
+		}
+		r := &replica{client: client, addr: addr}
+		client.AddHook(replicaHook{replica: r, interval: &c.retryInterval})
+		c.replicas = append(c.replicas, r)
+	}
+	return c, nil
+}
+
+// SetReplicaRetryInterval sets how long a failed replica is skipped (default:
+// DefaultReplicaRetryInterval); call it before the client is used
+func (c *ReadWriteClient) SetReplicaRetryInterval(d time.Duration) {
+	if d > 0 {
+		c.retryInterval = d
+	}
+}
+
+// Primary returns the client of the primary
+func (c *ReadWriteClient) Primary() *redis.Client {
+	return c.Client
+}
+
+// Replicas returns the addresses of the replicas that are currently up
+func (c *ReadWriteClient) Replicas() []string {
+	now := time.Now()
+	var addrs []string
+	for _, r := range c.replicas {
+		if r.up(now) {
+			addrs = append(addrs, r.addr)
+		}
+	}
+	return addrs
+}
+
+// Reader returns the client of the next replica that is up, or the primary if none is
+func (c *ReadWriteClient) Reader() *redis.Client {
+	if len(c.replicas) == 0 {
+		return c.Client
+	}
+	now := time.Now()
+	start := c.next.Add(1)
+	for i := range uint64(len(c.replicas)) {
+		if r := c.replicas[(start+i)%uint64(len(c.replicas))]; r.up(now) {
+			return r.client
+		}
+	}
+	return c.Client
+}
+
+// Read runs fn against a replica and, if the replica fails with an error utils.IsRetryableError
+// accepts, such as a connection error, runs it again against the primary; redis.Nil and other
+// command errors are returned as is
+func (c *ReadWriteClient) Read(ctx context.Context, fn func(redis.Cmdable) error) error {
+	reader := c.Reader()
+	err := fn(reader)
+	if reader == c.Client || !utils.IsRetryableError(err) || ctx.Err() != nil {
+		return err
+	}
+	return fn(c.Client)
+}
+
+// CheckReplicas pings every replica, marking the ones that fail as down and the others as up,
+// and returns the number that are up; it lets a replica come back before its retry interval ends
+func (c *ReadWriteClient) CheckReplicas(ctx context.Context) int {
+	up := 0
+	for _, r := range c.replicas {
+		if err := r.client.Ping(ctx).Err(); err != nil {
+			if ctx.Err() == nil {
+				r.downUntil.Store(time.Now().Add(c.retryInterval).UnixNano())
+			}
+			continue
+		}
+		r.downUntil.Store(0)
+		up++
+	}
+	return up
+}
+
+// Close closes the primary and replica clients
+func (c *ReadWriteClient) Close() error {
+	errs := make([]error, 0, len(c.replicas)+1)
+	for _, r := range c.replicas {
+		errs = append(errs, r.client.Close())
+	}
+	errs = append(errs, c.Client.Close())
+	return errors.Join(errs...)
+}
+
+// replicaHook marks its replica down when a command or dial on it fails
+type replicaHook struct {
+	replica  *replica
+	interval *time.Duration
+}
+
+func (h replicaHook) observe(err error) {
+	if utils.IsRetryableError(err) {
+		h.replica.downUntil.Store(time.Now().Add(*h.interval).UnixNano())
+	}
+}
+
+func (h replicaHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		h.observe(err)
+		return conn, err
+	}
+}
+
+func (h replicaHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		h.observe(err)
+		return err
+	}
+}
+
+func (h replicaHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		h.observe(err)
+		return err
+	}
+}
@@ -0,0 +1,234 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+// topology dials a separate mock per address; marking an address down drops its connections
+// and fails new dials to it
+type topology struct {
+	mu    sync.Mutex
+	nodes map[string]*testutil.MockRedis
+	down  map[string]bool
+	conns map[string][]net.Conn
+}
+
+func newTopology(addrs ...string) *topology {
+	t := &topology{
+		nodes: make(map[string]*testutil.MockRedis),
+		down:  make(map[string]bool),
+		conns: make(map[string][]net.Conn),
+	}
+	for _, addr := range addrs {
+		t.nodes[addr] = testutil.NewMockRedis()
+	}
+	return t
+}
+
+func (t *topology) setDown(addr string, down bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.down[addr] = down
+	if down {
+		for _, conn := range t.conns[addr] {
+			_ = conn.Close()
+		}
+		delete(t.conns, addr)
+	}
+}
+
+func (t *topology) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	t.mu.Lock()
+	node, down := t.nodes[addr], t.down[addr]
+	t.mu.Unlock()
+	if down || node == nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: errors.New("connection refused")}
+	}
+	conn, err := node.Dialer()(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.conns[addr] = append(t.conns[addr], conn)
+	t.mu.Unlock()
+	return droppableConn{conn}, nil
+}
+
+// droppableConn reports a dropped pipe as io.EOF, like a TCP connection the server closed
+type droppableConn struct {
+	net.Conn
+}
+
+func dropped(err error) error {
+	if errors.Is(err, io.ErrClosedPipe) {
+		return io.EOF
+	}
+	return err
+}
+
+func (c droppableConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	return n, dropped(err)
+}
+
+func (c droppableConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	return n, dropped(err)
+}
+
+func (c droppableConn) SetDeadline(t time.Time) error {
+	return dropped(c.Conn.SetDeadline(t))
+}
+
+func (c droppableConn) SetReadDeadline(t time.Time) error {
+	return dropped(c.Conn.SetReadDeadline(t))
+}
+
+func (c droppableConn) SetWriteDeadline(t time.Time) error {
+	return dropped(c.Conn.SetWriteDeadline(t))
+}
+
+// newReadWriteTestClient creates a client over the topology without command retries
+func newReadWriteTestClient(t *testing.T, topo *topology, primary string, replicas ...string) *ReadWriteClient {
+	t.Helper()
+	cfg := DefaultConfig().WithAddr(primary).WithDialTimeout(time.Second).WithMaxRetries(-1)
+	cfg.Dialer = topo.dial
+	c, err := NewReadWriteClient(cfg, replicas...)
+	if err != nil {
+		t.Fatalf("NewReadWriteClient() error = %v, want nil", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestReadWriteClient(t *testing.T) {
+	topo := newTopology("primary:6379", "r1:6379", "r2:6379")
+	c := newReadWriteTestClient(t, topo, "primary:6379", "r1:6379", "r2:6379")
+	ctx := context.Background()
+
+	// Writes go to the primary only
+	if err := c.Set(ctx, "k", "p", 0).Err(); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	if c.Primary() != c.Client || len(c.Replicas()) != 2 {
+		t.Errorf("Primary() / Replicas() = %v / %v", c.Primary(), c.Replicas())
+	}
+
+	// Reads alternate between the replicas
+	for _, addr := range []string{"r1:6379", "r2:6379"} {
+		_ = redis.NewClient(&redis.Options{Addr: addr, Dialer: topo.dial}).Set(ctx, "k", addr, 0).Err()
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		var got string
+		err := c.Read(ctx, func(r redis.Cmdable) error {
+			var err error
+			got, err = r.Get(ctx, "k").Result()
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Read() error = %v, want nil", err)
+		}
+		seen[got] = true
+	}
+	if !seen["r1:6379"] || !seen["r2:6379"] || seen["p"] {
+		t.Errorf("reads served by %v, want both replicas only", seen)
+	}
+
+	// Command errors and misses are not failovers
+	err := c.Read(ctx, func(r redis.Cmdable) error { return r.Get(ctx, "missing").Err() })
+	if !errors.Is(err, redis.Nil) || len(c.Replicas()) != 2 {
+		t.Errorf("Read() miss error = %v, replicas up %v, want redis.Nil and both up", err, c.Replicas())
+	}
+}
+
+func TestReadWriteClient_Fallback(t *testing.T) {
+	topo := newTopology("primary:6379", "r1:6379")
+	c := newReadWriteTestClient(t, topo, "primary:6379", "r1:6379")
+	c.SetReplicaRetryInterval(time.Hour)
+	ctx := context.Background()
+	_ = c.Set(ctx, "k", "p", 0).Err()
+
+	// A failing replica is retried on the primary and then skipped
+	topo.setDown("r1:6379", true)
+	var got string
+	err := c.Read(ctx, func(r redis.Cmdable) error {
+		var err error
+		got, err = r.Get(ctx, "k").Result()
+		return err
+	})
+	if err != nil || got != "p" {
+		t.Fatalf("Read() with replica down = %q, %v, want the primary's value", got, err)
+	}
+	if len(c.Replicas()) != 0 || c.Reader() != c.Client {
+		t.Errorf("Replicas() = %v, want the failed replica skipped", c.Replicas())
+	}
+
+	// CheckReplicas brings it back once it recovers
+	if n := c.CheckReplicas(ctx); n != 0 {
+		t.Errorf("CheckReplicas() while down = %d, want 0", n)
+	}
+	topo.setDown("r1:6379", false)
+	deadline := time.Now().Add(5 * time.Second)
+	for c.CheckReplicas(ctx) != 1 {
+		// The replica's pool holds back new dials for a moment after failed ones
+		if time.Now().After(deadline) {
+			t.Fatal("CheckReplicas() did not see the replica recover")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if c.Reader() == c.Client {
+		t.Error("Reader() after recovery returned the primary, want the replica")
+	}
+}
+
+func TestReadWriteClient_Errors(t *testing.T) {
+	if _, err := NewReadWriteClient(DefaultConfig().WithAddr("")); err == nil {
+		t.Error("NewReadWriteClient() without primary address error = nil, want error")
+	}
+
+	// A replica down at startup does not prevent construction
+	topo := newTopology("primary:6379")
+	c := newReadWriteTestClient(t, topo, "primary:6379", "gone:6379")
+	if err := c.Read(context.Background(), func(r redis.Cmdable) error { return r.Ping(context.Background()).Err() }); err != nil {
+		t.Errorf("Read() with unreachable replica error = %v, want fallback to primary", err)
+	}
+
+	// Without replicas everything goes to the primary
+	c = newReadWriteTestClient(t, topo, "primary:6379")
+	if c.Reader() != c.Client {
+		t.Error("Reader() without replicas should return the primary")
+	}
+}
+
+func TestReadWriteClient_Cache(t *testing.T) {
+	topo := newTopology("primary:6379", "r1:6379")
+	c := newReadWriteTestClient(t, topo, "primary:6379", "r1:6379")
+	ctx := context.Background()
+
+	users := cache.NewCache(c, "users:", cache.WithReadRouter(c))
+	if err := users.Set(ctx, "1", "alice", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	var got string
+	if err := users.Get(ctx, "1", &got); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get() from an unreplicated replica error = %v, want ErrNotFound", err)
+	}
+
+	topo.setDown("r1:6379", true)
+	c.SetReplicaRetryInterval(time.Hour)
+	_ = c.CheckReplicas(ctx)
+	if err := users.Get(ctx, "1", &got); err != nil || got != "alice" {
+		t.Errorf("Get() with replica down = %q, %v, want the primary's value", got, err)
+	}
+}
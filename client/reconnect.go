@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// Defaults for WatchOptions
+const (
+	DefaultFailureThreshold = 3
+	DefaultProbeInterval    = time.Second
+)
+
+// WatchOptions configures a ConnectionWatcher
+type WatchOptions struct {
+	// FailureThreshold is the number of consecutive network errors after which the connection
+	// is reported lost (default: DefaultFailureThreshold)
+	FailureThreshold int
+
+	// ProbeInterval is how often the client is pinged while the connection is lost, so
+	// recovery is noticed when callers stopped sending commands (default: DefaultProbeInterval)
+	ProbeInterval time.Duration
+}
+
+// ConnectionWatcher follows the outcome of a client's commands and reports when Redis becomes
+// unreachable and when it is reachable again
+// Network errors count as failures; any reply from the server, including error replies and
+// redis.Nil, counts as a success. Context errors count as neither
+type ConnectionWatcher struct {
+	client redis.UniversalClient
+	opts   WatchOptions
+
+	failures atomic.Int64
+	lost     atomic.Bool
+	probing  atomic.Bool
+
+	// notifyMu serializes state changes and their callbacks
+	notifyMu sync.Mutex
+
+	mu             sync.Mutex
+	onLost         []func(error)
+	onReconnected  []func()
+	lastDisconnect error
+}
+
+// WatchConnection adds a hook to client that feeds the returned watcher
+// A nil client returns a watcher that never reports a change
+func WatchConnection(client redis.UniversalClient, opts WatchOptions) *ConnectionWatcher {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultFailureThreshold
+	}
+	if opts.ProbeInterval <= 0 {
+		opts.ProbeInterval = DefaultProbeInterval
+	}
+	w := &ConnectionWatcher{client: utils.NormalizeClient(client), opts: opts}
+	if w.client != nil {
+		w.client.AddHook(watchHook{watcher: w})
+	}
+	return w
+}
+
+// OnConnectionLost registers fn to be called with the last network error when the connection
+// is lost
+// Callbacks run one at a time on the goroutine that noticed the change and must not send
+// commands through the watched client
+func (w *ConnectionWatcher) OnConnectionLost(fn func(error)) {
+	if fn == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onLost = append(w.onLost, fn)
+}
+
+// OnReconnected registers fn to be called when the connection is back after being lost
+// Callbacks run like those of OnConnectionLost
+func (w *ConnectionWatcher) OnReconnected(fn func()) {
+	if fn == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onReconnected = append(w.onReconnected, fn)
+}
+
+// Connected reports whether the connection is considered up
+func (w *ConnectionWatcher) Connected() bool {
+	return !w.lost.Load()
+}
+
+// LastError returns the network error the connection was last lost with, or nil
+func (w *ConnectionWatcher) LastError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastDisconnect
+}
+
+// observe records the outcome of a command
+func (w *ConnectionWatcher) observe(err error) {
+	switch {
+	case isConnectionError(err):
+		w.failure(err)
+	case err == nil || isServerReply(err):
+		w.success()
+	}
+}
+
+func (w *ConnectionWatcher) failure(err error) {
+	if w.lost.Load() || w.failures.Add(1) < int64(w.opts.FailureThreshold) {
+		return
+	}
+
+	w.notifyMu.Lock()
+	defer w.notifyMu.Unlock()
+	if w.lost.Load() {
+		return
+	}
+	w.lost.Store(true)
+	w.mu.Lock()
+	w.lastDisconnect = err
+	callbacks := append([]func(error){}, w.onLost...)
+	w.mu.Unlock()
+
+	if w.probing.CompareAndSwap(false, true) {
+		go w.probe()
+	}
+	for _, fn := range callbacks {
+		fn(err)
+	}
+}
+
+func (w *ConnectionWatcher) success() {
+	w.failures.Store(0)
+	if !w.lost.Load() {
+		return
+	}
+
+	w.notifyMu.Lock()
+	defer w.notifyMu.Unlock()
+	if !w.lost.Load() {
+		return
+	}
+	w.lost.Store(false)
+	w.mu.Lock()
+	callbacks := append([]func(){}, w.onReconnected...)
+	w.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn()
+	}
+}
+
+// probe pings the client while the connection is lost; the pings go through the hook, so a
+// reply reports the reconnection. It stops when the client is closed
+func (w *ConnectionWatcher) probe() {
+	ticker := time.NewTicker(w.opts.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		for w.lost.Load() {
+			<-ticker.C
+			ctx, cancel := context.WithTimeout(context.Background(), w.opts.ProbeInterval)
+			err := w.client.Ping(ctx).Err()
+			cancel()
+			if errors.Is(err, redis.ErrClosed) {
+				w.probing.Store(false)
+				return
+			}
+		}
+		w.probing.Store(false)
+		// The connection may have been lost again before probing was cleared
+		if !w.lost.Load() || !w.probing.CompareAndSwap(false, true) {
+			return
+		}
+	}
+}
+
+// isConnectionError reports whether err means Redis could not be reached
+func isConnectionError(err error) bool {
+	if err == nil || isServerReply(err) || errors.Is(err, redis.ErrClosed) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isServerReply reports whether err is a reply from the server, which proves it is reachable
+func isServerReply(err error) bool {
+	var redisErr redis.Error
+	return errors.As(err, &redisErr)
+}
+
+// watchHook feeds the outcome of every command and pipeline to a ConnectionWatcher
+type watchHook struct {
+	watcher *ConnectionWatcher
+}
+
+func (h watchHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h watchHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		h.watcher.observe(err)
+		return err
+	}
+}
+
+func (h watchHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		h.watcher.observe(err)
+		return err
+	}
+}
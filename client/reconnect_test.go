@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/lock"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestWatchConnection(t *testing.T) {
+	topo := newTopology("redis:6379")
+	cfg := DefaultConfig().WithAddr("redis:6379").WithMinIdleConns(0).WithMaxRetries(-1).WithDialTimeout(time.Second)
+	cfg.Dialer = topo.dial
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	w := WatchConnection(client, WatchOptions{FailureThreshold: 2, ProbeInterval: 20 * time.Millisecond})
+	locker := lock.NewHybridLocker(client)
+
+	var mu sync.Mutex
+	var events []string
+	w.OnConnectionLost(func(err error) {
+		mu.Lock()
+		events = append(events, "lost")
+		mu.Unlock()
+		locker.SetFallback(true)
+	})
+	w.OnReconnected(func() {
+		mu.Lock()
+		events = append(events, "reconnected")
+		mu.Unlock()
+		locker.SetFallback(false)
+	})
+	w.OnConnectionLost(nil)
+	w.OnReconnected(nil)
+
+	ctx := context.Background()
+	if !w.Connected() || w.LastError() != nil {
+		t.Fatal("watcher starts disconnected")
+	}
+
+	// Server replies, errors included, keep the connection up
+	_ = client.Get(ctx, "missing").Err()
+	_ = client.Do(ctx, "nosuchcommand").Err()
+
+	topo.setDown("redis:6379", true)
+	_ = client.Get(ctx, "k").Err()
+	if !w.Connected() {
+		t.Fatal("Connected() = false after one failure, want true below the threshold")
+	}
+	_ = client.Get(ctx, "k").Err()
+	if w.Connected() || w.LastError() == nil || !locker.Fallback() {
+		t.Fatalf("after two failures Connected() = %v, LastError() = %v, Fallback() = %v, want lost",
+			w.Connected(), w.LastError(), locker.Fallback())
+	}
+
+	// The probe notices the recovery without further commands
+	topo.setDown("redis:6379", false)
+	eventually(t, "the reconnection is reported", w.Connected)
+	if locker.Fallback() {
+		t.Error("locker still in fallback after reconnecting")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 || events[0] != "lost" || events[1] != "reconnected" {
+		t.Errorf("events = %v, want [lost reconnected]", events)
+	}
+}
+
+func TestWatchConnection_Pipeline(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	w := WatchConnection(client, WatchOptions{FailureThreshold: 1, ProbeInterval: time.Hour})
+
+	// Error replies are not connection failures
+	mock.SetShouldFail(true)
+	pipe := client.Pipeline()
+	pipe.Get(context.Background(), "k")
+	_, _ = pipe.Exec(context.Background())
+	if !w.Connected() {
+		t.Error("Connected() = false after an error reply")
+	}
+
+	// Commands on a closed client are not failures, and stop the probe
+	_ = client.Close()
+	_ = client.Get(context.Background(), "k").Err()
+	if !w.Connected() {
+		t.Error("Connected() = false after closing the client")
+	}
+
+	if w := WatchConnection(nil, WatchOptions{}); !w.Connected() {
+		t.Error("WatchConnection(nil) watcher is not connected")
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{redis.Nil, false},
+		{errors.New("plain"), false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, false},
+		{redis.ErrClosed, false},
+		{io.EOF, true},
+		{io.ErrUnexpectedEOF, true},
+		{net.ErrClosed, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+	}
+	for _, tt := range tests {
+		if got := isConnectionError(tt.err); got != tt.want {
+			t.Errorf("isConnectionError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
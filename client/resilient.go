@@ -0,0 +1,50 @@
+package client
+
+import (
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// ResilientClient is a client that reports whether Redis is available, giving the kit one
+// degradation switch
+// It reports itself degraded once a ConnectionWatcher saw FailureThreshold consecutive network
+// errors, and available again after a probe or command reaches Redis. Components created with
+// it consult the flag on every call: the cache misses on reads and drops writes, HybridLocker
+// uses its local lock, and the rate limiter counts requests in process memory. Other commands
+// are sent as usual
+type ResilientClient struct {
+	redis.UniversalClient
+	watcher *ConnectionWatcher
+}
+
+var (
+	_ utils.DegradedReporter = (*ResilientClient)(nil)
+	_ utils.Unwrapper        = (*ResilientClient)(nil)
+)
+
+// NewResilientClient wraps client, watching its connection with opts
+// Pass the returned client, not client, to the components that should degrade with it; a nil
+// client returns nil
+func NewResilientClient(client redis.UniversalClient, opts WatchOptions) *ResilientClient {
+	client = utils.NormalizeClient(client)
+	if client == nil {
+		return nil
+	}
+	return &ResilientClient{UniversalClient: client, watcher: WatchConnection(client, opts)}
+}
+
+// Degraded reports whether Redis is considered unavailable
+func (c *ResilientClient) Degraded() bool {
+	return !c.watcher.Connected()
+}
+
+// Watcher returns the connection watcher behind Degraded, e.g. to register callbacks
+func (c *ResilientClient) Watcher() *ConnectionWatcher {
+	return c.watcher
+}
+
+// Unwrap returns the wrapped client, so keyspace-wide helpers such as utils.ScanKeys reach
+// every node of a cluster
+func (c *ResilientClient) Unwrap() redis.UniversalClient {
+	return c.UniversalClient
+}
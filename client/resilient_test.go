@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/lock"
+	"github.com/soulteary/redis-kit/ratelimit"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+func TestResilientClient(t *testing.T) {
+	topo := newTopology("redis:6379")
+	cfg := DefaultConfig().WithAddr("redis:6379").WithMinIdleConns(0).WithMaxRetries(-1).WithDialTimeout(time.Second)
+	cfg.Dialer = topo.dial
+	base, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	defer func() { _ = base.Close() }()
+
+	client := NewResilientClient(base, WatchOptions{FailureThreshold: 2, ProbeInterval: 20 * time.Millisecond})
+	c := cache.NewCache(client, "c:")
+	locker := lock.NewHybridLocker(client)
+	limiter := ratelimit.NewRateLimiter(client)
+	ctx := context.Background()
+
+	if client.Degraded() || utils.Degraded(client) {
+		t.Fatal("new client is degraded")
+	}
+	if client.Unwrap() != base {
+		t.Error("Unwrap() did not return the wrapped client")
+	}
+	if err := c.Set(ctx, "k", "v", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+
+	// Redis goes away: the first failures surface, then every component degrades together
+	topo.setDown("redis:6379", true)
+	var got string
+	for i := 0; i < 2; i++ {
+		if err := c.Get(ctx, "k", &got); err == nil || errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("Get() #%d with Redis down error = %v, want a connection error", i, err)
+		}
+	}
+	if !client.Degraded() {
+		t.Fatal("Degraded() = false after two failures")
+	}
+	if err := c.Get(ctx, "k", &got); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get() while degraded error = %v, want a miss", err)
+	}
+	if ok, err := locker.Lock("job"); !ok || err != nil {
+		t.Errorf("Lock() while degraded = %v, %v, want the local lock", ok, err)
+	}
+	if allowed, _, _, err := limiter.CheckLimit(ctx, "user:1", 1, time.Minute); !allowed || err != nil {
+		t.Errorf("CheckLimit() while degraded = %v, %v, want a local decision", allowed, err)
+	}
+	if allowed, _, _, _ := limiter.CheckLimit(ctx, "user:1", 1, time.Minute); allowed {
+		t.Error("CheckLimit() while degraded ignored the limit")
+	}
+	if err := locker.Unlock("job"); err != nil {
+		t.Errorf("Unlock() while degraded error = %v, want nil", err)
+	}
+
+	// Probes notice the recovery and the components use Redis again
+	topo.setDown("redis:6379", false)
+	eventually(t, "the client recovers", func() bool { return !client.Degraded() })
+	if err := c.Get(ctx, "k", &got); err != nil || got != "v" {
+		t.Errorf("Get() after recovery = %q, %v, want v", got, err)
+	}
+	if client.Watcher() == nil {
+		t.Error("Watcher() = nil")
+	}
+
+	if NewResilientClient(nil, WatchOptions{}) != nil {
+		t.Error("NewResilientClient(nil) != nil")
+	}
+}
@@ -0,0 +1,212 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// resolveTimeout bounds a background re-resolution
+const resolveTimeout = 5 * time.Second
+
+// errStaleAddress makes the pool's health check discard a connection to a retired address
+var errStaleAddress = errors.New("redis address no longer resolves")
+
+// resolvingDialer dials the current addresses of a host name, resolving it again once the last
+// resolution is older than ttl. With recycle set, connections to addresses a new resolution no
+// longer returns are marked stale, and the pool discards them before their next use
+type resolvingDialer struct {
+	forward   Dialer // dials a resolved address
+	tlsConfig *tls.Config
+	ttl       time.Duration
+	recycle   bool
+	lookup    func(ctx context.Context, host string) ([]string, error)
+
+	mu    sync.Mutex
+	hosts map[string]*resolution
+}
+
+// resolution is a host name's last resolved addresses and the connections dialed to them
+type resolution struct {
+	host       string
+	expires    atomic.Int64 // unix nanoseconds
+	refreshing atomic.Bool
+
+	// guarded by resolvingDialer.mu
+	addrs []string
+	conns map[*resolvedConn]struct{}
+}
+
+func newResolvingDialer(forward Dialer, tlsConfig *tls.Config, ttl time.Duration, recycle bool) *resolvingDialer {
+	return &resolvingDialer{
+		forward:   forward,
+		tlsConfig: tlsConfig,
+		ttl:       ttl,
+		recycle:   recycle,
+		lookup:    net.DefaultResolver.LookupHost,
+		hosts:     make(map[string]*resolution),
+	}
+}
+
+// dial connects to the first reachable address addr's host resolves to
+func (d *resolvingDialer) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis address %q: %w", addr, err)
+	}
+	if net.ParseIP(host) != nil {
+		conn, err := d.forward(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return d.secure(conn, host), nil
+	}
+
+	r, addrs, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	var ip string
+	for _, ip = range addrs {
+		if conn, err = d.forward(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !d.recycle {
+		return d.secure(conn, host), nil
+	}
+	rc := &resolvedConn{Conn: d.secure(conn, host), dialer: d, res: r, ip: ip}
+	if sys, ok := conn.(syscall.Conn); ok && d.tlsConfig == nil {
+		rc.sys = sys
+	}
+	d.mu.Lock()
+	r.conns[rc] = struct{}{}
+	d.mu.Unlock()
+	return rc, nil
+}
+
+// secure wraps conn in TLS when configured, as go-redis does not apply it to custom dialers
+func (d *resolvingDialer) secure(conn net.Conn, host string) net.Conn {
+	return tlsClient(conn, d.tlsConfig, host)
+}
+
+// resolve returns host's addresses, resolving it when the last resolution expired. A failed
+// lookup falls back to the previous addresses
+func (d *resolvingDialer) resolve(ctx context.Context, host string) (*resolution, []string, error) {
+	d.mu.Lock()
+	r, ok := d.hosts[host]
+	if !ok {
+		r = &resolution{host: host, conns: make(map[*resolvedConn]struct{})}
+		d.hosts[host] = r
+	}
+	addrs := r.addrs
+	d.mu.Unlock()
+
+	if addrs != nil && time.Now().UnixNano() < r.expires.Load() {
+		return r, addrs, nil
+	}
+	fresh, err := d.refresh(ctx, r)
+	if err != nil {
+		if addrs != nil {
+			return r, addrs, nil
+		}
+		return nil, nil, err
+	}
+	return r, fresh, nil
+}
+
+// refresh resolves r's host again, marking connections to addresses that disappeared stale
+func (d *resolvingDialer) refresh(ctx context.Context, r *resolution) ([]string, error) {
+	addrs, err := d.lookup(ctx, r.host)
+	if err == nil && len(addrs) == 0 {
+		err = fmt.Errorf("no addresses for %s", r.host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve redis host: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r.addrs = addrs
+	r.expires.Store(time.Now().Add(d.ttl).UnixNano())
+	for c := range r.conns {
+		if !slices.Contains(addrs, c.ip) {
+			c.stale.Store(true)
+			delete(r.conns, c)
+		}
+	}
+	return addrs, nil
+}
+
+// refreshExpired resolves r's host again in the background once its resolution expired, so a
+// pool that no longer dials still notices address changes
+func (d *resolvingDialer) refreshExpired(r *resolution) {
+	if time.Now().UnixNano() < r.expires.Load() || !r.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer r.refreshing.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+		defer cancel()
+		_, _ = d.refresh(ctx, r)
+	}()
+}
+
+// forget stops tracking a closed connection
+func (d *resolvingDialer) forget(c *resolvedConn) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(c.res.conns, c)
+}
+
+// resolvedConn is a connection to one resolved address of a host name
+type resolvedConn struct {
+	net.Conn
+	sys    syscall.Conn // the plain TCP connection, nil under TLS
+	dialer *resolvingDialer
+	res    *resolution
+	ip     string
+	stale  atomic.Bool
+}
+
+// Write checks the resolution on every command, as the connection may outlive many of them
+func (c *resolvedConn) Write(b []byte) (int, error) {
+	c.dialer.refreshExpired(c.res)
+	return c.Conn.Write(b)
+}
+
+func (c *resolvedConn) Close() error {
+	c.dialer.forget(c)
+	return c.Conn.Close()
+}
+
+// SyscallConn serves the pool's health check, which discards a connection when it fails
+func (c *resolvedConn) SyscallConn() (syscall.RawConn, error) {
+	if c.stale.Load() {
+		return nil, errStaleAddress
+	}
+	if c.sys != nil {
+		return c.sys.SyscallConn()
+	}
+	// TLS connections are not checked, as without this wrapper
+	return uncheckedConn{}, nil
+}
+
+// uncheckedConn reports a healthy connection without touching its socket
+type uncheckedConn struct{}
+
+func (uncheckedConn) Control(func(fd uintptr)) error    { return nil }
+func (uncheckedConn) Read(func(fd uintptr) bool) error  { return nil }
+func (uncheckedConn) Write(func(fd uintptr) bool) error { return nil }
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDNS answers lookups with settable addresses and dials every address to one mock server
+type fakeDNS struct {
+	target string
+
+	mu      sync.Mutex
+	addrs   []string
+	err     error
+	lookups int
+	dialed  []string
+}
+
+func (f *fakeDNS) set(err error, addrs ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.addrs, f.err = addrs, err
+}
+
+func (f *fakeDNS) lookup(ctx context.Context, host string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups++
+	return f.addrs, f.err
+}
+
+func (f *fakeDNS) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	f.mu.Lock()
+	f.dialed = append(f.dialed, addr)
+	f.mu.Unlock()
+	var d net.Dialer
+	return d.DialContext(ctx, network, f.target)
+}
+
+func (f *fakeDNS) stats() (lookups int, dialed []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lookups, append([]string(nil), f.dialed...)
+}
+
+func TestResolvingDialer_Recycle(t *testing.T) {
+	target, _ := listenMock(t)
+	dns := &fakeDNS{target: target}
+	dns.set(nil, "10.0.0.1")
+	d := newResolvingDialer(dns.dial, nil, 50*time.Millisecond, true)
+	d.lookup = dns.lookup
+
+	cfg := DefaultConfig().WithAddr("redis.internal:6379").WithMinIdleConns(0).WithPoolSize(1)
+	cfg.Dialer = d.dial
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if err := client.Set(ctx, "k", "v", 0).Err(); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	if lookups, dialed := dns.stats(); lookups != 1 || len(dialed) != 1 || dialed[0] != "10.0.0.1:6379" {
+		t.Fatalf("lookups = %d, dialed = %v, want 1 lookup and a dial to 10.0.0.1:6379", lookups, dialed)
+	}
+
+	// The endpoint moves; commands on the pooled connection notice once the TTL passes
+	dns.set(nil, "10.0.0.2")
+	eventually(t, "traffic moves to the new address", func() bool {
+		if err := client.Get(ctx, "k").Err(); err != nil {
+			t.Fatalf("Get() error = %v, want nil", err)
+		}
+		_, dialed := dns.stats()
+		return dialed[len(dialed)-1] == "10.0.0.2:6379"
+	})
+	if total := client.PoolStats().TotalConns; total != 1 {
+		t.Errorf("pool holds %d connections, want the stale one discarded", total)
+	}
+}
+
+func TestResolvingDialer(t *testing.T) {
+	target, _ := listenMock(t)
+	dns := &fakeDNS{target: target}
+	d := newResolvingDialer(dns.dial, nil, time.Hour, false)
+	d.lookup = dns.lookup
+	ctx := context.Background()
+
+	dns.set(errors.New("no such host"))
+	if _, err := d.dial(ctx, "tcp", "redis.internal:6379"); err == nil {
+		t.Error("dial() error = nil when the host does not resolve, want error")
+	}
+	dns.set(nil)
+	if _, err := d.dial(ctx, "tcp", "redis.internal:6379"); err == nil {
+		t.Error("dial() error = nil when the host has no addresses, want error")
+	}
+
+	dns.set(nil, "10.0.0.1", "10.0.0.2")
+	for i := 0; i < 2; i++ {
+		conn, err := d.dial(ctx, "tcp", "redis.internal:6379")
+		if err != nil {
+			t.Fatalf("dial() error = %v, want nil", err)
+		}
+		if _, ok := conn.(*resolvedConn); ok {
+			t.Error("dial() without recycling returned a tracked connection")
+		}
+		_ = conn.Close()
+	}
+	if lookups, dialed := dns.stats(); lookups != 3 || dialed[len(dialed)-1] != "10.0.0.1:6379" {
+		t.Errorf("lookups = %d, dialed = %v, want the cached resolution reused", lookups, dialed)
+	}
+
+	// An expired resolution that fails to refresh keeps the previous addresses
+	d.ttl = 0
+	dns.set(errors.New("server misbehaving"))
+	if conn, err := d.dial(ctx, "tcp", "redis.internal:6379"); err != nil {
+		t.Errorf("dial() with a failed refresh error = %v, want the previous addresses", err)
+	} else {
+		_ = conn.Close()
+	}
+
+	// IP addresses are dialed as they are
+	lookups, _ := dns.stats()
+	if conn, err := d.dial(ctx, "tcp", "10.0.0.9:6379"); err != nil {
+		t.Errorf("dial() to an IP error = %v, want nil", err)
+	} else {
+		_ = conn.Close()
+	}
+	if n, dialed := dns.stats(); n != lookups || dialed[len(dialed)-1] != "10.0.0.9:6379" {
+		t.Errorf("dial() to an IP looked up %d times, dialed %v", n-lookups, dialed)
+	}
+	if _, err := d.dial(ctx, "tcp", "no-port"); err == nil {
+		t.Error("dial() to an address without port error = nil, want error")
+	}
+}
+
+func TestNewClient_DNSRefresh(t *testing.T) {
+	target, _ := listenMock(t)
+	_, port, _ := net.SplitHostPort(target)
+	cfg := DefaultConfig().WithAddr("localhost:"+port).WithDialTimeout(2*time.Second).WithDNSRefresh(time.Minute, true)
+
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() with dns refresh error = %v, want nil", err)
+	}
+	_ = client.Close()
+
+	// The proxy is given the resolved address
+	proxy := startProxy(t, "socks5", "")
+	client, err = NewClient(cfg.WithProxy("socks5h://" + proxy.addr))
+	if err != nil {
+		t.Fatalf("NewClient() with dns refresh through a proxy error = %v, want nil", err)
+	}
+	_ = client.Close()
+	if seen := proxy.seen(); seen[len(seen)-1] != target {
+		t.Errorf("proxy target = %v, want %s", seen, target)
+	}
+
+	if _, err := NewClient(cfg.WithUnixSocket("/tmp/redis.sock").WithLazyConnect(true)); err == nil {
+		t.Error("NewClient() with dns refresh over a unix socket error = nil, want error")
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// handshakeCommands are sent by go-redis through the hooks when it sets up a connection; their
+// errors are expected from older servers and handled by go-redis, so they are never retried
+var handshakeCommands = map[string]struct{}{
+	"hello":  {},
+	"auth":   {},
+	"select": {},
+	"client": {},
+}
+
+// retryHook retries commands and pipelines under a policy, replacing go-redis's own retries
+// when Config.RetryPredicate is set
+// A pipeline is retried as a whole, as go-redis does
+type retryHook struct {
+	policy utils.RetryPolicy
+}
+
+// handshake reports whether cmds set up a connection
+func handshake(cmds ...redis.Cmder) bool {
+	for _, cmd := range cmds {
+		if _, ok := handshakeCommands[cmd.Name()]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// maxRetries returns the MaxRetries passed to go-redis, which does not retry when a
+// retryHook retries in its place
+func maxRetries(cfg Config) int {
+	if cfg.RetryPredicate != nil {
+		return -1
+	}
+	return cfg.MaxRetries
+}
+
+func (h retryHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h retryHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if handshake(cmd) {
+			return next(ctx, cmd)
+		}
+		return utils.Retry(ctx, h.policy, func(ctx context.Context) error {
+			countAttempt(ctx)
+			cmd.SetErr(nil)
+			return next(ctx, cmd)
+		})
+	}
+}
+
+func (h retryHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if handshake(cmds...) {
+			return next(ctx, cmds)
+		}
+		return utils.Retry(ctx, h.policy, func(ctx context.Context) error {
+			countAttempt(ctx)
+			for _, cmd := range cmds {
+				cmd.SetErr(nil)
+			}
+			return next(ctx, cmds)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SentinelConfig locates a Redis master through Redis Sentinel
+type SentinelConfig struct {
+	// MasterName is the name the sentinels monitor the master under (e.g., "mymaster")
+	MasterName string
+
+	// SentinelAddrs are the addresses of the sentinels (e.g., "10.0.0.1:26379")
+	SentinelAddrs []string
+
+	// SentinelPassword authenticates to the sentinels (empty if they require no password);
+	// Config.Password authenticates to the master
+	SentinelPassword string
+}
+
+// NewFailoverClient creates a Redis client that asks the sentinels for the current master
+// and follows it when they promote a replica. Pool, timeout, authentication, and TLS settings
+// are taken from cfg and TLS applies to the sentinels too; cfg.Addr, cfg.Addrs, and cfg.Network
+// are ignored. cfg.Dialer and cfg.Proxy, when set, apply to the sentinels as well as the
+// master, and ClientName and OnConnect apply to sentinel connections too
+func NewFailoverClient(cfg Config, sentinel SentinelConfig) (*redis.Client, error) {
+	if sentinel.MasterName == "" {
+		return nil, fmt.Errorf("sentinel master name is required")
+	}
+	if len(sentinel.SentinelAddrs) == 0 {
+		return nil, fmt.Errorf("sentinel addresses are required")
+	}
+
+	tlsConfig, err := buildTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := protocol(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dial, err := buildAddrDialer(cfg, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &redis.FailoverOptions{
+		MasterName:       sentinel.MasterName,
+		SentinelAddrs:    sentinel.SentinelAddrs,
+		SentinelPassword: sentinel.SentinelPassword,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		DB:               cfg.DB,
+		Protocol:         resp,
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.MinIdleConns,
+		DialTimeout:      cfg.DialTimeout,
+		ReadTimeout:      cfg.ReadTimeout,
+		WriteTimeout:     cfg.WriteTimeout,
+		MaxRetries:       maxRetries(cfg),
+		MinRetryBackoff:  cfg.MinRetryBackoff,
+		MaxRetryBackoff:  cfg.MaxRetryBackoff,
+		PoolTimeout:      cfg.PoolTimeout,
+		TLSConfig:        tlsConfig,
+		ClientName:       cfg.ClientName,
+		OnConnect:        cfg.OnConnect,
+	}
+	opts.ContextTimeoutEnabled = cfg.ContextTimeout
+	if dial != nil {
+		opts.Dialer = dial
+	}
+
+	return connect(redis.NewFailoverClient(opts), cfg)
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestNewFailoverClient(t *testing.T) {
+	t.Run("missing master name", func(t *testing.T) {
+		_, err := NewFailoverClient(DefaultConfig(), SentinelConfig{SentinelAddrs: []string{"localhost:26379"}})
+		if err == nil || err.Error() != "sentinel master name is required" {
+			t.Errorf("NewFailoverClient() error = %v, want missing master name", err)
+		}
+	})
+
+	t.Run("missing sentinel addresses", func(t *testing.T) {
+		_, err := NewFailoverClient(DefaultConfig(), SentinelConfig{MasterName: "mymaster"})
+		if err == nil || err.Error() != "sentinel addresses are required" {
+			t.Errorf("NewFailoverClient() error = %v, want missing addresses", err)
+		}
+	})
+
+	t.Run("invalid tls settings", func(t *testing.T) {
+		cfg := DefaultConfig().WithTLSCertFiles("client.crt", "")
+		_, err := NewFailoverClient(cfg, SentinelConfig{MasterName: "mymaster", SentinelAddrs: []string{"localhost:26379"}})
+		if err == nil {
+			t.Error("NewFailoverClient() with invalid TLS settings error = nil, want error")
+		}
+	})
+
+	t.Run("unknown master", func(t *testing.T) {
+		mock := testutil.NewMockRedis()
+		cfg := DefaultConfig().WithDialTimeout(200 * time.Millisecond).WithMaxRetries(-1)
+		cfg.Dialer = mock.Dialer()
+
+		_, err := NewFailoverClient(cfg, SentinelConfig{MasterName: "mymaster", SentinelAddrs: []string{"sentinel:26379"}})
+		if err == nil {
+			t.Error("NewFailoverClient() for an unmonitored master error = nil, want error")
+		}
+	})
+
+	t.Run("successful creation with mock dialer", func(t *testing.T) {
+		mock := testutil.NewMockRedis()
+		mock.SetSentinelMaster("mymaster", "10.0.0.1:6379")
+		cfg := DefaultConfig().WithDialTimeout(2 * time.Second)
+		cfg.Dialer = mock.Dialer()
+
+		client, err := NewFailoverClient(cfg, SentinelConfig{
+			MasterName:    "mymaster",
+			SentinelAddrs: []string{"sentinel:26379"},
+		})
+		if err != nil {
+			t.Fatalf("NewFailoverClient() error = %v, want nil", err)
+		}
+		defer func() { _ = client.Close() }()
+
+		ctx := context.Background()
+		if err := client.Set(ctx, "k", "v", 0).Err(); err != nil {
+			t.Errorf("Set() through failover client error = %v, want nil", err)
+		}
+		if got, _ := client.Get(ctx, "k").Result(); got != "v" {
+			t.Errorf("Get() = %q, want v", got)
+		}
+	})
+}
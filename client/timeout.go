@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// readCommands are the read-only commands given the read timeout by WithDefaultTimeouts
+var readCommands = map[string]struct{}{
+	"get": {}, "mget": {}, "getrange": {}, "strlen": {}, "getbit": {}, "bitcount": {}, "bitpos": {},
+	"exists": {}, "type": {}, "ttl": {}, "pttl": {}, "expiretime": {}, "pexpiretime": {},
+	"keys": {}, "scan": {}, "randomkey": {}, "dbsize": {}, "object": {}, "dump": {},
+	"hget": {}, "hmget": {}, "hgetall": {}, "hexists": {}, "hlen": {}, "hkeys": {}, "hvals": {},
+	"hstrlen": {}, "hrandfield": {}, "hscan": {},
+	"llen": {}, "lrange": {}, "lindex": {}, "lpos": {},
+	"scard": {}, "smembers": {}, "sismember": {}, "smismember": {}, "srandmember": {},
+	"sinter": {}, "sunion": {}, "sdiff": {}, "sintercard": {}, "sscan": {},
+	"zcard": {}, "zcount": {}, "zlexcount": {}, "zscore": {}, "zmscore": {}, "zrank": {}, "zrevrank": {},
+	"zrange": {}, "zrangebyscore": {}, "zrangebylex": {}, "zrevrange": {}, "zrevrangebyscore": {},
+	"zrevrangebylex": {}, "zrandmember": {}, "zscan": {},
+	"pfcount": {}, "xlen": {}, "xrange": {}, "xrevrange": {}, "xpending": {}, "xinfo": {},
+	"geopos": {}, "geodist": {}, "geohash": {}, "geosearch": {}, "georadius_ro": {}, "georadiusbymember_ro": {},
+	"eval_ro": {}, "evalsha_ro": {}, "fcall_ro": {},
+	"ping": {}, "echo": {}, "info": {}, "time": {},
+}
+
+// blockingCommands wait on the server for as long as their own timeout argument says, so
+// WithDefaultTimeouts leaves them alone
+var blockingCommands = map[string]struct{}{
+	"blpop": {}, "brpop": {}, "brpoplpush": {}, "blmove": {}, "blmpop": {},
+	"bzpopmin": {}, "bzpopmax": {}, "bzmpop": {},
+	"xread": {}, "xreadgroup": {}, "wait": {}, "waitaof": {},
+}
+
+// WithDefaultTimeouts makes every command of client that runs under a context without a
+// deadline time out after read (read-only commands) or write (other commands and
+// pipelines), so kit operations called with context.Background() cannot hang on a stuck
+// connection; a timeout attached with utils.ContextWithTimeoutOverride takes precedence. The
+// deadline bounds socket reads and writes only on clients created with Config.ContextTimeout
+// It adds a hook to client, so the timeouts apply to every user of client, and returns client
+// for use in constructors. Blocking commands such as BLPOP are left to their own timeout, and
+// a non-positive read or write leaves those commands unbounded
+func WithDefaultTimeouts(client redis.UniversalClient, read, write time.Duration) redis.UniversalClient {
+	client = utils.NormalizeClient(client)
+	if client == nil {
+		return nil
+	}
+	client.AddHook(timeoutHook{read: read, write: write})
+	return client
+}
+
+// timeoutHook gives commands without a deadline the default timeout of their kind
+type timeoutHook struct {
+	read, write time.Duration
+}
+
+// timeout returns the default timeout for cmds, or 0 for none
+func (h timeoutHook) timeout(cmds ...redis.Cmder) time.Duration {
+	timeout := h.read
+	for _, cmd := range cmds {
+		name := cmd.Name()
+		if _, ok := blockingCommands[name]; ok {
+			return 0
+		}
+		if _, ok := readCommands[name]; !ok {
+			timeout = h.write
+		}
+	}
+	return timeout
+}
+
+// withTimeout returns ctx bounded by timeout unless it already has a deadline
+func (h timeoutHook) withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	if _, ok := utils.TimeoutOverride(ctx); !ok && timeout <= 0 {
+		return ctx, func() {}
+	}
+	return utils.WithOperationTimeout(ctx, timeout)
+}
+
+func (h timeoutHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h timeoutHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, cancel := h.withTimeout(ctx, h.timeout(cmd))
+		defer cancel()
+		return next(ctx, cmd)
+	}
+}
+
+func (h timeoutHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, cancel := h.withTimeout(ctx, h.timeout(cmds...))
+		defer cancel()
+		return next(ctx, cmds)
+	}
+}
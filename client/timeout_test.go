@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/chaos"
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// deadlineRecorder keeps the time left on the context of the last command
+type deadlineRecorder struct {
+	left time.Duration
+	set  bool
+}
+
+func (r *deadlineRecorder) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (r *deadlineRecorder) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		r.record(ctx, cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (r *deadlineRecorder) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		r.record(ctx, cmds...)
+		return next(ctx, cmds)
+	}
+}
+
+func (r *deadlineRecorder) record(ctx context.Context, cmds ...redis.Cmder) {
+	if handshake(cmds...) {
+		return
+	}
+	deadline, ok := ctx.Deadline()
+	r.left, r.set = time.Until(deadline), ok
+}
+
+func TestWithDefaultTimeouts(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	if got := WithDefaultTimeouts(client, time.Second, time.Minute); got != client {
+		t.Fatal("WithDefaultTimeouts() did not return the client")
+	}
+	rec := &deadlineRecorder{}
+	client.AddHook(rec)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context)
+		want time.Duration // 0 for no deadline
+	}{
+		{"read", func(ctx context.Context) { client.Get(ctx, "k") }, time.Second},
+		{"write", func(ctx context.Context) { client.Set(ctx, "k", "v", 0) }, time.Minute},
+		{"read pipeline", func(ctx context.Context) {
+			pipe := client.Pipeline()
+			pipe.Get(ctx, "a")
+			pipe.TTL(ctx, "a")
+			_, _ = pipe.Exec(ctx)
+		}, time.Second},
+		{"mixed pipeline", func(ctx context.Context) {
+			pipe := client.Pipeline()
+			pipe.Get(ctx, "a")
+			pipe.Incr(ctx, "b")
+			_, _ = pipe.Exec(ctx)
+		}, time.Minute},
+		{"blocking", func(ctx context.Context) { client.BLPop(ctx, time.Second, "list") }, 0},
+	}
+	for _, tt := range tests {
+		tt.run(ctx)
+		if tt.want == 0 {
+			if rec.set {
+				t.Errorf("%s: deadline set %v ahead, want none", tt.name, rec.left)
+			}
+			continue
+		}
+		if !rec.set || rec.left > tt.want || rec.left < tt.want-time.Second/2 {
+			t.Errorf("%s: deadline %v ahead (set %v), want about %v", tt.name, rec.left, rec.set, tt.want)
+		}
+	}
+
+	// A caller's deadline is kept, and a timeout override wins over the defaults
+	short, cancel := context.WithTimeout(ctx, time.Hour)
+	defer cancel()
+	client.Get(short, "k")
+	if rec.left < 59*time.Minute {
+		t.Errorf("deadline %v ahead, want the caller's hour", rec.left)
+	}
+	client.BLPop(utils.ContextWithTimeoutOverride(ctx, 5*time.Second), time.Second, "list")
+	if !rec.set || rec.left > 5*time.Second || rec.left < 4*time.Second {
+		t.Errorf("deadline %v ahead (set %v), want the 5s override", rec.left, rec.set)
+	}
+
+	if WithDefaultTimeouts(nil, time.Second, time.Second) != nil {
+		t.Error("WithDefaultTimeouts(nil) != nil")
+	}
+}
+
+func TestWithDefaultTimeouts_Unbounded(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	WithDefaultTimeouts(client, 0, time.Minute)
+	rec := &deadlineRecorder{}
+	client.AddHook(rec)
+	client.Get(context.Background(), "k")
+	if rec.set {
+		t.Errorf("read deadline set %v ahead, want none with a zero read timeout", rec.left)
+	}
+}
+
+func TestWithDefaultTimeouts_Stuck(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	// Every command stalls for an hour; the default timeout cuts the cache call short
+	WithDefaultTimeouts(client, 50*time.Millisecond, 50*time.Millisecond)
+	chaos.Inject(client, chaos.DefaultConfig().WithLatency(1, time.Hour))
+
+	start := time.Now()
+	err := cache.NewCache(client, "app").Set(context.Background(), "k", "v", time.Minute)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Set() on a stuck connection error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Set() took %v, want about 50ms", elapsed)
+	}
+}
+
+func TestNewClient_ContextTimeout(t *testing.T) {
+	cfg := DefaultConfig().WithContextTimeout(true).WithLazyConnect(true)
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+	if !client.Options().ContextTimeoutEnabled {
+		t.Error("NewClient() ContextTimeoutEnabled = false, want true")
+	}
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+)
+
+// tlsEnabled reports whether cfg asks for TLS
+func (c Config) tlsEnabled() bool {
+	return c.TLSConfig != nil || c.TLSCertFile != "" || c.TLSKeyFile != "" || c.TLSCAFile != "" || c.TLSInsecureSkipVerify
+}
+
+// buildTLSConfig returns the TLS configuration described by cfg, or nil when TLS is not enabled
+// TLSConfig is cloned, never modified, before the file settings are applied
+func buildTLSConfig(cfg Config) (*tls.Config, error) {
+	if !cfg.tlsEnabled() {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLSConfig != nil {
+		tlsConfig = cfg.TLSConfig.Clone()
+	}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+			return nil, fmt.Errorf("tls cert file and key file must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls client certificate: %w", err)
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+	}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in tls ca file %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSInsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	return tlsConfig, nil
+}
+
+// tlsClient wraps conn in TLS when tlsConfig is set, verifying host unless tlsConfig names a
+// server, as go-redis does not apply TLS to custom dialers
+func tlsClient(conn net.Conn, tlsConfig *tls.Config, host string) net.Conn {
+	if tlsConfig == nil {
+		return conn
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = host
+	}
+	return tls.Client(conn, tlsConfig)
+}
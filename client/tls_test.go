@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+// writeCert writes a self-signed certificate for 127.0.0.1 and its key to dir
+func writeCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis-kit test"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir)
+
+	if tlsConfig, err := buildTLSConfig(DefaultConfig()); err != nil || tlsConfig != nil {
+		t.Errorf("buildTLSConfig() default = %v, %v, want nil", tlsConfig, err)
+	}
+
+	base := &tls.Config{ServerName: "redis.example.com"}
+	tlsConfig, err := buildTLSConfig(DefaultConfig().WithTLS(base).
+		WithTLSCertFiles(certFile, keyFile).
+		WithTLSCAFile(certFile).
+		WithTLSInsecureSkipVerify(true))
+	if err != nil {
+		t.Fatalf("buildTLSConfig() error = %v, want nil", err)
+	}
+	if tlsConfig == base || tlsConfig.ServerName != "redis.example.com" {
+		t.Error("buildTLSConfig() should build on a clone of TLSConfig")
+	}
+	if len(tlsConfig.Certificates) != 1 || tlsConfig.RootCAs == nil || !tlsConfig.InsecureSkipVerify {
+		t.Errorf("buildTLSConfig() = %+v, want certificate, CAs and InsecureSkipVerify", tlsConfig)
+	}
+	if len(base.Certificates) != 0 || base.RootCAs != nil || base.InsecureSkipVerify {
+		t.Error("buildTLSConfig() modified TLSConfig")
+	}
+
+	tlsConfig, err = buildTLSConfig(DefaultConfig().WithTLSInsecureSkipVerify(true))
+	if err != nil || tlsConfig == nil || tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("buildTLSConfig() InsecureSkipVerify only = %+v, %v, want TLS 1.2 minimum", tlsConfig, err)
+	}
+
+	errorCases := map[string]Config{
+		"cert without key": DefaultConfig().WithTLSCertFiles(certFile, ""),
+		"missing cert":     DefaultConfig().WithTLSCertFiles(filepath.Join(dir, "missing.pem"), keyFile),
+		"missing ca":       DefaultConfig().WithTLSCAFile(filepath.Join(dir, "missing.pem")),
+		"ca without certs": DefaultConfig().WithTLSCAFile(keyFile),
+	}
+	for name, cfg := range errorCases {
+		if _, err := buildTLSConfig(cfg); err == nil {
+			t.Errorf("buildTLSConfig() %s error = nil, want error", name)
+		}
+		if _, err := NewClient(cfg); err == nil {
+			t.Errorf("NewClient() %s error = nil, want error", name)
+		}
+	}
+}
+
+func TestNewClient_TLS(t *testing.T) {
+	certFile, keyFile := writeCert(t, t.TempDir())
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Terminate TLS in front of the mock
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	go serveMock(ln, testutil.NewMockRedis())
+
+	cfg := DefaultConfig().WithAddr(ln.Addr().String()).WithDialTimeout(2 * time.Second).WithTLSCAFile(certFile)
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() over TLS error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+	if err := client.Set(context.Background(), "k", "v", 0).Err(); err != nil {
+		t.Errorf("Set() over TLS error = %v, want nil", err)
+	}
+
+	// TLS is applied inside a proxy tunnel, verifying the Redis host rather than the proxy
+	proxy := startProxy(t, "socks5", "")
+	tunneled, err := NewClient(cfg.WithProxy("socks5://" + proxy.addr))
+	if err != nil {
+		t.Fatalf("NewClient() over TLS through a proxy error = %v, want nil", err)
+	}
+	defer func() { _ = tunneled.Close() }()
+	if err := tunneled.Get(context.Background(), "k").Err(); err != nil || len(proxy.seen()) == 0 {
+		t.Errorf("Get() over TLS through a proxy error = %v, want the value through the tunnel", err)
+	}
+
+	// Without the CA the server certificate is rejected
+	if _, err := NewClient(cfg.WithTLSCAFile("").WithTLS(&tls.Config{MinVersion: tls.VersionTLS12})); err == nil {
+		t.Error("NewClient() with untrusted server certificate error = nil, want error")
+	}
+}
@@ -0,0 +1,206 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation name of the tracer EnableTracing uses
+const TracerName = "github.com/soulteary/redis-kit/client"
+
+// Span attribute keys set by EnableTracing besides the OpenTelemetry database conventions
+const (
+	// AttrKeyPrefix is the first key of a command up to and including its last ':'
+	AttrKeyPrefix = attribute.Key("redis.key_prefix")
+
+	// AttrOutcome is the metrics outcome of a command: success, miss, or error
+	AttrOutcome = attribute.Key("redis.outcome")
+)
+
+var (
+	attrDBSystem      = attribute.String("db.system.name", "redis")
+	attrOperationName = attribute.Key("db.operation.name")
+	attrQueryText     = attribute.Key("db.query.text")
+	attrBatchSize     = attribute.Key("db.operation.batch.size")
+)
+
+// TracingOptions configures the spans created by EnableTracing
+type TracingOptions struct {
+	// TracerProvider creates the tracer (default: otel.GetTracerProvider())
+	TracerProvider trace.TracerProvider
+
+	// RedactValues replaces the arguments after the key with "?" in the recorded statement,
+	// keeping cached values, tokens, and other payloads out of traces
+	RedactValues bool
+
+	// OmitStatement leaves the statement out of spans entirely
+	OmitStatement bool
+}
+
+// EnableTracing makes client create an OpenTelemetry span for every command, pipeline, and dial,
+// including those issued by the kit's cache, lock, and rate limiter on top of it
+// Spans are named after the command and carry its key prefix and outcome; Redis errors other
+// than a missing key set the span status to error
+func EnableTracing(client redis.UniversalClient, opts TracingOptions) {
+	client = utils.NormalizeClient(client)
+	if client == nil {
+		return
+	}
+	provider := opts.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	client.AddHook(tracingHook{tracer: provider.Tracer(TracerName), opts: opts})
+}
+
+// tracingHook wraps commands, pipelines, and dials in client spans
+type tracingHook struct {
+	tracer trace.Tracer
+	opts   TracingOptions
+}
+
+func (h tracingHook) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attrDBSystem)
+	return h.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// end records the outcome of err on span and ends it
+func (h tracingHook) end(span trace.Span, err error) {
+	span.SetAttributes(AttrOutcome.String(metrics.Outcome(err)))
+	if err != nil && !errors.Is(err, redis.Nil) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// commandAttrs describes cmd for its span
+func (h tracingHook) commandAttrs(cmd redis.Cmder) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attrOperationName.String(cmd.Name())}
+	if prefix := keyPrefix(commandKey(cmd.Args())); prefix != "" {
+		attrs = append(attrs, AttrKeyPrefix.String(prefix))
+	}
+	if !h.opts.OmitStatement {
+		attrs = append(attrs, attrQueryText.String(statement(cmd.Args(), h.opts.RedactValues)))
+	}
+	return attrs
+}
+
+func (h tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := h.start(ctx, "dial", attribute.String("network.transport", network), attribute.String("server.address", addr))
+		conn, err := next(ctx, network, addr)
+		h.end(span, err)
+		return conn, err
+	}
+}
+
+func (h tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := h.start(ctx, cmd.Name(), h.commandAttrs(cmd)...)
+		err := next(ctx, cmd)
+		h.end(span, err)
+		return err
+	}
+}
+
+func (h tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		names := make([]string, len(cmds))
+		statements := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			names[i] = cmd.Name()
+			statements[i] = statement(cmd.Args(), h.opts.RedactValues)
+		}
+		attrs := []attribute.KeyValue{
+			attrOperationName.String("pipeline " + strings.Join(names, " ")),
+			attrBatchSize.Int(len(cmds)),
+		}
+		if !h.opts.OmitStatement {
+			attrs = append(attrs, attrQueryText.String(strings.Join(statements, "\n")))
+		}
+
+		ctx, span := h.start(ctx, "pipeline", attrs...)
+		err := next(ctx, cmds)
+		h.end(span, err)
+		return err
+	}
+}
+
+// keyPos returns the position of the first key in a command, or -1 for commands without keys
+// Scripts and functions name their keys after the key count; other commands take it first
+func keyPos(args []interface{}) int {
+	if len(args) < 2 {
+		return -1
+	}
+	switch strings.ToLower(fmt.Sprint(args[0])) {
+	case "eval", "evalsha", "eval_ro", "evalsha_ro", "fcall", "fcall_ro":
+		if len(args) < 4 {
+			return -1
+		}
+		if n, err := strconv.Atoi(fmt.Sprint(args[2])); err != nil || n < 1 {
+			return -1
+		}
+		return 3
+	case "ping", "echo", "info", "hello", "client", "auth", "select", "flushdb", "flushall", "dbsize",
+		"publish", "subscribe", "psubscribe", "script", "function", "time", "scan":
+		return -1
+	}
+	return 1
+}
+
+// commandKey returns the first key of a command, or "" for commands without keys
+func commandKey(args []interface{}) string {
+	if pos := keyPos(args); pos >= 0 {
+		return formatArg(args[pos])
+	}
+	return ""
+}
+
+// formatArg formats a command argument, showing byte slices as text
+func formatArg(arg interface{}) string {
+	if b, ok := arg.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(arg)
+}
+
+// keyPrefix returns key up to and including its last ':', which names the kit primitive and
+// namespace without the identifying part of the key
+func keyPrefix(key string) string {
+	if i := strings.LastIndexByte(key, ':'); i >= 0 {
+		return key[:i+1]
+	}
+	return ""
+}
+
+// statement formats a command, replacing the arguments after its key with "?" when redact is set
+func statement(args []interface{}, redact bool) string {
+	keep := len(args)
+	if redact {
+		// Keep the command name and everything up to the key
+		keep = min(keep, max(keyPos(args)+1, 1))
+	}
+
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		if i < keep {
+			parts[i] = formatArg(arg)
+		} else {
+			parts[i] = "?"
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/lock"
+	"github.com/soulteary/redis-kit/testutil"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordedSpan is a finished span seen by spanRecorder
+type recordedSpan struct {
+	name   string
+	attrs  map[attribute.Key]attribute.Value
+	status codes.Code
+}
+
+// spanRecorder is a TracerProvider keeping the spans it creates
+type spanRecorder struct {
+	noop.TracerProvider
+
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *spanRecorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{recorder: r}
+}
+
+// find returns the last finished span called name
+func (r *spanRecorder) find(name string) *recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.spans) - 1; i >= 0; i-- {
+		if r.spans[i].name == name {
+			return r.spans[i]
+		}
+	}
+	return nil
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	recorder *spanRecorder
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordingSpan{recorder: t.recorder, span: &recordedSpan{name: name, attrs: map[attribute.Key]attribute.Value{}}}
+	cfg := trace.NewSpanStartConfig(opts...)
+	span.SetAttributes(cfg.Attributes()...)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordingSpan struct {
+	noop.Span
+	recorder *spanRecorder
+	span     *recordedSpan
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...attribute.KeyValue) {
+	for _, attr := range attrs {
+		s.span.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) {
+	s.span.status = code
+}
+
+func (s *recordingSpan) End(...trace.SpanEndOption) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans = append(s.recorder.spans, s.span)
+}
+
+func TestEnableTracing(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	rec := &spanRecorder{}
+	EnableTracing(client, TracingOptions{TracerProvider: rec})
+
+	ctx := context.Background()
+	c := cache.NewCache(client, "cache:user:")
+	if err := c.Set(ctx, "42", "secret", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	var got string
+	_ = c.Get(ctx, "missing", &got)
+
+	set := rec.find("set")
+	if set == nil {
+		t.Fatal("no span for set")
+	}
+	if set.attrs[AttrKeyPrefix].AsString() != "cache:user:" || set.attrs[AttrOutcome].AsString() != "success" ||
+		set.attrs[attrOperationName].AsString() != "set" || set.attrs["db.system.name"].AsString() != "redis" {
+		t.Errorf("set span attributes = %v", set.attrs)
+	}
+	if stmt := set.attrs[attrQueryText].AsString(); stmt != "set cache:user:42 secret ex 60" {
+		t.Errorf("set statement = %q, want the full command", stmt)
+	}
+	if get := rec.find("get"); get == nil || get.attrs[AttrOutcome].AsString() != "miss" || get.status != codes.Unset {
+		t.Errorf("get span = %+v, want a miss without error status", get)
+	}
+	if rec.find("dial") == nil {
+		t.Error("no span for dial")
+	}
+
+	if ok, err := lock.NewRedisLocker(client, lock.WithKeyPrefix("lock:")).Lock("job"); err != nil || !ok {
+		t.Fatalf("Lock() = %v, %v", ok, err)
+	}
+	if set := rec.find("set"); set.attrs[AttrKeyPrefix].AsString() != "lock:" {
+		t.Errorf("lock span key prefix = %q, want lock:", set.attrs[AttrKeyPrefix].AsString())
+	}
+	pipe := client.Pipeline()
+	pipe.Get(ctx, "a")
+	pipe.Get(ctx, "b")
+	_, _ = pipe.Exec(ctx)
+	if p := rec.find("pipeline"); p == nil || p.attrs[attrBatchSize].AsInt64() != 2 || p.attrs[attrOperationName].AsString() != "pipeline get get" {
+		t.Errorf("pipeline span = %+v, want 2 gets", p)
+	}
+
+	mock.SetShouldFail(true)
+	_ = client.Get(ctx, "k").Err()
+	if get := rec.find("get"); get.status != codes.Error || get.attrs[AttrOutcome].AsString() != "error" {
+		t.Errorf("failed get span = %+v, want error status", get)
+	}
+}
+
+func TestEnableTracing_Redact(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	rec := &spanRecorder{}
+	EnableTracing(client, TracingOptions{TracerProvider: rec, RedactValues: true})
+
+	ctx := context.Background()
+	_ = client.Ping(ctx).Err()
+	_ = client.Set(ctx, "session:abc", "token", 0).Err()
+	_ = client.Eval(ctx, "-- redis-kit:cooldown\nreturn 1", []string{"ratelimit:cooldown:ip"}, "60000").Err()
+
+	if stmt := rec.find("set").attrs[attrQueryText].AsString(); stmt != "set session:abc ?" {
+		t.Errorf("redacted set statement = %q, want the value hidden", stmt)
+	}
+	eval := rec.find("eval")
+	if eval == nil || eval.attrs[AttrKeyPrefix].AsString() != "ratelimit:cooldown:" {
+		t.Fatalf("eval span = %+v, want the key prefix of its first key", eval)
+	}
+	if stmt := eval.attrs[attrQueryText].AsString(); stmt != "eval -- redis-kit:cooldown\nreturn 1 1 ratelimit:cooldown:ip ?" {
+		t.Errorf("redacted eval statement = %q", stmt)
+	}
+	if ping := rec.find("ping"); ping == nil || ping.attrs[attrQueryText].AsString() != "ping" {
+		t.Errorf("ping span = %+v, want statement ping", ping)
+	}
+
+	client2, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client2.Close() }()
+	rec = &spanRecorder{}
+	EnableTracing(client2, TracingOptions{TracerProvider: rec, OmitStatement: true})
+	_ = client2.Set(ctx, "k", "v", 0).Err()
+	if _, ok := rec.find("set").attrs[attrQueryText]; ok {
+		t.Error("OmitStatement span has a statement")
+	}
+}
+
+func TestEnableTracing_NilClient(t *testing.T) {
+	var client *redis.Client
+	EnableTracing(client, TracingOptions{})
+	EnableTracing(nil, TracingOptions{})
+
+	// The global provider is used by default
+	real, _ := testutil.NewMockRedisClient()
+	defer func() { _ = real.Close() }()
+	EnableTracing(real, TracingOptions{})
+	if err := real.Ping(context.Background()).Err(); err != nil {
+		t.Errorf("Ping() with default provider error = %v, want nil", err)
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	tests := []struct {
+		args []interface{}
+		want string
+	}{
+		{[]interface{}{"get", "cache:user:42"}, "cache:user:"},
+		{[]interface{}{"get", "plain"}, ""},
+		{[]interface{}{"ping"}, ""},
+		{[]interface{}{"publish", "events:x", "m"}, ""},
+		{[]interface{}{"evalsha", "abc", 0}, ""},
+		{[]interface{}{"evalsha", "abc", 1, "statemachine:orders:{42}"}, "statemachine:orders:"},
+	}
+	for _, tt := range tests {
+		if got := keyPrefix(commandKey(tt.args)); got != tt.want {
+			t.Errorf("keyPrefix(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package client
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Defaults for TrackingOptions
+const (
+	DefaultTrackingMaxEntries = 10000
+	DefaultTrackingMaxAge     = time.Minute
+)
+
+// trackingChannel carries the invalidations Redis sends to a RESP2 redirect target
+const trackingChannel = "__redis__:invalidate"
+
+// trackingRetryDelay is how long the invalidation listener waits after a connection error
+const trackingRetryDelay = 100 * time.Millisecond
+
+// TrackingOptions configures a TrackingCache
+type TrackingOptions struct {
+	// Prefixes limits caching to keys starting with one of them, and Redis only sends
+	// invalidations for those keys (default: all keys)
+	Prefixes []string
+
+	// MaxEntries caps the number of cached keys, evicting the least recently used
+	// (default: DefaultTrackingMaxEntries)
+	MaxEntries int
+
+	// MaxAge bounds how long a value is served without asking Redis, as Redis may report the
+	// expiry of a key late (default: DefaultTrackingMaxAge)
+	MaxAge time.Duration
+
+	// OnError receives errors of the invalidation connection (default: ignored)
+	OnError func(error)
+}
+
+// TrackingStats counts the reads of a TrackingCache
+type TrackingStats struct {
+	Hits          uint64 // reads served from memory
+	Misses        uint64 // reads sent to Redis
+	Invalidations uint64 // keys Redis reported as changed
+}
+
+// TrackingCache keeps the values of string keys in process memory, coherent with Redis through
+// server-assisted client-side caching (Redis 6+)
+// A dedicated connection enables CLIENT TRACKING in broadcasting mode and receives an
+// invalidation whenever a matching key is written by any client, so cached values are dropped
+// shortly after they change. While that connection is down, reads go to Redis and nothing is
+// cached; after it reconnects the cache starts empty, as invalidations may have been missed
+//
+// Invalidations are received over RESP2 pub/sub, so the cache works whichever protocol client
+// uses. Values may be stale for the time an invalidation takes to arrive
+type TrackingCache struct {
+	client *redis.Client
+	sub    *redis.Client
+	pubsub *redis.PubSub
+	opts   TrackingOptions
+	prefix []string
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // of *trackedEntry, most recently used first
+	ready   bool       // the invalidation connection is subscribed
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	invalidations atomic.Uint64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// trackedEntry is a cached value, or a read in progress while pending
+type trackedEntry struct {
+	key     string
+	value   string
+	missing bool // the key did not exist
+	pending bool
+	expires time.Time
+}
+
+// NewTrackingCache creates a cache reading through client and opens its invalidation
+// connection with client's options; it fails if Redis rejects CLIENT TRACKING
+// Close stops the cache; it does not close client
+func NewTrackingCache(ctx context.Context, client *redis.Client, opts TrackingOptions) (*TrackingCache, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultTrackingMaxEntries
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultTrackingMaxAge
+	}
+
+	c := &TrackingCache{
+		client:  client,
+		opts:    opts,
+		prefix:  append([]string(nil), opts.Prefixes...),
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		done:    make(chan struct{}),
+	}
+
+	// The invalidation connection speaks RESP2, so invalidations arrive as pub/sub messages;
+	// tracking is enabled again each time it reconnects
+	subOpts := *client.Options()
+	onConnect := subOpts.OnConnect
+	subOpts.Protocol = 2
+	subOpts.PoolSize = 1
+	subOpts.MinIdleConns = 0
+	subOpts.MaxIdleConns = 0
+	subOpts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		if onConnect != nil {
+			if err := onConnect(ctx, cn); err != nil {
+				return err
+			}
+		}
+		return c.enableTracking(ctx, cn)
+	}
+	c.sub = redis.NewClient(&subOpts)
+
+	c.pubsub = c.sub.Subscribe(ctx, trackingChannel)
+	if _, err := c.pubsub.ReceiveTimeout(ctx, subOpts.DialTimeout+subOpts.ReadTimeout); err != nil {
+		_ = c.pubsub.Close()
+		_ = c.sub.Close()
+		return nil, fmt.Errorf("failed to enable client tracking: %w", err)
+	}
+	c.setReady(true)
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go c.listen(runCtx)
+	return c, nil
+}
+
+// enableTracking turns on broadcasting tracking for cn, redirected to itself
+func (c *TrackingCache) enableTracking(ctx context.Context, cn *redis.Conn) error {
+	id, err := cn.ClientID(ctx).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %w", err)
+	}
+	args := []interface{}{"client", "tracking", "on", "redirect", id, "bcast"}
+	for _, prefix := range c.prefix {
+		args = append(args, "prefix", prefix)
+	}
+	if err := cn.Do(ctx, args...).Err(); err != nil {
+		return fmt.Errorf("failed to enable client tracking: %w", err)
+	}
+	return nil
+}
+
+// listen applies invalidations until ctx is cancelled; the pub/sub connection reconnects and
+// resubscribes by itself after errors
+// Redis reports a flush of the keyspace with a nil payload, which go-redis fails to parse;
+// the error empties the cache like a lost connection does
+func (c *TrackingCache) listen(ctx context.Context) {
+	defer close(c.done)
+	for {
+		msg, err := c.pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.setReady(false)
+			if c.opts.OnError != nil {
+				c.opts.OnError(fmt.Errorf("client tracking connection failed: %w", err))
+			}
+			timer := time.NewTimer(trackingRetryDelay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			if msg.Kind == "subscribe" {
+				c.setReady(true)
+			}
+		case *redis.Message:
+			if msg.Channel != trackingChannel {
+				continue
+			}
+			keys := msg.PayloadSlice
+			if len(keys) == 0 {
+				keys = []string{msg.Payload}
+			}
+			c.invalidations.Add(uint64(len(keys)))
+			c.Invalidate(keys...)
+		}
+	}
+}
+
+// setReady empties the cache and sets whether values may be cached
+func (c *TrackingCache) setReady(ready bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.ready = ready
+}
+
+// tracked reports whether key is covered by the tracking prefixes
+func (c *TrackingCache) tracked(key string) bool {
+	if len(c.prefix) == 0 {
+		return true
+	}
+	for _, prefix := range c.prefix {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the value of key, from memory when it is cached and from Redis otherwise
+// A key that does not exist returns redis.Nil, and is cached as missing too
+func (c *TrackingCache) Get(ctx context.Context, key string) (string, error) {
+	if !c.tracked(key) {
+		c.misses.Add(1)
+		return c.client.Get(ctx, key).Result()
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*trackedEntry)
+		if !entry.pending && now.Before(entry.expires) {
+			c.lru.MoveToFront(elem)
+			c.mu.Unlock()
+			c.hits.Add(1)
+			if entry.missing {
+				return "", redis.Nil
+			}
+			return entry.value, nil
+		}
+	}
+	// Mark the read in progress; an invalidation arriving before it completes removes the
+	// marker, so the value read is not cached
+	var marker *list.Element
+	if c.ready {
+		marker = c.storeLocked(&trackedEntry{key: key, pending: true})
+	}
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	value, err := c.client.Get(ctx, key).Result()
+	if marker == nil {
+		return value, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[key] != marker {
+		return value, err
+	}
+	if err != nil && !errors.Is(err, redis.Nil) {
+		c.removeLocked(marker)
+		return value, err
+	}
+	entry := marker.Value.(*trackedEntry)
+	entry.value = value
+	entry.missing = err != nil
+	entry.pending = false
+	entry.expires = now.Add(c.opts.MaxAge)
+	return value, err
+}
+
+// storeLocked caches entry as the most recently used, evicting beyond MaxEntries
+func (c *TrackingCache) storeLocked(entry *trackedEntry) *list.Element {
+	if elem, ok := c.entries[entry.key]; ok {
+		c.removeLocked(elem)
+	}
+	elem := c.lru.PushFront(entry)
+	c.entries[entry.key] = elem
+	for c.lru.Len() > c.opts.MaxEntries {
+		c.removeLocked(c.lru.Back())
+	}
+	return elem
+}
+
+func (c *TrackingCache) removeLocked(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*trackedEntry).key)
+}
+
+// Invalidate drops keys from memory, e.g. right after writing them, before Redis reports it
+func (c *TrackingCache) Invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := c.entries[key]; ok {
+			c.removeLocked(elem)
+		}
+	}
+}
+
+// Len returns the number of cached keys
+func (c *TrackingCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Ready reports whether the invalidation connection is up, so values are cached
+func (c *TrackingCache) Ready() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ready
+}
+
+// Stats returns the read counters
+func (c *TrackingCache) Stats() TrackingStats {
+	return TrackingStats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Invalidations: c.invalidations.Load(),
+	}
+}
+
+// Close stops receiving invalidations and empties the cache
+func (c *TrackingCache) Close() error {
+	c.cancel()
+	err := c.pubsub.Close()
+	<-c.done
+	c.setReady(false)
+	return errors.Join(err, c.sub.Close())
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+// eventually polls cond until it holds or a few seconds passed
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTrackingCache(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	tc, err := NewTrackingCache(ctx, client, TrackingOptions{Prefixes: []string{"user:"}})
+	if err != nil {
+		t.Fatalf("NewTrackingCache() error = %v, want nil", err)
+	}
+	defer func() { _ = tc.Close() }()
+	if !tc.Ready() {
+		t.Error("Ready() = false, want true")
+	}
+
+	// Invalidations are asynchronous; let the one for this write arrive before reading
+	_ = client.Set(ctx, "user:1", "alice", 0).Err()
+	eventually(t, "the write is reported", func() bool { return tc.Stats().Invalidations == 1 })
+	for i := 0; i < 3; i++ {
+		if v, err := tc.Get(ctx, "user:1"); err != nil || v != "alice" {
+			t.Fatalf("Get(user:1) = %q, %v, want alice", v, err)
+		}
+	}
+	if stats := tc.Stats(); stats.Misses != 1 || stats.Hits != 2 {
+		t.Errorf("Stats() = %+v, want 1 miss and 2 hits", stats)
+	}
+
+	// A write by any client invalidates the cached value
+	_ = client.Set(ctx, "user:1", "bob", 0).Err()
+	eventually(t, "the write is seen", func() bool {
+		v, _ := tc.Get(ctx, "user:1")
+		return v == "bob"
+	})
+
+	// Missing keys are cached until they are created
+	if _, err := tc.Get(ctx, "user:2"); !errors.Is(err, redis.Nil) {
+		t.Errorf("Get(user:2) error = %v, want redis.Nil", err)
+	}
+	hits := tc.Stats().Hits
+	if _, err := tc.Get(ctx, "user:2"); !errors.Is(err, redis.Nil) || tc.Stats().Hits != hits+1 {
+		t.Errorf("Get(user:2) again error = %v, want a cached redis.Nil", err)
+	}
+	_ = client.Set(ctx, "user:2", "carol", 0).Err()
+	eventually(t, "the new key is seen", func() bool {
+		v, _ := tc.Get(ctx, "user:2")
+		return v == "carol"
+	})
+
+	// Keys outside the prefixes always go to Redis
+	_ = client.Set(ctx, "order:1", "x", 0).Err()
+	misses := tc.Stats().Misses
+	_, _ = tc.Get(ctx, "order:1")
+	_, _ = tc.Get(ctx, "order:1")
+	if tc.Stats().Misses != misses+2 || tc.Len() != 2 {
+		t.Errorf("untracked reads: misses %d -> %d, Len() = %d, want 2 misses and 2 entries", misses, tc.Stats().Misses, tc.Len())
+	}
+
+	tc.Invalidate("user:1", "user:2")
+	if tc.Len() != 0 {
+		t.Errorf("Len() after Invalidate = %d, want 0", tc.Len())
+	}
+}
+
+func TestTrackingCache_Limits(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	tc, err := NewTrackingCache(ctx, client, TrackingOptions{MaxEntries: 2, MaxAge: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewTrackingCache() error = %v, want nil", err)
+	}
+	defer func() { _ = tc.Close() }()
+
+	for i := 0; i < 3; i++ {
+		_, _ = tc.Get(ctx, fmt.Sprintf("k%d", i))
+	}
+	if tc.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", tc.Len())
+	}
+
+	// Values older than MaxAge are read again
+	_, _ = tc.Get(ctx, "k2")
+	time.Sleep(60 * time.Millisecond)
+	misses := tc.Stats().Misses
+	_, _ = tc.Get(ctx, "k2")
+	if tc.Stats().Misses != misses+1 {
+		t.Error("Get() after MaxAge was served from memory, want a read from Redis")
+	}
+}
+
+func TestTrackingCache_Reconnect(t *testing.T) {
+	topo := newTopology("redis:6379")
+	cfg := DefaultConfig().WithAddr("redis:6379").WithMinIdleConns(0).WithDialTimeout(time.Second)
+	cfg.Dialer = topo.dial
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	var errs atomic.Int32
+	tc, err := NewTrackingCache(ctx, client, TrackingOptions{OnError: func(error) { errs.Add(1) }})
+	if err != nil {
+		t.Fatalf("NewTrackingCache() error = %v, want nil", err)
+	}
+	defer func() { _ = tc.Close() }()
+
+	_ = client.Set(ctx, "k", "v1", 0).Err()
+	_, _ = tc.Get(ctx, "k")
+
+	// Losing the invalidation connection empties the cache and disables caching
+	topo.setDown("redis:6379", true)
+	eventually(t, "the connection loss is noticed", func() bool { return !tc.Ready() })
+	if tc.Len() != 0 || errs.Load() == 0 {
+		t.Errorf("after disconnect Len() = %d, errors = %d, want an empty cache and an error", tc.Len(), errs.Load())
+	}
+
+	topo.setDown("redis:6379", false)
+	eventually(t, "tracking is enabled again", tc.Ready)
+	_ = client.Set(ctx, "k", "v2", 0).Err()
+	eventually(t, "the write is seen", func() bool {
+		v, _ := tc.Get(ctx, "k")
+		return v == "v2"
+	})
+	_ = client.Set(ctx, "k", "v3", 0).Err()
+	eventually(t, "invalidations arrive after reconnecting", func() bool {
+		v, _ := tc.Get(ctx, "k")
+		return v == "v3"
+	})
+}
+
+func TestTrackingCache_Cache(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	tc, err := NewTrackingCache(ctx, client, TrackingOptions{Prefixes: []string{"users:"}})
+	if err != nil {
+		t.Fatalf("NewTrackingCache() error = %v, want nil", err)
+	}
+	defer func() { _ = tc.Close() }()
+
+	users := cache.NewCache(client, "users:", cache.WithLocalTier(tc))
+	if err := users.Set(ctx, "1", map[string]string{"name": "alice"}, time.Minute); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	eventually(t, "the write is reported", func() bool { return tc.Stats().Invalidations == 1 })
+	var got map[string]string
+	for i := 0; i < 2; i++ {
+		if err := users.Get(ctx, "1", &got); err != nil || got["name"] != "alice" {
+			t.Fatalf("Get() = %v, %v, want alice", got, err)
+		}
+	}
+	if tc.Stats().Hits != 1 {
+		t.Errorf("Stats().Hits = %d, want the second Get served from memory", tc.Stats().Hits)
+	}
+
+	// The cache's own writes are visible immediately
+	_ = users.Set(ctx, "1", map[string]string{"name": "bob"}, time.Minute)
+	if err := users.Get(ctx, "1", &got); err != nil || got["name"] != "bob" {
+		t.Errorf("Get() after Set = %v, %v, want bob", got, err)
+	}
+	_ = users.Del(ctx, "1")
+	if err := users.Get(ctx, "1", &got); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get() after Del error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestTrackingCache_Errors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := NewTrackingCache(ctx, nil, TrackingOptions{}); err == nil {
+		t.Error("NewTrackingCache() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	mock.SetShouldFail(true)
+	if _, err := NewTrackingCache(ctx, client, TrackingOptions{}); err == nil {
+		t.Error("NewTrackingCache() with failing Redis error = nil, want error")
+	}
+}
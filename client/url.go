@@ -0,0 +1,61 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ParseURL returns DefaultConfig updated with the settings of a Redis URL
+// It accepts redis://[user:password@]host[:port][/db], rediss:// for TLS, and
+// unix://[user:password@]/path/to/redis.sock for a Unix domain socket, whose database is
+// given as ?db=N. Query parameters supported by go-redis, such as dial_timeout=3s,
+// pool_size=20, or client_name=api, override the defaults
+func ParseURL(redisURL string) (Config, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Network = opts.Network
+	cfg.Addr = opts.Addr
+	cfg.Username = opts.Username
+	cfg.Password = opts.Password
+	cfg.DB = opts.DB
+	cfg.ClientName = opts.ClientName
+	cfg.TLSConfig = opts.TLSConfig
+
+	// Parameters absent from the URL are zero and keep their defaults
+	if opts.Protocol != 0 {
+		cfg.Protocol = opts.Protocol
+	}
+	if opts.PoolSize != 0 {
+		cfg.PoolSize = opts.PoolSize
+	}
+	if opts.MinIdleConns != 0 {
+		cfg.MinIdleConns = opts.MinIdleConns
+	}
+	if opts.DialTimeout != 0 {
+		cfg.DialTimeout = opts.DialTimeout
+	}
+	if opts.ReadTimeout != 0 {
+		cfg.ReadTimeout = opts.ReadTimeout
+	}
+	if opts.WriteTimeout != 0 {
+		cfg.WriteTimeout = opts.WriteTimeout
+	}
+	if opts.PoolTimeout != 0 {
+		cfg.PoolTimeout = opts.PoolTimeout
+	}
+	if opts.MaxRetries != 0 {
+		cfg.MaxRetries = opts.MaxRetries
+	}
+	if opts.MinRetryBackoff != 0 {
+		cfg.MinRetryBackoff = opts.MinRetryBackoff
+	}
+	if opts.MaxRetryBackoff != 0 {
+		cfg.MaxRetryBackoff = opts.MaxRetryBackoff
+	}
+	return cfg, nil
+}
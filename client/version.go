@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUnsupportedServer is returned when the server is older than the required version, or
+// does not report its version the way Redis does
+var ErrUnsupportedServer = errors.New("unsupported redis server")
+
+// serverVersion is a major.minor.patch Redis version
+type serverVersion [3]int
+
+// parseServerVersion parses versions such as "6", "6.2", or "7.2.4", ignoring a suffix after
+// '-' (e.g. "7.4.0-rc1")
+func parseServerVersion(s string) (serverVersion, error) {
+	var v serverVersion
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "-")
+	parts := strings.Split(s, ".")
+	if s == "" || len(parts) > len(v) {
+		return v, fmt.Errorf("invalid redis version: %q", s)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid redis version: %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// less reports whether v is older than other
+func (v serverVersion) less(other serverVersion) bool {
+	for i := range v {
+		if v[i] != other[i] {
+			return v[i] < other[i]
+		}
+	}
+	return false
+}
+
+// CheckServerVersion reads INFO server and fails with ErrUnsupportedServer if Redis is older
+// than minVersion (e.g. "6.0"), or if the server does not support INFO or report
+// redis_version, as limited emulators do
+// NewClient runs it when Config.MinServerVersion is set; call it directly for lazily
+// connected clients
+func CheckServerVersion(ctx context.Context, client redis.UniversalClient, minVersion string) error {
+	if client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	required, err := parseServerVersion(minVersion)
+	if err != nil {
+		return err
+	}
+
+	raw, err := client.Info(ctx, "server").Result()
+	if isServerReply(err) {
+		return fmt.Errorf("%w: INFO is not supported (%v); the server may be a limited Redis emulator", ErrUnsupportedServer, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read redis info: %w", err)
+	}
+
+	reported, ok := parseInfo(raw)["redis_version"]
+	if !ok {
+		return fmt.Errorf("%w: INFO reports no redis_version; the server may be a limited Redis emulator", ErrUnsupportedServer)
+	}
+	version, err := parseServerVersion(reported)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedServer, err)
+	}
+	if version.less(required) {
+		return fmt.Errorf("%w: redis %s is older than the required %s", ErrUnsupportedServer, reported, minVersion)
+	}
+	return nil
+}
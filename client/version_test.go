@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+// infoRewriter replaces the arguments of INFO commands, to imitate servers the mock is not
+type infoRewriter []interface{}
+
+func rewriteHook(args ...interface{}) redis.Hook {
+	return infoRewriter(args)
+}
+
+func (h infoRewriter) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h infoRewriter) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "info" {
+			copy(cmd.Args(), h)
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h infoRewriter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestParseServerVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want serverVersion
+		ok   bool
+	}{
+		{"6", serverVersion{6, 0, 0}, true},
+		{"6.2", serverVersion{6, 2, 0}, true},
+		{" 7.2.4 ", serverVersion{7, 2, 4}, true},
+		{"7.4.0-rc1", serverVersion{7, 4, 0}, true},
+		{"", serverVersion{}, false},
+		{"6.x", serverVersion{}, false},
+		{"1.2.3.4", serverVersion{}, false},
+		{"-1", serverVersion{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseServerVersion(tt.in)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+			t.Errorf("parseServerVersion(%q) = %v, %v, want %v, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+
+	if !(serverVersion{5, 0, 7}).less(serverVersion{6, 0, 0}) || (serverVersion{6, 2, 0}).less(serverVersion{6, 0, 9}) ||
+		(serverVersion{6, 0, 0}).less(serverVersion{6, 0, 0}) {
+		t.Error("serverVersion.less() ordered versions wrongly")
+	}
+}
+
+func TestCheckServerVersion(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	// The mock reports 7.2.0
+	for _, minVersion := range []string{"6.0", "7.2", "7.2.0"} {
+		if err := CheckServerVersion(ctx, client, minVersion); err != nil {
+			t.Errorf("CheckServerVersion(%s) error = %v, want nil", minVersion, err)
+		}
+	}
+	err := CheckServerVersion(ctx, client, "7.4")
+	if !errors.Is(err, ErrUnsupportedServer) || !strings.Contains(err.Error(), "7.2.0") {
+		t.Errorf("CheckServerVersion(7.4) error = %v, want ErrUnsupportedServer naming 7.2.0", err)
+	}
+
+	mock.SetInfo("server", "redis_version", "not-a-version")
+	if err := CheckServerVersion(ctx, client, "6.0"); !errors.Is(err, ErrUnsupportedServer) {
+		t.Errorf("CheckServerVersion() with a bad version error = %v, want ErrUnsupportedServer", err)
+	}
+	mock.SetInfo("server", "redis_version", "")
+
+	if err := CheckServerVersion(ctx, client, "six"); err == nil || errors.Is(err, ErrUnsupportedServer) {
+		t.Errorf("CheckServerVersion(six) error = %v, want an invalid version error", err)
+	}
+	if err := CheckServerVersion(ctx, nil, "6.0"); err == nil {
+		t.Error("CheckServerVersion() with nil client error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if err := CheckServerVersion(ctx, client, "6.0"); err == nil {
+		t.Error("CheckServerVersion() with failing Redis error = nil, want error")
+	}
+}
+
+func TestCheckServerVersion_Emulator(t *testing.T) {
+	ctx := context.Background()
+
+	// An emulator without INFO
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	client.AddHook(rewriteHook("nosuchinfo"))
+	err := CheckServerVersion(ctx, client, "6.0")
+	if !errors.Is(err, ErrUnsupportedServer) || !strings.Contains(err.Error(), "emulator") {
+		t.Errorf("CheckServerVersion() without INFO error = %v, want ErrUnsupportedServer about an emulator", err)
+	}
+
+	// An emulator whose INFO has no redis_version
+	other, _ := testutil.NewMockRedisClient()
+	defer func() { _ = other.Close() }()
+	_ = other.Set(ctx, "fake-info", "# Server\r\nemulator_version:1.0\r\n", 0).Err()
+	other.AddHook(rewriteHook("get", "fake-info"))
+	err = CheckServerVersion(ctx, other, "6.0")
+	if !errors.Is(err, ErrUnsupportedServer) || !strings.Contains(err.Error(), "redis_version") {
+		t.Errorf("CheckServerVersion() without redis_version error = %v, want ErrUnsupportedServer", err)
+	}
+}
+
+func TestNewClient_MinServerVersion(t *testing.T) {
+	mock := testutil.NewMockRedis()
+	cfg := DefaultConfig().WithAddr("mock").WithDialTimeout(time.Second).WithMinServerVersion("6.0")
+	cfg.Dialer = mock.Dialer()
+
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v, want nil", err)
+	}
+	_ = client.Close()
+
+	mock.SetInfo("server", "redis_version", "5.0.7")
+	if _, err := NewClient(cfg); !errors.Is(err, ErrUnsupportedServer) {
+		t.Errorf("NewClient() with Redis 5 error = %v, want ErrUnsupportedServer", err)
+	}
+
+	// Lazy clients skip the check but still validate the setting
+	client, err = NewClient(cfg.WithLazyConnect(true))
+	if err != nil {
+		t.Errorf("NewClient() lazy error = %v, want nil", err)
+	} else {
+		_ = client.Close()
+	}
+	if _, err := NewClient(cfg.WithLazyConnect(true).WithMinServerVersion("latest")); err == nil {
+		t.Error("NewClient() with an invalid MinServerVersion error = nil, want error")
+	}
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// Warmup opens n pool connections ahead of traffic, so the first burst after a deploy does not
+// wait on dials. It pings over n pinned connections at once and then returns them to the pool
+// idle. n is capped by the pool size and by MaxIdleConns, as further connections would be
+// closed on release. Cluster and ring clients warm up n connections to every shard
+func Warmup(ctx context.Context, client redis.UniversalClient, n int) error {
+	client = utils.NormalizeClient(client)
+	if client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	switch c := client.(type) {
+	case *redis.Client:
+		return warmupClient(ctx, c, n)
+	case *redis.ClusterClient:
+		return c.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+			return warmupClient(ctx, shard, n)
+		})
+	case *redis.Ring:
+		return c.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+			return warmupClient(ctx, shard, n)
+		})
+	}
+
+	// Other clients expose no pool; concurrent pings still make it grow
+	return warmup(n, func(int) error { return client.Ping(ctx).Err() })
+}
+
+// warmupClient pins up to n connections of client's pool, pinging each
+func warmupClient(ctx context.Context, client *redis.Client, n int) error {
+	opts := client.Options()
+	n = min(n, opts.PoolSize)
+	if opts.MaxIdleConns > 0 {
+		n = min(n, opts.MaxIdleConns)
+	}
+	if opts.MaxActiveConns > 0 {
+		n = min(n, opts.MaxActiveConns)
+	}
+
+	// A first ping settles the handshake options go-redis updates on the shared Options, which
+	// connections set up concurrently would race on
+	if err := client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to warm up redis connections: %w", err)
+	}
+
+	conns := make([]*redis.Conn, n)
+	for i := range conns {
+		conns[i] = client.Conn()
+	}
+	// Each Conn holds its connection until closed, so the pings cannot share one
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+
+	return warmup(n, func(i int) error { return conns[i].Ping(ctx).Err() })
+}
+
+// warmup runs ping(0) to ping(n-1) concurrently and returns the first error
+func warmup(n int, ping func(i int) error) error {
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) { errs <- ping(i) }(i)
+	}
+	var first error
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	if first != nil {
+		return fmt.Errorf("failed to warm up redis connections: %w", first)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+// countingDialer dials addr, failing once more than limit connections were opened
+func countingDialer(addr string, limit int32, dials *atomic.Int32) Dialer {
+	return func(ctx context.Context, network, _ string) (net.Conn, error) {
+		if dials.Add(1) > limit {
+			return nil, errors.New("dial limit reached")
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+func TestNewClient_Warmup(t *testing.T) {
+	addr, _ := listenMock(t)
+	tests := []struct {
+		name     string
+		poolSize int
+		warmup   int
+		want     uint32
+	}{
+		{"warm up", 10, 5, 5},
+		{"capped by pool size", 3, 10, 3},
+		{"none", 10, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dials atomic.Int32
+			cfg := DefaultConfig().WithAddr(addr).WithMinIdleConns(0).WithPoolSize(tt.poolSize).WithWarmup(tt.warmup)
+			cfg.Dialer = countingDialer(addr, 100, &dials)
+			client, err := NewClient(cfg)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v, want nil", err)
+			}
+			defer func() { _ = client.Close() }()
+
+			stats := client.PoolStats()
+			if stats.TotalConns != tt.want || stats.IdleConns != tt.want {
+				t.Errorf("after warm-up %d total, %d idle connections, want %d", stats.TotalConns, stats.IdleConns, tt.want)
+			}
+			// Traffic reuses the warm connections
+			before := dials.Load()
+			if err := client.Set(context.Background(), "k", "v", 0).Err(); err != nil {
+				t.Fatalf("Set() error = %v, want nil", err)
+			}
+			if dials.Load() != before {
+				t.Error("Set() dialed a new connection")
+			}
+		})
+	}
+}
+
+func TestNewClient_WarmupError(t *testing.T) {
+	addr, _ := listenMock(t)
+	var dials atomic.Int32
+	cfg := DefaultConfig().WithAddr(addr).WithMinIdleConns(0).WithMaxRetries(-1).WithDialTimeout(time.Second).WithWarmup(4)
+	cfg.Dialer = countingDialer(addr, 2, &dials)
+	if _, err := NewClient(cfg); err == nil {
+		t.Error("NewClient() error = nil when warm-up cannot dial, want error")
+	}
+
+	// Lazy clients skip the warm-up
+	dials.Store(0)
+	client, err := NewClient(cfg.WithLazyConnect(true))
+	if err != nil {
+		t.Fatalf("NewClient() lazy error = %v, want nil", err)
+	}
+	defer func() { _ = client.Close() }()
+	if n := dials.Load(); n != 0 {
+		t.Errorf("lazy NewClient() dialed %d connections, want 0", n)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	ctx := context.Background()
+	if err := Warmup(ctx, nil, 3); err == nil {
+		t.Error("Warmup(nil) error = nil, want error")
+	}
+
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	if err := Warmup(ctx, client, 0); err != nil {
+		t.Errorf("Warmup(0) error = %v, want nil", err)
+	}
+	if err := Warmup(ctx, client, 4); err != nil {
+		t.Fatalf("Warmup() error = %v, want nil", err)
+	}
+	if idle := client.PoolStats().IdleConns; idle < 4 {
+		t.Errorf("Warmup() left %d idle connections, want 4", idle)
+	}
+}
@@ -0,0 +1,235 @@
+// Package configstore provides versioned config documents in Redis that processes can
+// watch for runtime reconfiguration
+// Every write bumps the document version and publishes the key on a change channel;
+// watchers also poll, so a missed notification only delays an update
+package configstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for document keys and the change channel
+	DefaultKeyPrefix = "config:"
+
+	// DefaultPollInterval is how often Watch re-reads a document by default
+	DefaultPollInterval = 30 * time.Second
+)
+
+const setScript = `
+-- redis-kit:config-set
+local current = tonumber(redis.call("hget", KEYS[1], "v") or "0")
+if ARGV[1] ~= "" and tonumber(ARGV[1]) ~= current then
+	return -1
+end
+local version = current + 1
+redis.call("hset", KEYS[1], "v", version, "d", ARGV[2], "t", ARGV[3])
+redis.call("publish", ARGV[4], ARGV[5])
+return version
+`
+
+// Document is a stored config document
+type Document struct {
+	// Key is the document key, without the store prefix
+	Key string
+
+	// Version starts at 1 and increases with every write
+	Version int64
+
+	// Data is the encoded document
+	Data []byte
+
+	// UpdatedAt is when the document was last written
+	UpdatedAt time.Time
+
+	codec codec.Codec
+}
+
+// Decode unmarshals the document into v using the store's codec
+func (d *Document) Decode(v interface{}) error {
+	if err := d.codec.Unmarshal(d.Data, v); err != nil {
+		return fmt.Errorf("failed to decode config: %w", err)
+	}
+	return nil
+}
+
+// Store reads, writes, and watches config documents
+type Store struct {
+	client       *redis.Client
+	keyPrefix    string
+	codec        codec.Codec
+	pollInterval time.Duration
+	onError      func(error)
+}
+
+// NewStore creates a config store
+func NewStore(client *redis.Client, opts ...Option) *Store {
+	s := &Store{
+		client:       client,
+		keyPrefix:    DefaultKeyPrefix,
+		codec:        codec.Default(),
+		pollInterval: DefaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Key returns the Redis key of the document key
+func (s *Store) Key(key string) string {
+	return s.keyPrefix + key
+}
+
+// Channel returns the pub/sub channel carrying the keys of changed documents
+func (s *Store) Channel() string {
+	return s.keyPrefix + "changes"
+}
+
+// Set stores value under key and returns the new version
+func (s *Store) Set(ctx context.Context, key string, value interface{}) (int64, error) {
+	return s.set(ctx, key, value, "")
+}
+
+// CompareAndSet stores value only if the document is still at version, where 0 means it
+// must not exist yet, and returns the new version
+// Returns ErrVersionConflict if another write got there first
+func (s *Store) CompareAndSet(ctx context.Context, key string, value interface{}, version int64) (int64, error) {
+	return s.set(ctx, key, value, strconv.FormatInt(version, 10))
+}
+
+func (s *Store) set(ctx context.Context, key string, value interface{}, expected string) (int64, error) {
+	if s.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode config: %w", err)
+	}
+	updatedAt := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	version, err := s.client.Eval(ctx, setScript, []string{s.Key(key)}, expected, data, updatedAt, s.Channel(), key).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to set config: %w", err)
+	}
+	if version < 0 {
+		return 0, ErrVersionConflict
+	}
+	return version, nil
+}
+
+// Get returns the document stored under key
+// Returns ErrNotFound if it does not exist
+func (s *Store) Get(ctx context.Context, key string) (*Document, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	fields, err := s.client.HGetAll(ctx, s.Key(key)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, ErrNotFound
+	}
+	version, err := strconv.ParseInt(fields["v"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config version: %w", err)
+	}
+	updatedAt, _ := strconv.ParseInt(fields["t"], 10, 64)
+	return &Document{
+		Key:       key,
+		Version:   version,
+		Data:      []byte(fields["d"]),
+		UpdatedAt: time.UnixMilli(updatedAt),
+		codec:     s.codec,
+	}, nil
+}
+
+// Load decodes the document stored under key into v and returns its version
+// Returns ErrNotFound if it does not exist
+func (s *Store) Load(ctx context.Context, key string, v interface{}) (int64, error) {
+	doc, err := s.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if err := doc.Decode(v); err != nil {
+		return 0, err
+	}
+	return doc.Version, nil
+}
+
+// Delete removes the document stored under key and notifies watchers
+func (s *Store) Delete(ctx context.Context, key string) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	pipe := s.client.Pipeline()
+	pipe.Del(ctx, s.Key(key))
+	pipe.Publish(ctx, s.Channel(), key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete config: %w", err)
+	}
+	return nil
+}
+
+// Watch calls fn with the document stored under key when Watch starts and after every
+// change, until ctx is cancelled; fn receives nil while the document does not exist
+// Changes arrive over pub/sub, and the document is also re-read every poll interval so
+// updates still get through while the subscription is down. fn runs on the Watch
+// goroutine and only sees versions it has not seen before. Watch returns nil on cancellation
+func (s *Store) Watch(ctx context.Context, key string, fn func(*Document)) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	// A document deleted and recreated restarts at version 1, so the write time is compared too
+	seenVersion, seenAt := int64(-1), time.Time{}
+	check := func() {
+		doc, err := s.Get(ctx, key)
+		version, updatedAt := int64(0), time.Time{}
+		switch {
+		case errors.Is(err, ErrNotFound):
+			doc = nil
+		case err != nil:
+			s.reportError(err)
+			return
+		default:
+			version, updatedAt = doc.Version, doc.UpdatedAt
+		}
+		if version != seenVersion || !updatedAt.Equal(seenAt) {
+			seenVersion, seenAt = version, updatedAt
+			fn(doc)
+		}
+	}
+
+	// The document is read once subscribed, so changes made after the read are not missed
+	return utils.WatchChannel(ctx, s.client, s.Channel(), utils.ChannelHandler{
+		OnSubscribe: check,
+		OnMessage: func(changed string) {
+			if changed == key {
+				check()
+			}
+		},
+		OnError: func(err error) {
+			s.reportError(fmt.Errorf("config subscription failed: %w", err))
+		},
+		OnPoll:       check,
+		PollInterval: s.pollInterval,
+	})
+}
+
+func (s *Store) reportError(err error) {
+	if s.onError != nil && err != nil && !errors.Is(err, context.Canceled) {
+		s.onError(err)
+	}
+}
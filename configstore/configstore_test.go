@@ -0,0 +1,212 @@
+package configstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+type limits struct {
+	Requests int    `json:"requests"`
+	Window   string `json:"window"`
+}
+
+func TestStore_SetGet(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client)
+	if s.Key("limits") != "config:limits" || s.Channel() != "config:changes" {
+		t.Errorf("Key()/Channel() = %q/%q", s.Key("limits"), s.Channel())
+	}
+
+	if _, err := s.Get(ctx, "limits"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() missing error = %v, want ErrNotFound", err)
+	}
+
+	v, err := s.Set(ctx, "limits", limits{Requests: 100, Window: "1m"})
+	if err != nil || v != 1 {
+		t.Fatalf("Set() = %d, %v, want 1", v, err)
+	}
+	if v, _ = s.Set(ctx, "limits", limits{Requests: 200, Window: "1m"}); v != 2 {
+		t.Errorf("Set() again = %d, want 2", v)
+	}
+
+	doc, err := s.Get(ctx, "limits")
+	if err != nil || doc.Key != "limits" || doc.Version != 2 || doc.UpdatedAt.IsZero() {
+		t.Fatalf("Get() = %+v, %v, want version 2", doc, err)
+	}
+	var got limits
+	if err := doc.Decode(&got); err != nil || got.Requests != 200 {
+		t.Errorf("Decode() = %+v, %v, want 200 requests", got, err)
+	}
+
+	got = limits{}
+	if v, err := s.Load(ctx, "limits", &got); err != nil || v != 2 || got.Window != "1m" {
+		t.Errorf("Load() = %d, %+v, %v, want version 2", v, got, err)
+	}
+	if _, err := s.Load(ctx, "missing", &got); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load() missing error = %v, want ErrNotFound", err)
+	}
+
+	if err := s.Delete(ctx, "limits"); err != nil {
+		t.Fatalf("Delete() error = %v, want nil", err)
+	}
+	if _, err := s.Get(ctx, "limits"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStore_CompareAndSet(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client, WithKeyPrefix("app:"), WithCodec(codec.Msgpack))
+
+	if v, err := s.CompareAndSet(ctx, "limits", limits{Requests: 1}, 0); err != nil || v != 1 {
+		t.Fatalf("CompareAndSet() create = %d, %v, want 1", v, err)
+	}
+	if _, err := s.CompareAndSet(ctx, "limits", limits{Requests: 2}, 0); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("CompareAndSet() existing error = %v, want ErrVersionConflict", err)
+	}
+	if v, err := s.CompareAndSet(ctx, "limits", limits{Requests: 3}, 1); err != nil || v != 2 {
+		t.Errorf("CompareAndSet() current version = %d, %v, want 2", v, err)
+	}
+
+	var got limits
+	if _, err := s.Load(ctx, "limits", &got); err != nil || got.Requests != 3 {
+		t.Errorf("Load() = %+v, %v, want 3 requests", got, err)
+	}
+}
+
+type recorder struct {
+	mu   sync.Mutex
+	docs []*Document
+}
+
+func (r *recorder) add(doc *Document) {
+	r.mu.Lock()
+	r.docs = append(r.docs, doc)
+	r.mu.Unlock()
+}
+
+func (r *recorder) wait(t *testing.T, n int) []*Document {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		docs := append([]*Document(nil), r.docs...)
+		r.mu.Unlock()
+		if len(docs) >= n {
+			return docs
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d updates", n)
+	return nil
+}
+
+func TestStore_Watch(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewStore(client)
+	_, _ = s.Set(ctx, "limits", limits{Requests: 1})
+
+	rec := &recorder{}
+	done := make(chan error, 1)
+	go func() { done <- s.Watch(ctx, "limits", rec.add) }()
+
+	if docs := rec.wait(t, 1); docs[0] == nil || docs[0].Version != 1 {
+		t.Fatalf("first update = %+v, want version 1", docs[0])
+	}
+
+	_, _ = s.Set(ctx, "other", limits{})
+	_, _ = s.Set(ctx, "limits", limits{Requests: 2})
+	docs := rec.wait(t, 2)
+	var got limits
+	if err := docs[1].Decode(&got); err != nil || got.Requests != 2 {
+		t.Errorf("second update = %+v, %v, want 2 requests", got, err)
+	}
+
+	_ = s.Delete(ctx, "limits")
+	if docs := rec.wait(t, 3); docs[2] != nil {
+		t.Errorf("update after Delete() = %+v, want nil", docs[2])
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Watch() error = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch() did not return after cancellation")
+	}
+	if docs := rec.wait(t, 3); len(docs) != 3 {
+		t.Errorf("updates = %d, want 3 (other keys ignored)", len(docs))
+	}
+}
+
+func TestStore_WatchPolling(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewStore(client, WithPollInterval(20*time.Millisecond))
+
+	rec := &recorder{}
+	go func() { _ = s.Watch(ctx, "limits", rec.add) }()
+	if docs := rec.wait(t, 1); docs[0] != nil {
+		t.Fatalf("first update = %+v, want nil for a missing document", docs[0])
+	}
+
+	// Write behind the store's back, as if the notification had been lost
+	_ = client.HSet(ctx, s.Key("limits"), "v", 7, "d", `{"requests":5}`, "t", time.Now().UnixMilli()).Err()
+	if docs := rec.wait(t, 2); docs[1] == nil || docs[1].Version != 7 {
+		t.Errorf("polled update = %+v, want version 7", docs[1])
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore(nil)
+	if _, err := s.Set(ctx, "k", 1); err == nil {
+		t.Error("Set() with nil client error = nil, want error")
+	}
+	if _, err := s.Get(ctx, "k"); err == nil {
+		t.Error("Get() with nil client error = nil, want error")
+	}
+	if err := s.Delete(ctx, "k"); err == nil {
+		t.Error("Delete() with nil client error = nil, want error")
+	}
+	if err := s.Watch(ctx, "k", func(*Document) {}); err == nil {
+		t.Error("Watch() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	s = NewStore(client)
+	if _, err := s.Set(ctx, "k", make(chan int)); err == nil {
+		t.Error("Set() with an unencodable value error = nil, want error")
+	}
+	mock.SetShouldFail(true)
+	if _, err := s.Set(ctx, "k", 1); err == nil {
+		t.Error("Set() with failing redis error = nil, want error")
+	}
+	if _, err := s.Get(ctx, "k"); err == nil {
+		t.Error("Get() with failing redis error = nil, want error")
+	}
+	if err := s.Delete(ctx, "k"); err == nil {
+		t.Error("Delete() with failing redis error = nil, want error")
+	}
+}
@@ -0,0 +1,11 @@
+package configstore
+
+import "errors"
+
+var (
+	// ErrNotFound indicates the config document does not exist.
+	ErrNotFound = errors.New("config not found")
+
+	// ErrVersionConflict indicates the document changed since the expected version was read.
+	ErrVersionConflict = errors.New("config version conflict")
+)
@@ -0,0 +1,43 @@
+package configstore
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithKeyPrefix sets the prefix of document keys and the change channel (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(s *Store) {
+		s.keyPrefix = prefix
+	}
+}
+
+// WithCodec sets the codec used for documents (default: codec.Default())
+func WithCodec(c codec.Codec) Option {
+	return func(s *Store) {
+		if c != nil {
+			s.codec = c
+		}
+	}
+}
+
+// WithPollInterval sets how often Watch re-reads a document in case a change
+// notification was missed (default: DefaultPollInterval)
+func WithPollInterval(interval time.Duration) Option {
+	return func(s *Store) {
+		if interval > 0 {
+			s.pollInterval = interval
+		}
+	}
+}
+
+// WithOnError sets a callback for errors in Watch, which keeps running after them
+func WithOnError(fn func(error)) Option {
+	return func(s *Store) {
+		s.onError = fn
+	}
+}
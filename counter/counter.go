@@ -0,0 +1,199 @@
+// Package counter provides sharded counters that spread writes over several keys
+package counter
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for counter keys
+	DefaultKeyPrefix = "counter:"
+
+	// DefaultShards is the default number of shard keys per counter
+	DefaultShards = 16
+)
+
+const flushScript = `
+-- redis-kit:counter-flush
+local v = tonumber(redis.call("get", KEYS[1]) or "0")
+if v ~= 0 then
+	redis.call("decrby", KEYS[1], v)
+	redis.call("incrby", KEYS[2], v)
+	if tonumber(ARGV[1]) > 0 then
+		redis.call("pexpire", KEYS[2], ARGV[1])
+	end
+end
+return v
+`
+
+// Counter is a distributed counter whose writes go to one of several shard keys
+// Reads sum the shards and the consolidated total, so a single hot key is avoided
+type Counter struct {
+	client    *redis.Client
+	name      string
+	keyPrefix string
+	shards    int
+	ttl       time.Duration
+	onError   func(error)
+}
+
+// NewCounter creates a sharded counter with the given name
+func NewCounter(client *redis.Client, name string, opts ...Option) *Counter {
+	c := &Counter{
+		client:    client,
+		name:      name,
+		keyPrefix: DefaultKeyPrefix,
+		shards:    DefaultShards,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Shards returns the number of shard keys
+func (c *Counter) Shards() int {
+	return c.shards
+}
+
+// TotalKey returns the key holding the consolidated total
+func (c *Counter) TotalKey() string {
+	return c.keyPrefix + c.name
+}
+
+// ShardKey returns the key of shard i
+func (c *Counter) ShardKey(i int) string {
+	return c.keyPrefix + c.name + ":shard:" + strconv.Itoa(i)
+}
+
+// Incr adds one to the counter
+func (c *Counter) Incr(ctx context.Context) error {
+	return c.Add(ctx, 1)
+}
+
+// Add adds delta (which may be negative) to a randomly chosen shard
+func (c *Counter) Add(ctx context.Context, delta int64) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	key := c.ShardKey(rand.IntN(c.shards))
+	var err error
+	if c.ttl > 0 {
+		pipe := c.client.Pipeline()
+		pipe.IncrBy(ctx, key, delta)
+		pipe.PExpire(ctx, key, c.ttl)
+		_, err = pipe.Exec(ctx)
+	} else {
+		err = c.client.IncrBy(ctx, key, delta).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add to counter: %w", err)
+	}
+	return nil
+}
+
+// Get returns the current value: the consolidated total plus all shards
+// They are read with one MGET, atomically, so a concurrent Flush is seen either before or
+// after it moves a shard, never halfway
+func (c *Counter) Get(ctx context.Context) (int64, error) {
+	if c.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	values, err := c.client.MGet(ctx, c.keys()...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get counter: %w", err)
+	}
+
+	var total int64
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse counter value: %w", err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Reset deletes the total and all shard keys
+func (c *Counter) Reset(ctx context.Context) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := c.client.Del(ctx, c.keys()...).Err(); err != nil {
+		return fmt.Errorf("failed to reset counter: %w", err)
+	}
+	return nil
+}
+
+// keys returns the total key followed by the shard keys
+func (c *Counter) keys() []string {
+	keys := make([]string, 0, c.shards+1)
+	keys = append(keys, c.TotalKey())
+	for i := 0; i < c.shards; i++ {
+		keys = append(keys, c.ShardKey(i))
+	}
+	return keys
+}
+
+// Flush moves the shard values into the consolidated total and returns the amount moved
+// Each shard is moved atomically, so concurrent writes and reads never lose or double count
+func (c *Counter) Flush(ctx context.Context) (int64, error) {
+	if c.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.Cmd, c.shards)
+	for i := 0; i < c.shards; i++ {
+		cmds[i] = pipe.Eval(ctx, flushScript, []string{c.ShardKey(i), c.TotalKey()}, c.ttl.Milliseconds())
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to flush counter: %w", err)
+	}
+
+	var moved int64
+	for _, cmd := range cmds {
+		n, _ := cmd.Int64()
+		moved += n
+	}
+	return moved, nil
+}
+
+// RunFlusher calls Flush every interval until ctx is cancelled
+// Flush errors are passed to the WithOnError callback. RunFlusher returns nil on cancellation
+func (c *Counter) RunFlusher(ctx context.Context, interval time.Duration) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("flush interval must be positive")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if _, err := c.Flush(ctx); err != nil && c.onError != nil && ctx.Err() == nil {
+			c.onError(err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package counter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestNewCounter(t *testing.T) {
+	c := NewCounter(nil, "views")
+	if c.Shards() != DefaultShards {
+		t.Errorf("NewCounter() shards = %d, want %d", c.Shards(), DefaultShards)
+	}
+	if c.TotalKey() != "counter:views" {
+		t.Errorf("TotalKey() = %q, want %q", c.TotalKey(), "counter:views")
+	}
+	if c.ShardKey(3) != "counter:views:shard:3" {
+		t.Errorf("ShardKey(3) = %q, want %q", c.ShardKey(3), "counter:views:shard:3")
+	}
+
+	c = NewCounter(nil, "views", WithShards(4), WithKeyPrefix("c:"), WithTTL(time.Hour), WithShards(0))
+	if c.Shards() != 4 || c.TotalKey() != "c:views" || c.ttl != time.Hour {
+		t.Errorf("NewCounter() with options = %d/%q/%v, want 4/c:views/1h", c.Shards(), c.TotalKey(), c.ttl)
+	}
+}
+
+func TestCounter_AddGetFlush(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	c := NewCounter(client, "views", WithShards(4))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Incr(ctx); err != nil {
+				t.Errorf("Incr() error = %v, want nil", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := c.Add(ctx, -10); err != nil {
+		t.Fatalf("Add() error = %v, want nil", err)
+	}
+
+	if n, err := c.Get(ctx); err != nil || n != 40 {
+		t.Errorf("Get() = %d, %v, want 40", n, err)
+	}
+
+	moved, err := c.Flush(ctx)
+	if err != nil || moved != 40 {
+		t.Errorf("Flush() = %d, %v, want 40", moved, err)
+	}
+	if v, _ := client.Get(ctx, c.TotalKey()).Int64(); v != 40 {
+		t.Errorf("total key = %d, want 40", v)
+	}
+	if n, _ := c.Get(ctx); n != 40 {
+		t.Errorf("Get() after Flush = %d, want 40", n)
+	}
+
+	_ = c.Add(ctx, 2)
+	if n, _ := c.Get(ctx); n != 42 {
+		t.Errorf("Get() after Flush and Add = %d, want 42", n)
+	}
+
+	if err := c.Reset(ctx); err != nil {
+		t.Fatalf("Reset() error = %v, want nil", err)
+	}
+	if n, _ := c.Get(ctx); n != 0 {
+		t.Errorf("Get() after Reset = %d, want 0", n)
+	}
+}
+
+// commandRecorder records the names of the commands sent through a client
+type commandRecorder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (h *commandRecorder) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *commandRecorder) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.observe(cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (h *commandRecorder) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			h.observe(cmd)
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func (h *commandRecorder) observe(cmd redis.Cmder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.names = append(h.names, cmd.Name())
+}
+
+func TestCounter_GetIsAtomic(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+	c := NewCounter(client, "views", WithShards(4))
+	_ = c.Add(ctx, 3)
+	_, _ = c.Flush(ctx)
+	_ = c.Add(ctx, 2)
+
+	// The total and the shards are read by one command, so a concurrent Flush moving a shard
+	// into the total cannot be seen halfway
+	hook := &commandRecorder{}
+	client.AddHook(hook)
+	if n, err := c.Get(ctx); err != nil || n != 5 {
+		t.Errorf("Get() = %d, %v, want 5", n, err)
+	}
+	if len(hook.names) != 1 || hook.names[0] != "mget" {
+		t.Errorf("Get() sent %v, want a single MGET", hook.names)
+	}
+}
+
+func TestCounter_TTL(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	c := NewCounter(client, "daily", WithShards(1), WithTTL(time.Hour))
+	_ = c.Incr(ctx)
+	if ttl, _ := client.PTTL(ctx, c.ShardKey(0)).Result(); ttl <= 0 {
+		t.Errorf("shard PTTL = %v, want positive", ttl)
+	}
+	_, _ = c.Flush(ctx)
+	if ttl, _ := client.PTTL(ctx, c.TotalKey()).Result(); ttl <= 0 {
+		t.Errorf("total PTTL = %v, want positive", ttl)
+	}
+}
+
+func TestCounter_RunFlusher(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	var mu sync.Mutex
+	var errs []error
+	c := NewCounter(client, "views", WithShards(2), WithOnError(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Add(ctx, 5)
+
+	result := make(chan error, 1)
+	go func() { result <- c.RunFlusher(ctx, 5*time.Millisecond) }()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if v, _ := client.Get(ctx, c.TotalKey()).Int64(); v == 5 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if v, _ := client.Get(ctx, c.TotalKey()).Int64(); v != 5 {
+		t.Errorf("total after RunFlusher = %d, want 5", v)
+	}
+
+	mock.SetShouldFail(true)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-result; err != nil {
+		t.Errorf("RunFlusher() error = %v, want nil", err)
+	}
+	mock.SetShouldFail(false)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) == 0 {
+		t.Error("RunFlusher() did not report flush errors")
+	}
+
+	if err := c.RunFlusher(context.Background(), 0); err == nil {
+		t.Error("RunFlusher() with zero interval should return error")
+	}
+}
+
+func TestCounter_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("nil client", func(t *testing.T) {
+		c := NewCounter(nil, "x")
+		if err := c.Incr(ctx); err == nil {
+			t.Error("Incr() with nil client should return error")
+		}
+		if _, err := c.Get(ctx); err == nil {
+			t.Error("Get() with nil client should return error")
+		}
+		if _, err := c.Flush(ctx); err == nil {
+			t.Error("Flush() with nil client should return error")
+		}
+		if err := c.Reset(ctx); err == nil {
+			t.Error("Reset() with nil client should return error")
+		}
+	})
+
+	t.Run("redis failure", func(t *testing.T) {
+		client, mock := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+		mock.SetShouldFail(true)
+
+		c := NewCounter(client, "x", WithShards(2))
+		if err := c.Incr(ctx); err == nil {
+			t.Error("Incr() should return error when Redis fails")
+		}
+		if _, err := c.Get(ctx); err == nil {
+			t.Error("Get() should return error when Redis fails")
+		}
+	})
+
+	t.Run("corrupt shard", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		c := NewCounter(client, "x", WithShards(1))
+		_ = client.Set(ctx, c.ShardKey(0), "abc", 0).Err()
+		if _, err := c.Get(ctx); err == nil {
+			t.Error("Get() with non-integer shard should return error")
+		}
+	})
+}
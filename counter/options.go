@@ -0,0 +1,36 @@
+package counter
+
+import "time"
+
+// Option configures a Counter
+type Option func(*Counter)
+
+// WithShards sets the number of shard keys writes are spread over (default: DefaultShards)
+func WithShards(n int) Option {
+	return func(c *Counter) {
+		if n > 0 {
+			c.shards = n
+		}
+	}
+}
+
+// WithKeyPrefix sets the prefix of the counter keys (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(c *Counter) {
+		c.keyPrefix = prefix
+	}
+}
+
+// WithTTL expires the counter keys after ttl without writes (default: no expiry)
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Counter) {
+		c.ttl = ttl
+	}
+}
+
+// WithOnError sets the callback for errors in RunFlusher
+func WithOnError(fn func(error)) Option {
+	return func(c *Counter) {
+		c.onError = fn
+	}
+}
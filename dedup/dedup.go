@@ -0,0 +1,153 @@
+// Package dedup remembers fingerprints of processed items (webhook deliveries, events,
+// messages) for a while, so duplicates can be detected and skipped
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/bloomfilter"
+)
+
+// DefaultKeyPrefix is the default prefix for fingerprint keys
+const DefaultKeyPrefix = "dedup:"
+
+// Store records which fingerprints were seen
+type Store struct {
+	client    *redis.Client
+	keyPrefix string
+	filter    *bloomfilter.Filter
+}
+
+// NewStore creates a dedup store
+func NewStore(client *redis.Client, opts ...Option) *Store {
+	s := &Store{
+		client:    client,
+		keyPrefix: DefaultKeyPrefix,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Key returns the Redis key of fingerprint
+func (s *Store) Key(fingerprint string) string {
+	return s.keyPrefix + fingerprint
+}
+
+// MarkSeen records fingerprint for ttl and reports whether it was already recorded
+// Exactly one of several concurrent callers with the same fingerprint gets false
+func (s *Store) MarkSeen(ctx context.Context, fingerprint string, ttl time.Duration) (bool, error) {
+	seen, err := s.MarkSeenMulti(ctx, []string{fingerprint}, ttl)
+	if err != nil {
+		return false, err
+	}
+	return seen[0], nil
+}
+
+// MarkSeenMulti records fingerprints for ttl in one round trip and reports for each
+// whether it was already recorded
+func (s *Store) MarkSeenMulti(ctx context.Context, fingerprints []string, ttl time.Duration) ([]bool, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("ttl must be positive")
+	}
+	if len(fingerprints) == 0 {
+		return []bool{}, nil
+	}
+
+	// The filter is updated first, so it never lacks a fingerprint whose key exists
+	if s.filter != nil {
+		if _, err := s.filter.AddMulti(ctx, fingerprints...); err != nil {
+			return nil, fmt.Errorf("failed to add fingerprints to bloom filter: %w", err)
+		}
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.BoolCmd, len(fingerprints))
+	for i, fp := range fingerprints {
+		cmds[i] = pipe.SetNX(ctx, s.Key(fp), "1", ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to mark fingerprints: %w", err)
+	}
+
+	seen := make([]bool, len(fingerprints))
+	for i, cmd := range cmds {
+		seen[i] = !cmd.Val()
+	}
+	return seen, nil
+}
+
+// Seen reports whether fingerprint is recorded, without recording it
+func (s *Store) Seen(ctx context.Context, fingerprint string) (bool, error) {
+	seen, err := s.SeenMulti(ctx, []string{fingerprint})
+	if err != nil {
+		return false, err
+	}
+	return seen[0], nil
+}
+
+// SeenMulti reports for each fingerprint whether it is recorded, without recording it
+// With a bloom filter, fingerprints the filter has never seen are answered without
+// looking up their keys
+func (s *Store) SeenMulti(ctx context.Context, fingerprints []string) ([]bool, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	seen := make([]bool, len(fingerprints))
+	if len(fingerprints) == 0 {
+		return seen, nil
+	}
+
+	candidates := make([]int, 0, len(fingerprints))
+	if s.filter != nil {
+		maybe, err := s.filter.ExistsMulti(ctx, fingerprints...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check bloom filter: %w", err)
+		}
+		for i, ok := range maybe {
+			if ok {
+				candidates = append(candidates, i)
+			}
+		}
+	} else {
+		for i := range fingerprints {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return seen, nil
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.IntCmd, len(candidates))
+	for j, i := range candidates {
+		cmds[j] = pipe.Exists(ctx, s.Key(fingerprints[i]))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to check fingerprints: %w", err)
+	}
+	for j, i := range candidates {
+		seen[i] = cmds[j].Val() > 0
+	}
+	return seen, nil
+}
+
+// Forget removes fingerprint, e.g. when processing the item failed and a retry must not
+// be treated as a duplicate; a bloom filter keeps it, which only costs a key lookup later
+func (s *Store) Forget(ctx context.Context, fingerprint string) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := s.client.Del(ctx, s.Key(fingerprint)).Err(); err != nil {
+		return fmt.Errorf("failed to forget fingerprint: %w", err)
+	}
+	return nil
+}
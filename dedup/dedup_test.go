@@ -0,0 +1,179 @@
+package dedup
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/bloomfilter"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestStore_MarkSeen(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client)
+	if s.Key("evt_1") != "dedup:evt_1" {
+		t.Errorf("Key() = %q, want dedup:evt_1", s.Key("evt_1"))
+	}
+
+	if seen, err := s.MarkSeen(ctx, "evt_1", time.Hour); err != nil || seen {
+		t.Fatalf("MarkSeen() first = %v, %v, want false", seen, err)
+	}
+	if seen, err := s.MarkSeen(ctx, "evt_1", time.Hour); err != nil || !seen {
+		t.Errorf("MarkSeen() again = %v, %v, want true", seen, err)
+	}
+	if ttl, _ := client.PTTL(ctx, "dedup:evt_1").Result(); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("PTTL() = %v, want up to 1h", ttl)
+	}
+
+	if seen, err := s.Seen(ctx, "evt_1"); err != nil || !seen {
+		t.Errorf("Seen() = %v, %v, want true", seen, err)
+	}
+	if seen, err := s.Seen(ctx, "evt_2"); err != nil || seen {
+		t.Errorf("Seen() unknown = %v, %v, want false", seen, err)
+	}
+
+	if err := s.Forget(ctx, "evt_1"); err != nil {
+		t.Fatalf("Forget() error = %v, want nil", err)
+	}
+	if seen, _ := s.MarkSeen(ctx, "evt_1", time.Hour); seen {
+		t.Error("MarkSeen() after Forget() = true, want false")
+	}
+}
+
+func TestStore_Multi(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client, WithKeyPrefix("hooks:"))
+	_, _ = s.MarkSeen(ctx, "b", time.Minute)
+
+	seen, err := s.MarkSeenMulti(ctx, []string{"a", "b", "c", "a"}, time.Minute)
+	want := []bool{false, true, false, true}
+	if err != nil || len(seen) != len(want) {
+		t.Fatalf("MarkSeenMulti() = %v, %v, want %v", seen, err, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Errorf("MarkSeenMulti()[%d] = %v, want %v", i, seen[i], want[i])
+		}
+	}
+
+	seen, err = s.SeenMulti(ctx, []string{"a", "x", "c"})
+	if err != nil || !seen[0] || seen[1] || !seen[2] {
+		t.Errorf("SeenMulti() = %v, %v, want [true false true]", seen, err)
+	}
+	if seen, err := s.MarkSeenMulti(ctx, nil, time.Minute); err != nil || len(seen) != 0 {
+		t.Errorf("MarkSeenMulti() empty = %v, %v, want none", seen, err)
+	}
+	if seen, err := s.SeenMulti(ctx, nil); err != nil || len(seen) != 0 {
+		t.Errorf("SeenMulti() empty = %v, %v, want none", seen, err)
+	}
+}
+
+// existsCounter counts EXISTS commands sent by a client
+type existsCounter struct {
+	count atomic.Int64
+}
+
+func (h *existsCounter) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *existsCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.observe(cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (h *existsCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			h.observe(cmd)
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func (h *existsCounter) observe(cmd redis.Cmder) {
+	if cmd.Name() == "exists" {
+		h.count.Add(1)
+	}
+}
+
+func TestStore_BloomFilter(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	hook := &existsCounter{}
+	client.AddHook(hook)
+
+	ctx := context.Background()
+	filter := bloomfilter.NewFilter(client, "dedup", 1000, 0.001, bloomfilter.WithMode(bloomfilter.ModeBitmap))
+	s := NewStore(client, WithBloomFilter(filter))
+
+	if seen, err := s.MarkSeen(ctx, "evt_1", time.Hour); err != nil || seen {
+		t.Fatalf("MarkSeen() = %v, %v, want false", seen, err)
+	}
+	if ok, _ := filter.Exists(ctx, "evt_1"); !ok {
+		t.Error("filter.Exists() after MarkSeen() = false, want true")
+	}
+	if seen, _ := s.Seen(ctx, "evt_1"); !seen {
+		t.Error("Seen() = false, want true")
+	}
+
+	// An expired fingerprint is still in the filter but its key decides
+	_ = client.Del(ctx, s.Key("evt_1")).Err()
+	if seen, _ := s.Seen(ctx, "evt_1"); seen {
+		t.Error("Seen() after the key expired = true, want false")
+	}
+
+	// Fingerprints the filter never saw are answered without key lookups
+	before := hook.count.Load()
+	if before == 0 {
+		t.Fatal("no EXISTS calls observed for known fingerprints")
+	}
+	if seen, err := s.SeenMulti(ctx, []string{"never-1", "never-2"}); err != nil || seen[0] || seen[1] {
+		t.Errorf("SeenMulti() unknown = %v, %v, want false", seen, err)
+	}
+	if after := hook.count.Load(); after != before {
+		t.Errorf("EXISTS calls = %d, want %d", after, before)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore(nil)
+	if _, err := s.MarkSeen(ctx, "a", time.Minute); err == nil {
+		t.Error("MarkSeen() with nil client error = nil, want error")
+	}
+	if _, err := s.Seen(ctx, "a"); err == nil {
+		t.Error("Seen() with nil client error = nil, want error")
+	}
+	if err := s.Forget(ctx, "a"); err == nil {
+		t.Error("Forget() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	s = NewStore(client, WithBloomFilter(bloomfilter.NewFilter(client, "x", 100, 0.01)))
+	if _, err := s.MarkSeen(ctx, "a", 0); err == nil {
+		t.Error("MarkSeen() with zero ttl error = nil, want error")
+	}
+	mock.SetShouldFail(true)
+	if _, err := s.MarkSeen(ctx, "a", time.Minute); err == nil {
+		t.Error("MarkSeen() with failing redis error = nil, want error")
+	}
+	if _, err := s.Seen(ctx, "a"); err == nil {
+		t.Error("Seen() with failing redis error = nil, want error")
+	}
+	if err := s.Forget(ctx, "a"); err == nil {
+		t.Error("Forget() with failing redis error = nil, want error")
+	}
+}
@@ -0,0 +1,22 @@
+package dedup
+
+import "github.com/soulteary/redis-kit/bloomfilter"
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithKeyPrefix sets the prefix of fingerprint keys (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(s *Store) {
+		s.keyPrefix = prefix
+	}
+}
+
+// WithBloomFilter records fingerprints in filter as well, so Seen can answer "never seen"
+// without touching the fingerprint keys; the filter should be sized for all fingerprints
+// expected over its lifetime, since it does not forget expired ones
+func WithBloomFilter(filter *bloomfilter.Filter) Option {
+	return func(s *Store) {
+		s.filter = filter
+	}
+}
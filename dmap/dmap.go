@@ -0,0 +1,311 @@
+// Package dmap provides a typed map stored in a Redis hash
+// Keys become hash fields and values are encoded with a codec; reads can be served from
+// a local cache that is invalidated over pub/sub when any process writes the map
+package dmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// DefaultKeyPrefix is the default prefix for map hash keys and invalidation channels
+const DefaultKeyPrefix = "dmap:"
+
+// rangeCount is the COUNT hint passed to HSCAN by Range
+const rangeCount = 100
+
+// Key is the set of types usable as map keys; they are stored in their string or decimal form
+type Key interface {
+	~string | ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+type localEntry[V any] struct {
+	value   V
+	found   bool
+	expires time.Time
+}
+
+// Map is a typed view of a Redis hash shared by every process using the same name
+type Map[K Key, V any] struct {
+	client *redis.Client
+	name   string
+	settings
+
+	mu         sync.Mutex
+	local      map[string]localEntry[V]
+	generation uint64
+}
+
+// NewMap creates a handle to the map name (e.g. "user-settings")
+func NewMap[K Key, V any](client *redis.Client, name string, opts ...Option) *Map[K, V] {
+	m := &Map[K, V]{
+		client: client,
+		name:   name,
+		settings: settings{
+			keyPrefix: DefaultKeyPrefix,
+			codec:     codec.Default(),
+		},
+		local: make(map[string]localEntry[V]),
+	}
+	for _, opt := range opts {
+		opt(&m.settings)
+	}
+	return m
+}
+
+// Key returns the Redis key of the hash
+func (m *Map[K, V]) Key() string {
+	return m.keyPrefix + m.name
+}
+
+// Channel returns the pub/sub channel carrying the fields of changed entries
+func (m *Map[K, V]) Channel() string {
+	return m.keyPrefix + m.name + ":changes"
+}
+
+// Put sets the value of key and notifies watching processes
+func (m *Map[K, V]) Put(ctx context.Context, key K, value V) error {
+	if m.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	data, err := m.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode map value: %w", err)
+	}
+
+	field := formatKey(key)
+	pipe := m.client.Pipeline()
+	pipe.HSet(ctx, m.Key(), field, data)
+	if m.ttl > 0 {
+		pipe.PExpire(ctx, m.Key(), m.ttl)
+	}
+	pipe.Publish(ctx, m.Channel(), field)
+	_, err = pipe.Exec(ctx)
+	m.invalidate(field)
+	if err != nil {
+		return fmt.Errorf("failed to put map entry: %w", err)
+	}
+	return nil
+}
+
+// Get returns the value of key and whether it exists
+// With a local cache, recent results, including misses, are served without a round trip
+func (m *Map[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	var zero V
+	if m.client == nil {
+		return zero, false, fmt.Errorf("redis client is nil")
+	}
+
+	field := formatKey(key)
+	generation, cached, ok := m.cached(field)
+	if ok {
+		return cached.value, cached.found, nil
+	}
+
+	data, err := m.client.HGet(ctx, m.Key(), field).Bytes()
+	if errors.Is(err, redis.Nil) {
+		m.store(field, generation, localEntry[V]{})
+		return zero, false, nil
+	}
+	if err != nil {
+		return zero, false, fmt.Errorf("failed to get map entry: %w", err)
+	}
+
+	var value V
+	if err := m.codec.Unmarshal(data, &value); err != nil {
+		return zero, false, fmt.Errorf("failed to decode map value: %w", err)
+	}
+	m.store(field, generation, localEntry[V]{value: value, found: true})
+	return value, true, nil
+}
+
+// Delete removes key and reports whether it existed
+func (m *Map[K, V]) Delete(ctx context.Context, key K) (bool, error) {
+	if m.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+
+	field := formatKey(key)
+	pipe := m.client.Pipeline()
+	del := pipe.HDel(ctx, m.Key(), field)
+	pipe.Publish(ctx, m.Channel(), field)
+	_, err := pipe.Exec(ctx)
+	m.invalidate(field)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete map entry: %w", err)
+	}
+	return del.Val() > 0, nil
+}
+
+// Len returns the number of entries
+func (m *Map[K, V]) Len(ctx context.Context) (int64, error) {
+	if m.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	n, err := m.client.HLen(ctx, m.Key()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get map length: %w", err)
+	}
+	return n, nil
+}
+
+// Range calls fn for every entry until it returns false, reading the hash with HSCAN
+// As with HSCAN, entries changed during iteration may be skipped or seen twice
+func (m *Map[K, V]) Range(ctx context.Context, fn func(key K, value V) bool) error {
+	if m.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	var cursor uint64
+	for {
+		kvs, next, err := m.client.HScan(ctx, m.Key(), cursor, "", rangeCount).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan map: %w", err)
+		}
+		for i := 0; i+1 < len(kvs); i += 2 {
+			key, err := parseKey[K](kvs[i])
+			if err != nil {
+				return err
+			}
+			var value V
+			if err := m.codec.Unmarshal([]byte(kvs[i+1]), &value); err != nil {
+				return fmt.Errorf("failed to decode map value: %w", err)
+			}
+			if !fn(key, value) {
+				return nil
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Clear deletes the whole map and drops the local cache of this process
+// Other processes keep their cached entries until the local cache TTL passes
+func (m *Map[K, V]) Clear(ctx context.Context) error {
+	if m.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	err := m.client.Del(ctx, m.Key()).Err()
+	m.invalidateAll()
+	if err != nil {
+		return fmt.Errorf("failed to clear map: %w", err)
+	}
+	return nil
+}
+
+// Watch drops locally cached entries as other processes change them, until ctx is cancelled
+// The whole local cache is dropped whenever the subscription is (re)established, so changes
+// made while disconnected are not missed. Watch returns nil on cancellation
+func (m *Map[K, V]) Watch(ctx context.Context) error {
+	if m.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	return utils.WatchChannel(ctx, m.client, m.Channel(), utils.ChannelHandler{
+		OnSubscribe: m.invalidateAll,
+		OnMessage:   m.invalidate,
+		OnError: func(err error) {
+			m.reportError(fmt.Errorf("map subscription failed: %w", err))
+		},
+	})
+}
+
+// cached returns the local entry of field if it is fresh, along with the current generation
+func (m *Map[K, V]) cached(field string) (uint64, localEntry[V], bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.local[field]
+	if ok && time.Now().After(entry.expires) {
+		delete(m.local, field)
+		ok = false
+	}
+	return m.generation, entry, ok
+}
+
+// store caches entry unless an invalidation happened since generation was read,
+// in which case the value read from Redis may already be stale
+func (m *Map[K, V]) store(field string, generation uint64, entry localEntry[V]) {
+	if m.cacheTTL <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.generation != generation {
+		return
+	}
+	entry.expires = time.Now().Add(m.cacheTTL)
+	m.local[field] = entry
+}
+
+func (m *Map[K, V]) invalidate(field string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generation++
+	delete(m.local, field)
+}
+
+func (m *Map[K, V]) invalidateAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generation++
+	clear(m.local)
+}
+
+func (m *Map[K, V]) reportError(err error) {
+	if m.onError != nil && err != nil && !errors.Is(err, context.Canceled) {
+		m.onError(err)
+	}
+}
+
+// formatKey returns the hash field of key
+func formatKey[K Key](key K) string {
+	v := reflect.ValueOf(key)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	default:
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+}
+
+// parseKey converts a hash field back into a key
+func parseKey[K Key](field string) (K, error) {
+	var key K
+	v := reflect.ValueOf(&key).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(field)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(field, 10, v.Type().Bits())
+		if err != nil {
+			return key, fmt.Errorf("%w: %q", ErrInvalidKey, field)
+		}
+		v.SetInt(n)
+	default:
+		n, err := strconv.ParseUint(field, 10, v.Type().Bits())
+		if err != nil {
+			return key, fmt.Errorf("%w: %q", ErrInvalidKey, field)
+		}
+		v.SetUint(n)
+	}
+	return key, nil
+}
@@ -0,0 +1,218 @@
+package dmap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+type profile struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestMap_PutGetDelete(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	m := NewMap[string, profile](client, "users")
+	if m.Key() != "dmap:users" || m.Channel() != "dmap:users:changes" {
+		t.Errorf("Key()/Channel() = %q/%q", m.Key(), m.Channel())
+	}
+
+	if _, ok, err := m.Get(ctx, "alice"); err != nil || ok {
+		t.Fatalf("Get() missing = %v, %v, want false, nil", ok, err)
+	}
+	if err := m.Put(ctx, "alice", profile{Name: "Alice", Age: 30}); err != nil {
+		t.Fatalf("Put() error = %v, want nil", err)
+	}
+	got, ok, err := m.Get(ctx, "alice")
+	if err != nil || !ok || got != (profile{Name: "Alice", Age: 30}) {
+		t.Fatalf("Get() = %+v, %v, %v", got, ok, err)
+	}
+
+	if n, err := m.Len(ctx); err != nil || n != 1 {
+		t.Errorf("Len() = %d, %v, want 1", n, err)
+	}
+	if ttl := client.PTTL(ctx, m.Key()).Val(); ttl >= 0 {
+		t.Errorf("PTTL() = %v, want no expiry", ttl)
+	}
+
+	if deleted, err := m.Delete(ctx, "alice"); err != nil || !deleted {
+		t.Errorf("Delete() = %v, %v, want true, nil", deleted, err)
+	}
+	if deleted, err := m.Delete(ctx, "alice"); err != nil || deleted {
+		t.Errorf("Delete() again = %v, %v, want false, nil", deleted, err)
+	}
+	if n, _ := m.Len(ctx); n != 0 {
+		t.Errorf("Len() after Delete = %d, want 0", n)
+	}
+}
+
+func TestMap_IntKeysAndRange(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	m := NewMap[int64, string](client, "ids")
+	want := map[int64]string{}
+	for i := int64(-5); i < 250; i++ {
+		want[i] = "v"
+		if err := m.Put(ctx, i, "v"); err != nil {
+			t.Fatalf("Put() error = %v, want nil", err)
+		}
+	}
+
+	seen := map[int64]string{}
+	if err := m.Range(ctx, func(k int64, v string) bool {
+		seen[k] = v
+		return true
+	}); err != nil {
+		t.Fatalf("Range() error = %v, want nil", err)
+	}
+	if len(seen) != len(want) {
+		t.Errorf("Range() saw %d entries, want %d", len(seen), len(want))
+	}
+	if _, ok := seen[-5]; !ok {
+		t.Error("Range() missed negative key")
+	}
+
+	calls := 0
+	_ = m.Range(ctx, func(int64, string) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Range() stopping early called fn %d times, want 1", calls)
+	}
+
+	if err := m.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error = %v, want nil", err)
+	}
+	if n, _ := m.Len(ctx); n != 0 {
+		t.Errorf("Len() after Clear = %d, want 0", n)
+	}
+}
+
+func TestMap_RangeInvalidKey(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	m := NewMap[uint8, string](client, "small")
+	_ = client.HSet(ctx, m.Key(), "300", `"x"`).Err()
+
+	err := m.Range(ctx, func(uint8, string) bool { return true })
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Range() error = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestMap_TTL(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	m := NewMap[string, int](client, "sessions", WithKeyPrefix("app:"), WithTTL(time.Minute))
+	if err := m.Put(ctx, "a", 1); err != nil {
+		t.Fatalf("Put() error = %v, want nil", err)
+	}
+	if m.Key() != "app:sessions" {
+		t.Errorf("Key() = %q, want app:sessions", m.Key())
+	}
+	if ttl := client.PTTL(ctx, m.Key()).Val(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("PTTL() = %v, want (0, 1m]", ttl)
+	}
+}
+
+func TestMap_LocalCacheAndWatch(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	writer := NewMap[string, int](client, "counters")
+	reader := NewMap[string, int](client, "counters", WithLocalCache(time.Hour))
+	_ = writer.Put(ctx, "a", 1)
+
+	if v, _, _ := reader.Get(ctx, "a"); v != 1 {
+		t.Fatalf("Get() = %d, want 1", v)
+	}
+	_ = writer.Put(ctx, "a", 2)
+	if v, _, _ := reader.Get(ctx, "a"); v != 1 {
+		t.Errorf("Get() without Watch = %d, want cached 1", v)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- reader.Watch(ctx) }()
+
+	waitFor := func(want int, wantOK bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if v, ok, _ := reader.Get(ctx, "a"); v == want && ok == wantOK {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("Get() did not become %d, %v", want, wantOK)
+	}
+
+	waitFor(2, true)
+	_ = writer.Put(ctx, "a", 3)
+	waitFor(3, true)
+	_, _ = writer.Delete(ctx, "a")
+	waitFor(0, false)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch() error = %v, want nil on cancellation", err)
+	}
+}
+
+func TestMap_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	m := NewMap[string, int](nil, "x")
+	if err := m.Put(ctx, "a", 1); err == nil {
+		t.Error("Put() with nil client error = nil, want error")
+	}
+	if _, _, err := m.Get(ctx, "a"); err == nil {
+		t.Error("Get() with nil client error = nil, want error")
+	}
+	if _, err := m.Delete(ctx, "a"); err == nil {
+		t.Error("Delete() with nil client error = nil, want error")
+	}
+	if _, err := m.Len(ctx); err == nil {
+		t.Error("Len() with nil client error = nil, want error")
+	}
+	if err := m.Range(ctx, func(string, int) bool { return true }); err == nil {
+		t.Error("Range() with nil client error = nil, want error")
+	}
+	if err := m.Watch(ctx); err == nil {
+		t.Error("Watch() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	m = NewMap[string, int](client, "x")
+	_ = client.HSet(ctx, m.Key(), "bad", "not-json").Err()
+	if _, _, err := m.Get(ctx, "bad"); err == nil {
+		t.Error("Get() undecodable value error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if err := m.Put(ctx, "a", 1); err == nil {
+		t.Error("Put() error = nil, want error")
+	}
+	if _, _, err := m.Get(ctx, "a"); err == nil {
+		t.Error("Get() error = nil, want error")
+	}
+	if err := m.Clear(ctx); err == nil {
+		t.Error("Clear() error = nil, want error")
+	}
+}
@@ -0,0 +1,8 @@
+package dmap
+
+import "errors"
+
+var (
+	// ErrInvalidKey indicates a hash field could not be parsed back into the map's key type.
+	ErrInvalidKey = errors.New("invalid map key")
+)
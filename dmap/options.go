@@ -0,0 +1,61 @@
+package dmap
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// settings holds the configuration shared by maps of every key and value type
+type settings struct {
+	keyPrefix string
+	codec     codec.Codec
+	ttl       time.Duration
+	cacheTTL  time.Duration
+	onError   func(error)
+}
+
+// Option configures a Map
+type Option func(*settings)
+
+// WithKeyPrefix sets the prefix of the hash key and invalidation channel (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(s *settings) {
+		s.keyPrefix = prefix
+	}
+}
+
+// WithCodec sets the value codec (default: codec.Default())
+func WithCodec(c codec.Codec) Option {
+	return func(s *settings) {
+		if c != nil {
+			s.codec = c
+		}
+	}
+}
+
+// WithTTL makes the whole map expire ttl after its last write (default: never)
+func WithTTL(ttl time.Duration) Option {
+	return func(s *settings) {
+		if ttl >= 0 {
+			s.ttl = ttl
+		}
+	}
+}
+
+// WithLocalCache keeps values read by Get in process for up to ttl (default: disabled)
+// Run Watch to drop entries as soon as another process changes them
+func WithLocalCache(ttl time.Duration) Option {
+	return func(s *settings) {
+		if ttl >= 0 {
+			s.cacheTTL = ttl
+		}
+	}
+}
+
+// WithOnError sets a callback for errors in Watch, which keeps running after them
+func WithOnError(fn func(error)) Option {
+	return func(s *settings) {
+		s.onError = fn
+	}
+}
@@ -0,0 +1,278 @@
+// Package dset provides a typed set stored in a Redis set
+// Members are encoded with a codec, so equal values must encode to equal bytes; union,
+// intersection, and difference run on the server and store their result in another set
+package dset
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// DefaultKeyPrefix is the default prefix for set keys
+const DefaultKeyPrefix = "dset:"
+
+// rangeCount is the COUNT hint passed to SSCAN by Range
+const rangeCount = 100
+
+// Set is a typed view of a Redis set shared by every process using the same name
+type Set[T any] struct {
+	client *redis.Client
+	name   string
+	settings
+}
+
+// NewSet creates a handle to the set name (e.g. "online-users")
+func NewSet[T any](client *redis.Client, name string, opts ...Option) *Set[T] {
+	s := &Set[T]{
+		client: client,
+		name:   name,
+		settings: settings{
+			keyPrefix: DefaultKeyPrefix,
+			codec:     codec.Default(),
+		},
+	}
+	for _, opt := range opts {
+		opt(&s.settings)
+	}
+	return s
+}
+
+// Key returns the Redis key of the set
+func (s *Set[T]) Key() string {
+	return s.keyPrefix + s.name
+}
+
+// Add adds members and returns how many were not already present
+func (s *Set[T]) Add(ctx context.Context, members ...T) (int64, error) {
+	if s.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+	if len(members) == 0 {
+		return 0, nil
+	}
+
+	encoded, err := s.encode(members)
+	if err != nil {
+		return 0, err
+	}
+
+	pipe := s.client.Pipeline()
+	add := pipe.SAdd(ctx, s.Key(), encoded...)
+	if s.ttl > 0 {
+		pipe.PExpire(ctx, s.Key(), s.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to add set members: %w", err)
+	}
+	return add.Val(), nil
+}
+
+// Remove removes members and returns how many were present
+func (s *Set[T]) Remove(ctx context.Context, members ...T) (int64, error) {
+	if s.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+	if len(members) == 0 {
+		return 0, nil
+	}
+
+	encoded, err := s.encode(members)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := s.client.SRem(ctx, s.Key(), encoded...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove set members: %w", err)
+	}
+	return n, nil
+}
+
+// Contains reports whether member is in the set
+func (s *Set[T]) Contains(ctx context.Context, member T) (bool, error) {
+	if s.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+
+	data, err := s.codec.Marshal(member)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode set member: %w", err)
+	}
+
+	ok, err := s.client.SIsMember(ctx, s.Key(), data).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check set membership: %w", err)
+	}
+	return ok, nil
+}
+
+// ContainsMany reports, in order, whether each of members is in the set, in one round trip
+func (s *Set[T]) ContainsMany(ctx context.Context, members ...T) ([]bool, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if len(members) == 0 {
+		return []bool{}, nil
+	}
+
+	encoded, err := s.encode(members)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.client.SMIsMember(ctx, s.Key(), encoded...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check set membership: %w", err)
+	}
+	return found, nil
+}
+
+// Members returns every member in no particular order
+// Use Range for large sets, as SMEMBERS reads the whole set in one reply
+func (s *Set[T]) Members(ctx context.Context) ([]T, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	raw, err := s.client.SMembers(ctx, s.Key()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get set members: %w", err)
+	}
+
+	members := make([]T, 0, len(raw))
+	for _, data := range raw {
+		member, err := s.decode(data)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// Range calls fn for every member until it returns false, reading the set with SSCAN
+// As with SSCAN, members changed during iteration may be skipped or seen twice
+func (s *Set[T]) Range(ctx context.Context, fn func(member T) bool) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	var cursor uint64
+	for {
+		raw, next, err := s.client.SScan(ctx, s.Key(), cursor, "", rangeCount).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan set: %w", err)
+		}
+		for _, data := range raw {
+			member, err := s.decode(data)
+			if err != nil {
+				return err
+			}
+			if !fn(member) {
+				return nil
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Card returns the number of members
+func (s *Set[T]) Card(ctx context.Context) (int64, error) {
+	if s.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	n, err := s.client.SCard(ctx, s.Key()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get set cardinality: %w", err)
+	}
+	return n, nil
+}
+
+// Clear deletes the set
+func (s *Set[T]) Clear(ctx context.Context) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := s.client.Del(ctx, s.Key()).Err(); err != nil {
+		return fmt.Errorf("failed to clear set: %w", err)
+	}
+	return nil
+}
+
+// Union stores the union of sets in dst, replacing its members, and returns its cardinality
+// The operation runs on dst's client; on Redis Cluster every key must hash to the same slot,
+// e.g. by putting a {hash tag} in the set names
+func Union[T any](ctx context.Context, dst *Set[T], sets ...*Set[T]) (int64, error) {
+	return store(ctx, dst, sets, "union", func(pipe redis.Pipeliner, keys []string) *redis.IntCmd {
+		return pipe.SUnionStore(ctx, dst.Key(), keys...)
+	})
+}
+
+// Intersect stores the members common to all of sets in dst, replacing its members, and
+// returns its cardinality; see Union for the cluster requirements
+func Intersect[T any](ctx context.Context, dst *Set[T], sets ...*Set[T]) (int64, error) {
+	return store(ctx, dst, sets, "intersection", func(pipe redis.Pipeliner, keys []string) *redis.IntCmd {
+		return pipe.SInterStore(ctx, dst.Key(), keys...)
+	})
+}
+
+// Difference stores the members of the first of sets that are in none of the others in dst,
+// replacing its members, and returns its cardinality; see Union for the cluster requirements
+func Difference[T any](ctx context.Context, dst *Set[T], sets ...*Set[T]) (int64, error) {
+	return store(ctx, dst, sets, "difference", func(pipe redis.Pipeliner, keys []string) *redis.IntCmd {
+		return pipe.SDiffStore(ctx, dst.Key(), keys...)
+	})
+}
+
+// store runs a *STORE command into dst and applies dst's TTL to the result
+func store[T any](ctx context.Context, dst *Set[T], sets []*Set[T], name string, cmd func(redis.Pipeliner, []string) *redis.IntCmd) (int64, error) {
+	if dst == nil || dst.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+	if len(sets) == 0 {
+		return 0, ErrNoSources
+	}
+
+	keys := make([]string, len(sets))
+	for i, set := range sets {
+		keys[i] = set.Key()
+	}
+
+	pipe := dst.client.Pipeline()
+	result := cmd(pipe, keys)
+	if dst.ttl > 0 {
+		pipe.PExpire(ctx, dst.Key(), dst.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to store set %s: %w", name, err)
+	}
+	return result.Val(), nil
+}
+
+func (s *Set[T]) encode(members []T) ([]interface{}, error) {
+	encoded := make([]interface{}, len(members))
+	for i, member := range members {
+		data, err := s.codec.Marshal(member)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode set member: %w", err)
+		}
+		encoded[i] = data
+	}
+	return encoded, nil
+}
+
+func (s *Set[T]) decode(data string) (T, error) {
+	var member T
+	if err := s.codec.Unmarshal([]byte(data), &member); err != nil {
+		return member, fmt.Errorf("failed to decode set member: %w", err)
+	}
+	return member, nil
+}
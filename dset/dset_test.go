@@ -0,0 +1,200 @@
+package dset
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+func TestSet_Basic(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewSet[int](client, "ids")
+	if s.Key() != "dset:ids" {
+		t.Errorf("Key() = %q, want dset:ids", s.Key())
+	}
+
+	if n, err := s.Add(ctx, 1, 2, 3, 2); err != nil || n != 3 {
+		t.Fatalf("Add() = %d, %v, want 3, nil", n, err)
+	}
+	if n, _ := s.Add(ctx, 3, 4); n != 1 {
+		t.Errorf("Add() existing = %d, want 1", n)
+	}
+	if n, _ := s.Add(ctx); n != 0 {
+		t.Errorf("Add() nothing = %d, want 0", n)
+	}
+	if ok, err := s.Contains(ctx, 4); err != nil || !ok {
+		t.Errorf("Contains(4) = %v, %v, want true, nil", ok, err)
+	}
+	if found, err := s.ContainsMany(ctx, 1, 9); err != nil || !slices.Equal(found, []bool{true, false}) {
+		t.Errorf("ContainsMany() = %v, %v, want [true false]", found, err)
+	}
+	if n, err := s.Card(ctx); err != nil || n != 4 {
+		t.Errorf("Card() = %d, %v, want 4", n, err)
+	}
+
+	members, err := s.Members(ctx)
+	slices.Sort(members)
+	if err != nil || !slices.Equal(members, []int{1, 2, 3, 4}) {
+		t.Errorf("Members() = %v, %v, want [1 2 3 4]", members, err)
+	}
+
+	if n, err := s.Remove(ctx, 1, 9); err != nil || n != 1 {
+		t.Errorf("Remove() = %d, %v, want 1, nil", n, err)
+	}
+	if ttl := client.PTTL(ctx, s.Key()).Val(); ttl >= 0 {
+		t.Errorf("PTTL() = %v, want no expiry", ttl)
+	}
+	if err := s.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error = %v, want nil", err)
+	}
+	if n, _ := s.Card(ctx); n != 0 {
+		t.Errorf("Card() after Clear = %d, want 0", n)
+	}
+}
+
+func TestSet_Range(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewSet[string](client, "users", WithCodec(codec.Raw))
+	for i := 0; i < 250; i++ {
+		_, _ = s.Add(ctx, string(rune('a'+i%26))+string(rune('a'+i/26)))
+	}
+	if ok, _ := client.SIsMember(ctx, s.Key(), "aa").Result(); !ok {
+		t.Error("raw codec did not store members as plain strings")
+	}
+
+	seen := map[string]bool{}
+	if err := s.Range(ctx, func(member string) bool {
+		seen[member] = true
+		return true
+	}); err != nil {
+		t.Fatalf("Range() error = %v, want nil", err)
+	}
+	if len(seen) != 250 {
+		t.Errorf("Range() saw %d members, want 250", len(seen))
+	}
+
+	calls := 0
+	_ = s.Range(ctx, func(string) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Range() stopping early called fn %d times, want 1", calls)
+	}
+}
+
+func TestSet_Algebra(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	a := NewSet[int](client, "a")
+	b := NewSet[int](client, "b")
+	dst := NewSet[int](client, "dst", WithTTL(time.Minute))
+	_, _ = a.Add(ctx, 1, 2, 3)
+	_, _ = b.Add(ctx, 2, 3, 4)
+
+	check := func(name string, n int64, err error, want []int) {
+		t.Helper()
+		members, _ := dst.Members(ctx)
+		slices.Sort(members)
+		if err != nil || n != int64(len(want)) || !slices.Equal(members, want) {
+			t.Errorf("%s() = %d, %v with members %v, want %v", name, n, err, members, want)
+		}
+		if ttl := client.PTTL(ctx, dst.Key()).Val(); ttl <= 0 || ttl > time.Minute {
+			t.Errorf("%s() destination PTTL = %v, want (0, 1m]", name, ttl)
+		}
+	}
+
+	n, err := Union(ctx, dst, a, b)
+	check("Union", n, err, []int{1, 2, 3, 4})
+	n, err = Intersect(ctx, dst, a, b)
+	check("Intersect", n, err, []int{2, 3})
+	n, err = Difference(ctx, dst, a, b)
+	check("Difference", n, err, []int{1})
+
+	if n, err := Intersect(ctx, dst, a, NewSet[int](client, "missing")); err != nil || n != 0 {
+		t.Errorf("Intersect() with empty set = %d, %v, want 0, nil", n, err)
+	}
+	if n, _ := dst.Card(ctx); n != 0 {
+		t.Errorf("Card() after empty Intersect = %d, want 0", n)
+	}
+	if _, err := Union(ctx, dst); !errors.Is(err, ErrNoSources) {
+		t.Errorf("Union() without sources error = %v, want ErrNoSources", err)
+	}
+}
+
+func TestSet_TTL(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewSet[string](client, "online", WithKeyPrefix("app:"), WithTTL(time.Minute))
+	if _, err := s.Add(ctx, "alice"); err != nil {
+		t.Fatalf("Add() error = %v, want nil", err)
+	}
+	if s.Key() != "app:online" {
+		t.Errorf("Key() = %q, want app:online", s.Key())
+	}
+	if ttl := client.PTTL(ctx, s.Key()).Val(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("PTTL() = %v, want (0, 1m]", ttl)
+	}
+}
+
+func TestSet_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	s := NewSet[int](nil, "x")
+	if _, err := s.Add(ctx, 1); err == nil {
+		t.Error("Add() with nil client error = nil, want error")
+	}
+	if _, err := s.Remove(ctx, 1); err == nil {
+		t.Error("Remove() with nil client error = nil, want error")
+	}
+	if _, err := s.Contains(ctx, 1); err == nil {
+		t.Error("Contains() with nil client error = nil, want error")
+	}
+	if _, err := s.Members(ctx); err == nil {
+		t.Error("Members() with nil client error = nil, want error")
+	}
+	if _, err := Union(ctx, s, s); err == nil {
+		t.Error("Union() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	s = NewSet[int](client, "x")
+	_ = client.SAdd(ctx, s.Key(), "not-json").Err()
+	if _, err := s.Members(ctx); err == nil {
+		t.Error("Members() undecodable member error = nil, want error")
+	}
+	if err := s.Range(ctx, func(int) bool { return true }); err == nil {
+		t.Error("Range() undecodable member error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if _, err := s.Add(ctx, 1); err == nil {
+		t.Error("Add() error = nil, want error")
+	}
+	if _, err := s.ContainsMany(ctx, 1); err == nil {
+		t.Error("ContainsMany() error = nil, want error")
+	}
+	if _, err := s.Card(ctx); err == nil {
+		t.Error("Card() error = nil, want error")
+	}
+	if _, err := Intersect(ctx, s, s); err == nil {
+		t.Error("Intersect() error = nil, want error")
+	}
+}
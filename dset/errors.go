@@ -0,0 +1,8 @@
+package dset
+
+import "errors"
+
+var (
+	// ErrNoSources indicates a set operation was called without source sets.
+	ErrNoSources = errors.New("no source sets")
+)
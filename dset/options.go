@@ -0,0 +1,43 @@
+package dset
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// settings holds the configuration shared by sets of every member type
+type settings struct {
+	keyPrefix string
+	codec     codec.Codec
+	ttl       time.Duration
+}
+
+// Option configures a Set
+type Option func(*settings)
+
+// WithKeyPrefix sets the prefix of the set key (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(s *settings) {
+		s.keyPrefix = prefix
+	}
+}
+
+// WithCodec sets the member codec (default: codec.Default())
+// It must encode equal members to equal bytes; codec.Raw stores strings as they are
+func WithCodec(c codec.Codec) Option {
+	return func(s *settings) {
+		if c != nil {
+			s.codec = c
+		}
+	}
+}
+
+// WithTTL makes the whole set expire ttl after its last write (default: never)
+func WithTTL(ttl time.Duration) Option {
+	return func(s *settings) {
+		if ttl >= 0 {
+			s.ttl = ttl
+		}
+	}
+}
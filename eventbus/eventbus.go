@@ -0,0 +1,194 @@
+// Package eventbus provides typed publish/subscribe topics with at-least-once delivery
+// Each topic is a Redis stream and each subscriber a consumer group on it, so every
+// subscriber sees every event. Failed handlers are retried with backoff, redelivered, and
+// finally dead-lettered, using the queue package for the delivery mechanics
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/queue"
+	"github.com/soulteary/redis-kit/utils"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// DefaultKeyPrefix is the default prefix for topic streams
+const DefaultKeyPrefix = "events:"
+
+// DefaultRetryPolicy returns the default in-process retry policy for handlers:
+// three attempts with exponential backoff, retrying every error
+func DefaultRetryPolicy() utils.RetryPolicy {
+	return utils.DefaultRetryPolicy().WithClassifier(retryAll)
+}
+
+func retryAll(error) bool {
+	return true
+}
+
+// Bus publishes events to topics and runs subscriptions
+type Bus struct {
+	client    *redis.Client
+	keyPrefix string
+	codec     codec.Codec
+	retry     utils.RetryPolicy
+	base      queue.Config
+}
+
+// NewBus creates an event bus
+func NewBus(client *redis.Client, opts ...Option) *Bus {
+	b := &Bus{
+		client:    client,
+		keyPrefix: DefaultKeyPrefix,
+		codec:     codec.Default(),
+		retry:     DefaultRetryPolicy(),
+		base:      queue.DefaultConfig(""),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Stream returns the Redis stream key of topic
+func (b *Bus) Stream(topic string) string {
+	return b.keyPrefix + topic
+}
+
+// DeadLetterStream returns the stream receiving the events subscriber gave up on
+func (b *Bus) DeadLetterStream(topic, subscriber string) string {
+	return b.Stream(topic) + ":" + subscriber + queue.DefaultDeadLetterSuffix
+}
+
+// queue returns the queue delivering topic to subscriber
+func (b *Bus) queue(topic, subscriber string) *queue.Queue {
+	cfg := b.base
+	cfg.Stream = b.Stream(topic)
+	cfg.Group = subscriber
+	cfg.DeadLetterStream = b.DeadLetterStream(topic, subscriber)
+	cfg.Codec = b.codec
+	return queue.NewQueue(b.client, cfg)
+}
+
+// PublishRaw appends an event that is already encoded with the bus codec and returns its entry ID
+func (b *Bus) PublishRaw(ctx context.Context, topic string, data []byte) (string, error) {
+	if b.client == nil {
+		return "", fmt.Errorf("redis client is nil")
+	}
+
+	id, err := b.queue(topic, "").EnqueueRaw(ctx, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish event: %w", err)
+	}
+	return id, nil
+}
+
+// DeadLetters returns up to count events subscriber gave up on, oldest first
+func (b *Bus) DeadLetters(ctx context.Context, topic, subscriber string, count int64) ([]queue.DeadLetter, error) {
+	if b.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	return b.queue(topic, subscriber).DeadLetters(ctx, count)
+}
+
+// Pending returns the number of events delivered to subscriber but not yet handled
+func (b *Bus) Pending(ctx context.Context, topic, subscriber string) (int64, error) {
+	if b.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+	return b.queue(topic, subscriber).Pending(ctx)
+}
+
+// Event is an event delivered to a subscriber
+type Event[T any] struct {
+	// ID is the stream entry ID, unique per topic; use it to deduplicate redeliveries
+	ID string
+
+	// Topic is the topic the event was published to
+	Topic string
+
+	// Data is the decoded event
+	Data T
+
+	// Attempts is the number of times the event has been delivered to this subscriber
+	Attempts int64
+
+	// PublishedAt is the time the event was published, derived from its ID
+	PublishedAt time.Time
+}
+
+// Handler processes one event; returning an error retries it and eventually dead-letters it
+type Handler[T any] func(ctx context.Context, event *Event[T]) error
+
+// Topic publishes and subscribes to events of type T
+type Topic[T any] struct {
+	bus  *Bus
+	name string
+}
+
+// NewTopic returns the topic name on bus carrying events of type T
+func NewTopic[T any](bus *Bus, name string) *Topic[T] {
+	return &Topic[T]{bus: bus, name: name}
+}
+
+// Name returns the topic name
+func (t *Topic[T]) Name() string {
+	return t.name
+}
+
+// Publish encodes event, appends it to the topic, and returns its entry ID
+func (t *Topic[T]) Publish(ctx context.Context, event T) (string, error) {
+	data, err := t.bus.codec.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode event: %w", err)
+	}
+	return t.bus.PublishRaw(ctx, t.name, data)
+}
+
+// PublishPipelined queues event on pipe and returns the command holding its entry ID after
+// Exec. With a TxPipeline the event is written atomically with the caller's own writes,
+// so state kept in Redis and the event announcing it cannot diverge
+func (t *Topic[T]) PublishPipelined(ctx context.Context, pipe redis.Pipeliner, event T) (*redis.StringCmd, error) {
+	data, err := t.bus.codec.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	return t.bus.queue(t.name, "").EnqueueRawPipelined(ctx, pipe, data), nil
+}
+
+// Subscribe delivers the topic's events to handler as subscriber until ctx is cancelled
+// Each subscriber name is a consumer group: processes subscribing with the same name share
+// the events, different names each receive all of them. A new subscriber starts with the
+// oldest event still in the stream. Failed handlers are retried with the bus retry policy,
+// then redelivered after the redelivery idle time, and dead-lettered after the maximum
+// number of deliveries; events that cannot be decoded are dead-lettered right away.
+// Subscribe returns nil once in-flight events finish
+func (t *Topic[T]) Subscribe(ctx context.Context, subscriber string, handler Handler[T]) error {
+	if t.bus.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if handler == nil {
+		return fmt.Errorf("event handler is nil")
+	}
+	if subscriber == "" {
+		return fmt.Errorf("subscriber name is empty")
+	}
+
+	q := t.bus.queue(t.name, subscriber)
+	return q.Consume(ctx, func(ctx context.Context, job *queue.Job) error {
+		event := &Event[T]{
+			ID:          job.ID,
+			Topic:       t.name,
+			Attempts:    job.Attempts,
+			PublishedAt: job.EnqueuedAt,
+		}
+		if err := job.Decode(&event.Data); err != nil {
+			return q.DeadLetter(ctx, job, err)
+		}
+		return utils.Retry(ctx, t.bus.retry, func(ctx context.Context) error {
+			return handler(ctx, event)
+		})
+	})
+}
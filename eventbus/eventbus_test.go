@@ -0,0 +1,255 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+type orderPlaced struct {
+	OrderID string `json:"order_id"`
+	Amount  int    `json:"amount"`
+}
+
+func testBus(t *testing.T, opts ...Option) *Bus {
+	t.Helper()
+	client, _ := testutil.NewMockRedisClient()
+	t.Cleanup(func() { _ = client.Close() })
+	base := []Option{
+		WithConsumer("c1"),
+		WithConcurrency(1),
+		WithBlockTimeout(10 * time.Millisecond),
+		WithRedelivery(10*time.Millisecond, 20*time.Millisecond),
+		WithRetryPolicy(DefaultRetryPolicy().WithBackoff(time.Millisecond, time.Millisecond)),
+	}
+	return NewBus(client, append(base, opts...)...)
+}
+
+// collect subscribes until want events were handled or the deadline passes
+func collect(t *testing.T, topic *Topic[orderPlaced], subscriber string, want int, handler Handler[orderPlaced]) []*Event[orderPlaced] {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var got []*Event[orderPlaced]
+	done := make(chan error, 1)
+	go func() {
+		done <- topic.Subscribe(ctx, subscriber, func(ctx context.Context, event *Event[orderPlaced]) error {
+			if handler != nil {
+				if err := handler(ctx, event); err != nil {
+					return err
+				}
+			}
+			mu.Lock()
+			got = append(got, event)
+			mu.Unlock()
+			return nil
+		})
+	}()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(got)
+		mu.Unlock()
+		if n >= want {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Subscribe() error = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscribe() did not return after cancel")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return got
+}
+
+func TestTopic_PublishSubscribe(t *testing.T) {
+	bus := testBus(t)
+	ctx := context.Background()
+	orders := NewTopic[orderPlaced](bus, "orders")
+	if orders.Name() != "orders" || bus.Stream("orders") != "events:orders" {
+		t.Errorf("Name()/Stream() = %q/%q", orders.Name(), bus.Stream("orders"))
+	}
+
+	id, err := orders.Publish(ctx, orderPlaced{OrderID: "o-1", Amount: 10})
+	if err != nil || id == "" {
+		t.Fatalf("Publish() = %q, %v, want an entry ID", id, err)
+	}
+	_, _ = orders.Publish(ctx, orderPlaced{OrderID: "o-2", Amount: 20})
+
+	// Every subscriber receives every event
+	for _, subscriber := range []string{"billing", "email"} {
+		got := collect(t, orders, subscriber, 2, nil)
+		if len(got) != 2 {
+			t.Fatalf("%s received %d events, want 2", subscriber, len(got))
+		}
+		first := got[0]
+		if first.ID != id || first.Topic != "orders" || first.Data.OrderID != "o-1" || first.Attempts != 1 {
+			t.Errorf("%s first event = %+v, want o-1 with ID %s", subscriber, first, id)
+		}
+		if first.PublishedAt.IsZero() || time.Since(first.PublishedAt) > time.Minute {
+			t.Errorf("%s PublishedAt = %v, want about now", subscriber, first.PublishedAt)
+		}
+		if n, _ := bus.Pending(ctx, "orders", subscriber); n != 0 {
+			t.Errorf("%s Pending() = %d, want 0", subscriber, n)
+		}
+	}
+}
+
+func TestTopic_PublishPipelined(t *testing.T) {
+	bus := testBus(t)
+	ctx := context.Background()
+	orders := NewTopic[orderPlaced](bus, "orders")
+
+	pipe := bus.client.Pipeline()
+	pipe.HSet(ctx, "order:o-3", "status", "placed")
+	cmd, err := orders.PublishPipelined(ctx, pipe, orderPlaced{OrderID: "o-3"})
+	if err != nil {
+		t.Fatalf("PublishPipelined() error = %v, want nil", err)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatalf("Exec() error = %v, want nil", err)
+	}
+
+	got := collect(t, orders, "billing", 1, nil)
+	if len(got) != 1 || got[0].ID != cmd.Val() || got[0].Data.OrderID != "o-3" {
+		t.Errorf("received %+v, want o-3 with ID %s", got, cmd.Val())
+	}
+
+	if _, err := orders.PublishPipelined(ctx, pipe, orderPlaced{}); err != nil {
+		t.Errorf("PublishPipelined() error = %v, want nil", err)
+	}
+	bad := NewTopic[chan int](bus, "bad")
+	if _, err := bad.PublishPipelined(ctx, pipe, make(chan int)); err == nil {
+		t.Error("PublishPipelined() with an unencodable event error = nil, want error")
+	}
+}
+
+func TestTopic_RetryAndDeadLetter(t *testing.T) {
+	bus := testBus(t, WithMaxDeliveries(2), WithRetryPolicy(DefaultRetryPolicy().WithMaxAttempts(2).WithBackoff(time.Millisecond, time.Millisecond)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	orders := NewTopic[orderPlaced](bus, "orders")
+
+	_, _ = orders.Publish(ctx, orderPlaced{OrderID: "flaky"})
+	poisonID, _ := orders.Publish(ctx, orderPlaced{OrderID: "poison"})
+	_, _ = orders.Publish(ctx, orderPlaced{OrderID: "ok"})
+
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	done := make(chan error, 1)
+	go func() {
+		done <- orders.Subscribe(ctx, "billing", func(_ context.Context, event *Event[orderPlaced]) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[event.Data.OrderID]++
+			switch event.Data.OrderID {
+			case "flaky":
+				if calls["flaky"] < 2 {
+					return errors.New("temporary failure")
+				}
+			case "poison":
+				return errors.New("always fails")
+			}
+			return nil
+		})
+	}()
+
+	var letters []string
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) && len(letters) == 0 {
+		dead, _ := bus.DeadLetters(context.Background(), "orders", "billing", 10)
+		for _, d := range dead {
+			letters = append(letters, d.SourceID)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Subscribe() error = %v, want nil", err)
+	}
+
+	if len(letters) != 1 || letters[0] != poisonID {
+		t.Errorf("DeadLetters() = %v, want the poison event %s", letters, poisonID)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// flaky succeeds on its in-process retry; poison fails 2 attempts on each of 2 deliveries
+	if calls["flaky"] != 2 || calls["ok"] != 1 || calls["poison"] != 4 {
+		t.Errorf("handler calls = %v, want flaky 2, ok 1, poison 4", calls)
+	}
+}
+
+func TestTopic_UndecodableEvent(t *testing.T) {
+	bus := testBus(t)
+	ctx := context.Background()
+	orders := NewTopic[orderPlaced](bus, "orders")
+
+	badID, _ := bus.PublishRaw(ctx, "orders", []byte("not json"))
+	_, _ = orders.Publish(ctx, orderPlaced{OrderID: "ok"})
+
+	got := collect(t, orders, "billing", 1, nil)
+	if len(got) != 1 || got[0].Data.OrderID != "ok" {
+		t.Errorf("received %+v, want only the decodable event", got)
+	}
+	dead, err := bus.DeadLetters(ctx, "orders", "billing", 10)
+	if err != nil || len(dead) != 1 || dead[0].SourceID != badID {
+		t.Errorf("DeadLetters() = %+v, %v, want the undecodable event", dead, err)
+	}
+	if bus.DeadLetterStream("orders", "billing") != "events:orders:billing:dead" {
+		t.Errorf("DeadLetterStream() = %q", bus.DeadLetterStream("orders", "billing"))
+	}
+}
+
+func TestBus_Errors(t *testing.T) {
+	ctx := context.Background()
+	bus := NewBus(nil)
+	orders := NewTopic[orderPlaced](bus, "orders")
+	if _, err := orders.Publish(ctx, orderPlaced{}); err == nil {
+		t.Error("Publish() with nil client error = nil, want error")
+	}
+	if err := orders.Subscribe(ctx, "billing", func(context.Context, *Event[orderPlaced]) error { return nil }); err == nil {
+		t.Error("Subscribe() with nil client error = nil, want error")
+	}
+	if _, err := bus.DeadLetters(ctx, "orders", "billing", 1); err == nil {
+		t.Error("DeadLetters() with nil client error = nil, want error")
+	}
+	if _, err := bus.Pending(ctx, "orders", "billing"); err == nil {
+		t.Error("Pending() with nil client error = nil, want error")
+	}
+
+	bus = testBus(t)
+	orders = NewTopic[orderPlaced](bus, "orders")
+	if err := orders.Subscribe(ctx, "billing", nil); err == nil {
+		t.Error("Subscribe() with nil handler error = nil, want error")
+	}
+	if err := orders.Subscribe(ctx, "", func(context.Context, *Event[orderPlaced]) error { return nil }); err == nil {
+		t.Error("Subscribe() without subscriber error = nil, want error")
+	}
+	bad := NewTopic[chan int](bus, "bad")
+	if _, err := bad.Publish(ctx, make(chan int)); err == nil {
+		t.Error("Publish() with an unencodable event error = nil, want error")
+	}
+}
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	p := DefaultRetryPolicy()
+	if p.MaxAttempts != utils.DefaultRetryPolicy().MaxAttempts || !p.Classifier(errors.New("any")) {
+		t.Errorf("DefaultRetryPolicy() = %+v, want the default attempts retrying every error", p)
+	}
+}
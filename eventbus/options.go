@@ -0,0 +1,91 @@
+package eventbus
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// Option configures a Bus
+type Option func(*Bus)
+
+// WithKeyPrefix sets the prefix of topic streams (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(b *Bus) {
+		b.keyPrefix = prefix
+	}
+}
+
+// WithCodec sets the codec used for event payloads (default: codec.Default())
+func WithCodec(c codec.Codec) Option {
+	return func(b *Bus) {
+		if c != nil {
+			b.codec = c
+		}
+	}
+}
+
+// WithConsumer sets the consumer name of this process within each subscriber group
+// (default: hostname, pid and a random suffix)
+func WithConsumer(consumer string) Option {
+	return func(b *Bus) {
+		if consumer != "" {
+			b.base.Consumer = consumer
+		}
+	}
+}
+
+// WithConcurrency sets the number of events a subscription handles in parallel (default: 4)
+func WithConcurrency(n int) Option {
+	return func(b *Bus) {
+		if n > 0 {
+			b.base.Concurrency = n
+		}
+	}
+}
+
+// WithBlockTimeout sets how long a subscription waits for new events per read (default: 2s)
+func WithBlockTimeout(timeout time.Duration) Option {
+	return func(b *Bus) {
+		b.base.BlockTimeout = timeout
+	}
+}
+
+// WithRedelivery sets how often subscriptions look for events left pending by failed
+// handlers or crashed consumers, and how long an event must be pending first (default: 30s, 1m)
+func WithRedelivery(interval, minIdle time.Duration) Option {
+	return func(b *Bus) {
+		b.base.ClaimInterval = interval
+		b.base.ClaimMinIdle = minIdle
+	}
+}
+
+// WithMaxDeliveries sets the number of deliveries after which an event is dead-lettered (default: 5)
+func WithMaxDeliveries(n int64) Option {
+	return func(b *Bus) {
+		b.base.MaxDeliveries = n
+	}
+}
+
+// WithRetryPolicy sets how a handler is retried in-process before the delivery counts as
+// failed (default: DefaultRetryPolicy())
+func WithRetryPolicy(policy utils.RetryPolicy) Option {
+	return func(b *Bus) {
+		b.retry = policy
+	}
+}
+
+// WithMaxLen approximately caps the length of topic streams on publish (0 means unbounded)
+func WithMaxLen(maxLen int64) Option {
+	return func(b *Bus) {
+		b.base.MaxLen = maxLen
+	}
+}
+
+// WithOnError sets a callback for errors in subscriptions, which keep running after them
+func WithOnError(fn func(error)) Option {
+	return func(b *Bus) {
+		b.base.OnError = fn
+	}
+}
@@ -0,0 +1,11 @@
+package featureflag
+
+import "errors"
+
+var (
+	// ErrFlagNotFound indicates the flag does not exist.
+	ErrFlagNotFound = errors.New("flag not found")
+
+	// ErrInvalidFlag indicates a flag with an empty name, unknown type, or invalid rollout.
+	ErrInvalidFlag = errors.New("invalid flag")
+)
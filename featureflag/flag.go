@@ -0,0 +1,126 @@
+package featureflag
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// Type is the kind of a flag
+type Type string
+
+const (
+	// TypeBool flags are on or off for everyone
+	TypeBool Type = "bool"
+	// TypePercentage flags are on for a stable percentage of subjects
+	TypePercentage Type = "percentage"
+	// TypeVariant flags assign each subject one of several weighted variants
+	TypeVariant Type = "variant"
+)
+
+// Variant is a weighted option of a variant flag
+type Variant struct {
+	// Name is returned by Store.Variant for subjects in this variant
+	Name string `json:"name"`
+
+	// Weight is the relative share of subjects; weights need not sum to 100
+	Weight int `json:"weight"`
+}
+
+// Flag is a feature flag definition
+type Flag struct {
+	// Name identifies the flag
+	Name string `json:"name"`
+
+	// Type selects how the flag is evaluated (default: TypeBool)
+	Type Type `json:"type"`
+
+	// Enabled is the master switch; a disabled flag is off for everyone
+	Enabled bool `json:"enabled"`
+
+	// Percentage is the share of subjects (0-100) a percentage flag is on for
+	Percentage float64 `json:"percentage,omitempty"`
+
+	// Variants are the options of a variant flag
+	Variants []Variant `json:"variants,omitempty"`
+
+	// Description documents the flag for humans
+	Description string `json:"description,omitempty"`
+
+	// UpdatedAt is set by Store.Set
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the flag definition
+func (f Flag) Validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("%w: name is empty", ErrInvalidFlag)
+	}
+	switch f.Type {
+	case TypeBool, "":
+	case TypePercentage:
+		if f.Percentage < 0 || f.Percentage > 100 {
+			return fmt.Errorf("%w: percentage %v out of range 0-100", ErrInvalidFlag, f.Percentage)
+		}
+	case TypeVariant:
+		total := 0
+		for _, v := range f.Variants {
+			if v.Name == "" || v.Weight < 0 {
+				return fmt.Errorf("%w: variant %q has an empty name or negative weight", ErrInvalidFlag, v.Name)
+			}
+			total += v.Weight
+		}
+		if total == 0 {
+			return fmt.Errorf("%w: variant flag needs a positive total weight", ErrInvalidFlag)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidFlag, f.Type)
+	}
+	return nil
+}
+
+// EnabledFor reports whether the flag is on for subject
+// Percentage flags put each subject in a stable bucket, so raising the percentage only adds subjects
+func (f Flag) EnabledFor(subject string) bool {
+	if !f.Enabled {
+		return false
+	}
+	switch f.Type {
+	case TypePercentage:
+		return float64(bucket(f.Name, subject))/100 < f.Percentage
+	default:
+		return true
+	}
+}
+
+// VariantFor returns the variant assigned to subject, or "" if the flag is off or not a variant flag
+func (f Flag) VariantFor(subject string) string {
+	if !f.Enabled || f.Type != TypeVariant {
+		return ""
+	}
+	total := 0
+	for _, v := range f.Variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return ""
+	}
+	point := bucket(f.Name, subject) % total
+	for _, v := range f.Variants {
+		if point < v.Weight {
+			return v.Name
+		}
+		point -= v.Weight
+	}
+	return ""
+}
+
+// bucket maps subject to a stable value in [0, 10000) per flag
+// Hashing the flag name in keeps the buckets of different flags independent
+func bucket(flag, subject string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(flag))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(subject))
+	return int(h.Sum32() % 10000)
+}
@@ -0,0 +1,95 @@
+package featureflag
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFlag_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		flag Flag
+		ok   bool
+	}{
+		{"bool", Flag{Name: "a"}, true},
+		{"percentage", Flag{Name: "a", Type: TypePercentage, Percentage: 25}, true},
+		{"variant", Flag{Name: "a", Type: TypeVariant, Variants: []Variant{{Name: "x", Weight: 1}}}, true},
+		{"empty name", Flag{}, false},
+		{"percentage out of range", Flag{Name: "a", Type: TypePercentage, Percentage: 101}, false},
+		{"variant without weight", Flag{Name: "a", Type: TypeVariant, Variants: []Variant{{Name: "x"}}}, false},
+		{"variant without name", Flag{Name: "a", Type: TypeVariant, Variants: []Variant{{Weight: 1}}}, false},
+		{"unknown type", Flag{Name: "a", Type: "json"}, false},
+	}
+	for _, tt := range tests {
+		err := tt.flag.Validate()
+		if tt.ok && err != nil {
+			t.Errorf("%s: Validate() error = %v, want nil", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidFlag) {
+			t.Errorf("%s: Validate() error = %v, want ErrInvalidFlag", tt.name, err)
+		}
+	}
+}
+
+func TestFlag_EnabledFor(t *testing.T) {
+	if (Flag{Name: "a", Enabled: false}).EnabledFor("u") {
+		t.Error("EnabledFor() on a disabled flag = true, want false")
+	}
+	if !(Flag{Name: "a", Type: TypeBool, Enabled: true}).EnabledFor("u") {
+		t.Error("EnabledFor() on an enabled bool flag = false, want true")
+	}
+
+	flag := Flag{Name: "new-checkout", Type: TypePercentage, Enabled: true, Percentage: 30}
+	on := 0
+	for i := 0; i < 10000; i++ {
+		subject := fmt.Sprintf("user-%d", i)
+		enabled := flag.EnabledFor(subject)
+		if enabled != flag.EnabledFor(subject) {
+			t.Fatalf("EnabledFor(%s) is not stable", subject)
+		}
+		if enabled {
+			on++
+			// Raising the percentage never turns a subject off
+			wider := flag
+			wider.Percentage = 50
+			if !wider.EnabledFor(subject) {
+				t.Fatalf("EnabledFor(%s) turned off when the rollout grew", subject)
+			}
+		}
+	}
+	if on < 2800 || on > 3200 {
+		t.Errorf("EnabledFor() at 30%% = %d/10000, want about 3000", on)
+	}
+
+	flag.Percentage = 0
+	if flag.EnabledFor("user-1") {
+		t.Error("EnabledFor() at 0% = true, want false")
+	}
+	flag.Percentage = 100
+	if !flag.EnabledFor("user-1") {
+		t.Error("EnabledFor() at 100% = false, want true")
+	}
+}
+
+func TestFlag_VariantFor(t *testing.T) {
+	flag := Flag{Name: "button", Type: TypeVariant, Enabled: true, Variants: []Variant{
+		{Name: "blue", Weight: 3},
+		{Name: "green", Weight: 1},
+	}}
+	counts := map[string]int{}
+	for i := 0; i < 8000; i++ {
+		counts[flag.VariantFor(fmt.Sprintf("user-%d", i))]++
+	}
+	if counts["blue"] < 5600 || counts["blue"] > 6400 || counts["blue"]+counts["green"] != 8000 {
+		t.Errorf("VariantFor() distribution = %v, want about 3:1", counts)
+	}
+
+	flag.Enabled = false
+	if v := flag.VariantFor("user-1"); v != "" {
+		t.Errorf("VariantFor() on a disabled flag = %q, want empty", v)
+	}
+	if v := (Flag{Name: "a", Enabled: true}).VariantFor("user-1"); v != "" {
+		t.Errorf("VariantFor() on a bool flag = %q, want empty", v)
+	}
+}
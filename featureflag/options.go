@@ -0,0 +1,30 @@
+package featureflag
+
+import "time"
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithKeyPrefix sets the prefix of the flag hash and invalidation channel (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(s *Store) {
+		s.keyPrefix = prefix
+	}
+}
+
+// WithCacheTTL sets how long the local cache is trusted before it is reloaded on read (default: DefaultCacheTTL)
+// With Watch running, changes arrive immediately and the TTL only bounds missed invalidations
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(s *Store) {
+		if ttl > 0 {
+			s.cacheTTL = ttl
+		}
+	}
+}
+
+// WithOnError sets a callback for errors in Watch, which keeps running after them
+func WithOnError(fn func(error)) Option {
+	return func(s *Store) {
+		s.onError = fn
+	}
+}
@@ -0,0 +1,271 @@
+// Package featureflag provides feature flags stored in Redis with a local cache
+// Flags live in one hash; writers publish the changed flag name so every Watch-ing
+// process refreshes its cache right away
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for the flag hash and invalidation channel
+	DefaultKeyPrefix = "featureflag:"
+
+	// DefaultCacheTTL is how long the local cache is trusted by default
+	DefaultCacheTTL = 30 * time.Second
+)
+
+// Store reads and writes flags and keeps a local copy for fast evaluation
+type Store struct {
+	client    *redis.Client
+	keyPrefix string
+	cacheTTL  time.Duration
+	onError   func(error)
+
+	mu       sync.RWMutex
+	flags    map[string]Flag
+	loadedAt time.Time
+}
+
+// NewStore creates a flag store
+func NewStore(client *redis.Client, opts ...Option) *Store {
+	s := &Store{
+		client:    client,
+		keyPrefix: DefaultKeyPrefix,
+		cacheTTL:  DefaultCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Key returns the hash key holding the flags
+func (s *Store) Key() string {
+	return s.keyPrefix + "flags"
+}
+
+// Channel returns the pub/sub channel carrying the names of changed flags
+func (s *Store) Channel() string {
+	return s.keyPrefix + "changes"
+}
+
+// Set creates or replaces a flag and notifies watchers
+func (s *Store) Set(ctx context.Context, flag Flag) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if err := flag.Validate(); err != nil {
+		return err
+	}
+	if flag.Type == "" {
+		flag.Type = TypeBool
+	}
+	flag.UpdatedAt = time.Now().UTC()
+
+	data, err := json.Marshal(flag)
+	if err != nil {
+		return fmt.Errorf("failed to marshal flag: %w", err)
+	}
+
+	pipe := s.client.Pipeline()
+	pipe.HSet(ctx, s.Key(), flag.Name, data)
+	pipe.Publish(ctx, s.Channel(), flag.Name)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set flag: %w", err)
+	}
+
+	s.updateCached(flag.Name, &flag)
+	return nil
+}
+
+// Delete removes a flag and notifies watchers
+func (s *Store) Delete(ctx context.Context, name string) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	pipe := s.client.Pipeline()
+	pipe.HDel(ctx, s.Key(), name)
+	pipe.Publish(ctx, s.Channel(), name)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete flag: %w", err)
+	}
+
+	s.updateCached(name, nil)
+	return nil
+}
+
+// Get returns a flag from the local cache, loading it first if the cache is stale
+// Returns ErrFlagNotFound if the flag does not exist
+func (s *Store) Get(ctx context.Context, name string) (Flag, error) {
+	flags, err := s.cached(ctx)
+	if err != nil {
+		return Flag{}, err
+	}
+	flag, ok := flags[name]
+	if !ok {
+		return Flag{}, ErrFlagNotFound
+	}
+	return flag, nil
+}
+
+// All returns every flag sorted by name
+func (s *Store) All(ctx context.Context) ([]Flag, error) {
+	flags, err := s.cached(ctx)
+	if err != nil {
+		return nil, err
+	}
+	all := make([]Flag, 0, len(flags))
+	for _, flag := range flags {
+		all = append(all, flag)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all, nil
+}
+
+// IsEnabled reports whether flag is on for subject (e.g. a user ID)
+// Unknown flags are off
+func (s *Store) IsEnabled(ctx context.Context, name, subject string) (bool, error) {
+	flag, err := s.Get(ctx, name)
+	if errors.Is(err, ErrFlagNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return flag.EnabledFor(subject), nil
+}
+
+// Variant returns the variant of flag assigned to subject, or "" if the flag is unknown or off
+func (s *Store) Variant(ctx context.Context, name, subject string) (string, error) {
+	flag, err := s.Get(ctx, name)
+	if errors.Is(err, ErrFlagNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return flag.VariantFor(subject), nil
+}
+
+// Refresh reloads all flags from Redis
+func (s *Store) Refresh(ctx context.Context) error {
+	_, err := s.load(ctx)
+	return err
+}
+
+// cached returns the local flags, reloading them when they are older than the cache TTL
+func (s *Store) cached(ctx context.Context) (map[string]Flag, error) {
+	s.mu.RLock()
+	flags, loadedAt := s.flags, s.loadedAt
+	s.mu.RUnlock()
+	if flags != nil && time.Since(loadedAt) < s.cacheTTL {
+		return flags, nil
+	}
+	return s.load(ctx)
+}
+
+func (s *Store) load(ctx context.Context) (map[string]Flag, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	raw, err := s.client.HGetAll(ctx, s.Key()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load flags: %w", err)
+	}
+	flags := make(map[string]Flag, len(raw))
+	for name, data := range raw {
+		var flag Flag
+		if err := json.Unmarshal([]byte(data), &flag); err != nil {
+			s.reportError(fmt.Errorf("failed to unmarshal flag %q: %w", name, err))
+			continue
+		}
+		flags[name] = flag
+	}
+
+	s.mu.Lock()
+	s.flags = flags
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return flags, nil
+}
+
+// reloadFlag refreshes a single cached flag after an invalidation message
+func (s *Store) reloadFlag(ctx context.Context, name string) error {
+	data, err := s.client.HGet(ctx, s.Key(), name).Bytes()
+	if err == redis.Nil {
+		s.updateCached(name, nil)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reload flag %q: %w", name, err)
+	}
+	var flag Flag
+	if err := json.Unmarshal(data, &flag); err != nil {
+		return fmt.Errorf("failed to unmarshal flag %q: %w", name, err)
+	}
+	s.updateCached(name, &flag)
+	return nil
+}
+
+// updateCached replaces (or with a nil flag removes) one cached flag
+// The map is copied on write, since readers use it without holding the lock
+func (s *Store) updateCached(name string, flag *Flag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.flags == nil {
+		return
+	}
+	flags := make(map[string]Flag, len(s.flags)+1)
+	for k, v := range s.flags {
+		flags[k] = v
+	}
+	if flag == nil {
+		delete(flags, name)
+	} else {
+		flags[name] = *flag
+	}
+	s.flags = flags
+}
+
+// Watch keeps the local cache in sync with flag changes until ctx is cancelled
+// It reloads all flags whenever the subscription is (re)established, so changes made
+// while disconnected are not missed. Watch returns nil on cancellation
+func (s *Store) Watch(ctx context.Context) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	return utils.WatchChannel(ctx, s.client, s.Channel(), utils.ChannelHandler{
+		OnSubscribe: func() {
+			if _, err := s.load(ctx); err != nil {
+				s.reportError(err)
+			}
+		},
+		OnMessage: func(name string) {
+			if err := s.reloadFlag(ctx, name); err != nil {
+				s.reportError(err)
+			}
+		},
+		OnError: func(err error) {
+			s.reportError(fmt.Errorf("flag subscription failed: %w", err))
+		},
+	})
+}
+
+func (s *Store) reportError(err error) {
+	if s.onError != nil && err != nil && !errors.Is(err, context.Canceled) {
+		s.onError(err)
+	}
+}
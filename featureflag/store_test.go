@@ -0,0 +1,158 @@
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestStore_SetGet(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client)
+	if s.Key() != "featureflag:flags" || s.Channel() != "featureflag:changes" {
+		t.Errorf("Key()/Channel() = %q/%q", s.Key(), s.Channel())
+	}
+
+	if err := s.Set(ctx, Flag{Name: "dark-mode", Enabled: true}); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	if err := s.Set(ctx, Flag{Name: "beta", Type: TypePercentage, Enabled: true, Percentage: 100}); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	if err := s.Set(ctx, Flag{Name: ""}); !errors.Is(err, ErrInvalidFlag) {
+		t.Errorf("Set() invalid flag error = %v, want ErrInvalidFlag", err)
+	}
+
+	flag, err := s.Get(ctx, "dark-mode")
+	if err != nil || flag.Type != TypeBool || flag.UpdatedAt.IsZero() {
+		t.Errorf("Get() = %+v, %v, want a bool flag with UpdatedAt", flag, err)
+	}
+	if _, err := s.Get(ctx, "missing"); !errors.Is(err, ErrFlagNotFound) {
+		t.Errorf("Get() missing error = %v, want ErrFlagNotFound", err)
+	}
+	if all, _ := s.All(ctx); len(all) != 2 || all[0].Name != "beta" {
+		t.Errorf("All() = %v, want beta, dark-mode", all)
+	}
+
+	if on, err := s.IsEnabled(ctx, "beta", "user-1"); err != nil || !on {
+		t.Errorf("IsEnabled(beta) = %v, %v, want true", on, err)
+	}
+	if on, err := s.IsEnabled(ctx, "missing", "user-1"); err != nil || on {
+		t.Errorf("IsEnabled(missing) = %v, %v, want false", on, err)
+	}
+	if v, err := s.Variant(ctx, "missing", "user-1"); err != nil || v != "" {
+		t.Errorf("Variant(missing) = %q, %v, want empty", v, err)
+	}
+
+	if err := s.Delete(ctx, "beta"); err != nil {
+		t.Fatalf("Delete() error = %v, want nil", err)
+	}
+	if on, _ := s.IsEnabled(ctx, "beta", "user-1"); on {
+		t.Error("IsEnabled() after Delete = true, want false")
+	}
+
+	// Another process sees the flags once its cache is loaded
+	other := NewStore(client)
+	if v, err := other.Variant(ctx, "dark-mode", "user-1"); err != nil || v != "" {
+		t.Errorf("Variant() on a bool flag = %q, %v, want empty", v, err)
+	}
+	if all, _ := other.All(ctx); len(all) != 1 {
+		t.Errorf("All() from another store = %v, want dark-mode", all)
+	}
+}
+
+func TestStore_CacheTTL(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	writer := NewStore(client)
+	reader := NewStore(client, WithCacheTTL(50*time.Millisecond))
+
+	if on, _ := reader.IsEnabled(ctx, "f", "u"); on {
+		t.Fatal("IsEnabled() before Set = true, want false")
+	}
+	_ = writer.Set(ctx, Flag{Name: "f", Enabled: true})
+	if on, _ := reader.IsEnabled(ctx, "f", "u"); on {
+		t.Error("IsEnabled() within the cache TTL = true, want the cached false")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if on, _ := reader.IsEnabled(ctx, "f", "u"); !on {
+		t.Error("IsEnabled() after the cache TTL = false, want true")
+	}
+}
+
+func TestStore_Watch(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	writer := NewStore(client, WithKeyPrefix("ff:"))
+	reader := NewStore(client, WithKeyPrefix("ff:"), WithCacheTTL(time.Hour))
+	_ = writer.Set(ctx, Flag{Name: "f", Enabled: false})
+
+	done := make(chan error, 1)
+	go func() { done <- reader.Watch(ctx) }()
+
+	waitFor := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if on, _ := reader.IsEnabled(ctx, "f", "u"); on == want {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("IsEnabled() did not become %v", want)
+	}
+
+	waitFor(false)
+	_ = writer.Set(ctx, Flag{Name: "f", Enabled: true})
+	waitFor(true)
+	_ = writer.Delete(ctx, "f")
+	waitFor(false)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch() error = %v, want nil on cancellation", err)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	s := NewStore(nil)
+	if err := s.Set(ctx, Flag{Name: "a"}); err == nil {
+		t.Error("Set() with nil client error = nil, want error")
+	}
+	if _, err := s.IsEnabled(ctx, "a", "u"); err == nil {
+		t.Error("IsEnabled() with nil client error = nil, want error")
+	}
+	if err := s.Watch(ctx); err == nil {
+		t.Error("Watch() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	mock.SetShouldFail(true)
+
+	s = NewStore(client)
+	if err := s.Set(ctx, Flag{Name: "a"}); err == nil {
+		t.Error("Set() error = nil, want error")
+	}
+	if err := s.Delete(ctx, "a"); err == nil {
+		t.Error("Delete() error = nil, want error")
+	}
+	if _, err := s.Variant(ctx, "a", "u"); err == nil {
+		t.Error("Variant() error = nil, want error")
+	}
+	if err := s.Refresh(ctx); err == nil {
+		t.Error("Refresh() error = nil, want error")
+	}
+}
@@ -0,0 +1,14 @@
+package geo
+
+import "errors"
+
+var (
+	// ErrLocationNotFound indicates the named location is not in the index.
+	ErrLocationNotFound = errors.New("location not found")
+
+	// ErrInvalidCoordinates indicates a longitude or latitude outside the range Redis accepts.
+	ErrInvalidCoordinates = errors.New("invalid coordinates")
+
+	// ErrInvalidQuery indicates a query without a center or without a radius or box.
+	ErrInvalidQuery = errors.New("invalid query")
+)
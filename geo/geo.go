@@ -0,0 +1,303 @@
+// Package geo provides a geospatial index for "nearby" queries on Redis GEO commands
+package geo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for index keys
+	DefaultKeyPrefix = "geo:"
+
+	// MaxLatitude is the largest latitude Redis can index (Web Mercator limit)
+	MaxLatitude = 85.05112878
+)
+
+// Location is a named point
+type Location struct {
+	// Name identifies the location within the index, e.g. a driver or store ID
+	Name string
+
+	// Longitude in degrees, -180 to 180
+	Longitude float64
+
+	// Latitude in degrees, -85.05112878 to 85.05112878
+	Latitude float64
+}
+
+// Valid reports whether the coordinates can be stored by Redis
+func (l Location) Valid() bool {
+	return l.Longitude >= -180 && l.Longitude <= 180 && l.Latitude >= -MaxLatitude && l.Latitude <= MaxLatitude
+}
+
+// Result is a location returned by a search
+type Result struct {
+	Location
+
+	// Distance from the query center in the index unit
+	Distance float64
+}
+
+// Query describes a search around a member or a point
+// Set Member or Longitude/Latitude as the center, and Radius or Width/Height as the area
+type Query struct {
+	// Member centers the search on an indexed location
+	Member string
+
+	// Longitude and Latitude center the search on a point when Member is empty
+	Longitude float64
+	Latitude  float64
+
+	// Radius searches a circle when positive
+	Radius float64
+
+	// Width and Height search an axis-aligned box when Radius is zero
+	Width  float64
+	Height float64
+
+	// Descending returns the farthest results first
+	Descending bool
+
+	// Offset skips the first results, for pagination
+	Offset int
+
+	// Limit caps the number of results; 0 returns all matches
+	Limit int
+}
+
+// Index stores named locations in a Redis GEO set
+type Index struct {
+	client    *redis.Client
+	name      string
+	keyPrefix string
+	unit      Unit
+}
+
+// NewIndex creates a geospatial index with the given name
+func NewIndex(client *redis.Client, name string, opts ...Option) *Index {
+	i := &Index{
+		client:    client,
+		name:      name,
+		keyPrefix: DefaultKeyPrefix,
+		unit:      Meters,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Key returns the Redis key of the index
+func (i *Index) Key() string {
+	return i.keyPrefix + i.name
+}
+
+// Unit returns the distance unit of the index
+func (i *Index) Unit() Unit {
+	return i.unit
+}
+
+// Add adds or moves locations and returns the number of new ones
+// Returns ErrInvalidCoordinates if any location is out of range; nothing is stored then
+func (i *Index) Add(ctx context.Context, locs ...Location) (int64, error) {
+	if i.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+	if len(locs) == 0 {
+		return 0, nil
+	}
+
+	geoLocs := make([]*redis.GeoLocation, len(locs))
+	for n, loc := range locs {
+		if !loc.Valid() {
+			return 0, fmt.Errorf("%w: %s (%v, %v)", ErrInvalidCoordinates, loc.Name, loc.Longitude, loc.Latitude)
+		}
+		geoLocs[n] = &redis.GeoLocation{Name: loc.Name, Longitude: loc.Longitude, Latitude: loc.Latitude}
+	}
+
+	added, err := i.client.GeoAdd(ctx, i.Key(), geoLocs...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to add locations: %w", err)
+	}
+	return added, nil
+}
+
+// Remove removes locations by name and returns the number removed
+func (i *Index) Remove(ctx context.Context, names ...string) (int64, error) {
+	if i.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+	if len(names) == 0 {
+		return 0, nil
+	}
+
+	members := make([]interface{}, len(names))
+	for n, name := range names {
+		members[n] = name
+	}
+	removed, err := i.client.ZRem(ctx, i.Key(), members...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove locations: %w", err)
+	}
+	return removed, nil
+}
+
+// Position returns the stored location of name
+// Coordinates come back with the ~0.6m precision of the GEO encoding
+// Returns ErrLocationNotFound if name is not indexed
+func (i *Index) Position(ctx context.Context, name string) (Location, error) {
+	locs, err := i.Positions(ctx, name)
+	if err != nil {
+		return Location{}, err
+	}
+	if locs[0] == nil {
+		return Location{}, ErrLocationNotFound
+	}
+	return *locs[0], nil
+}
+
+// Positions returns the stored locations of names; missing names yield nil entries
+func (i *Index) Positions(ctx context.Context, names ...string) ([]*Location, error) {
+	if i.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if len(names) == 0 {
+		return []*Location{}, nil
+	}
+
+	pos, err := i.client.GeoPos(ctx, i.Key(), names...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+	locs := make([]*Location, len(names))
+	for n, p := range pos {
+		if p != nil {
+			locs[n] = &Location{Name: names[n], Longitude: p.Longitude, Latitude: p.Latitude}
+		}
+	}
+	return locs, nil
+}
+
+// Distance returns the distance between two indexed locations in the index unit
+// Returns ErrLocationNotFound if either is not indexed
+func (i *Index) Distance(ctx context.Context, from, to string) (float64, error) {
+	if i.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	dist, err := i.client.GeoDist(ctx, i.Key(), from, to, string(i.unit)).Result()
+	if err == redis.Nil {
+		return 0, ErrLocationNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get distance: %w", err)
+	}
+	return dist, nil
+}
+
+// Nearby returns up to limit locations within radius of a point, nearest first
+func (i *Index) Nearby(ctx context.Context, longitude, latitude, radius float64, limit int) ([]Result, error) {
+	return i.Search(ctx, Query{Longitude: longitude, Latitude: latitude, Radius: radius, Limit: limit})
+}
+
+// NearbyMember returns up to limit locations within radius of an indexed location, nearest first
+// The member itself is included at distance 0
+func (i *Index) NearbyMember(ctx context.Context, name string, radius float64, limit int) ([]Result, error) {
+	return i.Search(ctx, Query{Member: name, Radius: radius, Limit: limit})
+}
+
+// WithinBox returns up to limit locations inside a width x height box centered on a point, nearest first
+func (i *Index) WithinBox(ctx context.Context, longitude, latitude, width, height float64, limit int) ([]Result, error) {
+	return i.Search(ctx, Query{Longitude: longitude, Latitude: latitude, Width: width, Height: height, Limit: limit})
+}
+
+// Search runs a radius or box query, sorted by distance
+// Pagination fetches Offset+Limit results and skips the first Offset, so deep pages cost more
+// Returns ErrLocationNotFound if the Member center is not indexed
+func (i *Index) Search(ctx context.Context, q Query) ([]Result, error) {
+	if i.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	sq := redis.GeoSearchQuery{Member: q.Member, Sort: "ASC"}
+	if q.Descending {
+		sq.Sort = "DESC"
+	}
+	if q.Member == "" {
+		if !(Location{Longitude: q.Longitude, Latitude: q.Latitude}).Valid() {
+			return nil, ErrInvalidCoordinates
+		}
+		sq.Longitude, sq.Latitude = q.Longitude, q.Latitude
+	}
+	switch {
+	case q.Radius > 0:
+		sq.Radius, sq.RadiusUnit = q.Radius, string(i.unit)
+	case q.Width > 0 && q.Height > 0:
+		sq.BoxWidth, sq.BoxHeight, sq.BoxUnit = q.Width, q.Height, string(i.unit)
+	default:
+		return nil, fmt.Errorf("%w: a positive radius or box size is required", ErrInvalidQuery)
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.Limit > 0 {
+		sq.Count = q.Offset + q.Limit
+	}
+
+	// Searching around an unknown member is an error in Redis, so check it up front
+	if q.Member != "" {
+		if _, err := i.client.ZScore(ctx, i.Key(), q.Member).Result(); err == redis.Nil {
+			return nil, ErrLocationNotFound
+		}
+	}
+
+	locs, err := i.client.GeoSearchLocation(ctx, i.Key(), &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: sq,
+		WithCoord:      true,
+		WithDist:       true,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search locations: %w", err)
+	}
+
+	if q.Offset >= len(locs) {
+		return []Result{}, nil
+	}
+	locs = locs[q.Offset:]
+	results := make([]Result, len(locs))
+	for n, loc := range locs {
+		results[n] = Result{
+			Location: Location{Name: loc.Name, Longitude: loc.Longitude, Latitude: loc.Latitude},
+			Distance: loc.Dist,
+		}
+	}
+	return results, nil
+}
+
+// Count returns the number of indexed locations
+func (i *Index) Count(ctx context.Context) (int64, error) {
+	if i.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	n, err := i.client.ZCard(ctx, i.Key()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count locations: %w", err)
+	}
+	return n, nil
+}
+
+// Clear deletes the index
+func (i *Index) Clear(ctx context.Context) error {
+	if i.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := i.client.Del(ctx, i.Key()).Err(); err != nil {
+		return fmt.Errorf("failed to clear index: %w", err)
+	}
+	return nil
+}
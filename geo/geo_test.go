@@ -0,0 +1,153 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+var stores = []Location{
+	{Name: "louvre", Longitude: 2.3376, Latitude: 48.8606},
+	{Name: "notre-dame", Longitude: 2.3499, Latitude: 48.8530},
+	{Name: "eiffel", Longitude: 2.2945, Latitude: 48.8584},
+	{Name: "versailles", Longitude: 2.1204, Latitude: 48.8049},
+}
+
+func newTestIndex(t *testing.T, opts ...Option) *Index {
+	t.Helper()
+	client, _ := testutil.NewMockRedisClient()
+	t.Cleanup(func() { _ = client.Close() })
+
+	idx := NewIndex(client, "stores", opts...)
+	if n, err := idx.Add(context.Background(), stores...); err != nil || n != int64(len(stores)) {
+		t.Fatalf("Add() = %d, %v, want %d", n, err, len(stores))
+	}
+	return idx
+}
+
+func TestNewIndex(t *testing.T) {
+	idx := NewIndex(nil, "stores")
+	if idx.Key() != "geo:stores" || idx.Unit() != Meters {
+		t.Errorf("NewIndex() = %q/%s, want geo:stores/m", idx.Key(), idx.Unit())
+	}
+	idx = NewIndex(nil, "stores", WithKeyPrefix("g:"), WithUnit(Kilometers), WithUnit(""))
+	if idx.Key() != "g:stores" || idx.Unit() != Kilometers {
+		t.Errorf("NewIndex() with options = %q/%s, want g:stores/km", idx.Key(), idx.Unit())
+	}
+}
+
+func TestIndex_PositionsAndDistance(t *testing.T) {
+	ctx := context.Background()
+	idx := newTestIndex(t, WithUnit(Kilometers))
+
+	loc, err := idx.Position(ctx, "eiffel")
+	if err != nil || math.Abs(loc.Longitude-2.2945) > 1e-4 || math.Abs(loc.Latitude-48.8584) > 1e-4 {
+		t.Errorf("Position() = %+v, %v, want eiffel", loc, err)
+	}
+	if _, err := idx.Position(ctx, "pantheon"); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("Position() missing error = %v, want ErrLocationNotFound", err)
+	}
+	locs, err := idx.Positions(ctx, "louvre", "pantheon")
+	if err != nil || len(locs) != 2 || locs[0] == nil || locs[0].Name != "louvre" || locs[1] != nil {
+		t.Errorf("Positions() = %v, %v, want louvre and nil", locs, err)
+	}
+
+	d, err := idx.Distance(ctx, "louvre", "eiffel")
+	if err != nil || d < 3 || d > 3.5 {
+		t.Errorf("Distance() = %v km, %v, want ~3.2", d, err)
+	}
+	if _, err := idx.Distance(ctx, "louvre", "pantheon"); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("Distance() missing error = %v, want ErrLocationNotFound", err)
+	}
+
+	if n, err := idx.Remove(ctx, "versailles", "pantheon"); err != nil || n != 1 {
+		t.Errorf("Remove() = %d, %v, want 1", n, err)
+	}
+	if n, _ := idx.Count(ctx); n != 3 {
+		t.Errorf("Count() = %d, want 3", n)
+	}
+	if err := idx.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error = %v, want nil", err)
+	}
+	if n, _ := idx.Count(ctx); n != 0 {
+		t.Errorf("Count() after Clear = %d, want 0", n)
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	ctx := context.Background()
+	idx := newTestIndex(t, WithUnit(Kilometers))
+
+	res, err := idx.Nearby(ctx, 2.3376, 48.8606, 5, 0)
+	if err != nil || len(res) != 3 {
+		t.Fatalf("Nearby() = %v, %v, want 3 results", res, err)
+	}
+	if res[0].Name != "louvre" || res[1].Name != "notre-dame" || res[2].Name != "eiffel" {
+		t.Errorf("Nearby() order = %s, %s, %s, want louvre, notre-dame, eiffel", res[0].Name, res[1].Name, res[2].Name)
+	}
+	if res[0].Distance > 0.01 || res[2].Distance < 3 {
+		t.Errorf("Nearby() distances = %v, %v, want ~0 and ~3.2", res[0].Distance, res[2].Distance)
+	}
+
+	res, err = idx.NearbyMember(ctx, "eiffel", 50, 2)
+	if err != nil || len(res) != 2 || res[0].Name != "eiffel" || res[1].Name != "louvre" {
+		t.Errorf("NearbyMember() = %v, %v, want eiffel, louvre", res, err)
+	}
+	if _, err := idx.NearbyMember(ctx, "pantheon", 5, 0); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("NearbyMember() missing error = %v, want ErrLocationNotFound", err)
+	}
+
+	// A wide, flat box around the Louvre reaches the Eiffel Tower but not Versailles
+	res, err = idx.WithinBox(ctx, 2.3376, 48.8606, 10, 2, 0)
+	if err != nil || len(res) != 3 {
+		t.Errorf("WithinBox() = %v, %v, want 3 results", res, err)
+	}
+
+	// Pages of two, farthest first
+	page1, _ := idx.Search(ctx, Query{Member: "louvre", Radius: 50, Descending: true, Limit: 2})
+	page2, _ := idx.Search(ctx, Query{Member: "louvre", Radius: 50, Descending: true, Offset: 2, Limit: 2})
+	page3, _ := idx.Search(ctx, Query{Member: "louvre", Radius: 50, Descending: true, Offset: 4, Limit: 2})
+	if len(page1) != 2 || page1[0].Name != "versailles" || len(page2) != 2 || page2[1].Name != "louvre" || len(page3) != 0 {
+		t.Errorf("Search() pages = %v / %v / %v", page1, page2, page3)
+	}
+}
+
+func TestIndex_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	idx := NewIndex(nil, "x")
+	if _, err := idx.Add(ctx, stores...); err == nil {
+		t.Error("Add() with nil client error = nil, want error")
+	}
+	if _, err := idx.Nearby(ctx, 0, 0, 1, 0); err == nil {
+		t.Error("Nearby() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	idx = NewIndex(client, "x")
+	if _, err := idx.Add(ctx, Location{Name: "pole", Latitude: 90}); !errors.Is(err, ErrInvalidCoordinates) {
+		t.Errorf("Add() at the pole error = %v, want ErrInvalidCoordinates", err)
+	}
+	if _, err := idx.Search(ctx, Query{Longitude: 1, Latitude: 1}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Search() without area error = %v, want ErrInvalidQuery", err)
+	}
+	if _, err := idx.Nearby(ctx, 181, 0, 1, 0); !errors.Is(err, ErrInvalidCoordinates) {
+		t.Errorf("Nearby() invalid center error = %v, want ErrInvalidCoordinates", err)
+	}
+
+	mock.SetShouldFail(true)
+	if _, err := idx.Add(ctx, stores[0]); err == nil {
+		t.Error("Add() error = nil, want error")
+	}
+	if _, err := idx.Nearby(ctx, 0, 0, 1, 0); err == nil {
+		t.Error("Nearby() error = nil, want error")
+	}
+	if _, err := idx.Distance(ctx, "a", "b"); err == nil || errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("Distance() error = %v, want redis error", err)
+	}
+}
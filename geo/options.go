@@ -0,0 +1,34 @@
+package geo
+
+// Unit is a distance unit
+type Unit string
+
+const (
+	// Meters is the meter unit
+	Meters Unit = "m"
+	// Kilometers is the kilometer unit
+	Kilometers Unit = "km"
+	// Miles is the mile unit
+	Miles Unit = "mi"
+	// Feet is the foot unit
+	Feet Unit = "ft"
+)
+
+// Option configures an Index
+type Option func(*Index)
+
+// WithKeyPrefix sets the prefix of the index key (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(i *Index) {
+		i.keyPrefix = prefix
+	}
+}
+
+// WithUnit sets the unit of radii, box sizes, and returned distances (default: Meters)
+func WithUnit(unit Unit) Option {
+	return func(i *Index) {
+		if unit != "" {
+			i.unit = unit
+		}
+	}
+}
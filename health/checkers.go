@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/queue"
+	"github.com/soulteary/redis-kit/ratelimit"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// Checker reports whether a component is usable; a nil error means healthy
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker
+type CheckerFunc func(ctx context.Context) error
+
+// Check implements Checker
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Ping returns a Checker that pings client
+func Ping(client redis.UniversalClient) Checker {
+	client = utils.NormalizeClient(client)
+	return CheckerFunc(func(ctx context.Context) error {
+		if client == nil {
+			return fmt.Errorf("redis client is nil")
+		}
+		return client.Ping(ctx).Err()
+	})
+}
+
+// Cache returns a Checker that pings the client behind c
+func Cache(c *cache.RedisCache) Checker {
+	return Ping(c.Client())
+}
+
+// RateLimiter returns a Checker that pings the client behind l
+func RateLimiter(l *ratelimit.RateLimiter) Checker {
+	return Ping(l.Client())
+}
+
+// Queue returns a Checker that reads the length of q's stream, which also fails
+// if the key holds something other than a stream
+func Queue(q *queue.Queue) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		_, err := q.Len(ctx)
+		return err
+	})
+}
@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"testing"
+
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/queue"
+	"github.com/soulteary/redis-kit/ratelimit"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestCheckers(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	checkers := map[string]Checker{
+		"ping":      Ping(client),
+		"cache":     Cache(cache.NewCache(client, "c:")),
+		"limiter":   RateLimiter(ratelimit.NewRateLimiter(client)),
+		"queue":     Queue(queue.NewQueue(client, queue.DefaultConfig("jobs"))),
+		"func":      CheckerFunc(func(context.Context) error { return nil }),
+		"nilClient": Ping(nil),
+	}
+	for name, c := range checkers {
+		err := c.Check(ctx)
+		if name == "nilClient" {
+			if err == nil {
+				t.Error("Ping(nil).Check() error = nil, want error")
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s.Check() error = %v, want nil", name, err)
+		}
+	}
+
+	mock.SetShouldFail(true)
+	for name, c := range checkers {
+		if name == "func" {
+			continue
+		}
+		if err := c.Check(ctx); err == nil {
+			t.Errorf("%s.Check() with failing Redis error = nil, want error", name)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Package health aggregates health checks of Redis clients and redis-kit components
+// into one report, served over HTTP or logged periodically
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultTimeout is how long each component check may take by default
+const DefaultTimeout = 2 * time.Second
+
+// ComponentStatus is the result of checking one component
+type ComponentStatus struct {
+	// Name is the name the component was registered under
+	Name string `json:"name"`
+
+	// Healthy reports whether the last check succeeded
+	Healthy bool `json:"healthy"`
+
+	// Latency is how long the last check took
+	Latency time.Duration `json:"latency"`
+
+	// Error is the error of the last check, empty if healthy
+	Error string `json:"error,omitempty"`
+
+	// CheckedAt is the time of the last check
+	CheckedAt time.Time `json:"checked_at"`
+
+	// LastError is the most recent error, kept after the component recovers
+	LastError string `json:"last_error,omitempty"`
+
+	// LastErrorAt is the time of LastError
+	LastErrorAt time.Time `json:"last_error_at,omitzero"`
+}
+
+// Report is the combined result of checking every registered component
+type Report struct {
+	// Healthy reports whether all components are healthy
+	Healthy bool `json:"healthy"`
+
+	// Components holds one status per component, in registration order
+	Components []ComponentStatus `json:"components"`
+
+	// CheckedAt is the time the check started
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type component struct {
+	name    string
+	checker Checker
+
+	// last is guarded by Registry.mu
+	last ComponentStatus
+}
+
+// Registry holds the components whose health is checked together
+type Registry struct {
+	timeout time.Duration
+
+	mu         sync.Mutex
+	components []*component
+	last       Report
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register adds a component under name; registering a name again replaces its checker
+func (r *Registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.components {
+		if c.name == name {
+			c.checker = checker
+			return
+		}
+	}
+	r.components = append(r.components, &component{
+		name:    name,
+		checker: checker,
+		last:    ComponentStatus{Name: name},
+	})
+}
+
+// RegisterClient adds a Redis client under name, checked with a ping
+func (r *Registry) RegisterClient(name string, client redis.UniversalClient) {
+	r.Register(name, Ping(client))
+}
+
+// Unregister removes the component registered under name
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, c := range r.components {
+		if c.name == name {
+			r.components = append(r.components[:i], r.components[i+1:]...)
+			return
+		}
+	}
+}
+
+// Check runs all component checks concurrently, each bounded by the registry timeout
+// A registry without components is healthy
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.Lock()
+	components := append([]*component(nil), r.components...)
+	checkers := make([]Checker, len(components))
+	for i, c := range components {
+		checkers[i] = c.checker
+	}
+	r.mu.Unlock()
+
+	report := Report{Healthy: true, CheckedAt: time.Now()}
+	errs := make([]error, len(components))
+	latencies := make([]time.Duration, len(components))
+
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+
+			start := time.Now()
+			errs[i] = checker.Check(checkCtx)
+			latencies[i] = time.Since(start)
+		}()
+	}
+	wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report.Components = make([]ComponentStatus, len(components))
+	for i, c := range components {
+		status := c.last
+		status.Healthy = errs[i] == nil
+		status.Latency = latencies[i]
+		status.CheckedAt = report.CheckedAt
+		status.Error = ""
+		if errs[i] != nil {
+			status.Error = errs[i].Error()
+			status.LastError = status.Error
+			status.LastErrorAt = report.CheckedAt
+			report.Healthy = false
+		}
+		c.last = status
+		report.Components[i] = status
+	}
+	r.last = report
+	return report
+}
+
+// Last returns the report of the most recent Check, or a zero Report before the first
+func (r *Registry) Last() Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+// Handler returns an http.Handler that runs Check and writes the report as JSON
+// The status code is 200 if all components are healthy and 503 otherwise
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Check(req.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// Run checks all components every interval until ctx is cancelled
+// Failing components are logged at warn level on every check, and recoveries at info level;
+// a nil logger uses slog.Default()
+func (r *Registry) Run(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if interval <= 0 {
+		interval = r.timeout
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	previous := make(map[string]bool)
+	for {
+		report := r.Check(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		for _, c := range report.Components {
+			if !c.Healthy {
+				logger.Warn("redis-kit health check failed", "component", c.Name, "latency", c.Latency, "error", c.Error)
+			} else if healthy, seen := previous[c.Name]; seen && !healthy {
+				logger.Info("redis-kit health check recovered", "component", c.Name, "latency", c.Latency)
+			}
+			previous[c.Name] = c.Healthy
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestRegistry_Check(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	r := NewRegistry()
+	if report := r.Check(ctx); !report.Healthy || len(report.Components) != 0 {
+		t.Errorf("Check() on empty registry = %+v, want healthy", report)
+	}
+
+	var failing atomic.Bool
+	r.RegisterClient("primary", client)
+	r.Register("queue", CheckerFunc(func(context.Context) error {
+		if failing.Load() {
+			return errors.New("stream unavailable")
+		}
+		return nil
+	}))
+
+	report := r.Check(ctx)
+	if !report.Healthy || len(report.Components) != 2 || report.Components[0].Name != "primary" {
+		t.Fatalf("Check() = %+v, want two healthy components in order", report)
+	}
+	if report.Components[0].Latency <= 0 || report.Components[0].CheckedAt.IsZero() {
+		t.Errorf("Check() primary = %+v, want latency and time set", report.Components[0])
+	}
+
+	failing.Store(true)
+	report = r.Check(ctx)
+	q := report.Components[1]
+	if report.Healthy || q.Healthy || q.Error != "stream unavailable" || q.LastError != q.Error {
+		t.Errorf("Check() failing = %+v, want unhealthy queue with its error", report)
+	}
+
+	failing.Store(false)
+	q = r.Check(ctx).Components[1]
+	if !q.Healthy || q.Error != "" || q.LastError != "stream unavailable" || q.LastErrorAt.IsZero() {
+		t.Errorf("Check() recovered = %+v, want healthy with the last error kept", q)
+	}
+	if last := r.Last(); !last.Healthy || len(last.Components) != 2 {
+		t.Errorf("Last() = %+v, want the recovered report", last)
+	}
+
+	r.Unregister("queue")
+	if report := r.Check(ctx); len(report.Components) != 1 {
+		t.Errorf("Check() after Unregister() = %d components, want 1", len(report.Components))
+	}
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	r := NewRegistry(WithTimeout(20 * time.Millisecond))
+	r.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	start := time.Now()
+	report := r.Check(context.Background())
+	if report.Healthy || time.Since(start) > time.Second {
+		t.Errorf("Check() = %+v after %v, want unhealthy after the timeout", report, time.Since(start))
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	r := NewRegistry()
+	r.RegisterClient("primary", client)
+	h := r.Handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Handler() = %d %q, want 200 application/json", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || !report.Healthy || len(report.Components) != 1 {
+		t.Errorf("Handler() body = %s (%v), want a healthy report", rec.Body.String(), err)
+	}
+
+	mock.SetShouldFail(true)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"healthy":false`) {
+		t.Errorf("Handler() failing = %d %s, want 503 with an unhealthy report", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRegistry_Run(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	r := NewRegistry()
+	r.Register("cache", CheckerFunc(func(context.Context) error {
+		if failing.Load() {
+			return errors.New("down")
+		}
+		return nil
+	}))
+
+	var buf bytes.Buffer
+	var writes atomic.Int32
+	logger := slog.New(slog.NewTextHandler(writerFunc(func(p []byte) (int, error) {
+		writes.Add(1)
+		return buf.Write(p)
+	}), nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(ctx, 10*time.Millisecond, logger)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for writes.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	failing.Store(false)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	out := buf.String()
+	if !strings.Contains(out, "health check failed") || !strings.Contains(out, "component=cache") {
+		t.Errorf("Run() log = %q, want a failure for cache", out)
+	}
+	if !strings.Contains(out, "health check recovered") {
+		t.Errorf("Run() log = %q, want a recovery", out)
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
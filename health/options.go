@@ -0,0 +1,15 @@
+package health
+
+import "time"
+
+// Option configures a Registry
+type Option func(*Registry)
+
+// WithTimeout sets how long each component check may take (default: DefaultTimeout)
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Registry) {
+		if timeout > 0 {
+			r.timeout = timeout
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package idempotency
+
+import "errors"
+
+var (
+	// ErrInProgress indicates another request holds the claim on the key.
+	ErrInProgress = errors.New("request already in progress")
+
+	// ErrClaimLost indicates the claim expired or was released before it was completed.
+	ErrClaimLost = errors.New("idempotency claim lost")
+
+	// ErrNotClaimed indicates Complete or Release was called on a result that holds no claim.
+	ErrNotClaimed = errors.New("result does not hold a claim")
+
+	// ErrNotFound indicates the idempotency key is unknown or expired.
+	ErrNotFound = errors.New("idempotency key not found")
+
+	// ErrEmptyKey indicates an empty idempotency key.
+	ErrEmptyKey = errors.New("idempotency key is empty")
+)
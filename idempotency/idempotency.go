@@ -0,0 +1,287 @@
+// Package idempotency provides idempotency keys for exactly-once request handling
+// The first request for a key claims it; later requests see the claim in progress or
+// get the stored response of the completed one
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+const (
+	// DefaultResultTTL is how long completed responses are kept by default (24 hours)
+	DefaultResultTTL = 24 * time.Hour
+
+	// Stored values are a state marker followed by the claim token or the encoded response
+	pendingMarker = "p:"
+	doneMarker    = "d:"
+)
+
+const completeScript = `
+-- redis-kit:compare-set
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	redis.call("set", KEYS[1], ARGV[2], "px", ARGV[3])
+	return 1
+end
+return 0
+`
+
+const releaseScript = `
+-- redis-kit:compare-del
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0
+`
+
+// Status is the state of an idempotency key as seen by Begin
+type Status int
+
+const (
+	// StatusClaimed means the caller claimed the key and must run the request
+	StatusClaimed Status = iota
+	// StatusInProgress means another caller holds the claim
+	StatusInProgress
+	// StatusCompleted means the request already ran and its response is available
+	StatusCompleted
+)
+
+// String returns the name of the status
+func (s Status) String() string {
+	switch s {
+	case StatusClaimed:
+		return "claimed"
+	case StatusInProgress:
+		return "in_progress"
+	case StatusCompleted:
+		return "completed"
+	default:
+		return "unknown"
+	}
+}
+
+// Result is the outcome of Begin
+type Result struct {
+	// Key is the idempotency key
+	Key string
+
+	// Status tells the caller whether to run the request, wait, or replay the response
+	Status Status
+
+	// Response is the encoded stored response when Status is StatusCompleted
+	Response []byte
+
+	token string
+	codec codec.Codec
+}
+
+// Decode unmarshals the stored response into v
+func (r *Result) Decode(v interface{}) error {
+	if r.Status != StatusCompleted {
+		return fmt.Errorf("no stored response for status %s", r.Status)
+	}
+	if err := r.codec.Unmarshal(r.Response, v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// Store keeps idempotency keys in Redis
+type Store struct {
+	client    *redis.Client
+	prefix    string
+	codec     codec.Codec
+	resultTTL time.Duration
+}
+
+// NewStore creates a new idempotency key store
+func NewStore(client *redis.Client, prefix string, opts ...Option) *Store {
+	s := &Store{
+		client:    client,
+		prefix:    prefix,
+		codec:     codec.Default(),
+		resultTTL: DefaultResultTTL,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// buildKey constructs the full key with prefix
+func (s *Store) buildKey(key string) string {
+	return s.prefix + key
+}
+
+// generateToken generates a random claim token
+func generateToken() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// Begin claims key for ttl, the longest the request may take to run
+// With StatusClaimed the caller must run the request and then call Complete, or Release
+// on failure so it can be retried; an unfinished claim frees itself after ttl
+func (s *Store) Begin(ctx context.Context, key string, ttl time.Duration) (*Result, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if key == "" {
+		return nil, ErrEmptyKey
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	fullKey := s.buildKey(key)
+
+	// A key that expires between SET NX and GET is claimed on the next attempt
+	for attempt := 0; attempt < 2; attempt++ {
+		ok, err := s.client.SetNX(ctx, fullKey, pendingMarker+token, ttl).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+		if ok {
+			return &Result{Key: key, Status: StatusClaimed, token: token, codec: s.codec}, nil
+		}
+
+		res, err := s.Get(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+	return &Result{Key: key, Status: StatusInProgress, codec: s.codec}, nil
+}
+
+// Get returns the current state of key without claiming it
+// Returns ErrNotFound if the key is unknown or expired
+func (s *Store) Get(ctx context.Context, key string) (*Result, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	val, err := s.client.Get(ctx, s.buildKey(key)).Result()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	if strings.HasPrefix(val, doneMarker) {
+		return &Result{
+			Key:      key,
+			Status:   StatusCompleted,
+			Response: []byte(strings.TrimPrefix(val, doneMarker)),
+			codec:    s.codec,
+		}, nil
+	}
+	return &Result{Key: key, Status: StatusInProgress, codec: s.codec}, nil
+}
+
+// Complete stores the response of a claimed request for later replays
+// Returns ErrClaimLost if the claim expired or was released in the meantime
+func (s *Store) Complete(ctx context.Context, res *Result, response interface{}) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if res == nil || res.Status != StatusClaimed {
+		return ErrNotClaimed
+	}
+
+	data, err := s.codec.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	ok, err := s.client.Eval(ctx, completeScript, []string{s.buildKey(res.Key)},
+		pendingMarker+res.token, doneMarker+string(data), s.resultTTL.Milliseconds(),
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	if ok == 0 {
+		return ErrClaimLost
+	}
+	res.Status = StatusCompleted
+	res.Response = data
+	return nil
+}
+
+// Release gives up a claim so the request can be retried, e.g. after a failure
+// Releasing a claim that already expired is not an error
+func (s *Store) Release(ctx context.Context, res *Result) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if res == nil || res.Status != StatusClaimed {
+		return ErrNotClaimed
+	}
+
+	if err := s.client.Eval(ctx, releaseScript, []string{s.buildKey(res.Key)}, pendingMarker+res.token).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Forget deletes key and any stored response
+func (s *Store) Forget(ctx context.Context, key string) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := s.client.Del(ctx, s.buildKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Do runs fn at most once per key and stores its response
+// A completed key returns the stored response; a key in progress returns ErrInProgress.
+// When fn fails the claim is released so the request can be retried. Once fn returns, its
+// response is stored, or the claim released, even if ctx is cancelled by then
+func (s *Store) Do(ctx context.Context, key string, ttl time.Duration, fn func(ctx context.Context) (interface{}, error)) (*Result, error) {
+	res, err := s.Begin(ctx, key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	switch res.Status {
+	case StatusCompleted:
+		return res, nil
+	case StatusInProgress:
+		return res, ErrInProgress
+	}
+
+	response, err := fn(ctx)
+
+	// fn has run, so its outcome is recorded even if the caller gave up meanwhile; otherwise
+	// the claim would stay pending until it expires
+	opCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), utils.DefaultOperationTimeout)
+	defer cancel()
+	if err != nil {
+		if relErr := s.Release(opCtx, res); relErr != nil {
+			return nil, fmt.Errorf("%w (release failed: %v)", err, relErr)
+		}
+		return nil, err
+	}
+	if err := s.Complete(opCtx, res, response); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,204 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+type charge struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+}
+
+func TestStore_BeginComplete(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client, "idem:", WithResultTTL(time.Hour))
+
+	res, err := s.Begin(ctx, "order-1", time.Minute)
+	if err != nil || res.Status != StatusClaimed {
+		t.Fatalf("Begin() = %v, %v, want claimed", res, err)
+	}
+
+	other, err := s.Begin(ctx, "order-1", time.Minute)
+	if err != nil || other.Status != StatusInProgress {
+		t.Fatalf("Begin() while claimed = %v, %v, want in progress", other, err)
+	}
+	if err := s.Complete(ctx, other, charge{}); !errors.Is(err, ErrNotClaimed) {
+		t.Errorf("Complete() without claim error = %v, want ErrNotClaimed", err)
+	}
+
+	if err := s.Complete(ctx, res, charge{ID: "ch_1", Amount: 500}); err != nil {
+		t.Fatalf("Complete() error = %v, want nil", err)
+	}
+	if ttl := client.PTTL(ctx, "idem:order-1").Val(); ttl <= time.Minute || ttl > time.Hour {
+		t.Errorf("PTTL() after Complete = %v, want up to the result TTL", ttl)
+	}
+
+	replay, err := s.Begin(ctx, "order-1", time.Minute)
+	if err != nil || replay.Status != StatusCompleted {
+		t.Fatalf("Begin() after Complete = %v, %v, want completed", replay, err)
+	}
+	var c charge
+	if err := replay.Decode(&c); err != nil || c.ID != "ch_1" || c.Amount != 500 {
+		t.Errorf("Decode() = %+v, %v, want ch_1/500", c, err)
+	}
+	if err := other.Decode(&c); err == nil {
+		t.Error("Decode() of an in-progress result error = nil, want error")
+	}
+
+	if err := s.Forget(ctx, "order-1"); err != nil {
+		t.Fatalf("Forget() error = %v, want nil", err)
+	}
+	if _, err := s.Get(ctx, "order-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Forget error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStore_ReleaseAndClaimLost(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client, "idem:")
+
+	res, _ := s.Begin(ctx, "k", time.Minute)
+	if err := s.Release(ctx, res); err != nil {
+		t.Fatalf("Release() error = %v, want nil", err)
+	}
+	again, err := s.Begin(ctx, "k", time.Minute)
+	if err != nil || again.Status != StatusClaimed {
+		t.Fatalf("Begin() after Release = %v, %v, want claimed", again, err)
+	}
+
+	// The first claim was released and taken over, so it can no longer complete
+	if err := s.Complete(ctx, res, "late"); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("Complete() with a lost claim error = %v, want ErrClaimLost", err)
+	}
+	if err := s.Release(ctx, res); err != nil {
+		t.Errorf("Release() of a lost claim error = %v, want nil", err)
+	}
+	if got, _ := s.Get(ctx, "k"); got == nil || got.Status != StatusInProgress {
+		t.Errorf("Get() = %v, want the second claim still in progress", got)
+	}
+
+	short, _ := s.Begin(ctx, "short", 50*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	if err := s.Complete(ctx, short, "late"); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("Complete() after the claim expired error = %v, want ErrClaimLost", err)
+	}
+}
+
+func TestStore_Do(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client, "idem:")
+
+	var calls atomic.Int32
+	fn := func(ctx context.Context) (interface{}, error) {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return charge{ID: "ch_2"}, nil
+	}
+
+	var wg sync.WaitGroup
+	var completed, inProgress atomic.Int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := s.Do(ctx, "webhook-1", time.Minute, fn)
+			switch {
+			case errors.Is(err, ErrInProgress):
+				inProgress.Add(1)
+			case err == nil && res.Status == StatusCompleted:
+				completed.Add(1)
+			default:
+				t.Errorf("Do() = %v, %v", res, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 || completed.Load()+inProgress.Load() != 5 {
+		t.Errorf("Do() ran fn %d times (%d completed, %d in progress), want once", calls.Load(), completed.Load(), inProgress.Load())
+	}
+
+	res, err := s.Do(ctx, "webhook-1", time.Minute, fn)
+	var c charge
+	if err != nil || res.Decode(&c) != nil || c.ID != "ch_2" || calls.Load() != 1 {
+		t.Errorf("Do() replay = %+v, %v, want stored ch_2 without calling fn", c, err)
+	}
+
+	failing := func(ctx context.Context) (interface{}, error) { return nil, errors.New("card declined") }
+	if _, err := s.Do(ctx, "webhook-2", time.Minute, failing); err == nil || err.Error() != "card declined" {
+		t.Errorf("Do() with failing fn error = %v, want card declined", err)
+	}
+	if _, err := s.Get(ctx, "webhook-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after a failed Do error = %v, want ErrNotFound (claim released)", err)
+	}
+
+	// A caller giving up once fn has run does not leave the claim pending
+	cancelled, cancel := context.WithCancel(ctx)
+	res, err = s.Do(cancelled, "webhook-3", time.Minute, func(ctx context.Context) (interface{}, error) {
+		cancel()
+		return charge{ID: "ch_3"}, nil
+	})
+	if err != nil || res.Status != StatusCompleted {
+		t.Errorf("Do() cancelled after fn = %+v, %v, want the response stored", res, err)
+	}
+	if stored, err := s.Get(ctx, "webhook-3"); err != nil || stored.Decode(&c) != nil || c.ID != "ch_3" {
+		t.Errorf("Get() after a cancelled Do = %+v, %v, want ch_3", c, err)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	s := NewStore(nil, "idem:")
+	if _, err := s.Begin(ctx, "k", time.Minute); err == nil {
+		t.Error("Begin() with nil client error = nil, want error")
+	}
+	if err := s.Forget(ctx, "k"); err == nil {
+		t.Error("Forget() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	s = NewStore(client, "idem:")
+	if _, err := s.Begin(ctx, "", time.Minute); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("Begin() empty key error = %v, want ErrEmptyKey", err)
+	}
+	if err := s.Release(ctx, nil); !errors.Is(err, ErrNotClaimed) {
+		t.Errorf("Release(nil) error = %v, want ErrNotClaimed", err)
+	}
+	res, _ := s.Begin(ctx, "k", time.Minute)
+
+	mock.SetShouldFail(true)
+	if _, err := s.Begin(ctx, "k2", time.Minute); err == nil {
+		t.Error("Begin() error = nil, want error")
+	}
+	if err := s.Complete(ctx, res, "x"); err == nil || errors.Is(err, ErrClaimLost) {
+		t.Errorf("Complete() error = %v, want redis error", err)
+	}
+	if err := s.Complete(ctx, res, make(chan int)); err == nil {
+		t.Error("Complete() with unencodable response error = nil, want error")
+	}
+}
+
+func TestStatus_String(t *testing.T) {
+	if StatusClaimed.String() != "claimed" || StatusInProgress.String() != "in_progress" ||
+		StatusCompleted.String() != "completed" || Status(9).String() != "unknown" {
+		t.Error("Status.String() returned unexpected names")
+	}
+}
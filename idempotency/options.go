@@ -0,0 +1,28 @@
+package idempotency
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithCodec sets the codec used for stored responses (default: codec.Default())
+func WithCodec(c codec.Codec) Option {
+	return func(s *Store) {
+		if c != nil {
+			s.codec = c
+		}
+	}
+}
+
+// WithResultTTL sets how long completed responses are kept (default: DefaultResultTTL)
+func WithResultTTL(ttl time.Duration) Option {
+	return func(s *Store) {
+		if ttl > 0 {
+			s.resultTTL = ttl
+		}
+	}
+}
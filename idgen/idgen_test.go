@@ -0,0 +1,150 @@
+package idgen
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestGenerator_Next(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	g := NewGenerator(client, "orders", WithBatchSize(3))
+	if g.Key() != "idgen:orders" {
+		t.Errorf("Key() = %q, want idgen:orders", g.Key())
+	}
+
+	for want := int64(1); want <= 4; want++ {
+		if id, err := g.Next(ctx); err != nil || id != want {
+			t.Fatalf("Next() = %d, %v, want %d", id, err, want)
+		}
+	}
+	if n, err := g.HighWaterMark(ctx); err != nil || n != 6 {
+		t.Errorf("HighWaterMark() = %d, %v, want 6", n, err)
+	}
+
+	// A second generator gets its own block
+	other := NewGenerator(client, "orders", WithBatchSize(3))
+	if id, _ := other.Next(ctx); id != 7 {
+		t.Errorf("other Next() = %d, want 7", id)
+	}
+	if id, _ := g.Next(ctx); id != 5 {
+		t.Errorf("Next() = %d, want 5", id)
+	}
+
+	g.Reset()
+	if id, _ := g.Next(ctx); id != 10 {
+		t.Errorf("Next() after Reset() = %d, want 10", id)
+	}
+}
+
+func TestGenerator_Concurrent(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	gens := []*Generator{
+		NewGenerator(client, "c", WithBatchSize(7)),
+		NewGenerator(client, "c", WithBatchSize(5)),
+	}
+
+	var mu sync.Mutex
+	seen := make(map[int64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(g *Generator) {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				id, err := g.Next(ctx)
+				if err != nil {
+					t.Errorf("Next() error = %v", err)
+					return
+				}
+				mu.Lock()
+				if seen[id] {
+					t.Errorf("Next() returned duplicate id %d", id)
+				}
+				seen[id] = true
+				mu.Unlock()
+			}
+		}(gens[i%2])
+	}
+	wg.Wait()
+	if len(seen) != 200 {
+		t.Errorf("unique ids = %d, want 200", len(seen))
+	}
+}
+
+func TestGenerator_Format(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	g := NewGenerator(client, "invoices", WithFormat("INV-", 6))
+	if s, err := g.NextString(ctx); err != nil || s != "INV-000001" {
+		t.Errorf("NextString() = %q, %v, want INV-000001", s, err)
+	}
+	if s := g.Format(12345678); s != "INV-12345678" {
+		t.Errorf("Format() wider than padding = %q, want INV-12345678", s)
+	}
+	if s := NewGenerator(client, "x").Format(42); s != "42" {
+		t.Errorf("Format() without format = %q, want 42", s)
+	}
+}
+
+func TestGenerator_EnsureAtLeast(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	g := NewGenerator(client, "users", WithBatchSize(10))
+	if n, err := g.HighWaterMark(ctx); err != nil || n != 0 {
+		t.Errorf("HighWaterMark() new sequence = %d, %v, want 0", n, err)
+	}
+
+	if err := g.EnsureAtLeast(ctx, 1000); err != nil {
+		t.Fatalf("EnsureAtLeast() error = %v, want nil", err)
+	}
+	if id, _ := g.Next(ctx); id != 1001 {
+		t.Errorf("Next() = %d, want 1001", id)
+	}
+	if err := g.EnsureAtLeast(ctx, 500); err != nil {
+		t.Fatalf("EnsureAtLeast() lower error = %v, want nil", err)
+	}
+	if n, _ := g.HighWaterMark(ctx); n != 1010 {
+		t.Errorf("HighWaterMark() = %d, want 1010", n)
+	}
+}
+
+func TestGenerator_Errors(t *testing.T) {
+	ctx := context.Background()
+	g := NewGenerator(nil, "x")
+	if _, err := g.Next(ctx); err == nil {
+		t.Error("Next() with nil client error = nil, want error")
+	}
+	if _, err := g.NextString(ctx); err == nil {
+		t.Error("NextString() with nil client error = nil, want error")
+	}
+	if _, err := g.HighWaterMark(ctx); err == nil {
+		t.Error("HighWaterMark() with nil client error = nil, want error")
+	}
+	if err := g.EnsureAtLeast(ctx, 1); err == nil {
+		t.Error("EnsureAtLeast() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	g = NewGenerator(client, "x")
+	mock.SetShouldFail(true)
+	if _, err := g.Next(ctx); err == nil {
+		t.Error("Next() with failing redis error = nil, want error")
+	}
+	if _, err := g.HighWaterMark(ctx); err == nil {
+		t.Error("HighWaterMark() with failing redis error = nil, want error")
+	}
+}
@@ -0,0 +1,32 @@
+package idgen
+
+// Option configures a Generator
+type Option func(*Generator)
+
+// WithKeyPrefix sets the prefix of the sequence key (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(g *Generator) {
+		g.keyPrefix = prefix
+	}
+}
+
+// WithBatchSize sets how many IDs are reserved per Redis round trip (default: DefaultBatchSize)
+// Larger batches mean fewer round trips but bigger gaps when a process exits with unused IDs
+func WithBatchSize(n int64) Option {
+	return func(g *Generator) {
+		if n > 0 {
+			g.batchSize = n
+		}
+	}
+}
+
+// WithFormat sets the prefix and zero-padded width of IDs returned by NextString
+// (default: no prefix and no padding)
+func WithFormat(prefix string, width int) Option {
+	return func(g *Generator) {
+		g.idPrefix = prefix
+		if width >= 0 {
+			g.width = width
+		}
+	}
+}
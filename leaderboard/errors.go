@@ -0,0 +1,11 @@
+package leaderboard
+
+import "errors"
+
+var (
+	// ErrMemberNotFound indicates the member has no score on the board.
+	ErrMemberNotFound = errors.New("member not found")
+
+	// ErrNoPayload indicates the entry has no member payload to decode.
+	ErrNoPayload = errors.New("entry has no payload")
+)
@@ -0,0 +1,375 @@
+// Package leaderboard provides ranked score boards on Redis sorted sets
+package leaderboard
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for leaderboard keys
+	DefaultKeyPrefix = "leaderboard:"
+
+	// DefaultRetention is the default number of past periods kept readable
+	DefaultRetention = 1
+)
+
+// Entry is one ranked member of a board
+type Entry struct {
+	// Member is the member name
+	Member string
+
+	// Score is the member's score
+	Score float64
+
+	// Rank is the 1-based position on the board
+	Rank int64
+
+	// Payload is the encoded member payload, or nil if none was set
+	Payload []byte
+
+	codec codec.Codec
+}
+
+// Decode unmarshals the member payload into v using the leaderboard's codec
+// Returns ErrNoPayload if the member has no payload
+func (e *Entry) Decode(v interface{}) error {
+	if e.Payload == nil {
+		return ErrNoPayload
+	}
+	if err := e.codec.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode member payload: %w", err)
+	}
+	return nil
+}
+
+// Leaderboard ranks members by score
+// With a period other than PeriodAllTime, scores go to a fresh board each period and
+// past boards expire after the retention window
+type Leaderboard struct {
+	client    *redis.Client
+	name      string
+	keyPrefix string
+	period    Period
+	loc       *time.Location
+	retention int
+	ascending bool
+	codec     codec.Codec
+	at        time.Time
+}
+
+// NewLeaderboard creates a leaderboard with the given name
+func NewLeaderboard(client *redis.Client, name string, opts ...Option) *Leaderboard {
+	l := &Leaderboard{
+		client:    client,
+		name:      name,
+		keyPrefix: DefaultKeyPrefix,
+		period:    PeriodAllTime,
+		loc:       time.UTC,
+		retention: DefaultRetention,
+		codec:     codec.Default(),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.loc == nil {
+		l.loc = time.UTC
+	}
+	return l
+}
+
+// At returns a view of the board for the period containing t
+// Use it to read or write past and future periods; it has no effect with PeriodAllTime
+func (l *Leaderboard) At(t time.Time) *Leaderboard {
+	cp := *l
+	cp.at = t
+	return &cp
+}
+
+// Previous returns a view of the board for the period before the current one
+func (l *Leaderboard) Previous() *Leaderboard {
+	start, _ := l.period.bounds(l.now(), l.loc)
+	return l.At(start.Add(-time.Nanosecond))
+}
+
+// Period returns the rotation period of the board
+func (l *Leaderboard) Period() Period {
+	return l.period
+}
+
+func (l *Leaderboard) now() time.Time {
+	if !l.at.IsZero() {
+		return l.at
+	}
+	return time.Now()
+}
+
+// Key returns the sorted set key of the current period's board
+func (l *Leaderboard) Key() string {
+	key := l.keyPrefix + l.name
+	if l.period != PeriodAllTime {
+		key += ":" + l.period.id(l.now(), l.loc)
+	}
+	return key
+}
+
+// PayloadKey returns the hash key holding member payloads, shared by all periods
+func (l *Leaderboard) PayloadKey() string {
+	return l.keyPrefix + l.name + ":members"
+}
+
+// ttl returns how long the current board must live to cover the retention window
+// Returns 0 for boards that never expire
+func (l *Leaderboard) ttl() time.Duration {
+	if l.period == PeriodAllTime {
+		return 0
+	}
+	now := l.now()
+	_, end := l.period.bounds(now, l.loc)
+	for i := 0; i < l.retention; i++ {
+		_, end = l.period.bounds(end, l.loc)
+	}
+	return end.Sub(now)
+}
+
+// AddScore sets the score of member, replacing any previous score
+func (l *Leaderboard) AddScore(ctx context.Context, member string, score float64) error {
+	if l.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	key := l.Key()
+	pipe := l.client.Pipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: score, Member: member})
+	if ttl := l.ttl(); ttl > 0 {
+		pipe.PExpire(ctx, key, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to add score: %w", err)
+	}
+	return nil
+}
+
+// IncrScore adds delta to the score of member and returns the new score
+// Members without a score start at 0
+func (l *Leaderboard) IncrScore(ctx context.Context, member string, delta float64) (float64, error) {
+	if l.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	key := l.Key()
+	pipe := l.client.Pipeline()
+	incr := pipe.ZIncrBy(ctx, key, delta, member)
+	if ttl := l.ttl(); ttl > 0 {
+		pipe.PExpire(ctx, key, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to increment score: %w", err)
+	}
+	return incr.Val(), nil
+}
+
+// Score returns the score of member
+// Returns ErrMemberNotFound if the member is not on the board
+func (l *Leaderboard) Score(ctx context.Context, member string) (float64, error) {
+	if l.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	score, err := l.client.ZScore(ctx, l.Key(), member).Result()
+	if err == redis.Nil {
+		return 0, ErrMemberNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get score: %w", err)
+	}
+	return score, nil
+}
+
+// Rank returns the 1-based rank of member
+// Returns ErrMemberNotFound if the member is not on the board
+func (l *Leaderboard) Rank(ctx context.Context, member string) (int64, error) {
+	if l.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	rank, err := l.rank(ctx, member)
+	if err != nil {
+		return 0, err
+	}
+	return rank + 1, nil
+}
+
+// rank returns the 0-based rank of member
+func (l *Leaderboard) rank(ctx context.Context, member string) (int64, error) {
+	var cmd *redis.IntCmd
+	if l.ascending {
+		cmd = l.client.ZRank(ctx, l.Key(), member)
+	} else {
+		cmd = l.client.ZRevRank(ctx, l.Key(), member)
+	}
+	rank, err := cmd.Result()
+	if err == redis.Nil {
+		return 0, ErrMemberNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rank: %w", err)
+	}
+	return rank, nil
+}
+
+// Remove removes members from the board; their payloads are kept
+func (l *Leaderboard) Remove(ctx context.Context, members ...string) error {
+	if l.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, len(members))
+	for i, member := range members {
+		args[i] = member
+	}
+	if err := l.client.ZRem(ctx, l.Key(), args...).Err(); err != nil {
+		return fmt.Errorf("failed to remove members: %w", err)
+	}
+	return nil
+}
+
+// Count returns the number of members on the board
+func (l *Leaderboard) Count(ctx context.Context) (int64, error) {
+	if l.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	n, err := l.client.ZCard(ctx, l.Key()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count members: %w", err)
+	}
+	return n, nil
+}
+
+// Reset deletes the board of the current period; payloads are kept
+func (l *Leaderboard) Reset(ctx context.Context) error {
+	if l.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := l.client.Del(ctx, l.Key()).Err(); err != nil {
+		return fmt.Errorf("failed to reset leaderboard: %w", err)
+	}
+	return nil
+}
+
+// SetPayload stores member data (e.g. display name, avatar) returned with ranked entries
+func (l *Leaderboard) SetPayload(ctx context.Context, member string, payload interface{}) error {
+	if l.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	data, err := l.codec.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode member payload: %w", err)
+	}
+	if err := l.client.HSet(ctx, l.PayloadKey(), member, data).Err(); err != nil {
+		return fmt.Errorf("failed to set member payload: %w", err)
+	}
+	return nil
+}
+
+// DeletePayload removes the stored data of members
+func (l *Leaderboard) DeletePayload(ctx context.Context, members ...string) error {
+	if l.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	if err := l.client.HDel(ctx, l.PayloadKey(), members...).Err(); err != nil {
+		return fmt.Errorf("failed to delete member payload: %w", err)
+	}
+	return nil
+}
+
+// TopN returns the n best ranked entries with their payloads
+func (l *Leaderboard) TopN(ctx context.Context, n int64) ([]Entry, error) {
+	if l.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if n < 1 {
+		return []Entry{}, nil
+	}
+	return l.Range(ctx, 1, n)
+}
+
+// AroundMember returns the entries ranked within radius places of member, including member
+// Returns ErrMemberNotFound if the member is not on the board
+func (l *Leaderboard) AroundMember(ctx context.Context, member string, radius int64) ([]Entry, error) {
+	if l.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if radius < 0 {
+		radius = 0
+	}
+
+	rank, err := l.rank(ctx, member)
+	if err != nil {
+		return nil, err
+	}
+	start := rank - radius
+	if start < 0 {
+		start = 0
+	}
+	return l.Range(ctx, start+1, rank+radius+1)
+}
+
+// Range returns the entries ranked from start to stop (1-based, inclusive) with their payloads
+func (l *Leaderboard) Range(ctx context.Context, start, stop int64) ([]Entry, error) {
+	if l.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if start < 1 {
+		start = 1
+	}
+	if stop < start {
+		return []Entry{}, nil
+	}
+
+	zs, err := l.client.ZRangeArgsWithScores(ctx, redis.ZRangeArgs{
+		Key:   l.Key(),
+		Start: start - 1,
+		Stop:  stop - 1,
+		Rev:   !l.ascending,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get range: %w", err)
+	}
+	if len(zs) == 0 {
+		return []Entry{}, nil
+	}
+
+	entries := make([]Entry, len(zs))
+	members := make([]string, len(zs))
+	for i, z := range zs {
+		member, _ := z.Member.(string)
+		members[i] = member
+		entries[i] = Entry{Member: member, Score: z.Score, Rank: start + int64(i), codec: l.codec}
+	}
+
+	payloads, err := l.client.HMGet(ctx, l.PayloadKey(), members...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member payloads: %w", err)
+	}
+	for i, p := range payloads {
+		if s, ok := p.(string); ok {
+			entries[i].Payload = []byte(s)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,207 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+type player struct {
+	Name string `json:"name"`
+}
+
+func TestNewLeaderboard(t *testing.T) {
+	l := NewLeaderboard(nil, "game")
+	if l.Key() != "leaderboard:game" {
+		t.Errorf("Key() = %q, want %q", l.Key(), "leaderboard:game")
+	}
+	if l.PayloadKey() != "leaderboard:game:members" {
+		t.Errorf("PayloadKey() = %q, want %q", l.PayloadKey(), "leaderboard:game:members")
+	}
+	if l.ttl() != 0 {
+		t.Errorf("ttl() = %v, want 0 for all-time boards", l.ttl())
+	}
+
+	ts := time.Date(2024, 5, 17, 12, 0, 0, 0, time.UTC)
+	l = NewLeaderboard(nil, "game", WithKeyPrefix("lb:"), WithPeriod(PeriodDaily), WithLocation(nil)).At(ts)
+	if l.Key() != "lb:game:2024-05-17" {
+		t.Errorf("Key() = %q, want %q", l.Key(), "lb:game:2024-05-17")
+	}
+	if l.Previous().Key() != "lb:game:2024-05-16" {
+		t.Errorf("Previous().Key() = %q, want %q", l.Previous().Key(), "lb:game:2024-05-16")
+	}
+	// 12h left today plus one retained day
+	if l.ttl() != 36*time.Hour {
+		t.Errorf("ttl() = %v, want 36h", l.ttl())
+	}
+	if ttl := NewLeaderboard(nil, "game", WithPeriod(PeriodDaily), WithRetention(0)).At(ts).ttl(); ttl != 12*time.Hour {
+		t.Errorf("ttl() with no retention = %v, want 12h", ttl)
+	}
+}
+
+func TestLeaderboard_Scores(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	l := NewLeaderboard(client, "game")
+
+	for member, score := range map[string]float64{"alice": 30, "bob": 10, "carol": 20} {
+		if err := l.AddScore(ctx, member, score); err != nil {
+			t.Fatalf("AddScore() error = %v, want nil", err)
+		}
+	}
+	if score, err := l.IncrScore(ctx, "bob", 25); err != nil || score != 35 {
+		t.Errorf("IncrScore() = %v, %v, want 35", score, err)
+	}
+	if score, err := l.Score(ctx, "carol"); err != nil || score != 20 {
+		t.Errorf("Score() = %v, %v, want 20", score, err)
+	}
+	if rank, err := l.Rank(ctx, "bob"); err != nil || rank != 1 {
+		t.Errorf("Rank(bob) = %d, %v, want 1", rank, err)
+	}
+	if _, err := l.Rank(ctx, "dave"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("Rank(dave) error = %v, want ErrMemberNotFound", err)
+	}
+	if _, err := l.Score(ctx, "dave"); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("Score(dave) error = %v, want ErrMemberNotFound", err)
+	}
+
+	asc := NewLeaderboard(client, "game", WithAscending())
+	if rank, err := asc.Rank(ctx, "carol"); err != nil || rank != 1 {
+		t.Errorf("ascending Rank(carol) = %d, %v, want 1", rank, err)
+	}
+
+	if err := l.Remove(ctx, "carol"); err != nil {
+		t.Fatalf("Remove() error = %v, want nil", err)
+	}
+	if n, err := l.Count(ctx); err != nil || n != 2 {
+		t.Errorf("Count() = %d, %v, want 2", n, err)
+	}
+	if err := l.Reset(ctx); err != nil {
+		t.Fatalf("Reset() error = %v, want nil", err)
+	}
+	if n, _ := l.Count(ctx); n != 0 {
+		t.Errorf("Count() after Reset = %d, want 0", n)
+	}
+}
+
+func TestLeaderboard_TopNAndAround(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	l := NewLeaderboard(client, "game")
+
+	members := []string{"a", "b", "c", "d", "e", "f"}
+	for i, member := range members {
+		if err := l.AddScore(ctx, member, float64(100-i*10)); err != nil {
+			t.Fatalf("AddScore() error = %v, want nil", err)
+		}
+	}
+	if err := l.SetPayload(ctx, "a", player{Name: "Alice"}); err != nil {
+		t.Fatalf("SetPayload() error = %v, want nil", err)
+	}
+
+	top, err := l.TopN(ctx, 3)
+	if err != nil || len(top) != 3 {
+		t.Fatalf("TopN() = %v, %v, want 3 entries", top, err)
+	}
+	for i, e := range top {
+		if e.Member != members[i] || e.Rank != int64(i+1) || e.Score != float64(100-i*10) {
+			t.Errorf("TopN()[%d] = %+v, want %s at rank %d", i, e, members[i], i+1)
+		}
+	}
+	var p player
+	if err := top[0].Decode(&p); err != nil || p.Name != "Alice" {
+		t.Errorf("Decode() = %+v, %v, want Alice", p, err)
+	}
+	if err := top[1].Decode(&p); !errors.Is(err, ErrNoPayload) {
+		t.Errorf("Decode() without payload error = %v, want ErrNoPayload", err)
+	}
+
+	around, err := l.AroundMember(ctx, "b", 2)
+	if err != nil || len(around) != 4 {
+		t.Fatalf("AroundMember(b, 2) = %v, %v, want 4 entries", around, err)
+	}
+	if around[0].Member != "a" || around[0].Rank != 1 || around[3].Member != "d" || around[3].Rank != 4 {
+		t.Errorf("AroundMember(b, 2) = %+v, want a..d", around)
+	}
+	around, err = l.AroundMember(ctx, "f", 1)
+	if err != nil || len(around) != 2 || around[0].Member != "e" || around[1].Rank != 6 {
+		t.Errorf("AroundMember(f, 1) = %+v, %v, want e, f", around, err)
+	}
+	if _, err := l.AroundMember(ctx, "z", 1); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("AroundMember(z) error = %v, want ErrMemberNotFound", err)
+	}
+
+	if err := l.DeletePayload(ctx, "a"); err != nil {
+		t.Fatalf("DeletePayload() error = %v, want nil", err)
+	}
+	if top, _ := l.TopN(ctx, 1); len(top) != 1 || top[0].Payload != nil {
+		t.Errorf("TopN() after DeletePayload = %+v, want no payload", top)
+	}
+	if top, err := l.TopN(ctx, 0); err != nil || len(top) != 0 {
+		t.Errorf("TopN(0) = %v, %v, want empty", top, err)
+	}
+}
+
+func TestLeaderboard_Rotation(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	monday := time.Date(2024, 5, 13, 9, 0, 0, 0, time.UTC)
+	l := NewLeaderboard(client, "weekly", WithPeriod(PeriodWeekly))
+
+	if err := l.At(monday).AddScore(ctx, "alice", 5); err != nil {
+		t.Fatalf("AddScore() error = %v, want nil", err)
+	}
+	next := l.At(monday.AddDate(0, 0, 7))
+	if _, err := next.IncrScore(ctx, "bob", 3); err != nil {
+		t.Fatalf("IncrScore() error = %v, want nil", err)
+	}
+
+	if n, _ := next.Count(ctx); n != 1 {
+		t.Errorf("Count() of the new week = %d, want 1", n)
+	}
+	if _, err := next.Previous().Score(ctx, "alice"); err != nil {
+		t.Errorf("Previous().Score(alice) error = %v, want nil", err)
+	}
+	if ttl := client.PTTL(ctx, next.Key()).Val(); ttl <= 0 {
+		t.Errorf("PTTL() of a periodic board = %v, want > 0", ttl)
+	}
+}
+
+func TestLeaderboard_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	l := NewLeaderboard(nil, "game")
+	if err := l.AddScore(ctx, "a", 1); err == nil {
+		t.Error("AddScore() with nil client error = nil, want error")
+	}
+	if _, err := l.TopN(ctx, 1); err == nil {
+		t.Error("TopN() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	mock.SetShouldFail(true)
+
+	l = NewLeaderboard(client, "game")
+	if _, err := l.IncrScore(ctx, "a", 1); err == nil {
+		t.Error("IncrScore() error = nil, want error")
+	}
+	if _, err := l.Rank(ctx, "a"); err == nil || errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("Rank() error = %v, want redis error", err)
+	}
+	if _, err := l.Range(ctx, 1, 10); err == nil {
+		t.Error("Range() error = nil, want error")
+	}
+	if err := l.SetPayload(ctx, "a", player{}); err == nil {
+		t.Error("SetPayload() error = nil, want error")
+	}
+}
@@ -0,0 +1,56 @@
+package leaderboard
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// Option configures a Leaderboard
+type Option func(*Leaderboard)
+
+// WithKeyPrefix sets the prefix of the leaderboard keys (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(l *Leaderboard) {
+		l.keyPrefix = prefix
+	}
+}
+
+// WithPeriod makes the board start over every period (default: PeriodAllTime)
+func WithPeriod(p Period) Option {
+	return func(l *Leaderboard) {
+		l.period = p
+	}
+}
+
+// WithLocation sets the time zone used for period boundaries (default: UTC)
+func WithLocation(loc *time.Location) Option {
+	return func(l *Leaderboard) {
+		l.loc = loc
+	}
+}
+
+// WithRetention sets how many past periods stay readable before their boards expire (default: 1)
+func WithRetention(periods int) Option {
+	return func(l *Leaderboard) {
+		if periods >= 0 {
+			l.retention = periods
+		}
+	}
+}
+
+// WithAscending ranks lower scores first, e.g. for fastest times (default: highest first)
+func WithAscending() Option {
+	return func(l *Leaderboard) {
+		l.ascending = true
+	}
+}
+
+// WithCodec sets the codec used for member payloads (default: codec.Default())
+func WithCodec(c codec.Codec) Option {
+	return func(l *Leaderboard) {
+		if c != nil {
+			l.codec = c
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package leaderboard
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// Period controls how often a leaderboard starts over
+type Period int
+
+const (
+	// PeriodAllTime keeps a single board forever
+	PeriodAllTime Period = iota
+	// PeriodDaily starts a new board every calendar day
+	PeriodDaily
+	// PeriodWeekly starts a new board every ISO week (Monday to Sunday)
+	PeriodWeekly
+	// PeriodMonthly starts a new board every calendar month
+	PeriodMonthly
+)
+
+// String returns the name of the period
+func (p Period) String() string {
+	switch p {
+	case PeriodDaily:
+		return "daily"
+	case PeriodWeekly:
+		return "weekly"
+	case PeriodMonthly:
+		return "monthly"
+	default:
+		return "alltime"
+	}
+}
+
+// bounds returns the start of the period containing t and the start of the next one
+func (p Period) bounds(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	day := utils.DayStart(t, loc)
+	switch p {
+	case PeriodDaily:
+		return day, utils.NextDayStart(t, loc)
+	case PeriodWeekly:
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case PeriodMonthly:
+		start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+		return start, start.AddDate(0, 1, 0)
+	default:
+		return time.Time{}, time.Time{}
+	}
+}
+
+// id returns the key suffix of the period containing t, e.g. "2024-05-17", "2024-W20", or "2024-05"
+func (p Period) id(t time.Time, loc *time.Location) string {
+	start, _ := p.bounds(t, loc)
+	switch p {
+	case PeriodDaily:
+		return start.Format("2006-01-02")
+	case PeriodWeekly:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case PeriodMonthly:
+		return start.Format("2006-01")
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,46 @@
+package leaderboard
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriod_ID(t *testing.T) {
+	ts := time.Date(2024, 5, 19, 23, 30, 0, 0, time.UTC) // Sunday
+	tests := []struct {
+		period Period
+		want   string
+	}{
+		{PeriodAllTime, ""},
+		{PeriodDaily, "2024-05-19"},
+		{PeriodWeekly, "2024-W20"},
+		{PeriodMonthly, "2024-05"},
+	}
+	for _, tt := range tests {
+		if got := tt.period.id(ts, time.UTC); got != tt.want {
+			t.Errorf("%s.id() = %q, want %q", tt.period, got, tt.want)
+		}
+	}
+
+	// The same instant falls on Monday in a zone ahead of UTC
+	tokyo := time.FixedZone("JST", 9*3600)
+	if got := PeriodWeekly.id(ts, tokyo); got != "2024-W21" {
+		t.Errorf("weekly id in JST = %q, want %q", got, "2024-W21")
+	}
+	// ISO week years differ from calendar years around the new year
+	if got := PeriodWeekly.id(time.Date(2024, 12, 30, 12, 0, 0, 0, time.UTC), time.UTC); got != "2025-W01" {
+		t.Errorf("weekly id for 2024-12-30 = %q, want %q", got, "2025-W01")
+	}
+}
+
+func TestPeriod_Bounds(t *testing.T) {
+	ts := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC) // Wednesday
+	start, end := PeriodWeekly.bounds(ts, time.UTC)
+	if !start.Equal(time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("weekly bounds = %v, %v, want 2024-05-13, 2024-05-20", start, end)
+	}
+	start, end = PeriodMonthly.bounds(ts, time.UTC)
+	if !start.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("monthly bounds = %v, %v, want 2024-05-01, 2024-06-01", start, end)
+	}
+}
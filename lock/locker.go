@@ -6,10 +6,14 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/utils"
 )
 
 const (
@@ -22,22 +26,32 @@ const (
 
 // RedisLocker provides Redis-based distributed lock functionality
 type RedisLocker struct {
-	client    *redis.Client
-	lockTime  time.Duration
-	lockStore sync.Map // Stores key -> lockValue mapping
+	client      redis.UniversalClient
+	lockTime    time.Duration
+	keyPrefix   string
+	retryPolicy utils.RetryPolicy
+	recorder    metrics.Recorder
+	logger      *slog.Logger
+	lockStore   sync.Map // Stores key -> lockValue mapping
 }
 
 // NewRedisLocker creates a new Redis-based distributed locker
-func NewRedisLocker(client *redis.Client) *RedisLocker {
-	return NewRedisLockerWithLockTime(client, DefaultLockTime)
+// client may be a single-node, cluster, sentinel, or ring client
+func NewRedisLocker(client redis.UniversalClient, opts ...Option) *RedisLocker {
+	return NewRedisLockerWithLockTime(client, DefaultLockTime, opts...)
 }
 
 // NewRedisLockerWithLockTime creates a new Redis-based distributed locker with custom lock time
-func NewRedisLockerWithLockTime(client *redis.Client, lockTime time.Duration) *RedisLocker {
-	return &RedisLocker{
-		client:   client,
-		lockTime: lockTime,
+func NewRedisLockerWithLockTime(client redis.UniversalClient, lockTime time.Duration, opts ...Option) *RedisLocker {
+	r := &RedisLocker{
+		client:      utils.NormalizeClient(client),
+		lockTime:    lockTime,
+		retryPolicy: utils.NoRetry(),
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // generateLockValue generates a unique lock value
@@ -52,6 +66,20 @@ func generateLockValue() (string, error) {
 // Lock acquires a distributed lock using Redis SETNX
 // Returns true if the lock was successfully acquired, false if the lock is already held
 func (r *RedisLocker) Lock(key string) (bool, error) {
+	start := time.Now()
+	acquired, err := r.lock(key)
+	outcome := metrics.OutcomeBusy
+	switch {
+	case err != nil:
+		outcome = metrics.OutcomeError
+	case acquired:
+		outcome = metrics.OutcomeAcquired
+	}
+	metrics.Observe(r.recorder, metrics.SubsystemLock, "lock", outcome, start)
+	return acquired, err
+}
+
+func (r *RedisLocker) lock(key string) (bool, error) {
 	if r.client == nil {
 		return false, fmt.Errorf("redis client is nil")
 	}
@@ -64,7 +92,26 @@ func (r *RedisLocker) Lock(key string) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), DefaultOperationTimeout)
 	defer cancel()
 
-	res, err := r.client.SetNX(ctx, key, lockValue, r.lockTime).Result()
+	redisKey := utils.BuildKey(r.keyPrefix, key)
+	var res bool
+	attempt := 0
+	err = utils.Retry(ctx, r.retryPolicy, func(ctx context.Context) error {
+		attempt++
+		acquired, setErr := r.client.SetNX(ctx, redisKey, lockValue, r.lockTime).Result()
+		if setErr != nil {
+			return setErr
+		}
+		if !acquired && attempt > 1 {
+			// An earlier attempt may have set the key before its reply was lost
+			current, getErr := r.client.Get(ctx, redisKey).Result()
+			if getErr != nil && !errors.Is(getErr, redis.Nil) {
+				return getErr
+			}
+			acquired = current == lockValue
+		}
+		res = acquired
+		return nil
+	})
 	if err != nil {
 		return false, fmt.Errorf("failed to acquire lock: %w", err)
 	}
@@ -80,6 +127,13 @@ func (r *RedisLocker) Lock(key string) (bool, error) {
 // Unlock releases a distributed lock using a Lua script to ensure atomicity
 // Only releases the lock if the lock value matches, preventing accidental release of another process's lock
 func (r *RedisLocker) Unlock(key string) error {
+	start := time.Now()
+	err := r.unlock(key)
+	metrics.Observe(r.recorder, metrics.SubsystemLock, "unlock", metrics.Outcome(err), start)
+	return err
+}
+
+func (r *RedisLocker) unlock(key string) error {
 	if r.client == nil {
 		return fmt.Errorf("redis client is nil")
 	}
@@ -106,7 +160,12 @@ func (r *RedisLocker) Unlock(key string) error {
 			return 0
 		end
 	`
-	result, err := r.client.Eval(ctx, script, []string{key}, lockValue).Result()
+	var result interface{}
+	err := utils.Retry(ctx, r.retryPolicy, func(ctx context.Context) error {
+		var evalErr error
+		result, evalErr = r.client.Eval(ctx, script, []string{utils.BuildKey(r.keyPrefix, key)}, lockValue).Result()
+		return evalErr
+	})
 	if err != nil {
 		return fmt.Errorf("failed to release lock: %w", err)
 	}
@@ -124,17 +183,21 @@ func (r *RedisLocker) Unlock(key string) error {
 type HybridLocker struct {
 	redisLocker *RedisLocker
 	localLocker *LocalLocker
+	fallback    atomic.Bool // use the local lock without trying Redis
 }
 
 // NewHybridLocker creates a new hybrid locker that supports both Redis and local locking
-// If client is nil, it will only use local locking
-func NewHybridLocker(client *redis.Client) *HybridLocker {
+// If client is nil, it will only use local locking; while a client.ResilientClient reports
+// Redis as degraded, it uses the local lock as with SetFallback
+// Options apply to the underlying RedisLocker; with a retry policy, transient Redis
+// failures are retried before falling back to the local lock
+func NewHybridLocker(client redis.UniversalClient, opts ...Option) *HybridLocker {
 	hl := &HybridLocker{
 		localLocker: NewLocalLocker(),
 	}
 
-	if client != nil {
-		hl.redisLocker = NewRedisLocker(client)
+	if client = utils.NormalizeClient(client); client != nil {
+		hl.redisLocker = NewRedisLocker(client, opts...)
 	}
 
 	return hl
@@ -143,20 +206,51 @@ func NewHybridLocker(client *redis.Client) *HybridLocker {
 // Lock acquires a lock, trying Redis first and falling back to local lock if Redis fails
 func (h *HybridLocker) Lock(key string) (bool, error) {
 	// Try Redis first if available
-	if h.redisLocker != nil {
+	if h.redisLocker != nil && !h.localOnly() {
 		success, err := h.redisLocker.Lock(key)
 		if err == nil {
 			return success, nil
 		}
 		// If Redis fails, fall back to local lock
+		h.logFallback("redis-kit lock falling back to local lock", key, err)
 	}
 
 	// Fall back to local lock
 	return h.localLocker.Lock(key)
 }
 
+// SetFallback makes Lock use the local lock without trying Redis while active, e.g. from
+// client.ConnectionWatcher callbacks, so calls do not wait for Redis to fail while it is down
+// Unlock releases locks held locally without trying Redis too
+func (h *HybridLocker) SetFallback(active bool) {
+	if h.fallback.Swap(active) == active || h.redisLocker == nil || h.redisLocker.logger == nil {
+		return
+	}
+	if active {
+		h.redisLocker.logger.Warn("redis-kit lock using the local lock only")
+	} else {
+		h.redisLocker.logger.Info("redis-kit lock using redis again")
+	}
+}
+
+// Fallback reports whether SetFallback made the locker use the local lock only
+func (h *HybridLocker) Fallback() bool {
+	return h.fallback.Load()
+}
+
+// localOnly reports whether Lock skips Redis, because of SetFallback or because the client
+// reports Redis as degraded
+func (h *HybridLocker) localOnly() bool {
+	return h.fallback.Load() || (h.redisLocker != nil && utils.Degraded(h.redisLocker.client))
+}
+
 // Unlock releases a lock, trying Redis first and falling back to local lock if Redis fails
 func (h *HybridLocker) Unlock(key string) error {
+	// Locks taken during a fallback are local
+	if h.localOnly() && h.localLocker.Unlock(key) == nil {
+		return nil
+	}
+
 	// Try Redis first if available
 	if h.redisLocker != nil {
 		// Check if this key was locked via Redis by checking if it exists in lockStore
@@ -173,6 +267,7 @@ func (h *HybridLocker) Unlock(key string) error {
 		}
 		// For other errors (e.g., connection failures), try local unlock
 		if localErr := h.localLocker.Unlock(key); localErr == nil {
+			h.logFallback("redis-kit unlock fell back to local lock", key, err)
 			return nil
 		}
 		return err
@@ -181,3 +276,10 @@ func (h *HybridLocker) Unlock(key string) error {
 	// Fall back to local lock
 	return h.localLocker.Unlock(key)
 }
+
+// logFallback logs a fallback to the local lock caused by err to the RedisLocker's logger
+func (h *HybridLocker) logFallback(msg, key string, err error) {
+	if logger := h.redisLocker.logger; logger != nil {
+		logger.Warn(msg, "key", key, "error", err)
+	}
+}
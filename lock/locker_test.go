@@ -1,6 +1,7 @@
 package lock
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"testing"
@@ -40,6 +41,31 @@ func TestNewRedisLockerWithLockTime(t *testing.T) {
 	}
 }
 
+func TestNewRedisLocker_UniversalClient(t *testing.T) {
+	ring, _ := testutil.NewMockRingClient()
+	defer func() { _ = ring.Close() }()
+
+	locker := NewRedisLocker(ring)
+	if ok, err := locker.Lock("job"); err != nil || !ok {
+		t.Fatalf("Lock() through ring = %v, %v, want true", ok, err)
+	}
+	if ok, _ := NewRedisLocker(ring).Lock("job"); ok {
+		t.Error("second Lock() through ring = true, want false")
+	}
+	if err := locker.Unlock("job"); err != nil {
+		t.Errorf("Unlock() through ring error = %v, want nil", err)
+	}
+
+	// A nil *redis.Client counts as no client
+	var client *redis.Client
+	if _, err := NewRedisLocker(client).Lock("job"); err == nil {
+		t.Error("Lock() with nil *redis.Client error = nil, want error")
+	}
+	if h := NewHybridLocker(client); h.redisLocker != nil {
+		t.Error("NewHybridLocker() with nil *redis.Client should use only local locking")
+	}
+}
+
 func TestGenerateLockValue(t *testing.T) {
 	// Test that generateLockValue creates unique values
 	values := make(map[string]bool)
@@ -450,6 +476,64 @@ func TestHybridLocker(t *testing.T) {
 		}
 	})
 
+	t.Run("hybrid fallback skips Redis", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		locker := NewHybridLocker(client)
+		locker.SetFallback(true)
+		if !locker.Fallback() {
+			t.Fatal("Fallback() = false after SetFallback(true)")
+		}
+		if ok, err := locker.Lock("fallback-key"); err != nil || !ok {
+			t.Fatalf("HybridLocker.Lock() in fallback = %v, %v, want true", ok, err)
+		}
+		if client.Exists(context.Background(), "fallback-key").Val() == 1 {
+			t.Error("HybridLocker.Lock() in fallback wrote to Redis")
+		}
+		if ok, _ := locker.Lock("fallback-key"); ok {
+			t.Error("HybridLocker.Lock() in fallback acquired a held local lock")
+		}
+		if err := locker.Unlock("fallback-key"); err != nil {
+			t.Errorf("HybridLocker.Unlock() in fallback error = %v, want nil", err)
+		}
+
+		// Back to Redis, a lock taken before the switch is released there
+		locker.SetFallback(false)
+		if ok, err := locker.Lock("redis-key"); err != nil || !ok || !(client.Exists(context.Background(), "redis-key").Val() == 1) {
+			t.Fatalf("HybridLocker.Lock() = %v, %v, want a Redis lock", ok, err)
+		}
+		locker.SetFallback(true)
+		if err := locker.Unlock("redis-key"); err != nil || (client.Exists(context.Background(), "redis-key").Val() == 1) {
+			t.Errorf("HybridLocker.Unlock() of a Redis lock in fallback = %v, want it released", err)
+		}
+	})
+
+	t.Run("hybrid uses the local lock while the client is degraded", func(t *testing.T) {
+		mockClient, _ := testutil.NewMockRedisClient()
+		defer func() { _ = mockClient.Close() }()
+		client := &degradedClient{UniversalClient: mockClient, degraded: true}
+
+		locker := NewHybridLocker(client)
+		if ok, err := locker.Lock("degraded-key"); err != nil || !ok {
+			t.Fatalf("HybridLocker.Lock() while degraded = %v, %v, want true", ok, err)
+		}
+		if mockClient.Exists(context.Background(), "degraded-key").Val() == 1 {
+			t.Error("HybridLocker.Lock() while degraded wrote to Redis")
+		}
+		if ok, _ := locker.Lock("degraded-key"); ok {
+			t.Error("HybridLocker.Lock() while degraded acquired a held local lock")
+		}
+		if err := locker.Unlock("degraded-key"); err != nil {
+			t.Errorf("HybridLocker.Unlock() while degraded error = %v, want nil", err)
+		}
+
+		client.degraded = false
+		if ok, err := locker.Lock("degraded-key"); err != nil || !ok || mockClient.Exists(context.Background(), "degraded-key").Val() != 1 {
+			t.Errorf("HybridLocker.Lock() after recovery = %v, %v, want a Redis lock", ok, err)
+		}
+	})
+
 	t.Run("hybrid unlock returns error on lock value mismatch without fallback", func(t *testing.T) {
 		client, _ := testutil.NewMockRedisClient()
 		defer func() { _ = client.Close() }()
@@ -558,3 +642,11 @@ func TestRedisLocker_Concurrent(t *testing.T) {
 		}
 	})
 }
+
+// degradedClient reports a settable degraded state, as client.ResilientClient does
+type degradedClient struct {
+	redis.UniversalClient
+	degraded bool
+}
+
+func (c *degradedClient) Degraded() bool { return c.degraded }
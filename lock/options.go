@@ -0,0 +1,41 @@
+package lock
+
+import (
+	"log/slog"
+
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// Option configures a RedisLocker
+type Option func(*RedisLocker)
+
+// WithKeyPrefix sets the prefix prepended to lock keys in Redis (default: none)
+func WithKeyPrefix(prefix string) Option {
+	return func(r *RedisLocker) {
+		r.keyPrefix = prefix
+	}
+}
+
+// WithRetryPolicy sets the retry policy applied to Redis lock operations (default: no retry)
+// A retried Lock recognizes a lock set by an attempt whose reply was lost, by its value
+func WithRetryPolicy(policy utils.RetryPolicy) Option {
+	return func(r *RedisLocker) {
+		r.retryPolicy = policy
+	}
+}
+
+// WithRecorder sets the recorder lock operations are reported to (default: metrics.Default())
+func WithRecorder(r metrics.Recorder) Option {
+	return func(rl *RedisLocker) {
+		rl.recorder = r
+	}
+}
+
+// WithLogger sets the logger HybridLocker reports its fallbacks to the local lock to
+// (default: nil, no logging); pass the client's logger to keep them next to its command log
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *RedisLocker) {
+		r.logger = logger
+	}
+}
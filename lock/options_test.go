@@ -0,0 +1,219 @@
+package lock
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+func TestWithRetryPolicy(t *testing.T) {
+	t.Run("default is no retry", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		locker := NewRedisLocker(client)
+		if locker.retryPolicy.MaxAttempts != 1 {
+			t.Errorf("NewRedisLocker() retryPolicy.MaxAttempts = %d, want 1", locker.retryPolicy.MaxAttempts)
+		}
+	})
+
+	t.Run("retries transient lock failure", func(t *testing.T) {
+		client, mock := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		calls := 0
+		policy := utils.NoRetry().
+			WithMaxAttempts(3).
+			WithBackoff(time.Millisecond, time.Millisecond).
+			WithClassifier(func(error) bool {
+				calls++
+				mock.SetShouldFail(false)
+				return true
+			})
+		locker := NewRedisLocker(client, WithRetryPolicy(policy))
+
+		mock.SetShouldFail(true)
+		success, err := locker.Lock("retry-lock")
+		if err != nil {
+			t.Fatalf("Lock() error = %v, want nil", err)
+		}
+		if !success {
+			t.Error("Lock() = false, want true")
+		}
+		if calls != 1 {
+			t.Errorf("classifier calls = %d, want 1", calls)
+		}
+
+		mock.SetShouldFail(true)
+		if err := locker.Unlock("retry-lock"); err != nil {
+			t.Errorf("Unlock() error = %v, want nil", err)
+		}
+		if calls != 2 {
+			t.Errorf("classifier calls = %d, want 2", calls)
+		}
+	})
+
+	t.Run("recognizes a lock set before its reply was lost", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+		hook := &lostSetReply{}
+		hook.failures.Store(1)
+		client.AddHook(hook)
+
+		policy := utils.DefaultRetryPolicy().WithBackoff(time.Millisecond, time.Millisecond)
+		locker := NewRedisLocker(client, WithRetryPolicy(policy))
+		success, err := locker.Lock("lost-reply")
+		if err != nil || !success {
+			t.Fatalf("Lock() = %v, %v, want the lock set by the first attempt", success, err)
+		}
+		if err := locker.Unlock("lost-reply"); err != nil {
+			t.Errorf("Unlock() error = %v, want nil", err)
+		}
+
+		// A lock held by someone else is still reported as held
+		other := NewRedisLocker(client)
+		if ok, _ := other.Lock("lost-reply"); !ok {
+			t.Fatal("Lock() by another locker failed")
+		}
+		hook.failures.Store(1)
+		if success, err := locker.Lock("lost-reply"); err != nil || success {
+			t.Errorf("Lock() on a held lock = %v, %v, want false", success, err)
+		}
+	})
+
+	t.Run("hybrid locker passes options", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		locker := NewHybridLocker(client, WithRetryPolicy(utils.DefaultRetryPolicy()))
+		if locker.redisLocker.retryPolicy.MaxAttempts != 3 {
+			t.Errorf("NewHybridLocker() retryPolicy.MaxAttempts = %d, want 3", locker.redisLocker.retryPolicy.MaxAttempts)
+		}
+	})
+}
+
+// lostSetReply runs the next SET commands but loses their replies
+type lostSetReply struct {
+	failures atomic.Int64
+}
+
+func (h *lostSetReply) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *lostSetReply) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() != "set" || h.failures.Add(-1) < 0 {
+			return next(ctx, cmd)
+		}
+		_ = next(ctx, cmd)
+		cmd.SetErr(io.EOF)
+		return io.EOF
+	}
+}
+
+func (h *lostSetReply) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestWithRecorder(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	rec := testutil.NewRecorder()
+	locker := NewRedisLocker(client, WithRecorder(rec))
+	other := NewRedisLocker(client, WithRecorder(rec))
+
+	_, _ = locker.Lock("job")
+	_, _ = other.Lock("job")
+	_ = locker.Unlock("job")
+	_ = locker.Unlock("job")
+
+	for _, tt := range []struct{ operation, outcome string }{
+		{"lock", metrics.OutcomeAcquired},
+		{"lock", metrics.OutcomeBusy},
+		{"unlock", metrics.OutcomeSuccess},
+		{"unlock", metrics.OutcomeError},
+	} {
+		if n := rec.Count(metrics.SubsystemLock, tt.operation, tt.outcome); n != 1 {
+			t.Errorf("Count(%s, %s) = %d, want 1", tt.operation, tt.outcome, n)
+		}
+	}
+}
+
+func TestWithKeyPrefix(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	locker := NewRedisLocker(client, WithKeyPrefix("app:lock:"))
+	if ok, err := locker.Lock("job"); !ok || err != nil {
+		t.Fatalf("Lock() = %v, %v, want true", ok, err)
+	}
+	if n := client.Exists(ctx, "app:lock:job").Val(); n != 1 {
+		t.Error("Lock() did not store the prefixed key")
+	}
+	if n := client.Exists(ctx, "job").Val(); n != 0 {
+		t.Error("Lock() stored the unprefixed key")
+	}
+
+	// Lockers with other prefixes do not contend
+	if ok, _ := NewRedisLocker(client).Lock("job"); !ok {
+		t.Error("Lock() without prefix = false, want true")
+	}
+	if err := locker.Unlock("job"); err != nil {
+		t.Errorf("Unlock() error = %v, want nil", err)
+	}
+	if n := client.Exists(ctx, "app:lock:job").Val(); n != 0 {
+		t.Error("Unlock() left the prefixed key")
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	var buf bytes.Buffer
+	locker := NewHybridLocker(client, WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	if ok, err := locker.Lock("ok"); err != nil || !ok {
+		t.Fatalf("Lock() = %v, %v, want true", ok, err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Redis lock logged %q", buf.String())
+	}
+
+	mock.SetShouldFail(true)
+	if ok, err := locker.Lock("job:1"); err != nil || !ok {
+		t.Fatalf("Lock() with Redis failing = %v, %v, want the local lock", ok, err)
+	}
+	if err := locker.Unlock("job:1"); err != nil {
+		t.Fatalf("Unlock() with Redis failing error = %v, want nil", err)
+	}
+	locker.SetFallback(true)
+	locker.SetFallback(true)
+	locker.SetFallback(false)
+
+	out := buf.String()
+	for _, want := range []string{
+		"redis-kit lock falling back to local lock",
+		"redis-kit unlock fell back to local lock",
+		"key=job:1",
+		"level=WARN msg=\"redis-kit lock using the local lock only\"",
+		"level=INFO msg=\"redis-kit lock using redis again\"",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("fallbacks logged %q, want it to contain %s", out, want)
+		}
+	}
+	if n := strings.Count(out, "local lock only"); n != 1 {
+		t.Errorf("SetFallback(true) twice logged %d times, want once", n)
+	}
+}
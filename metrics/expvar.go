@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// DefaultExpvarName is the expvar variable an ExpvarRecorder publishes by default
+const DefaultExpvarName = "redis_kit"
+
+// expvarMu serializes lookups and publishing, since expvar panics on duplicate names
+var expvarMu sync.Mutex
+
+// ExpvarRecorder publishes operation counts and total durations through expvar,
+// so they show up on /debug/vars
+//
+// The published map holds "subsystem.operation.outcome.count" and
+// "subsystem.operation.outcome.seconds" (the summed duration) for every series
+type ExpvarRecorder struct {
+	vars *expvar.Map
+}
+
+// NewExpvarRecorder publishes a map under name (default: DefaultExpvarName)
+// Recorders created with the same name share the map, as expvar names are global;
+// like expvar.NewMap, it panics if name is taken by a variable that is not a map
+func NewExpvarRecorder(name string) *ExpvarRecorder {
+	if name == "" {
+		name = DefaultExpvarName
+	}
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+
+	vars, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		vars = expvar.NewMap(name)
+	}
+	return &ExpvarRecorder{vars: vars}
+}
+
+// Map returns the published expvar map
+func (e *ExpvarRecorder) Map() *expvar.Map {
+	return e.vars
+}
+
+// ObserveOperation implements Recorder
+func (e *ExpvarRecorder) ObserveOperation(subsystem, operation, outcome string, duration time.Duration) {
+	key := subsystem + "." + operation + "." + outcome
+	e.vars.Add(key+".count", 1)
+	e.vars.AddFloat(key+".seconds", duration.Seconds())
+}
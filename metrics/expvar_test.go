@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestExpvarRecorder(t *testing.T) {
+	e := NewExpvarRecorder("redis_kit_test")
+	e.ObserveOperation(SubsystemCache, "get", OutcomeMiss, 2*time.Second)
+	e.ObserveOperation(SubsystemCache, "get", OutcomeMiss, time.Second)
+
+	if expvar.Get("redis_kit_test") != e.Map() {
+		t.Fatal("NewExpvarRecorder() did not publish its map")
+	}
+	if got := e.Map().Get("cache.get.miss.count").(*expvar.Int).Value(); got != 2 {
+		t.Errorf("count = %d, want 2", got)
+	}
+	if got := e.Map().Get("cache.get.miss.seconds").(*expvar.Float).Value(); got != 3 {
+		t.Errorf("seconds = %v, want 3", got)
+	}
+
+	// A second recorder with the same name shares the published map
+	again := NewExpvarRecorder("redis_kit_test")
+	again.ObserveOperation(SubsystemCache, "get", OutcomeMiss, 0)
+	if got := e.Map().Get("cache.get.miss.count").(*expvar.Int).Value(); got != 3 {
+		t.Errorf("count after second recorder = %d, want 3", got)
+	}
+}
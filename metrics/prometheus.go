@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultNamespace prefixes the metric names of a PrometheusRecorder
+const DefaultNamespace = "redis_kit"
+
+// DefaultBuckets are the latency histogram buckets in seconds, from 0.5ms to 5s
+var DefaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+type seriesKey struct {
+	subsystem, operation, outcome string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// PrometheusRecorder keeps operation latency histograms and serves them in the
+// Prometheus text exposition format, without depending on the Prometheus client library
+//
+// It exports <namespace>_operation_duration_seconds with the subsystem, operation, and
+// outcome labels; the histogram's _count series doubles as the operation counter
+type PrometheusRecorder struct {
+	name    string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[seriesKey]*histogram
+}
+
+// NewPrometheusRecorder creates a recorder exporting metrics under namespace
+// An empty namespace uses DefaultNamespace and nil buckets use DefaultBuckets
+func NewPrometheusRecorder(namespace string, buckets []float64) *PrometheusRecorder {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &PrometheusRecorder{
+		name:    namespace + "_operation_duration_seconds",
+		buckets: sorted,
+		series:  make(map[seriesKey]*histogram),
+	}
+}
+
+// ObserveOperation implements Recorder
+func (p *PrometheusRecorder) ObserveOperation(subsystem, operation, outcome string, duration time.Duration) {
+	seconds := duration.Seconds()
+	key := seriesKey{subsystem: subsystem, operation: operation, outcome: outcome}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h, ok := p.series[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(p.buckets))}
+		p.series[key] = h
+	}
+	for i, upper := range p.buckets {
+		if seconds <= upper {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// WriteTo writes all series in the Prometheus text exposition format
+func (p *PrometheusRecorder) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	keys := make([]seriesKey, 0, len(p.series))
+	snapshot := make(map[seriesKey]histogram, len(p.series))
+	for key, h := range p.series {
+		keys = append(keys, key)
+		snapshot[key] = histogram{counts: append([]uint64(nil), h.counts...), count: h.count, sum: h.sum}
+	}
+	p.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.subsystem != b.subsystem {
+			return a.subsystem < b.subsystem
+		}
+		if a.operation != b.operation {
+			return a.operation < b.operation
+		}
+		return a.outcome < b.outcome
+	})
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	fmt.Fprintf(cw, "# HELP %s Duration of redis-kit operations by subsystem, operation, and outcome.\n", p.name)
+	fmt.Fprintf(cw, "# TYPE %s histogram\n", p.name)
+	for _, key := range keys {
+		h := snapshot[key]
+		labels := fmt.Sprintf(`subsystem="%s",operation="%s",outcome="%s"`,
+			escapeLabel(key.subsystem), escapeLabel(key.operation), escapeLabel(key.outcome))
+
+		var cumulative uint64
+		for i, upper := range p.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(cw, "%s_bucket{%s,le=\"%s\"} %d\n", p.name, labels, formatFloat(upper), cumulative)
+		}
+		fmt.Fprintf(cw, "%s_bucket{%s,le=\"+Inf\"} %d\n", p.name, labels, h.count)
+		fmt.Fprintf(cw, "%s_sum{%s} %s\n", p.name, labels, formatFloat(h.sum))
+		fmt.Fprintf(cw, "%s_count{%s} %d\n", p.name, labels, h.count)
+	}
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP serves the metrics, so the recorder can be mounted at /metrics
+func (p *PrometheusRecorder) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = p.WriteTo(w)
+}
+
+// countingWriter tracks bytes written and keeps the first error
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusRecorder(t *testing.T) {
+	p := NewPrometheusRecorder("", []float64{0.1, 0.01})
+	p.ObserveOperation(SubsystemCache, "get", OutcomeHit, 5*time.Millisecond)
+	p.ObserveOperation(SubsystemCache, "get", OutcomeHit, 50*time.Millisecond)
+	p.ObserveOperation(SubsystemCache, "get", OutcomeHit, time.Second)
+	p.ObserveOperation(SubsystemLock, "lock", "we\"ird", time.Millisecond)
+
+	var sb strings.Builder
+	n, err := p.WriteTo(&sb)
+	if err != nil || n != int64(sb.Len()) {
+		t.Fatalf("WriteTo() = %d, %v, want %d bytes", n, err, sb.Len())
+	}
+	out := sb.String()
+
+	for _, want := range []string{
+		"# TYPE redis_kit_operation_duration_seconds histogram\n",
+		`redis_kit_operation_duration_seconds_bucket{subsystem="cache",operation="get",outcome="hit",le="0.01"} 1` + "\n",
+		`redis_kit_operation_duration_seconds_bucket{subsystem="cache",operation="get",outcome="hit",le="0.1"} 2` + "\n",
+		`redis_kit_operation_duration_seconds_bucket{subsystem="cache",operation="get",outcome="hit",le="+Inf"} 3` + "\n",
+		`redis_kit_operation_duration_seconds_sum{subsystem="cache",operation="get",outcome="hit"} 1.055` + "\n",
+		`redis_kit_operation_duration_seconds_count{subsystem="cache",operation="get",outcome="hit"} 3` + "\n",
+		`outcome="we\"ird"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteTo() output missing %q\n%s", want, out)
+		}
+	}
+	if strings.Index(out, `subsystem="cache"`) > strings.Index(out, `subsystem="lock"`) {
+		t.Error("WriteTo() series are not sorted")
+	}
+}
+
+func TestPrometheusRecorder_ServeHTTP(t *testing.T) {
+	p := NewPrometheusRecorder("myapp", nil)
+	p.ObserveOperation(SubsystemRateLimit, "check_limit", OutcomeAllowed, time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want the Prometheus text format", ct)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `myapp_operation_duration_seconds_count{subsystem="ratelimit",operation="check_limit",outcome="allowed"} 1`) {
+		t.Errorf("ServeHTTP() body = %s, want the check_limit series", body)
+	}
+	if got := strings.Count(body, "_bucket{"); got != len(DefaultBuckets)+1 {
+		t.Errorf("ServeHTTP() bucket lines = %d, want %d", got, len(DefaultBuckets)+1)
+	}
+}
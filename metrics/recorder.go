@@ -0,0 +1,134 @@
+// Package metrics instruments redis-kit components
+// Components report every operation to a Recorder with three labels: subsystem (the
+// package, e.g. "cache"), operation (the snake_case method, e.g. "check_limit"), and
+// outcome (one of the Outcome constants). Register a recorder once with SetDefault, or
+// pass one per component with its WithRecorder option
+package metrics
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Subsystem labels of the instrumented packages
+const (
+	SubsystemClient    = "client"
+	SubsystemCache     = "cache"
+	SubsystemLock      = "lock"
+	SubsystemRateLimit = "ratelimit"
+)
+
+// Outcome labels
+const (
+	// OutcomeSuccess is an operation that completed without error
+	OutcomeSuccess = "success"
+
+	// OutcomeError is an operation that failed
+	OutcomeError = "error"
+
+	// OutcomeHit is a read that found its key
+	OutcomeHit = "hit"
+
+	// OutcomeMiss is a read whose key does not exist
+	OutcomeMiss = "miss"
+
+	// OutcomeAllowed is a rate limit or cooldown check that let the request through
+	OutcomeAllowed = "allowed"
+
+	// OutcomeDenied is a rate limit or cooldown check that rejected the request
+	OutcomeDenied = "denied"
+
+	// OutcomeAcquired is a lock attempt that obtained the lock
+	OutcomeAcquired = "acquired"
+
+	// OutcomeBusy is a lock attempt that found the lock held by someone else
+	OutcomeBusy = "busy"
+
+	// OutcomeDegraded is an operation that skipped Redis because its client reported Redis
+	// as unavailable
+	OutcomeDegraded = "degraded"
+
+	// OutcomeTooLarge is a write whose value exceeded a size limit and was rejected, truncated,
+	// or skipped
+	OutcomeTooLarge = "too_large"
+)
+
+// Recorder receives one observation per instrumented operation
+// Implementations must be safe for concurrent use
+type Recorder interface {
+	ObserveOperation(subsystem, operation, outcome string, duration time.Duration)
+}
+
+type nopRecorder struct{}
+
+func (nopRecorder) ObserveOperation(string, string, string, time.Duration) {}
+
+// Nop returns a Recorder that discards all observations
+func Nop() Recorder {
+	return nopRecorder{}
+}
+
+type multiRecorder []Recorder
+
+func (m multiRecorder) ObserveOperation(subsystem, operation, outcome string, duration time.Duration) {
+	for _, r := range m {
+		r.ObserveOperation(subsystem, operation, outcome, duration)
+	}
+}
+
+// Multi returns a Recorder that forwards every observation to each of recorders
+func Multi(recorders ...Recorder) Recorder {
+	var m multiRecorder
+	for _, r := range recorders {
+		if r != nil {
+			m = append(m, r)
+		}
+	}
+	return m
+}
+
+type recorderBox struct {
+	r Recorder
+}
+
+var defaultRecorder atomic.Pointer[recorderBox]
+
+// SetDefault sets the recorder used by components without their own; nil restores Nop
+// Components look the default up on every operation, so it may be set after they are created
+func SetDefault(r Recorder) {
+	if r == nil {
+		r = Nop()
+	}
+	defaultRecorder.Store(&recorderBox{r: r})
+}
+
+// Default returns the recorder set with SetDefault, or Nop
+func Default() Recorder {
+	if box := defaultRecorder.Load(); box != nil {
+		return box.r
+	}
+	return Nop()
+}
+
+// Observe records an operation that started at start on r, or on Default() if r is nil
+func Observe(r Recorder, subsystem, operation, outcome string, start time.Time) {
+	if r == nil {
+		r = Default()
+	}
+	r.ObserveOperation(subsystem, operation, outcome, time.Since(start))
+}
+
+// Outcome maps err to OutcomeSuccess or OutcomeError; redis.Nil counts as OutcomeMiss
+func Outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, redis.Nil):
+		return OutcomeMiss
+	default:
+		return OutcomeError
+	}
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type countingRecorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *countingRecorder) ObserveOperation(subsystem, operation, outcome string, _ time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, subsystem+"/"+operation+"/"+outcome)
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, OutcomeSuccess},
+		{redis.Nil, OutcomeMiss},
+		{fmt.Errorf("wrapped: %w", redis.Nil), OutcomeMiss},
+		{errors.New("boom"), OutcomeError},
+	}
+	for _, tt := range tests {
+		if got := Outcome(tt.err); got != tt.want {
+			t.Errorf("Outcome(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	defer SetDefault(nil)
+
+	if _, ok := Default().(nopRecorder); !ok {
+		t.Fatalf("Default() = %T, want the no-op recorder", Default())
+	}
+
+	rec := &countingRecorder{}
+	SetDefault(rec)
+	Observe(nil, SubsystemCache, "get", OutcomeHit, time.Now())
+	explicit := &countingRecorder{}
+	Observe(explicit, SubsystemLock, "lock", OutcomeBusy, time.Now())
+
+	if len(rec.calls) != 1 || rec.calls[0] != "cache/get/hit" {
+		t.Errorf("default recorder calls = %v, want [cache/get/hit]", rec.calls)
+	}
+	if len(explicit.calls) != 1 || explicit.calls[0] != "lock/lock/busy" {
+		t.Errorf("explicit recorder calls = %v, want [lock/lock/busy]", explicit.calls)
+	}
+
+	SetDefault(nil)
+	if _, ok := Default().(nopRecorder); !ok {
+		t.Errorf("Default() after SetDefault(nil) = %T, want the no-op recorder", Default())
+	}
+}
+
+func TestMulti(t *testing.T) {
+	a, b := &countingRecorder{}, &countingRecorder{}
+	m := Multi(a, nil, b)
+	m.ObserveOperation(SubsystemRateLimit, "check_limit", OutcomeDenied, time.Millisecond)
+	Nop().ObserveOperation(SubsystemRateLimit, "check_limit", OutcomeDenied, time.Millisecond)
+
+	if len(a.calls) != 1 || len(b.calls) != 1 {
+		t.Errorf("Multi() forwarded %d/%d calls, want 1/1", len(a.calls), len(b.calls))
+	}
+}
@@ -0,0 +1,11 @@
+package rollup
+
+import "errors"
+
+var (
+	// ErrRangeTooLarge indicates a range query spans more than MaxRangeBuckets buckets.
+	ErrRangeTooLarge = errors.New("range spans too many buckets")
+
+	// ErrResolutionNotTracked indicates the counter does not record the requested resolution.
+	ErrResolutionNotTracked = errors.New("resolution not tracked")
+)
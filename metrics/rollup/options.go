@@ -0,0 +1,45 @@
+package rollup
+
+import "time"
+
+// Option configures a Counter
+type Option func(*Counter)
+
+// WithKeyPrefix sets the prefix of the bucket keys (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(c *Counter) {
+		c.keyPrefix = prefix
+	}
+}
+
+// WithResolutions sets the resolutions every increment is recorded in (default: Minute, Hour, Day)
+func WithResolutions(resolutions ...Resolution) Option {
+	return func(c *Counter) {
+		var valid []Resolution
+		for _, r := range resolutions {
+			if r.valid() {
+				valid = append(valid, r)
+			}
+		}
+		if len(valid) > 0 {
+			c.resolutions = valid
+		}
+	}
+}
+
+// WithRetention makes buckets of resolution r expire this long after they end; 0 keeps
+// them forever (default: 24h for minutes, 30 days for hours, 365 days for days)
+func WithRetention(r Resolution, d time.Duration) Option {
+	return func(c *Counter) {
+		if r.valid() && d >= 0 {
+			c.retention[r] = d
+		}
+	}
+}
+
+// WithLocation sets the time zone used for hour and day boundaries (default: UTC)
+func WithLocation(loc *time.Location) Option {
+	return func(c *Counter) {
+		c.loc = loc
+	}
+}
@@ -0,0 +1,77 @@
+package rollup
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// Resolution is the width of a time bucket
+type Resolution int
+
+const (
+	// Minute buckets counts per minute
+	Minute Resolution = iota
+	// Hour buckets counts per hour
+	Hour
+	// Day buckets counts per calendar day
+	Day
+)
+
+// String returns the name of the resolution, which is also part of bucket keys
+func (r Resolution) String() string {
+	switch r {
+	case Minute:
+		return "minute"
+	case Hour:
+		return "hour"
+	case Day:
+		return "day"
+	default:
+		return "unknown"
+	}
+}
+
+// valid reports whether r is one of the defined resolutions
+func (r Resolution) valid() bool {
+	return r >= Minute && r <= Day
+}
+
+// bounds returns the start of the bucket containing t and the start of the next one
+func (r Resolution) bounds(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	local := t.In(loc)
+	switch r {
+	case Minute:
+		start := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), 0, 0, loc)
+		return start, start.Add(time.Minute)
+	case Hour:
+		start := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, loc)
+		return start, start.Add(time.Hour)
+	default:
+		return utils.DayStart(t, loc), utils.NextDayStart(t, loc)
+	}
+}
+
+// id formats the start of a bucket for use in its key
+func (r Resolution) id(start time.Time) string {
+	switch r {
+	case Minute:
+		return start.Format("2006-01-02T15:04")
+	case Hour:
+		return start.Format("2006-01-02T15")
+	default:
+		return start.Format("2006-01-02")
+	}
+}
+
+// defaultRetention is how long buckets of r are kept after they end by default
+func (r Resolution) defaultRetention() time.Duration {
+	switch r {
+	case Minute:
+		return 24 * time.Hour
+	case Hour:
+		return 30 * 24 * time.Hour
+	default:
+		return 365 * 24 * time.Hour
+	}
+}
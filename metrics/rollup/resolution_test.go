@@ -0,0 +1,37 @@
+package rollup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolution_Bounds(t *testing.T) {
+	ts := time.Date(2026, 3, 8, 14, 37, 21, 0, time.UTC)
+	tests := []struct {
+		r          Resolution
+		start, end time.Time
+		id         string
+	}{
+		{Minute, time.Date(2026, 3, 8, 14, 37, 0, 0, time.UTC), time.Date(2026, 3, 8, 14, 38, 0, 0, time.UTC), "2026-03-08T14:37"},
+		{Hour, time.Date(2026, 3, 8, 14, 0, 0, 0, time.UTC), time.Date(2026, 3, 8, 15, 0, 0, 0, time.UTC), "2026-03-08T14"},
+		{Day, time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), "2026-03-08"},
+	}
+	for _, tt := range tests {
+		start, end := tt.r.bounds(ts, time.UTC)
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Errorf("%v bounds() = %v, %v, want %v, %v", tt.r, start, end, tt.start, tt.end)
+		}
+		if id := tt.r.id(start); id != tt.id {
+			t.Errorf("%v id() = %q, want %q", tt.r, id, tt.id)
+		}
+	}
+}
+
+func TestResolution_String(t *testing.T) {
+	if Minute.String() != "minute" || Hour.String() != "hour" || Day.String() != "day" || Resolution(9).String() != "unknown" {
+		t.Error("String() returned unexpected names")
+	}
+	if Resolution(9).valid() || Resolution(-1).valid() {
+		t.Error("valid() = true for an undefined resolution")
+	}
+}
@@ -0,0 +1,234 @@
+// Package rollup provides time-bucketed counters for lightweight analytics
+// Each increment is added to a per-minute, per-hour, and per-day hash keyed by bucket
+// start, with one field per counted key (e.g. a page or event name); old buckets expire
+package rollup
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for bucket keys
+	DefaultKeyPrefix = "rollup:"
+
+	// MaxRangeBuckets is the largest number of buckets a series query may span (a week of minutes)
+	MaxRangeBuckets = 7 * 24 * 60
+)
+
+// Point is the value of one bucket in a series
+type Point struct {
+	// Start is the start of the bucket
+	Start time.Time
+
+	// Value is the count in the bucket
+	Value int64
+}
+
+// Counter records counts per key in time buckets
+type Counter struct {
+	client      *redis.Client
+	name        string
+	keyPrefix   string
+	resolutions []Resolution
+	retention   map[Resolution]time.Duration
+	loc         *time.Location
+}
+
+// NewCounter creates a rollup counter with the given name (e.g. "pageviews")
+func NewCounter(client *redis.Client, name string, opts ...Option) *Counter {
+	c := &Counter{
+		client:      client,
+		name:        name,
+		keyPrefix:   DefaultKeyPrefix,
+		resolutions: []Resolution{Minute, Hour, Day},
+		retention: map[Resolution]time.Duration{
+			Minute: Minute.defaultRetention(),
+			Hour:   Hour.defaultRetention(),
+			Day:    Day.defaultRetention(),
+		},
+		loc: time.UTC,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.loc == nil {
+		c.loc = time.UTC
+	}
+	return c
+}
+
+// Resolutions returns the resolutions the counter records
+func (c *Counter) Resolutions() []Resolution {
+	return append([]Resolution(nil), c.resolutions...)
+}
+
+// Key returns the key of the r bucket containing t
+func (c *Counter) Key(r Resolution, t time.Time) string {
+	start, _ := r.bounds(t, c.loc)
+	return c.keyPrefix + c.name + ":" + r.String() + ":" + r.id(start)
+}
+
+// Incr adds n to key in the current buckets
+func (c *Counter) Incr(ctx context.Context, key string, n int64) error {
+	return c.IncrAt(ctx, time.Now(), key, n)
+}
+
+// IncrAt adds n to key in the buckets containing t, e.g. when replaying events
+// Buckets that are already past their retention are skipped
+func (c *Counter) IncrAt(ctx context.Context, t time.Time, key string, n int64) error {
+	return c.IncrMultiAt(ctx, t, map[string]int64{key: n})
+}
+
+// IncrMulti adds several keys to the current buckets in one round trip
+func (c *Counter) IncrMulti(ctx context.Context, counts map[string]int64) error {
+	return c.IncrMultiAt(ctx, time.Now(), counts)
+}
+
+// IncrMultiAt adds several keys to the buckets containing t in one round trip
+func (c *Counter) IncrMultiAt(ctx context.Context, t time.Time, counts map[string]int64) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	pipe := c.client.Pipeline()
+	queued := false
+	for _, r := range c.resolutions {
+		ttl := c.ttl(r, t)
+		if ttl < 0 {
+			continue
+		}
+		bucket := c.Key(r, t)
+		for key, n := range counts {
+			pipe.HIncrBy(ctx, bucket, key, n)
+		}
+		if ttl > 0 {
+			pipe.PExpire(ctx, bucket, ttl)
+		}
+		queued = true
+	}
+	if !queued {
+		return nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to increment rollup: %w", err)
+	}
+	return nil
+}
+
+// ttl returns how long the r bucket containing t must live to cover its retention,
+// 0 for buckets that never expire, and a negative value for buckets already past it
+func (c *Counter) ttl(r Resolution, t time.Time) time.Duration {
+	retention := c.retention[r]
+	if retention <= 0 {
+		return 0
+	}
+	_, end := r.bounds(t, c.loc)
+	if ttl := time.Until(end.Add(retention)); ttl > 0 {
+		return ttl
+	}
+	return -1
+}
+
+func (c *Counter) tracks(r Resolution) bool {
+	for _, tracked := range c.resolutions {
+		if tracked == r {
+			return true
+		}
+	}
+	return false
+}
+
+// starts returns the starts of all r buckets overlapping [from, to], oldest first
+func (c *Counter) starts(r Resolution, from, to time.Time) ([]time.Time, error) {
+	if !c.tracks(r) {
+		return nil, ErrResolutionNotTracked
+	}
+	var starts []time.Time
+	if to.Before(from) {
+		return starts, nil
+	}
+	for start, _ := r.bounds(from, c.loc); !start.After(to); _, start = r.bounds(start, c.loc) {
+		if len(starts) == MaxRangeBuckets {
+			return nil, ErrRangeTooLarge
+		}
+		starts = append(starts, start)
+	}
+	return starts, nil
+}
+
+// Series returns the count of key in every r bucket overlapping [from, to], oldest first
+// Buckets without data have a zero value, so the points are contiguous
+func (c *Counter) Series(ctx context.Context, key string, r Resolution, from, to time.Time) ([]Point, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	starts, err := c.starts(r, from, to)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]Point, len(starts))
+	if len(starts) == 0 {
+		return points, nil
+	}
+
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(starts))
+	for i, start := range starts {
+		cmds[i] = pipe.HGet(ctx, c.Key(r, start), key)
+	}
+	// Missing buckets make Exec report redis.Nil, so errors are checked per command
+	_, _ = pipe.Exec(ctx)
+	for i, start := range starts {
+		n, err := cmds[i].Int64()
+		if err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("failed to read rollup series: %w", err)
+		}
+		points[i] = Point{Start: start, Value: n}
+	}
+	return points, nil
+}
+
+// Sum returns the total count of key across the r buckets overlapping [from, to]
+func (c *Counter) Sum(ctx context.Context, key string, r Resolution, from, to time.Time) (int64, error) {
+	points, err := c.Series(ctx, key, r, from, to)
+	if err != nil {
+		return 0, err
+	}
+	var sum int64
+	for _, p := range points {
+		sum += p.Value
+	}
+	return sum, nil
+}
+
+// Bucket returns the counts of all keys in the r bucket containing t, e.g. to list the
+// busiest pages of the last hour
+func (c *Counter) Bucket(ctx context.Context, r Resolution, t time.Time) (map[string]int64, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if !c.tracks(r) {
+		return nil, ErrResolutionNotTracked
+	}
+
+	raw, err := c.client.HGetAll(ctx, c.Key(r, t)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rollup bucket: %w", err)
+	}
+	counts := make(map[string]int64, len(raw))
+	for key, value := range raw {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			counts[key] = n
+		}
+	}
+	return counts, nil
+}
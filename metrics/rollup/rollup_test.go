@@ -0,0 +1,167 @@
+package rollup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestCounter_IncrAndSeries(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	c := NewCounter(client, "pageviews")
+	now := time.Now().UTC()
+	hourStart := now.Truncate(time.Hour)
+
+	if key := c.Key(Hour, hourStart); key != "rollup:pageviews:hour:"+hourStart.Format("2006-01-02T15") {
+		t.Errorf("Key() = %q", key)
+	}
+
+	if err := c.IncrAt(ctx, hourStart.Add(time.Minute), "/home", 2); err != nil {
+		t.Fatalf("IncrAt() error = %v, want nil", err)
+	}
+	if err := c.IncrAt(ctx, hourStart.Add(3*time.Minute), "/home", 1); err != nil {
+		t.Fatalf("IncrAt() error = %v, want nil", err)
+	}
+	if err := c.IncrMultiAt(ctx, hourStart.Add(3*time.Minute), map[string]int64{"/home": 1, "/about": 5}); err != nil {
+		t.Fatalf("IncrMultiAt() error = %v, want nil", err)
+	}
+
+	points, err := c.Series(ctx, "/home", Minute, hourStart, hourStart.Add(4*time.Minute))
+	if err != nil || len(points) != 5 {
+		t.Fatalf("Series() = %v, %v, want 5 points", points, err)
+	}
+	want := []int64{0, 2, 0, 2, 0}
+	for i, p := range points {
+		if p.Value != want[i] || !p.Start.Equal(hourStart.Add(time.Duration(i)*time.Minute)) {
+			t.Errorf("Series()[%d] = %+v, want %d at minute %d", i, p, want[i], i)
+		}
+	}
+
+	if sum, err := c.Sum(ctx, "/home", Hour, hourStart, hourStart); err != nil || sum != 4 {
+		t.Errorf("Sum(hour) = %d, %v, want 4", sum, err)
+	}
+	if sum, _ := c.Sum(ctx, "/about", Day, hourStart, hourStart); sum != 5 {
+		t.Errorf("Sum(day) = %d, want 5", sum)
+	}
+
+	bucket, err := c.Bucket(ctx, Hour, hourStart)
+	if err != nil || len(bucket) != 2 || bucket["/home"] != 4 || bucket["/about"] != 5 {
+		t.Errorf("Bucket() = %v, %v, want /home 4, /about 5", bucket, err)
+	}
+
+	if ttl, _ := client.PTTL(ctx, c.Key(Minute, hourStart.Add(time.Minute))).Result(); ttl <= 0 || ttl > 25*time.Hour {
+		t.Errorf("minute bucket PTTL() = %v, want about a day", ttl)
+	}
+
+	if err := c.Incr(ctx, "/now", 1); err != nil {
+		t.Fatalf("Incr() error = %v, want nil", err)
+	}
+	if err := c.IncrMulti(ctx, map[string]int64{"/now": 1}); err != nil {
+		t.Fatalf("IncrMulti() error = %v, want nil", err)
+	}
+	if sum, _ := c.Sum(ctx, "/now", Minute, time.Now(), time.Now()); sum != 2 {
+		t.Errorf("Sum() current minute = %d, want 2", sum)
+	}
+	if err := c.IncrMulti(ctx, nil); err != nil {
+		t.Errorf("IncrMulti() empty error = %v, want nil", err)
+	}
+}
+
+func TestCounter_Options(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	c := NewCounter(client, "signups",
+		WithKeyPrefix("stats:"),
+		WithResolutions(Day, Resolution(42)),
+		WithRetention(Day, 0),
+		WithLocation(nil),
+	)
+	if r := c.Resolutions(); len(r) != 1 || r[0] != Day {
+		t.Errorf("Resolutions() = %v, want [day]", r)
+	}
+
+	day := time.Date(2020, 1, 2, 10, 0, 0, 0, time.UTC)
+	if err := c.IncrAt(ctx, day, "web", 3); err != nil {
+		t.Fatalf("IncrAt() error = %v, want nil", err)
+	}
+	key := "stats:signups:day:2020-01-02"
+	if n, _ := client.HGet(ctx, key, "web").Int64(); n != 3 {
+		t.Errorf("HGet() = %d, want 3", n)
+	}
+	if ttl, _ := client.PTTL(ctx, key).Result(); ttl != -1 {
+		t.Errorf("PTTL() = %v, want no expiry", ttl)
+	}
+
+	if _, err := c.Series(ctx, "web", Minute, day, day); !errors.Is(err, ErrResolutionNotTracked) {
+		t.Errorf("Series() untracked error = %v, want ErrResolutionNotTracked", err)
+	}
+	if _, err := c.Bucket(ctx, Hour, day); !errors.Is(err, ErrResolutionNotTracked) {
+		t.Errorf("Bucket() untracked error = %v, want ErrResolutionNotTracked", err)
+	}
+	if points, err := c.Series(ctx, "web", Day, day, day.Add(-time.Hour)); err != nil || len(points) != 0 {
+		t.Errorf("Series() reversed range = %v, %v, want none", points, err)
+	}
+	if _, err := c.Series(ctx, "web", Day, day, day.AddDate(30, 0, 0)); !errors.Is(err, ErrRangeTooLarge) {
+		t.Errorf("Series() huge range error = %v, want ErrRangeTooLarge", err)
+	}
+}
+
+func TestCounter_SkipsExpiredBuckets(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	c := NewCounter(client, "views", WithResolutions(Minute, Day), WithRetention(Minute, time.Hour))
+	old := time.Now().Add(-3 * time.Hour)
+	if err := c.IncrAt(ctx, old, "/home", 1); err != nil {
+		t.Fatalf("IncrAt() error = %v, want nil", err)
+	}
+	if n, _ := client.Exists(ctx, c.Key(Minute, old)).Result(); n != 0 {
+		t.Error("minute bucket past retention was written")
+	}
+	if n, _ := client.HGet(ctx, c.Key(Day, old), "/home").Int64(); n != 1 {
+		t.Errorf("day bucket = %d, want 1", n)
+	}
+}
+
+func TestCounter_Errors(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewCounter(nil, "x")
+	if err := c.Incr(ctx, "k", 1); err == nil {
+		t.Error("Incr() with nil client error = nil, want error")
+	}
+	if _, err := c.Series(ctx, "k", Hour, now, now); err == nil {
+		t.Error("Series() with nil client error = nil, want error")
+	}
+	if _, err := c.Bucket(ctx, Hour, now); err == nil {
+		t.Error("Bucket() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	c = NewCounter(client, "x")
+	_ = client.HSet(ctx, c.Key(Hour, now), "bad", "not-a-number").Err()
+	if _, err := c.Series(ctx, "bad", Hour, now, now); err == nil {
+		t.Error("Series() with a non-integer value error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if err := c.Incr(ctx, "k", 1); err == nil {
+		t.Error("Incr() with failing redis error = nil, want error")
+	}
+	if _, err := c.Sum(ctx, "k", Hour, now, now); err == nil {
+		t.Error("Sum() with failing redis error = nil, want error")
+	}
+	if _, err := c.Bucket(ctx, Hour, now); err == nil {
+		t.Error("Bucket() with failing redis error = nil, want error")
+	}
+}
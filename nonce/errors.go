@@ -0,0 +1,14 @@
+package nonce
+
+import "errors"
+
+var (
+	// ErrInvalidNonce indicates a nonce that was never issued, has expired, or was already used.
+	ErrInvalidNonce = errors.New("invalid nonce")
+
+	// ErrReplay indicates a message ID that was already seen within the replay window.
+	ErrReplay = errors.New("message replayed")
+
+	// ErrStale indicates a message timestamp outside the replay window.
+	ErrStale = errors.New("message timestamp outside replay window")
+)
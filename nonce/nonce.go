@@ -0,0 +1,160 @@
+// Package nonce issues single-use nonces and rejects replayed messages
+// Nonces suit CSRF tokens and OAuth state: Generate stores one with SET NX and Consume
+// takes it back with GETDEL, so each is accepted once. CheckReplay protects signed
+// messages such as webhooks by rejecting stale timestamps and IDs seen before
+package nonce
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for nonce and replay keys
+	DefaultKeyPrefix = "nonce:"
+
+	// DefaultTTL is how long nonces stay valid by default
+	DefaultTTL = 10 * time.Minute
+
+	// DefaultSize is the default number of random bytes in a nonce
+	DefaultSize = 16
+
+	// DefaultReplayWindow is the default tolerance of CheckReplay for message timestamps
+	DefaultReplayWindow = 5 * time.Minute
+)
+
+// generateAttempts bounds the retries of Generate on the (unlikely) collision with a live nonce
+const generateAttempts = 3
+
+// Store issues nonces and remembers message IDs
+type Store struct {
+	client    *redis.Client
+	keyPrefix string
+	ttl       time.Duration
+	size      int
+	window    time.Duration
+}
+
+// NewStore creates a nonce store
+func NewStore(client *redis.Client, opts ...Option) *Store {
+	s := &Store{
+		client:    client,
+		keyPrefix: DefaultKeyPrefix,
+		ttl:       DefaultTTL,
+		size:      DefaultSize,
+		window:    DefaultReplayWindow,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// nonceKey returns the Redis key of a nonce; callers check it with valid first, as a string
+// holding a colon could name a replay key
+func (s *Store) nonceKey(nonce string) string {
+	return s.keyPrefix + nonce
+}
+
+func (s *Store) replayKey(id string) string {
+	return s.keyPrefix + "seen:" + id
+}
+
+// Generate issues a nonce valid for the store's TTL and binds data to it, such as the
+// session ID of a CSRF token or the redirect target of an OAuth flow
+func (s *Store) Generate(ctx context.Context, data string) (string, error) {
+	return s.GenerateWithTTL(ctx, data, s.ttl)
+}
+
+// GenerateWithTTL issues a nonce valid for ttl and binds data to it
+func (s *Store) GenerateWithTTL(ctx context.Context, data string, ttl time.Duration) (string, error) {
+	if s.client == nil {
+		return "", fmt.Errorf("redis client is nil")
+	}
+	if ttl <= 0 {
+		return "", fmt.Errorf("ttl must be positive")
+	}
+
+	for i := 0; i < generateAttempts; i++ {
+		nonce, err := s.random()
+		if err != nil {
+			return "", err
+		}
+		ok, err := s.client.SetNX(ctx, s.nonceKey(nonce), data, ttl).Result()
+		if err != nil {
+			return "", fmt.Errorf("failed to store nonce: %w", err)
+		}
+		if ok {
+			return nonce, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate an unused nonce after %d attempts", generateAttempts)
+}
+
+// Consume accepts nonce once and returns the data bound to it
+// Returns ErrInvalidNonce if the nonce was never issued, has expired, or was already consumed
+func (s *Store) Consume(ctx context.Context, nonce string) (string, error) {
+	if s.client == nil {
+		return "", fmt.Errorf("redis client is nil")
+	}
+	if !s.valid(nonce) {
+		return "", ErrInvalidNonce
+	}
+
+	data, err := s.client.GetDel(ctx, s.nonceKey(nonce)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrInvalidNonce
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to consume nonce: %w", err)
+	}
+	return data, nil
+}
+
+// CheckReplay accepts a message once: it returns ErrStale if timestamp is further than the
+// replay window from now, and ErrReplay if id was already accepted. IDs are remembered for
+// twice the window, as long as a message carrying them could still pass the timestamp check
+func (s *Store) CheckReplay(ctx context.Context, id string, timestamp time.Time) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	skew := time.Since(timestamp)
+	if skew > s.window || skew < -s.window {
+		return fmt.Errorf("%w: %s", ErrStale, timestamp.Format(time.RFC3339))
+	}
+
+	ok, err := s.client.SetNX(ctx, s.replayKey(id), "1", 2*s.window).Result()
+	if err != nil {
+		return fmt.Errorf("failed to record message: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrReplay, id)
+	}
+	return nil
+}
+
+// valid reports whether nonce could have been issued by the store: base64url of its size, so
+// it cannot name a replay key such as "seen:<id>"
+func (s *Store) valid(nonce string) bool {
+	if len(nonce) != base64.RawURLEncoding.EncodedLen(s.size) {
+		return false
+	}
+	_, err := base64.RawURLEncoding.DecodeString(nonce)
+	return err == nil
+}
+
+// random returns a new base64url-encoded nonce
+func (s *Store) random() (string, error) {
+	bytes := make([]byte, s.size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
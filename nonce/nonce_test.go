@@ -0,0 +1,173 @@
+package nonce
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestNewStore(t *testing.T) {
+	s := NewStore(nil)
+	if s.keyPrefix != DefaultKeyPrefix || s.ttl != DefaultTTL || s.size != DefaultSize || s.window != DefaultReplayWindow {
+		t.Errorf("NewStore() = %+v, want defaults", s)
+	}
+
+	s = NewStore(nil, WithKeyPrefix("csrf:"), WithTTL(time.Hour), WithSize(32), WithReplayWindow(time.Minute))
+	if s.keyPrefix != "csrf:" || s.ttl != time.Hour || s.size != 32 || s.window != time.Minute {
+		t.Errorf("NewStore() with options = %+v", s)
+	}
+
+	s = NewStore(nil, WithTTL(0), WithSize(0), WithReplayWindow(-time.Second))
+	if s.ttl != DefaultTTL || s.size != DefaultSize || s.window != DefaultReplayWindow {
+		t.Errorf("NewStore() with invalid options = %+v, want defaults", s)
+	}
+}
+
+func TestStore_GenerateConsume(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client)
+
+	nonce, err := s.Generate(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("Generate() error = %v, want nil", err)
+	}
+	if len(nonce) != 22 {
+		t.Errorf("Generate() = %q, want 22 base64url characters", nonce)
+	}
+	if other, _ := s.Generate(ctx, "session-1"); other == nonce {
+		t.Error("Generate() returned the same nonce twice")
+	}
+	if ttl := client.TTL(ctx, "nonce:"+nonce).Val(); ttl <= 0 || ttl > DefaultTTL {
+		t.Errorf("TTL() = %v, want at most %v", ttl, DefaultTTL)
+	}
+
+	data, err := s.Consume(ctx, nonce)
+	if err != nil || data != "session-1" {
+		t.Errorf("Consume() = %q, %v, want session-1", data, err)
+	}
+	if _, err := s.Consume(ctx, nonce); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("Consume() twice error = %v, want ErrInvalidNonce", err)
+	}
+	if _, err := s.Consume(ctx, "never-issued"); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("Consume() of an unknown nonce error = %v, want ErrInvalidNonce", err)
+	}
+	if _, err := s.Consume(ctx, ""); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("Consume() of an empty nonce error = %v, want ErrInvalidNonce", err)
+	}
+
+	short, _ := s.GenerateWithTTL(ctx, "", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, err := s.Consume(ctx, short); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("Consume() of an expired nonce error = %v, want ErrInvalidNonce", err)
+	}
+}
+
+func TestStore_ConsumeOnce(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client)
+	nonce, _ := s.Generate(ctx, "state")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Consume(ctx, nonce); err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted != 1 {
+		t.Errorf("concurrent Consume() accepted %d times, want 1", accepted)
+	}
+}
+
+func TestStore_CheckReplay(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client, WithReplayWindow(time.Minute))
+
+	if err := s.CheckReplay(ctx, "evt-1", time.Now()); err != nil {
+		t.Fatalf("CheckReplay() error = %v, want nil", err)
+	}
+	if err := s.CheckReplay(ctx, "evt-1", time.Now()); !errors.Is(err, ErrReplay) {
+		t.Errorf("CheckReplay() of a seen ID error = %v, want ErrReplay", err)
+	}
+	if ttl := client.TTL(ctx, "nonce:seen:evt-1").Val(); ttl <= time.Minute || ttl > 2*time.Minute {
+		t.Errorf("TTL() = %v, want twice the window", ttl)
+	}
+
+	if err := s.CheckReplay(ctx, "evt-2", time.Now().Add(-2*time.Minute)); !errors.Is(err, ErrStale) {
+		t.Errorf("CheckReplay() of an old message error = %v, want ErrStale", err)
+	}
+	if err := s.CheckReplay(ctx, "evt-3", time.Now().Add(2*time.Minute)); !errors.Is(err, ErrStale) {
+		t.Errorf("CheckReplay() of a future message error = %v, want ErrStale", err)
+	}
+	// Stale messages are not recorded
+	if err := s.CheckReplay(ctx, "evt-2", time.Now()); err != nil {
+		t.Errorf("CheckReplay() after a stale attempt error = %v, want nil", err)
+	}
+
+	// A replay marker cannot be consumed as a nonce, which would also wipe it; this one is as
+	// long as a nonce
+	id := "evt-0123456789abc"
+	_ = s.CheckReplay(ctx, id, time.Now())
+	if _, err := s.Consume(ctx, "seen:"+id); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("Consume() of a replay key error = %v, want ErrInvalidNonce", err)
+	}
+	if err := s.CheckReplay(ctx, id, time.Now()); !errors.Is(err, ErrReplay) {
+		t.Errorf("CheckReplay() after consuming its key error = %v, want ErrReplay", err)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	s := NewStore(nil)
+	if _, err := s.Generate(ctx, ""); err == nil {
+		t.Error("Generate() with nil client error = nil, want error")
+	}
+	if _, err := s.Consume(ctx, "n"); err == nil {
+		t.Error("Consume() with nil client error = nil, want error")
+	}
+	if err := s.CheckReplay(ctx, "id", time.Now()); err == nil {
+		t.Error("CheckReplay() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	s = NewStore(client)
+	if _, err := s.GenerateWithTTL(ctx, "", 0); err == nil {
+		t.Error("GenerateWithTTL() with zero ttl error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if _, err := s.Generate(ctx, ""); err == nil {
+		t.Error("Generate() error = nil, want error")
+	}
+	if _, err := s.Consume(ctx, strings.Repeat("A", 22)); err == nil || errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("Consume() error = %v, want a non-ErrInvalidNonce error", err)
+	}
+	if err := s.CheckReplay(ctx, "id", time.Now()); err == nil {
+		t.Error("CheckReplay() error = nil, want error")
+	}
+}
@@ -0,0 +1,41 @@
+package nonce
+
+import "time"
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithKeyPrefix sets the prefix of nonce and replay keys (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(s *Store) {
+		s.keyPrefix = prefix
+	}
+}
+
+// WithTTL sets how long a generated nonce stays valid (default: DefaultTTL)
+func WithTTL(ttl time.Duration) Option {
+	return func(s *Store) {
+		if ttl > 0 {
+			s.ttl = ttl
+		}
+	}
+}
+
+// WithSize sets the number of random bytes in a nonce (default: DefaultSize)
+func WithSize(n int) Option {
+	return func(s *Store) {
+		if n > 0 {
+			s.size = n
+		}
+	}
+}
+
+// WithReplayWindow sets how far a message timestamp may be from now for CheckReplay to
+// accept it (default: DefaultReplayWindow)
+func WithReplayWindow(window time.Duration) Option {
+	return func(s *Store) {
+		if window > 0 {
+			s.window = window
+		}
+	}
+}
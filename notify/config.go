@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// DefaultKeyPrefix is the default prefix for notifier keys
+const DefaultKeyPrefix = "notify:"
+
+// Config represents notifier configuration
+type Config struct {
+	// KeyPrefix prefixes the notifier's keys (default: "notify:" + name + ":")
+	KeyPrefix string
+
+	// Codec encodes events (default: codec.Default())
+	Codec codec.Codec
+
+	// QuietPeriod is how long a recipient must receive no events before its digest is due (default: 1m)
+	QuietPeriod time.Duration
+
+	// MaxDelay caps how long the first buffered event of a recipient waits, so a steady
+	// stream of events still produces digests (default: 15m)
+	MaxDelay time.Duration
+
+	// MaxEvents is the number of most recent events kept per digest; 0 keeps all (default: 100)
+	MaxEvents int64
+
+	// PollInterval is how often Run checks for due digests (default: 1s)
+	PollInterval time.Duration
+
+	// BatchSize is the maximum number of digests drained per round trip (default: 100)
+	BatchSize int64
+
+	// OnError is called with errors from Run's poll loop and handler, and with events that
+	// cannot be decoded (optional)
+	OnError func(error)
+}
+
+// DefaultConfig returns a Config with default values for the notifier with the given name
+func DefaultConfig(name string) Config {
+	return Config{
+		KeyPrefix:    DefaultKeyPrefix + name + ":",
+		Codec:        codec.Default(),
+		QuietPeriod:  time.Minute,
+		MaxDelay:     15 * time.Minute,
+		MaxEvents:    100,
+		PollInterval: time.Second,
+		BatchSize:    100,
+	}
+}
+
+// WithCodec sets the event codec
+func (c Config) WithCodec(cd codec.Codec) Config {
+	c.Codec = cd
+	return c
+}
+
+// WithDelays sets the quiet period and the maximum delay of a digest
+func (c Config) WithDelays(quiet, maxDelay time.Duration) Config {
+	c.QuietPeriod = quiet
+	c.MaxDelay = maxDelay
+	return c
+}
+
+// WithMaxEvents sets the number of most recent events kept per digest
+func (c Config) WithMaxEvents(n int64) Config {
+	c.MaxEvents = n
+	return c
+}
+
+// WithPollInterval sets how often due digests are polled
+func (c Config) WithPollInterval(interval time.Duration) Config {
+	c.PollInterval = interval
+	return c
+}
+
+// WithBatchSize sets the maximum number of digests drained per round trip
+func (c Config) WithBatchSize(n int64) Config {
+	c.BatchSize = n
+	return c
+}
+
+// WithOnError sets the callback for poll loop, handler, and decoding errors
+func (c Config) WithOnError(fn func(error)) Config {
+	c.OnError = fn
+	return c
+}
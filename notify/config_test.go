@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig("mentions")
+
+	if cfg.KeyPrefix != "notify:mentions:" {
+		t.Errorf("DefaultConfig().KeyPrefix = %q, want %q", cfg.KeyPrefix, "notify:mentions:")
+	}
+	if cfg.Codec == nil || cfg.Codec.Name() != codec.NameJSON {
+		t.Errorf("DefaultConfig().Codec = %v, want json", cfg.Codec)
+	}
+	if cfg.QuietPeriod != time.Minute || cfg.MaxDelay != 15*time.Minute {
+		t.Errorf("DefaultConfig() delays = %v/%v, want 1m/15m", cfg.QuietPeriod, cfg.MaxDelay)
+	}
+	if cfg.MaxEvents != 100 || cfg.BatchSize != 100 || cfg.PollInterval != time.Second {
+		t.Errorf("DefaultConfig() = %d/%d/%v, want 100/100/1s", cfg.MaxEvents, cfg.BatchSize, cfg.PollInterval)
+	}
+}
+
+func TestConfig_With(t *testing.T) {
+	var called bool
+	cfg := DefaultConfig("n").
+		WithCodec(codec.Gob).
+		WithDelays(time.Second, time.Minute).
+		WithMaxEvents(5).
+		WithPollInterval(time.Millisecond).
+		WithBatchSize(7).
+		WithOnError(func(error) { called = true })
+
+	if cfg.Codec.Name() != codec.NameGob {
+		t.Errorf("WithCodec() = %q, want gob", cfg.Codec.Name())
+	}
+	if cfg.QuietPeriod != time.Second || cfg.MaxDelay != time.Minute {
+		t.Errorf("WithDelays() = %v/%v, want 1s/1m", cfg.QuietPeriod, cfg.MaxDelay)
+	}
+	if cfg.MaxEvents != 5 || cfg.PollInterval != time.Millisecond || cfg.BatchSize != 7 {
+		t.Errorf("WithMaxEvents/WithPollInterval/WithBatchSize = %d/%v/%d, want 5/1ms/7", cfg.MaxEvents, cfg.PollInterval, cfg.BatchSize)
+	}
+	cfg.OnError(nil)
+	if !called {
+		t.Error("WithOnError() callback not set")
+	}
+}
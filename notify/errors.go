@@ -0,0 +1,8 @@
+package notify
+
+import "errors"
+
+var (
+	// ErrHandlerPanic indicates the digest handler panicked while processing a digest.
+	ErrHandlerPanic = errors.New("digest handler panicked")
+)
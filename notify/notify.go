@@ -0,0 +1,292 @@
+// Package notify coalesces notification events per recipient into digests
+// Record buffers an event in the recipient's list and schedules the recipient's digest in a
+// sorted set; the digest falls due once no event has arrived for QuietPeriod, or MaxDelay
+// after its first event, whichever is earlier. Drain and Run hand out the due digests
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+const recordScript = `
+-- redis-kit:notify-record
+local now = tonumber(ARGV[1])
+local first, count = now, 0
+local meta = redis.call("hget", KEYS[2], ARGV[5])
+if meta then
+	local sep = string.find(meta, ":", 1, true)
+	first = tonumber(string.sub(meta, 1, sep - 1))
+	count = tonumber(string.sub(meta, sep + 1))
+end
+count = count + 1
+redis.call("hset", KEYS[2], ARGV[5], string.format("%.0f:%.0f", first, count))
+redis.call("rpush", KEYS[3], ARGV[6])
+if tonumber(ARGV[4]) > 0 then
+	redis.call("ltrim", KEYS[3], -tonumber(ARGV[4]), -1)
+end
+local due = math.min(now + tonumber(ARGV[2]), first + tonumber(ARGV[3]))
+redis.call("zadd", KEYS[1], string.format("%.0f", due), ARGV[5])
+return count
+`
+
+const drainScript = `
+-- redis-kit:notify-drain
+local due = redis.call("zrangebyscore", KEYS[1], "-inf", ARGV[1], "limit", 0, ARGV[2])
+local out = {}
+for _, user in ipairs(due) do
+	redis.call("zrem", KEYS[1], user)
+	local meta = redis.call("hget", KEYS[2], user)
+	redis.call("hdel", KEYS[2], user)
+	local key = ARGV[3] .. user
+	local events = redis.call("lrange", key, 0, -1)
+	redis.call("del", key)
+	if meta then
+		table.insert(out, user)
+		table.insert(out, meta)
+		table.insert(out, tostring(#events))
+		for _, event in ipairs(events) do
+			table.insert(out, event)
+		end
+	end
+end
+return out
+`
+
+// Handler processes a due digest
+// The digest has already been removed, so returning an error only reports it
+type Handler[T any] func(ctx context.Context, digest *Digest[T]) error
+
+// Digest is the batch of events buffered for a recipient
+type Digest[T any] struct {
+	// User is the recipient
+	User string
+
+	// Events are the buffered events, oldest first; at most MaxEvents of the most recent
+	Events []T
+
+	// Count is the number of events recorded, including those dropped by MaxEvents
+	Count int64
+
+	// FirstAt is when the first event of the digest was recorded
+	FirstAt time.Time
+}
+
+// Notifier buffers events per recipient until their digest is due
+// Drain deletes the event lists it names without declaring them, so on Redis Cluster the
+// prefix must contain a {hash tag} that keeps all of the notifier's keys in one slot
+type Notifier[T any] struct {
+	client *redis.Client
+	cfg    Config
+}
+
+// NewNotifier creates a new notifier; zero config values fall back to sane minimums
+// A MaxDelay below QuietPeriod makes digests due MaxDelay after their first event
+func NewNotifier[T any](client *redis.Client, cfg Config) *Notifier[T] {
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = DefaultKeyPrefix
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = codec.Default()
+	}
+	if cfg.QuietPeriod < 0 {
+		cfg.QuietPeriod = 0
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = cfg.QuietPeriod
+	}
+	if cfg.MaxEvents < 0 {
+		cfg.MaxEvents = 0
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 1
+	}
+	return &Notifier[T]{client: client, cfg: cfg}
+}
+
+// Config returns the notifier configuration
+func (n *Notifier[T]) Config() Config {
+	return n.cfg
+}
+
+func (n *Notifier[T]) dueKey() string {
+	return n.cfg.KeyPrefix + "due"
+}
+
+func (n *Notifier[T]) metaKey() string {
+	return n.cfg.KeyPrefix + "meta"
+}
+
+func (n *Notifier[T]) eventsPrefix() string {
+	return n.cfg.KeyPrefix + "events:"
+}
+
+// Record buffers event for user and pushes back the user's digest by QuietPeriod, up to
+// MaxDelay after its first event
+// Returns the number of events in the pending digest, so callers can tell the first event
+func (n *Notifier[T]) Record(ctx context.Context, user string, event T) (int64, error) {
+	if n.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	data, err := n.cfg.Codec.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	count, err := n.client.Eval(ctx, recordScript,
+		[]string{n.dueKey(), n.metaKey(), n.eventsPrefix() + user},
+		time.Now().UnixMilli(), n.cfg.QuietPeriod.Milliseconds(), n.cfg.MaxDelay.Milliseconds(),
+		n.cfg.MaxEvents, user, data,
+	).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to record event: %w", err)
+	}
+	return count, nil
+}
+
+// Discard drops the pending digest of user, for instance once the user has seen the events
+// Returns true if a digest was pending
+func (n *Notifier[T]) Discard(ctx context.Context, user string) (bool, error) {
+	if n.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+
+	// Removing from the sorted set first means a concurrent drain cannot take the digest anymore
+	removed, err := n.client.ZRem(ctx, n.dueKey(), user).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to discard digest: %w", err)
+	}
+	pipe := n.client.Pipeline()
+	pipe.HDel(ctx, n.metaKey(), user)
+	pipe.Del(ctx, n.eventsPrefix()+user)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to delete digest events: %w", err)
+	}
+	return removed > 0, nil
+}
+
+// Pending returns the number of recipients with a pending digest
+func (n *Notifier[T]) Pending(ctx context.Context) (int64, error) {
+	if n.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	count, err := n.client.ZCard(ctx, n.dueKey()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pending digests: %w", err)
+	}
+	return count, nil
+}
+
+// Drain atomically removes and returns up to limit digests due at or before now, earliest first
+// Events that cannot be decoded are left out of their digest and reported to OnError
+func (n *Notifier[T]) Drain(ctx context.Context, now time.Time, limit int64) ([]*Digest[T], error) {
+	if n.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if limit < 1 {
+		limit = n.cfg.BatchSize
+	}
+
+	res, err := n.client.Eval(ctx, drainScript,
+		[]string{n.dueKey(), n.metaKey()},
+		now.UnixMilli(), limit, n.eventsPrefix(),
+	).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to drain digests: %w", err)
+	}
+
+	var digests []*Digest[T]
+	for i := 0; i+2 < len(res); {
+		digest := &Digest[T]{User: res[i]}
+		first, count, _ := strings.Cut(res[i+1], ":")
+		firstMs, _ := strconv.ParseInt(first, 10, 64)
+		digest.FirstAt = time.UnixMilli(firstMs)
+		digest.Count, _ = strconv.ParseInt(count, 10, 64)
+		size, _ := strconv.Atoi(res[i+2])
+		i += 3
+
+		end := min(i+size, len(res))
+		digest.Events = make([]T, 0, end-i)
+		for ; i < end; i++ {
+			var event T
+			if err := n.cfg.Codec.Unmarshal([]byte(res[i]), &event); err != nil {
+				n.reportError(fmt.Errorf("dropped undecodable event for %s: %w", digest.User, err))
+				continue
+			}
+			digest.Events = append(digest.Events, event)
+		}
+		digests = append(digests, digest)
+	}
+	return digests, nil
+}
+
+// Run polls for due digests every PollInterval and passes them to handler until ctx is cancelled
+// Digests are removed before the handler runs, so each is delivered at most once.
+// Run returns nil on cancellation
+func (n *Notifier[T]) Run(ctx context.Context, handler Handler[T]) error {
+	if n.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if handler == nil {
+		return fmt.Errorf("digest handler is nil")
+	}
+
+	ticker := time.NewTicker(n.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		n.drain(ctx, handler)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain pops and handles due digests until fewer than a full batch is returned
+func (n *Notifier[T]) drain(ctx context.Context, handler Handler[T]) {
+	for ctx.Err() == nil {
+		digests, err := n.Drain(ctx, time.Now(), n.cfg.BatchSize)
+		if err != nil {
+			n.reportError(err)
+			return
+		}
+		for _, digest := range digests {
+			if err := runHandler(ctx, handler, digest); err != nil {
+				n.reportError(fmt.Errorf("digest for %s failed: %w", digest.User, err))
+			}
+		}
+		if int64(len(digests)) < n.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func runHandler[T any](ctx context.Context, handler Handler[T], digest *Digest[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+		}
+	}()
+	return handler(ctx, digest)
+}
+
+func (n *Notifier[T]) reportError(err error) {
+	if n.cfg.OnError != nil && err != nil && !errors.Is(err, context.Canceled) {
+		n.cfg.OnError(err)
+	}
+}
@@ -0,0 +1,264 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+type event struct {
+	Actor string
+}
+
+func TestNewNotifier_Normalizes(t *testing.T) {
+	n := NewNotifier[event](nil, Config{QuietPeriod: -time.Second, MaxEvents: -1})
+	cfg := n.Config()
+	if cfg.KeyPrefix != DefaultKeyPrefix || cfg.Codec == nil || cfg.QuietPeriod != 0 || cfg.MaxDelay != 0 ||
+		cfg.MaxEvents != 0 || cfg.PollInterval != time.Second || cfg.BatchSize != 1 {
+		t.Errorf("NewNotifier() config = %+v, want normalized", cfg)
+	}
+
+	n = NewNotifier[event](nil, Config{QuietPeriod: time.Minute})
+	if n.Config().MaxDelay != time.Minute {
+		t.Errorf("NewNotifier() MaxDelay = %v, want QuietPeriod", n.Config().MaxDelay)
+	}
+}
+
+func TestNotifier_NilClient(t *testing.T) {
+	ctx := context.Background()
+	n := NewNotifier[event](nil, DefaultConfig("n"))
+
+	if _, err := n.Record(ctx, "alice", event{}); err == nil {
+		t.Error("Record() with nil client error = nil, want error")
+	}
+	if _, err := n.Discard(ctx, "alice"); err == nil {
+		t.Error("Discard() with nil client error = nil, want error")
+	}
+	if _, err := n.Pending(ctx); err == nil {
+		t.Error("Pending() with nil client error = nil, want error")
+	}
+	if _, err := n.Drain(ctx, time.Now(), 0); err == nil {
+		t.Error("Drain() with nil client error = nil, want error")
+	}
+	if err := n.Run(ctx, func(context.Context, *Digest[event]) error { return nil }); err == nil {
+		t.Error("Run() with nil client error = nil, want error")
+	}
+}
+
+func TestNotifier_QuietPeriod(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	n := NewNotifier[event](client, DefaultConfig("mentions").WithDelays(time.Minute, time.Hour))
+
+	start := time.Now()
+	for i, actor := range []string{"bob", "carol", "dave"} {
+		count, err := n.Record(ctx, "alice", event{Actor: actor})
+		if err != nil || count != int64(i+1) {
+			t.Fatalf("Record() = %d, %v, want %d", count, err, i+1)
+		}
+	}
+	_, _ = n.Record(ctx, "erin", event{Actor: "bob"})
+	if pending, _ := n.Pending(ctx); pending != 2 {
+		t.Errorf("Pending() = %d, want 2", pending)
+	}
+
+	// Nothing is due while events keep arriving within the quiet period
+	if digests, err := n.Drain(ctx, start.Add(30*time.Second), 0); err != nil || len(digests) != 0 {
+		t.Fatalf("Drain() before the quiet period = %v, %v, want none", digests, err)
+	}
+
+	digests, err := n.Drain(ctx, time.Now().Add(time.Minute), 1)
+	if err != nil || len(digests) != 1 {
+		t.Fatalf("Drain() = %v, %v, want one digest", digests, err)
+	}
+	d := digests[0]
+	if d.User != "alice" || d.Count != 3 || len(d.Events) != 3 || d.Events[0].Actor != "bob" || d.Events[2].Actor != "dave" {
+		t.Errorf("Drain() digest = %+v, want alice's three events in order", d)
+	}
+	if d.FirstAt.Before(start.Truncate(time.Millisecond)) || d.FirstAt.After(time.Now()) {
+		t.Errorf("Drain() FirstAt = %v, want the first Record", d.FirstAt)
+	}
+
+	digests, _ = n.Drain(ctx, time.Now().Add(time.Minute), 0)
+	if len(digests) != 1 || digests[0].User != "erin" {
+		t.Errorf("second Drain() = %v, want erin's digest", digests)
+	}
+	if pending, _ := n.Pending(ctx); pending != 0 {
+		t.Errorf("Pending() after draining = %d, want 0", pending)
+	}
+	if keys, _ := client.Keys(ctx, "notify:mentions:*").Result(); len(keys) != 0 {
+		t.Errorf("keys left after draining: %v", keys)
+	}
+
+	// A new event after a drain starts a new digest
+	if count, _ := n.Record(ctx, "alice", event{Actor: "frank"}); count != 1 {
+		t.Errorf("Record() after Drain = %d, want 1", count)
+	}
+}
+
+func TestNotifier_MaxDelay(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	n := NewNotifier[event](client, DefaultConfig("n").WithDelays(time.Hour, 10*time.Minute))
+	start := time.Now()
+	_, _ = n.Record(ctx, "alice", event{Actor: "bob"})
+
+	// A steady stream still produces a digest MaxDelay after the first event
+	score, _ := client.ZScore(ctx, "notify:n:due", "alice").Result()
+	due := time.UnixMilli(int64(score))
+	if due.Before(start.Add(10*time.Minute-time.Second)) || due.After(time.Now().Add(10*time.Minute)) {
+		t.Errorf("digest due at %v, want about 10m after the first event", due)
+	}
+	if digests, _ := n.Drain(ctx, start.Add(11*time.Minute), 0); len(digests) != 1 {
+		t.Errorf("Drain() after MaxDelay = %v, want one digest", digests)
+	}
+}
+
+func TestNotifier_MaxEvents(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	n := NewNotifier[int](client, DefaultConfig("n").WithMaxEvents(2))
+	for i := 1; i <= 5; i++ {
+		_, _ = n.Record(ctx, "alice", i)
+	}
+
+	digests, _ := n.Drain(ctx, time.Now().Add(time.Hour), 0)
+	if len(digests) != 1 || digests[0].Count != 5 || len(digests[0].Events) != 2 ||
+		digests[0].Events[0] != 4 || digests[0].Events[1] != 5 {
+		t.Errorf("Drain() = %+v, want count 5 with events 4 and 5", digests)
+	}
+}
+
+func TestNotifier_Discard(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	n := NewNotifier[event](client, DefaultConfig("n"))
+	_, _ = n.Record(ctx, "alice", event{Actor: "bob"})
+
+	if discarded, err := n.Discard(ctx, "alice"); err != nil || !discarded {
+		t.Errorf("Discard() = %v, %v, want true", discarded, err)
+	}
+	if discarded, _ := n.Discard(ctx, "alice"); discarded {
+		t.Error("Discard() twice = true, want false")
+	}
+	if digests, _ := n.Drain(ctx, time.Now().Add(time.Hour), 0); len(digests) != 0 {
+		t.Errorf("Drain() after Discard = %v, want none", digests)
+	}
+}
+
+func TestNotifier_UndecodableEvent(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	var errs []error
+	n := NewNotifier[event](client, DefaultConfig("n").WithOnError(func(err error) { errs = append(errs, err) }))
+	_, _ = n.Record(ctx, "alice", event{Actor: "bob"})
+	_ = client.RPush(ctx, "notify:n:events:alice", "not-json").Err()
+
+	digests, err := n.Drain(ctx, time.Now().Add(time.Hour), 0)
+	if err != nil || len(digests) != 1 || len(digests[0].Events) != 1 {
+		t.Fatalf("Drain() = %v, %v, want the decodable event", digests, err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("OnError received %v, want the decoding error", errs)
+	}
+}
+
+func TestNotifier_Run(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	var mu sync.Mutex
+	var errs []error
+	n := NewNotifier[event](client, DefaultConfig("n").
+		WithDelays(10*time.Millisecond, time.Second).
+		WithPollInterval(5*time.Millisecond).
+		WithOnError(func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, _ = n.Record(ctx, "alice", event{Actor: "bob"})
+	_, _ = n.Record(ctx, "panic", event{Actor: "bob"})
+
+	got := make(chan *Digest[event], 2)
+	done := make(chan error, 1)
+	go func() {
+		done <- n.Run(ctx, func(_ context.Context, d *Digest[event]) error {
+			if d.User == "panic" {
+				panic("boom")
+			}
+			got <- d
+			return nil
+		})
+	}()
+
+	select {
+	case d := <-got:
+		if d.User != "alice" || len(d.Events) != 1 {
+			t.Errorf("Run() digest = %+v, want alice's", d)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not deliver the digest")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v, want nil on cancellation", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 || !errors.Is(errs[0], ErrHandlerPanic) {
+		t.Errorf("OnError received %v, want ErrHandlerPanic", errs)
+	}
+
+	if err := n.Run(context.Background(), nil); err == nil {
+		t.Error("Run() with nil handler error = nil, want error")
+	}
+}
+
+func TestNotifier_RedisError(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	n := NewNotifier[event](client, DefaultConfig("n"))
+	if _, err := n.Record(ctx, "alice", event{}); err != nil {
+		t.Fatalf("Record() error = %v, want nil", err)
+	}
+
+	if _, err := NewNotifier[chan int](client, DefaultConfig("n")).Record(ctx, "alice", make(chan int)); err == nil {
+		t.Error("Record() unencodable event error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if _, err := n.Record(ctx, "alice", event{}); err == nil {
+		t.Error("Record() error = nil, want error")
+	}
+	if _, err := n.Discard(ctx, "alice"); err == nil {
+		t.Error("Discard() error = nil, want error")
+	}
+	if _, err := n.Pending(ctx); err == nil {
+		t.Error("Pending() error = nil, want error")
+	}
+	if _, err := n.Drain(ctx, time.Now(), 0); err == nil {
+		t.Error("Drain() error = nil, want error")
+	}
+}
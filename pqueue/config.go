@@ -0,0 +1,49 @@
+package pqueue
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// DefaultKeyPrefix is the default prefix for priority queue keys
+const DefaultKeyPrefix = "pqueue:"
+
+// Config represents priority queue configuration
+type Config struct {
+	// Key is the sorted set holding encoded items scored by priority (default: "pqueue:" + name)
+	Key string
+
+	// Codec encodes items (default: codec.Default())
+	// Equal items must encode to equal bytes, as an item is its own sorted set member
+	Codec codec.Codec
+
+	// PollInterval is how long a blocking Pop first waits after finding the queue empty (default: 50ms)
+	PollInterval time.Duration
+
+	// MaxPollInterval caps the wait, which doubles after every empty poll (default: 2s)
+	MaxPollInterval time.Duration
+}
+
+// DefaultConfig returns a Config with default values for the queue with the given name
+func DefaultConfig(name string) Config {
+	return Config{
+		Key:             DefaultKeyPrefix + name,
+		Codec:           codec.Default(),
+		PollInterval:    50 * time.Millisecond,
+		MaxPollInterval: 2 * time.Second,
+	}
+}
+
+// WithCodec sets the item codec
+func (c Config) WithCodec(cd codec.Codec) Config {
+	c.Codec = cd
+	return c
+}
+
+// WithPollInterval sets the first and the longest wait between polls of an empty queue
+func (c Config) WithPollInterval(initial, maxInterval time.Duration) Config {
+	c.PollInterval = initial
+	c.MaxPollInterval = maxInterval
+	return c
+}
@@ -0,0 +1,35 @@
+package pqueue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig("jobs")
+
+	if cfg.Key != "pqueue:jobs" {
+		t.Errorf("DefaultConfig().Key = %q, want %q", cfg.Key, "pqueue:jobs")
+	}
+	if cfg.Codec == nil || cfg.Codec.Name() != codec.NameJSON {
+		t.Errorf("DefaultConfig().Codec = %v, want json", cfg.Codec)
+	}
+	if cfg.PollInterval != 50*time.Millisecond || cfg.MaxPollInterval != 2*time.Second {
+		t.Errorf("DefaultConfig() poll intervals = %v/%v, want 50ms/2s", cfg.PollInterval, cfg.MaxPollInterval)
+	}
+}
+
+func TestConfig_With(t *testing.T) {
+	cfg := DefaultConfig("q").
+		WithCodec(codec.Msgpack).
+		WithPollInterval(time.Millisecond, 10*time.Millisecond)
+
+	if cfg.Codec.Name() != codec.NameMsgpack {
+		t.Errorf("WithCodec() = %q, want msgpack", cfg.Codec.Name())
+	}
+	if cfg.PollInterval != time.Millisecond || cfg.MaxPollInterval != 10*time.Millisecond {
+		t.Errorf("WithPollInterval() = %v/%v, want 1ms/10ms", cfg.PollInterval, cfg.MaxPollInterval)
+	}
+}
@@ -0,0 +1,236 @@
+// Package pqueue provides a priority queue on a Redis sorted set
+// Items are scored by priority and popped from either end, so urgent work can overtake
+// older work; use the queue package when jobs must be processed in arrival order
+package pqueue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+const popScript = `
+-- redis-kit:pqueue-pop
+local item
+if ARGV[1] == "highest" then
+	item = redis.call("zrevrange", KEYS[1], 0, 0, "withscores")
+else
+	item = redis.call("zrange", KEYS[1], 0, 0, "withscores")
+end
+if item[1] then
+	redis.call("zrem", KEYS[1], item[1])
+end
+return item
+`
+
+// Order selects the end of the queue an item is taken from
+type Order int
+
+const (
+	// Highest takes the item with the highest priority
+	Highest Order = iota
+
+	// Lowest takes the item with the lowest priority
+	Lowest
+)
+
+func (o Order) String() string {
+	if o == Lowest {
+		return "lowest"
+	}
+	return "highest"
+}
+
+// Item is a queued value with its priority
+type Item[T any] struct {
+	Value    T
+	Priority float64
+}
+
+// Queue is a priority queue shared by every process using the same key
+type Queue[T any] struct {
+	client *redis.Client
+	cfg    Config
+}
+
+// NewQueue creates a new priority queue; zero config values fall back to defaults
+// Key has no default, and operations on a queue without one return an error
+func NewQueue[T any](client *redis.Client, cfg Config) *Queue[T] {
+	if cfg.Codec == nil {
+		cfg.Codec = codec.Default()
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 50 * time.Millisecond
+	}
+	if cfg.MaxPollInterval <= 0 {
+		cfg.MaxPollInterval = 2 * time.Second
+	}
+	if cfg.MaxPollInterval < cfg.PollInterval {
+		cfg.MaxPollInterval = cfg.PollInterval
+	}
+	return &Queue[T]{client: client, cfg: cfg}
+}
+
+// Config returns the queue configuration
+func (q *Queue[T]) Config() Config {
+	return q.cfg
+}
+
+// Push adds value with the given priority
+// Pushing a value that is already queued only changes its priority
+func (q *Queue[T]) Push(ctx context.Context, value T, priority float64) error {
+	if err := q.check(); err != nil {
+		return err
+	}
+
+	data, err := q.cfg.Codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode queue item: %w", err)
+	}
+
+	if err := q.client.ZAdd(ctx, q.cfg.Key, redis.Z{Score: priority, Member: data}).Err(); err != nil {
+		return fmt.Errorf("failed to push queue item: %w", err)
+	}
+	return nil
+}
+
+// PopHighest removes and returns the item with the highest priority
+// The boolean is false if the queue is empty
+func (q *Queue[T]) PopHighest(ctx context.Context) (Item[T], bool, error) {
+	return q.TryPop(ctx, Highest)
+}
+
+// PopLowest removes and returns the item with the lowest priority
+// The boolean is false if the queue is empty
+func (q *Queue[T]) PopLowest(ctx context.Context) (Item[T], bool, error) {
+	return q.TryPop(ctx, Lowest)
+}
+
+// TryPop removes and returns the item at the given end without waiting
+// The read and removal run in one script, so concurrent consumers never get the same item
+func (q *Queue[T]) TryPop(ctx context.Context, order Order) (Item[T], bool, error) {
+	if err := q.check(); err != nil {
+		return Item[T]{}, false, err
+	}
+
+	res, err := q.client.Eval(ctx, popScript, []string{q.cfg.Key}, order.String()).StringSlice()
+	if err != nil {
+		return Item[T]{}, false, fmt.Errorf("failed to pop queue item: %w", err)
+	}
+	if len(res) < 2 {
+		return Item[T]{}, false, nil
+	}
+	priority, err := strconv.ParseFloat(res[1], 64)
+	if err != nil {
+		return Item[T]{}, false, fmt.Errorf("failed to parse item priority: %w", err)
+	}
+	return q.decode(res[0], priority)
+}
+
+// Pop removes and returns the item at the given end, waiting for one if the queue is empty
+// The queue is polled with a wait that doubles from PollInterval up to MaxPollInterval;
+// Pop returns ctx.Err() if ctx is done first
+func (q *Queue[T]) Pop(ctx context.Context, order Order) (Item[T], error) {
+	backoff := utils.RetryPolicy{
+		InitialBackoff: q.cfg.PollInterval,
+		MaxBackoff:     q.cfg.MaxPollInterval,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+
+	for attempt := 1; ; attempt++ {
+		item, ok, err := q.TryPop(ctx, order)
+		if err != nil {
+			return Item[T]{}, err
+		}
+		if ok {
+			return item, nil
+		}
+
+		timer := time.NewTimer(backoff.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Item[T]{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Peek returns the item at the given end without removing it
+// The boolean is false if the queue is empty
+func (q *Queue[T]) Peek(ctx context.Context, order Order) (Item[T], bool, error) {
+	if err := q.check(); err != nil {
+		return Item[T]{}, false, err
+	}
+
+	var cmd *redis.ZSliceCmd
+	if order == Lowest {
+		cmd = q.client.ZRangeWithScores(ctx, q.cfg.Key, 0, 0)
+	} else {
+		cmd = q.client.ZRevRangeWithScores(ctx, q.cfg.Key, 0, 0)
+	}
+	res, err := cmd.Result()
+	if err != nil {
+		return Item[T]{}, false, fmt.Errorf("failed to peek queue item: %w", err)
+	}
+	if len(res) == 0 {
+		return Item[T]{}, false, nil
+	}
+	member, _ := res[0].Member.(string)
+	return q.decode(member, res[0].Score)
+}
+
+// Remove removes value from the queue and reports whether it was queued
+func (q *Queue[T]) Remove(ctx context.Context, value T) (bool, error) {
+	if err := q.check(); err != nil {
+		return false, err
+	}
+
+	data, err := q.cfg.Codec.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode queue item: %w", err)
+	}
+
+	n, err := q.client.ZRem(ctx, q.cfg.Key, data).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove queue item: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Len returns the number of queued items
+func (q *Queue[T]) Len(ctx context.Context) (int64, error) {
+	if err := q.check(); err != nil {
+		return 0, err
+	}
+
+	n, err := q.client.ZCard(ctx, q.cfg.Key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get queue length: %w", err)
+	}
+	return n, nil
+}
+
+func (q *Queue[T]) check() error {
+	if q.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if q.cfg.Key == "" {
+		return fmt.Errorf("queue key is required")
+	}
+	return nil
+}
+
+func (q *Queue[T]) decode(member string, priority float64) (Item[T], bool, error) {
+	item := Item[T]{Priority: priority}
+	if err := q.cfg.Codec.Unmarshal([]byte(member), &item.Value); err != nil {
+		return Item[T]{}, false, fmt.Errorf("failed to decode queue item: %w", err)
+	}
+	return item, true, nil
+}
@@ -0,0 +1,209 @@
+package pqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+type job struct {
+	ID string `json:"id"`
+}
+
+func TestQueue_PushPop(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	q := NewQueue[job](client, DefaultConfig("jobs"))
+	for _, p := range []struct {
+		id       string
+		priority float64
+	}{{"a", 5}, {"b", 1}, {"c", 10}, {"d", 3}} {
+		if err := q.Push(ctx, job{ID: p.id}, p.priority); err != nil {
+			t.Fatalf("Push() error = %v, want nil", err)
+		}
+	}
+	if n, err := q.Len(ctx); err != nil || n != 4 {
+		t.Errorf("Len() = %d, %v, want 4", n, err)
+	}
+
+	if item, ok, err := q.Peek(ctx, Highest); err != nil || !ok || item.Value.ID != "c" || item.Priority != 10 {
+		t.Errorf("Peek(Highest) = %+v, %v, %v, want c/10", item, ok, err)
+	}
+	if item, ok, _ := q.Peek(ctx, Lowest); !ok || item.Value.ID != "b" {
+		t.Errorf("Peek(Lowest) = %+v, want b", item)
+	}
+	if n, _ := q.Len(ctx); n != 4 {
+		t.Errorf("Len() after Peek = %d, want 4", n)
+	}
+
+	if item, ok, err := q.PopHighest(ctx); err != nil || !ok || item.Value.ID != "c" || item.Priority != 10 {
+		t.Errorf("PopHighest() = %+v, %v, %v, want c/10", item, ok, err)
+	}
+	if item, ok, err := q.PopLowest(ctx); err != nil || !ok || item.Value.ID != "b" || item.Priority != 1 {
+		t.Errorf("PopLowest() = %+v, %v, %v, want b/1", item, ok, err)
+	}
+
+	// Pushing a queued item again only changes its priority
+	_ = q.Push(ctx, job{ID: "d"}, 100)
+	if n, _ := q.Len(ctx); n != 2 {
+		t.Errorf("Len() after re-push = %d, want 2", n)
+	}
+	if item, _, _ := q.PopHighest(ctx); item.Value.ID != "d" || item.Priority != 100 {
+		t.Errorf("PopHighest() after re-push = %+v, want d/100", item)
+	}
+
+	if removed, err := q.Remove(ctx, job{ID: "a"}); err != nil || !removed {
+		t.Errorf("Remove() = %v, %v, want true, nil", removed, err)
+	}
+	if removed, _ := q.Remove(ctx, job{ID: "a"}); removed {
+		t.Error("Remove() of a missing item = true, want false")
+	}
+
+	if _, ok, err := q.PopHighest(ctx); err != nil || ok {
+		t.Errorf("PopHighest() on empty queue = %v, %v, want false, nil", ok, err)
+	}
+	if _, ok, err := q.Peek(ctx, Lowest); err != nil || ok {
+		t.Errorf("Peek() on empty queue = %v, %v, want false, nil", ok, err)
+	}
+}
+
+func TestQueue_ConcurrentPop(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	q := NewQueue[int](client, DefaultConfig("ints"))
+	for i := 0; i < 50; i++ {
+		_ = q.Push(ctx, i, float64(i))
+	}
+
+	var mu sync.Mutex
+	seen := map[int]int{}
+	var wg sync.WaitGroup
+	for w := 0; w < 5; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				item, ok, err := q.PopHighest(ctx)
+				if err != nil || !ok {
+					return
+				}
+				mu.Lock()
+				seen[item.Value]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 50 {
+		t.Errorf("popped %d distinct items, want 50", len(seen))
+	}
+	for v, n := range seen {
+		if n != 1 {
+			t.Errorf("item %d popped %d times, want 1", v, n)
+		}
+	}
+}
+
+func TestQueue_BlockingPop(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	q := NewQueue[string](client, DefaultConfig("wait").WithPollInterval(time.Millisecond, 5*time.Millisecond))
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		_ = q.Push(ctx, "late", 1)
+	}()
+
+	popCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	item, err := q.Pop(popCtx, Lowest)
+	if err != nil || item.Value != "late" {
+		t.Fatalf("Pop() = %+v, %v, want late", item, err)
+	}
+
+	shortCtx, cancelShort := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelShort()
+	if _, err := q.Pop(shortCtx, Highest); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Pop() on empty queue error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestNewQueue_Defaults(t *testing.T) {
+	q := NewQueue[int](nil, Config{Key: "k", MaxPollInterval: time.Nanosecond})
+	cfg := q.Config()
+	if cfg.Codec == nil || cfg.PollInterval != 50*time.Millisecond || cfg.MaxPollInterval != cfg.PollInterval {
+		t.Errorf("NewQueue() config = %+v, want codec and 50ms poll intervals", cfg)
+	}
+
+	cfg = NewQueue[int](nil, Config{Key: "k"}).Config()
+	if cfg.PollInterval != 50*time.Millisecond || cfg.MaxPollInterval != 2*time.Second {
+		t.Errorf("NewQueue() zero config = %+v, want 50ms and 2s poll intervals", cfg)
+	}
+}
+
+func TestQueue_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	q := NewQueue[int](nil, DefaultConfig("x"))
+	if err := q.Push(ctx, 1, 1); err == nil {
+		t.Error("Push() with nil client error = nil, want error")
+	}
+	if _, _, err := q.PopHighest(ctx); err == nil {
+		t.Error("PopHighest() with nil client error = nil, want error")
+	}
+	if _, err := q.Pop(ctx, Lowest); err == nil {
+		t.Error("Pop() with nil client error = nil, want error")
+	}
+	if _, _, err := q.Peek(ctx, Highest); err == nil {
+		t.Error("Peek() with nil client error = nil, want error")
+	}
+	if _, err := q.Remove(ctx, 1); err == nil {
+		t.Error("Remove() with nil client error = nil, want error")
+	}
+	if _, err := q.Len(ctx); err == nil {
+		t.Error("Len() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	q = NewQueue[int](client, Config{})
+	if err := q.Push(ctx, 1, 1); err == nil {
+		t.Error("Push() without a key error = nil, want error")
+	}
+	if _, err := q.Len(ctx); err == nil {
+		t.Error("Len() without a key error = nil, want error")
+	}
+
+	q = NewQueue[int](client, DefaultConfig("x"))
+	_ = client.ZAdd(ctx, q.Config().Key, redis.Z{Score: 1, Member: "not-json"}).Err()
+	if _, _, err := q.PopLowest(ctx); err == nil {
+		t.Error("PopLowest() undecodable item error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if err := q.Push(ctx, 1, 1); err == nil {
+		t.Error("Push() error = nil, want error")
+	}
+	if _, _, err := q.PopHighest(ctx); err == nil {
+		t.Error("PopHighest() error = nil, want error")
+	}
+	if _, _, err := q.Peek(ctx, Lowest); err == nil {
+		t.Error("Peek() error = nil, want error")
+	}
+	if _, err := q.Len(ctx); err == nil {
+		t.Error("Len() error = nil, want error")
+	}
+}
@@ -0,0 +1,22 @@
+package presence
+
+import "time"
+
+// Option configures a Tracker
+type Option func(*Tracker)
+
+// WithKeyPrefix sets the prefix of the presence key (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(t *Tracker) {
+		t.keyPrefix = prefix
+	}
+}
+
+// WithDefaultTTL sets the TTL used by heartbeats that pass a non-positive TTL (default: DefaultTTL)
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(t *Tracker) {
+		if ttl > 0 {
+			t.defaultTTL = ttl
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package presence tracks which users or instances are online from periodic heartbeats
+package presence
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for presence keys
+	DefaultKeyPrefix = "presence:"
+
+	// DefaultTTL is how long a heartbeat keeps an id online by default
+	DefaultTTL = 30 * time.Second
+)
+
+// Entry is one online id
+type Entry struct {
+	// ID is the user or instance id
+	ID string
+
+	// ExpiresAt is when the id goes offline unless it sends another heartbeat
+	ExpiresAt time.Time
+}
+
+// Tracker records heartbeats in a sorted set scored by each id's expiry, so ids that
+// stopped sending heartbeats drop out of queries and are pruned lazily
+type Tracker struct {
+	client     *redis.Client
+	name       string
+	keyPrefix  string
+	defaultTTL time.Duration
+}
+
+// NewTracker creates a presence tracker for the group name (e.g. "chat", "workers")
+func NewTracker(client *redis.Client, name string, opts ...Option) *Tracker {
+	t := &Tracker{
+		client:     client,
+		name:       name,
+		keyPrefix:  DefaultKeyPrefix,
+		defaultTTL: DefaultTTL,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Key returns the Redis key of the tracker's sorted set
+func (t *Tracker) Key() string {
+	return t.keyPrefix + t.name
+}
+
+func toMillis(tm time.Time) string {
+	return strconv.FormatInt(tm.UnixMilli(), 10)
+}
+
+// Heartbeat marks id online for ttl from now; a non-positive ttl uses the default TTL
+func (t *Tracker) Heartbeat(ctx context.Context, id string, ttl time.Duration) error {
+	if t.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if ttl <= 0 {
+		ttl = t.defaultTTL
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	if err := t.client.ZAdd(ctx, t.Key(), redis.Z{Score: float64(expiresAt.UnixMilli()), Member: id}).Err(); err != nil {
+		return fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+	return nil
+}
+
+// Leave marks id offline right away
+func (t *Tracker) Leave(ctx context.Context, id string) error {
+	if t.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := t.client.ZRem(ctx, t.Key(), id).Err(); err != nil {
+		return fmt.Errorf("failed to remove presence: %w", err)
+	}
+	return nil
+}
+
+// Online reports whether id sent a heartbeat that has not expired yet
+func (t *Tracker) Online(ctx context.Context, id string) (bool, error) {
+	if t.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+
+	score, err := t.client.ZScore(ctx, t.Key(), id).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check presence: %w", err)
+	}
+	return int64(score) > time.Now().UnixMilli(), nil
+}
+
+// ExpiresAt returns when id goes offline, or false if it is not online
+func (t *Tracker) ExpiresAt(ctx context.Context, id string) (time.Time, bool, error) {
+	if t.client == nil {
+		return time.Time{}, false, fmt.Errorf("redis client is nil")
+	}
+
+	score, err := t.client.ZScore(ctx, t.Key(), id).Result()
+	if err == redis.Nil {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to check presence: %w", err)
+	}
+	expiresAt := time.UnixMilli(int64(score))
+	if !expiresAt.After(time.Now()) {
+		return time.Time{}, false, nil
+	}
+	return expiresAt, true, nil
+}
+
+// CountOnline returns the number of online ids, pruning expired ones
+func (t *Tracker) CountOnline(ctx context.Context) (int64, error) {
+	if t.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	now := toMillis(time.Now())
+	pipe := t.client.Pipeline()
+	pipe.ZRemRangeByScore(ctx, t.Key(), "-inf", now)
+	count := pipe.ZCount(ctx, t.Key(), "("+now, "+inf")
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to count online ids: %w", err)
+	}
+	return count.Val(), nil
+}
+
+// ListOnline returns up to limit online ids (0 means all), pruning expired ones
+// Ids with the latest expiry come first, which is the most recently seen when ids share a TTL
+func (t *Tracker) ListOnline(ctx context.Context, limit int64) ([]Entry, error) {
+	if t.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if limit < 0 {
+		limit = 0
+	}
+
+	now := toMillis(time.Now())
+	pipe := t.client.Pipeline()
+	pipe.ZRemRangeByScore(ctx, t.Key(), "-inf", now)
+	members := pipe.ZRevRangeByScoreWithScores(ctx, t.Key(), &redis.ZRangeBy{
+		Min:   "(" + now,
+		Max:   "+inf",
+		Count: limit,
+	})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list online ids: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(members.Val()))
+	for _, z := range members.Val() {
+		id, _ := z.Member.(string)
+		entries = append(entries, Entry{ID: id, ExpiresAt: time.UnixMilli(int64(z.Score))})
+	}
+	return entries, nil
+}
+
+// Prune removes expired ids and returns how many were removed
+// Queries prune as they go, so calling Prune is only needed to bound memory for write-only trackers
+func (t *Tracker) Prune(ctx context.Context) (int64, error) {
+	if t.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	n, err := t.client.ZRemRangeByScore(ctx, t.Key(), "-inf", toMillis(time.Now())).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune presence: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,140 @@
+package presence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestTracker_Heartbeat(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	tr := NewTracker(client, "chat")
+	if tr.Key() != "presence:chat" {
+		t.Errorf("Key() = %q, want presence:chat", tr.Key())
+	}
+
+	if err := tr.Heartbeat(ctx, "alice", time.Minute); err != nil {
+		t.Fatalf("Heartbeat() error = %v, want nil", err)
+	}
+	if err := tr.Heartbeat(ctx, "bob", 0); err != nil {
+		t.Fatalf("Heartbeat() default ttl error = %v, want nil", err)
+	}
+
+	if on, err := tr.Online(ctx, "alice"); err != nil || !on {
+		t.Errorf("Online(alice) = %v, %v, want true", on, err)
+	}
+	if on, err := tr.Online(ctx, "carol"); err != nil || on {
+		t.Errorf("Online(carol) = %v, %v, want false", on, err)
+	}
+	expiresAt, ok, err := tr.ExpiresAt(ctx, "bob")
+	if err != nil || !ok || time.Until(expiresAt) <= 0 || time.Until(expiresAt) > DefaultTTL {
+		t.Errorf("ExpiresAt(bob) = %v, %v, %v, want within %v", expiresAt, ok, err, DefaultTTL)
+	}
+	if _, ok, _ := tr.ExpiresAt(ctx, "carol"); ok {
+		t.Error("ExpiresAt(carol) ok = true, want false")
+	}
+
+	if n, err := tr.CountOnline(ctx); err != nil || n != 2 {
+		t.Errorf("CountOnline() = %d, %v, want 2", n, err)
+	}
+	entries, err := tr.ListOnline(ctx, 0)
+	if err != nil || len(entries) != 2 || entries[0].ID != "alice" || entries[1].ID != "bob" {
+		t.Errorf("ListOnline() = %v, %v, want alice, bob", entries, err)
+	}
+	if entries, _ := tr.ListOnline(ctx, 1); len(entries) != 1 {
+		t.Errorf("ListOnline(1) = %v, want 1 entry", entries)
+	}
+
+	if err := tr.Leave(ctx, "alice"); err != nil {
+		t.Fatalf("Leave() error = %v, want nil", err)
+	}
+	if on, _ := tr.Online(ctx, "alice"); on {
+		t.Error("Online(alice) after Leave() = true, want false")
+	}
+}
+
+func TestTracker_Expiry(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	tr := NewTracker(client, "workers", WithKeyPrefix("test:"), WithDefaultTTL(time.Minute))
+
+	_ = tr.Heartbeat(ctx, "w1", 20*time.Millisecond)
+	_ = tr.Heartbeat(ctx, "w2", 20*time.Millisecond)
+	_ = tr.Heartbeat(ctx, "w3", 0)
+	time.Sleep(40 * time.Millisecond)
+
+	if on, _ := tr.Online(ctx, "w1"); on {
+		t.Error("Online(w1) after expiry = true, want false")
+	}
+	if n, _ := client.ZCard(ctx, "test:workers").Result(); n != 3 {
+		t.Errorf("ZCard() before pruning = %d, want 3", n)
+	}
+	if n, err := tr.CountOnline(ctx); err != nil || n != 1 {
+		t.Errorf("CountOnline() = %d, %v, want 1", n, err)
+	}
+	if n, _ := client.ZCard(ctx, "test:workers").Result(); n != 1 {
+		t.Errorf("ZCard() after pruning = %d, want 1", n)
+	}
+
+	_ = tr.Heartbeat(ctx, "w4", 20*time.Millisecond)
+	time.Sleep(40 * time.Millisecond)
+	if entries, err := tr.ListOnline(ctx, 0); err != nil || len(entries) != 1 || entries[0].ID != "w3" {
+		t.Errorf("ListOnline() = %v, %v, want w3", entries, err)
+	}
+
+	_ = tr.Heartbeat(ctx, "w5", 20*time.Millisecond)
+	time.Sleep(40 * time.Millisecond)
+	if n, err := tr.Prune(ctx); err != nil || n != 1 {
+		t.Errorf("Prune() = %d, %v, want 1", n, err)
+	}
+}
+
+func TestTracker_Errors(t *testing.T) {
+	ctx := context.Background()
+	tr := NewTracker(nil, "x")
+	if err := tr.Heartbeat(ctx, "a", time.Second); err == nil {
+		t.Error("Heartbeat() with nil client error = nil, want error")
+	}
+	if _, err := tr.Online(ctx, "a"); err == nil {
+		t.Error("Online() with nil client error = nil, want error")
+	}
+	if _, _, err := tr.ExpiresAt(ctx, "a"); err == nil {
+		t.Error("ExpiresAt() with nil client error = nil, want error")
+	}
+	if _, err := tr.CountOnline(ctx); err == nil {
+		t.Error("CountOnline() with nil client error = nil, want error")
+	}
+	if _, err := tr.ListOnline(ctx, 0); err == nil {
+		t.Error("ListOnline() with nil client error = nil, want error")
+	}
+	if err := tr.Leave(ctx, "a"); err == nil {
+		t.Error("Leave() with nil client error = nil, want error")
+	}
+	if _, err := tr.Prune(ctx); err == nil {
+		t.Error("Prune() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	tr = NewTracker(client, "x")
+	mock.SetShouldFail(true)
+	if err := tr.Heartbeat(ctx, "a", time.Second); err == nil {
+		t.Error("Heartbeat() with failing redis error = nil, want error")
+	}
+	if _, err := tr.Online(ctx, "a"); err == nil {
+		t.Error("Online() with failing redis error = nil, want error")
+	}
+	if _, err := tr.CountOnline(ctx); err == nil {
+		t.Error("CountOnline() with failing redis error = nil, want error")
+	}
+	if _, err := tr.ListOnline(ctx, 0); err == nil {
+		t.Error("ListOnline() with failing redis error = nil, want error")
+	}
+}
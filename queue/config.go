@@ -0,0 +1,162 @@
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+const (
+	// DefaultStreamPrefix is the default prefix for queue stream keys
+	DefaultStreamPrefix = "queue:"
+
+	// DefaultGroup is the default consumer group name
+	DefaultGroup = "workers"
+
+	// DefaultDeadLetterSuffix is appended to the stream key to name the dead-letter stream
+	DefaultDeadLetterSuffix = ":dead"
+)
+
+// Config represents job queue configuration
+type Config struct {
+	// Stream is the Redis stream key holding the jobs (default: "queue:" + name)
+	Stream string
+
+	// Group is the consumer group shared by all workers of the queue (default: "workers")
+	Group string
+
+	// Consumer identifies this process within the group (default: hostname, pid and a random suffix)
+	Consumer string
+
+	// Codec encodes job payloads (default: codec.Default())
+	Codec codec.Codec
+
+	// Concurrency is the number of jobs handled in parallel by Consume (default: 4)
+	Concurrency int
+
+	// BatchSize is the maximum number of jobs fetched per read (default: 10)
+	BatchSize int64
+
+	// BlockTimeout is how long a read waits for new jobs (default: 2s)
+	BlockTimeout time.Duration
+
+	// ClaimInterval is how often Consume looks for stuck jobs (default: 30s)
+	ClaimInterval time.Duration
+
+	// ClaimMinIdle is how long a job must be pending before another consumer may claim it (default: 1m)
+	ClaimMinIdle time.Duration
+
+	// MaxDeliveries is the number of deliveries after which a failing job is dead-lettered (default: 5)
+	MaxDeliveries int64
+
+	// DeadLetterStream receives jobs that exhausted their deliveries (default: Stream + ":dead")
+	DeadLetterStream string
+
+	// MaxLen approximately caps the stream length on enqueue (0 means unbounded)
+	MaxLen int64
+
+	// OnError is called with errors from the background fetch and claim loops (optional)
+	OnError func(error)
+}
+
+// DefaultConfig returns a Config with default values for the queue with the given name
+func DefaultConfig(name string) Config {
+	stream := DefaultStreamPrefix + name
+	return Config{
+		Stream:           stream,
+		Group:            DefaultGroup,
+		Consumer:         defaultConsumerName(),
+		Codec:            codec.Default(),
+		Concurrency:      4,
+		BatchSize:        10,
+		BlockTimeout:     2 * time.Second,
+		ClaimInterval:    30 * time.Second,
+		ClaimMinIdle:     time.Minute,
+		MaxDeliveries:    5,
+		DeadLetterStream: stream + DefaultDeadLetterSuffix,
+	}
+}
+
+// WithGroup sets the consumer group name
+func (c Config) WithGroup(group string) Config {
+	c.Group = group
+	return c
+}
+
+// WithConsumer sets the consumer name of this process
+func (c Config) WithConsumer(consumer string) Config {
+	c.Consumer = consumer
+	return c
+}
+
+// WithCodec sets the payload codec
+func (c Config) WithCodec(cd codec.Codec) Config {
+	c.Codec = cd
+	return c
+}
+
+// WithConcurrency sets the number of parallel workers
+func (c Config) WithConcurrency(n int) Config {
+	c.Concurrency = n
+	return c
+}
+
+// WithBatchSize sets the maximum number of jobs fetched per read
+func (c Config) WithBatchSize(n int64) Config {
+	c.BatchSize = n
+	return c
+}
+
+// WithBlockTimeout sets how long a read waits for new jobs
+func (c Config) WithBlockTimeout(timeout time.Duration) Config {
+	c.BlockTimeout = timeout
+	return c
+}
+
+// WithClaim sets how often stuck jobs are claimed and how long they must be idle first
+func (c Config) WithClaim(interval, minIdle time.Duration) Config {
+	c.ClaimInterval = interval
+	c.ClaimMinIdle = minIdle
+	return c
+}
+
+// WithMaxDeliveries sets the number of deliveries before a job is dead-lettered
+func (c Config) WithMaxDeliveries(n int64) Config {
+	c.MaxDeliveries = n
+	return c
+}
+
+// WithDeadLetterStream sets the dead-letter stream key
+func (c Config) WithDeadLetterStream(stream string) Config {
+	c.DeadLetterStream = stream
+	return c
+}
+
+// WithMaxLen sets the approximate maximum stream length
+func (c Config) WithMaxLen(maxLen int64) Config {
+	c.MaxLen = maxLen
+	return c
+}
+
+// WithOnError sets the callback for background loop errors
+func (c Config) WithOnError(fn func(error)) Config {
+	c.OnError = fn
+	return c
+}
+
+// defaultConsumerName builds a consumer name that is unique per process
+func defaultConsumerName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "consumer"
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return host + "-" + strconv.Itoa(os.Getpid())
+	}
+	return host + "-" + strconv.Itoa(os.Getpid()) + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,83 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig("emails")
+
+	if cfg.Stream != "queue:emails" {
+		t.Errorf("DefaultConfig().Stream = %q, want %q", cfg.Stream, "queue:emails")
+	}
+	if cfg.Group != DefaultGroup {
+		t.Errorf("DefaultConfig().Group = %q, want %q", cfg.Group, DefaultGroup)
+	}
+	if cfg.Consumer == "" {
+		t.Error("DefaultConfig().Consumer should not be empty")
+	}
+	if cfg.Codec == nil || cfg.Codec.Name() != codec.NameJSON {
+		t.Errorf("DefaultConfig().Codec = %v, want json", cfg.Codec)
+	}
+	if cfg.Concurrency != 4 {
+		t.Errorf("DefaultConfig().Concurrency = %d, want 4", cfg.Concurrency)
+	}
+	if cfg.BatchSize != 10 {
+		t.Errorf("DefaultConfig().BatchSize = %d, want 10", cfg.BatchSize)
+	}
+	if cfg.BlockTimeout != 2*time.Second {
+		t.Errorf("DefaultConfig().BlockTimeout = %v, want %v", cfg.BlockTimeout, 2*time.Second)
+	}
+	if cfg.ClaimInterval != 30*time.Second || cfg.ClaimMinIdle != time.Minute {
+		t.Errorf("DefaultConfig() claim = %v/%v, want 30s/1m", cfg.ClaimInterval, cfg.ClaimMinIdle)
+	}
+	if cfg.MaxDeliveries != 5 {
+		t.Errorf("DefaultConfig().MaxDeliveries = %d, want 5", cfg.MaxDeliveries)
+	}
+	if cfg.DeadLetterStream != "queue:emails:dead" {
+		t.Errorf("DefaultConfig().DeadLetterStream = %q, want %q", cfg.DeadLetterStream, "queue:emails:dead")
+	}
+
+	if other := DefaultConfig("emails"); other.Consumer == cfg.Consumer {
+		t.Error("DefaultConfig() should generate unique consumer names")
+	}
+}
+
+func TestConfig_With(t *testing.T) {
+	var called bool
+	cfg := DefaultConfig("q").
+		WithGroup("g").
+		WithConsumer("c").
+		WithCodec(codec.Msgpack).
+		WithConcurrency(2).
+		WithBatchSize(5).
+		WithBlockTimeout(time.Second).
+		WithClaim(time.Second, 2*time.Second).
+		WithMaxDeliveries(3).
+		WithDeadLetterStream("dlq").
+		WithMaxLen(1000).
+		WithOnError(func(error) { called = true })
+
+	if cfg.Group != "g" || cfg.Consumer != "c" {
+		t.Errorf("WithGroup/WithConsumer = %q/%q, want g/c", cfg.Group, cfg.Consumer)
+	}
+	if cfg.Codec.Name() != codec.NameMsgpack {
+		t.Errorf("WithCodec() = %q, want msgpack", cfg.Codec.Name())
+	}
+	if cfg.Concurrency != 2 || cfg.BatchSize != 5 || cfg.BlockTimeout != time.Second {
+		t.Errorf("WithConcurrency/WithBatchSize/WithBlockTimeout = %d/%d/%v", cfg.Concurrency, cfg.BatchSize, cfg.BlockTimeout)
+	}
+	if cfg.ClaimInterval != time.Second || cfg.ClaimMinIdle != 2*time.Second {
+		t.Errorf("WithClaim() = %v/%v, want 1s/2s", cfg.ClaimInterval, cfg.ClaimMinIdle)
+	}
+	if cfg.MaxDeliveries != 3 || cfg.DeadLetterStream != "dlq" || cfg.MaxLen != 1000 {
+		t.Errorf("WithMaxDeliveries/WithDeadLetterStream/WithMaxLen = %d/%q/%d", cfg.MaxDeliveries, cfg.DeadLetterStream, cfg.MaxLen)
+	}
+	cfg.OnError(nil)
+	if !called {
+		t.Error("WithOnError() callback not set")
+	}
+}
@@ -0,0 +1,10 @@
+package queue
+
+import "errors"
+
+var (
+	// ErrMaxDeliveries indicates a job was delivered more often than the queue allows.
+	ErrMaxDeliveries = errors.New("job exceeded max deliveries")
+	// ErrHandlerPanic indicates the job handler panicked while processing a job.
+	ErrHandlerPanic = errors.New("job handler panicked")
+)
@@ -0,0 +1,566 @@
+// Package queue provides a job queue built on Redis Streams consumer groups
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// DefaultOperationTimeout bounds the ack and dead-letter calls made after a handler returns
+const DefaultOperationTimeout = 5 * time.Second
+
+const fieldPayload = "payload"
+
+const deadLetterScript = `
+-- redis-kit:queue-deadletter
+redis.call("xadd", KEYS[2], "*", "payload", ARGV[2], "error", ARGV[3], "source_id", ARGV[1], "attempts", ARGV[4], "failed_at", ARGV[5])
+return redis.call("xack", KEYS[1], ARGV[6], ARGV[1])
+`
+
+// loopBackoff paces the background loops after consecutive Redis errors
+var loopBackoff = utils.DefaultRetryPolicy().WithBackoff(100*time.Millisecond, 5*time.Second)
+
+// Handler processes a single job; returning an error leaves the job for redelivery
+type Handler func(ctx context.Context, job *Job) error
+
+// Job is a message delivered from the queue
+type Job struct {
+	// ID is the stream entry ID
+	ID string
+
+	// Payload is the encoded job payload
+	Payload []byte
+
+	// Attempts is the number of times the job has been delivered, including this delivery
+	Attempts int64
+
+	// EnqueuedAt is the time the job was added, derived from its ID
+	EnqueuedAt time.Time
+
+	codec codec.Codec
+}
+
+// Decode unmarshals the job payload into v using the queue's codec
+func (j *Job) Decode(v interface{}) error {
+	if err := j.codec.Unmarshal(j.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode job payload: %w", err)
+	}
+	return nil
+}
+
+// DeadLetter is a job that was moved to the dead-letter stream
+type DeadLetter struct {
+	// ID is the entry ID in the dead-letter stream
+	ID string
+
+	// SourceID is the ID the job had in the queue stream
+	SourceID string
+
+	// Payload is the encoded job payload
+	Payload []byte
+
+	// Error is the failure that caused the job to be dead-lettered
+	Error string
+
+	// Attempts is the number of deliveries the job received
+	Attempts int64
+
+	// FailedAt is the time the job was dead-lettered
+	FailedAt time.Time
+}
+
+// Queue is a Redis Streams backed job queue
+type Queue struct {
+	client *redis.Client
+	cfg    Config
+
+	claimMu     sync.Mutex
+	claimCursor string
+}
+
+// NewQueue creates a new job queue; zero config values fall back to sane minimums
+func NewQueue(client *redis.Client, cfg Config) *Queue {
+	if cfg.Group == "" {
+		cfg.Group = DefaultGroup
+	}
+	if cfg.Consumer == "" {
+		cfg.Consumer = defaultConsumerName()
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = codec.Default()
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 1
+	}
+	return &Queue{
+		client:      client,
+		cfg:         cfg,
+		claimCursor: "0-0",
+	}
+}
+
+// Config returns the queue configuration
+func (q *Queue) Config() Config {
+	return q.cfg
+}
+
+// EnsureGroup creates the stream and consumer group if they do not exist yet
+// A new group starts at the beginning of the stream, so jobs enqueued earlier are delivered
+func (q *Queue) EnsureGroup(ctx context.Context) error {
+	if q.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	err := q.client.XGroupCreateMkStream(ctx, q.cfg.Stream, q.cfg.Group, "0").Err()
+	if err != nil && !redis.HasErrorPrefix(err, "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+	return nil
+}
+
+// Enqueue encodes payload and appends it to the queue, returning the job ID
+func (q *Queue) Enqueue(ctx context.Context, payload interface{}) (string, error) {
+	if q.client == nil {
+		return "", fmt.Errorf("redis client is nil")
+	}
+
+	data, err := q.cfg.Codec.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode job payload: %w", err)
+	}
+	return q.EnqueueRaw(ctx, data)
+}
+
+// EnqueueRaw appends a payload that is already encoded with the queue's codec
+func (q *Queue) EnqueueRaw(ctx context.Context, data []byte) (string, error) {
+	if q.client == nil {
+		return "", fmt.Errorf("redis client is nil")
+	}
+
+	id, err := q.client.XAdd(ctx, q.addArgs(data)).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return id, nil
+}
+
+// EnqueueRawPipelined queues an already encoded payload on pipe, e.g. a TxPipeline that
+// also writes the records the job refers to; the job ID is the command's value after Exec
+func (q *Queue) EnqueueRawPipelined(ctx context.Context, pipe redis.Pipeliner, data []byte) *redis.StringCmd {
+	return pipe.XAdd(ctx, q.addArgs(data))
+}
+
+func (q *Queue) addArgs(data []byte) *redis.XAddArgs {
+	args := &redis.XAddArgs{
+		Stream: q.cfg.Stream,
+		Values: []interface{}{fieldPayload, data},
+	}
+	if q.cfg.MaxLen > 0 {
+		args.MaxLen = q.cfg.MaxLen
+		args.Approx = true
+	}
+	return args
+}
+
+// Read fetches up to BatchSize new jobs for this consumer, waiting up to BlockTimeout
+// A BlockTimeout of zero or less makes the read return immediately
+func (q *Queue) Read(ctx context.Context) ([]*Job, error) {
+	if q.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	block := q.cfg.BlockTimeout
+	if block <= 0 {
+		block = -1
+	}
+
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.cfg.Group,
+		Consumer: q.cfg.Consumer,
+		Streams:  []string{q.cfg.Stream, ">"},
+		Count:    q.cfg.BatchSize,
+		Block:    block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs: %w", err)
+	}
+
+	var jobs []*Job
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			jobs = append(jobs, q.newJob(msg, 1))
+		}
+	}
+	return jobs, nil
+}
+
+// Ack marks jobs as done, removing them from the group's pending list
+func (q *Queue) Ack(ctx context.Context, ids ...string) error {
+	if q.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := q.client.XAck(ctx, q.cfg.Stream, q.cfg.Group, ids...).Err(); err != nil {
+		return fmt.Errorf("failed to ack jobs: %w", err)
+	}
+	return nil
+}
+
+// Nack records a failed delivery
+// Jobs that reached MaxDeliveries are dead-lettered; others stay pending and are
+// redelivered once they have been idle for ClaimMinIdle
+func (q *Queue) Nack(ctx context.Context, job *Job, cause error) error {
+	if q.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if job == nil {
+		return nil
+	}
+
+	if q.cfg.MaxDeliveries > 0 && job.Attempts >= q.cfg.MaxDeliveries {
+		return q.DeadLetter(ctx, job, cause)
+	}
+	return nil
+}
+
+// DeadLetter atomically moves a job to the dead-letter stream and acks it
+// Without a DeadLetterStream the job is only acked, i.e. dropped
+func (q *Queue) DeadLetter(ctx context.Context, job *Job, cause error) error {
+	if q.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if q.cfg.DeadLetterStream == "" {
+		return q.Ack(ctx, job.ID)
+	}
+
+	reason := ""
+	if cause != nil {
+		reason = cause.Error()
+	}
+
+	err := q.client.Eval(ctx, deadLetterScript,
+		[]string{q.cfg.Stream, q.cfg.DeadLetterStream},
+		job.ID, job.Payload, reason, job.Attempts, time.Now().UnixMilli(), q.cfg.Group,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter job: %w", err)
+	}
+	return nil
+}
+
+// Claim takes over up to BatchSize jobs that have been pending longer than ClaimMinIdle
+// Claimed jobs that already exceeded MaxDeliveries are dead-lettered instead of returned
+func (q *Queue) Claim(ctx context.Context) ([]*Job, error) {
+	if q.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	q.claimMu.Lock()
+	start := q.claimCursor
+	q.claimMu.Unlock()
+
+	msgs, next, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   q.cfg.Stream,
+		Group:    q.cfg.Group,
+		Consumer: q.cfg.Consumer,
+		MinIdle:  q.cfg.ClaimMinIdle,
+		Start:    start,
+		Count:    q.cfg.BatchSize,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+
+	q.claimMu.Lock()
+	q.claimCursor = next
+	q.claimMu.Unlock()
+
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+
+	attempts, err := q.deliveryCounts(ctx, msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(msgs))
+	for _, msg := range msgs {
+		job := q.newJob(msg, attempts[msg.ID])
+		if q.cfg.MaxDeliveries > 0 && job.Attempts > q.cfg.MaxDeliveries {
+			if err := q.DeadLetter(ctx, job, ErrMaxDeliveries); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// deliveryCounts looks up how often each claimed message has been delivered
+func (q *Queue) deliveryCounts(ctx context.Context, msgs []redis.XMessage) (map[string]int64, error) {
+	pipe := q.client.Pipeline()
+	cmds := make([]*redis.XPendingExtCmd, len(msgs))
+	for i, msg := range msgs {
+		cmds[i] = pipe.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: q.cfg.Stream,
+			Group:  q.cfg.Group,
+			Start:  msg.ID,
+			End:    msg.ID,
+			Count:  1,
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read delivery counts: %w", err)
+	}
+
+	counts := make(map[string]int64, len(msgs))
+	for _, cmd := range cmds {
+		for _, p := range cmd.Val() {
+			counts[p.ID] = p.RetryCount
+		}
+	}
+	return counts, nil
+}
+
+// Len returns the number of entries in the queue stream, including pending ones
+func (q *Queue) Len(ctx context.Context) (int64, error) {
+	if q.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	n, err := q.client.XLen(ctx, q.cfg.Stream).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get queue length: %w", err)
+	}
+	return n, nil
+}
+
+// Pending returns the number of delivered but unacknowledged jobs
+func (q *Queue) Pending(ctx context.Context) (int64, error) {
+	if q.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	res, err := q.client.XPending(ctx, q.cfg.Stream, q.cfg.Group).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending jobs: %w", err)
+	}
+	return res.Count, nil
+}
+
+// DeadLetters returns up to count entries from the dead-letter stream, oldest first
+func (q *Queue) DeadLetters(ctx context.Context, count int64) ([]DeadLetter, error) {
+	if q.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if q.cfg.DeadLetterStream == "" {
+		return nil, nil
+	}
+
+	msgs, err := q.client.XRangeN(ctx, q.cfg.DeadLetterStream, "-", "+", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+
+	letters := make([]DeadLetter, 0, len(msgs))
+	for _, msg := range msgs {
+		attempts, _ := strconv.ParseInt(stringValue(msg.Values, "attempts"), 10, 64)
+		failedAt, _ := strconv.ParseInt(stringValue(msg.Values, "failed_at"), 10, 64)
+		letters = append(letters, DeadLetter{
+			ID:       msg.ID,
+			SourceID: stringValue(msg.Values, "source_id"),
+			Payload:  []byte(stringValue(msg.Values, fieldPayload)),
+			Error:    stringValue(msg.Values, "error"),
+			Attempts: attempts,
+			FailedAt: time.UnixMilli(failedAt),
+		})
+	}
+	return letters, nil
+}
+
+// Consume runs a worker pool that processes jobs until ctx is cancelled
+// Successful jobs are acked, failed ones are nacked; stuck jobs of other consumers
+// are claimed every ClaimInterval. Consume returns nil once in-flight jobs finish
+func (q *Queue) Consume(ctx context.Context, handler Handler) error {
+	if q.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if handler == nil {
+		return fmt.Errorf("job handler is nil")
+	}
+	if err := q.EnsureGroup(ctx); err != nil {
+		return err
+	}
+
+	jobs := make(chan *Job)
+	var workers sync.WaitGroup
+	for i := 0; i < q.cfg.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				q.process(ctx, handler, job)
+			}
+		}()
+	}
+
+	var loops sync.WaitGroup
+	if q.cfg.ClaimInterval > 0 {
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			q.claimLoop(ctx, jobs)
+		}()
+	}
+	q.fetchLoop(ctx, jobs)
+
+	loops.Wait()
+	close(jobs)
+	workers.Wait()
+	return nil
+}
+
+func (q *Queue) fetchLoop(ctx context.Context, jobs chan<- *Job) {
+	failures := 0
+	for ctx.Err() == nil {
+		batch, err := q.Read(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			q.reportError(err)
+			failures++
+			if !wait(ctx, loopBackoff.Backoff(failures)) {
+				return
+			}
+			continue
+		}
+		failures = 0
+		if !dispatch(ctx, jobs, batch) {
+			return
+		}
+	}
+}
+
+func (q *Queue) claimLoop(ctx context.Context, jobs chan<- *Job) {
+	ticker := time.NewTicker(q.cfg.ClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		batch, err := q.Claim(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			q.reportError(err)
+			continue
+		}
+		if !dispatch(ctx, jobs, batch) {
+			return
+		}
+	}
+}
+
+// process runs the handler and settles the job even if ctx was cancelled meanwhile
+func (q *Queue) process(ctx context.Context, handler Handler, job *Job) {
+	err := runHandler(ctx, handler, job)
+
+	opCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), DefaultOperationTimeout)
+	defer cancel()
+
+	if err == nil {
+		err = q.Ack(opCtx, job.ID)
+	} else {
+		err = q.Nack(opCtx, job, err)
+	}
+	if err != nil {
+		q.reportError(err)
+	}
+}
+
+func runHandler(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+func (q *Queue) reportError(err error) {
+	if q.cfg.OnError != nil && err != nil && !errors.Is(err, context.Canceled) {
+		q.cfg.OnError(err)
+	}
+}
+
+func (q *Queue) newJob(msg redis.XMessage, attempts int64) *Job {
+	job := &Job{
+		ID:       msg.ID,
+		Payload:  []byte(stringValue(msg.Values, fieldPayload)),
+		Attempts: attempts,
+		codec:    q.cfg.Codec,
+	}
+	if ms, _, ok := strings.Cut(msg.ID, "-"); ok {
+		if n, err := strconv.ParseInt(ms, 10, 64); err == nil {
+			job.EnqueuedAt = time.UnixMilli(n)
+		}
+	}
+	return job
+}
+
+// dispatch hands jobs to the workers, returning false if ctx is cancelled first
+func dispatch(ctx context.Context, jobs chan<- *Job, batch []*Job) bool {
+	for _, job := range batch {
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// wait sleeps for d, returning false if ctx is cancelled first
+func wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func stringValue(values map[string]interface{}, field string) string {
+	if s, ok := values[field].(string); ok {
+		return s
+	}
+	return ""
+}
@@ -0,0 +1,332 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+type testPayload struct {
+	To string `json:"to"`
+}
+
+func testConfig() Config {
+	return DefaultConfig("test").
+		WithConsumer("c1").
+		WithBlockTimeout(10*time.Millisecond).
+		WithClaim(10*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestQueue_NilClient(t *testing.T) {
+	q := NewQueue(nil, testConfig())
+	ctx := context.Background()
+
+	if _, err := q.Enqueue(ctx, "x"); err == nil {
+		t.Error("Enqueue() with nil client should return error")
+	}
+	if _, err := q.Read(ctx); err == nil {
+		t.Error("Read() with nil client should return error")
+	}
+	if err := q.Consume(ctx, func(context.Context, *Job) error { return nil }); err == nil {
+		t.Error("Consume() with nil client should return error")
+	}
+	if _, err := q.Len(ctx); err == nil {
+		t.Error("Len() with nil client should return error")
+	}
+}
+
+func TestNewQueue_Normalizes(t *testing.T) {
+	q := NewQueue(nil, Config{Stream: "s"})
+	cfg := q.Config()
+	if cfg.Group != DefaultGroup || cfg.Consumer == "" || cfg.Codec == nil {
+		t.Errorf("NewQueue() config = %+v, want defaults filled in", cfg)
+	}
+	if cfg.Concurrency != 1 || cfg.BatchSize != 1 {
+		t.Errorf("NewQueue() Concurrency/BatchSize = %d/%d, want 1/1", cfg.Concurrency, cfg.BatchSize)
+	}
+}
+
+func TestQueue_EnqueueReadAck(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	q := NewQueue(client, testConfig())
+
+	// Jobs enqueued before the group exists are still delivered
+	id, err := q.Enqueue(ctx, testPayload{To: "a@example.com"})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v, want nil", err)
+	}
+	if err := q.EnsureGroup(ctx); err != nil {
+		t.Fatalf("EnsureGroup() error = %v, want nil", err)
+	}
+	if err := q.EnsureGroup(ctx); err != nil {
+		t.Errorf("EnsureGroup() twice error = %v, want nil", err)
+	}
+
+	jobs, err := q.Read(ctx)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("Read() = %v, %v, want one job", jobs, err)
+	}
+	job := jobs[0]
+	if job.ID != id || job.Attempts != 1 {
+		t.Errorf("Read() job = %s/%d, want %s/1", job.ID, job.Attempts, id)
+	}
+	if job.EnqueuedAt.IsZero() || time.Since(job.EnqueuedAt) > time.Minute {
+		t.Errorf("Read() job.EnqueuedAt = %v, want about now", job.EnqueuedAt)
+	}
+	var p testPayload
+	if err := job.Decode(&p); err != nil || p.To != "a@example.com" {
+		t.Errorf("Decode() = %+v, %v, want a@example.com", p, err)
+	}
+
+	if n, _ := q.Pending(ctx); n != 1 {
+		t.Errorf("Pending() = %d, want 1", n)
+	}
+	if err := q.Ack(ctx, job.ID); err != nil {
+		t.Fatalf("Ack() error = %v, want nil", err)
+	}
+	if n, _ := q.Pending(ctx); n != 0 {
+		t.Errorf("Pending() after Ack = %d, want 0", n)
+	}
+	if n, _ := q.Len(ctx); n != 1 {
+		t.Errorf("Len() = %d, want 1", n)
+	}
+
+	jobs, err = q.Read(ctx)
+	if err != nil || len(jobs) != 0 {
+		t.Errorf("Read() on drained queue = %v, %v, want none", jobs, err)
+	}
+}
+
+func TestQueue_EnqueueRawPipelined(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	q := NewQueue(client, testConfig())
+
+	pipe := client.Pipeline()
+	pipe.Set(ctx, "order:1", "created", 0)
+	cmd := q.EnqueueRawPipelined(ctx, pipe, []byte(`{"to":"b@example.com"}`))
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatalf("Exec() error = %v, want nil", err)
+	}
+	if cmd.Val() == "" {
+		t.Error("EnqueueRawPipelined() id is empty after Exec()")
+	}
+
+	_ = q.EnsureGroup(ctx)
+	jobs, err := q.Read(ctx)
+	if err != nil || len(jobs) != 1 || jobs[0].ID != cmd.Val() {
+		t.Fatalf("Read() = %v, %v, want the pipelined job", jobs, err)
+	}
+	var p testPayload
+	if err := jobs[0].Decode(&p); err != nil || p.To != "b@example.com" {
+		t.Errorf("Decode() = %+v, %v, want b@example.com", p, err)
+	}
+}
+
+func TestQueue_ClaimAndDeadLetter(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	cfg := testConfig().WithMaxDeliveries(2)
+	q := NewQueue(client, cfg)
+	other := NewQueue(client, cfg.WithConsumer("c2"))
+
+	if err := q.EnsureGroup(ctx); err != nil {
+		t.Fatalf("EnsureGroup() error = %v, want nil", err)
+	}
+	if _, err := q.Enqueue(ctx, testPayload{To: "b"}); err != nil {
+		t.Fatalf("Enqueue() error = %v, want nil", err)
+	}
+
+	jobs, _ := q.Read(ctx)
+	if len(jobs) != 1 {
+		t.Fatalf("Read() returned %d jobs, want 1", len(jobs))
+	}
+	if err := q.Nack(ctx, jobs[0], errors.New("boom")); err != nil {
+		t.Fatalf("Nack() error = %v, want nil", err)
+	}
+
+	if claimed, err := other.Claim(ctx); err != nil || len(claimed) != 0 {
+		t.Errorf("Claim() before MinIdle = %v, %v, want none", claimed, err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	claimed, err := other.Claim(ctx)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("Claim() = %v, %v, want one job", claimed, err)
+	}
+	if claimed[0].Attempts != 2 {
+		t.Errorf("Claim() job.Attempts = %d, want 2", claimed[0].Attempts)
+	}
+
+	if err := other.Nack(ctx, claimed[0], errors.New("boom again")); err != nil {
+		t.Fatalf("Nack() error = %v, want nil", err)
+	}
+	if n, _ := q.Pending(ctx); n != 0 {
+		t.Errorf("Pending() after dead-letter = %d, want 0", n)
+	}
+
+	letters, err := q.DeadLetters(ctx, 10)
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("DeadLetters() = %v, %v, want one entry", letters, err)
+	}
+	dl := letters[0]
+	if dl.SourceID != claimed[0].ID || dl.Error != "boom again" || dl.Attempts != 2 {
+		t.Errorf("DeadLetters()[0] = %+v, want source %s, error boom again, 2 attempts", dl, claimed[0].ID)
+	}
+	if string(dl.Payload) != `{"to":"b"}` || dl.FailedAt.IsZero() {
+		t.Errorf("DeadLetters()[0] payload = %s, failedAt = %v", dl.Payload, dl.FailedAt)
+	}
+}
+
+func TestQueue_ClaimDeadLettersExhaustedJobs(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	q := NewQueue(client, testConfig().WithMaxDeliveries(1))
+	_ = q.EnsureGroup(ctx)
+	_, _ = q.Enqueue(ctx, "x")
+	_, _ = q.Read(ctx)
+
+	// The consumer "crashed" without nacking; the next claim exceeds MaxDeliveries
+	time.Sleep(30 * time.Millisecond)
+	claimed, err := q.Claim(ctx)
+	if err != nil || len(claimed) != 0 {
+		t.Fatalf("Claim() = %v, %v, want none", claimed, err)
+	}
+	letters, _ := q.DeadLetters(ctx, 10)
+	if len(letters) != 1 || letters[0].Error != ErrMaxDeliveries.Error() {
+		t.Errorf("DeadLetters() = %+v, want one max-deliveries entry", letters)
+	}
+}
+
+func TestQueue_Consume(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := NewQueue(client, testConfig().WithConcurrency(3).WithMaxDeliveries(3))
+	for i := 0; i < 5; i++ {
+		if _, err := q.Enqueue(ctx, testPayload{To: "ok"}); err != nil {
+			t.Fatalf("Enqueue() error = %v, want nil", err)
+		}
+	}
+	if _, err := q.Enqueue(ctx, testPayload{To: "flaky"}); err != nil {
+		t.Fatalf("Enqueue() error = %v, want nil", err)
+	}
+	if _, err := q.Enqueue(ctx, testPayload{To: "panic"}); err != nil {
+		t.Fatalf("Enqueue() error = %v, want nil", err)
+	}
+
+	var done atomic.Int64
+	var flakyAttempts atomic.Int64
+	var mu sync.Mutex
+	var errs []error
+
+	q.cfg.OnError = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- q.Consume(ctx, func(_ context.Context, job *Job) error {
+			var p testPayload
+			if err := job.Decode(&p); err != nil {
+				return err
+			}
+			switch p.To {
+			case "flaky":
+				if flakyAttempts.Add(1) < 2 {
+					return errors.New("temporary failure")
+				}
+			case "panic":
+				panic("handler bug")
+			}
+			done.Add(1)
+			return nil
+		})
+	}()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		letters, _ := q.DeadLetters(ctx, 10)
+		if done.Load() == 6 && len(letters) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("Consume() error = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Consume() did not return after cancel")
+	}
+
+	if got := done.Load(); got != 6 {
+		t.Errorf("processed %d jobs, want 6", got)
+	}
+	if got := flakyAttempts.Load(); got != 2 {
+		t.Errorf("flaky job attempts = %d, want 2", got)
+	}
+
+	letters, _ := q.DeadLetters(context.Background(), 10)
+	if len(letters) != 1 || !strings.HasPrefix(letters[0].Error, ErrHandlerPanic.Error()) || letters[0].Attempts != 3 {
+		t.Errorf("DeadLetters() = %+v, want the panicking job after 3 attempts", letters)
+	}
+	if n, _ := q.Pending(context.Background()); n != 0 {
+		t.Errorf("Pending() after Consume = %d, want 0", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, err := range errs {
+		t.Errorf("OnError() reported %v", err)
+	}
+}
+
+func TestQueue_ConsumeNilHandler(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	q := NewQueue(client, testConfig())
+	if err := q.Consume(context.Background(), nil); err == nil {
+		t.Error("Consume() with nil handler should return error")
+	}
+}
+
+func TestQueue_RedisError(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	mock.SetShouldFail(true)
+	q := NewQueue(client, testConfig())
+	ctx := context.Background()
+
+	if _, err := q.Enqueue(ctx, "x"); err == nil {
+		t.Error("Enqueue() should return error when Redis fails")
+	}
+	if err := q.Consume(ctx, func(context.Context, *Job) error { return nil }); err == nil {
+		t.Error("Consume() should return error when the group cannot be created")
+	}
+}
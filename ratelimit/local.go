@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// localSweepInterval is the number of checks between sweeps of expired local windows
+const localSweepInterval = 1024
+
+// localLimiter applies limits and cooldowns in process memory while Redis is degraded
+// Each process counts only its own requests, so a limit shared by several instances is
+// enforced per instance until Redis recovers; windows counted locally are not carried over
+type localLimiter struct {
+	mu      sync.Mutex
+	windows map[string]localWindow
+	checks  int
+}
+
+// localWindow is the request count of a key until reset
+type localWindow struct {
+	count int
+	reset time.Time
+}
+
+// window returns the live window of key, sweeping expired ones now and then; the caller holds mu
+func (l *localLimiter) window(key string, now time.Time) (localWindow, bool) {
+	if l.windows == nil {
+		l.windows = make(map[string]localWindow)
+	}
+	l.checks++
+	if l.checks%localSweepInterval == 0 {
+		for k, w := range l.windows {
+			if !now.Before(w.reset) {
+				delete(l.windows, k)
+			}
+		}
+	}
+	w, ok := l.windows[key]
+	if ok && !now.Before(w.reset) {
+		return localWindow{}, false
+	}
+	return w, ok
+}
+
+// checkLimit counts a request against limit per window, as rateLimitScript does
+func (l *localLimiter) checkLimit(key string, limit int, window time.Duration) (bool, int, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	w, ok := l.window(key, now)
+	if !ok {
+		l.windows[key] = localWindow{count: 1, reset: now.Add(window)}
+		return true, limit - 1, now.Add(window)
+	}
+	if w.count >= limit {
+		return false, 0, w.reset
+	}
+	w.count++
+	l.windows[key] = w
+	return true, max(limit-w.count, 0), w.reset
+}
+
+// checkCooldown allows one request per cooldown, as cooldownScript does
+func (l *localLimiter) checkCooldown(key string, cooldown time.Duration) (bool, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if w, ok := l.window(key, now); ok {
+		return false, w.reset
+	}
+	l.windows[key] = localWindow{count: 1, reset: now.Add(cooldown)}
+	return true, now.Add(cooldown)
+}
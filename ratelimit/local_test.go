@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLocalLimiter(t *testing.T) {
+	var l localLimiter
+
+	if allowed, remaining, reset := l.checkLimit("a", 1, 20*time.Millisecond); !allowed || remaining != 0 || reset.IsZero() {
+		t.Errorf("checkLimit() = %v, %d, %v, want allowed with none left", allowed, remaining, reset)
+	}
+	if allowed, _, _ := l.checkLimit("a", 1, 20*time.Millisecond); allowed {
+		t.Error("checkLimit() over the limit allowed")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if allowed, _, _ := l.checkLimit("a", 1, 20*time.Millisecond); !allowed {
+		t.Error("checkLimit() in a new window denied")
+	}
+
+	if allowed, _ := l.checkCooldown("c", 20*time.Millisecond); !allowed {
+		t.Error("checkCooldown() denied the first request")
+	}
+	if allowed, reset := l.checkCooldown("c", 20*time.Millisecond); allowed || time.Until(reset) <= 0 {
+		t.Errorf("checkCooldown() during the cooldown = %v, %v, want denied until later", allowed, reset)
+	}
+
+	// Expired windows are swept
+	for i := 0; i < localSweepInterval; i++ {
+		l.checkCooldown(fmt.Sprintf("sweep:%d", i), time.Nanosecond)
+	}
+	time.Sleep(time.Millisecond)
+	for i := 0; i < localSweepInterval; i++ {
+		l.checkLimit("live", localSweepInterval*2, time.Minute)
+	}
+	if n := len(l.windows); n > 4 {
+		t.Errorf("%d local windows kept, want the expired ones swept", n)
+	}
+}
@@ -3,10 +3,13 @@ package ratelimit
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/utils"
 )
 
 const (
@@ -58,28 +61,89 @@ return {0, ttl}
 
 // RateLimiter provides rate limiting functionality using Redis
 type RateLimiter struct {
-	client         *redis.Client
+	client         redis.UniversalClient
 	keyPrefix      string
 	cooldownPrefix string
+	retryPolicy    utils.RetryPolicy
+	recorder       metrics.Recorder
+	logger         *slog.Logger
+	local          localLimiter // used while the client reports Redis as degraded
 }
 
 // NewRateLimiter creates a new rate limiter with default prefixes
-func NewRateLimiter(client *redis.Client) *RateLimiter {
-	return NewRateLimiterWithPrefixes(client, DefaultKeyPrefix, DefaultCooldownPrefix)
+// client may be a single-node, cluster, sentinel, or ring client; while a
+// client.ResilientClient reports Redis as degraded, checks are counted in process memory
+func NewRateLimiter(client redis.UniversalClient, opts ...Option) *RateLimiter {
+	return NewRateLimiterWithPrefixes(client, DefaultKeyPrefix, DefaultCooldownPrefix, opts...)
 }
 
 // NewRateLimiterWithPrefixes creates a new rate limiter with custom prefixes
-func NewRateLimiterWithPrefixes(client *redis.Client, keyPrefix, cooldownPrefix string) *RateLimiter {
-	return &RateLimiter{
-		client:         client,
+func NewRateLimiterWithPrefixes(client redis.UniversalClient, keyPrefix, cooldownPrefix string, opts ...Option) *RateLimiter {
+	r := &RateLimiter{
+		client:         utils.NormalizeClient(client),
 		keyPrefix:      keyPrefix,
 		cooldownPrefix: cooldownPrefix,
+		retryPolicy:    utils.NoRetry(),
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Client returns the underlying Redis client
+func (r *RateLimiter) Client() redis.UniversalClient {
+	return r.client
+}
+
+// eval runs a script under the limiter's retry policy
+// The scripts count requests, so only failures showing a script never ran are retried; a
+// retry after a lost reply could count the request twice
+func (r *RateLimiter) eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	policy := r.retryPolicy
+	classifier := policy.Classifier
+	if classifier == nil {
+		classifier = utils.IsRetryableError
+	}
+	policy = policy.WithClassifier(func(err error) bool {
+		return utils.IsUnsentError(err) && classifier(err)
+	})
+
+	var result interface{}
+	err := utils.Retry(ctx, policy, func(ctx context.Context) error {
+		var evalErr error
+		result, evalErr = r.client.Eval(ctx, script, keys, args...).Result()
+		return evalErr
+	})
+	return result, err
+}
+
+// observe reports a check to the limiter's recorder, or the default one, and a failed check to
+// its logger
+func (r *RateLimiter) observe(operation, key string, allowed bool, err error, start time.Time) {
+	if err != nil && r.logger != nil {
+		r.logger.Warn("redis-kit rate limit check failed", "operation", operation, "key", key, "error", err)
+	}
+	outcome := metrics.OutcomeDenied
+	switch {
+	case err != nil:
+		outcome = metrics.OutcomeError
+	case allowed:
+		outcome = metrics.OutcomeAllowed
+	}
+	metrics.Observe(r.recorder, metrics.SubsystemRateLimit, operation, outcome, start)
 }
 
 // CheckLimit checks if a request should be rate limited
 // Returns (allowed, remaining, resetTime, error)
 func (r *RateLimiter) CheckLimit(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Time, error) {
+	start := time.Now()
+	allowed, remaining, resetTime, err := r.checkLimit(ctx, key, limit, window)
+	r.observe("check_limit", key, allowed, err, start)
+	return allowed, remaining, resetTime, err
+}
+
+func (r *RateLimiter) checkLimit(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Time, error) {
 	if r.client == nil {
 		return false, 0, time.Time{}, fmt.Errorf("redis client is nil")
 	}
@@ -90,8 +154,12 @@ func (r *RateLimiter) CheckLimit(ctx context.Context, key string, limit int, win
 	}
 
 	redisKey := r.keyPrefix + key
+	if utils.Degraded(r.client) {
+		allowed, remaining, resetTime := r.local.checkLimit(redisKey, limit, window)
+		return allowed, remaining, resetTime, nil
+	}
 
-	result, err := r.client.Eval(ctx, rateLimitScript, []string{redisKey}, limit, windowMs).Result()
+	result, err := r.eval(ctx, rateLimitScript, []string{redisKey}, limit, windowMs)
 	if err != nil {
 		return false, 0, time.Time{}, fmt.Errorf("failed to apply rate limit: %w", err)
 	}
@@ -125,6 +193,13 @@ func (r *RateLimiter) CheckLimit(ctx context.Context, key string, limit int, win
 // CheckCooldown checks if resend is allowed (cooldown period)
 // Returns (allowed, resetTime, error)
 func (r *RateLimiter) CheckCooldown(ctx context.Context, key string, cooldown time.Duration) (bool, time.Time, error) {
+	start := time.Now()
+	allowed, resetTime, err := r.checkCooldown(ctx, key, cooldown)
+	r.observe("check_cooldown", key, allowed, err, start)
+	return allowed, resetTime, err
+}
+
+func (r *RateLimiter) checkCooldown(ctx context.Context, key string, cooldown time.Duration) (bool, time.Time, error) {
 	if r.client == nil {
 		return false, time.Time{}, fmt.Errorf("redis client is nil")
 	}
@@ -135,8 +210,12 @@ func (r *RateLimiter) CheckCooldown(ctx context.Context, key string, cooldown ti
 	}
 
 	redisKey := r.cooldownPrefix + key
+	if utils.Degraded(r.client) {
+		allowed, resetTime := r.local.checkCooldown(redisKey, cooldown)
+		return allowed, resetTime, nil
+	}
 
-	result, err := r.client.Eval(ctx, cooldownScript, []string{redisKey}, cooldownMs).Result()
+	result, err := r.eval(ctx, cooldownScript, []string{redisKey}, cooldownMs)
 	if err != nil {
 		return false, time.Time{}, fmt.Errorf("failed to apply cooldown: %w", err)
 	}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/soulteary/redis-kit/testutil"
 )
 
@@ -28,6 +29,39 @@ func TestNewRateLimiter(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_Client(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	if got := NewRateLimiter(client).Client(); got != client {
+		t.Errorf("Client() = %v, want the client passed to NewRateLimiter", got)
+	}
+	if got := NewRateLimiter(nil).Client(); got != nil {
+		t.Errorf("Client() without a client = %v, want nil", got)
+	}
+}
+
+func TestNewRateLimiter_UniversalClient(t *testing.T) {
+	ring, _ := testutil.NewMockRingClient()
+	defer func() { _ = ring.Close() }()
+
+	ctx := context.Background()
+	limiter := NewRateLimiter(ring)
+	for i := 0; i < 2; i++ {
+		if allowed, _, _, err := limiter.CheckLimit(ctx, "k", 2, time.Minute); err != nil || !allowed {
+			t.Fatalf("CheckLimit() #%d through ring = %v, %v, want allowed", i+1, allowed, err)
+		}
+	}
+	if allowed, _, _, _ := limiter.CheckLimit(ctx, "k", 2, time.Minute); allowed {
+		t.Error("CheckLimit() over the limit through ring = true, want false")
+	}
+
+	var client *redis.Client
+	if _, _, _, err := NewRateLimiter(client).CheckLimit(ctx, "k", 2, time.Minute); err == nil {
+		t.Error("CheckLimit() with nil *redis.Client error = nil, want error")
+	}
+}
+
 func TestNewRateLimiterWithPrefixes(t *testing.T) {
 	client, _ := testutil.NewMockRedisClient()
 	defer func() { _ = client.Close() }()
@@ -890,3 +924,44 @@ func TestRateLimiter_Convenience_Methods(t *testing.T) {
 		}
 	})
 }
+
+// degradedClient reports a settable degraded state, as client.ResilientClient does
+type degradedClient struct {
+	redis.UniversalClient
+	degraded bool
+}
+
+func (c *degradedClient) Degraded() bool { return c.degraded }
+
+func TestRateLimiter_Degraded(t *testing.T) {
+	mockClient, _ := testutil.NewMockRedisClient()
+	defer func() { _ = mockClient.Close() }()
+	client := &degradedClient{UniversalClient: mockClient, degraded: true}
+	limiter := NewRateLimiter(client)
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		allowed, remaining, _, err := limiter.CheckLimit(ctx, "user:1", 2, time.Minute)
+		if err != nil || allowed != want {
+			t.Errorf("CheckLimit() #%d while degraded = %v, %v, want %v", i, allowed, err, want)
+		}
+		if want && remaining != 1-i {
+			t.Errorf("CheckLimit() #%d remaining = %d, want %d", i, remaining, 1-i)
+		}
+	}
+	if allowed, _, err := limiter.CheckCooldown(ctx, "user:1", time.Minute); !allowed || err != nil {
+		t.Errorf("CheckCooldown() while degraded = %v, %v, want allowed", allowed, err)
+	}
+	if allowed, _, _ := limiter.CheckCooldown(ctx, "user:1", time.Minute); allowed {
+		t.Error("CheckCooldown() twice while degraded allowed both")
+	}
+	if n := mockClient.Exists(ctx, DefaultKeyPrefix+"user:1", DefaultCooldownPrefix+"user:1").Val(); n != 0 {
+		t.Errorf("checks while degraded wrote %d keys to Redis", n)
+	}
+
+	// Redis counts from scratch once it is back
+	client.degraded = false
+	if allowed, remaining, _, err := limiter.CheckLimit(ctx, "user:1", 2, time.Minute); !allowed || remaining != 1 || err != nil {
+		t.Errorf("CheckLimit() after recovery = %v, %d, %v, want allowed with 1 left", allowed, remaining, err)
+	}
+}
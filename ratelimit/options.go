@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"log/slog"
+
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// Option configures a RateLimiter
+type Option func(*RateLimiter)
+
+// WithRetryPolicy sets the retry policy applied to rate limit scripts (default: no retry)
+// Only failures showing a script never ran, such as a refused connection or a LOADING
+// reply, are retried, so a request is never counted twice
+func WithRetryPolicy(policy utils.RetryPolicy) Option {
+	return func(r *RateLimiter) {
+		r.retryPolicy = policy
+	}
+}
+
+// WithRecorder sets the recorder checks are reported to (default: metrics.Default())
+func WithRecorder(r metrics.Recorder) Option {
+	return func(rl *RateLimiter) {
+		rl.recorder = r
+	}
+}
+
+// WithLogger sets the logger failed checks are reported to (default: nil, no logging); pass
+// the client's logger so the failures callers fail open or closed on are recorded next to it
+func WithLogger(logger *slog.Logger) Option {
+	return func(rl *RateLimiter) {
+		rl.logger = logger
+	}
+}
@@ -0,0 +1,191 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/metrics"
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+func TestWithRetryPolicy(t *testing.T) {
+	t.Run("default is no retry", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		limiter := NewRateLimiter(client)
+		if limiter.retryPolicy.MaxAttempts != 1 {
+			t.Errorf("NewRateLimiter() retryPolicy.MaxAttempts = %d, want 1", limiter.retryPolicy.MaxAttempts)
+		}
+	})
+
+	t.Run("retries failures where the script never ran", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+		hook := &failingEval{err: serverError("LOADING Redis is loading the dataset in memory")}
+		client.AddHook(hook)
+
+		calls := 0
+		policy := utils.NoRetry().
+			WithMaxAttempts(2).
+			WithBackoff(time.Millisecond, time.Millisecond).
+			WithClassifier(func(error) bool {
+				calls++
+				return true
+			})
+		limiter := NewRateLimiter(client, WithRetryPolicy(policy))
+		ctx := context.Background()
+
+		hook.failures.Store(1)
+		allowed, remaining, _, err := limiter.CheckLimit(ctx, "retry", 5, time.Minute)
+		if err != nil {
+			t.Fatalf("CheckLimit() error = %v, want nil", err)
+		}
+		if !allowed || remaining != 4 {
+			t.Errorf("CheckLimit() = (%v, %d), want (true, 4)", allowed, remaining)
+		}
+
+		hook.failures.Store(1)
+		allowed, _, err = limiter.CheckCooldown(ctx, "retry", time.Minute)
+		if err != nil {
+			t.Fatalf("CheckCooldown() error = %v, want nil", err)
+		}
+		if !allowed {
+			t.Error("CheckCooldown() = false, want true")
+		}
+		if calls != 2 {
+			t.Errorf("classifier calls = %d, want 2", calls)
+		}
+	})
+
+	t.Run("does not retry after the script ran", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+		hook := &failingEval{err: io.EOF, run: true}
+		client.AddHook(hook)
+
+		policy := utils.DefaultRetryPolicy().WithBackoff(time.Millisecond, time.Millisecond)
+		limiter := NewRateLimiter(client, WithRetryPolicy(policy))
+		ctx := context.Background()
+
+		// The reply is lost after the request was counted, so a retry would count it twice
+		hook.failures.Store(1)
+		if _, _, _, err := limiter.CheckLimit(ctx, "lost", 5, time.Minute); err == nil {
+			t.Fatal("CheckLimit() with a lost reply should return error")
+		}
+		if _, remaining, _, err := limiter.CheckLimit(ctx, "lost", 5, time.Minute); err != nil || remaining != 3 {
+			t.Errorf("CheckLimit() remaining = %d, %v, want 3 after one counted request", remaining, err)
+		}
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+		hook := &failingEval{err: serverError("TRYAGAIN Multiple keys request during rehashing of slot")}
+		client.AddHook(hook)
+
+		policy := utils.NoRetry().WithMaxAttempts(2).WithClassifier(func(error) bool { return true })
+		limiter := NewRateLimiterWithPrefixes(client, "a:", "b:", WithRetryPolicy(policy))
+
+		hook.failures.Store(2)
+		if _, _, _, err := limiter.CheckLimit(context.Background(), "retry", 5, time.Minute); err == nil {
+			t.Error("CheckLimit() with persistent failure should return error")
+		}
+	})
+}
+
+// serverError mimics a server error reply
+type serverError string
+
+func (e serverError) Error() string { return string(e) }
+
+func (serverError) RedisError() {}
+
+// failingEval fails the next scripts with err; with run set, each script runs before its
+// reply is lost
+type failingEval struct {
+	failures atomic.Int64
+	err      error
+	run      bool
+}
+
+func (h *failingEval) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *failingEval) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() != "eval" || h.failures.Add(-1) < 0 {
+			return next(ctx, cmd)
+		}
+		if h.run {
+			_ = next(ctx, cmd)
+		}
+		cmd.SetErr(h.err)
+		return h.err
+	}
+}
+
+func (h *failingEval) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestWithRecorder(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	rec := testutil.NewRecorder()
+	limiter := NewRateLimiter(client, WithRecorder(rec))
+	ctx := context.Background()
+
+	_, _, _, _ = limiter.CheckLimit(ctx, "k", 1, time.Minute)
+	_, _, _, _ = limiter.CheckLimit(ctx, "k", 1, time.Minute)
+	_, _, _ = limiter.CheckCooldown(ctx, "k", time.Minute)
+	_, _, _ = limiter.CheckCooldown(ctx, "k", time.Minute)
+	mock.SetShouldFail(true)
+	_, _, _, _ = limiter.CheckUserLimit(ctx, "u", 1, time.Minute)
+
+	for _, tt := range []struct{ operation, outcome string }{
+		{"check_limit", metrics.OutcomeAllowed},
+		{"check_limit", metrics.OutcomeDenied},
+		{"check_limit", metrics.OutcomeError},
+		{"check_cooldown", metrics.OutcomeAllowed},
+		{"check_cooldown", metrics.OutcomeDenied},
+	} {
+		if n := rec.Count(metrics.SubsystemRateLimit, tt.operation, tt.outcome); n != 1 {
+			t.Errorf("Count(%s, %s) = %d, want 1", tt.operation, tt.outcome, n)
+		}
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	var buf bytes.Buffer
+	limiter := NewRateLimiter(client, WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	ctx := context.Background()
+
+	if _, _, _, err := limiter.CheckLimit(ctx, "ok", 5, time.Minute); err != nil {
+		t.Fatalf("CheckLimit() error = %v, want nil", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("successful check logged %q", buf.String())
+	}
+
+	mock.SetShouldFail(true)
+	_, _, _, _ = limiter.CheckLimit(ctx, "user:1", 5, time.Minute)
+	_, _, _ = limiter.CheckCooldown(ctx, "user:1", time.Minute)
+	out := buf.String()
+	for _, want := range []string{"level=WARN", "operation=check_limit", "operation=check_cooldown", "key=user:1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("failed checks logged %q, want it to contain %s", out, want)
+		}
+	}
+}
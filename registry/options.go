@@ -0,0 +1,37 @@
+package registry
+
+import "time"
+
+// settings holds the configuration of a Registry
+type settings struct {
+	keyPrefix    string
+	pollInterval time.Duration
+	onError      func(error)
+}
+
+// Option configures a Registry
+type Option func(*settings)
+
+// WithKeyPrefix sets the prefix of registry keys and channels (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(s *settings) {
+		s.keyPrefix = prefix
+	}
+}
+
+// WithPollInterval sets how often Watch re-reads the instances besides change notifications,
+// which also bounds how late it reports expired instances (default: DefaultPollInterval)
+func WithPollInterval(d time.Duration) Option {
+	return func(s *settings) {
+		if d > 0 {
+			s.pollInterval = d
+		}
+	}
+}
+
+// WithOnError sets a callback for errors in Watch and KeepAlive, which keep running after them
+func WithOnError(fn func(error)) Option {
+	return func(s *settings) {
+		s.onError = fn
+	}
+}
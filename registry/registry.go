@@ -0,0 +1,354 @@
+// Package registry provides service discovery on Redis
+// Instances register under a service name with a TTL and keep themselves listed with
+// heartbeats; instances that stop sending heartbeats expire and are pruned by the next
+// lookup. Consumers discover the live instances of a service or watch its membership,
+// with changes announced over pub/sub
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix of registry keys and channels
+	DefaultKeyPrefix = "registry:"
+
+	// DefaultTTL is how long a registration lasts without heartbeats by default
+	DefaultTTL = 30 * time.Second
+
+	// DefaultPollInterval is how often Watch re-reads the instances by default
+	DefaultPollInterval = 5 * time.Second
+)
+
+// pruneScript removes the instances of KEYS[1] whose expiry is at or before ARGV[1], along
+// with their entries in KEYS[2], and returns their IDs
+const pruneScript = `
+-- redis-kit:registry-prune
+local expired = redis.call("zrangebyscore", KEYS[1], "-inf", ARGV[1])
+if #expired > 0 then
+	redis.call("zrem", KEYS[1], unpack(expired))
+	redis.call("hdel", KEYS[2], unpack(expired))
+end
+return expired
+`
+
+// Instance is a registered instance of a service
+type Instance struct {
+	// ID identifies the instance within its service; it is the instance's address
+	ID string `json:"id"`
+
+	Service  string            `json:"service"`
+	Addr     string            `json:"addr"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// RegisteredAt is when the registration was made
+	RegisteredAt time.Time `json:"registered_at"`
+
+	// ExpiresAt is when the instance expires unless it sends another heartbeat
+	ExpiresAt time.Time `json:"-"`
+}
+
+// EventType is the kind of a membership change
+type EventType string
+
+const (
+	// EventJoin reports an instance that appeared
+	EventJoin EventType = "join"
+
+	// EventLeave reports an instance that deregistered or expired
+	EventLeave EventType = "leave"
+
+	// EventUpdate reports an instance registered again with other metadata
+	EventUpdate EventType = "update"
+)
+
+// Event is a membership change seen by Watch
+type Event struct {
+	Type     EventType
+	Instance Instance
+}
+
+// Registry registers and discovers service instances
+type Registry struct {
+	client *redis.Client
+	settings
+}
+
+// NewRegistry creates a service registry
+func NewRegistry(client *redis.Client, opts ...Option) *Registry {
+	r := &Registry{
+		client:   client,
+		settings: settings{keyPrefix: DefaultKeyPrefix, pollInterval: DefaultPollInterval},
+	}
+	for _, opt := range opts {
+		opt(&r.settings)
+	}
+	return r
+}
+
+// Key returns the sorted set of the instances of service, scored by expiry; the service
+// name is a hash tag, so on Redis Cluster the keys of a service share a slot
+func (r *Registry) Key(service string) string {
+	return r.keyPrefix + "{" + service + "}:instances"
+}
+
+// InfoKey returns the hash holding the details of the instances of service
+func (r *Registry) InfoKey(service string) string {
+	return r.keyPrefix + "{" + service + "}:info"
+}
+
+// Channel returns the pub/sub channel announcing changes to the instances of service
+func (r *Registry) Channel(service string) string {
+	return r.keyPrefix + "{" + service + "}:changes"
+}
+
+// Register lists addr as an instance of service for ttl; a non-positive ttl uses DefaultTTL
+// Registering an address again replaces its registration. Keep the instance listed with
+// Heartbeat or KeepAlive on the returned Registration
+func (r *Registry) Register(ctx context.Context, service, addr string, ttl time.Duration) (*Registration, error) {
+	return r.RegisterWithMetadata(ctx, service, addr, ttl, nil)
+}
+
+// RegisterWithMetadata registers an instance carrying metadata, such as its version or zone
+func (r *Registry) RegisterWithMetadata(ctx context.Context, service, addr string, ttl time.Duration, metadata map[string]string) (*Registration, error) {
+	if service == "" || addr == "" {
+		return nil, fmt.Errorf("service and addr are required")
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	reg := &Registration{
+		registry: r,
+		ttl:      ttl,
+		instance: Instance{
+			ID:           addr,
+			Service:      service,
+			Addr:         addr,
+			Metadata:     maps.Clone(metadata),
+			RegisteredAt: time.UnixMilli(time.Now().UnixMilli()),
+		},
+	}
+	if err := reg.write(ctx, true); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// Discover returns the live instances of service ordered by ID, pruning expired ones
+func (r *Registry) Discover(ctx context.Context, service string) ([]Instance, error) {
+	if r.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	now := time.Now().UnixMilli()
+	pruned, err := r.client.Eval(ctx, pruneScript, []string{r.Key(service), r.InfoKey(service)}, now).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune instances: %w", err)
+	}
+	if len(pruned) > 0 {
+		// Watchers re-read the instances and report the expired ones as leaving
+		_ = r.client.Publish(ctx, r.Channel(service), "").Err()
+	}
+
+	pipe := r.client.Pipeline()
+	live := pipe.ZRangeByScoreWithScores(ctx, r.Key(service), &redis.ZRangeBy{Min: "(" + strconv.FormatInt(now, 10), Max: "+inf"})
+	info := pipe.HGetAll(ctx, r.InfoKey(service))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to discover instances: %w", err)
+	}
+
+	instances := make([]Instance, 0, len(live.Val()))
+	for _, z := range live.Val() {
+		id, _ := z.Member.(string)
+		data, ok := info.Val()[id]
+		if !ok {
+			continue
+		}
+		var inst Instance
+		if err := json.Unmarshal([]byte(data), &inst); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal instance %s: %w", id, err)
+		}
+		inst.ExpiresAt = time.UnixMilli(int64(z.Score))
+		instances = append(instances, inst)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	return instances, nil
+}
+
+// Watch calls fn with a join event for every live instance of service when Watch starts,
+// then with every membership change, until ctx is cancelled
+// Changes arrive over pub/sub, and the instances are also re-read every poll interval, which
+// is how expired instances are noticed. fn runs on the Watch goroutine. Watch returns nil on
+// cancellation
+func (r *Registry) Watch(ctx context.Context, service string, fn func(Event)) error {
+	if r.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	known := make(map[string]Instance)
+	check := func() {
+		instances, err := r.Discover(ctx, service)
+		if err != nil {
+			r.reportError(err)
+			return
+		}
+		for _, event := range diff(known, instances) {
+			fn(event)
+		}
+	}
+
+	// The instances are read once subscribed, so changes made after the read are not missed
+	return utils.WatchChannel(ctx, r.client, r.Channel(service), utils.ChannelHandler{
+		OnSubscribe: check,
+		OnMessage:   func(string) { check() },
+		OnError: func(err error) {
+			r.reportError(fmt.Errorf("registry subscription failed: %w", err))
+		},
+		OnPoll:       check,
+		PollInterval: r.pollInterval,
+	})
+}
+
+// diff updates known to instances and returns the changes, leaves first
+func diff(known map[string]Instance, instances []Instance) []Event {
+	current := make(map[string]Instance, len(instances))
+	for _, inst := range instances {
+		current[inst.ID] = inst
+	}
+
+	var events []Event
+	for _, id := range sortedKeys(known) {
+		if _, ok := current[id]; !ok {
+			events = append(events, Event{Type: EventLeave, Instance: known[id]})
+			delete(known, id)
+		}
+	}
+	for _, inst := range instances {
+		prev, ok := known[inst.ID]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: EventJoin, Instance: inst})
+		case !prev.RegisteredAt.Equal(inst.RegisteredAt) || !maps.Equal(prev.Metadata, inst.Metadata):
+			events = append(events, Event{Type: EventUpdate, Instance: inst})
+		}
+		known[inst.ID] = inst
+	}
+	return events
+}
+
+func sortedKeys(m map[string]Instance) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (r *Registry) reportError(err error) {
+	if r.onError != nil && err != nil && !errors.Is(err, context.Canceled) {
+		r.onError(err)
+	}
+}
+
+// Registration is the handle of a registered instance
+type Registration struct {
+	registry *Registry
+	instance Instance
+	ttl      time.Duration
+}
+
+// Instance returns the registered instance
+func (g *Registration) Instance() Instance {
+	return g.instance
+}
+
+// TTL returns how long the registration lasts without heartbeats
+func (g *Registration) TTL() time.Duration {
+	return g.ttl
+}
+
+// Heartbeat extends the registration by its TTL, registering the instance again if it expired
+func (g *Registration) Heartbeat(ctx context.Context) error {
+	return g.write(ctx, false)
+}
+
+// write stores the instance and its expiry, announcing it when it was not listed or always is set
+func (g *Registration) write(ctx context.Context, always bool) error {
+	r := g.registry
+	if r.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	data, err := json.Marshal(g.instance)
+	if err != nil {
+		return fmt.Errorf("failed to marshal instance: %w", err)
+	}
+	service := g.instance.Service
+	expiresAt := time.Now().Add(g.ttl).UnixMilli()
+
+	pipe := r.client.Pipeline()
+	added := pipe.ZAdd(ctx, r.Key(service), redis.Z{Score: float64(expiresAt), Member: g.instance.ID})
+	pipe.HSet(ctx, r.InfoKey(service), g.instance.ID, data)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to register instance: %w", err)
+	}
+	if always || added.Val() > 0 {
+		if err := r.client.Publish(ctx, r.Channel(service), g.instance.ID).Err(); err != nil {
+			return fmt.Errorf("failed to announce instance: %w", err)
+		}
+	}
+	return nil
+}
+
+// Deregister removes the instance right away
+func (g *Registration) Deregister(ctx context.Context) error {
+	r := g.registry
+	if r.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	service := g.instance.Service
+	pipe := r.client.Pipeline()
+	pipe.ZRem(ctx, r.Key(service), g.instance.ID)
+	pipe.HDel(ctx, r.InfoKey(service), g.instance.ID)
+	pipe.Publish(ctx, r.Channel(service), g.instance.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to deregister instance: %w", err)
+	}
+	return nil
+}
+
+// KeepAlive sends a heartbeat every third of the TTL until ctx is cancelled, then deregisters
+// the instance and returns nil; failed heartbeats are reported to the OnError callback
+func (g *Registration) KeepAlive(ctx context.Context) error {
+	if g.registry.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	ticker := time.NewTicker(g.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			dctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			return g.Deregister(dctx)
+		case <-ticker.C:
+			if err := g.Heartbeat(ctx); err != nil {
+				g.registry.reportError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package registry
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestNewRegistry(t *testing.T) {
+	r := NewRegistry(nil)
+	if r.keyPrefix != DefaultKeyPrefix || r.pollInterval != DefaultPollInterval {
+		t.Errorf("NewRegistry() settings = %+v, want defaults", r.settings)
+	}
+	if got := r.Key("api"); got != "registry:{api}:instances" {
+		t.Errorf("Key() = %q, want registry:{api}:instances", got)
+	}
+	if got := r.InfoKey("api"); got != "registry:{api}:info" {
+		t.Errorf("InfoKey() = %q, want registry:{api}:info", got)
+	}
+	if got := r.Channel("api"); got != "registry:{api}:changes" {
+		t.Errorf("Channel() = %q, want registry:{api}:changes", got)
+	}
+
+	var called bool
+	r = NewRegistry(nil, WithKeyPrefix("svc:"), WithPollInterval(time.Second), WithPollInterval(0),
+		WithOnError(func(error) { called = true }))
+	if r.keyPrefix != "svc:" || r.pollInterval != time.Second {
+		t.Errorf("NewRegistry() with options settings = %+v", r.settings)
+	}
+	r.onError(nil)
+	if !called {
+		t.Error("WithOnError() callback was not set")
+	}
+}
+
+func TestRegisterDiscover(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	r := NewRegistry(client)
+
+	b, err := r.RegisterWithMetadata(ctx, "api", "10.0.0.2:80", time.Minute, map[string]string{"zone": "b"})
+	if err != nil {
+		t.Fatalf("RegisterWithMetadata() error = %v, want nil", err)
+	}
+	if b.TTL() != time.Minute || b.Instance().Addr != "10.0.0.2:80" {
+		t.Errorf("Registration = %+v, %v", b.Instance(), b.TTL())
+	}
+	a, err := r.Register(ctx, "api", "10.0.0.1:80", 0)
+	if err != nil {
+		t.Fatalf("Register() error = %v, want nil", err)
+	}
+	if a.TTL() != DefaultTTL {
+		t.Errorf("TTL() = %v, want DefaultTTL", a.TTL())
+	}
+	_, _ = r.Register(ctx, "web", "10.0.0.9:80", time.Minute)
+
+	instances, err := r.Discover(ctx, "api")
+	if err != nil || len(instances) != 2 {
+		t.Fatalf("Discover() = %+v, %v, want 2 instances", instances, err)
+	}
+	if instances[0].Addr != "10.0.0.1:80" || instances[1].Metadata["zone"] != "b" || instances[1].Service != "api" {
+		t.Errorf("Discover() = %+v, want both api instances ordered by ID", instances)
+	}
+	if until := time.Until(instances[1].ExpiresAt); until <= 50*time.Second || until > time.Minute {
+		t.Errorf("ExpiresAt in %v, want about a minute", until)
+	}
+
+	if err := b.Deregister(ctx); err != nil {
+		t.Fatalf("Deregister() error = %v, want nil", err)
+	}
+	if instances, _ := r.Discover(ctx, "api"); len(instances) != 1 || instances[0].ID != "10.0.0.1:80" {
+		t.Errorf("Discover() after Deregister = %+v, want only 10.0.0.1:80", instances)
+	}
+	if instances, _ := r.Discover(ctx, "missing"); len(instances) != 0 {
+		t.Errorf("Discover() unknown service = %+v, want none", instances)
+	}
+}
+
+func TestExpiryAndHeartbeat(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	r := NewRegistry(client)
+	live, _ := r.Register(ctx, "api", "live:80", 100*time.Millisecond)
+	_, _ = r.Register(ctx, "api", "dead:80", 100*time.Millisecond)
+
+	time.Sleep(60 * time.Millisecond)
+	if err := live.Heartbeat(ctx); err != nil {
+		t.Fatalf("Heartbeat() error = %v, want nil", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	instances, err := r.Discover(ctx, "api")
+	if err != nil || len(instances) != 1 || instances[0].ID != "live:80" {
+		t.Fatalf("Discover() = %+v, %v, want only live:80", instances, err)
+	}
+	if n, _ := client.HLen(ctx, r.InfoKey("api")).Result(); n != 1 {
+		t.Errorf("info entries = %d, want the expired one pruned", n)
+	}
+
+	// A heartbeat after expiry registers the instance again
+	time.Sleep(120 * time.Millisecond)
+	if instances, _ := r.Discover(ctx, "api"); len(instances) != 0 {
+		t.Errorf("Discover() after expiry = %+v, want none", instances)
+	}
+	_ = live.Heartbeat(ctx)
+	if instances, _ := r.Discover(ctx, "api"); len(instances) != 1 {
+		t.Errorf("Discover() after late Heartbeat = %+v, want live:80", instances)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewRegistry(client)
+	reg, _ := r.Register(ctx, "api", "a:80", 90*time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- reg.KeepAlive(ctx) }()
+	time.Sleep(200 * time.Millisecond)
+	if instances, _ := r.Discover(context.Background(), "api"); len(instances) != 1 {
+		t.Errorf("Discover() during KeepAlive = %+v, want a:80", instances)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("KeepAlive() error = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("KeepAlive() did not return after cancellation")
+	}
+	if instances, _ := r.Discover(context.Background(), "api"); len(instances) != 0 {
+		t.Errorf("Discover() after KeepAlive = %+v, want deregistered", instances)
+	}
+}
+
+// recorder collects the events seen by Watch
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) add(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// wait returns the events once there are at least n of them
+func (r *recorder) wait(t *testing.T, n int) []Event {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		r.mu.Lock()
+		events := append([]Event(nil), r.events...)
+		r.mu.Unlock()
+		if len(events) >= n {
+			return events
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d events, want %d: %+v", len(events), n, events)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewRegistry(client)
+	a, _ := r.Register(ctx, "api", "a:80", time.Minute)
+
+	rec := &recorder{}
+	done := make(chan error, 1)
+	go func() { done <- r.Watch(ctx, "api", rec.add) }()
+	if events := rec.wait(t, 1); events[0].Type != EventJoin || events[0].Instance.ID != "a:80" {
+		t.Fatalf("first event = %+v, want a:80 joining", events[0])
+	}
+
+	_, _ = r.Register(ctx, "web", "w:80", time.Minute)
+	_, _ = r.Register(ctx, "api", "b:80", time.Minute)
+	if events := rec.wait(t, 2); events[1].Type != EventJoin || events[1].Instance.ID != "b:80" {
+		t.Errorf("second event = %+v, want b:80 joining", events[1])
+	}
+
+	_, _ = r.RegisterWithMetadata(ctx, "api", "b:80", time.Minute, map[string]string{"v": "2"})
+	if events := rec.wait(t, 3); events[2].Type != EventUpdate || events[2].Instance.Metadata["v"] != "2" {
+		t.Errorf("third event = %+v, want b:80 updated", events[2])
+	}
+
+	_ = a.Heartbeat(ctx)
+	_ = a.Deregister(ctx)
+	if events := rec.wait(t, 4); events[3].Type != EventLeave || events[3].Instance.ID != "a:80" {
+		t.Errorf("fourth event = %+v, want a:80 leaving", events[3])
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Watch() error = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch() did not return after cancellation")
+	}
+	if events := rec.wait(t, 4); len(events) != 4 {
+		t.Errorf("events = %+v, want 4 (heartbeats and other services ignored)", events)
+	}
+}
+
+func TestWatch_Expiry(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewRegistry(client, WithPollInterval(20*time.Millisecond))
+	_, _ = r.Register(ctx, "api", "a:80", 50*time.Millisecond)
+
+	rec := &recorder{}
+	go func() { _ = r.Watch(ctx, "api", rec.add) }()
+	rec.wait(t, 1)
+	if events := rec.wait(t, 2); events[1].Type != EventLeave || events[1].Instance.ID != "a:80" {
+		t.Errorf("second event = %+v, want a:80 leaving on expiry", events[1])
+	}
+}
+
+func TestRegistry_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewRegistry(nil)
+	if _, err := r.Register(ctx, "api", "a:80", 0); err == nil {
+		t.Error("Register() with nil client error = nil, want error")
+	}
+	if _, err := r.Discover(ctx, "api"); err == nil {
+		t.Error("Discover() with nil client error = nil, want error")
+	}
+	if err := r.Watch(ctx, "api", func(Event) {}); err == nil {
+		t.Error("Watch() with nil client error = nil, want error")
+	}
+	reg := &Registration{registry: r, instance: Instance{ID: "a:80", Service: "api"}, ttl: time.Second}
+	if err := reg.Deregister(ctx); err == nil {
+		t.Error("Deregister() with nil client error = nil, want error")
+	}
+	if err := reg.KeepAlive(ctx); err == nil {
+		t.Error("KeepAlive() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	r = NewRegistry(client)
+	if _, err := r.Register(ctx, "", "a:80", 0); err == nil {
+		t.Error("Register() without service error = nil, want error")
+	}
+	if _, err := r.Register(ctx, "api", "", 0); err == nil {
+		t.Error("Register() without addr error = nil, want error")
+	}
+	reg, _ = r.Register(ctx, "api", "a:80", time.Minute)
+
+	mock.SetShouldFail(true)
+	if _, err := r.Register(ctx, "api", "b:80", 0); err == nil {
+		t.Error("Register() error = nil, want error")
+	}
+	if err := reg.Heartbeat(ctx); err == nil {
+		t.Error("Heartbeat() error = nil, want error")
+	}
+	if err := reg.Deregister(ctx); err == nil {
+		t.Error("Deregister() error = nil, want error")
+	}
+	if _, err := r.Discover(ctx, "api"); err == nil {
+		t.Error("Discover() error = nil, want error")
+	}
+}
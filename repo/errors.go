@@ -0,0 +1,14 @@
+package repo
+
+import "errors"
+
+var (
+	// ErrNotFound indicates no entity is stored under the key.
+	ErrNotFound = errors.New("entity not found")
+
+	// ErrEmptyKey indicates the key function returned an empty key.
+	ErrEmptyKey = errors.New("entity key is empty")
+
+	// ErrUnknownIndex indicates the field has no index.
+	ErrUnknownIndex = errors.New("unknown index")
+)
@@ -0,0 +1,33 @@
+package repo
+
+import "time"
+
+// Option configures a Repository
+type Option[T any] func(*Repository[T])
+
+// WithTTL makes saved entities expire after ttl (default: never)
+func WithTTL[T any](ttl time.Duration) Option[T] {
+	return func(r *Repository[T]) {
+		if ttl >= 0 {
+			r.ttl = ttl
+		}
+	}
+}
+
+// WithTTLFunc sets a per-entity TTL, e.g. to expire sessions at their own deadline;
+// it overrides WithTTL, and a result of 0 or less stores the entity without expiry
+func WithTTLFunc[T any](fn func(T) time.Duration) Option[T] {
+	return func(r *Repository[T]) {
+		r.ttlFn = fn
+	}
+}
+
+// WithIndex maintains a set of entity keys per value of field, as returned by fn, so
+// ListByField can find entities by it; entities for which fn returns "" are not indexed
+func WithIndex[T any](field string, fn func(T) string) Option[T] {
+	return func(r *Repository[T]) {
+		if field != "" && fn != nil {
+			r.indexes[field] = fn
+		}
+	}
+}
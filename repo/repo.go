@@ -0,0 +1,291 @@
+// Package repo provides a small object mapper on top of the cache package: entities are
+// stored under their key with the cache's codec, JSON by default, with optional TTLs and
+// secondary indexes
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/cache"
+)
+
+// indexPrefix is prepended to index set keys, inside the cache's key prefix
+const indexPrefix = "idx:"
+
+// Repository stores entities of type T
+type Repository[T any] struct {
+	cache   *cache.RedisCache
+	keyFn   func(T) string
+	ttl     time.Duration
+	ttlFn   func(T) time.Duration
+	indexes map[string]func(T) string
+}
+
+// NewRepository creates a repository storing entities in c under the key returned by keyFn
+// Give each entity type its own cache prefix, e.g. cache.NewCache(client, "users:")
+func NewRepository[T any](c *cache.RedisCache, keyFn func(T) string, opts ...Option[T]) *Repository[T] {
+	r := &Repository[T]{
+		cache:   c,
+		keyFn:   keyFn,
+		indexes: make(map[string]func(T) string),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// IndexKey returns the Redis key of the index set for value of field
+func (r *Repository[T]) IndexKey(field, value string) string {
+	return r.cache.Key(indexPrefix + field + ":" + value)
+}
+
+// indexKey returns IndexKey within the scope of ctx set with cache.ContextWithPrefix, if any,
+// so each scope indexes its own entities
+func (r *Repository[T]) indexKey(ctx context.Context, field, value string) string {
+	return r.cache.Key(cache.PrefixFromContext(ctx) + indexPrefix + field + ":" + value)
+}
+
+func (r *Repository[T]) client() (redis.UniversalClient, error) {
+	if r.cache == nil || r.cache.Client() == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	return r.cache.Client(), nil
+}
+
+func (r *Repository[T]) entityTTL(entity T) time.Duration {
+	if r.ttlFn != nil {
+		if ttl := r.ttlFn(entity); ttl > 0 {
+			return ttl
+		}
+		return 0
+	}
+	return r.ttl
+}
+
+// Save creates or replaces entity and updates its index entries
+// Index sets are only ever a superset of the matching entities, since new entries are
+// added before the entity is written and stale ones removed after; ListByField filters
+// out the rest, and entries of expired entities stay until PruneIndex removes them
+func (r *Repository[T]) Save(ctx context.Context, entity T) error {
+	client, err := r.client()
+	if err != nil {
+		return err
+	}
+	key := r.keyFn(entity)
+	if key == "" {
+		return ErrEmptyKey
+	}
+
+	var stale []string
+	if len(r.indexes) > 0 {
+		var previous *T
+		old, err := r.Find(ctx, key)
+		switch {
+		case err == nil:
+			previous = &old
+		case !errors.Is(err, ErrNotFound):
+			return err
+		}
+
+		pipe := client.Pipeline()
+		for field, fn := range r.indexes {
+			value := fn(entity)
+			if value != "" {
+				pipe.SAdd(ctx, r.indexKey(ctx, field, value), key)
+			}
+			if previous != nil {
+				if old := fn(*previous); old != "" && old != value {
+					stale = append(stale, r.indexKey(ctx, field, old))
+				}
+			}
+		}
+		if pipe.Len() > 0 {
+			if _, err := pipe.Exec(ctx); err != nil {
+				return fmt.Errorf("failed to update indexes: %w", err)
+			}
+		}
+	}
+
+	if err := r.cache.Set(ctx, key, entity, r.entityTTL(entity)); err != nil {
+		return err
+	}
+	return r.unindex(ctx, client, key, stale)
+}
+
+// unindex removes key from the given index sets
+func (r *Repository[T]) unindex(ctx context.Context, client redis.UniversalClient, key string, indexKeys []string) error {
+	if len(indexKeys) == 0 {
+		return nil
+	}
+	pipe := client.Pipeline()
+	for _, indexKey := range indexKeys {
+		pipe.SRem(ctx, indexKey, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to update indexes: %w", err)
+	}
+	return nil
+}
+
+// Find returns the entity stored under key
+// Returns ErrNotFound if there is none
+func (r *Repository[T]) Find(ctx context.Context, key string) (T, error) {
+	var entity T
+	if _, err := r.client(); err != nil {
+		return entity, err
+	}
+
+	if err := r.cache.Get(ctx, key, &entity); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return entity, ErrNotFound
+		}
+		return entity, err
+	}
+	return entity, nil
+}
+
+// FindMany returns the entities stored under keys in one round trip, in the order of
+// keys; keys without an entity are skipped
+func (r *Repository[T]) FindMany(ctx context.Context, keys []string) ([]T, error) {
+	entities, _, err := r.findMany(ctx, keys)
+	return entities, err
+}
+
+// findMany also returns the keys of the entities found
+func (r *Repository[T]) findMany(ctx context.Context, keys []string) ([]T, []string, error) {
+	client, err := r.client()
+	if err != nil {
+		return nil, nil, err
+	}
+	entities := make([]T, 0, len(keys))
+	if len(keys) == 0 {
+		return entities, nil, nil
+	}
+
+	fullKeys := make([]string, len(keys))
+	for i, key := range keys {
+		fullKeys[i] = r.cache.Key(cache.PrefixFromContext(ctx) + key)
+	}
+	values, err := client.MGet(ctx, fullKeys...).Result()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get entities: %w", err)
+	}
+
+	found := make([]string, 0, len(keys))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var entity T
+		if err := r.cache.Decode([]byte(data), &entity); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal entity %q: %w", keys[i], err)
+		}
+		entities = append(entities, entity)
+		found = append(found, keys[i])
+	}
+	return entities, found, nil
+}
+
+// Exists reports whether an entity is stored under key
+func (r *Repository[T]) Exists(ctx context.Context, key string) (bool, error) {
+	if _, err := r.client(); err != nil {
+		return false, err
+	}
+	return r.cache.Exists(ctx, key)
+}
+
+// Delete removes the entity stored under key and its index entries
+func (r *Repository[T]) Delete(ctx context.Context, key string) error {
+	client, err := r.client()
+	if err != nil {
+		return err
+	}
+
+	var indexKeys []string
+	if len(r.indexes) > 0 {
+		old, err := r.Find(ctx, key)
+		switch {
+		case err == nil:
+			for field, fn := range r.indexes {
+				if value := fn(old); value != "" {
+					indexKeys = append(indexKeys, r.indexKey(ctx, field, value))
+				}
+			}
+		case !errors.Is(err, ErrNotFound):
+			return err
+		}
+	}
+
+	if err := r.cache.Del(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete entity: %w", err)
+	}
+	return r.unindex(ctx, client, key, indexKeys)
+}
+
+// ListByField returns the entities whose indexed field equals value
+// Index entries of expired, deleted, or changed entities are skipped
+func (r *Repository[T]) ListByField(ctx context.Context, field, value string) ([]T, error) {
+	entities, _, err := r.listByField(ctx, field, value)
+	return entities, err
+}
+
+// listByField also returns the index entries that matched no entity
+func (r *Repository[T]) listByField(ctx context.Context, field, value string) ([]T, []interface{}, error) {
+	client, err := r.client()
+	if err != nil {
+		return nil, nil, err
+	}
+	fn, ok := r.indexes[field]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownIndex, field)
+	}
+
+	keys, err := client.SMembers(ctx, r.indexKey(ctx, field, value)).Result()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	candidates, found, err := r.findMany(ctx, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matched := make(map[string]bool, len(found))
+	entities := make([]T, 0, len(candidates))
+	for i, entity := range candidates {
+		if fn(entity) == value {
+			entities = append(entities, entity)
+			matched[found[i]] = true
+		}
+	}
+
+	var stale []interface{}
+	for _, key := range keys {
+		if !matched[key] {
+			stale = append(stale, key)
+		}
+	}
+	return entities, stale, nil
+}
+
+// PruneIndex removes the entries for value of field whose entity expired, was deleted,
+// or no longer has that value, and returns how many were removed
+// Run it from maintenance jobs: an entity saved concurrently can lose its fresh entry
+// until it is saved again
+func (r *Repository[T]) PruneIndex(ctx context.Context, field, value string) (int64, error) {
+	_, stale, err := r.listByField(ctx, field, value)
+	if err != nil || len(stale) == 0 {
+		return 0, err
+	}
+
+	n, err := r.cache.Client().SRem(ctx, r.indexKey(ctx, field, value), stale...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune index: %w", err)
+	}
+	return n, nil
+}
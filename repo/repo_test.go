@@ -0,0 +1,230 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+type user struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Team  string `json:"team"`
+}
+
+func userKey(u user) string { return u.ID }
+
+func newUsers(t *testing.T, opts ...Option[user]) (*Repository[user], *cache.RedisCache) {
+	t.Helper()
+	client, _ := testutil.NewMockRedisClient()
+	t.Cleanup(func() { _ = client.Close() })
+	c := cache.NewCache(client, "users:")
+	return NewRepository(c, userKey, opts...), c
+}
+
+func TestRepository_SaveFind(t *testing.T) {
+	users, c := newUsers(t)
+	ctx := context.Background()
+
+	if err := users.Save(ctx, user{ID: "u1", Email: "a@example.com"}); err != nil {
+		t.Fatalf("Save() error = %v, want nil", err)
+	}
+	if err := users.Save(ctx, user{ID: "u2", Email: "b@example.com"}); err != nil {
+		t.Fatalf("Save() error = %v, want nil", err)
+	}
+	if err := users.Save(ctx, user{}); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("Save() without key error = %v, want ErrEmptyKey", err)
+	}
+
+	got, err := users.Find(ctx, "u1")
+	if err != nil || got.Email != "a@example.com" {
+		t.Errorf("Find() = %+v, %v, want a@example.com", got, err)
+	}
+	if _, err := users.Find(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Find() missing error = %v, want ErrNotFound", err)
+	}
+	if ttl, _ := c.TTL(ctx, "u1"); ttl != -1 {
+		t.Errorf("TTL() = %v, want no expiry", ttl)
+	}
+
+	many, err := users.FindMany(ctx, []string{"u2", "missing", "u1"})
+	if err != nil || len(many) != 2 || many[0].ID != "u2" || many[1].ID != "u1" {
+		t.Errorf("FindMany() = %+v, %v, want u2, u1", many, err)
+	}
+	if many, err := users.FindMany(ctx, nil); err != nil || len(many) != 0 {
+		t.Errorf("FindMany() empty = %v, %v, want none", many, err)
+	}
+
+	if ok, err := users.Exists(ctx, "u1"); err != nil || !ok {
+		t.Errorf("Exists() = %v, %v, want true", ok, err)
+	}
+	if err := users.Delete(ctx, "u1"); err != nil {
+		t.Fatalf("Delete() error = %v, want nil", err)
+	}
+	if ok, _ := users.Exists(ctx, "u1"); ok {
+		t.Error("Exists() after Delete() = true, want false")
+	}
+	if err := users.Delete(ctx, "u1"); err != nil {
+		t.Errorf("Delete() missing error = %v, want nil", err)
+	}
+}
+
+func TestRepository_TTL(t *testing.T) {
+	ctx := context.Background()
+
+	users, c := newUsers(t, WithTTL[user](time.Hour))
+	_ = users.Save(ctx, user{ID: "u1"})
+	if ttl, _ := c.TTL(ctx, "u1"); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("TTL() = %v, want up to 1h", ttl)
+	}
+
+	users, c = newUsers(t, WithTTL[user](time.Hour), WithTTLFunc(func(u user) time.Duration {
+		if u.Team == "guests" {
+			return time.Minute
+		}
+		return 0
+	}))
+	_ = users.Save(ctx, user{ID: "guest", Team: "guests"})
+	_ = users.Save(ctx, user{ID: "member", Team: "staff"})
+	if ttl, _ := c.TTL(ctx, "guest"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("TTL(guest) = %v, want up to 1m", ttl)
+	}
+	if ttl, _ := c.TTL(ctx, "member"); ttl != -1 {
+		t.Errorf("TTL(member) = %v, want no expiry", ttl)
+	}
+}
+
+func TestRepository_Indexes(t *testing.T) {
+	users, c := newUsers(t,
+		WithIndex("team", func(u user) string { return u.Team }),
+		WithIndex("email", func(u user) string { return u.Email }),
+	)
+	ctx := context.Background()
+	client := c.Client()
+
+	_ = users.Save(ctx, user{ID: "u1", Email: "a@example.com", Team: "core"})
+	_ = users.Save(ctx, user{ID: "u2", Email: "b@example.com", Team: "core"})
+	_ = users.Save(ctx, user{ID: "u3", Email: "c@example.com"})
+
+	core, err := users.ListByField(ctx, "team", "core")
+	if err != nil || len(core) != 2 || core[0].ID != "u1" || core[1].ID != "u2" {
+		t.Fatalf("ListByField(team, core) = %+v, %v, want u1, u2", core, err)
+	}
+	if byEmail, _ := users.ListByField(ctx, "email", "c@example.com"); len(byEmail) != 1 || byEmail[0].ID != "u3" {
+		t.Errorf("ListByField(email) = %+v, want u3", byEmail)
+	}
+	if users.IndexKey("team", "core") != "users:idx:team:core" {
+		t.Errorf("IndexKey() = %q, want users:idx:team:core", users.IndexKey("team", "core"))
+	}
+
+	// Moving u2 to another team updates both index sets
+	_ = users.Save(ctx, user{ID: "u2", Email: "b@example.com", Team: "infra"})
+	if members, _ := client.SMembers(ctx, users.IndexKey("team", "core")).Result(); len(members) != 1 || members[0] != "u1" {
+		t.Errorf("core index = %v, want [u1]", members)
+	}
+	if infra, _ := users.ListByField(ctx, "team", "infra"); len(infra) != 1 || infra[0].ID != "u2" {
+		t.Errorf("ListByField(team, infra) = %+v, want u2", infra)
+	}
+
+	if err := users.Delete(ctx, "u1"); err != nil {
+		t.Fatalf("Delete() error = %v, want nil", err)
+	}
+	if n, _ := client.Exists(ctx, users.IndexKey("team", "core"), users.IndexKey("email", "a@example.com")).Result(); n != 0 {
+		t.Errorf("index sets of a deleted entity still exist (%d)", n)
+	}
+
+	if _, err := users.ListByField(ctx, "name", "x"); !errors.Is(err, ErrUnknownIndex) {
+		t.Errorf("ListByField() unknown index error = %v, want ErrUnknownIndex", err)
+	}
+}
+
+func TestRepository_PruneIndex(t *testing.T) {
+	users, c := newUsers(t, WithIndex("team", func(u user) string { return u.Team }))
+	ctx := context.Background()
+
+	_ = users.Save(ctx, user{ID: "u1", Team: "core"})
+	_ = users.Save(ctx, user{ID: "u2", Team: "core"})
+	// Expire u2 behind the repository's back
+	_ = c.Client().Del(ctx, c.Key("u2")).Err()
+
+	if core, _ := users.ListByField(ctx, "team", "core"); len(core) != 1 || core[0].ID != "u1" {
+		t.Errorf("ListByField() = %+v, want only u1", core)
+	}
+	if n, err := users.PruneIndex(ctx, "team", "core"); err != nil || n != 1 {
+		t.Errorf("PruneIndex() = %d, %v, want 1", n, err)
+	}
+	if n, err := users.PruneIndex(ctx, "team", "core"); err != nil || n != 0 {
+		t.Errorf("PruneIndex() again = %d, %v, want 0", n, err)
+	}
+}
+
+func TestRepository_Errors(t *testing.T) {
+	ctx := context.Background()
+	users := NewRepository(cache.NewCache(nil, "users:"), userKey)
+	if err := users.Save(ctx, user{ID: "u1"}); err == nil {
+		t.Error("Save() with nil client error = nil, want error")
+	}
+	if _, err := users.Find(ctx, "u1"); err == nil {
+		t.Error("Find() with nil client error = nil, want error")
+	}
+	if _, err := users.FindMany(ctx, []string{"u1"}); err == nil {
+		t.Error("FindMany() with nil client error = nil, want error")
+	}
+	if _, err := users.Exists(ctx, "u1"); err == nil {
+		t.Error("Exists() with nil client error = nil, want error")
+	}
+	if err := users.Delete(ctx, "u1"); err == nil {
+		t.Error("Delete() with nil client error = nil, want error")
+	}
+	if _, err := users.ListByField(ctx, "team", "x"); err == nil {
+		t.Error("ListByField() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	c := cache.NewCache(client, "users:")
+	users = NewRepository(c, userKey, WithIndex("team", func(u user) string { return u.Team }))
+	_ = client.Set(ctx, c.Key("bad"), "not json", 0).Err()
+	if _, err := users.FindMany(ctx, []string{"bad"}); err == nil {
+		t.Error("FindMany() with a corrupt entity error = nil, want error")
+	}
+	if err := users.Save(ctx, user{ID: "bad", Team: "core"}); err == nil {
+		t.Error("Save() over a corrupt entity error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if err := users.Save(ctx, user{ID: "u1", Team: "core"}); err == nil {
+		t.Error("Save() with failing redis error = nil, want error")
+	}
+	if _, err := users.Find(ctx, "u1"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Find() with failing redis error = %v, want a redis error", err)
+	}
+	if _, err := users.ListByField(ctx, "team", "core"); err == nil {
+		t.Error("ListByField() with failing redis error = nil, want error")
+	}
+}
+
+func TestRepository_Codec(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	t.Cleanup(func() { _ = client.Close() })
+	c := cache.NewCacheWithCodec(client, "users:", codec.Msgpack)
+	users := NewRepository(c, userKey, WithIndex("team", func(u user) string { return u.Team }))
+	ctx := context.Background()
+
+	for _, u := range []user{{ID: "u1", Team: "red"}, {ID: "u2", Team: "red"}} {
+		if err := users.Save(ctx, u); err != nil {
+			t.Fatalf("Save() error = %v, want nil", err)
+		}
+	}
+	if got, err := users.FindMany(ctx, []string{"u1", "u2"}); err != nil || len(got) != 2 {
+		t.Errorf("FindMany() with msgpack = %+v, %v, want 2 users", got, err)
+	}
+	if got, err := users.ListByField(ctx, "team", "red"); err != nil || len(got) != 2 {
+		t.Errorf("ListByField() with msgpack = %+v, %v, want 2 users", got, err)
+	}
+}
@@ -0,0 +1,37 @@
+package revocation
+
+import "time"
+
+// settings holds the configuration of a Store
+type settings struct {
+	keyPrefix string
+	cacheTTL  time.Duration
+	onError   func(error)
+}
+
+// Option configures a Store
+type Option func(*settings)
+
+// WithKeyPrefix sets the prefix of revocation keys and the invalidation channel (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(s *settings) {
+		s.keyPrefix = prefix
+	}
+}
+
+// WithLocalCache remembers in process for up to ttl that a token or subject is not revoked
+// (default: disabled). Run Watch so revocations made by other processes take effect at once
+func WithLocalCache(ttl time.Duration) Option {
+	return func(s *settings) {
+		if ttl >= 0 {
+			s.cacheTTL = ttl
+		}
+	}
+}
+
+// WithOnError sets a callback for errors in Watch, which keeps running after them
+func WithOnError(fn func(error)) Option {
+	return func(s *settings) {
+		s.onError = fn
+	}
+}
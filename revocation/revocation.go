@@ -0,0 +1,243 @@
+// Package revocation keeps a denylist of revoked tokens, such as JWTs, on Redis
+// A revoked token ID is stored until the token expires, and revoking a subject denies every
+// token issued to it until then. Checks that find nothing revoked can be cached in process,
+// with revocations broadcast over pub/sub to drop the cached answers
+package revocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// DefaultKeyPrefix is the default prefix for revocation keys and the invalidation channel
+const DefaultKeyPrefix = "revoked:"
+
+// Token identifies an issued token, typically from its jti, sub, and iat claims
+type Token struct {
+	ID       string
+	Subject  string
+	IssuedAt time.Time
+}
+
+// Store records revoked tokens and subjects
+type Store struct {
+	client *redis.Client
+	settings
+
+	mu         sync.Mutex
+	local      map[string]time.Time // names known not to be revoked, with their expiry
+	generation uint64
+}
+
+// NewStore creates a revocation store
+func NewStore(client *redis.Client, opts ...Option) *Store {
+	s := &Store{
+		client:   client,
+		settings: settings{keyPrefix: DefaultKeyPrefix},
+		local:    make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(&s.settings)
+	}
+	return s
+}
+
+// Channel returns the pub/sub channel announcing revocations
+func (s *Store) Channel() string {
+	return s.keyPrefix + "changes"
+}
+
+// tokenName and subjectName name an entry both in Redis, after the prefix, and in the local cache
+func tokenName(id string) string {
+	return "jti:" + id
+}
+
+func subjectName(subject string) string {
+	return "sub:" + subject
+}
+
+// Revoke denies the token id until expiresAt, after which the token is invalid anyway
+// Revoking a token that has already expired does nothing
+func (s *Store) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := s.write(ctx, tokenName(id), "1", ttl); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// RevokeSubject denies every token issued to subject up to now
+// maxLifetime is the longest lifetime of a token, after which the record is no longer needed
+// Tokens are compared by IssuedAt, so with second precision iat claims, tokens issued within
+// the same second as the revocation are denied too
+func (s *Store) RevokeSubject(ctx context.Context, subject string, maxLifetime time.Duration) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if maxLifetime <= 0 {
+		return nil
+	}
+
+	cutoff := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	if err := s.write(ctx, subjectName(subject), cutoff, maxLifetime); err != nil {
+		return fmt.Errorf("failed to revoke subject: %w", err)
+	}
+	return nil
+}
+
+// write stores a revocation and announces it, dropping the local cache entry right away
+func (s *Store) write(ctx context.Context, name, value string, ttl time.Duration) error {
+	pipe := s.client.Pipeline()
+	pipe.Set(ctx, s.keyPrefix+name, value, ttl)
+	pipe.Publish(ctx, s.Channel(), name)
+	_, err := pipe.Exec(ctx)
+	s.invalidate(name)
+	return err
+}
+
+// IsRevoked reports whether the token id has been revoked
+func (s *Store) IsRevoked(ctx context.Context, id string) (bool, error) {
+	return s.Check(ctx, Token{ID: id})
+}
+
+// IsSubjectRevoked reports whether a token issued to subject at issuedAt has been revoked
+// through RevokeSubject
+func (s *Store) IsSubjectRevoked(ctx context.Context, subject string, issuedAt time.Time) (bool, error) {
+	return s.Check(ctx, Token{Subject: subject, IssuedAt: issuedAt})
+}
+
+// Check reports whether token has been revoked, by its ID or through its subject, in at most
+// one round trip. An empty ID or Subject skips that check
+func (s *Store) Check(ctx context.Context, token Token) (bool, error) {
+	if s.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+
+	var names []string
+	if token.ID != "" {
+		names = append(names, tokenName(token.ID))
+	}
+	if token.Subject != "" {
+		names = append(names, subjectName(token.Subject))
+	}
+
+	generation, names := s.uncached(names)
+	if len(names) == 0 {
+		return false, nil
+	}
+
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = s.keyPrefix + name
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check revocation: %w", err)
+	}
+
+	revoked := false
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			s.store(names[i], generation)
+			continue
+		}
+		if names[i] == tokenName(token.ID) {
+			revoked = true
+			continue
+		}
+		cutoff, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("malformed subject revocation %q: %w", raw, err)
+		}
+		// A cutoff is never lifted early, so tokens issued after it stay valid until it expires
+		if token.IssuedAt.UnixMilli() <= cutoff {
+			revoked = true
+		}
+	}
+	return revoked, nil
+}
+
+// Watch drops locally cached answers as other processes revoke tokens, until ctx is cancelled
+// The whole local cache is dropped whenever the subscription is (re)established, so
+// revocations made while disconnected are not missed. Watch returns nil on cancellation
+func (s *Store) Watch(ctx context.Context) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	return utils.WatchChannel(ctx, s.client, s.Channel(), utils.ChannelHandler{
+		OnSubscribe: s.invalidateAll,
+		OnMessage:   s.invalidate,
+		OnError: func(err error) {
+			s.reportError(fmt.Errorf("revocation subscription failed: %w", err))
+		},
+	})
+}
+
+// uncached returns the names without a fresh local entry, along with the current generation
+func (s *Store) uncached(names []string) (uint64, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	out := names[:0]
+	for _, name := range names {
+		expires, ok := s.local[name]
+		if ok && now.Before(expires) {
+			continue
+		}
+		delete(s.local, name)
+		out = append(out, name)
+	}
+	return s.generation, out
+}
+
+// store caches that name is not revoked unless an invalidation happened since generation
+// was read, in which case the answer read from Redis may already be stale
+func (s *Store) store(name string, generation uint64) {
+	if s.cacheTTL <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation != generation {
+		return
+	}
+	s.local[name] = time.Now().Add(s.cacheTTL)
+}
+
+func (s *Store) invalidate(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	delete(s.local, name)
+}
+
+func (s *Store) invalidateAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	clear(s.local)
+}
+
+func (s *Store) reportError(err error) {
+	if s.onError != nil && err != nil && !errors.Is(err, context.Canceled) {
+		s.onError(err)
+	}
+}
@@ -0,0 +1,191 @@
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestNewStore(t *testing.T) {
+	s := NewStore(nil)
+	if s.Channel() != "revoked:changes" || s.cacheTTL != 0 {
+		t.Errorf("NewStore() = %q/%v, want defaults", s.Channel(), s.cacheTTL)
+	}
+
+	s = NewStore(nil, WithKeyPrefix("jwt:"), WithLocalCache(time.Minute), WithLocalCache(-time.Second))
+	if s.Channel() != "jwt:changes" || s.cacheTTL != time.Minute {
+		t.Errorf("NewStore() with options = %q/%v, want jwt:changes/1m", s.Channel(), s.cacheTTL)
+	}
+}
+
+func TestStore_Revoke(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client)
+
+	if revoked, err := s.IsRevoked(ctx, "t1"); err != nil || revoked {
+		t.Fatalf("IsRevoked() before Revoke = %v, %v, want false", revoked, err)
+	}
+	if err := s.Revoke(ctx, "t1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Revoke() error = %v, want nil", err)
+	}
+	if revoked, err := s.IsRevoked(ctx, "t1"); err != nil || !revoked {
+		t.Errorf("IsRevoked() = %v, %v, want true", revoked, err)
+	}
+	if ttl := client.TTL(ctx, "revoked:jti:t1").Val(); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("TTL() = %v, want the token's remaining lifetime", ttl)
+	}
+
+	// Expired tokens are not recorded
+	if err := s.Revoke(ctx, "t2", time.Now().Add(-time.Second)); err != nil {
+		t.Errorf("Revoke() of an expired token error = %v, want nil", err)
+	}
+	if n, _ := client.Exists(ctx, "revoked:jti:t2").Result(); n != 0 {
+		t.Error("Revoke() stored an expired token")
+	}
+}
+
+func TestStore_RevokeSubject(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client)
+	before := time.Now().Add(-time.Minute)
+
+	if revoked, _ := s.IsSubjectRevoked(ctx, "alice", before); revoked {
+		t.Error("IsSubjectRevoked() before RevokeSubject = true, want false")
+	}
+	if err := s.RevokeSubject(ctx, "alice", time.Hour); err != nil {
+		t.Fatalf("RevokeSubject() error = %v, want nil", err)
+	}
+	if revoked, err := s.IsSubjectRevoked(ctx, "alice", before); err != nil || !revoked {
+		t.Errorf("IsSubjectRevoked() of an older token = %v, %v, want true", revoked, err)
+	}
+	if revoked, _ := s.IsSubjectRevoked(ctx, "alice", time.Now().Add(time.Second)); revoked {
+		t.Error("IsSubjectRevoked() of a newer token = true, want false")
+	}
+	if revoked, _ := s.IsSubjectRevoked(ctx, "bob", before); revoked {
+		t.Error("IsSubjectRevoked() of another subject = true, want false")
+	}
+
+	// Check combines both lists
+	if revoked, _ := s.Check(ctx, Token{ID: "t1", Subject: "alice", IssuedAt: before}); !revoked {
+		t.Error("Check() of a token of a revoked subject = false, want true")
+	}
+	_ = s.Revoke(ctx, "t2", time.Now().Add(time.Hour))
+	if revoked, _ := s.Check(ctx, Token{ID: "t2", Subject: "bob", IssuedAt: before}); !revoked {
+		t.Error("Check() of a revoked token = false, want true")
+	}
+	if revoked, _ := s.Check(ctx, Token{ID: "t3", Subject: "bob", IssuedAt: before}); revoked {
+		t.Error("Check() of a valid token = true, want false")
+	}
+	if revoked, err := s.Check(ctx, Token{}); err != nil || revoked {
+		t.Errorf("Check() of an empty token = %v, %v, want false", revoked, err)
+	}
+
+	if err := s.RevokeSubject(ctx, "carol", 0); err != nil {
+		t.Errorf("RevokeSubject() with zero lifetime error = %v, want nil", err)
+	}
+	if revoked, _ := s.IsSubjectRevoked(ctx, "carol", before); revoked {
+		t.Error("RevokeSubject() with zero lifetime revoked the subject")
+	}
+}
+
+func TestStore_LocalCacheAndWatch(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	writer := NewStore(client)
+	reader := NewStore(client, WithLocalCache(time.Hour))
+
+	if revoked, _ := reader.IsRevoked(ctx, "t1"); revoked {
+		t.Fatal("IsRevoked() = true, want false")
+	}
+	_ = writer.Revoke(ctx, "t1", time.Now().Add(time.Hour))
+	if revoked, _ := reader.IsRevoked(ctx, "t1"); revoked {
+		t.Error("IsRevoked() without Watch = true, want the cached false")
+	}
+
+	// The revoking process drops its own cached answer at once
+	if revoked, _ := reader.IsSubjectRevoked(ctx, "alice", time.Now()); revoked {
+		t.Fatal("IsSubjectRevoked() = true, want false")
+	}
+	_ = reader.RevokeSubject(ctx, "alice", time.Hour)
+	if revoked, _ := reader.IsSubjectRevoked(ctx, "alice", time.Now().Add(-time.Second)); !revoked {
+		t.Error("IsSubjectRevoked() after a local revocation = false, want true")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- reader.Watch(ctx) }()
+
+	waitFor := func(id string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if revoked, _ := reader.IsRevoked(ctx, id); revoked {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("IsRevoked(%s) did not become true", id)
+	}
+
+	waitFor("t1")
+	if revoked, _ := reader.IsRevoked(ctx, "t2"); revoked {
+		t.Fatal("IsRevoked(t2) = true, want false")
+	}
+	_ = writer.Revoke(ctx, "t2", time.Now().Add(time.Hour))
+	waitFor("t2")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch() error = %v, want nil on cancellation", err)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	s := NewStore(nil)
+	if err := s.Revoke(ctx, "t", time.Now().Add(time.Hour)); err == nil {
+		t.Error("Revoke() with nil client error = nil, want error")
+	}
+	if err := s.RevokeSubject(ctx, "alice", time.Hour); err == nil {
+		t.Error("RevokeSubject() with nil client error = nil, want error")
+	}
+	if _, err := s.IsRevoked(ctx, "t"); err == nil {
+		t.Error("IsRevoked() with nil client error = nil, want error")
+	}
+	if _, err := s.Check(ctx, Token{ID: "t"}); err == nil {
+		t.Error("Check() with nil client error = nil, want error")
+	}
+	if err := s.Watch(ctx); err == nil {
+		t.Error("Watch() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	s = NewStore(client)
+	_ = client.Set(ctx, "revoked:sub:alice", "garbage", 0).Err()
+	if _, err := s.IsSubjectRevoked(ctx, "alice", time.Now()); err == nil {
+		t.Error("IsSubjectRevoked() with a malformed cutoff error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if err := s.Revoke(ctx, "t", time.Now().Add(time.Hour)); err == nil {
+		t.Error("Revoke() error = nil, want error")
+	}
+	if err := s.RevokeSubject(ctx, "bob", time.Hour); err == nil {
+		t.Error("RevokeSubject() error = nil, want error")
+	}
+	if _, err := s.IsRevoked(ctx, "t"); err == nil {
+		t.Error("IsRevoked() error = nil, want error")
+	}
+}
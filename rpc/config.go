@@ -0,0 +1,114 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// DefaultKeyPrefix is the default prefix for request and reply lists
+const DefaultKeyPrefix = "rpc:"
+
+// Config represents RPC client and server configuration
+// Clients and servers of a service must agree on KeyPrefix and Codec
+type Config struct {
+	// KeyPrefix prefixes the request list of every service and the reply list of every call
+	// (default: "rpc:")
+	KeyPrefix string
+
+	// Codec encodes request and reply payloads (default: codec.Default())
+	Codec codec.Codec
+
+	// Timeout bounds a call whose context has no earlier deadline (default: 5s)
+	Timeout time.Duration
+
+	// ReplyTTL is how long a reply waits for a caller that already gave up (default: 1m)
+	ReplyTTL time.Duration
+
+	// Concurrency is the number of requests handled in parallel by Serve (default: 4)
+	Concurrency int
+
+	// BlockTimeout is how long Serve waits for a request before checking for cancellation;
+	// Redis blocks in whole seconds, so it is rounded down to at least 1s (default: 1s)
+	BlockTimeout time.Duration
+
+	// OnError is called with errors from Serve's receive loop and handlers (optional)
+	OnError func(error)
+}
+
+// DefaultConfig returns a Config with default values
+func DefaultConfig() Config {
+	return Config{
+		KeyPrefix:    DefaultKeyPrefix,
+		Codec:        codec.Default(),
+		Timeout:      5 * time.Second,
+		ReplyTTL:     time.Minute,
+		Concurrency:  4,
+		BlockTimeout: time.Second,
+	}
+}
+
+// WithKeyPrefix sets the prefix of request and reply lists
+func (c Config) WithKeyPrefix(prefix string) Config {
+	c.KeyPrefix = prefix
+	return c
+}
+
+// WithCodec sets the payload codec
+func (c Config) WithCodec(cd codec.Codec) Config {
+	c.Codec = cd
+	return c
+}
+
+// WithTimeout sets the default call timeout
+func (c Config) WithTimeout(timeout time.Duration) Config {
+	c.Timeout = timeout
+	return c
+}
+
+// WithReplyTTL sets how long unread replies are kept
+func (c Config) WithReplyTTL(ttl time.Duration) Config {
+	c.ReplyTTL = ttl
+	return c
+}
+
+// WithConcurrency sets the number of requests handled in parallel by Serve
+func (c Config) WithConcurrency(n int) Config {
+	c.Concurrency = n
+	return c
+}
+
+// WithBlockTimeout sets how long Serve waits for a request per round trip
+func (c Config) WithBlockTimeout(timeout time.Duration) Config {
+	c.BlockTimeout = timeout
+	return c
+}
+
+// WithOnError sets the callback for receive loop and handler errors
+func (c Config) WithOnError(fn func(error)) Config {
+	c.OnError = fn
+	return c
+}
+
+// withDefaults fills zero values with defaults
+func withDefaults(cfg Config) Config {
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = DefaultKeyPrefix
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = codec.Default()
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.ReplyTTL <= 0 {
+		cfg.ReplyTTL = time.Minute
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	if cfg.BlockTimeout < time.Second {
+		cfg.BlockTimeout = time.Second
+	}
+	return cfg
+}
@@ -0,0 +1,61 @@
+package rpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+
+	if cfg.KeyPrefix != "rpc:" {
+		t.Errorf("DefaultConfig().KeyPrefix = %q, want %q", cfg.KeyPrefix, "rpc:")
+	}
+	if cfg.Codec == nil || cfg.Codec.Name() != codec.NameJSON {
+		t.Errorf("DefaultConfig().Codec = %v, want json", cfg.Codec)
+	}
+	if cfg.Timeout != 5*time.Second || cfg.ReplyTTL != time.Minute {
+		t.Errorf("DefaultConfig() Timeout/ReplyTTL = %v/%v, want 5s/1m", cfg.Timeout, cfg.ReplyTTL)
+	}
+	if cfg.Concurrency != 4 || cfg.BlockTimeout != time.Second {
+		t.Errorf("DefaultConfig() Concurrency/BlockTimeout = %d/%v, want 4/1s", cfg.Concurrency, cfg.BlockTimeout)
+	}
+}
+
+func TestConfig_With(t *testing.T) {
+	var called bool
+	cfg := DefaultConfig().
+		WithKeyPrefix("svc:").
+		WithCodec(codec.Msgpack).
+		WithTimeout(time.Second).
+		WithReplyTTL(10 * time.Second).
+		WithConcurrency(8).
+		WithBlockTimeout(3 * time.Second).
+		WithOnError(func(error) { called = true })
+
+	if cfg.KeyPrefix != "svc:" || cfg.Codec.Name() != codec.NameMsgpack {
+		t.Errorf("WithKeyPrefix/WithCodec = %q/%q, want svc:/msgpack", cfg.KeyPrefix, cfg.Codec.Name())
+	}
+	if cfg.Timeout != time.Second || cfg.ReplyTTL != 10*time.Second {
+		t.Errorf("WithTimeout/WithReplyTTL = %v/%v, want 1s/10s", cfg.Timeout, cfg.ReplyTTL)
+	}
+	if cfg.Concurrency != 8 || cfg.BlockTimeout != 3*time.Second {
+		t.Errorf("WithConcurrency/WithBlockTimeout = %d/%v, want 8/3s", cfg.Concurrency, cfg.BlockTimeout)
+	}
+	cfg.OnError(nil)
+	if !called {
+		t.Error("WithOnError() callback not set")
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	cfg := withDefaults(Config{BlockTimeout: 10 * time.Millisecond})
+	if cfg.KeyPrefix != DefaultKeyPrefix || cfg.Codec == nil || cfg.Timeout != 5*time.Second || cfg.ReplyTTL != time.Minute {
+		t.Errorf("withDefaults() = %+v, want defaults", cfg)
+	}
+	if cfg.Concurrency != 1 || cfg.BlockTimeout != time.Second {
+		t.Errorf("withDefaults() Concurrency/BlockTimeout = %d/%v, want 1/1s", cfg.Concurrency, cfg.BlockTimeout)
+	}
+}
@@ -0,0 +1,29 @@
+package rpc
+
+import "errors"
+
+var (
+	// ErrTimeout indicates no reply arrived before the call's deadline.
+	ErrTimeout = errors.New("rpc call timed out")
+
+	// ErrRemote indicates the remote handler returned an error; it is wrapped by RemoteError.
+	ErrRemote = errors.New("rpc handler failed")
+
+	// ErrHandlerPanic indicates the request handler panicked while processing a request.
+	ErrHandlerPanic = errors.New("rpc handler panicked")
+)
+
+// RemoteError carries the error message a service's handler returned
+// It wraps ErrRemote
+type RemoteError struct {
+	Service string
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return "rpc service " + e.Service + ": " + e.Message
+}
+
+func (e *RemoteError) Unwrap() error {
+	return ErrRemote
+}
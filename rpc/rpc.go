@@ -0,0 +1,165 @@
+// Package rpc provides request/reply calls between services that share a Redis server
+// A call pushes its request onto the service's list with the name of a reply list, and
+// waits on that list for the response. Requests are popped by one server, so a call is
+// handled at most once; a request whose caller has given up is dropped unhandled
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// replyPollInterval is how often a call checks for its reply in the last second before
+// its deadline, as Redis blocks in whole seconds
+const replyPollInterval = 10 * time.Millisecond
+
+// envelope is a request as stored in the service's list; the server replies on the list
+// replyKey derives from ID, never on one the request names, so requests cannot make it write
+// to arbitrary keys
+type envelope struct {
+	ID       string `json:"id"`
+	Payload  []byte `json:"payload,omitempty"`
+	Deadline int64  `json:"deadline"` // Unix milliseconds
+}
+
+// reply is a response as stored in the call's reply list
+type reply struct {
+	Payload []byte `json:"payload,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// requestKey returns the list holding the pending requests of service
+func requestKey(prefix, service string) string {
+	return prefix + service + ":requests"
+}
+
+// replyKey returns the list receiving the reply of call id
+func replyKey(prefix, id string) string {
+	return prefix + "replies:" + id
+}
+
+// generateCallID generates a unique call ID
+func generateCallID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// Client calls services served by a Server
+type Client struct {
+	client *redis.Client
+	cfg    Config
+}
+
+// NewClient creates a new RPC client; zero config values fall back to defaults
+func NewClient(client *redis.Client, cfg Config) *Client {
+	return &Client{client: client, cfg: withDefaults(cfg)}
+}
+
+// Config returns the client configuration
+func (c *Client) Config() Config {
+	return c.cfg
+}
+
+// Call sends payload to service and decodes the response into result, unless result is nil
+// The call waits until ctx's deadline or Timeout, whichever is earlier, and returns
+// ErrTimeout if no reply arrived; an error returned by the handler comes back as *RemoteError
+func (c *Client) Call(ctx context.Context, service string, payload, result interface{}) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	deadline := time.Now().Add(c.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	data, err := c.cfg.Codec.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode rpc payload: %w", err)
+	}
+	id, err := generateCallID()
+	if err != nil {
+		return err
+	}
+	req, err := json.Marshal(envelope{
+		ID:       id,
+		Payload:  data,
+		Deadline: deadline.UnixMilli(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode rpc request: %w", err)
+	}
+
+	if err := c.client.LPush(ctx, requestKey(c.cfg.KeyPrefix, service), req).Err(); err != nil {
+		return fmt.Errorf("failed to send rpc request: %w", err)
+	}
+
+	raw, err := c.waitReply(ctx, replyKey(c.cfg.KeyPrefix, id), deadline)
+	if err != nil {
+		return err
+	}
+
+	var resp reply
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		return fmt.Errorf("failed to decode rpc reply: %w", err)
+	}
+	if resp.Error != "" {
+		return &RemoteError{Service: service, Message: resp.Error}
+	}
+	if result != nil && len(resp.Payload) > 0 {
+		if err := c.cfg.Codec.Unmarshal(resp.Payload, result); err != nil {
+			return fmt.Errorf("failed to decode rpc result: %w", err)
+		}
+	}
+	return nil
+}
+
+// waitReply pops the reply from key, blocking a second at a time so cancellation is
+// noticed, then polling until deadline once less than a second remains
+func (c *Client) waitReply(ctx context.Context, key string, deadline time.Time) (string, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return "", ErrTimeout
+		}
+
+		if remaining >= time.Second {
+			res, err := c.client.BLPop(ctx, time.Second, key).Result()
+			if err == nil && len(res) == 2 {
+				return res[1], nil
+			}
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return "", fmt.Errorf("failed to receive rpc reply: %w", err)
+			}
+			continue
+		}
+
+		res, err := c.client.LPop(ctx, key).Result()
+		if err == nil {
+			return res, nil
+		}
+		if !errors.Is(err, redis.Nil) {
+			return "", fmt.Errorf("failed to receive rpc reply: %w", err)
+		}
+		timer := time.NewTimer(min(replyPollInterval, remaining))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+type sumRequest struct {
+	A, B int
+}
+
+type sumResponse struct {
+	Sum int
+}
+
+// startServer runs srv until the test ends
+func startServer(t *testing.T, srv *Server) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v, want nil on cancellation", err)
+		}
+	})
+}
+
+func TestCall(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	var mu sync.Mutex
+	var errs []error
+	srv := NewServer(client, DefaultConfig().WithConcurrency(2).WithOnError(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}))
+	srv.Handle("math.sum", func(ctx context.Context, req *Request) (interface{}, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("handler context has no deadline")
+		}
+		var in sumRequest
+		if err := req.Decode(&in); err != nil {
+			return nil, err
+		}
+		return sumResponse{Sum: in.A + in.B}, nil
+	})
+	srv.Handle("math.fail", func(context.Context, *Request) (interface{}, error) {
+		return nil, errors.New("division by zero")
+	})
+	srv.Handle("math.panic", func(context.Context, *Request) (interface{}, error) {
+		panic("boom")
+	})
+	srv.Handle("math.noop", func(context.Context, *Request) (interface{}, error) {
+		return nil, nil
+	})
+	startServer(t, srv)
+
+	ctx := context.Background()
+	c := NewClient(client, DefaultConfig())
+
+	var out sumResponse
+	if err := c.Call(ctx, "math.sum", sumRequest{A: 2, B: 3}, &out); err != nil || out.Sum != 5 {
+		t.Fatalf("Call(math.sum) = %+v, %v, want 5", out, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var out sumResponse
+			if err := c.Call(ctx, "math.sum", sumRequest{A: i, B: i}, &out); err != nil || out.Sum != 2*i {
+				t.Errorf("concurrent Call() = %+v, %v, want %d", out, err, 2*i)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	err := c.Call(ctx, "math.fail", nil, nil)
+	var remote *RemoteError
+	if !errors.As(err, &remote) || !errors.Is(err, ErrRemote) || remote.Service != "math.fail" || remote.Message != "division by zero" {
+		t.Errorf("Call(math.fail) error = %v, want RemoteError", err)
+	}
+
+	err = c.Call(ctx, "math.panic", nil, nil)
+	if !errors.As(err, &remote) || !strings.Contains(remote.Message, "panicked") {
+		t.Errorf("Call(math.panic) error = %v, want RemoteError about the panic", err)
+	}
+
+	out = sumResponse{Sum: -1}
+	if err := c.Call(ctx, "math.noop", nil, &out); err != nil || out.Sum != -1 {
+		t.Errorf("Call(math.noop) = %+v, %v, want result untouched", out, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 2 || !errors.Is(errs[1], ErrHandlerPanic) {
+		t.Errorf("OnError received %v, want the handler error and ErrHandlerPanic", errs)
+	}
+}
+
+func TestCall_Timeout(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	c := NewClient(client, DefaultConfig().WithTimeout(100*time.Millisecond))
+
+	start := time.Now()
+	if err := c.Call(ctx, "nobody", "ping", nil); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Call() without server error = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Call() took %v, want about 100ms", elapsed)
+	}
+
+	// A context deadline earlier than Timeout wins
+	c = NewClient(client, DefaultConfig())
+	shortCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := c.Call(shortCtx, "nobody", "ping", nil); !errors.Is(err, ErrTimeout) && !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Call() with short context error = %v, want timeout", err)
+	}
+
+	// The late server drops requests whose callers gave up
+	handled := make(chan struct{}, 2)
+	srv := NewServer(client, DefaultConfig())
+	srv.Handle("nobody", func(context.Context, *Request) (interface{}, error) {
+		handled <- struct{}{}
+		return nil, nil
+	})
+	startServer(t, srv)
+	time.Sleep(100 * time.Millisecond)
+	if len(handled) != 0 {
+		t.Error("server handled a request whose deadline had passed")
+	}
+	if n, _ := client.LLen(ctx, requestKey(DefaultKeyPrefix, "nobody")).Result(); n != 0 {
+		t.Errorf("request list length = %d, want 0", n)
+	}
+}
+
+func TestCall_Cancelled(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewClient(client, DefaultConfig().WithTimeout(time.Minute))
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if err := c.Call(ctx, "nobody", "ping", nil); err == nil {
+		t.Error("Call() after cancellation error = nil, want error")
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	if err := NewServer(nil, DefaultConfig()).Serve(ctx); err == nil {
+		t.Error("Serve() with nil client error = nil, want error")
+	}
+	if err := NewClient(nil, DefaultConfig()).Call(ctx, "s", nil, nil); err == nil {
+		t.Error("Call() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	srv := NewServer(client, DefaultConfig())
+	if err := srv.Serve(ctx); err == nil {
+		t.Error("Serve() without handlers error = nil, want error")
+	}
+	srv.Handle("s", func(context.Context, *Request) (interface{}, error) { return nil, nil })
+	srv.Handle("s", nil)
+	if err := srv.Serve(ctx); err == nil {
+		t.Error("Serve() after removing the only handler error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if err := NewClient(client, DefaultConfig()).Call(ctx, "s", nil, nil); err == nil {
+		t.Error("Call() error = nil, want error")
+	}
+}
+
+func TestServer_MalformedRequest(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	errs := make(chan error, 2)
+	srv := NewServer(client, DefaultConfig().WithOnError(func(err error) { errs <- err }))
+	srv.Handle("s", func(context.Context, *Request) (interface{}, error) { return nil, nil })
+	startServer(t, srv)
+
+	ctx := context.Background()
+	_ = client.LPush(ctx, requestKey(DefaultKeyPrefix, "s"), "not-json").Err()
+	_ = client.LPush(ctx, requestKey(DefaultKeyPrefix, "s"), `{"deadline":1}`).Err()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-errs:
+		case <-time.After(2 * time.Second):
+			t.Fatal("malformed request was not reported")
+		}
+	}
+}
+
+func TestServer_IgnoresReplyTo(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	srv := NewServer(client, DefaultConfig())
+	srv.Handle("s", func(context.Context, *Request) (interface{}, error) { return "ok", nil })
+	startServer(t, srv)
+
+	// A request naming another key as its reply list cannot make the server write there
+	ctx := context.Background()
+	_ = client.Set(ctx, "victim", "intact", 0).Err()
+	deadline := time.Now().Add(time.Minute).UnixMilli()
+	_ = client.LPush(ctx, requestKey(DefaultKeyPrefix, "s"), fmt.Sprintf(`{"id":"forged","reply_to":"victim","deadline":%d}`, deadline)).Err()
+
+	replies := replyKey(DefaultKeyPrefix, "forged")
+	if _, err := client.BLPop(ctx, 2*time.Second, replies).Result(); err != nil {
+		t.Fatalf("BLPop() of the derived reply list error = %v, want the reply", err)
+	}
+	if v, err := client.Get(ctx, "victim").Result(); err != nil || v != "intact" {
+		t.Errorf("victim key = %q, %v, want it untouched", v, err)
+	}
+	if ttl := client.PTTL(ctx, "victim").Val(); ttl != -1 {
+		t.Errorf("PTTL() of the victim key = %v, want no TTL set", ttl)
+	}
+}
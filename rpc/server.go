@@ -0,0 +1,218 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// Handler serves a request and returns the response payload, which may be nil
+// A returned error is sent to the caller as a RemoteError carrying its message
+type Handler func(ctx context.Context, req *Request) (interface{}, error)
+
+// Request is a call received by a Server
+type Request struct {
+	// ID identifies the call
+	ID string
+
+	// Service is the service the call was sent to
+	Service string
+
+	// Payload is the encoded request payload
+	Payload []byte
+
+	// Deadline is when the caller stops waiting for the reply
+	Deadline time.Time
+
+	codec codec.Codec
+}
+
+// Decode unmarshals the request payload into v using the server's codec
+func (r *Request) Decode(v interface{}) error {
+	if err := r.codec.Unmarshal(r.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode rpc payload: %w", err)
+	}
+	return nil
+}
+
+// Server dispatches requests sent to its services to their handlers
+type Server struct {
+	client *redis.Client
+	cfg    Config
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// NewServer creates a new RPC server; zero config values fall back to defaults
+func NewServer(client *redis.Client, cfg Config) *Server {
+	return &Server{
+		client:   client,
+		cfg:      withDefaults(cfg),
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Config returns the server configuration
+func (s *Server) Config() Config {
+	return s.cfg
+}
+
+// Handle registers handler for service, replacing any previous handler
+// Services must be registered before Serve is called
+func (s *Server) Handle(service string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if handler == nil {
+		delete(s.handlers, service)
+		return
+	}
+	s.handlers[service] = handler
+}
+
+// Serve receives requests for the registered services with Concurrency workers until ctx is
+// cancelled, then waits for the handlers in progress and returns nil
+// Handlers get a context carrying the caller's deadline that is not cancelled with ctx, so
+// requests already received are answered during shutdown
+func (s *Server) Serve(ctx context.Context) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	s.mu.RLock()
+	keys := make([]string, 0, len(s.handlers))
+	services := make(map[string]string, len(s.handlers))
+	for service := range s.handlers {
+		key := requestKey(s.cfg.KeyPrefix, service)
+		keys = append(keys, key)
+		services[key] = service
+	}
+	s.mu.RUnlock()
+	if len(keys) == 0 {
+		return fmt.Errorf("no rpc handlers registered")
+	}
+	sort.Strings(keys)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx, keys, services)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// work pops and handles requests until ctx is cancelled
+func (s *Server) work(ctx context.Context, keys []string, services map[string]string) {
+	for ctx.Err() == nil {
+		res, err := s.client.BRPop(ctx, s.cfg.BlockTimeout, keys...).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.reportError(fmt.Errorf("failed to receive rpc request: %w", err))
+			wait(ctx, s.cfg.BlockTimeout)
+			continue
+		}
+		if len(res) != 2 {
+			continue
+		}
+		s.handle(context.WithoutCancel(ctx), services[res[0]], res[1])
+	}
+}
+
+// handle runs the handler of service for the raw request and sends its reply
+func (s *Server) handle(ctx context.Context, service, raw string) {
+	var env envelope
+	if err := json.Unmarshal([]byte(raw), &env); err != nil {
+		s.reportError(fmt.Errorf("dropped malformed rpc request for %s: %w", service, err))
+		return
+	}
+	if env.ID == "" {
+		s.reportError(fmt.Errorf("dropped rpc request for %s without a call ID", service))
+		return
+	}
+	deadline := time.UnixMilli(env.Deadline)
+	if time.Now().After(deadline) {
+		// The caller has already returned ErrTimeout
+		return
+	}
+
+	s.mu.RLock()
+	handler := s.handlers[service]
+	s.mu.RUnlock()
+
+	var resp reply
+	if handler == nil {
+		resp.Error = "no handler registered"
+	} else {
+		req := &Request{ID: env.ID, Service: service, Payload: env.Payload, Deadline: deadline, codec: s.cfg.Codec}
+		hctx, cancel := context.WithDeadline(ctx, deadline)
+		result, err := runHandler(hctx, handler, req)
+		cancel()
+		switch {
+		case err != nil:
+			s.reportError(fmt.Errorf("rpc %s call %s failed: %w", service, env.ID, err))
+			resp.Error = err.Error()
+		case result != nil:
+			data, err := s.cfg.Codec.Marshal(result)
+			if err != nil {
+				s.reportError(fmt.Errorf("failed to encode rpc result: %w", err))
+				resp.Error = "failed to encode result"
+				break
+			}
+			resp.Payload = data
+		}
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		s.reportError(fmt.Errorf("failed to encode rpc reply: %w", err))
+		return
+	}
+	key := replyKey(s.cfg.KeyPrefix, env.ID)
+	pipe := s.client.Pipeline()
+	pipe.RPush(ctx, key, data)
+	pipe.PExpire(ctx, key, s.cfg.ReplyTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.reportError(fmt.Errorf("failed to send rpc reply: %w", err))
+	}
+}
+
+func runHandler(ctx context.Context, handler Handler, req *Request) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func (s *Server) reportError(err error) {
+	if s.cfg.OnError != nil && err != nil && !errors.Is(err, context.Canceled) {
+		s.cfg.OnError(err)
+	}
+}
+
+// wait sleeps for d or until ctx is cancelled
+func wait(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,72 @@
+package schedule
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for schedule keys
+	DefaultKeyPrefix = "schedule:"
+
+	// DefaultPayloadSuffix is appended to the schedule key to name the payload hash
+	DefaultPayloadSuffix = ":payloads"
+)
+
+// Config represents scheduler configuration
+type Config struct {
+	// Key is the sorted set holding task IDs scored by due time (default: "schedule:" + name)
+	Key string
+
+	// PayloadKey is the hash holding task payloads by ID (default: Key + ":payloads")
+	PayloadKey string
+
+	// Codec encodes task payloads (default: codec.Default())
+	Codec codec.Codec
+
+	// PollInterval is how often Run checks for due tasks (default: 1s)
+	PollInterval time.Duration
+
+	// BatchSize is the maximum number of tasks popped per round trip (default: 100)
+	BatchSize int64
+
+	// OnError is called with errors from Run's poll loop and handler (optional)
+	OnError func(error)
+}
+
+// DefaultConfig returns a Config with default values for the schedule with the given name
+func DefaultConfig(name string) Config {
+	key := DefaultKeyPrefix + name
+	return Config{
+		Key:          key,
+		PayloadKey:   key + DefaultPayloadSuffix,
+		Codec:        codec.Default(),
+		PollInterval: time.Second,
+		BatchSize:    100,
+	}
+}
+
+// WithCodec sets the payload codec
+func (c Config) WithCodec(cd codec.Codec) Config {
+	c.Codec = cd
+	return c
+}
+
+// WithPollInterval sets how often due tasks are polled
+func (c Config) WithPollInterval(interval time.Duration) Config {
+	c.PollInterval = interval
+	return c
+}
+
+// WithBatchSize sets the maximum number of tasks popped per round trip
+func (c Config) WithBatchSize(n int64) Config {
+	c.BatchSize = n
+	return c
+}
+
+// WithOnError sets the callback for poll loop and handler errors
+func (c Config) WithOnError(fn func(error)) Config {
+	c.OnError = fn
+	return c
+}
@@ -0,0 +1,48 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig("reminders")
+
+	if cfg.Key != "schedule:reminders" {
+		t.Errorf("DefaultConfig().Key = %q, want %q", cfg.Key, "schedule:reminders")
+	}
+	if cfg.PayloadKey != "schedule:reminders:payloads" {
+		t.Errorf("DefaultConfig().PayloadKey = %q, want %q", cfg.PayloadKey, "schedule:reminders:payloads")
+	}
+	if cfg.Codec == nil || cfg.Codec.Name() != codec.NameJSON {
+		t.Errorf("DefaultConfig().Codec = %v, want json", cfg.Codec)
+	}
+	if cfg.PollInterval != time.Second {
+		t.Errorf("DefaultConfig().PollInterval = %v, want %v", cfg.PollInterval, time.Second)
+	}
+	if cfg.BatchSize != 100 {
+		t.Errorf("DefaultConfig().BatchSize = %d, want 100", cfg.BatchSize)
+	}
+}
+
+func TestConfig_With(t *testing.T) {
+	var called bool
+	cfg := DefaultConfig("s").
+		WithCodec(codec.Gob).
+		WithPollInterval(time.Millisecond).
+		WithBatchSize(7).
+		WithOnError(func(error) { called = true })
+
+	if cfg.Codec.Name() != codec.NameGob {
+		t.Errorf("WithCodec() = %q, want gob", cfg.Codec.Name())
+	}
+	if cfg.PollInterval != time.Millisecond || cfg.BatchSize != 7 {
+		t.Errorf("WithPollInterval/WithBatchSize = %v/%d, want 1ms/7", cfg.PollInterval, cfg.BatchSize)
+	}
+	cfg.OnError(nil)
+	if !called {
+		t.Error("WithOnError() callback not set")
+	}
+}
@@ -0,0 +1,8 @@
+package schedule
+
+import "errors"
+
+var (
+	// ErrHandlerPanic indicates the task handler panicked while processing a task.
+	ErrHandlerPanic = errors.New("task handler panicked")
+)
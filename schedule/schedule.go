@@ -0,0 +1,280 @@
+// Package schedule provides delayed task scheduling on a Redis sorted set
+package schedule
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/queue"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+const popDueScript = `
+-- redis-kit:schedule-pop
+local due = redis.call("zrangebyscore", KEYS[1], "-inf", ARGV[1], "withscores", "limit", 0, ARGV[2])
+local out = {}
+for i = 1, #due, 2 do
+	local id = due[i]
+	redis.call("zrem", KEYS[1], id)
+	local payload = redis.call("hget", KEYS[2], id)
+	if payload then
+		redis.call("hdel", KEYS[2], id)
+		table.insert(out, id)
+		table.insert(out, payload)
+		table.insert(out, due[i + 1])
+	end
+end
+return out
+`
+
+// Handler processes a due task
+// The task has already been removed from the schedule, so returning an error only reports it
+type Handler func(ctx context.Context, task *Task) error
+
+// Task is a scheduled unit of work
+type Task struct {
+	// ID identifies the task within the schedule
+	ID string
+
+	// Payload is the encoded task payload
+	Payload []byte
+
+	// DueAt is the time the task was scheduled for
+	DueAt time.Time
+
+	codec codec.Codec
+}
+
+// Decode unmarshals the task payload into v using the scheduler's codec
+func (t *Task) Decode(v interface{}) error {
+	if err := t.codec.Unmarshal(t.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode task payload: %w", err)
+	}
+	return nil
+}
+
+// Scheduler stores tasks until they are due and hands them to a handler
+type Scheduler struct {
+	client *redis.Client
+	cfg    Config
+}
+
+// NewScheduler creates a new scheduler; zero config values fall back to sane minimums
+// Key has no default, and operations on a scheduler without one return an error
+func NewScheduler(client *redis.Client, cfg Config) *Scheduler {
+	if cfg.PayloadKey == "" && cfg.Key != "" {
+		cfg.PayloadKey = cfg.Key + DefaultPayloadSuffix
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = codec.Default()
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 1
+	}
+	return &Scheduler{client: client, cfg: cfg}
+}
+
+// Config returns the scheduler configuration
+func (s *Scheduler) Config() Config {
+	return s.cfg
+}
+
+// generateTaskID generates a unique task ID
+func generateTaskID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// EnqueueAt schedules payload to run at the given time and returns the new task ID
+func (s *Scheduler) EnqueueAt(ctx context.Context, at time.Time, payload interface{}) (string, error) {
+	id, err := generateTaskID()
+	if err != nil {
+		return "", err
+	}
+	if err := s.ScheduleAt(ctx, id, at, payload); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// EnqueueIn schedules payload to run after delay and returns the new task ID
+func (s *Scheduler) EnqueueIn(ctx context.Context, delay time.Duration, payload interface{}) (string, error) {
+	return s.EnqueueAt(ctx, time.Now().Add(delay), payload)
+}
+
+// ScheduleAt schedules payload under a caller-chosen ID
+// Scheduling an existing ID replaces its payload and due time
+func (s *Scheduler) ScheduleAt(ctx context.Context, id string, at time.Time, payload interface{}) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+
+	data, err := s.cfg.Codec.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode task payload: %w", err)
+	}
+
+	// The payload is written first so a concurrent pop never sees an ID without it
+	pipe := s.client.Pipeline()
+	pipe.HSet(ctx, s.cfg.PayloadKey, id, data)
+	pipe.ZAdd(ctx, s.cfg.Key, redis.Z{Score: float64(at.UnixMilli()), Member: id})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to schedule task: %w", err)
+	}
+	return nil
+}
+
+// Cancel removes a scheduled task
+// Returns true if the task was still pending, false if it was unknown or already popped
+func (s *Scheduler) Cancel(ctx context.Context, id string) (bool, error) {
+	if err := s.check(); err != nil {
+		return false, err
+	}
+
+	// Removing from the sorted set first means a concurrent pop cannot take the task anymore
+	removed, err := s.client.ZRem(ctx, s.cfg.Key, id).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel task: %w", err)
+	}
+	if err := s.client.HDel(ctx, s.cfg.PayloadKey, id).Err(); err != nil {
+		return false, fmt.Errorf("failed to delete task payload: %w", err)
+	}
+	return removed > 0, nil
+}
+
+// Len returns the number of scheduled tasks
+func (s *Scheduler) Len(ctx context.Context) (int64, error) {
+	if err := s.check(); err != nil {
+		return 0, err
+	}
+
+	n, err := s.client.ZCard(ctx, s.cfg.Key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schedule length: %w", err)
+	}
+	return n, nil
+}
+
+// PopDue atomically removes and returns up to limit tasks due at or before now, earliest first
+func (s *Scheduler) PopDue(ctx context.Context, now time.Time, limit int64) ([]*Task, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	if limit < 1 {
+		limit = s.cfg.BatchSize
+	}
+
+	res, err := s.client.Eval(ctx, popDueScript,
+		[]string{s.cfg.Key, s.cfg.PayloadKey},
+		now.UnixMilli(), limit,
+	).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pop due tasks: %w", err)
+	}
+
+	tasks := make([]*Task, 0, len(res)/3)
+	for i := 0; i+2 < len(res); i += 3 {
+		due, _ := strconv.ParseFloat(res[i+2], 64)
+		tasks = append(tasks, &Task{
+			ID:      res[i],
+			Payload: []byte(res[i+1]),
+			DueAt:   time.UnixMilli(int64(due)),
+			codec:   s.cfg.Codec,
+		})
+	}
+	return tasks, nil
+}
+
+// Run polls for due tasks every PollInterval and passes them to handler until ctx is cancelled
+// Tasks are removed before the handler runs, so each task is delivered at most once;
+// use PromoteTo to hand tasks to a queue when they need retries. Handlers get a context that
+// is not cancelled with ctx, so tasks already removed are still handled on shutdown rather
+// than lost; Run returns nil once they are
+func (s *Scheduler) Run(ctx context.Context, handler Handler) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	if handler == nil {
+		return fmt.Errorf("task handler is nil")
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.drain(ctx, handler)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain pops and handles due tasks until fewer than a full batch is returned
+func (s *Scheduler) drain(ctx context.Context, handler Handler) {
+	// Popped tasks exist nowhere else, so they are handled even when ctx is cancelled meanwhile
+	handlerCtx := context.WithoutCancel(ctx)
+	for ctx.Err() == nil {
+		tasks, err := s.PopDue(ctx, time.Now(), s.cfg.BatchSize)
+		if err != nil {
+			s.reportError(err)
+			return
+		}
+		for _, task := range tasks {
+			if err := runHandler(handlerCtx, handler, task); err != nil {
+				s.reportError(fmt.Errorf("task %s failed: %w", task.ID, err))
+			}
+		}
+		if int64(len(tasks)) < s.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func runHandler(ctx context.Context, handler Handler, task *Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+		}
+	}()
+	return handler(ctx, task)
+}
+
+func (s *Scheduler) check() error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if s.cfg.Key == "" {
+		return fmt.Errorf("schedule key is required")
+	}
+	return nil
+}
+
+func (s *Scheduler) reportError(err error) {
+	if s.cfg.OnError != nil && err != nil && !errors.Is(err, context.Canceled) {
+		s.cfg.OnError(err)
+	}
+}
+
+// PromoteTo returns a Handler that moves due tasks into a job queue
+// The task payload is passed through as is, so the scheduler and queue must share a codec
+func PromoteTo(q *queue.Queue) Handler {
+	return func(ctx context.Context, task *Task) error {
+		_, err := q.EnqueueRaw(ctx, task.Payload)
+		return err
+	}
+}
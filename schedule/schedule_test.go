@@ -0,0 +1,292 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/queue"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+type reminder struct {
+	Text string `json:"text"`
+}
+
+func TestScheduler_NilClient(t *testing.T) {
+	s := NewScheduler(nil, DefaultConfig("test"))
+	ctx := context.Background()
+
+	if _, err := s.EnqueueIn(ctx, time.Second, "x"); err == nil {
+		t.Error("EnqueueIn() with nil client should return error")
+	}
+	if _, err := s.Cancel(ctx, "id"); err == nil {
+		t.Error("Cancel() with nil client should return error")
+	}
+	if _, err := s.PopDue(ctx, time.Now(), 10); err == nil {
+		t.Error("PopDue() with nil client should return error")
+	}
+	if err := s.Run(ctx, func(context.Context, *Task) error { return nil }); err == nil {
+		t.Error("Run() with nil client should return error")
+	}
+}
+
+func TestScheduler_EmptyKey(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	s := NewScheduler(client, Config{})
+	ctx := context.Background()
+
+	if s.Config().PayloadKey != "" {
+		t.Errorf("NewScheduler() PayloadKey = %q, want none without a Key", s.Config().PayloadKey)
+	}
+	if err := s.ScheduleAt(ctx, "id", time.Now(), "x"); err == nil {
+		t.Error("ScheduleAt() without a key should return error")
+	}
+	if _, err := s.Cancel(ctx, "id"); err == nil {
+		t.Error("Cancel() without a key should return error")
+	}
+	if _, err := s.Len(ctx); err == nil {
+		t.Error("Len() without a key should return error")
+	}
+	if _, err := s.PopDue(ctx, time.Now(), 10); err == nil {
+		t.Error("PopDue() without a key should return error")
+	}
+	if err := s.Run(ctx, func(context.Context, *Task) error { return nil }); err == nil {
+		t.Error("Run() without a key should return error")
+	}
+	if n, err := client.Exists(ctx, "", DefaultPayloadSuffix).Result(); err != nil || n != 0 {
+		t.Errorf("Exists() on the shared keys = %d, %v, want nothing written", n, err)
+	}
+}
+
+func TestNewScheduler_Normalizes(t *testing.T) {
+	s := NewScheduler(nil, Config{Key: "k"})
+	cfg := s.Config()
+	if cfg.PayloadKey != "k:payloads" || cfg.Codec == nil || cfg.PollInterval != time.Second || cfg.BatchSize != 1 {
+		t.Errorf("NewScheduler() config = %+v, want defaults filled in", cfg)
+	}
+}
+
+func TestScheduler_PopDue(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewScheduler(client, DefaultConfig("test"))
+	now := time.Now()
+
+	later, err := s.EnqueueAt(ctx, now.Add(time.Hour), reminder{Text: "later"})
+	if err != nil {
+		t.Fatalf("EnqueueAt() error = %v, want nil", err)
+	}
+	second, _ := s.EnqueueAt(ctx, now.Add(-time.Second), reminder{Text: "second"})
+	first, _ := s.EnqueueAt(ctx, now.Add(-time.Minute), reminder{Text: "first"})
+
+	if n, _ := s.Len(ctx); n != 3 {
+		t.Errorf("Len() = %d, want 3", n)
+	}
+
+	tasks, err := s.PopDue(ctx, now, 10)
+	if err != nil || len(tasks) != 2 {
+		t.Fatalf("PopDue() = %v, %v, want two tasks", tasks, err)
+	}
+	if tasks[0].ID != first || tasks[1].ID != second {
+		t.Errorf("PopDue() order = %s, %s, want %s, %s", tasks[0].ID, tasks[1].ID, first, second)
+	}
+	if tasks[0].DueAt.UnixMilli() != now.Add(-time.Minute).UnixMilli() {
+		t.Errorf("PopDue() DueAt = %v, want %v", tasks[0].DueAt, now.Add(-time.Minute))
+	}
+	var r reminder
+	if err := tasks[0].Decode(&r); err != nil || r.Text != "first" {
+		t.Errorf("Decode() = %+v, %v, want first", r, err)
+	}
+
+	if tasks, _ := s.PopDue(ctx, now, 10); len(tasks) != 0 {
+		t.Errorf("PopDue() again = %v, want none", tasks)
+	}
+	if n, _ := s.Len(ctx); n != 1 {
+		t.Errorf("Len() after pop = %d, want 1", n)
+	}
+
+	ok, err := s.Cancel(ctx, later)
+	if err != nil || !ok {
+		t.Errorf("Cancel() = %v, %v, want true", ok, err)
+	}
+	if ok, _ := s.Cancel(ctx, later); ok {
+		t.Error("Cancel() twice should return false")
+	}
+	if tasks, _ := s.PopDue(ctx, now.Add(2*time.Hour), 10); len(tasks) != 0 {
+		t.Errorf("PopDue() after Cancel = %v, want none", tasks)
+	}
+}
+
+func TestScheduler_ScheduleAtReplaces(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewScheduler(client, DefaultConfig("test"))
+	now := time.Now()
+
+	_ = s.ScheduleAt(ctx, "user:1", now.Add(-time.Second), reminder{Text: "old"})
+	if err := s.ScheduleAt(ctx, "user:1", now.Add(time.Hour), reminder{Text: "new"}); err != nil {
+		t.Fatalf("ScheduleAt() error = %v, want nil", err)
+	}
+	if tasks, _ := s.PopDue(ctx, now, 10); len(tasks) != 0 {
+		t.Errorf("PopDue() = %v, want rescheduled task to be pending", tasks)
+	}
+	tasks, _ := s.PopDue(ctx, now.Add(2*time.Hour), 10)
+	var r reminder
+	if len(tasks) != 1 || tasks[0].Decode(&r) != nil || r.Text != "new" {
+		t.Errorf("PopDue() = %v, want the replaced payload", tasks)
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var errs []error
+	cfg := DefaultConfig("test").
+		WithPollInterval(5 * time.Millisecond).
+		WithBatchSize(2).
+		WithOnError(func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		})
+	s := NewScheduler(client, cfg)
+
+	for i := 0; i < 5; i++ {
+		_, _ = s.EnqueueIn(ctx, -time.Second, reminder{Text: "due"})
+	}
+	_, _ = s.EnqueueIn(ctx, 20*time.Millisecond, reminder{Text: "soon"})
+	_, _ = s.EnqueueIn(ctx, 0, reminder{Text: "fail"})
+	_, _ = s.EnqueueIn(ctx, time.Hour, reminder{Text: "later"})
+
+	handled := make(chan string, 10)
+	result := make(chan error, 1)
+	go func() {
+		result <- s.Run(ctx, func(_ context.Context, task *Task) error {
+			var r reminder
+			if err := task.Decode(&r); err != nil {
+				return err
+			}
+			if r.Text == "fail" {
+				return errors.New("boom")
+			}
+			handled <- r.Text
+			return nil
+		})
+	}()
+
+	got := map[string]int{}
+	timeout := time.After(2 * time.Second)
+	for got["due"] < 5 || got["soon"] < 1 {
+		select {
+		case text := <-handled:
+			got[text]++
+		case <-timeout:
+			t.Fatalf("Run() handled %v before timeout", got)
+		}
+	}
+	cancel()
+	if err := <-result; err != nil {
+		t.Errorf("Run() error = %v, want nil", err)
+	}
+	if got["later"] != 0 {
+		t.Error("Run() handled a task before it was due")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 {
+		t.Errorf("OnError() called with %v, want the failed task only", errs)
+	}
+}
+
+func TestScheduler_RunShutdown(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewScheduler(client, DefaultConfig("test").WithBatchSize(10))
+	for i := 0; i < 3; i++ {
+		_, _ = s.EnqueueIn(ctx, -time.Second, reminder{Text: "due"})
+	}
+
+	// Shutting down while a batch is handled does not cancel the tasks already popped
+	var handled []error
+	err := s.Run(ctx, func(ctx context.Context, task *Task) error {
+		cancel()
+		handled = append(handled, ctx.Err())
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Run() error = %v, want nil", err)
+	}
+	if len(handled) != 3 || slices.ContainsFunc(handled, func(err error) bool { return err != nil }) {
+		t.Errorf("handler contexts = %v, want 3 tasks handled uncancelled", handled)
+	}
+	if n, _ := s.Len(context.Background()); n != 0 {
+		t.Errorf("Len() after shutdown = %d, want 0", n)
+	}
+}
+
+func TestPromoteTo(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewScheduler(client, DefaultConfig("test"))
+	q := queue.NewQueue(client, queue.DefaultConfig("test").WithBlockTimeout(0))
+	if err := q.EnsureGroup(ctx); err != nil {
+		t.Fatalf("EnsureGroup() error = %v, want nil", err)
+	}
+
+	_, _ = s.EnqueueIn(ctx, -time.Second, reminder{Text: "promoted"})
+	tasks, _ := s.PopDue(ctx, time.Now(), 10)
+	if len(tasks) != 1 {
+		t.Fatalf("PopDue() returned %d tasks, want 1", len(tasks))
+	}
+	if err := PromoteTo(q)(ctx, tasks[0]); err != nil {
+		t.Fatalf("PromoteTo() error = %v, want nil", err)
+	}
+
+	jobs, err := q.Read(ctx)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("Read() = %v, %v, want one job", jobs, err)
+	}
+	var r reminder
+	if err := jobs[0].Decode(&r); err != nil || r.Text != "promoted" {
+		t.Errorf("Decode() = %+v, %v, want promoted", r, err)
+	}
+}
+
+func TestScheduler_RedisError(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	mock.SetShouldFail(true)
+	s := NewScheduler(client, DefaultConfig("test"))
+	ctx := context.Background()
+
+	if _, err := s.EnqueueIn(ctx, time.Second, "x"); err == nil {
+		t.Error("EnqueueIn() should return error when Redis fails")
+	}
+	if _, err := s.PopDue(ctx, time.Now(), 10); err == nil {
+		t.Error("PopDue() should return error when Redis fails")
+	}
+	if _, err := s.Len(ctx); err == nil {
+		t.Error("Len() should return error when Redis fails")
+	}
+}
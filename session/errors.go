@@ -0,0 +1,12 @@
+package session
+
+import "errors"
+
+var (
+	// ErrSessionNotFound indicates the session does not exist or has expired.
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrInvalidSessionID indicates the session ID is not in the format generated by the store.
+	ErrInvalidSessionID = errors.New("invalid session ID")
+	// ErrSessionExists indicates a new session ID collided with an existing session.
+	ErrSessionExists = errors.New("session already exists")
+)
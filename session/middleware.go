@@ -0,0 +1,201 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// DefaultCookieName is the default name of the session cookie
+const DefaultCookieName = "session_id"
+
+type contextKey struct{}
+
+// MiddlewareConfig represents the session cookie and error handling of Middleware
+type MiddlewareConfig struct {
+	// CookieName is the name of the session cookie (default: "session_id")
+	CookieName string
+
+	// CookiePath is the cookie path (default: "/")
+	CookiePath string
+
+	// CookieDomain is the cookie domain (default: empty, i.e. the request host)
+	CookieDomain string
+
+	// Secure restricts the cookie to HTTPS (default: true)
+	Secure bool
+
+	// HTTPOnly hides the cookie from JavaScript (default: true)
+	HTTPOnly bool
+
+	// SameSite is the cookie SameSite mode (default: http.SameSiteLaxMode)
+	SameSite http.SameSite
+
+	// ErrorHandler responds when the session cannot be loaded (default: 500 Internal Server Error)
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+	// OnSaveError is called when the session cannot be saved after the handler ran (optional)
+	OnSaveError func(r *http.Request, err error)
+}
+
+// DefaultMiddlewareConfig returns a MiddlewareConfig with default values
+func DefaultMiddlewareConfig() MiddlewareConfig {
+	return MiddlewareConfig{
+		CookieName: DefaultCookieName,
+		CookiePath: "/",
+		Secure:     true,
+		HTTPOnly:   true,
+		SameSite:   http.SameSiteLaxMode,
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, _ error) {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		},
+	}
+}
+
+// WithCookieName sets the session cookie name
+func (c MiddlewareConfig) WithCookieName(name string) MiddlewareConfig {
+	c.CookieName = name
+	return c
+}
+
+// WithCookieDomain sets the session cookie domain
+func (c MiddlewareConfig) WithCookieDomain(domain string) MiddlewareConfig {
+	c.CookieDomain = domain
+	return c
+}
+
+// WithSecure sets whether the cookie is restricted to HTTPS
+func (c MiddlewareConfig) WithSecure(secure bool) MiddlewareConfig {
+	c.Secure = secure
+	return c
+}
+
+// WithErrorHandler sets the handler used when a session cannot be loaded
+func (c MiddlewareConfig) WithErrorHandler(fn func(w http.ResponseWriter, r *http.Request, err error)) MiddlewareConfig {
+	c.ErrorHandler = fn
+	return c
+}
+
+// WithOnSaveError sets the callback for session save failures
+func (c MiddlewareConfig) WithOnSaveError(fn func(r *http.Request, err error)) MiddlewareConfig {
+	c.OnSaveError = fn
+	return c
+}
+
+// FromContext returns the session attached by Middleware, or nil if there is none
+func FromContext(ctx context.Context) *Session {
+	sess, _ := ctx.Value(contextKey{}).(*Session)
+	return sess
+}
+
+// Middleware loads the session named by the request cookie, or starts a new one, and
+// attaches it to the request context (see FromContext)
+// New sessions are only stored once a value is set. Modified sessions are saved, and
+// invalidated ones destroyed, before the response headers are written
+func (s *Store) Middleware(cfg MiddlewareConfig) func(http.Handler) http.Handler {
+	if cfg.CookieName == "" {
+		cfg.CookieName = DefaultCookieName
+	}
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = DefaultMiddlewareConfig().ErrorHandler
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookieID := ""
+			var sess *Session
+			if cookie, err := r.Cookie(cfg.CookieName); err == nil {
+				cookieID = cookie.Value
+				sess, err = s.Get(r.Context(), cookieID)
+				if err != nil && !errors.Is(err, ErrSessionNotFound) && !errors.Is(err, ErrInvalidSessionID) {
+					cfg.ErrorHandler(w, r, err)
+					return
+				}
+			}
+			if sess == nil {
+				var err error
+				if sess, err = s.New(); err != nil {
+					cfg.ErrorHandler(w, r, err)
+					return
+				}
+			}
+
+			sw := &sessionWriter{ResponseWriter: w}
+			sw.commit = func() { s.commit(sw.ResponseWriter, r, cfg, sess, cookieID) }
+
+			next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), contextKey{}, sess)))
+			sw.commitOnce()
+		})
+	}
+}
+
+// commit persists session changes and updates the cookie
+func (s *Store) commit(w http.ResponseWriter, r *http.Request, cfg MiddlewareConfig, sess *Session, cookieID string) {
+	ctx := context.WithoutCancel(r.Context())
+
+	if sess.Invalidated() {
+		if !sess.IsNew() {
+			if err := s.Destroy(ctx, sess.ID); err != nil && cfg.OnSaveError != nil {
+				cfg.OnSaveError(r, err)
+			}
+		}
+		if cookieID != "" {
+			http.SetCookie(w, s.cookie(cfg, "", -1))
+		}
+		return
+	}
+
+	if sess.Modified() {
+		if err := s.Save(ctx, sess); err != nil {
+			if cfg.OnSaveError != nil {
+				cfg.OnSaveError(r, err)
+			}
+			return
+		}
+	}
+
+	// Refresh the cookie when the ID changed or the expiry slides with each request
+	if !sess.IsNew() && (sess.ID != cookieID || s.sliding) {
+		http.SetCookie(w, s.cookie(cfg, sess.ID, int(s.ttl.Seconds())))
+	}
+}
+
+func (s *Store) cookie(cfg MiddlewareConfig, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     cfg.CookieName,
+		Value:    value,
+		Path:     cfg.CookiePath,
+		Domain:   cfg.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   cfg.Secure,
+		HttpOnly: cfg.HTTPOnly,
+		SameSite: cfg.SameSite,
+	}
+}
+
+// sessionWriter commits the session right before the response headers are written
+type sessionWriter struct {
+	http.ResponseWriter
+	once   sync.Once
+	commit func()
+}
+
+func (w *sessionWriter) commitOnce() {
+	w.once.Do(w.commit)
+}
+
+func (w *sessionWriter) WriteHeader(code int) {
+	w.commitOnce()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionWriter) Write(p []byte) (int, error) {
+	w.commitOnce()
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
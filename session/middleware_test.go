@@ -0,0 +1,153 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func sessionCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == DefaultCookieName {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	store := NewStore(client, "sess:", time.Hour)
+	handler := store.Middleware(DefaultMiddlewareConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess := FromContext(r.Context())
+		switch r.URL.Path {
+		case "/login":
+			_ = sess.Set("user", "alice")
+			if err := store.Regenerate(r.Context(), sess); err != nil {
+				t.Errorf("Regenerate() error = %v", err)
+			}
+		case "/whoami":
+			var user string
+			_, _ = sess.Get("user", &user)
+			_, _ = w.Write([]byte(user))
+		case "/logout":
+			sess.Invalidate()
+		}
+	}))
+
+	// Anonymous requests do not create sessions
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/whoami", nil))
+	if c := sessionCookie(rec); c != nil {
+		t.Errorf("anonymous request set cookie %v", c)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+	cookie := sessionCookie(rec)
+	if cookie == nil || !cookie.HttpOnly || !cookie.Secure || cookie.MaxAge != 3600 {
+		t.Fatalf("login cookie = %+v, want secure HttpOnly cookie with MaxAge 3600", cookie)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Body.String() != "alice" {
+		t.Errorf("whoami = %q, want alice", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/logout", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if c := sessionCookie(rec); c == nil || c.MaxAge >= 0 {
+		t.Errorf("logout cookie = %+v, want expired cookie", c)
+	}
+	if _, err := store.Get(context.Background(), cookie.Value); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Get() after logout error = %v, want ErrSessionNotFound", err)
+	}
+
+	// A stale cookie starts a fresh session
+	req = httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "" {
+		t.Errorf("whoami with stale cookie = %d %q, want 200 and no user", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMiddleware_SaveBeforeHeaders(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	store := NewStore(client, "sess:", time.Hour)
+	handler := store.Middleware(DefaultMiddlewareConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = FromContext(r.Context()).Set("k", "v")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("done"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	cookie := sessionCookie(rec)
+	if rec.Code != http.StatusCreated || cookie == nil {
+		t.Fatalf("response = %d, cookie %v, want 201 with cookie", rec.Code, cookie)
+	}
+	if sess, err := store.Get(context.Background(), cookie.Value); err != nil || !sess.Has("k") {
+		t.Errorf("Get() = %v, %v, want saved session", sess, err)
+	}
+}
+
+func TestMiddleware_LoadError(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	store := NewStore(client, "sess:", time.Hour)
+	sess, _ := store.Create(context.Background())
+	mock.SetShouldFail(true)
+
+	called := false
+	cfg := DefaultMiddlewareConfig().WithErrorHandler(func(w http.ResponseWriter, _ *http.Request, _ error) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	handler := store.Middleware(cfg)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: DefaultCookieName, Value: sess.ID})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if called || rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("handler called = %v, code = %d, want error handler response", called, rec.Code)
+	}
+}
+
+func TestMiddlewareConfig_With(t *testing.T) {
+	var saveErr error
+	cfg := DefaultMiddlewareConfig().
+		WithCookieName("sid").
+		WithCookieDomain("example.com").
+		WithSecure(false).
+		WithOnSaveError(func(_ *http.Request, err error) { saveErr = err })
+
+	if cfg.CookieName != "sid" || cfg.CookieDomain != "example.com" || cfg.Secure {
+		t.Errorf("MiddlewareConfig = %+v, want sid/example.com/insecure", cfg)
+	}
+	cfg.OnSaveError(nil, errors.New("x"))
+	if saveErr == nil {
+		t.Error("WithOnSaveError() callback not set")
+	}
+	if FromContext(context.Background()) != nil {
+		t.Error("FromContext() without middleware should return nil")
+	}
+}
@@ -0,0 +1,22 @@
+package session
+
+import "github.com/soulteary/redis-kit/utils/codec"
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithCodec sets the codec used to encode session values (default: codec.Default())
+func WithCodec(c codec.Codec) Option {
+	return func(s *Store) {
+		if c != nil {
+			s.codec = c
+		}
+	}
+}
+
+// WithSlidingExpiration sets whether reading or saving a session extends its TTL (default: true)
+func WithSlidingExpiration(sliding bool) Option {
+	return func(s *Store) {
+		s.sliding = sliding
+	}
+}
@@ -0,0 +1,133 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// Session holds the values of one client session
+// Values are encoded individually with the store's codec, so they keep their Go types
+type Session struct {
+	// ID is the session identifier sent to the client
+	ID string
+
+	// CreatedAt is the time the session was first created
+	CreatedAt time.Time
+
+	mu        sync.RWMutex
+	values    map[string][]byte
+	codec     codec.Codec
+	isNew     bool
+	modified  bool
+	destroyed bool
+}
+
+func newSession(id string, c codec.Codec) *Session {
+	return &Session{
+		ID:        id,
+		CreatedAt: time.Now(),
+		values:    make(map[string][]byte),
+		codec:     c,
+		isNew:     true,
+	}
+}
+
+// Get decodes the value stored under key into v
+// Returns false if the key is not set
+func (s *Session) Get(key string, v interface{}) (bool, error) {
+	s.mu.RLock()
+	data, ok := s.values[key]
+	s.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	if err := s.codec.Unmarshal(data, v); err != nil {
+		return true, fmt.Errorf("failed to decode session value %q: %w", key, err)
+	}
+	return true, nil
+}
+
+// Set encodes v and stores it under key
+func (s *Session) Set(key string, v interface{}) error {
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode session value %q: %w", key, err)
+	}
+	s.mu.Lock()
+	s.values[key] = data
+	s.modified = true
+	s.mu.Unlock()
+	return nil
+}
+
+// Has reports whether key is set
+func (s *Session) Has(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.values[key]
+	return ok
+}
+
+// Delete removes key from the session
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+}
+
+// Clear removes all values from the session
+func (s *Session) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.values) > 0 {
+		s.values = make(map[string][]byte)
+		s.modified = true
+	}
+}
+
+// Keys returns the keys set in the session in sorted order
+func (s *Session) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// IsNew reports whether the session has not been saved yet
+func (s *Session) IsNew() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.isNew
+}
+
+// Modified reports whether the session changed since it was loaded or saved
+func (s *Session) Modified() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.modified
+}
+
+// Invalidate marks the session for destruction by the middleware, e.g. on logout
+func (s *Session) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.destroyed = true
+}
+
+// Invalidated reports whether Invalidate was called
+func (s *Session) Invalidated() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.destroyed
+}
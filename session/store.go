@@ -0,0 +1,263 @@
+// Package session provides Redis-backed HTTP sessions
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for session keys
+	DefaultKeyPrefix = "session:"
+
+	// DefaultTTL is the default session lifetime (24 hours)
+	DefaultTTL = 24 * time.Hour
+
+	// idBytes is the number of random bytes in a session ID (256 bits)
+	idBytes = 32
+)
+
+// idLength is the length of an encoded session ID
+var idLength = base64.RawURLEncoding.EncodedLen(idBytes)
+
+// record is the stored form of a session
+type record struct {
+	Values    map[string][]byte `json:"v"`
+	CreatedAt int64             `json:"c"`
+}
+
+// Store persists sessions in Redis
+type Store struct {
+	client  *redis.Client
+	prefix  string
+	ttl     time.Duration
+	codec   codec.Codec
+	sliding bool
+}
+
+// NewStore creates a new session store
+// A non-positive ttl falls back to DefaultTTL
+func NewStore(client *redis.Client, prefix string, ttl time.Duration, opts ...Option) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	s := &Store{
+		client:  client,
+		prefix:  prefix,
+		ttl:     ttl,
+		codec:   codec.Default(),
+		sliding: true,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// TTL returns the session lifetime
+func (s *Store) TTL() time.Duration {
+	return s.ttl
+}
+
+// buildKey constructs the full key with prefix
+func (s *Store) buildKey(id string) string {
+	return s.prefix + id
+}
+
+// generateID generates a URL-safe session ID with 256 bits of entropy
+func generateID() (string, error) {
+	bytes := make([]byte, idBytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// validID reports whether id looks like an ID produced by generateID
+func validID(id string) bool {
+	if len(id) != idLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// New returns a new, unsaved session with a fresh ID
+func (s *Store) New() (*Session, error) {
+	id, err := generateID()
+	if err != nil {
+		return nil, err
+	}
+	return newSession(id, s.codec), nil
+}
+
+// Create creates and saves a new empty session
+func (s *Store) Create(ctx context.Context) (*Session, error) {
+	sess, err := s.New()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Save(ctx, sess); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// Get loads a session; with sliding expiration its TTL is reset
+// Returns ErrSessionNotFound if the session does not exist or has expired
+func (s *Store) Get(ctx context.Context, id string) (*Session, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if !validID(id) {
+		return nil, ErrInvalidSessionID
+	}
+
+	var data []byte
+	var err error
+	if s.sliding {
+		data, err = s.client.GetEx(ctx, s.buildKey(id), s.ttl).Bytes()
+	} else {
+		data, err = s.client.Get(ctx, s.buildKey(id)).Bytes()
+	}
+	if err == redis.Nil {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	sess := newSession(id, s.codec)
+	sess.isNew = false
+	sess.CreatedAt = time.UnixMilli(rec.CreatedAt)
+	if rec.Values != nil {
+		sess.values = rec.Values
+	}
+	return sess, nil
+}
+
+// Save stores the session
+// New sessions are created with the full TTL; existing ones get a fresh TTL with sliding
+// expiration and keep their remaining TTL otherwise. Saving a session that expired or was
+// destroyed meanwhile returns ErrSessionNotFound
+func (s *Store) Save(ctx context.Context, sess *Session) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	sess.mu.RLock()
+	data, err := json.Marshal(record{Values: sess.values, CreatedAt: sess.CreatedAt.UnixMilli()})
+	isNew := sess.isNew
+	sess.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	args := redis.SetArgs{Mode: "XX", TTL: s.ttl}
+	switch {
+	case isNew:
+		args.Mode = "NX"
+	case !s.sliding:
+		args = redis.SetArgs{Mode: "XX", KeepTTL: true}
+	}
+
+	err = s.client.SetArgs(ctx, s.buildKey(sess.ID), data, args).Err()
+	if err == redis.Nil {
+		if isNew {
+			return ErrSessionExists
+		}
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	sess.mu.Lock()
+	sess.isNew = false
+	sess.modified = false
+	sess.mu.Unlock()
+	return nil
+}
+
+// Touch resets the TTL of a session without loading it
+// Returns ErrSessionNotFound if the session does not exist
+func (s *Store) Touch(ctx context.Context, id string) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if !validID(id) {
+		return ErrInvalidSessionID
+	}
+
+	ok, err := s.client.PExpire(ctx, s.buildKey(id), s.ttl).Result()
+	if err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// Destroy deletes a session
+func (s *Store) Destroy(ctx context.Context, id string) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if !validID(id) {
+		return ErrInvalidSessionID
+	}
+
+	if err := s.client.Del(ctx, s.buildKey(id)).Err(); err != nil {
+		return fmt.Errorf("failed to destroy session: %w", err)
+	}
+	return nil
+}
+
+// Regenerate moves the session to a fresh ID and deletes the old one
+// Call it when privileges change (e.g. on login) to prevent session fixation
+func (s *Store) Regenerate(ctx context.Context, sess *Session) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	id, err := generateID()
+	if err != nil {
+		return err
+	}
+
+	sess.mu.Lock()
+	oldID, wasNew := sess.ID, sess.isNew
+	sess.ID = id
+	sess.isNew = true
+	sess.mu.Unlock()
+
+	if err := s.Save(ctx, sess); err != nil {
+		sess.mu.Lock()
+		sess.ID, sess.isNew = oldID, wasNew
+		sess.mu.Unlock()
+		return err
+	}
+	if !wasNew {
+		if err := s.client.Del(ctx, s.buildKey(oldID)).Err(); err != nil {
+			return fmt.Errorf("failed to delete old session: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+type profile struct {
+	Name  string `json:"name"`
+	Admin bool   `json:"admin"`
+}
+
+func TestNewStore(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	s := NewStore(client, DefaultKeyPrefix, 0)
+	if s.TTL() != DefaultTTL {
+		t.Errorf("NewStore() TTL = %v, want %v", s.TTL(), DefaultTTL)
+	}
+	if !s.sliding || s.codec.Name() != codec.NameJSON {
+		t.Errorf("NewStore() sliding = %v, codec = %q, want true, json", s.sliding, s.codec.Name())
+	}
+
+	s = NewStore(client, "", time.Minute, WithSlidingExpiration(false), WithCodec(codec.Gob))
+	if s.sliding || s.codec.Name() != codec.NameGob {
+		t.Errorf("NewStore() with options sliding = %v, codec = %q, want false, gob", s.sliding, s.codec.Name())
+	}
+}
+
+func TestGenerateID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id, err := generateID()
+		if err != nil {
+			t.Fatalf("generateID() error = %v, want nil", err)
+		}
+		if !validID(id) {
+			t.Errorf("generateID() = %q, not a valid ID", id)
+		}
+		if seen[id] {
+			t.Fatalf("generateID() returned duplicate %q", id)
+		}
+		seen[id] = true
+	}
+
+	for _, id := range []string{"", "short", "../../etc/passwd", string(make([]byte, idLength))} {
+		if validID(id) {
+			t.Errorf("validID(%q) = true, want false", id)
+		}
+	}
+}
+
+func TestStore_Lifecycle(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client, "sess:", time.Hour)
+
+	sess, err := s.Create(ctx)
+	if err != nil {
+		t.Fatalf("Create() error = %v, want nil", err)
+	}
+	if sess.IsNew() {
+		t.Error("Create() session should not be new")
+	}
+
+	if err := sess.Set("user", profile{Name: "alice", Admin: true}); err != nil {
+		t.Fatalf("Set() error = %v, want nil", err)
+	}
+	_ = sess.Set("count", 3)
+	if !sess.Modified() {
+		t.Error("Modified() = false after Set")
+	}
+	if err := s.Save(ctx, sess); err != nil {
+		t.Fatalf("Save() error = %v, want nil", err)
+	}
+	if sess.Modified() {
+		t.Error("Modified() = true after Save")
+	}
+
+	loaded, err := s.Get(ctx, sess.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v, want nil", err)
+	}
+	var p profile
+	if ok, err := loaded.Get("user", &p); !ok || err != nil || p.Name != "alice" || !p.Admin {
+		t.Errorf("Session.Get() = %+v, %v, %v, want alice admin", p, ok, err)
+	}
+	var count int
+	if ok, _ := loaded.Get("count", &count); !ok || count != 3 {
+		t.Errorf("Session.Get(count) = %d, want 3", count)
+	}
+	if ok, _ := loaded.Get("missing", &count); ok {
+		t.Error("Session.Get(missing) = true, want false")
+	}
+	if keys := loaded.Keys(); len(keys) != 2 || keys[0] != "count" {
+		t.Errorf("Keys() = %v, want [count user]", keys)
+	}
+	if loaded.CreatedAt.UnixMilli() != sess.CreatedAt.UnixMilli() {
+		t.Errorf("CreatedAt = %v, want %v", loaded.CreatedAt, sess.CreatedAt)
+	}
+
+	loaded.Delete("count")
+	if loaded.Has("count") {
+		t.Error("Has() after Delete = true, want false")
+	}
+
+	if err := s.Touch(ctx, sess.ID); err != nil {
+		t.Errorf("Touch() error = %v, want nil", err)
+	}
+	if err := s.Destroy(ctx, sess.ID); err != nil {
+		t.Fatalf("Destroy() error = %v, want nil", err)
+	}
+	if _, err := s.Get(ctx, sess.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Get() after Destroy error = %v, want ErrSessionNotFound", err)
+	}
+	if err := s.Save(ctx, loaded); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Save() after Destroy error = %v, want ErrSessionNotFound", err)
+	}
+	if err := s.Touch(ctx, sess.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Touch() after Destroy error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestStore_InvalidID(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	s := NewStore(client, "sess:", time.Hour)
+	ctx := context.Background()
+	if _, err := s.Get(ctx, "*"); !errors.Is(err, ErrInvalidSessionID) {
+		t.Errorf("Get() error = %v, want ErrInvalidSessionID", err)
+	}
+	if err := s.Destroy(ctx, "*"); !errors.Is(err, ErrInvalidSessionID) {
+		t.Errorf("Destroy() error = %v, want ErrInvalidSessionID", err)
+	}
+}
+
+func TestStore_SlidingExpiration(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+
+	t.Run("sliding", func(t *testing.T) {
+		s := NewStore(client, "sliding:", 80*time.Millisecond)
+		sess, _ := s.Create(ctx)
+		for i := 0; i < 4; i++ {
+			time.Sleep(40 * time.Millisecond)
+			if _, err := s.Get(ctx, sess.ID); err != nil {
+				t.Fatalf("Get() after %d reads error = %v, want nil", i, err)
+			}
+		}
+	})
+
+	t.Run("fixed", func(t *testing.T) {
+		s := NewStore(client, "fixed:", 80*time.Millisecond, WithSlidingExpiration(false))
+		sess, _ := s.Create(ctx)
+		time.Sleep(40 * time.Millisecond)
+		_ = sess.Set("k", "v")
+		if err := s.Save(ctx, sess); err != nil {
+			t.Fatalf("Save() error = %v, want nil", err)
+		}
+		time.Sleep(60 * time.Millisecond)
+		if _, err := s.Get(ctx, sess.ID); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Get() after fixed TTL error = %v, want ErrSessionNotFound", err)
+		}
+	})
+}
+
+func TestStore_Regenerate(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client, "sess:", time.Hour)
+	sess, _ := s.Create(ctx)
+	_ = sess.Set("k", "v")
+	_ = s.Save(ctx, sess)
+	oldID := sess.ID
+
+	if err := s.Regenerate(ctx, sess); err != nil {
+		t.Fatalf("Regenerate() error = %v, want nil", err)
+	}
+	if sess.ID == oldID {
+		t.Error("Regenerate() kept the old ID")
+	}
+	if _, err := s.Get(ctx, oldID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Get(old) error = %v, want ErrSessionNotFound", err)
+	}
+	loaded, err := s.Get(ctx, sess.ID)
+	if err != nil || !loaded.Has("k") {
+		t.Errorf("Get(new) = %v, %v, want the session values", loaded, err)
+	}
+}
+
+func TestStore_RedisError(t *testing.T) {
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	s := NewStore(client, "sess:", time.Hour)
+	ctx := context.Background()
+	sess, _ := s.Create(ctx)
+
+	mock.SetShouldFail(true)
+	if _, err := s.Create(ctx); err == nil {
+		t.Error("Create() should return error when Redis fails")
+	}
+	if _, err := s.Get(ctx, sess.ID); err == nil || errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Get() error = %v, want a Redis error", err)
+	}
+}
+
+func TestStore_NilClient(t *testing.T) {
+	s := NewStore(nil, "sess:", time.Hour)
+	ctx := context.Background()
+	if _, err := s.Create(ctx); err == nil {
+		t.Error("Create() with nil client should return error")
+	}
+	if _, err := s.Get(ctx, "x"); err == nil {
+		t.Error("Get() with nil client should return error")
+	}
+}
@@ -0,0 +1,148 @@
+package snapshot
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// DefaultBatchSize is the default number of keys read or written per pipeline
+const DefaultBatchSize = 100
+
+// Format is the layout of a snapshot file
+type Format string
+
+const (
+	// FormatNDJSON writes one record per line, so a snapshot can be streamed and grepped
+	FormatNDJSON Format = "ndjson"
+
+	// FormatJSON writes a single JSON array of records
+	FormatJSON Format = "json"
+)
+
+// ConflictPolicy decides what Import does with a key that already exists
+type ConflictPolicy int
+
+const (
+	// ConflictSkip leaves the existing key untouched
+	ConflictSkip ConflictPolicy = iota
+
+	// ConflictOverwrite deletes the existing key and restores the record
+	ConflictOverwrite
+
+	// ConflictFail stops the import with ErrConflict
+	ConflictFail
+)
+
+// ExportConfig represents which keys Export dumps and how
+type ExportConfig struct {
+	// Prefix selects the exported keys; empty exports the whole keyspace
+	Prefix string
+
+	// Format is the layout of the output (default: FormatNDJSON)
+	Format Format
+
+	// ScanCount is the COUNT hint passed to SCAN (default: 100)
+	ScanCount int64
+
+	// BatchSize is the number of keys read per pipeline (default: 100)
+	BatchSize int
+
+	// Interval is the pause between batches, limiting the load put on the server (default: 0)
+	Interval time.Duration
+}
+
+// DefaultExportConfig returns an ExportConfig with default values that exports the keys under prefix
+func DefaultExportConfig(prefix string) ExportConfig {
+	return ExportConfig{
+		Prefix:    prefix,
+		Format:    FormatNDJSON,
+		ScanCount: utils.DefaultScanCount,
+		BatchSize: DefaultBatchSize,
+	}
+}
+
+// WithFormat sets the output format
+func (c ExportConfig) WithFormat(format Format) ExportConfig {
+	c.Format = format
+	return c
+}
+
+// WithBatchSize sets the number of keys read per pipeline
+func (c ExportConfig) WithBatchSize(size int) ExportConfig {
+	c.BatchSize = size
+	return c
+}
+
+// WithInterval sets the pause between batches
+func (c ExportConfig) WithInterval(interval time.Duration) ExportConfig {
+	c.Interval = interval
+	return c
+}
+
+func (c ExportConfig) withDefaults() ExportConfig {
+	if c.Format == "" {
+		c.Format = FormatNDJSON
+	}
+	if c.ScanCount <= 0 {
+		c.ScanCount = utils.DefaultScanCount
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	return c
+}
+
+// ImportConfig represents how Import restores records
+type ImportConfig struct {
+	// Conflict decides what happens to keys that already exist (default: ConflictSkip)
+	Conflict ConflictPolicy
+
+	// BatchSize is the number of records written per pipeline (default: 100)
+	BatchSize int
+
+	// Interval is the pause between batches, limiting the load put on the server (default: 0)
+	Interval time.Duration
+
+	// IgnoreTTL restores every key without an expiration
+	IgnoreTTL bool
+}
+
+// DefaultImportConfig returns an ImportConfig with default values
+func DefaultImportConfig() ImportConfig {
+	return ImportConfig{
+		Conflict:  ConflictSkip,
+		BatchSize: DefaultBatchSize,
+	}
+}
+
+// WithConflict sets the conflict policy
+func (c ImportConfig) WithConflict(policy ConflictPolicy) ImportConfig {
+	c.Conflict = policy
+	return c
+}
+
+// WithBatchSize sets the number of records written per pipeline
+func (c ImportConfig) WithBatchSize(size int) ImportConfig {
+	c.BatchSize = size
+	return c
+}
+
+// WithInterval sets the pause between batches
+func (c ImportConfig) WithInterval(interval time.Duration) ImportConfig {
+	c.Interval = interval
+	return c
+}
+
+// WithIgnoreTTL enables or disables restoring keys without an expiration
+func (c ImportConfig) WithIgnoreTTL(ignore bool) ImportConfig {
+	c.IgnoreTTL = ignore
+	return c
+}
+
+func (c ImportConfig) withDefaults() ImportConfig {
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	return c
+}
@@ -0,0 +1,39 @@
+package snapshot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefaultExportConfig(t *testing.T) {
+	cfg := DefaultExportConfig("app:")
+	if cfg.Prefix != "app:" || cfg.Format != FormatNDJSON || cfg.ScanCount != 100 || cfg.BatchSize != DefaultBatchSize {
+		t.Errorf("DefaultExportConfig() = %+v, want ndjson, scan count 100, batches of %d", cfg, DefaultBatchSize)
+	}
+
+	cfg = cfg.WithFormat(FormatJSON).WithBatchSize(10).WithInterval(time.Second)
+	if cfg.Format != FormatJSON || cfg.BatchSize != 10 || cfg.Interval != time.Second {
+		t.Errorf("With*() = %+v", cfg)
+	}
+
+	cfg = ExportConfig{}.withDefaults()
+	if cfg.Format != FormatNDJSON || cfg.ScanCount != 100 || cfg.BatchSize != DefaultBatchSize {
+		t.Errorf("withDefaults() = %+v, want the defaults", cfg)
+	}
+}
+
+func TestDefaultImportConfig(t *testing.T) {
+	cfg := DefaultImportConfig()
+	if cfg.Conflict != ConflictSkip || cfg.BatchSize != DefaultBatchSize || cfg.IgnoreTTL {
+		t.Errorf("DefaultImportConfig() = %+v, want skip conflicts, batches of %d", cfg, DefaultBatchSize)
+	}
+
+	cfg = cfg.WithConflict(ConflictFail).WithBatchSize(5).WithInterval(time.Second).WithIgnoreTTL(true)
+	if cfg.Conflict != ConflictFail || cfg.BatchSize != 5 || cfg.Interval != time.Second || !cfg.IgnoreTTL {
+		t.Errorf("With*() = %+v", cfg)
+	}
+
+	if cfg = (ImportConfig{BatchSize: -1}).withDefaults(); cfg.BatchSize != DefaultBatchSize {
+		t.Errorf("withDefaults().BatchSize = %d, want %d", cfg.BatchSize, DefaultBatchSize)
+	}
+}
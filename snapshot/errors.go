@@ -0,0 +1,11 @@
+package snapshot
+
+import "errors"
+
+var (
+	// ErrConflict indicates an imported key that already exists under ConflictFail.
+	ErrConflict = errors.New("key already exists")
+
+	// ErrInvalidRecord indicates a record that cannot be restored.
+	ErrInvalidRecord = errors.New("invalid snapshot record")
+)
@@ -0,0 +1,248 @@
+// Package snapshot dumps the keys under a prefix to a JSON file and restores them
+// A snapshot holds the values, types, and remaining TTLs of strings, hashes, lists, sets,
+// sorted sets, and streams, which is enough to seed a staging environment or reproduce
+// production state locally. Keys are read one batch at a time while the server keeps
+// serving writes, so a snapshot is not a point-in-time copy; stream consumer groups and
+// other module types are not included
+package snapshot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// Stats reports the outcome of an export or an import
+type Stats struct {
+	// Keys is the number of keys exported or restored
+	Keys int64 `json:"keys"`
+
+	// Skipped is the number of keys left out: on export, keys of unsupported types or
+	// deleted while being read; on import, existing keys under ConflictSkip
+	Skipped int64 `json:"skipped"`
+
+	// Overwritten is the number of existing keys replaced under ConflictOverwrite
+	Overwritten int64 `json:"overwritten"`
+}
+
+// Export writes every key under cfg.Prefix to w; zero config values fall back to defaults
+// Keys are found with SCAN and read with pipelined TYPE, PTTL, and value commands, batches
+// paced by cfg.Interval. Whole values are read at once, so very large collections are
+// held in memory while being written. The partial stats are returned on error
+func Export(ctx context.Context, client redis.Cmdable, w io.Writer, cfg ExportConfig) (Stats, error) {
+	var stats Stats
+	if client == nil {
+		return stats, fmt.Errorf("redis client is nil")
+	}
+	cfg = cfg.withDefaults()
+	if cfg.Format != FormatNDJSON && cfg.Format != FormatJSON {
+		return stats, fmt.Errorf("unsupported snapshot format %q", cfg.Format)
+	}
+
+	out := newWriter(w, cfg.Format)
+	seen := make(map[string]struct{})
+	batch := make([]string, 0, cfg.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		records, err := readBatch(ctx, client, batch)
+		if err != nil {
+			return err
+		}
+		stats.Skipped += int64(len(batch) - len(records))
+		for _, rec := range records {
+			if err := out.write(rec); err != nil {
+				return err
+			}
+			stats.Keys++
+		}
+		batch = batch[:0]
+		return sleepContext(ctx, cfg.Interval)
+	}
+
+	for key, err := range utils.ScanKeys(ctx, client, utils.EscapeGlob(cfg.Prefix)+"*", cfg.ScanCount) {
+		if err != nil {
+			return stats, err
+		}
+		// SCAN may return a key more than once
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		batch = append(batch, key)
+		if len(batch) >= cfg.BatchSize {
+			if err := flush(); err != nil {
+				return stats, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+	return stats, out.close()
+}
+
+// readBatch reads the records of keys, leaving out the ones of unsupported types and the
+// ones deleted in the meantime
+func readBatch(ctx context.Context, client redis.Cmdable, keys []string) ([]*Record, error) {
+	ttls, err := utils.TTLs(ctx, client, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key TTLs: %w", err)
+	}
+	pipe := client.Pipeline()
+	types := make([]*redis.StatusCmd, len(keys))
+	for i, key := range keys {
+		types[i] = pipe.Type(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read key types: %w", err)
+	}
+
+	pipe = client.Pipeline()
+	records := make([]*Record, 0, len(keys))
+	reads := make([]redis.Cmder, 0, len(keys))
+	for i, key := range keys {
+		rec := &Record{Key: key, Type: types[i].Val()}
+		switch rec.Type {
+		case TypeString:
+			reads = append(reads, pipe.Get(ctx, key))
+		case TypeHash:
+			reads = append(reads, pipe.HGetAll(ctx, key))
+		case TypeList:
+			reads = append(reads, pipe.LRange(ctx, key, 0, -1))
+		case TypeSet:
+			reads = append(reads, pipe.SMembers(ctx, key))
+		case TypeZSet:
+			reads = append(reads, pipe.ZRangeWithScores(ctx, key, 0, -1))
+		case TypeStream:
+			reads = append(reads, pipe.XRange(ctx, key, "-", "+"))
+		default:
+			// "none" for keys deleted since SCAN, or a module type
+			continue
+		}
+		if ttl := ttls[key]; ttl > 0 {
+			rec.TTL = max(ttl.Milliseconds(), 1)
+		}
+		records = append(records, rec)
+	}
+	if len(reads) == 0 {
+		return nil, nil
+	}
+	_, _ = pipe.Exec(ctx)
+
+	kept := records[:0]
+	for i, rec := range records {
+		// A key deleted or replaced by another type since TYPE is left out
+		if err := reads[i].Err(); err != nil {
+			if errors.Is(err, redis.Nil) || redis.HasErrorPrefix(err, "WRONGTYPE") {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read values: %w", err)
+		}
+		var found bool
+		switch cmd := reads[i].(type) {
+		case *redis.StringCmd:
+			rec.String, found = cmd.Val(), true
+		case *redis.MapStringStringCmd:
+			rec.Hash = cmd.Val()
+			found = len(rec.Hash) > 0
+		case *redis.StringSliceCmd:
+			if rec.Type == TypeList {
+				rec.List = cmd.Val()
+				found = len(rec.List) > 0
+			} else {
+				rec.Set = cmd.Val()
+				found = len(rec.Set) > 0
+			}
+		case *redis.ZSliceCmd:
+			for _, z := range cmd.Val() {
+				rec.ZSet = append(rec.ZSet, ZMember{Member: fmt.Sprint(z.Member), Score: z.Score})
+			}
+			found = len(rec.ZSet) > 0
+		case *redis.XMessageSliceCmd:
+			for _, msg := range cmd.Val() {
+				entry := StreamEntry{ID: msg.ID, Fields: make(map[string]string, len(msg.Values))}
+				for field, value := range msg.Values {
+					entry.Fields[field] = fmt.Sprint(value)
+				}
+				rec.Stream = append(rec.Stream, entry)
+			}
+			// An empty stream cannot be recreated without its consumer groups
+			found = len(rec.Stream) > 0
+		}
+		if found {
+			rec.encode()
+			kept = append(kept, rec)
+		}
+	}
+	return kept, nil
+}
+
+// writer encodes records in one of the snapshot formats
+type writer struct {
+	buf    *bufio.Writer
+	format Format
+	count  int
+}
+
+func newWriter(w io.Writer, format Format) *writer {
+	return &writer{buf: bufio.NewWriter(w), format: format}
+}
+
+func (w *writer) write(rec *Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode record %s: %w", rec.Key, err)
+	}
+	switch {
+	case w.format == FormatNDJSON:
+		data = append(data, '\n')
+	case w.count == 0:
+		_, _ = w.buf.WriteString("[\n")
+	default:
+		_, _ = w.buf.WriteString(",\n")
+	}
+	w.count++
+	if _, err := w.buf.Write(data); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// close terminates the JSON array and flushes the buffered output
+func (w *writer) close() error {
+	if w.format == FormatJSON {
+		end := "\n]\n"
+		if w.count == 0 {
+			end = "[]\n"
+		}
+		_, _ = w.buf.WriteString(end)
+	}
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,196 @@
+package snapshot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Import restores the records read from r; zero config values fall back to defaults
+// Both formats are accepted and told apart by the first character. Records are written in
+// batches of pipelined commands paced by cfg.Interval, each preceded by an EXISTS check that
+// applies cfg.Conflict; under ConflictFail, no key of the conflicting batch is written, but
+// earlier batches stay restored. TTLs are restored relative to the time of the import.
+// The partial stats are returned on error
+func Import(ctx context.Context, client redis.Cmdable, r io.Reader, cfg ImportConfig) (Stats, error) {
+	var stats Stats
+	if client == nil {
+		return stats, fmt.Errorf("redis client is nil")
+	}
+	cfg = cfg.withDefaults()
+
+	rd, err := newReader(r)
+	if err != nil {
+		return stats, err
+	}
+
+	batch := make([]*Record, 0, cfg.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := restoreBatch(ctx, client, cfg, batch, &stats); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return sleepContext(ctx, cfg.Interval)
+	}
+
+	for {
+		rec, err := rd.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+		batch = append(batch, rec)
+		if len(batch) >= cfg.BatchSize {
+			if err := flush(); err != nil {
+				return stats, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// restoreBatch writes the records of batch according to the conflict policy
+func restoreBatch(ctx context.Context, client redis.Cmdable, cfg ImportConfig, batch []*Record, stats *Stats) error {
+	pipe := client.Pipeline()
+	exists := make([]*redis.IntCmd, len(batch))
+	for i, rec := range batch {
+		exists[i] = pipe.Exists(ctx, rec.Key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to check existing keys: %w", err)
+	}
+	if cfg.Conflict == ConflictFail {
+		for i, rec := range batch {
+			if exists[i].Val() > 0 {
+				return fmt.Errorf("%w: %s", ErrConflict, rec.Key)
+			}
+		}
+	}
+
+	var restored, skipped, overwritten int64
+	pipe = client.Pipeline()
+	for i, rec := range batch {
+		if exists[i].Val() > 0 {
+			if cfg.Conflict == ConflictSkip {
+				skipped++
+				continue
+			}
+			pipe.Del(ctx, rec.Key)
+			overwritten++
+		}
+		restore(ctx, pipe, rec, cfg.IgnoreTTL)
+		restored++
+	}
+	if restored > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to restore keys: %w", err)
+		}
+	}
+	stats.Keys += restored
+	stats.Skipped += skipped
+	stats.Overwritten += overwritten
+	return nil
+}
+
+// restore queues the commands recreating rec on pipe
+func restore(ctx context.Context, pipe redis.Pipeliner, rec *Record, ignoreTTL bool) {
+	switch rec.Type {
+	case TypeString:
+		pipe.Set(ctx, rec.Key, rec.String, 0)
+	case TypeHash:
+		if len(rec.Hash) > 0 {
+			pipe.HSet(ctx, rec.Key, rec.Hash)
+		}
+	case TypeList:
+		if len(rec.List) > 0 {
+			pipe.RPush(ctx, rec.Key, rec.List)
+		}
+	case TypeSet:
+		if len(rec.Set) > 0 {
+			pipe.SAdd(ctx, rec.Key, rec.Set)
+		}
+	case TypeZSet:
+		if len(rec.ZSet) > 0 {
+			members := make([]redis.Z, len(rec.ZSet))
+			for i, z := range rec.ZSet {
+				members[i] = redis.Z{Score: z.Score, Member: z.Member}
+			}
+			pipe.ZAdd(ctx, rec.Key, members...)
+		}
+	case TypeStream:
+		for _, entry := range rec.Stream {
+			pipe.XAdd(ctx, &redis.XAddArgs{Stream: rec.Key, ID: entry.ID, Values: entry.Fields})
+		}
+	}
+	if rec.TTL > 0 && !ignoreTTL {
+		pipe.PExpire(ctx, rec.Key, time.Duration(rec.TTL)*time.Millisecond)
+	}
+}
+
+// reader decodes records from a snapshot in either format
+type reader struct {
+	dec   *json.Decoder
+	array bool
+}
+
+// newReader detects the format of r from its first non-blank character
+func newReader(r io.Reader) (*reader, error) {
+	buf := bufio.NewReader(r)
+	rd := &reader{dec: json.NewDecoder(buf)}
+	for {
+		b, err := buf.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return rd, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		_ = buf.UnreadByte()
+		if b == '[' {
+			if _, err := rd.dec.Token(); err != nil {
+				return nil, fmt.Errorf("failed to read snapshot: %w", err)
+			}
+			rd.array = true
+		}
+		return rd, nil
+	}
+}
+
+// next returns the next record, or io.EOF after the last one
+func (r *reader) next() (*Record, error) {
+	if r.array && !r.dec.More() {
+		if _, err := r.dec.Token(); err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		return nil, io.EOF
+	}
+
+	var rec Record
+	if err := r.dec.Decode(&rec); err != nil {
+		if errors.Is(err, io.EOF) && !r.array {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if err := rec.decode(); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
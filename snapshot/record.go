@@ -0,0 +1,183 @@
+package snapshot
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// Key types a snapshot can hold, as reported by TYPE
+const (
+	TypeString = "string"
+	TypeHash   = "hash"
+	TypeList   = "list"
+	TypeSet    = "set"
+	TypeZSet   = "zset"
+	TypeStream = "stream"
+)
+
+// EncodingBase64 marks a record whose key and values are base64 encoded, because
+// some of them are not valid UTF-8 and would be mangled by JSON
+const EncodingBase64 = "base64"
+
+// Record is one key of a snapshot
+// Only the field matching Type is set
+type Record struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+
+	// TTL is the remaining time to live in milliseconds when the key was exported; 0 means no expiration
+	TTL int64 `json:"ttl_ms,omitempty"`
+
+	// Encoding is EncodingBase64 when the strings of the record are base64 encoded
+	Encoding string `json:"encoding,omitempty"`
+
+	String string            `json:"string,omitempty"`
+	Hash   map[string]string `json:"hash,omitempty"`
+	List   []string          `json:"list,omitempty"`
+	Set    []string          `json:"set,omitempty"`
+	ZSet   []ZMember         `json:"zset,omitempty"`
+	Stream []StreamEntry     `json:"stream,omitempty"`
+}
+
+// ZMember is a sorted set member with its score
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+type zmemberJSON struct {
+	Member string          `json:"member"`
+	Score  json.RawMessage `json:"score"`
+}
+
+// MarshalJSON encodes infinite scores as the strings "inf" and "-inf", which JSON numbers cannot hold
+func (z ZMember) MarshalJSON() ([]byte, error) {
+	var score string
+	switch {
+	case math.IsInf(z.Score, 1):
+		score = `"inf"`
+	case math.IsInf(z.Score, -1):
+		score = `"-inf"`
+	default:
+		data, err := json.Marshal(z.Score)
+		if err != nil {
+			return nil, err
+		}
+		score = string(data)
+	}
+	return json.Marshal(zmemberJSON{Member: z.Member, Score: json.RawMessage(score)})
+}
+
+// UnmarshalJSON decodes a member written by MarshalJSON
+func (z *ZMember) UnmarshalJSON(data []byte) error {
+	var raw zmemberJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	z.Member = raw.Member
+	switch string(raw.Score) {
+	case `"inf"`:
+		z.Score = math.Inf(1)
+		return nil
+	case `"-inf"`:
+		z.Score = math.Inf(-1)
+		return nil
+	}
+	return json.Unmarshal(raw.Score, &z.Score)
+}
+
+// StreamEntry is a stream entry with its ID
+type StreamEntry struct {
+	ID     string            `json:"id"`
+	Fields map[string]string `json:"fields"`
+}
+
+// transform replaces every string of the record, key included, with fn's result
+func (r *Record) transform(fn func(string) (string, error)) error {
+	var err error
+	apply := func(s string) string {
+		if err != nil {
+			return s
+		}
+		var out string
+		out, err = fn(s)
+		return out
+	}
+	applyMap := func(m map[string]string) map[string]string {
+		if m == nil {
+			return nil
+		}
+		out := make(map[string]string, len(m))
+		for k, v := range m {
+			out[apply(k)] = apply(v)
+		}
+		return out
+	}
+
+	r.Key = apply(r.Key)
+	r.String = apply(r.String)
+	r.Hash = applyMap(r.Hash)
+	for i := range r.List {
+		r.List[i] = apply(r.List[i])
+	}
+	for i := range r.Set {
+		r.Set[i] = apply(r.Set[i])
+	}
+	for i := range r.ZSet {
+		r.ZSet[i].Member = apply(r.ZSet[i].Member)
+	}
+	for i := range r.Stream {
+		r.Stream[i].Fields = applyMap(r.Stream[i].Fields)
+	}
+	return err
+}
+
+// encode base64 encodes the record if any of its strings is not valid UTF-8
+func (r *Record) encode() {
+	valid := true
+	_ = r.transform(func(s string) (string, error) {
+		valid = valid && utf8.ValidString(s)
+		return s, nil
+	})
+	if valid {
+		return
+	}
+	_ = r.transform(func(s string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(s)), nil
+	})
+	r.Encoding = EncodingBase64
+}
+
+// decode reverses encode and checks that the record can be restored
+func (r *Record) decode() error {
+	switch r.Encoding {
+	case "":
+	case EncodingBase64:
+		err := r.transform(func(s string) (string, error) {
+			data, err := base64.StdEncoding.DecodeString(s)
+			return string(data), err
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidRecord, r.Key, err)
+		}
+		r.Encoding = ""
+	default:
+		return fmt.Errorf("%w: %s: unknown encoding %q", ErrInvalidRecord, r.Key, r.Encoding)
+	}
+
+	if r.Key == "" {
+		return fmt.Errorf("%w: empty key", ErrInvalidRecord)
+	}
+	switch r.Type {
+	case TypeString, TypeHash, TypeList, TypeSet, TypeZSet, TypeStream:
+	default:
+		return fmt.Errorf("%w: %s: unsupported type %q", ErrInvalidRecord, r.Key, r.Type)
+	}
+	if r.TTL < 0 {
+		return fmt.Errorf("%w: %s: negative ttl", ErrInvalidRecord, r.Key)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestZMember_JSON(t *testing.T) {
+	members := []ZMember{{"a", 1.5}, {"b", math.Inf(1)}, {"c", math.Inf(-1)}}
+	data, err := json.Marshal(members)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v, want nil", err)
+	}
+	if string(data) != `[{"member":"a","score":1.5},{"member":"b","score":"inf"},{"member":"c","score":"-inf"}]` {
+		t.Errorf("Marshal() = %s", data)
+	}
+
+	var got []ZMember
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v, want nil", err)
+	}
+	for i, z := range got {
+		if z != members[i] {
+			t.Errorf("Unmarshal()[%d] = %+v, want %+v", i, z, members[i])
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"member":"a","score":"x"}`), &got[0]); err == nil {
+		t.Error("Unmarshal() bad score error = nil, want error")
+	}
+}
+
+func TestRecord_Encoding(t *testing.T) {
+	rec := &Record{Key: "k", Type: TypeHash, Hash: map[string]string{"f": "v"}}
+	rec.encode()
+	if rec.Encoding != "" || rec.Hash["f"] != "v" {
+		t.Errorf("encode() of UTF-8 record = %+v, want it unchanged", rec)
+	}
+
+	binary := string([]byte{0xff, 0x00, 0xfe})
+	rec = &Record{Key: "bin", Type: TypeZSet, ZSet: []ZMember{{binary, 1}}}
+	rec.encode()
+	if rec.Encoding != EncodingBase64 || rec.Key != "Ymlu" || rec.ZSet[0].Member == binary {
+		t.Fatalf("encode() of binary record = %+v, want base64", rec)
+	}
+	if err := rec.decode(); err != nil || rec.Key != "bin" || rec.ZSet[0].Member != binary || rec.Encoding != "" {
+		t.Errorf("decode() = %+v, %v, want the original record", rec, err)
+	}
+}
+
+func TestRecord_DecodeInvalid(t *testing.T) {
+	for _, rec := range []Record{
+		{Key: "", Type: TypeString},
+		{Key: "k", Type: "ReJSON-RL"},
+		{Key: "k", Type: TypeString, TTL: -1},
+		{Key: "k", Type: TypeString, Encoding: "hex"},
+		{Key: "!!", Type: TypeString, Encoding: EncodingBase64},
+	} {
+		if err := rec.decode(); !errors.Is(err, ErrInvalidRecord) {
+			t.Errorf("decode(%+v) error = %v, want ErrInvalidRecord", rec, err)
+		}
+	}
+}
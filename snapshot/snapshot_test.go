@@ -0,0 +1,240 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+// seed writes one key of every supported type under app:, plus a key outside the prefix
+func seed(t *testing.T, client *redis.Client) {
+	t.Helper()
+	ctx := context.Background()
+	_ = client.Set(ctx, "app:string", "hello", time.Hour).Err()
+	_ = client.Set(ctx, "app:binary", string([]byte{0xff, 0x01}), 0).Err()
+	_ = client.HSet(ctx, "app:hash", "a", "1", "b", "2").Err()
+	_ = client.RPush(ctx, "app:list", "x", "y", "x").Err()
+	_ = client.SAdd(ctx, "app:set", "m", "n").Err()
+	_ = client.ZAdd(ctx, "app:zset", redis.Z{Score: 1.5, Member: "p"}, redis.Z{Score: math.Inf(1), Member: "q"}).Err()
+	_ = client.XAdd(ctx, &redis.XAddArgs{Stream: "app:stream", ID: "1-1", Values: map[string]interface{}{"k": "v"}}).Err()
+	_ = client.XAdd(ctx, &redis.XAddArgs{Stream: "app:stream", ID: "2-1", Values: map[string]interface{}{"k": "w"}}).Err()
+	_ = client.Set(ctx, "other:key", "v", 0).Err()
+}
+
+func TestExportImport(t *testing.T) {
+	for _, format := range []Format{FormatNDJSON, FormatJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			src, _ := testutil.NewMockRedisClient()
+			defer func() { _ = src.Close() }()
+			seed(t, src)
+
+			ctx := context.Background()
+			var buf bytes.Buffer
+			stats, err := Export(ctx, src, &buf, DefaultExportConfig("app:").WithFormat(format).WithBatchSize(3))
+			if err != nil {
+				t.Fatalf("Export() error = %v, want nil", err)
+			}
+			if stats.Keys != 7 || stats.Skipped != 0 {
+				t.Errorf("Export() stats = %+v, want 7 keys", stats)
+			}
+			if strings.Contains(buf.String(), "other:key") {
+				t.Error("Export() included a key outside the prefix")
+			}
+			if format == FormatJSON && !strings.HasPrefix(buf.String(), "[\n{") {
+				t.Errorf("Export() = %q, want a JSON array", buf.String()[:10])
+			}
+			if format == FormatNDJSON && strings.Count(buf.String(), "\n") != 7 {
+				t.Errorf("Export() = %q, want 7 lines", buf.String())
+			}
+
+			dst, _ := testutil.NewMockRedisClient()
+			defer func() { _ = dst.Close() }()
+			stats, err = Import(ctx, dst, &buf, DefaultImportConfig().WithBatchSize(2))
+			if err != nil {
+				t.Fatalf("Import() error = %v, want nil", err)
+			}
+			if stats.Keys != 7 || stats.Skipped != 0 || stats.Overwritten != 0 {
+				t.Errorf("Import() stats = %+v, want 7 keys", stats)
+			}
+
+			if v := dst.Get(ctx, "app:string").Val(); v != "hello" {
+				t.Errorf("string = %q, want hello", v)
+			}
+			if ttl := dst.PTTL(ctx, "app:string").Val(); ttl <= 59*time.Minute || ttl > time.Hour {
+				t.Errorf("string TTL = %v, want about 1h", ttl)
+			}
+			if v := dst.Get(ctx, "app:binary").Val(); v != string([]byte{0xff, 0x01}) {
+				t.Errorf("binary = %q, want the original bytes", v)
+			}
+			if ttl := dst.PTTL(ctx, "app:binary").Val(); ttl != -1 {
+				t.Errorf("binary TTL = %v, want no expiry", ttl)
+			}
+			if h := dst.HGetAll(ctx, "app:hash").Val(); len(h) != 2 || h["b"] != "2" {
+				t.Errorf("hash = %v, want a:1 b:2", h)
+			}
+			if l := dst.LRange(ctx, "app:list", 0, -1).Val(); strings.Join(l, ",") != "x,y,x" {
+				t.Errorf("list = %v, want [x y x]", l)
+			}
+			if n := dst.SCard(ctx, "app:set").Val(); n != 2 {
+				t.Errorf("set size = %d, want 2", n)
+			}
+			if s := dst.ZScore(ctx, "app:zset", "q").Val(); !math.IsInf(s, 1) {
+				t.Errorf("zset score of q = %v, want +inf", s)
+			}
+			msgs := dst.XRange(ctx, "app:stream", "-", "+").Val()
+			if len(msgs) != 2 || msgs[0].ID != "1-1" || msgs[1].Values["k"] != "w" {
+				t.Errorf("stream = %+v, want the two entries with their IDs", msgs)
+			}
+			if n := dst.Exists(ctx, "other:key").Val(); n != 0 {
+				t.Error("Import() restored a key outside the snapshot")
+			}
+		})
+	}
+}
+
+func TestExport_Empty(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	var buf bytes.Buffer
+	if stats, err := Export(ctx, client, &buf, DefaultExportConfig("none:").WithFormat(FormatJSON)); err != nil || stats.Keys != 0 {
+		t.Fatalf("Export() = %+v, %v, want no keys", stats, err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("Export() = %q, want an empty array", buf.String())
+	}
+	if stats, err := Import(ctx, client, &buf, DefaultImportConfig()); err != nil || stats.Keys != 0 {
+		t.Errorf("Import() of an empty array = %+v, %v, want no keys", stats, err)
+	}
+	if stats, err := Import(ctx, client, strings.NewReader("  \n"), DefaultImportConfig()); err != nil || stats.Keys != 0 {
+		t.Errorf("Import() of a blank file = %+v, %v, want no keys", stats, err)
+	}
+}
+
+func TestExport_GlobPrefix(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	_ = client.Set(ctx, "a*:1", "v", 0).Err()
+	_ = client.Set(ctx, "ab:1", "v", 0).Err()
+
+	var buf bytes.Buffer
+	if stats, err := Export(ctx, client, &buf, DefaultExportConfig("a*:")); err != nil || stats.Keys != 1 {
+		t.Errorf("Export() = %+v, %v, want only the literal prefix", stats, err)
+	}
+}
+
+func TestImport_Conflicts(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	snapshot := `{"key":"k1","type":"string","string":"new"}
+{"key":"k2","type":"list","list":["a","b"],"ttl_ms":60000}
+`
+	_ = client.Set(ctx, "k1", "old", 0).Err()
+	_ = client.RPush(ctx, "k2", "z").Err()
+
+	stats, err := Import(ctx, client, strings.NewReader(snapshot), DefaultImportConfig())
+	if err != nil || stats.Keys != 0 || stats.Skipped != 2 {
+		t.Errorf("Import() skip = %+v, %v, want 2 skipped", stats, err)
+	}
+	if v := client.Get(ctx, "k1").Val(); v != "old" {
+		t.Errorf("k1 = %q after skip, want old", v)
+	}
+
+	_, err = Import(ctx, client, strings.NewReader(snapshot), DefaultImportConfig().WithConflict(ConflictFail))
+	if !errors.Is(err, ErrConflict) || !strings.Contains(err.Error(), "k1") {
+		t.Errorf("Import() fail error = %v, want ErrConflict for k1", err)
+	}
+
+	stats, err = Import(ctx, client, strings.NewReader(snapshot), DefaultImportConfig().WithConflict(ConflictOverwrite).WithIgnoreTTL(true))
+	if err != nil || stats.Keys != 2 || stats.Overwritten != 2 {
+		t.Errorf("Import() overwrite = %+v, %v, want 2 overwritten", stats, err)
+	}
+	if v := client.Get(ctx, "k1").Val(); v != "new" {
+		t.Errorf("k1 = %q after overwrite, want new", v)
+	}
+	if l := client.LRange(ctx, "k2", 0, -1).Val(); strings.Join(l, ",") != "a,b" {
+		t.Errorf("k2 = %v after overwrite, want [a b] without the old element", l)
+	}
+	if ttl := client.PTTL(ctx, "k2").Val(); ttl != -1 {
+		t.Errorf("k2 TTL = %v, want none with IgnoreTTL", ttl)
+	}
+}
+
+func TestImport_Invalid(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	for name, input := range map[string]string{
+		"malformed":   "{not json",
+		"truncated":   `[{"key":"k","type":"string"}`,
+		"bad type":    `{"key":"k","type":"module"}`,
+		"empty key":   `{"key":"","type":"string"}`,
+		"bad base64":  `{"key":"***","type":"string","encoding":"base64"}`,
+		"bad element": `[1]`,
+	} {
+		if _, err := Import(ctx, client, strings.NewReader(input), DefaultImportConfig()); err == nil {
+			t.Errorf("Import(%s) error = nil, want error", name)
+		}
+	}
+}
+
+func TestSnapshot_Errors(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+
+	if _, err := Export(ctx, nil, &buf, DefaultExportConfig("")); err == nil {
+		t.Error("Export() with nil client error = nil, want error")
+	}
+	if _, err := Import(ctx, nil, &buf, DefaultImportConfig()); err == nil {
+		t.Error("Import() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	if _, err := Export(ctx, client, &buf, DefaultExportConfig("").WithFormat("xml")); err == nil {
+		t.Error("Export() with unknown format error = nil, want error")
+	}
+
+	_ = client.Set(ctx, "k", "v", 0).Err()
+	mock.SetShouldFail(true)
+	if _, err := Export(ctx, client, &buf, DefaultExportConfig("")); err == nil {
+		t.Error("Export() error = nil, want error")
+	}
+	if _, err := Import(ctx, client, strings.NewReader(`{"key":"k","type":"string"}`), DefaultImportConfig()); err == nil {
+		t.Error("Import() error = nil, want error")
+	}
+}
+
+func TestExport_Cancelled(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, key := range []string{"a", "b", "c"} {
+		_ = client.Set(ctx, key, "v", 0).Err()
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	var buf bytes.Buffer
+	stats, err := Export(ctx, client, &buf, DefaultExportConfig("").WithBatchSize(1).WithInterval(time.Minute))
+	if !errors.Is(err, context.Canceled) || stats.Keys != 1 {
+		t.Errorf("Export() = %+v, %v, want cancellation after the first batch", stats, err)
+	}
+}
@@ -0,0 +1,18 @@
+package statemachine
+
+import "errors"
+
+var (
+	// ErrNotFound indicates an entity that has not been started, or has expired.
+	ErrNotFound = errors.New("entity not found")
+
+	// ErrExists indicates a Start for an entity that already has a state.
+	ErrExists = errors.New("entity already exists")
+
+	// ErrInvalidTransition indicates a transition the definition does not allow.
+	ErrInvalidTransition = errors.New("transition not allowed")
+
+	// ErrConflict indicates an entity that is no longer in the expected state, usually because
+	// a concurrent transition won.
+	ErrConflict = errors.New("entity state changed")
+)
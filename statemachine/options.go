@@ -0,0 +1,39 @@
+package statemachine
+
+import "time"
+
+// settings holds the configuration of a Machine
+type settings struct {
+	keyPrefix    string
+	historyLimit int64
+	ttl          time.Duration
+}
+
+// Option configures a Machine
+type Option func(*settings)
+
+// WithKeyPrefix sets the prefix of entity keys (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(s *settings) {
+		s.keyPrefix = prefix
+	}
+}
+
+// WithHistoryLimit sets how many of the latest steps are kept per entity; 0 keeps them all
+// (default: DefaultHistoryLimit)
+func WithHistoryLimit(n int64) Option {
+	return func(s *settings) {
+		if n >= 0 {
+			s.historyLimit = n
+		}
+	}
+}
+
+// WithTTL expires an entity and its history ttl after its last transition (default: never)
+func WithTTL(ttl time.Duration) Option {
+	return func(s *settings) {
+		if ttl >= 0 {
+			s.ttl = ttl
+		}
+	}
+}
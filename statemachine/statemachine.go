@@ -0,0 +1,270 @@
+// Package statemachine persists the state of workflow entities on Redis
+// A Machine follows a Definition of allowed transitions; each entity keeps its current state,
+// a version bumped by every transition, and a capped history of steps with their payloads.
+// Transitions are compare-and-set operations run in one script, so when processes race to
+// move an entity only one of them wins and the others get ErrConflict, which makes a
+// Machine suitable for coordinating the steps of sagas and other distributed processes
+package statemachine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix of entity keys
+	DefaultKeyPrefix = "statemachine:"
+
+	// DefaultHistoryLimit is the default number of steps kept per entity
+	DefaultHistoryLimit = 100
+)
+
+// transitionScript moves KEYS[1] from ARGV[1] to ARGV[2] and appends the step ARGV[4] to the
+// history KEYS[2], unless the entity is in another state; an empty ARGV[1] starts the entity
+// and requires that it does not exist. The reply is a status and the version or current state
+const transitionScript = `
+-- redis-kit:statemachine-transition
+local current = redis.call("hget", KEYS[1], "state")
+if ARGV[1] == "" then
+	if current then
+		return {"exists", current}
+	end
+elseif not current then
+	return {"missing", ""}
+elseif current ~= ARGV[1] then
+	return {"conflict", current}
+end
+local version = redis.call("hincrby", KEYS[1], "version", 1)
+redis.call("hset", KEYS[1], "state", ARGV[2], "updated", ARGV[3])
+redis.call("rpush", KEYS[2], version .. ":" .. ARGV[4])
+local limit = tonumber(ARGV[5])
+if limit > 0 then
+	redis.call("ltrim", KEYS[2], -limit, -1)
+end
+local ttl = tonumber(ARGV[6])
+if ttl > 0 then
+	redis.call("pexpire", KEYS[1], ttl)
+	redis.call("pexpire", KEYS[2], ttl)
+end
+return {"ok", tostring(version)}
+`
+
+// Definition describes the states of a workflow and the transitions between them
+type Definition struct {
+	// Initial is the state entities are started in
+	Initial string
+
+	// Transitions maps each state to the states it may move to; states without outgoing
+	// transitions are final
+	Transitions map[string][]string
+}
+
+// Can reports whether the definition allows moving from one state to another
+func (d Definition) Can(from, to string) bool {
+	return slices.Contains(d.Transitions[from], to)
+}
+
+// Allowed returns the states an entity in state may move to
+func (d Definition) Allowed(state string) []string {
+	return slices.Clone(d.Transitions[state])
+}
+
+// IsFinal reports whether state has no outgoing transitions
+func (d Definition) IsFinal(state string) bool {
+	return len(d.Transitions[state]) == 0
+}
+
+// Entity is the persisted state of one workflow instance
+type Entity struct {
+	ID      string
+	State   string
+	Version int64
+
+	// UpdatedAt is the time of the last transition
+	UpdatedAt time.Time
+}
+
+// Step is a recorded transition; the first step of an entity has an empty From
+type Step struct {
+	Version int64
+	From    string
+	To      string
+	At      time.Time
+
+	// Payload is the JSON encoded payload given to the transition, if any
+	Payload json.RawMessage
+}
+
+// stepRecord is a step as stored in the history, after its version
+type stepRecord struct {
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	At      int64           `json:"at"` // Unix milliseconds
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Machine stores entities following a Definition
+type Machine struct {
+	client *redis.Client
+	name   string
+	def    Definition
+	settings
+}
+
+// NewMachine creates a state machine; name namespaces its entities
+func NewMachine(client *redis.Client, name string, def Definition, opts ...Option) *Machine {
+	m := &Machine{
+		client:   client,
+		name:     name,
+		def:      def,
+		settings: settings{keyPrefix: DefaultKeyPrefix, historyLimit: DefaultHistoryLimit},
+	}
+	for _, opt := range opts {
+		opt(&m.settings)
+	}
+	return m
+}
+
+// Definition returns the machine's definition
+func (m *Machine) Definition() Definition {
+	return m.def
+}
+
+// Key returns the Redis key of the entity id; the ID is a hash tag, so on Redis Cluster the
+// entity and its history share a slot
+func (m *Machine) Key(id string) string {
+	return m.keyPrefix + m.name + ":{" + id + "}"
+}
+
+// HistoryKey returns the Redis key of the history of the entity id
+func (m *Machine) HistoryKey(id string) string {
+	return m.Key(id) + ":history"
+}
+
+// Start creates the entity id in the initial state, recording payload with the first step
+// It returns ErrExists if the entity already has a state
+func (m *Machine) Start(ctx context.Context, id string, payload interface{}) (*Entity, error) {
+	if m.def.Initial == "" {
+		return nil, fmt.Errorf("initial state is not set")
+	}
+	return m.transition(ctx, id, "", m.def.Initial, payload)
+}
+
+// Transition moves the entity id from one state to another, recording payload with the step
+// It returns ErrInvalidTransition if the definition does not allow the move, ErrNotFound if
+// the entity does not exist, and ErrConflict if it is not in state from
+func (m *Machine) Transition(ctx context.Context, id, from, to string, payload interface{}) (*Entity, error) {
+	if !m.def.Can(from, to) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+	}
+	return m.transition(ctx, id, from, to, payload)
+}
+
+func (m *Machine) transition(ctx context.Context, id, from, to string, payload interface{}) (*Entity, error) {
+	if m.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	now := time.Now()
+	step := stepRecord{From: from, To: to, At: now.UnixMilli()}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		step.Payload = data
+	}
+	data, err := json.Marshal(step)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal step: %w", err)
+	}
+
+	res, err := m.client.Eval(ctx, transitionScript, []string{m.Key(id), m.HistoryKey(id)},
+		from, to, now.UnixMilli(), data, m.historyLimit, m.ttl.Milliseconds()).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to transition entity: %w", err)
+	}
+	if len(res) != 2 {
+		return nil, errors.New("unexpected transition script reply")
+	}
+
+	switch res[0] {
+	case "ok":
+		version, err := strconv.ParseInt(res[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entity version %q: %w", res[1], err)
+		}
+		return &Entity{ID: id, State: to, Version: version, UpdatedAt: time.UnixMilli(now.UnixMilli())}, nil
+	case "exists":
+		return nil, fmt.Errorf("%w: %s is in state %q", ErrExists, id, res[1])
+	case "missing":
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	default:
+		return nil, fmt.Errorf("%w: %s is in state %q, not %q", ErrConflict, id, res[1], from)
+	}
+}
+
+// Get returns the entity id, or ErrNotFound
+func (m *Machine) Get(ctx context.Context, id string) (*Entity, error) {
+	if m.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	fields, err := m.client.HGetAll(ctx, m.Key(id)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entity: %w", err)
+	}
+	state, ok := fields["state"]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	version, _ := strconv.ParseInt(fields["version"], 10, 64)
+	updated, _ := strconv.ParseInt(fields["updated"], 10, 64)
+	return &Entity{ID: id, State: state, Version: version, UpdatedAt: time.UnixMilli(updated)}, nil
+}
+
+// History returns the recorded steps of the entity id, oldest first
+// Only the latest steps are kept when a history limit is set
+func (m *Machine) History(ctx context.Context, id string) ([]Step, error) {
+	if m.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	items, err := m.client.LRange(ctx, m.HistoryKey(id), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+	steps := make([]Step, 0, len(items))
+	for _, item := range items {
+		version, data, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed history step %q", item)
+		}
+		var rec stepRecord
+		if err := json.Unmarshal([]byte(data), &rec); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal history step: %w", err)
+		}
+		n, _ := strconv.ParseInt(version, 10, 64)
+		steps = append(steps, Step{Version: n, From: rec.From, To: rec.To, At: time.UnixMilli(rec.At), Payload: rec.Payload})
+	}
+	return steps, nil
+}
+
+// Delete removes the entity id and its history
+func (m *Machine) Delete(ctx context.Context, id string) error {
+	if m.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if err := m.client.Del(ctx, m.Key(id), m.HistoryKey(id)).Err(); err != nil {
+		return fmt.Errorf("failed to delete entity: %w", err)
+	}
+	return nil
+}
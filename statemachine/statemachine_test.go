@@ -0,0 +1,232 @@
+package statemachine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+var orderFlow = Definition{
+	Initial: "pending",
+	Transitions: map[string][]string{
+		"pending":  {"paid", "cancelled"},
+		"paid":     {"shipped", "refunded"},
+		"shipped":  {"delivered"},
+		"refunded": nil,
+	},
+}
+
+func TestDefinition(t *testing.T) {
+	if !orderFlow.Can("pending", "paid") || orderFlow.Can("pending", "shipped") || orderFlow.Can("", "pending") {
+		t.Error("Can() does not follow the transitions")
+	}
+	allowed := orderFlow.Allowed("paid")
+	if len(allowed) != 2 || allowed[0] != "shipped" {
+		t.Errorf("Allowed(paid) = %v, want [shipped refunded]", allowed)
+	}
+	allowed[0] = "mutated"
+	if orderFlow.Transitions["paid"][0] != "shipped" {
+		t.Error("Allowed() returned the definition's own slice")
+	}
+	if !orderFlow.IsFinal("delivered") || !orderFlow.IsFinal("refunded") || orderFlow.IsFinal("pending") {
+		t.Error("IsFinal() does not match the states without transitions")
+	}
+}
+
+func TestMachine_Lifecycle(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	m := NewMachine(client, "orders", orderFlow)
+	if m.Key("42") != "statemachine:orders:{42}" || m.HistoryKey("42") != "statemachine:orders:{42}:history" {
+		t.Errorf("Key() = %q, HistoryKey() = %q", m.Key("42"), m.HistoryKey("42"))
+	}
+	if m.Definition().Initial != "pending" {
+		t.Errorf("Definition().Initial = %q, want pending", m.Definition().Initial)
+	}
+
+	e, err := m.Start(ctx, "42", map[string]int{"amount": 100})
+	if err != nil || e.State != "pending" || e.Version != 1 {
+		t.Fatalf("Start() = %+v, %v, want pending at version 1", e, err)
+	}
+	if _, err := m.Start(ctx, "42", nil); !errors.Is(err, ErrExists) {
+		t.Errorf("Start() again error = %v, want ErrExists", err)
+	}
+
+	if _, err := m.Transition(ctx, "42", "pending", "shipped", nil); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Transition(pending->shipped) error = %v, want ErrInvalidTransition", err)
+	}
+	if e, err = m.Transition(ctx, "42", "pending", "paid", map[string]string{"tx": "abc"}); err != nil || e.State != "paid" || e.Version != 2 {
+		t.Fatalf("Transition(pending->paid) = %+v, %v, want paid at version 2", e, err)
+	}
+	if _, err := m.Transition(ctx, "42", "pending", "cancelled", nil); !errors.Is(err, ErrConflict) {
+		t.Errorf("Transition() from a stale state error = %v, want ErrConflict", err)
+	}
+	if _, err := m.Transition(ctx, "missing", "pending", "paid", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Transition() of a missing entity error = %v, want ErrNotFound", err)
+	}
+	_, _ = m.Transition(ctx, "42", "paid", "shipped", nil)
+
+	got, err := m.Get(ctx, "42")
+	if err != nil || got.State != "shipped" || got.Version != 3 || time.Since(got.UpdatedAt) > time.Minute {
+		t.Errorf("Get() = %+v, %v, want shipped at version 3", got, err)
+	}
+	if _, err := m.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a missing entity error = %v, want ErrNotFound", err)
+	}
+
+	steps, err := m.History(ctx, "42")
+	if err != nil || len(steps) != 3 {
+		t.Fatalf("History() = %+v, %v, want 3 steps", steps, err)
+	}
+	if steps[0].From != "" || steps[0].To != "pending" || steps[2].Version != 3 || steps[2].To != "shipped" {
+		t.Errorf("History() = %+v, want pending, paid, shipped", steps)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(steps[1].Payload, &payload); err != nil || payload["tx"] != "abc" {
+		t.Errorf("History()[1].Payload = %s, want the transition payload", steps[1].Payload)
+	}
+	if steps[2].Payload != nil || steps[1].At.IsZero() {
+		t.Errorf("History()[2] = %+v, want no payload", steps[2])
+	}
+
+	if err := m.Delete(ctx, "42"); err != nil {
+		t.Fatalf("Delete() error = %v, want nil", err)
+	}
+	if _, err := m.Get(ctx, "42"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrNotFound", err)
+	}
+	if steps, _ := m.History(ctx, "42"); len(steps) != 0 {
+		t.Errorf("History() after Delete = %+v, want none", steps)
+	}
+}
+
+func TestMachine_ConcurrentTransitions(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	m := NewMachine(client, "orders", orderFlow)
+	_, _ = m.Start(ctx, "1", nil)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	won, conflicts := 0, 0
+	for _, to := range []string{"paid", "cancelled", "paid", "cancelled", "paid"} {
+		wg.Add(1)
+		go func(to string) {
+			defer wg.Done()
+			_, err := m.Transition(ctx, "1", "pending", to, nil)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				won++
+			case errors.Is(err, ErrConflict):
+				conflicts++
+			default:
+				t.Errorf("Transition() error = %v, want nil or ErrConflict", err)
+			}
+		}(to)
+	}
+	wg.Wait()
+
+	if won != 1 || conflicts != 4 {
+		t.Errorf("won %d, conflicts %d, want exactly one winner", won, conflicts)
+	}
+	if e, _ := m.Get(ctx, "1"); e.Version != 2 {
+		t.Errorf("Version = %d, want 2", e.Version)
+	}
+}
+
+func TestMachine_Options(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	loop := Definition{Initial: "a", Transitions: map[string][]string{"a": {"b"}, "b": {"a"}}}
+	m := NewMachine(client, "loop", loop, WithKeyPrefix("fsm:"), WithHistoryLimit(3), WithTTL(time.Minute))
+	if m.Key("x") != "fsm:loop:{x}" {
+		t.Errorf("Key() = %q, want fsm:loop:{x}", m.Key("x"))
+	}
+
+	_, _ = m.Start(ctx, "x", nil)
+	state := "a"
+	for i := 0; i < 5; i++ {
+		next := loop.Transitions[state][0]
+		if _, err := m.Transition(ctx, "x", state, next, nil); err != nil {
+			t.Fatalf("Transition() error = %v, want nil", err)
+		}
+		state = next
+	}
+
+	steps, _ := m.History(ctx, "x")
+	if len(steps) != 3 || steps[0].Version != 4 || steps[2].Version != 6 {
+		t.Errorf("History() = %+v, want the latest 3 of 6 steps", steps)
+	}
+	for _, key := range []string{m.Key("x"), m.HistoryKey("x")} {
+		if ttl := client.PTTL(ctx, key).Val(); ttl <= 0 || ttl > time.Minute {
+			t.Errorf("PTTL(%s) = %v, want about 1m", key, ttl)
+		}
+	}
+
+	s := settings{historyLimit: 7}
+	WithHistoryLimit(-1)(&s)
+	WithTTL(-time.Second)(&s)
+	if s.historyLimit != 7 || s.ttl != 0 {
+		t.Errorf("negative options = %+v, want them ignored", s)
+	}
+}
+
+func TestMachine_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	m := NewMachine(nil, "orders", orderFlow)
+	if _, err := m.Start(ctx, "1", nil); err == nil {
+		t.Error("Start() with nil client error = nil, want error")
+	}
+	if _, err := m.Get(ctx, "1"); err == nil {
+		t.Error("Get() with nil client error = nil, want error")
+	}
+	if _, err := m.History(ctx, "1"); err == nil {
+		t.Error("History() with nil client error = nil, want error")
+	}
+	if err := m.Delete(ctx, "1"); err == nil {
+		t.Error("Delete() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	if _, err := NewMachine(client, "x", Definition{}).Start(ctx, "1", nil); err == nil {
+		t.Error("Start() without initial state error = nil, want error")
+	}
+	m = NewMachine(client, "orders", orderFlow)
+	if _, err := m.Start(ctx, "1", make(chan int)); err == nil {
+		t.Error("Start() with unmarshalable payload error = nil, want error")
+	}
+	_ = client.RPush(ctx, m.HistoryKey("bad"), "no-version").Err()
+	if _, err := m.History(ctx, "bad"); err == nil {
+		t.Error("History() with malformed step error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if _, err := m.Start(ctx, "1", nil); err == nil {
+		t.Error("Start() error = nil, want error")
+	}
+	if _, err := m.Get(ctx, "1"); err == nil {
+		t.Error("Get() error = nil, want error")
+	}
+	if _, err := m.History(ctx, "1"); err == nil {
+		t.Error("History() error = nil, want error")
+	}
+	if err := m.Delete(ctx, "1"); err == nil {
+		t.Error("Delete() error = nil, want error")
+	}
+}
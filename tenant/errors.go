@@ -0,0 +1,9 @@
+package tenant
+
+import "errors"
+
+var (
+	// ErrInvalidTenantID indicates an empty tenant ID, or one containing a character that would
+	// let its namespace overlap another tenant's.
+	ErrInvalidTenantID = errors.New("invalid tenant id")
+)
@@ -0,0 +1,67 @@
+package tenant
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/lock"
+	"github.com/soulteary/redis-kit/ratelimit"
+)
+
+// settings holds the configuration of a Kit
+type settings struct {
+	keyPrefix     string
+	hashTag       bool
+	lockTime      time.Duration
+	cacheOpts     []cache.Option
+	lockOpts      []lock.Option
+	rateLimitOpts []ratelimit.Option
+}
+
+// Option configures a Kit
+type Option func(*settings)
+
+// WithKeyPrefix sets the prefix of every tenant namespace (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(s *settings) {
+		s.keyPrefix = prefix
+	}
+}
+
+// WithHashTag wraps the tenant ID in a {hash tag}, so on Redis Cluster all keys of a tenant
+// live in one slot and can be used together in scripts and transactions (default: disabled)
+func WithHashTag(enabled bool) Option {
+	return func(s *settings) {
+		s.hashTag = enabled
+	}
+}
+
+// WithLockTime sets the expiration of the tenant's locks (default: lock.DefaultLockTime)
+func WithLockTime(d time.Duration) Option {
+	return func(s *settings) {
+		if d > 0 {
+			s.lockTime = d
+		}
+	}
+}
+
+// WithCacheOptions sets options applied to the tenant's cache
+func WithCacheOptions(opts ...cache.Option) Option {
+	return func(s *settings) {
+		s.cacheOpts = append(s.cacheOpts, opts...)
+	}
+}
+
+// WithLockOptions sets options applied to the tenant's locker
+func WithLockOptions(opts ...lock.Option) Option {
+	return func(s *settings) {
+		s.lockOpts = append(s.lockOpts, opts...)
+	}
+}
+
+// WithRateLimitOptions sets options applied to the tenant's rate limiter
+func WithRateLimitOptions(opts ...ratelimit.Option) Option {
+	return func(s *settings) {
+		s.rateLimitOpts = append(s.rateLimitOpts, opts...)
+	}
+}
@@ -0,0 +1,41 @@
+package tenant
+
+import (
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/lock"
+	"github.com/soulteary/redis-kit/ratelimit"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestOptions(t *testing.T) {
+	rec := testutil.NewRecorder()
+	s := settings{lockTime: lock.DefaultLockTime}
+	for _, opt := range []Option{
+		WithKeyPrefix("saas:"),
+		WithHashTag(true),
+		WithLockTime(-time.Second),
+		WithCacheOptions(cache.WithRecorder(rec)),
+		WithLockOptions(lock.WithRecorder(rec)),
+		WithRateLimitOptions(ratelimit.WithRecorder(rec)),
+	} {
+		opt(&s)
+	}
+
+	if s.keyPrefix != "saas:" || !s.hashTag {
+		t.Errorf("options = %+v, want saas: with hash tag", s)
+	}
+	if s.lockTime != lock.DefaultLockTime {
+		t.Errorf("WithLockTime(-1s) lockTime = %v, want the default kept", s.lockTime)
+	}
+	if len(s.cacheOpts) != 1 || len(s.lockOpts) != 1 || len(s.rateLimitOpts) != 1 {
+		t.Errorf("options = %+v, want one option per primitive", s)
+	}
+
+	WithLockTime(time.Minute)(&s)
+	if s.lockTime != time.Minute {
+		t.Errorf("WithLockTime(1m) lockTime = %v, want 1m", s.lockTime)
+	}
+}
@@ -0,0 +1,133 @@
+// Package tenant namespaces redis-kit primitives per tenant of a multi-tenant application
+// A Kit derives one key prefix from the tenant ID and hands out a cache, a locker, and a
+// rate limiter confined to it, all sharing one client, so tenants never see each other's
+// keys and all keys of a tenant can be listed or wiped with a single prefix scan
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/lock"
+	"github.com/soulteary/redis-kit/ratelimit"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+// DefaultKeyPrefix is the default prefix of tenant namespaces
+const DefaultKeyPrefix = "tenant:"
+
+// Namespaces of the primitives within a tenant's prefix
+const (
+	CacheNamespace     = "cache:"
+	LockNamespace      = "lock:"
+	RateLimitNamespace = "ratelimit:"
+	CooldownNamespace  = "ratelimit:cooldown:"
+)
+
+// Kit holds the primitives of one tenant
+type Kit struct {
+	client  *redis.Client
+	id      string
+	prefix  string
+	cache   *cache.RedisCache
+	locker  *lock.RedisLocker
+	limiter *ratelimit.RateLimiter
+}
+
+// NewTenantKit creates the primitives of tenantID, namespaced under "<prefix><tenantID>:"
+// The ID must not be empty or contain ':', '{', or '}', so that no tenant's namespace
+// contains another's; ErrInvalidTenantID is returned otherwise
+func NewTenantKit(client *redis.Client, tenantID string, opts ...Option) (*Kit, error) {
+	if tenantID == "" || strings.ContainsAny(tenantID, ":{}") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTenantID, tenantID)
+	}
+
+	s := settings{keyPrefix: DefaultKeyPrefix, lockTime: lock.DefaultLockTime}
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	prefix := s.keyPrefix + tenantID + ":"
+	if s.hashTag {
+		prefix = s.keyPrefix + "{" + tenantID + "}:"
+	}
+
+	lockOpts := append([]lock.Option{lock.WithKeyPrefix(prefix + LockNamespace)}, s.lockOpts...)
+	return &Kit{
+		client:  client,
+		id:      tenantID,
+		prefix:  prefix,
+		cache:   cache.NewCache(client, prefix+CacheNamespace, s.cacheOpts...),
+		locker:  lock.NewRedisLockerWithLockTime(client, s.lockTime, lockOpts...),
+		limiter: ratelimit.NewRateLimiterWithPrefixes(client, prefix+RateLimitNamespace, prefix+CooldownNamespace, s.rateLimitOpts...),
+	}, nil
+}
+
+// ID returns the tenant ID
+func (k *Kit) ID() string {
+	return k.id
+}
+
+// Prefix returns the prefix of every key of the tenant
+func (k *Kit) Prefix() string {
+	return k.prefix
+}
+
+// Key returns key within the tenant's namespace, for data kept outside the kit's primitives
+func (k *Kit) Key(key string) string {
+	return k.prefix + key
+}
+
+// Client returns the underlying Redis client
+func (k *Kit) Client() *redis.Client {
+	return k.client
+}
+
+// Cache returns the tenant's cache
+func (k *Kit) Cache() *cache.RedisCache {
+	return k.cache
+}
+
+// Locker returns the tenant's distributed locker
+func (k *Kit) Locker() *lock.RedisLocker {
+	return k.locker
+}
+
+// RateLimiter returns the tenant's rate limiter
+func (k *Kit) RateLimiter() *ratelimit.RateLimiter {
+	return k.limiter
+}
+
+// Keys returns an iterator over the keys of the tenant, found with SCAN
+// As with SCAN itself, a key may be yielded more than once
+func (k *Kit) Keys(ctx context.Context) iter.Seq2[string, error] {
+	if k.client == nil {
+		return func(yield func(string, error) bool) {
+			yield("", fmt.Errorf("redis client is nil"))
+		}
+	}
+	return utils.ScanKeys(ctx, k.client, k.pattern(), utils.DefaultScanCount)
+}
+
+// Wipe removes every key of the tenant with SCAN and batched UNLINK, paced by opts
+// Locks held at the time are removed too, so stop the tenant's workers first; use
+// opts.DryRun to count the keys without deleting them
+func (k *Kit) Wipe(ctx context.Context, opts utils.DeleteOptions) (utils.DeleteResult, error) {
+	if k.client == nil {
+		return utils.DeleteResult{}, fmt.Errorf("redis client is nil")
+	}
+	result, err := utils.DeleteByPattern(ctx, k.client, k.pattern(), opts)
+	if err != nil {
+		return result, fmt.Errorf("failed to wipe tenant %s: %w", k.id, err)
+	}
+	return result, nil
+}
+
+// pattern matches the tenant's keys, with glob characters of the prefix matched literally
+func (k *Kit) pattern() string {
+	return utils.EscapeGlob(k.prefix) + "*"
+}
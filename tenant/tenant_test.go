@@ -0,0 +1,139 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/cache"
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+func TestNewTenantKit(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	kit, err := NewTenantKit(client, "acme")
+	if err != nil {
+		t.Fatalf("NewTenantKit() error = %v, want nil", err)
+	}
+	if kit.ID() != "acme" || kit.Prefix() != "tenant:acme:" || kit.Key("x") != "tenant:acme:x" || kit.Client() != client {
+		t.Errorf("NewTenantKit() = %q/%q, want tenant:acme:", kit.ID(), kit.Prefix())
+	}
+
+	kit, _ = NewTenantKit(client, "acme", WithKeyPrefix("saas:"), WithHashTag(true))
+	if kit.Prefix() != "saas:{acme}:" {
+		t.Errorf("Prefix() with hash tag = %q, want saas:{acme}:", kit.Prefix())
+	}
+
+	for _, id := range []string{"", "a:b", "{a}", "a}"} {
+		if _, err := NewTenantKit(client, id); !errors.Is(err, ErrInvalidTenantID) {
+			t.Errorf("NewTenantKit(%q) error = %v, want ErrInvalidTenantID", id, err)
+		}
+	}
+}
+
+func TestKit_Isolation(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	acme, _ := NewTenantKit(client, "acme", WithLockTime(time.Minute))
+	globex, _ := NewTenantKit(client, "globex")
+
+	_ = acme.Cache().Set(ctx, "user:1", "alice", time.Minute)
+	var got string
+	if err := globex.Cache().Get(ctx, "user:1", &got); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get() from another tenant error = %v, want ErrNotFound", err)
+	}
+	if n := client.Exists(ctx, "tenant:acme:cache:user:1").Val(); n != 1 {
+		t.Error("Cache().Set() did not write under the tenant prefix")
+	}
+
+	if ok, _ := acme.Locker().Lock("job"); !ok {
+		t.Fatal("Lock() = false, want true")
+	}
+	if ok, _ := globex.Locker().Lock("job"); !ok {
+		t.Error("Lock() of another tenant = false, want true")
+	}
+	if ttl := client.PTTL(ctx, "tenant:acme:lock:job").Val(); ttl <= 30*time.Second {
+		t.Errorf("lock TTL = %v, want about 1m", ttl)
+	}
+
+	if ok, _, _, _ := acme.RateLimiter().CheckLimit(ctx, "api", 1, time.Minute); !ok {
+		t.Error("CheckLimit() first = false, want true")
+	}
+	if ok, _, _, _ := acme.RateLimiter().CheckLimit(ctx, "api", 1, time.Minute); ok {
+		t.Error("CheckLimit() over the limit = true, want false")
+	}
+	if ok, _, _, _ := globex.RateLimiter().CheckLimit(ctx, "api", 1, time.Minute); !ok {
+		t.Error("CheckLimit() of another tenant = false, want true")
+	}
+	if ok, _, _ := acme.RateLimiter().CheckCooldown(ctx, "sms", time.Minute); !ok {
+		t.Error("CheckCooldown() = false, want true")
+	}
+}
+
+func TestKit_KeysAndWipe(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	acme, _ := NewTenantKit(client, "ac*me")
+	other, _ := NewTenantKit(client, "acXme")
+	_ = acme.Cache().Set(ctx, "a", 1, 0)
+	_, _ = acme.Locker().Lock("job")
+	_ = client.Set(ctx, acme.Key("raw"), "v", 0).Err()
+	_ = other.Cache().Set(ctx, "a", 1, 0)
+
+	var keys []string
+	for key, err := range acme.Keys(ctx) {
+		if err != nil {
+			t.Fatalf("Keys() error = %v, want nil", err)
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	want := []string{"tenant:ac*me:cache:a", "tenant:ac*me:lock:job", "tenant:ac*me:raw"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("Keys() = %v, want %v", keys, want)
+	}
+
+	res, err := acme.Wipe(ctx, utils.DefaultDeleteOptions().WithDryRun(true))
+	if err != nil || res.Matched != 3 || res.Deleted != 0 {
+		t.Errorf("Wipe() dry run = %+v, %v, want 3 matched", res, err)
+	}
+	res, err = acme.Wipe(ctx, utils.DefaultDeleteOptions())
+	if err != nil || res.Deleted != 3 {
+		t.Errorf("Wipe() = %+v, %v, want 3 deleted", res, err)
+	}
+	if n := client.Exists(ctx, "tenant:acXme:cache:a").Val(); n != 1 {
+		t.Error("Wipe() removed another tenant's key")
+	}
+}
+
+func TestKit_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	kit, _ := NewTenantKit(nil, "acme")
+	if _, err := kit.Wipe(ctx, utils.DefaultDeleteOptions()); err == nil {
+		t.Error("Wipe() with nil client error = nil, want error")
+	}
+	for _, err := range kit.Keys(ctx) {
+		if err == nil {
+			t.Error("Keys() with nil client error = nil, want error")
+		}
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	kit, _ = NewTenantKit(client, "acme")
+	mock.SetShouldFail(true)
+	if _, err := kit.Wipe(ctx, utils.DefaultDeleteOptions()); err == nil {
+		t.Error("Wipe() error = nil, want error")
+	}
+}
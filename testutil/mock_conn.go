@@ -0,0 +1,75 @@
+package testutil
+
+import (
+	"net"
+	"sync"
+)
+
+// replyQueue buffers replies in memory and writes them to the connection from
+// its own goroutine, so a client sending a large pipeline over the synchronous
+// net.Pipe is never blocked by replies it has not started reading yet
+type replyQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	chunks [][]byte
+	closed bool
+	done   chan struct{}
+}
+
+func newReplyQueue(conn net.Conn) *replyQueue {
+	q := &replyQueue{done: make(chan struct{})}
+	q.cond = sync.NewCond(&q.mu)
+	go q.pump(conn)
+	return q
+}
+
+// Write queues a copy of p for delivery
+func (q *replyQueue) Write(p []byte) (int, error) {
+	chunk := make([]byte, len(p))
+	copy(chunk, p)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, net.ErrClosed
+	}
+	q.chunks = append(q.chunks, chunk)
+	q.cond.Signal()
+	return len(p), nil
+}
+
+// Close stops accepting writes and waits until queued replies are delivered
+func (q *replyQueue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Signal()
+	q.mu.Unlock()
+	<-q.done
+}
+
+func (q *replyQueue) pump(conn net.Conn) {
+	defer close(q.done)
+	for {
+		q.mu.Lock()
+		for len(q.chunks) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		chunks := q.chunks
+		q.chunks = nil
+		closed := q.closed
+		q.mu.Unlock()
+
+		for _, chunk := range chunks {
+			if _, err := conn.Write(chunk); err != nil {
+				q.mu.Lock()
+				q.closed = true
+				q.chunks = nil
+				q.mu.Unlock()
+				return
+			}
+		}
+		if closed {
+			return
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -17,20 +18,53 @@ import (
 
 // MockRedis is a simple in-memory Redis mock for testing
 type MockRedis struct {
-	data       map[string]mockValue
-	mu         sync.RWMutex
-	shouldFail bool // For testing error scenarios
+	data        map[string]mockValue
+	mu          sync.RWMutex
+	shouldFail  bool // For testing error scenarios
+	scanCursors map[int]string
+	nextCursor  int
+
+	psMu        sync.Mutex
+	subscribers map[*mockSubscriber]struct{}
+
+	// sentinelMasters maps master names to the addresses SENTINEL reports for them
+	sentinelMasters map[string]string
+
+	// INFO state: start time, open connections, and fields overridden with SetInfo
+	started     time.Time
+	connections atomic.Int64
+	info        map[string]string
+
+	// clients holds the per-connection state reported by CLIENT, keyed by client ID
+	clients      map[int64]*mockClient
+	nextClientID int64
 }
 
 type mockValue struct {
 	value     string
 	expiresAt *time.Time
+
+	// Container types; at most one is set and none for plain strings
+	stream *mockStream
+	hash   map[string]string
+	zset   map[string]float64
+	set    map[string]struct{}
+	list   *mockList
+
+	// hll holds the exact members of a HyperLogLog; TYPE reports it as a string like Redis
+	hll map[string]struct{}
 }
 
 // NewMockRedis creates a new mock Redis instance
 func NewMockRedis() *MockRedis {
 	return &MockRedis{
-		data: make(map[string]mockValue),
+		data:            make(map[string]mockValue),
+		scanCursors:     make(map[int]string),
+		subscribers:     make(map[*mockSubscriber]struct{}),
+		sentinelMasters: make(map[string]string),
+		started:         time.Now(),
+		info:            make(map[string]string),
+		clients:         make(map[int64]*mockClient),
 	}
 }
 
@@ -55,16 +89,38 @@ func (m *MockRedis) Dialer() func(context.Context, string, string) (net.Conn, er
 
 // serveConn handles connections to the mock Redis
 func (m *MockRedis) serveConn(conn net.Conn) {
-	defer func() { _ = conn.Close() }()
+	queue := newReplyQueue(conn)
+	sub := newMockSubscriber(queue)
+	client := m.addClient(sub)
+	m.connections.Add(1)
+	defer func() {
+		m.connections.Add(-1)
+		m.removeClient(client)
+		m.removeSubscriber(sub)
+		queue.Close()
+		_ = conn.Close()
+	}()
 
 	reader := bufio.NewReader(conn)
-	writer := bufio.NewWriter(conn)
+	writer := bufio.NewWriter(queue)
 	for {
 		args, err := readCommand(reader)
 		if err != nil {
 			return
 		}
-		if err := m.handleCommand(args, writer); err != nil {
+		if len(args) > 0 && isPubSubCommand(strings.ToUpper(args[0])) {
+			if err := m.handlePubSub(sub, args); err != nil {
+				return
+			}
+			continue
+		}
+		if len(args) > 0 && strings.EqualFold(args[0], "CLIENT") {
+			err = m.handleClient(client, args, writer)
+		} else {
+			err = m.handleCommand(args, writer)
+			m.invalidate(writtenKeys(args))
+		}
+		if err != nil {
 			_ = writer.Flush() // flush error response before closing
 			return
 		}
@@ -96,18 +152,185 @@ func (m *MockRedis) handleCommand(args []string, w *bufio.Writer) error {
 		return m.handleSet(args, w)
 	case "GET":
 		return m.handleGet(args, w)
-	case "DEL":
+	case "MGET":
+		return m.handleMGet(args, w)
+	case "DEL", "UNLINK":
 		return m.handleDel(args, w)
 	case "EXISTS":
 		return m.handleExists(args, w)
 	case "INCR":
 		return m.handleIncr(args, w)
+	case "DECR":
+		if len(args) < 2 {
+			return writeError(w, "invalid args")
+		}
+		return m.writeIncrBy(args[1], -1, w)
+	case "INCRBY":
+		return m.handleIncrBy(args, w, 1)
+	case "DECRBY":
+		return m.handleIncrBy(args, w, -1)
 	case "TTL":
 		return m.handleTTL(args, w)
+	case "PTTL":
+		return m.handlePTTL(args, w)
 	case "EXPIRE":
 		return m.handleExpire(args, w)
+	case "PEXPIRE":
+		return m.handlePExpire(args, w)
+	case "PERSIST":
+		return m.handlePersist(args, w)
+	case "GETEX":
+		return m.handleGetEx(args, w)
+	case "GETDEL":
+		return m.handleGetDel(args, w)
 	case "EVAL":
 		return m.handleEval(args, w)
+	case "SCAN":
+		return m.handleScan(args, w)
+	case "TYPE":
+		return m.handleType(args, w)
+	case "MEMORY":
+		return m.handleMemory(args, w)
+	case "XADD":
+		return m.handleXAdd(args, w)
+	case "XLEN":
+		return m.handleXLen(args, w)
+	case "XRANGE":
+		return m.handleXRange(args, w, false)
+	case "XREVRANGE":
+		return m.handleXRange(args, w, true)
+	case "XDEL":
+		return m.handleXDel(args, w)
+	case "XTRIM":
+		return m.handleXTrim(args, w)
+	case "XGROUP":
+		return m.handleXGroup(args, w)
+	case "XREAD":
+		return m.handleXRead(args, w)
+	case "XREADGROUP":
+		return m.handleXReadGroup(args, w)
+	case "XACK":
+		return m.handleXAck(args, w)
+	case "XPENDING":
+		return m.handleXPending(args, w)
+	case "XAUTOCLAIM":
+		return m.handleXAutoClaim(args, w)
+	case "HSET":
+		return m.handleHSet(args, w, false)
+	case "HSETNX":
+		return m.handleHSet(args, w, true)
+	case "HGET":
+		return m.handleHGet(args, w)
+	case "HMGET":
+		return m.handleHMGet(args, w)
+	case "HDEL":
+		return m.handleHDel(args, w)
+	case "HLEN":
+		return m.handleHLen(args, w)
+	case "HEXISTS":
+		return m.handleHExists(args, w)
+	case "HGETALL":
+		return m.handleHGetAll(args, w)
+	case "HSCAN":
+		return m.handleHScan(args, w)
+	case "HKEYS":
+		return m.handleHKeys(args, w)
+	case "HINCRBY":
+		return m.handleHIncrBy(args, w)
+	case "HINCRBYFLOAT":
+		return m.handleHIncrByFloat(args, w)
+	case "ZADD":
+		return m.handleZAdd(args, w)
+	case "ZINCRBY":
+		return m.handleZIncrBy(args, w)
+	case "ZREM":
+		return m.handleZRem(args, w)
+	case "ZSCORE":
+		return m.handleZScore(args, w)
+	case "ZMSCORE":
+		return m.handleZMScore(args, w)
+	case "ZCARD":
+		return m.handleZCard(args, w)
+	case "ZCOUNT":
+		return m.handleZCount(args, w)
+	case "ZRANK":
+		return m.handleZRank(args, w, false)
+	case "ZREVRANK":
+		return m.handleZRank(args, w, true)
+	case "ZRANGE":
+		return m.handleZRange(args, w, false, false)
+	case "ZREVRANGE":
+		return m.handleZRange(args, w, false, true)
+	case "ZRANGEBYSCORE":
+		return m.handleZRange(args, w, true, false)
+	case "ZREVRANGEBYSCORE":
+		return m.handleZRange(args, w, true, true)
+	case "ZREMRANGEBYSCORE":
+		return m.handleZRemRange(args, w, true)
+	case "ZREMRANGEBYRANK":
+		return m.handleZRemRange(args, w, false)
+	case "LPUSH":
+		return m.handlePush(args, w, true)
+	case "RPUSH":
+		return m.handlePush(args, w, false)
+	case "LPOP":
+		return m.handlePop(args, w, true)
+	case "RPOP":
+		return m.handlePop(args, w, false)
+	case "BLPOP":
+		return m.handleBPop(args, w, true)
+	case "BRPOP":
+		return m.handleBPop(args, w, false)
+	case "LLEN":
+		return m.handleLLen(args, w)
+	case "LRANGE":
+		return m.handleLRange(args, w)
+	case "SADD":
+		return m.handleSAdd(args, w)
+	case "SREM":
+		return m.handleSRem(args, w)
+	case "SMEMBERS":
+		return m.handleSMembers(args, w)
+	case "SISMEMBER":
+		return m.handleSIsMember(args, w)
+	case "SMISMEMBER":
+		return m.handleSMIsMember(args, w)
+	case "SCARD":
+		return m.handleSCard(args, w)
+	case "SSCAN":
+		return m.handleSScan(args, w)
+	case "SUNIONSTORE":
+		return m.handleSStore(args, w, "union")
+	case "SINTERSTORE":
+		return m.handleSStore(args, w, "inter")
+	case "SDIFFSTORE":
+		return m.handleSStore(args, w, "diff")
+	case "SETBIT":
+		return m.handleSetBit(args, w)
+	case "GETBIT":
+		return m.handleGetBit(args, w)
+	case "BITCOUNT":
+		return m.handleBitCount(args, w)
+	case "PFADD":
+		return m.handlePFAdd(args, w)
+	case "PFCOUNT":
+		return m.handlePFCount(args, w)
+	case "PFMERGE":
+		return m.handlePFMerge(args, w)
+	case "GEOADD":
+		return m.handleGeoAdd(args, w)
+	case "GEOPOS":
+		return m.handleGeoPos(args, w)
+	case "GEODIST":
+		return m.handleGeoDist(args, w)
+	case "GEOSEARCH":
+		return m.handleGeoSearch(args, w)
+	case "PUBLISH":
+		return m.handlePublish(args, w)
+	case "SENTINEL":
+		return m.handleSentinel(args, w)
+	case "INFO":
+		return m.handleInfo(args, w)
 	case "FLUSHDB":
 		m.mu.Lock()
 		m.data = make(map[string]mockValue)
@@ -127,8 +350,10 @@ func (m *MockRedis) handleSet(args []string, w *bufio.Writer) error {
 	value := args[2]
 	ttl := time.Duration(0)
 	nx := false
+	xx := false
+	keepTTL := false
 
-	// Parse options (SET key value [EX seconds|PX milliseconds] [NX|XX])
+	// Parse options (SET key value [EX seconds|PX milliseconds|KEEPTTL] [NX|XX])
 	for i := 3; i < len(args); i++ {
 		opt := strings.ToUpper(args[i])
 		if opt == "EX" && i+1 < len(args) {
@@ -141,6 +366,10 @@ func (m *MockRedis) handleSet(args []string, w *bufio.Writer) error {
 			i++ // Skip the next argument
 		} else if opt == "NX" {
 			nx = true
+		} else if opt == "XX" {
+			xx = true
+		} else if opt == "KEEPTTL" {
+			keepTTL = true
 		}
 	}
 
@@ -155,27 +384,18 @@ func (m *MockRedis) handleSet(args []string, w *bufio.Writer) error {
 		exists = false
 	}
 
-	if nx {
-		// NX option: only set if key doesn't exist
-		if exists {
-			// Key exists, return nil (go-redis SetNX interprets this as false)
-			return writeNil(w)
-		}
-		// Key doesn't exist, set it
-		var expiresAt *time.Time
-		if ttl > 0 {
-			exp := time.Now().Add(ttl)
-			expiresAt = &exp
-		}
-		m.data[key] = mockValue{value: value, expiresAt: expiresAt}
-		// For SET NX, return OK when successful
-		return writeSimpleString(w, "OK")
+	// NX: only set if key doesn't exist; XX: only set if it does
+	// A skipped SET returns nil (go-redis SetNX/SetXX interpret this as false)
+	if (nx && exists) || (xx && !exists) {
+		return writeNil(w)
 	}
 
 	var expiresAt *time.Time
 	if ttl > 0 {
 		exp := time.Now().Add(ttl)
 		expiresAt = &exp
+	} else if keepTTL && exists {
+		expiresAt = val.expiresAt
 	}
 	m.data[key] = mockValue{value: value, expiresAt: expiresAt}
 
@@ -250,25 +470,73 @@ func (m *MockRedis) handleIncr(args []string, w *bufio.Writer) error {
 	if len(args) < 2 {
 		return writeError(w, "invalid args")
 	}
+	return m.writeIncrBy(args[1], 1, w)
+}
 
-	key := args[1]
+// handleIncrBy serves INCRBY and DECRBY; sign is -1 for DECRBY
+func (m *MockRedis) handleIncrBy(args []string, w *bufio.Writer, sign int64) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+	delta, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return writeError(w, "value is not an integer or out of range")
+	}
+	return m.writeIncrBy(args[1], sign*delta, w)
+}
+
+func (m *MockRedis) writeIncrBy(key string, delta int64, w *bufio.Writer) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	val, ok := m.data[key]
+	num, err := m.incrByLocked(key, delta)
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	return writeInt(w, num)
+}
+
+// incrByLocked adds delta to the integer at key, keeping its TTL
+// The caller must hold m.mu for writing
+func (m *MockRedis) incrByLocked(key string, delta int64) (int64, error) {
+	val, ok := m.lookup(key)
 	var num int64
 	if ok {
 		var err error
 		num, err = strconv.ParseInt(val.value, 10, 64)
 		if err != nil {
-			return writeError(w, "value is not an integer")
+			return 0, errors.New("value is not an integer")
 		}
-	} else {
-		num = 0
 	}
 
-	num++
+	num += delta
 	m.data[key] = mockValue{value: strconv.FormatInt(num, 10), expiresAt: val.expiresAt}
+	return num, nil
+}
+
+// evalCacheIncr emulates the cache package's counter script: add ARGV[1] to KEYS[1] and, when
+// the key has no TTL, expire it in ARGV[2] milliseconds
+func (m *MockRedis) evalCacheIncr(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 1 || len(argv) < 2 {
+		return writeError(w, "invalid args")
+	}
+	delta, err1 := strconv.ParseInt(argv[0], 10, 64)
+	ttl, err2 := strconv.ParseInt(argv[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return writeError(w, "value is not an integer or out of range")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	num, err := m.incrByLocked(keys[0], delta)
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	if val := m.data[keys[0]]; val.expiresAt == nil && ttl > 0 {
+		expiresAt := time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		val.expiresAt = &expiresAt
+		m.data[keys[0]] = val
+	}
 	return writeInt(w, num)
 }
 
@@ -339,33 +607,71 @@ func (m *MockRedis) handleEval(args []string, w *bufio.Writer) error {
 		return writeError(w, "invalid numkeys")
 	}
 
-	if numKeys < 1 || len(args) < 3+numKeys+1 {
+	if numKeys < 1 || len(args) < 3+numKeys {
 		return writeError(w, "invalid args")
 	}
 
 	key := args[3]
 	argv := args[3+numKeys:]
 
+	// Scripts marked with a redis-kit name are matched before the generic unlock check
+	keys := args[3 : 3+numKeys]
+	switch {
+	case strings.Contains(script, "redis-kit:queue-deadletter"):
+		return m.evalQueueDeadLetter(keys, argv, w)
+	case strings.Contains(script, "redis-kit:schedule-pop"):
+		return m.evalSchedulePop(keys, argv, w)
+	case strings.Contains(script, "redis-kit:counter-flush"):
+		return m.evalCounterFlush(keys, argv, w)
+	case strings.Contains(script, "redis-kit:verifycode-attempt"):
+		return m.evalVerifyCodeAttempt(keys, w)
+	case strings.Contains(script, "redis-kit:config-set"):
+		return m.evalConfigSet(keys, argv, w)
+	case strings.Contains(script, "redis-kit:compare-set"):
+		return m.evalCompareAndSet(keys, argv, w)
+	case strings.Contains(script, "redis-kit:compare-del"):
+		return m.evalCompareDel(key, argv, w)
+	case strings.Contains(script, "redis-kit:workerpool-claim"):
+		return m.evalWorkerpoolClaim(keys, argv, w)
+	case strings.Contains(script, "redis-kit:workerpool-requeue"):
+		return m.evalWorkerpoolRequeue(keys, argv, w)
+	case strings.Contains(script, "redis-kit:workerpool-"):
+		return m.evalWorkerpoolSettle(script, keys, argv, w)
+	case strings.Contains(script, "redis-kit:pqueue-pop"):
+		return m.evalPQueuePop(keys, argv, w)
+	case strings.Contains(script, "redis-kit:cache-tag-add"):
+		return m.evalCacheTagAdd(keys, argv, w)
+	case strings.Contains(script, "redis-kit:cache-hash-set"):
+		return m.evalCacheHashSet(keys, argv, w)
+	case strings.Contains(script, "redis-kit:cache-fill"):
+		return m.evalCacheFill(keys, argv, w)
+	case strings.Contains(script, "redis-kit:cache-rename"):
+		return m.evalCacheMove(keys, nil, w)
+	case strings.Contains(script, "redis-kit:cache-copy"):
+		return m.evalCacheMove(keys, argv, w)
+	case strings.Contains(script, "redis-kit:cache-incr"):
+		return m.evalCacheIncr(keys, argv, w)
+	case strings.Contains(script, "redis-kit:lru-set"):
+		return m.evalLRUSet(keys, argv, w)
+	case strings.Contains(script, "redis-kit:lru-get"):
+		return m.evalLRUGet(keys, w)
+	case strings.Contains(script, "redis-kit:notify-record"):
+		return m.evalNotifyRecord(keys, argv, w)
+	case strings.Contains(script, "redis-kit:notify-drain"):
+		return m.evalNotifyDrain(keys, argv, w)
+	case strings.Contains(script, "redis-kit:votes-vote"):
+		return m.evalVotes(keys, argv, w, true)
+	case strings.Contains(script, "redis-kit:votes-unvote"):
+		return m.evalVotes(keys, argv, w, false)
+	case strings.Contains(script, "redis-kit:statemachine-transition"):
+		return m.evalStateMachineTransition(keys, argv, w)
+	case strings.Contains(script, "redis-kit:registry-prune"):
+		return m.evalRegistryPrune(keys, argv, w)
+	}
+
 	// Handle the unlock script: if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end
 	if strings.Contains(script, "get") && strings.Contains(script, "del") {
-		m.mu.Lock()
-		defer m.mu.Unlock()
-
-		if len(argv) < 1 {
-			return writeError(w, "invalid args")
-		}
-		lockValue := argv[0]
-		val, ok := m.data[key]
-		if !ok {
-			return writeInt(w, 0)
-		}
-
-		if val.value == lockValue {
-			delete(m.data, key)
-			return writeInt(w, 1)
-		}
-
-		return writeInt(w, 0)
+		return m.evalCompareDel(key, argv, w)
 	}
 
 	if strings.Contains(script, "redis-kit:ratelimit") {
@@ -455,6 +761,22 @@ func (m *MockRedis) handleEval(args []string, w *bufio.Writer) error {
 	return writeError(w, "unsupported script")
 }
 
+// evalCompareDel deletes key if it holds ARGV[1], as the lock's unlock script does
+func (m *MockRedis) evalCompareDel(key string, argv []string, w *bufio.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(argv) < 1 {
+		return writeError(w, "invalid args")
+	}
+	val, ok := m.data[key]
+	if !ok || val.value != argv[0] {
+		return writeInt(w, 0)
+	}
+	delete(m.data, key)
+	return writeInt(w, 1)
+}
+
 // NewMockRedisClient creates a Redis client that uses the mock
 func NewMockRedisClient() (*redis.Client, *MockRedis) {
 	mock := NewMockRedis()
@@ -465,6 +787,49 @@ func NewMockRedisClient() (*redis.Client, *MockRedis) {
 	return client, mock
 }
 
+// NewMockRingClient creates a single-shard Redis ring that uses the mock, for code accepting a
+// redis.UniversalClient that must work with more than *redis.Client
+func NewMockRingClient() (*redis.Ring, *MockRedis) {
+	mock := NewMockRedis()
+	ring := redis.NewRing(&redis.RingOptions{
+		Addrs:  map[string]string{"shard": "mock"},
+		Dialer: mock.dialer,
+	})
+	return ring, mock
+}
+
+// NewMockClusterClient creates a Redis cluster client over n mocks, each a master serving an
+// equal range of the hash slots, for code that must cover every node of a cluster. Mocks do not
+// check slots themselves: a command sent to the wrong node runs there
+func NewMockClusterClient(n int) (*redis.ClusterClient, []*MockRedis) {
+	if n <= 0 {
+		n = 1
+	}
+	mocks := make([]*MockRedis, n)
+	slots := make([]redis.ClusterSlot, n)
+	for i := range mocks {
+		mocks[i] = NewMockRedis()
+		slots[i] = redis.ClusterSlot{
+			Start: i * 16384 / n,
+			End:   (i+1)*16384/n - 1,
+			Nodes: []redis.ClusterNode{{Addr: fmt.Sprintf("mock%d", i)}},
+		}
+	}
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func(context.Context) ([]redis.ClusterSlot, error) {
+			return slots, nil
+		},
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var i int
+			if _, err := fmt.Sscanf(addr, "mock%d", &i); err != nil || i >= n {
+				return nil, fmt.Errorf("unknown mock node %q", addr)
+			}
+			return mocks[i].dialer(ctx, network, addr)
+		},
+	})
+	return cluster, mocks
+}
+
 // Helper functions for RESP protocol
 
 func readCommand(r *bufio.Reader) ([]string, error) {
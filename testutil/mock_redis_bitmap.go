@@ -0,0 +1,109 @@
+package testutil
+
+import (
+	"bufio"
+	"errors"
+	"math/bits"
+	"strconv"
+)
+
+// maxBitOffset is the largest offset SETBIT accepts (512 MB strings, as in Redis)
+const maxBitOffset = 1<<32 - 1
+
+// stringLocked returns the string value at key
+// The caller must hold m.mu for writing
+func (m *MockRedis) stringLocked(key string) (mockValue, bool, error) {
+	val, ok := m.lookup(key)
+	if ok && val.typeName() != "string" {
+		return mockValue{}, false, errors.New(wrongTypeError)
+	}
+	return val, ok, nil
+}
+
+func parseBitOffset(s string) (int64, bool) {
+	offset, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || offset < 0 || offset > maxBitOffset {
+		return 0, false
+	}
+	return offset, true
+}
+
+func (m *MockRedis) handleSetBit(args []string, w *bufio.Writer) error {
+	if len(args) != 4 {
+		return writeError(w, "wrong number of arguments for 'setbit' command")
+	}
+	offset, ok := parseBitOffset(args[2])
+	if !ok {
+		return writeError(w, "bit offset is not an integer or out of range")
+	}
+	if args[3] != "0" && args[3] != "1" {
+		return writeError(w, "bit is not an integer or out of range")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, _, err := m.stringLocked(args[1])
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+
+	data := []byte(val.value)
+	idx := int(offset / 8)
+	if idx >= len(data) {
+		data = append(data, make([]byte, idx-len(data)+1)...)
+	}
+	mask := byte(1) << (7 - uint(offset%8))
+	old := int64(0)
+	if data[idx]&mask != 0 {
+		old = 1
+	}
+	if args[3] == "1" {
+		data[idx] |= mask
+	} else {
+		data[idx] &^= mask
+	}
+	m.data[args[1]] = mockValue{value: string(data), expiresAt: val.expiresAt}
+	return writeInt(w, old)
+}
+
+func (m *MockRedis) handleGetBit(args []string, w *bufio.Writer) error {
+	if len(args) != 3 {
+		return writeError(w, "wrong number of arguments for 'getbit' command")
+	}
+	offset, ok := parseBitOffset(args[2])
+	if !ok {
+		return writeError(w, "bit offset is not an integer or out of range")
+	}
+
+	m.mu.Lock()
+	val, _, err := m.stringLocked(args[1])
+	m.mu.Unlock()
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+
+	idx := int(offset / 8)
+	if idx >= len(val.value) {
+		return writeInt(w, 0)
+	}
+	return writeInt(w, int64(val.value[idx]>>(7-uint(offset%8))&1))
+}
+
+func (m *MockRedis) handleBitCount(args []string, w *bufio.Writer) error {
+	if len(args) != 2 {
+		return writeError(w, "BITCOUNT with a range is not supported by the mock")
+	}
+
+	m.mu.Lock()
+	val, _, err := m.stringLocked(args[1])
+	m.mu.Unlock()
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+
+	count := 0
+	for i := 0; i < len(val.value); i++ {
+		count += bits.OnesCount8(val.value[i])
+	}
+	return writeInt(w, int64(count))
+}
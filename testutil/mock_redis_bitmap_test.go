@@ -0,0 +1,46 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMockRedis_Bitmap(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if old, err := client.SetBit(ctx, "bits", 7, 1).Result(); err != nil || old != 0 {
+		t.Fatalf("SetBit() = %d, %v, want 0", old, err)
+	}
+	if old, _ := client.SetBit(ctx, "bits", 7, 1).Result(); old != 1 {
+		t.Errorf("SetBit() on a set bit = %d, want 1", old)
+	}
+	_ = client.SetBit(ctx, "bits", 100, 1)
+	if v, _ := client.Get(ctx, "bits").Result(); len(v) != 13 || v[0] != 0x01 {
+		t.Errorf("Get() = %q, want 13 bytes starting with 0x01", v)
+	}
+	if bit, _ := client.GetBit(ctx, "bits", 100).Result(); bit != 1 {
+		t.Errorf("GetBit(100) = %d, want 1", bit)
+	}
+	if bit, _ := client.GetBit(ctx, "bits", 1000).Result(); bit != 0 {
+		t.Errorf("GetBit() past the end = %d, want 0", bit)
+	}
+	if n, _ := client.BitCount(ctx, "bits", nil).Result(); n != 2 {
+		t.Errorf("BitCount() = %d, want 2", n)
+	}
+	if old, _ := client.SetBit(ctx, "bits", 7, 0).Result(); old != 1 {
+		t.Errorf("SetBit() clearing = %d, want 1", old)
+	}
+	if n, _ := client.BitCount(ctx, "bits", nil).Result(); n != 1 {
+		t.Errorf("BitCount() after clearing = %d, want 1", n)
+	}
+
+	if err := client.SetBit(ctx, "bits", -1, 1).Err(); err == nil {
+		t.Error("SetBit() negative offset error = nil, want error")
+	}
+	client.HSet(ctx, "h", "f", "v")
+	if err := client.GetBit(ctx, "h", 0).Err(); err == nil {
+		t.Error("GetBit() on a hash error = nil, want WRONGTYPE")
+	}
+}
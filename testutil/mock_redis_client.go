@@ -0,0 +1,115 @@
+package testutil
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// mockClient is the per-connection state reported by CLIENT
+type mockClient struct {
+	id      int64
+	name    string
+	created time.Time
+	sub     *mockSubscriber
+
+	// Client-side caching state set with CLIENT TRACKING
+	tracking bool
+	redirect int64
+	prefixes []string
+}
+
+// addClient registers a new connection and assigns it the next client ID
+func (m *MockRedis) addClient(sub *mockSubscriber) *mockClient {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextClientID++
+	c := &mockClient{id: m.nextClientID, created: time.Now(), sub: sub}
+	m.clients[c.id] = c
+	return c
+}
+
+// removeClient forgets a closed connection
+func (m *MockRedis) removeClient(c *mockClient) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.clients, c.id)
+}
+
+// clientIDsLocked returns the IDs of the open connections in ascending order
+func (m *MockRedis) clientIDsLocked() []int64 {
+	ids := make([]int64, 0, len(m.clients))
+	for id := range m.clients {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// handleClient serves CLIENT SETNAME, GETNAME, ID, LIST, SETINFO, and TRACKING for connection c
+func (m *MockRedis) handleClient(c *mockClient, args []string, w *bufio.Writer) error {
+	m.mu.RLock()
+	shouldFail := m.shouldFail
+	m.mu.RUnlock()
+	if shouldFail {
+		return writeError(w, "mock redis failure")
+	}
+	if len(args) < 2 {
+		return writeError(w, "wrong number of arguments for 'client' command")
+	}
+
+	switch strings.ToUpper(args[1]) {
+	case "SETNAME":
+		if len(args) != 3 {
+			return writeError(w, "wrong number of arguments for 'client|setname' command")
+		}
+		if strings.ContainsAny(args[2], " \n") {
+			return writeError(w, "Client names cannot contain spaces, newlines or special characters.")
+		}
+		m.mu.Lock()
+		c.name = args[2]
+		m.mu.Unlock()
+		return writeSimpleString(w, "OK")
+	case "GETNAME":
+		m.mu.RLock()
+		name := c.name
+		m.mu.RUnlock()
+		if name == "" {
+			return writeNil(w)
+		}
+		return writeBulkString(w, name)
+	case "ID":
+		return writeInt(w, c.id)
+	case "LIST":
+		m.mu.RLock()
+		var b strings.Builder
+		for _, id := range m.clientIDsLocked() {
+			client := m.clients[id]
+			fmt.Fprintf(&b, "id=%d addr=mock:%d name=%s age=%d db=0\n",
+				client.id, client.id, client.name, int64(time.Since(client.created).Seconds()))
+		}
+		m.mu.RUnlock()
+		return writeBulkString(w, b.String())
+	case "SETINFO":
+		return writeSimpleString(w, "OK")
+	case "TRACKING":
+		return m.handleTracking(c, args, w)
+	default:
+		return writeError(w, "unknown client subcommand: "+args[1])
+	}
+}
+
+// ClientNames returns the names of the open connections set with CLIENT SETNAME, including
+// empty names, in connection order
+func (m *MockRedis) ClientNames() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := m.clientIDsLocked()
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = m.clients[id].name
+	}
+	return names
+}
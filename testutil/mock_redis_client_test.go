@@ -0,0 +1,103 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMockRedis_Client(t *testing.T) {
+	client, mock := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	conn := client.Conn()
+	defer func() { _ = conn.Close() }()
+
+	if err := conn.ClientGetName(ctx).Err(); !errors.Is(err, redis.Nil) {
+		t.Errorf("ClientGetName() unnamed error = %v, want redis.Nil", err)
+	}
+	if err := conn.ClientSetName(ctx, "worker-1").Err(); err != nil {
+		t.Fatalf("ClientSetName() error = %v, want nil", err)
+	}
+	if name, _ := conn.ClientGetName(ctx).Result(); name != "worker-1" {
+		t.Errorf("ClientGetName() = %q, want worker-1", name)
+	}
+	if err := conn.ClientSetName(ctx, "bad name").Err(); err == nil {
+		t.Error("ClientSetName() with a space error = nil, want error")
+	}
+
+	id, err := conn.ClientID(ctx).Result()
+	if err != nil || id <= 0 {
+		t.Errorf("ClientID() = %d, %v, want a positive ID", id, err)
+	}
+	list, _ := conn.ClientList(ctx).Result()
+	if !strings.Contains(list, "name=worker-1") {
+		t.Errorf("ClientList() = %q, want the named connection", list)
+	}
+	if names := mock.ClientNames(); !strings.Contains(strings.Join(names, ","), "worker-1") {
+		t.Errorf("ClientNames() = %v, want worker-1 among them", names)
+	}
+
+	if err := conn.Do(ctx, "client", "nope").Err(); err == nil {
+		t.Error("CLIENT NOPE error = nil, want error")
+	}
+	mock.SetShouldFail(true)
+	if err := conn.ClientID(ctx).Err(); err == nil {
+		t.Error("ClientID() error = nil, want error")
+	}
+}
+
+func TestMockRedis_ClientTracking(t *testing.T) {
+	client, mock := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	conn := client.Conn()
+	defer func() { _ = conn.Close() }()
+	if err := conn.Do(ctx, "client", "tracking", "on").Err(); err == nil {
+		t.Error("CLIENT TRACKING without BCAST error = nil, want error")
+	}
+	if err := conn.Do(ctx, "client", "tracking", "on", "redirect", 9999, "bcast").Err(); err == nil {
+		t.Error("CLIENT TRACKING to an unknown client error = nil, want error")
+	}
+
+	// A RESP2 connection enables tracking redirected to itself, then subscribes
+	listener := redis.NewClient(&redis.Options{
+		Addr:   "mock",
+		Dialer: mock.Dialer(),
+		OnConnect: func(ctx context.Context, cn *redis.Conn) error {
+			id, err := cn.ClientID(ctx).Result()
+			if err != nil {
+				return err
+			}
+			return cn.Do(ctx, "client", "tracking", "on", "redirect", id, "bcast", "prefix", "user:").Err()
+		},
+	})
+	defer func() { _ = listener.Close() }()
+	ps := listener.Subscribe(ctx, trackingChannel)
+	defer func() { _ = ps.Close() }()
+	if _, err := ps.Receive(ctx); err != nil {
+		t.Fatalf("Subscribe() error = %v, want nil", err)
+	}
+
+	_ = client.Set(ctx, "order:1", "x", 0).Err()
+	_ = client.Del(ctx, "user:1", "user:2").Err()
+	msg, err := ps.ReceiveMessage(ctx)
+	if err != nil || len(msg.PayloadSlice) != 2 || msg.PayloadSlice[0] != "user:1" {
+		t.Errorf("invalidation = %+v, %v, want user:1 and user:2 only", msg, err)
+	}
+
+	// A flush is reported with a nil payload, which go-redis does not parse
+	_ = client.FlushDB(ctx).Err()
+	if _, err := ps.ReceiveMessage(ctx); err == nil {
+		t.Error("ReceiveMessage() after FLUSHDB error = nil, want the unparsed nil payload")
+	}
+
+	if err := conn.Do(ctx, "client", "tracking", "off").Err(); err != nil {
+		t.Errorf("CLIENT TRACKING OFF error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,383 @@
+package testutil
+
+import (
+	"bufio"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Geo members are stored in a sorted set scored by a 52-bit interleaved geohash, as in Redis
+
+const (
+	geoStep      = 26
+	geoLatMin    = -85.05112878
+	geoLatMax    = 85.05112878
+	geoLonMin    = -180.0
+	geoLonMax    = 180.0
+	earthRadiusM = 6372797.560856
+)
+
+func geoEncode(lon, lat float64) uint64 {
+	latOffset := uint64((lat - geoLatMin) / (geoLatMax - geoLatMin) * (1 << geoStep))
+	lonOffset := uint64((lon - geoLonMin) / (geoLonMax - geoLonMin) * (1 << geoStep))
+	var hash uint64
+	for i := 0; i < geoStep; i++ {
+		hash |= (latOffset >> i & 1) << (2 * i)
+		hash |= (lonOffset >> i & 1) << (2*i + 1)
+	}
+	return hash
+}
+
+// geoDecode returns the center of the geohash cell
+func geoDecode(hash uint64) (float64, float64) {
+	var latOffset, lonOffset uint64
+	for i := 0; i < geoStep; i++ {
+		latOffset |= (hash >> (2 * i) & 1) << i
+		lonOffset |= (hash >> (2*i + 1) & 1) << i
+	}
+	cell := 1.0 / (1 << geoStep)
+	lat := geoLatMin + (float64(latOffset)+0.5)*cell*(geoLatMax-geoLatMin)
+	lon := geoLonMin + (float64(lonOffset)+0.5)*cell*(geoLonMax-geoLonMin)
+	return lon, lat
+}
+
+// geoDistance returns the haversine distance in meters
+func geoDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	lat1r, lat2r := lat1*math.Pi/180, lat2*math.Pi/180
+	u := math.Sin((lat2r - lat1r) / 2)
+	v := math.Sin((lon2 - lon1) * math.Pi / 180 / 2)
+	return 2 * earthRadiusM * math.Asin(math.Sqrt(u*u+math.Cos(lat1r)*math.Cos(lat2r)*v*v))
+}
+
+func geoUnitFactor(unit string) (float64, bool) {
+	switch strings.ToLower(unit) {
+	case "m":
+		return 1, true
+	case "km":
+		return 1000, true
+	case "mi":
+		return 1609.34, true
+	case "ft":
+		return 0.3048, true
+	}
+	return 0, false
+}
+
+func formatGeoFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (m *MockRedis) handleGeoAdd(args []string, w *bufio.Writer) error {
+	if len(args) < 5 || (len(args)-2)%3 != 0 {
+		return writeError(w, "wrong number of arguments for 'geoadd' command")
+	}
+
+	type point struct {
+		member string
+		score  float64
+	}
+	points := make([]point, 0, (len(args)-2)/3)
+	for i := 2; i+2 < len(args); i += 3 {
+		lon, err1 := strconv.ParseFloat(args[i], 64)
+		lat, err2 := strconv.ParseFloat(args[i+1], 64)
+		if err1 != nil || err2 != nil {
+			return writeError(w, "value is not a valid float")
+		}
+		if lon < geoLonMin || lon > geoLonMax || lat < geoLatMin || lat > geoLatMax {
+			return writeError(w, "invalid longitude,latitude pair "+args[i]+","+args[i+1])
+		}
+		points = append(points, point{member: args[i+2], score: float64(geoEncode(lon, lat))})
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(args[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	added := 0
+	for _, p := range points {
+		if _, ok := zset[p.member]; !ok {
+			added++
+		}
+		zset[p.member] = p.score
+	}
+	return writeInt(w, int64(added))
+}
+
+func (m *MockRedis) handleGeoPos(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "wrong number of arguments for 'geopos' command")
+	}
+
+	m.mu.Lock()
+	zset, err := m.zsetLocked(args[1], false)
+	scores := make([]*float64, len(args)-2)
+	if err == nil {
+		for i, member := range args[2:] {
+			if score, ok := zset[member]; ok {
+				scores[i] = &score
+			}
+		}
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+
+	if err := writeArrayLen(w, len(scores)); err != nil {
+		return err
+	}
+	for _, score := range scores {
+		if score == nil {
+			if err := writeNilArray(w); err != nil {
+				return err
+			}
+			continue
+		}
+		lon, lat := geoDecode(uint64(*score))
+		if err := writeArrayLen(w, 2); err != nil {
+			return err
+		}
+		if err := writeBulkString(w, formatGeoFloat(lon)); err != nil {
+			return err
+		}
+		if err := writeBulkString(w, formatGeoFloat(lat)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockRedis) handleGeoDist(args []string, w *bufio.Writer) error {
+	if len(args) != 4 && len(args) != 5 {
+		return writeError(w, "wrong number of arguments for 'geodist' command")
+	}
+	factor := 1.0
+	if len(args) == 5 {
+		var ok bool
+		if factor, ok = geoUnitFactor(args[4]); !ok {
+			return writeError(w, "unsupported unit provided. please use M, KM, FT, MI")
+		}
+	}
+
+	m.mu.Lock()
+	zset, err := m.zsetLocked(args[1], false)
+	s1, ok1 := zset[args[2]]
+	s2, ok2 := zset[args[3]]
+	m.mu.Unlock()
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if !ok1 || !ok2 {
+		return writeNil(w)
+	}
+
+	lon1, lat1 := geoDecode(uint64(s1))
+	lon2, lat2 := geoDecode(uint64(s2))
+	return writeBulkString(w, strconv.FormatFloat(geoDistance(lon1, lat1, lon2, lat2)/factor, 'f', 4, 64))
+}
+
+type geoHit struct {
+	member   string
+	hash     uint64
+	lon, lat float64
+	dist     float64
+}
+
+// handleGeoSearch serves GEOSEARCH key FROMMEMBER|FROMLONLAT BYRADIUS|BYBOX [ASC|DESC] [COUNT n [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH]
+func (m *MockRedis) handleGeoSearch(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "wrong number of arguments for 'geosearch' command")
+	}
+
+	var (
+		fromMember           string
+		hasMember, hasLonLat bool
+		lon, lat             float64
+		radius               float64
+		width, height        float64
+		byRadius, byBox      bool
+		factor               = 1.0
+		sortDir              string
+		count                = -1
+		withCoord, withDist  bool
+		withHash             bool
+	)
+	parseFloat := func(i int) (float64, bool) {
+		if i >= len(args) {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(args[i], 64)
+		return f, err == nil
+	}
+	for i := 2; i < len(args); i++ {
+		var ok bool
+		switch strings.ToUpper(args[i]) {
+		case "FROMMEMBER":
+			if i+1 >= len(args) {
+				return writeError(w, "syntax error")
+			}
+			fromMember, hasMember = args[i+1], true
+			i++
+		case "FROMLONLAT":
+			var ok2 bool
+			lon, ok = parseFloat(i + 1)
+			lat, ok2 = parseFloat(i + 2)
+			if !ok || !ok2 {
+				return writeError(w, "value is not a valid float")
+			}
+			hasLonLat = true
+			i += 2
+		case "BYRADIUS":
+			if radius, ok = parseFloat(i + 1); !ok || i+2 >= len(args) {
+				return writeError(w, "syntax error")
+			}
+			if factor, ok = geoUnitFactor(args[i+2]); !ok {
+				return writeError(w, "unsupported unit provided. please use M, KM, FT, MI")
+			}
+			byRadius = true
+			i += 2
+		case "BYBOX":
+			var ok2 bool
+			width, ok = parseFloat(i + 1)
+			height, ok2 = parseFloat(i + 2)
+			if !ok || !ok2 || i+3 >= len(args) {
+				return writeError(w, "syntax error")
+			}
+			if factor, ok = geoUnitFactor(args[i+3]); !ok {
+				return writeError(w, "unsupported unit provided. please use M, KM, FT, MI")
+			}
+			byBox = true
+			i += 3
+		case "ASC", "DESC":
+			sortDir = strings.ToUpper(args[i])
+		case "COUNT":
+			if i+1 >= len(args) {
+				return writeError(w, "syntax error")
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				return writeError(w, "COUNT must be > 0")
+			}
+			count = n
+			i++
+			if i+1 < len(args) && strings.EqualFold(args[i+1], "ANY") {
+				i++
+			}
+		case "WITHCOORD":
+			withCoord = true
+		case "WITHDIST":
+			withDist = true
+		case "WITHHASH":
+			withHash = true
+		default:
+			return writeError(w, "syntax error")
+		}
+	}
+	if hasMember == hasLonLat {
+		return writeError(w, "exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOSEARCH")
+	}
+	if byRadius == byBox {
+		return writeError(w, "exactly one of BYRADIUS and BYBOX can be specified for GEOSEARCH")
+	}
+
+	m.mu.Lock()
+	zset, err := m.zsetLocked(args[1], false)
+	if err != nil {
+		m.mu.Unlock()
+		return writeRawError(w, err.Error())
+	}
+	if hasMember {
+		score, ok := zset[fromMember]
+		if !ok {
+			m.mu.Unlock()
+			return writeError(w, "could not decode requested zset member")
+		}
+		lon, lat = geoDecode(uint64(score))
+	}
+	var hits []geoHit
+	for member, score := range zset {
+		hash := uint64(score)
+		plon, plat := geoDecode(hash)
+		dist := geoDistance(lon, lat, plon, plat)
+		if byRadius && dist > radius*factor {
+			continue
+		}
+		if byBox && (geoDistance(plon, plat, plon, lat) > height*factor/2 ||
+			geoDistance(plon, plat, lon, plat) > width*factor/2) {
+			continue
+		}
+		hits = append(hits, geoHit{member: member, hash: hash, lon: plon, lat: plat, dist: dist / factor})
+	}
+	m.mu.Unlock()
+
+	// COUNT without a sort order still returns the nearest matches, like Redis does
+	if sortDir == "" && count > 0 {
+		sortDir = "ASC"
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].dist != hits[j].dist {
+			if sortDir == "DESC" {
+				return hits[i].dist > hits[j].dist
+			}
+			return hits[i].dist < hits[j].dist
+		}
+		return hits[i].member < hits[j].member
+	})
+	if count > 0 && count < len(hits) {
+		hits = hits[:count]
+	}
+
+	return writeGeoHits(w, hits, withCoord, withDist, withHash)
+}
+
+func writeGeoHits(w *bufio.Writer, hits []geoHit, withCoord, withDist, withHash bool) error {
+	if err := writeArrayLen(w, len(hits)); err != nil {
+		return err
+	}
+	fields := 1
+	for _, with := range []bool{withCoord, withDist, withHash} {
+		if with {
+			fields++
+		}
+	}
+	for _, hit := range hits {
+		if fields == 1 {
+			if err := writeBulkString(w, hit.member); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeArrayLen(w, fields); err != nil {
+			return err
+		}
+		if err := writeBulkString(w, hit.member); err != nil {
+			return err
+		}
+		if withDist {
+			if err := writeBulkString(w, strconv.FormatFloat(hit.dist, 'f', 4, 64)); err != nil {
+				return err
+			}
+		}
+		if withHash {
+			if err := writeInt(w, int64(hit.hash)); err != nil {
+				return err
+			}
+		}
+		if withCoord {
+			if err := writeArrayLen(w, 2); err != nil {
+				return err
+			}
+			if err := writeBulkString(w, formatGeoFloat(hit.lon)); err != nil {
+				return err
+			}
+			if err := writeBulkString(w, formatGeoFloat(hit.lat)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package testutil
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMockRedis_Geo(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	n, err := client.GeoAdd(ctx, "sicily",
+		&redis.GeoLocation{Name: "Palermo", Longitude: 13.361389, Latitude: 38.115556},
+		&redis.GeoLocation{Name: "Catania", Longitude: 15.087269, Latitude: 37.502669},
+	).Result()
+	if err != nil || n != 2 {
+		t.Fatalf("GeoAdd() = %d, %v, want 2", n, err)
+	}
+	if err := client.GeoAdd(ctx, "sicily", &redis.GeoLocation{Name: "x", Longitude: 200}).Err(); err == nil {
+		t.Error("GeoAdd() invalid longitude error = nil, want error")
+	}
+
+	// Same result as the GEODIST example in the Redis documentation
+	if d, _ := client.GeoDist(ctx, "sicily", "Palermo", "Catania", "km").Result(); math.Abs(d-166.2742) > 0.001 {
+		t.Errorf("GeoDist() = %v km, want 166.2742", d)
+	}
+	if err := client.GeoDist(ctx, "sicily", "Palermo", "Rome", "m").Err(); err != redis.Nil {
+		t.Errorf("GeoDist() missing member error = %v, want redis.Nil", err)
+	}
+
+	pos, err := client.GeoPos(ctx, "sicily", "Palermo", "Rome").Result()
+	if err != nil || len(pos) != 2 || pos[1] != nil {
+		t.Fatalf("GeoPos() = %v, %v, want one position and nil", pos, err)
+	}
+	if math.Abs(pos[0].Longitude-13.361389) > 1e-5 || math.Abs(pos[0].Latitude-38.115556) > 1e-5 {
+		t.Errorf("GeoPos() = %+v, want Palermo", pos[0])
+	}
+
+	locs, err := client.GeoSearchLocation(ctx, "sicily", &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{Longitude: 15, Latitude: 37, Radius: 200, RadiusUnit: "km", Sort: "ASC"},
+		WithCoord:      true,
+		WithDist:       true,
+	}).Result()
+	if err != nil || len(locs) != 2 || locs[0].Name != "Catania" || locs[0].Dist < 56 || locs[0].Dist > 57 {
+		t.Fatalf("GeoSearchLocation() by radius = %+v, %v, want Catania (56.4 km) first", locs, err)
+	}
+
+	names, err := client.GeoSearch(ctx, "sicily", &redis.GeoSearchQuery{
+		Member: "Palermo", BoxWidth: 400, BoxHeight: 40, BoxUnit: "km",
+	}).Result()
+	if err != nil || len(names) != 1 || names[0] != "Palermo" {
+		t.Errorf("GeoSearch() by box = %v, %v, want [Palermo]", names, err)
+	}
+	names, _ = client.GeoSearch(ctx, "sicily", &redis.GeoSearchQuery{
+		Longitude: 15, Latitude: 37, Radius: 500, RadiusUnit: "km", Sort: "DESC", Count: 1,
+	}).Result()
+	if len(names) != 1 || names[0] != "Palermo" {
+		t.Errorf("GeoSearch() DESC COUNT 1 = %v, want [Palermo]", names)
+	}
+	if err := client.GeoSearch(ctx, "sicily", &redis.GeoSearchQuery{Member: "Rome", Radius: 1}).Err(); err == nil {
+		t.Error("GeoSearch() from a missing member error = nil, want error")
+	}
+}
@@ -0,0 +1,480 @@
+package testutil
+
+import (
+	"bufio"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hashLocked returns the hash at key; create makes it if missing
+// The caller must hold m.mu for writing
+func (m *MockRedis) hashLocked(key string, create bool) (map[string]string, error) {
+	val, ok := m.lookup(key)
+	if ok {
+		if val.hash == nil {
+			return nil, errors.New(wrongTypeError)
+		}
+		return val.hash, nil
+	}
+	if !create {
+		return nil, nil
+	}
+	hash := make(map[string]string)
+	m.data[key] = mockValue{hash: hash}
+	return hash, nil
+}
+
+// dropIfEmpty removes a container key once its last element is gone, as Redis does
+// The caller must hold m.mu for writing
+func (m *MockRedis) dropIfEmpty(key string) {
+	val, ok := m.data[key]
+	if !ok {
+		return
+	}
+	if (val.hash != nil && len(val.hash) == 0) || (val.zset != nil && len(val.zset) == 0) || (val.set != nil && len(val.set) == 0) ||
+		(val.list != nil && len(val.list.items) == 0) {
+		delete(m.data, key)
+	}
+}
+
+func (m *MockRedis) handleHSet(args []string, w *bufio.Writer, nx bool) error {
+	if len(args) < 4 || len(args)%2 != 0 {
+		return writeError(w, "wrong number of arguments for 'hset' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, err := m.hashLocked(args[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+
+	added := 0
+	for i := 2; i+1 < len(args); i += 2 {
+		if _, exists := hash[args[i]]; exists {
+			if nx {
+				continue
+			}
+		} else {
+			added++
+		}
+		hash[args[i]] = args[i+1]
+	}
+	return writeInt(w, int64(added))
+}
+
+func (m *MockRedis) handleHGet(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, err := m.hashLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	value, ok := hash[args[2]]
+	if !ok {
+		return writeNil(w)
+	}
+	return writeBulkString(w, value)
+}
+
+func (m *MockRedis) handleHMGet(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, err := m.hashLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if err := writeArrayLen(w, len(args)-2); err != nil {
+		return err
+	}
+	for _, field := range args[2:] {
+		value, ok := hash[field]
+		if !ok {
+			if err := writeNil(w); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeBulkString(w, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockRedis) handleHDel(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, err := m.hashLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	deleted := 0
+	for _, field := range args[2:] {
+		if _, ok := hash[field]; ok {
+			delete(hash, field)
+			deleted++
+		}
+	}
+	m.dropIfEmpty(args[1])
+	return writeInt(w, int64(deleted))
+}
+
+func (m *MockRedis) handleHLen(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, err := m.hashLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	return writeInt(w, int64(len(hash)))
+}
+
+func (m *MockRedis) handleHExists(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, err := m.hashLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if _, ok := hash[args[2]]; ok {
+		return writeInt(w, 1)
+	}
+	return writeInt(w, 0)
+}
+
+func (m *MockRedis) handleHGetAll(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, err := m.hashLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	fields := sortedFields(hash)
+	flat := make([]string, 0, 2*len(fields))
+	for _, field := range fields {
+		flat = append(flat, field, hash[field])
+	}
+	return writeArrayBulk(w, flat)
+}
+
+func (m *MockRedis) handleHKeys(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, err := m.hashLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	return writeArrayBulk(w, sortedFields(hash))
+}
+
+func (m *MockRedis) handleHIncrBy(args []string, w *bufio.Writer) error {
+	if len(args) < 4 {
+		return writeError(w, "invalid args")
+	}
+	delta, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		return writeError(w, "value is not an integer or out of range")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, err := m.hashLocked(args[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	current := int64(0)
+	if value, ok := hash[args[2]]; ok {
+		if current, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return writeError(w, "hash value is not an integer")
+		}
+	}
+	current += delta
+	hash[args[2]] = strconv.FormatInt(current, 10)
+	return writeInt(w, current)
+}
+
+func (m *MockRedis) handleHIncrByFloat(args []string, w *bufio.Writer) error {
+	if len(args) < 4 {
+		return writeError(w, "invalid args")
+	}
+	delta, err := strconv.ParseFloat(args[3], 64)
+	if err != nil {
+		return writeError(w, "value is not a valid float")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, err := m.hashLocked(args[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	current := 0.0
+	if value, ok := hash[args[2]]; ok {
+		if current, err = strconv.ParseFloat(value, 64); err != nil {
+			return writeError(w, "hash value is not a float")
+		}
+	}
+	current += delta
+	hash[args[2]] = strconv.FormatFloat(current, 'f', -1, 64)
+	return writeBulkString(w, hash[args[2]])
+}
+
+// evalCacheHashSet emulates the cache package's hash set script: replace KEYS[1] with a hash
+// of the field and value pairs in ARGV[2:], expiring in ARGV[1] milliseconds when positive
+func (m *MockRedis) evalCacheHashSet(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 1 || len(argv) < 3 || len(argv)%2 != 1 {
+		return writeError(w, "invalid args")
+	}
+	ttl, err := strconv.ParseInt(argv[0], 10, 64)
+	if err != nil {
+		return writeError(w, "value is not an integer or out of range")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash := make(map[string]string, (len(argv)-1)/2)
+	for i := 1; i+1 < len(argv); i += 2 {
+		hash[argv[i]] = argv[i+1]
+	}
+	val := mockValue{hash: hash}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		val.expiresAt = &expiresAt
+	}
+	m.data[keys[0]] = val
+	return writeInt(w, 1)
+}
+
+func sortedFields(hash map[string]string) []string {
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// evalVerifyCodeAttempt emulates the verifycode package's attempt script: count an
+// attempt on the code hash at KEYS[1] and return its digest, salt, and attempt count
+func (m *MockRedis) evalVerifyCodeAttempt(keys []string, w *bufio.Writer) error {
+	if len(keys) < 1 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, err := m.hashLocked(keys[0], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if hash == nil {
+		return writeNil(w)
+	}
+
+	attempts, _ := strconv.ParseInt(hash["a"], 10, 64)
+	attempts++
+	hash["a"] = strconv.FormatInt(attempts, 10)
+
+	if err := writeArrayLen(w, 3); err != nil {
+		return err
+	}
+	if err := writeBulkString(w, hash["h"]); err != nil {
+		return err
+	}
+	if err := writeBulkString(w, hash["s"]); err != nil {
+		return err
+	}
+	return writeInt(w, attempts)
+}
+
+// evalConfigSet emulates the configstore package's set script: bump the version of the
+// document hash at KEYS[1] unless ARGV[1] names a different expected version, store
+// ARGV[2] and ARGV[3], and publish ARGV[5] on channel ARGV[4]; returns the new version or -1
+func (m *MockRedis) evalConfigSet(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 1 || len(argv) < 5 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	hash, err := m.hashLocked(keys[0], false)
+	if err != nil {
+		m.mu.Unlock()
+		return writeRawError(w, err.Error())
+	}
+	current := int64(0)
+	if hash != nil {
+		current, _ = strconv.ParseInt(hash["v"], 10, 64)
+	}
+	if argv[0] != "" {
+		expected, err := strconv.ParseInt(argv[0], 10, 64)
+		if err != nil {
+			m.mu.Unlock()
+			return writeError(w, "invalid version")
+		}
+		if expected != current {
+			m.mu.Unlock()
+			return writeInt(w, -1)
+		}
+	}
+	hash, _ = m.hashLocked(keys[0], true)
+	version := current + 1
+	hash["v"] = strconv.FormatInt(version, 10)
+	hash["d"] = argv[1]
+	hash["t"] = argv[2]
+	m.mu.Unlock()
+
+	m.Publish(argv[3], argv[4])
+	return writeInt(w, version)
+}
+
+// scanPage pages through sorted names for HSCAN-style commands, whose args start at the cursor
+// The cursor is an offset into names, which is stable as long as the container is not modified
+func scanPage(names []string, args []string) (page []string, next int, err error) {
+	cursor, err := strconv.Atoi(args[0])
+	if err != nil || cursor < 0 {
+		return nil, 0, errors.New("invalid cursor")
+	}
+	pattern := "*"
+	count := 10
+	for i := 1; i+1 < len(args); i += 2 {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			if count, err = strconv.Atoi(args[i+1]); err != nil || count <= 0 {
+				return nil, 0, errors.New("invalid count")
+			}
+		}
+	}
+
+	end := min(cursor+count, len(names))
+	if end < len(names) {
+		next = end
+	}
+	for _, name := range names[min(cursor, end):end] {
+		if matchPattern(pattern, name) {
+			page = append(page, name)
+		}
+	}
+	return page, next, nil
+}
+
+func (m *MockRedis) handleHScan(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	hash, err := m.hashLocked(args[1], false)
+	if err != nil {
+		m.mu.Unlock()
+		return writeRawError(w, err.Error())
+	}
+	fields, next, err := scanPage(sortedFields(hash), args[2:])
+	flat := make([]string, 0, 2*len(fields))
+	for _, field := range fields {
+		flat = append(flat, field, hash[field])
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+
+	if err := writeArrayLen(w, 2); err != nil {
+		return err
+	}
+	if err := writeBulkString(w, strconv.Itoa(next)); err != nil {
+		return err
+	}
+	return writeArrayBulk(w, flat)
+}
+
+// evalStateMachineTransition mirrors the statemachine transition script: a compare-and-set of
+// the state field of KEYS[1] that appends the step to the capped history list KEYS[2]
+func (m *MockRedis) evalStateMachineTransition(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 2 || len(argv) < 6 {
+		return writeError(w, "invalid args")
+	}
+	limit, err := strconv.Atoi(argv[4])
+	if err != nil {
+		return writeError(w, "invalid history limit")
+	}
+	ttl, err := strconv.ParseInt(argv[5], 10, 64)
+	if err != nil {
+		return writeError(w, "invalid ttl")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, err := m.hashLocked(keys[0], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	current, exists := hash["state"]
+	switch {
+	case argv[0] == "" && exists:
+		return writeArrayBulk(w, []string{"exists", current})
+	case argv[0] != "" && !exists:
+		return writeArrayBulk(w, []string{"missing", ""})
+	case argv[0] != "" && current != argv[0]:
+		return writeArrayBulk(w, []string{"conflict", current})
+	}
+
+	list, err := m.listLocked(keys[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if hash == nil {
+		hash, _ = m.hashLocked(keys[0], true)
+	}
+	version, _ := strconv.ParseInt(hash["version"], 10, 64)
+	version++
+	hash["version"] = strconv.FormatInt(version, 10)
+	hash["state"] = argv[1]
+	hash["updated"] = argv[2]
+	list.items = append(list.items, strconv.FormatInt(version, 10)+":"+argv[3])
+	if limit > 0 && len(list.items) > limit {
+		list.items = list.items[len(list.items)-limit:]
+	}
+	if ttl > 0 {
+		exp := time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		for _, key := range keys[:2] {
+			val := m.data[key]
+			val.expiresAt = &exp
+			m.data[key] = val
+		}
+	}
+	return writeArrayBulk(w, []string{"ok", strconv.FormatInt(version, 10)})
+}
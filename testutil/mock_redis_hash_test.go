@@ -0,0 +1,140 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMockRedis_Hash(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if n, err := client.HSet(ctx, "h", "a", "1", "b", "2").Result(); err != nil || n != 2 {
+		t.Fatalf("HSet() = %d, %v, want 2", n, err)
+	}
+	if n, _ := client.HSet(ctx, "h", "a", "3").Result(); n != 0 {
+		t.Errorf("HSet() existing field = %d, want 0", n)
+	}
+	if ok, _ := client.HSetNX(ctx, "h", "a", "4").Result(); ok {
+		t.Error("HSetNX() existing field should return false")
+	}
+	if v, _ := client.HGet(ctx, "h", "a").Result(); v != "3" {
+		t.Errorf("HGet() = %q, want 3", v)
+	}
+	if err := client.HGet(ctx, "h", "missing").Err(); err != redis.Nil {
+		t.Errorf("HGet() missing field error = %v, want redis.Nil", err)
+	}
+	vals, _ := client.HMGet(ctx, "h", "a", "missing").Result()
+	if len(vals) != 2 || vals[0] != "3" || vals[1] != nil {
+		t.Errorf("HMGet() = %v, want [3 <nil>]", vals)
+	}
+	if all, _ := client.HGetAll(ctx, "h").Result(); len(all) != 2 || all["b"] != "2" {
+		t.Errorf("HGetAll() = %v, want a and b", all)
+	}
+	if keys, _ := client.HKeys(ctx, "h").Result(); len(keys) != 2 || keys[0] != "a" {
+		t.Errorf("HKeys() = %v, want [a b]", keys)
+	}
+	if n, _ := client.HIncrBy(ctx, "h", "c", 5).Result(); n != 5 {
+		t.Errorf("HIncrBy() = %d, want 5", n)
+	}
+	if ok, _ := client.HExists(ctx, "h", "c").Result(); !ok {
+		t.Error("HExists() = false, want true")
+	}
+	if n, _ := client.HLen(ctx, "h").Result(); n != 3 {
+		t.Errorf("HLen() = %d, want 3", n)
+	}
+	if typ, _ := client.Type(ctx, "h").Result(); typ != "hash" {
+		t.Errorf("Type() = %q, want hash", typ)
+	}
+
+	// Removing the last field removes the key
+	_ = client.HDel(ctx, "h", "a", "b", "c").Err()
+	if n, _ := client.Exists(ctx, "h").Result(); n != 0 {
+		t.Errorf("Exists() after deleting all fields = %d, want 0", n)
+	}
+
+	_ = client.Set(ctx, "str", "v", 0).Err()
+	if err := client.HSet(ctx, "str", "a", "1").Err(); err == nil || !redis.HasErrorPrefix(err, "WRONGTYPE") {
+		t.Errorf("HSet() on string error = %v, want WRONGTYPE", err)
+	}
+}
+
+func TestMockRedis_EvalVerifyCodeAttempt(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	script := "-- redis-kit:verifycode-attempt\nlocal attempts = redis.call(\"hincrby\", KEYS[1], \"a\", 1)"
+	if err := client.Eval(ctx, script, []string{"missing"}).Err(); err != redis.Nil {
+		t.Errorf("Eval() on a missing key error = %v, want redis.Nil", err)
+	}
+
+	_ = client.HSet(ctx, "code", "h", "digest", "s", "salt", "a", 0).Err()
+	for want := int64(1); want <= 2; want++ {
+		res, err := client.Eval(ctx, script, []string{"code"}).Slice()
+		if err != nil || len(res) != 3 || res[0] != "digest" || res[1] != "salt" || res[2] != want {
+			t.Errorf("Eval() = %v, %v, want [digest salt %d]", res, err, want)
+		}
+	}
+}
+
+func TestMockRedis_EvalConfigSet(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	script := "-- redis-kit:config-set\nredis.call(\"hset\", KEYS[1], \"v\", version)"
+	if v, err := client.Eval(ctx, script, []string{"doc"}, "", "a", "1", "changes", "doc").Int(); err != nil || v != 1 {
+		t.Fatalf("Eval() = %d, %v, want 1", v, err)
+	}
+	if v, _ := client.Eval(ctx, script, []string{"doc"}, "0", "b", "2", "changes", "doc").Int(); v != -1 {
+		t.Errorf("Eval() with a stale version = %d, want -1", v)
+	}
+	if v, _ := client.Eval(ctx, script, []string{"doc"}, "1", "b", "2", "changes", "doc").Int(); v != 2 {
+		t.Errorf("Eval() with the current version = %d, want 2", v)
+	}
+	if got, _ := client.HGetAll(ctx, "doc").Result(); got["v"] != "2" || got["d"] != "b" || got["t"] != "2" {
+		t.Errorf("HGetAll() = %v, want version 2 with data b", got)
+	}
+}
+
+func TestMockRedis_HScan(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	for i := 0; i < 25; i++ {
+		_ = client.HSet(ctx, "h", fmt.Sprintf("f%02d", i), i).Err()
+	}
+
+	seen := map[string]string{}
+	var cursor uint64
+	for {
+		kvs, next, err := client.HScan(ctx, "h", cursor, "", 10).Result()
+		if err != nil {
+			t.Fatalf("HScan() error = %v", err)
+		}
+		for i := 0; i+1 < len(kvs); i += 2 {
+			seen[kvs[i]] = kvs[i+1]
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != 25 || seen["f07"] != "7" {
+		t.Errorf("HScan() collected %d fields (f07=%q), want 25", len(seen), seen["f07"])
+	}
+
+	kvs, _, _ := client.HScan(ctx, "h", 0, "f1*", 100).Result()
+	if len(kvs) != 20 {
+		t.Errorf("HScan(MATCH f1*) = %d values, want 10 pairs", len(kvs))
+	}
+	if kvs, next, err := client.HScan(ctx, "missing", 0, "", 10).Result(); err != nil || len(kvs) != 0 || next != 0 {
+		t.Errorf("HScan(missing) = %v, %d, %v, want empty", kvs, next, err)
+	}
+}
@@ -0,0 +1,100 @@
+package testutil
+
+import (
+	"bufio"
+	"errors"
+)
+
+// The mock keeps HyperLogLogs as exact sets, so counts in tests are deterministic
+
+// hllLocked returns the HyperLogLog at key; create makes it if missing
+// The caller must hold m.mu for writing
+func (m *MockRedis) hllLocked(key string, create bool) (map[string]struct{}, error) {
+	val, ok := m.lookup(key)
+	if ok {
+		if val.hll == nil {
+			return nil, errors.New(wrongTypeError)
+		}
+		return val.hll, nil
+	}
+	if !create {
+		return nil, nil
+	}
+	hll := make(map[string]struct{})
+	m.data[key] = mockValue{hll: hll}
+	return hll, nil
+}
+
+func (m *MockRedis) handlePFAdd(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "wrong number of arguments for 'pfadd' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, existed := m.lookup(args[1])
+	hll, err := m.hllLocked(args[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+
+	// Like Redis, creating an empty HyperLogLog counts as a change
+	changed := !existed
+	for _, member := range args[2:] {
+		if _, ok := hll[member]; !ok {
+			hll[member] = struct{}{}
+			changed = true
+		}
+	}
+	if changed {
+		return writeInt(w, 1)
+	}
+	return writeInt(w, 0)
+}
+
+func (m *MockRedis) handlePFCount(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "wrong number of arguments for 'pfcount' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	union := make(map[string]struct{})
+	for _, key := range args[1:] {
+		hll, err := m.hllLocked(key, false)
+		if err != nil {
+			return writeRawError(w, err.Error())
+		}
+		for member := range hll {
+			union[member] = struct{}{}
+		}
+	}
+	return writeInt(w, int64(len(union)))
+}
+
+func (m *MockRedis) handlePFMerge(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "wrong number of arguments for 'pfmerge' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sources := make([]map[string]struct{}, 0, len(args)-2)
+	for _, key := range args[2:] {
+		hll, err := m.hllLocked(key, false)
+		if err != nil {
+			return writeRawError(w, err.Error())
+		}
+		sources = append(sources, hll)
+	}
+	dest, err := m.hllLocked(args[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	for _, hll := range sources {
+		for member := range hll {
+			dest[member] = struct{}{}
+		}
+	}
+	return writeSimpleString(w, "OK")
+}
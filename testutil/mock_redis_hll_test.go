@@ -0,0 +1,41 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMockRedis_HyperLogLog(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if n, err := client.PFAdd(ctx, "a", "x", "y").Result(); err != nil || n != 1 {
+		t.Fatalf("PFAdd() = %d, %v, want 1", n, err)
+	}
+	if n, _ := client.PFAdd(ctx, "a", "x").Result(); n != 0 {
+		t.Errorf("PFAdd() existing member = %d, want 0", n)
+	}
+	_ = client.PFAdd(ctx, "b", "y", "z")
+
+	if n, _ := client.PFCount(ctx, "a").Result(); n != 2 {
+		t.Errorf("PFCount(a) = %d, want 2", n)
+	}
+	if n, _ := client.PFCount(ctx, "a", "b", "missing").Result(); n != 3 {
+		t.Errorf("PFCount(a, b) = %d, want 3", n)
+	}
+	if err := client.PFMerge(ctx, "ab", "a", "b").Err(); err != nil {
+		t.Fatalf("PFMerge() error = %v, want nil", err)
+	}
+	if n, _ := client.PFCount(ctx, "ab").Result(); n != 3 {
+		t.Errorf("PFCount(ab) = %d, want 3", n)
+	}
+	if typ, _ := client.Type(ctx, "ab").Result(); typ != "string" {
+		t.Errorf("Type() = %q, want string", typ)
+	}
+
+	client.HSet(ctx, "h", "f", "v")
+	if err := client.PFCount(ctx, "h").Err(); err == nil {
+		t.Error("PFCount() on a hash error = nil, want WRONGTYPE")
+	}
+}
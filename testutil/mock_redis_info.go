@@ -0,0 +1,116 @@
+package testutil
+
+import (
+	"bufio"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mockInfoVersion is the redis_version the mock reports
+const mockInfoVersion = "7.2.0"
+
+// infoSections lists the INFO sections the mock reports and the fields each starts with
+var infoSections = []struct {
+	name   string
+	fields []string
+}{
+	{"server", []string{"redis_version", "redis_mode", "uptime_in_seconds"}},
+	{"clients", []string{"connected_clients"}},
+	{"memory", []string{"used_memory", "used_memory_human"}},
+	{"replication", []string{"role", "connected_slaves"}},
+	{"keyspace", nil},
+}
+
+// SetInfo overrides an INFO field in section, e.g. to simulate a replica; an empty value
+// removes the override. Fields the mock does not report are added to the section
+func (m *MockRedis) SetInfo(section, field, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := strings.ToLower(section) + "." + field
+	if value == "" {
+		delete(m.info, key)
+		return
+	}
+	m.info[key] = value
+}
+
+// handleInfo reports server, clients, memory, replication, and keyspace fields derived from the
+// mock's state, with the overrides set by SetInfo
+func (m *MockRedis) handleInfo(args []string, w *bufio.Writer) error {
+	wanted := make(map[string]bool)
+	for _, arg := range args[1:] {
+		wanted[strings.ToLower(arg)] = true
+	}
+	all := len(wanted) == 0 || wanted["all"] || wanted["everything"] || wanted["default"]
+
+	m.mu.Lock()
+	now := time.Now()
+	var keys, expires, memory int64
+	for key, val := range m.data {
+		if val.expiresAt != nil && now.After(*val.expiresAt) {
+			continue
+		}
+		keys++
+		if val.expiresAt != nil {
+			expires++
+		}
+		memory += int64(len(key) + len(val.value))
+	}
+	overrides := make(map[string]string, len(m.info))
+	for k, v := range m.info {
+		overrides[k] = v
+	}
+	m.mu.Unlock()
+
+	// A fresh Redis uses about a megabyte
+	usedMemory := int64(1<<20) + memory
+	values := map[string]string{
+		"server.redis_version":         mockInfoVersion,
+		"server.redis_mode":            "standalone",
+		"server.uptime_in_seconds":     strconv.FormatInt(int64(time.Since(m.started).Seconds()), 10),
+		"clients.connected_clients":    strconv.FormatInt(m.connections.Load(), 10),
+		"memory.used_memory":           strconv.FormatInt(usedMemory, 10),
+		"memory.used_memory_human":     strconv.FormatFloat(float64(usedMemory)/(1<<20), 'f', 2, 64) + "M",
+		"replication.role":             "master",
+		"replication.connected_slaves": "0",
+	}
+	if keys > 0 {
+		values["keyspace.db0"] = "keys=" + strconv.FormatInt(keys, 10) + ",expires=" + strconv.FormatInt(expires, 10) + ",avg_ttl=0"
+	}
+	for k, v := range overrides {
+		values[k] = v
+	}
+
+	var b strings.Builder
+	for _, section := range infoSections {
+		if !all && !wanted[section.name] {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString("# " + strings.ToUpper(section.name[:1]) + section.name[1:] + "\r\n")
+
+		// Known fields first in Redis order, then the rest sorted
+		written := make(map[string]bool)
+		for _, field := range section.fields {
+			if v, ok := values[section.name+"."+field]; ok {
+				b.WriteString(field + ":" + v + "\r\n")
+				written[field] = true
+			}
+		}
+		var extra []string
+		for k := range values {
+			if name, field, ok := strings.Cut(k, "."); ok && name == section.name && !written[field] {
+				extra = append(extra, field)
+			}
+		}
+		sort.Strings(extra)
+		for _, field := range extra {
+			b.WriteString(field + ":" + values[section.name+"."+field] + "\r\n")
+		}
+	}
+	return writeBulkString(w, b.String())
+}
@@ -0,0 +1,38 @@
+package testutil
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMockRedis_Info(t *testing.T) {
+	client, mock := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	_ = client.Set(ctx, "a", "1", 0)
+	_ = client.Set(ctx, "b", "2", time.Minute)
+
+	info, err := client.Info(ctx).Result()
+	if err != nil {
+		t.Fatalf("Info() error = %v, want nil", err)
+	}
+	for _, want := range []string{"# Server\r\n", "redis_version:" + mockInfoVersion, "connected_clients:1", "role:master", "db0:keys=2,expires=1"} {
+		if !strings.Contains(info, want) {
+			t.Errorf("Info() missing %q:\n%s", want, info)
+		}
+	}
+
+	mock.SetInfo("replication", "role", "slave")
+	mock.SetInfo("replication", "master_link_status", "up")
+	info, _ = client.Info(ctx, "replication").Result()
+	if !strings.Contains(info, "role:slave\r\n") || !strings.Contains(info, "master_link_status:up\r\n") || strings.Contains(info, "# Server") {
+		t.Errorf("Info(replication) = %q, want only the overridden replication section", info)
+	}
+	mock.SetInfo("replication", "role", "")
+	if info, _ = client.Info(ctx, "replication").Result(); !strings.Contains(info, "role:master") {
+		t.Errorf("Info(replication) after clearing override = %q, want role:master", info)
+	}
+}
@@ -0,0 +1,470 @@
+package testutil
+
+import (
+	"bufio"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (m *MockRedis) handleScan(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "invalid args")
+	}
+
+	cursor, err := strconv.Atoi(args[1])
+	if err != nil || cursor < 0 {
+		return writeError(w, "invalid cursor")
+	}
+
+	pattern := "*"
+	count := 10
+	for i := 2; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		if opt == "MATCH" && i+1 < len(args) {
+			pattern = args[i+1]
+			i++
+		} else if opt == "COUNT" && i+1 < len(args) {
+			count, err = strconv.Atoi(args[i+1])
+			if err != nil || count <= 0 {
+				return writeError(w, "invalid count")
+			}
+			i++
+		}
+	}
+
+	m.mu.Lock()
+	after := ""
+	if cursor != 0 {
+		var ok bool
+		if after, ok = m.scanCursors[cursor]; !ok {
+			m.mu.Unlock()
+			return writeError(w, "invalid cursor")
+		}
+		delete(m.scanCursors, cursor)
+	}
+	keys := make([]string, 0, len(m.data))
+	now := time.Now()
+	for key, val := range m.data {
+		if val.expiresAt != nil && now.After(*val.expiresAt) {
+			continue
+		}
+		if cursor != 0 && key <= after {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Cursors remember the last key returned, so iteration stays stable while
+	// keys are deleted; COUNT bounds the number of keys examined per call
+	next := 0
+	if count < len(keys) {
+		keys = keys[:count]
+		m.nextCursor++
+		next = m.nextCursor
+		m.scanCursors[next] = keys[len(keys)-1]
+	}
+	m.mu.Unlock()
+
+	matched := []string{}
+	for _, key := range keys {
+		if matchPattern(pattern, key) {
+			matched = append(matched, key)
+		}
+	}
+
+	if _, err := w.WriteString("*2\r\n"); err != nil {
+		return err
+	}
+	if err := writeBulkString(w, strconv.Itoa(next)); err != nil {
+		return err
+	}
+	return writeArrayBulk(w, matched)
+}
+
+// matchPattern implements Redis glob-style matching (*, ?, [...], and \ escapes)
+func matchPattern(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchPattern(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				// Unterminated class, match '[' literally
+				if s[0] != '[' {
+					return false
+				}
+				s = s[1:]
+				pattern = pattern[1:]
+				continue
+			}
+			class := pattern[1 : end+1]
+			negate := len(class) > 0 && class[0] == '^'
+			if negate {
+				class = class[1:]
+			}
+			if matchClass(class, s[0]) == negate {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[end+2:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+func matchClass(class string, c byte) bool {
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			if class[i] <= c && c <= class[i+2] {
+				return true
+			}
+			i += 2
+			continue
+		}
+		if class[i] == c {
+			return true
+		}
+	}
+	return false
+}
+
+func writeArrayBulk(w *bufio.Writer, values []string) error {
+	if _, err := w.WriteString("*" + strconv.Itoa(len(values)) + "\r\n"); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if err := writeBulkString(w, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockRedis) handlePTTL(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "invalid args")
+	}
+
+	key := args[1]
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	val, ok := m.data[key]
+	if !ok {
+		return writeInt(w, -2)
+	}
+	ttl := ttlMilliseconds(val.expiresAt)
+	if ttl == -2 {
+		delete(m.data, key)
+	}
+	return writeInt(w, ttl)
+}
+
+func (m *MockRedis) handlePExpire(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+	millis, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return writeError(w, "invalid milliseconds")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, ok := m.lookup(args[1])
+	if !ok {
+		return writeInt(w, 0)
+	}
+	exp := time.Now().Add(time.Duration(millis) * time.Millisecond)
+	val.expiresAt = &exp
+	m.data[args[1]] = val
+	return writeInt(w, 1)
+}
+
+// handlePersist serves PERSIST key
+func (m *MockRedis) handlePersist(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "invalid args")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, ok := m.lookup(args[1])
+	if !ok || val.expiresAt == nil {
+		return writeInt(w, 0)
+	}
+	val.expiresAt = nil
+	m.data[args[1]] = val
+	return writeInt(w, 1)
+}
+
+// evalCacheFill emulates the cache package's fill script: set KEYS[1] to ARGV[1] with a TTL
+// of ARGV[2] milliseconds, unless it holds the tombstone ARGV[3]
+func (m *MockRedis) evalCacheFill(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 1 || len(argv) < 3 {
+		return writeError(w, "invalid args")
+	}
+	ttl, err := strconv.ParseInt(argv[1], 10, 64)
+	if err != nil {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if val, ok := m.lookup(keys[0]); ok && val.value == argv[2] {
+		return writeInt(w, 0)
+	}
+	var expiresAt *time.Time
+	if ttl > 0 {
+		exp := time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		expiresAt = &exp
+	}
+	m.data[keys[0]] = mockValue{value: argv[0], expiresAt: expiresAt}
+	return writeInt(w, 1)
+}
+
+// evalCacheMove emulates the cache package's rename script, or its copy script when argv
+// holds the copy's TTL in milliseconds: move or copy KEYS[1] to KEYS[2], carrying its score
+// in the LRU index KEYS[3], if given
+func (m *MockRedis) evalCacheMove(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 2 {
+		return writeError(w, "invalid args")
+	}
+	copying := argv != nil
+	var ttl int64
+	if copying {
+		var err error
+		if ttl, err = strconv.ParseInt(argv[0], 10, 64); err != nil {
+			return writeError(w, "invalid args")
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, ok := m.lookup(keys[0])
+	if !ok {
+		return writeInt(w, 0)
+	}
+	if copying {
+		if val.stream != nil || val.list != nil {
+			return writeError(w, "mock COPY supports strings, hashes, sets, and sorted sets only")
+		}
+		val.hash = maps.Clone(val.hash)
+		val.zset = maps.Clone(val.zset)
+		val.set = maps.Clone(val.set)
+		val.hll = maps.Clone(val.hll)
+		switch {
+		case ttl > 0:
+			exp := time.Now().Add(time.Duration(ttl) * time.Millisecond)
+			val.expiresAt = &exp
+		case ttl == 0:
+			val.expiresAt = nil
+		}
+	} else {
+		delete(m.data, keys[0])
+	}
+	m.data[keys[1]] = val
+	if len(keys) > 2 {
+		if index, _ := m.zsetLocked(keys[2], false); index != nil {
+			if score, ok := index[keys[0]]; ok {
+				if !copying {
+					delete(index, keys[0])
+				}
+				index[keys[1]] = score
+			}
+		}
+	}
+	return writeInt(w, 1)
+}
+
+// handleGetEx serves GETEX key [EX seconds|PX milliseconds|PERSIST]
+func (m *MockRedis) handleGetEx(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "invalid args")
+	}
+
+	var ttl time.Duration
+	persist := false
+	if len(args) >= 3 {
+		switch strings.ToUpper(args[2]) {
+		case "PERSIST":
+			persist = true
+		case "EX", "PX":
+			if len(args) < 4 {
+				return writeError(w, "syntax error")
+			}
+			n, err := strconv.ParseInt(args[3], 10, 64)
+			if err != nil || n <= 0 {
+				return writeError(w, "invalid expire time in 'getex' command")
+			}
+			ttl = time.Duration(n) * time.Millisecond
+			if strings.ToUpper(args[2]) == "EX" {
+				ttl = time.Duration(n) * time.Second
+			}
+		default:
+			return writeError(w, "syntax error")
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, ok := m.lookup(args[1])
+	if !ok {
+		return writeNil(w)
+	}
+	if persist {
+		val.expiresAt = nil
+	} else if ttl > 0 {
+		exp := time.Now().Add(ttl)
+		val.expiresAt = &exp
+	}
+	m.data[args[1]] = val
+	return writeBulkString(w, val.value)
+}
+
+// handleGetDel serves GETDEL key
+func (m *MockRedis) handleGetDel(args []string, w *bufio.Writer) error {
+	if len(args) != 2 {
+		return writeError(w, "wrong number of arguments for 'getdel' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, ok := m.lookup(args[1])
+	if !ok {
+		return writeNil(w)
+	}
+	if val.hash != nil || val.zset != nil || val.set != nil || val.list != nil {
+		return writeRawError(w, wrongTypeError)
+	}
+	delete(m.data, args[1])
+	return writeBulkString(w, val.value)
+}
+
+// evalCounterFlush emulates the counter package's flush script: move the value of
+// KEYS[1] into KEYS[2], optionally setting a TTL of ARGV[1] ms on the total
+func (m *MockRedis) evalCounterFlush(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 2 || len(argv) < 1 {
+		return writeError(w, "invalid args")
+	}
+	ttl, err := strconv.ParseInt(argv[0], 10, 64)
+	if err != nil {
+		return writeError(w, "invalid ttl")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	val, ok := m.lookup(keys[0])
+	if !ok {
+		return writeInt(w, 0)
+	}
+	moved, err := strconv.ParseInt(val.value, 10, 64)
+	if err != nil {
+		return writeError(w, "value is not an integer")
+	}
+	if moved == 0 {
+		return writeInt(w, 0)
+	}
+	if _, err := m.incrByLocked(keys[0], -moved); err != nil {
+		return writeError(w, err.Error())
+	}
+	if _, err := m.incrByLocked(keys[1], moved); err != nil {
+		return writeError(w, err.Error())
+	}
+	if ttl > 0 {
+		total := m.data[keys[1]]
+		exp := time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		total.expiresAt = &exp
+		m.data[keys[1]] = total
+	}
+	return writeInt(w, moved)
+}
+
+// evalCompareAndSet emulates scripts that replace KEYS[1] with ARGV[2] and a TTL of ARGV[3] ms
+// only while it still holds ARGV[1]; returns 1 on success and 0 otherwise
+func (m *MockRedis) evalCompareAndSet(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 1 || len(argv) < 3 {
+		return writeError(w, "invalid args")
+	}
+	ttl, err := strconv.ParseInt(argv[2], 10, 64)
+	if err != nil {
+		return writeError(w, "invalid ttl")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	val, ok := m.lookup(keys[0])
+	if !ok || val.typeName() != "string" || val.value != argv[0] {
+		return writeInt(w, 0)
+	}
+	var expiresAt *time.Time
+	if ttl > 0 {
+		exp := time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		expiresAt = &exp
+	}
+	m.data[keys[0]] = mockValue{value: argv[1], expiresAt: expiresAt}
+	return writeInt(w, 1)
+}
+
+// handleMGet returns nil for missing keys and keys holding other types, as Redis does
+func (m *MockRedis) handleMGet(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "wrong number of arguments for 'mget' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := writeArrayLen(w, len(args)-1); err != nil {
+		return err
+	}
+	for _, key := range args[1:] {
+		val, ok := m.lookup(key)
+		if !ok || val.typeName() != "string" || val.hll != nil {
+			if err := writeNil(w); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeBulkString(w, val.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
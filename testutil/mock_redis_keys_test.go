@@ -0,0 +1,294 @@
+package testutil
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMockRedis_SCAN(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	for _, key := range []string{"a:1", "a:2", "a:3", "b:1"} {
+		_ = client.Set(ctx, key, "v", 0).Err()
+	}
+	_ = client.Set(ctx, "a:expired", "v", time.Millisecond).Err()
+	time.Sleep(5 * time.Millisecond)
+
+	t.Run("pages with cursor", func(t *testing.T) {
+		var all []string
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(ctx, cursor, "a:*", 2).Result()
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			all = append(all, keys...)
+			if next == 0 {
+				break
+			}
+			cursor = next
+		}
+		if len(all) != 3 {
+			t.Errorf("Scan() collected %v, want 3 keys", all)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		if err := client.Do(ctx, "SCAN", "x").Err(); err == nil {
+			t.Error("Scan() with invalid cursor should return error")
+		}
+	})
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "anything", true},
+		{"user:*", "user:1", true},
+		{"user:*", "session:1", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"k[0-9]", "k5", true},
+		{"k[^0-9]", "k5", false},
+		{"k[abc]", "kb", true},
+		{`a\*b`, "a*b", true},
+		{`a\*b`, "axb", false},
+		{"a**b", "ab", true},
+		{"[", "[", true},
+	}
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestMockRedis_SCAN_StableUnderDeletes(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	for _, key := range []string{"k1", "k2", "k3", "k4", "k5"} {
+		_ = client.Set(ctx, key, "v", 0).Err()
+	}
+
+	seen := 0
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, "*", 2).Result()
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		seen += len(keys)
+		if len(keys) > 0 {
+			_ = client.Unlink(ctx, keys...).Err()
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	if seen != 5 {
+		t.Errorf("Scan() with deletes saw %d keys, want 5", seen)
+	}
+}
+
+func TestMockRedis_UNLINK(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	_ = client.Set(ctx, "u1", "v", 0).Err()
+	_ = client.Set(ctx, "u2", "v", 0).Err()
+
+	n, err := client.Unlink(ctx, "u1", "u2", "missing").Result()
+	if err != nil {
+		t.Fatalf("Unlink() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Unlink() = %d, want 2", n)
+	}
+}
+
+func TestMockRedis_PTTL(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	_ = client.Set(ctx, "with-ttl", "v", time.Minute).Err()
+	_ = client.Set(ctx, "no-ttl", "v", 0).Err()
+	_ = client.Set(ctx, "expired", "v", time.Millisecond).Err()
+	time.Sleep(5 * time.Millisecond)
+
+	if ttl, err := client.PTTL(ctx, "with-ttl").Result(); err != nil || ttl <= 59*time.Second || ttl > time.Minute {
+		t.Errorf("PTTL(with-ttl) = (%v, %v), want ~1m", ttl, err)
+	}
+	if ttl, _ := client.PTTL(ctx, "no-ttl").Result(); ttl != -1 {
+		t.Errorf("PTTL(no-ttl) = %v, want -1", ttl)
+	}
+	if ttl, _ := client.PTTL(ctx, "expired").Result(); ttl != -2 {
+		t.Errorf("PTTL(expired) = %v, want -2", ttl)
+	}
+	if ttl, _ := client.PTTL(ctx, "missing").Result(); ttl != -2 {
+		t.Errorf("PTTL(missing) = %v, want -2", ttl)
+	}
+}
+
+func TestMockRedis_SetXXKeepTTL(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if ok, _ := client.SetXX(ctx, "k", "v", 0).Result(); ok {
+		t.Error("SetXX() on missing key should return false")
+	}
+	_ = client.Set(ctx, "k", "v1", time.Hour).Err()
+	if err := client.SetArgs(ctx, "k", "v2", redis.SetArgs{Mode: "XX", KeepTTL: true}).Err(); err != nil {
+		t.Fatalf("SetArgs() XX KEEPTTL error = %v, want nil", err)
+	}
+	if v, _ := client.Get(ctx, "k").Result(); v != "v2" {
+		t.Errorf("Get() = %q, want v2", v)
+	}
+	if ttl, _ := client.PTTL(ctx, "k").Result(); ttl <= 0 {
+		t.Errorf("PTTL() after KEEPTTL = %v, want positive", ttl)
+	}
+}
+
+func TestMockRedis_GetExPExpire(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if err := client.GetEx(ctx, "missing", time.Second).Err(); err != redis.Nil {
+		t.Errorf("GetEx() missing key error = %v, want redis.Nil", err)
+	}
+
+	_ = client.Set(ctx, "k", "v", 0).Err()
+	if v, err := client.GetEx(ctx, "k", 50*time.Millisecond).Result(); err != nil || v != "v" {
+		t.Errorf("GetEx() = %q, %v, want v", v, err)
+	}
+	if ttl, _ := client.PTTL(ctx, "k").Result(); ttl <= 0 || ttl > 50*time.Millisecond {
+		t.Errorf("PTTL() after GetEx = %v, want at most 50ms", ttl)
+	}
+	if ok, _ := client.PExpire(ctx, "k", time.Hour).Result(); !ok {
+		t.Error("PExpire() = false, want true")
+	}
+	if ttl, _ := client.PTTL(ctx, "k").Result(); ttl < time.Minute {
+		t.Errorf("PTTL() after PExpire = %v, want about 1h", ttl)
+	}
+	if ok, _ := client.PExpire(ctx, "missing", time.Hour).Result(); ok {
+		t.Error("PExpire() on missing key = true, want false")
+	}
+}
+
+func TestMockRedis_GetDel(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if err := client.GetDel(ctx, "missing").Err(); err != redis.Nil {
+		t.Errorf("GetDel() missing key error = %v, want redis.Nil", err)
+	}
+
+	_ = client.Set(ctx, "k", "v", time.Minute).Err()
+	if v, err := client.GetDel(ctx, "k").Result(); err != nil || v != "v" {
+		t.Errorf("GetDel() = %q, %v, want v", v, err)
+	}
+	if n, _ := client.Exists(ctx, "k").Result(); n != 0 {
+		t.Error("key exists after GetDel()")
+	}
+
+	_ = client.HSet(ctx, "h", "f", "v").Err()
+	if err := client.GetDel(ctx, "h").Err(); err == nil || !strings.Contains(err.Error(), "WRONGTYPE") {
+		t.Errorf("GetDel() on a hash error = %v, want WRONGTYPE", err)
+	}
+}
+
+func TestMockRedis_IncrByDecrBy(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if n, err := client.IncrBy(ctx, "n", 5).Result(); err != nil || n != 5 {
+		t.Errorf("IncrBy() = %d, %v, want 5", n, err)
+	}
+	if n, err := client.DecrBy(ctx, "n", 7).Result(); err != nil || n != -2 {
+		t.Errorf("DecrBy() = %d, %v, want -2", n, err)
+	}
+	_ = client.Set(ctx, "s", "abc", 0).Err()
+	if err := client.IncrBy(ctx, "s", 1).Err(); err == nil {
+		t.Error("IncrBy() on non-integer should return error")
+	}
+}
+
+func TestMockRedis_EvalCompareAndSet(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	script := "-- redis-kit:compare-set\nif redis.call(\"get\", KEYS[1]) == ARGV[1] then ... end"
+	_ = client.Set(ctx, "k", "old", 0).Err()
+
+	if n, _ := client.Eval(ctx, script, []string{"k"}, "other", "new", 0).Int(); n != 0 {
+		t.Errorf("Eval() with a mismatched value = %d, want 0", n)
+	}
+	if n, err := client.Eval(ctx, script, []string{"k"}, "old", "new", 60000).Int(); err != nil || n != 1 {
+		t.Fatalf("Eval() = %d, %v, want 1", n, err)
+	}
+	if v, _ := client.Get(ctx, "k").Result(); v != "new" {
+		t.Errorf("Get() = %q, want new", v)
+	}
+	if ttl, _ := client.PTTL(ctx, "k").Result(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("PTTL() = %v, want up to 1m", ttl)
+	}
+	if n, _ := client.Eval(ctx, script, []string{"missing"}, "", "x", 0).Int(); n != 0 {
+		t.Errorf("Eval() on a missing key = %d, want 0", n)
+	}
+}
+
+func TestMockRedis_MGet(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	_ = client.Set(ctx, "a", "1", 0).Err()
+	_ = client.Set(ctx, "c", "3", 0).Err()
+	_ = client.HSet(ctx, "h", "f", "v").Err()
+
+	vals, err := client.MGet(ctx, "a", "b", "c", "h").Result()
+	if err != nil || len(vals) != 4 || vals[0] != "1" || vals[1] != nil || vals[2] != "3" || vals[3] != nil {
+		t.Errorf("MGet() = %v, %v, want [1 <nil> 3 <nil>]", vals, err)
+	}
+}
+
+func TestMockRedis_MemoryUsage(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	_ = client.Set(ctx, "small", "v", 0).Err()
+	_ = client.Set(ctx, "large", string(make([]byte, 1000)), 0).Err()
+	_ = client.HSet(ctx, "hash", "field", "value").Err()
+
+	small, err := client.MemoryUsage(ctx, "small").Result()
+	if err != nil || small <= 0 {
+		t.Fatalf("MemoryUsage(small) = (%d, %v), want positive", small, err)
+	}
+	if large, _ := client.MemoryUsage(ctx, "large", 5).Result(); large < small+990 {
+		t.Errorf("MemoryUsage(large) = %d, want about 1000 more than %d", large, small)
+	}
+	if n, _ := client.MemoryUsage(ctx, "hash").Result(); n <= 0 {
+		t.Errorf("MemoryUsage(hash) = %d, want positive", n)
+	}
+	if _, err := client.MemoryUsage(ctx, "missing").Result(); err != redis.Nil {
+		t.Errorf("MemoryUsage(missing) error = %v, want redis.Nil", err)
+	}
+}
@@ -0,0 +1,291 @@
+package testutil
+
+import (
+	"bufio"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mockList holds the items of a list, head first
+type mockList struct {
+	items []string
+}
+
+// listLocked returns the list at key; create makes it if missing
+// The caller must hold m.mu for writing
+func (m *MockRedis) listLocked(key string, create bool) (*mockList, error) {
+	val, ok := m.lookup(key)
+	if ok {
+		if val.list == nil {
+			return nil, errors.New(wrongTypeError)
+		}
+		return val.list, nil
+	}
+	if !create {
+		return nil, nil
+	}
+	list := &mockList{}
+	m.data[key] = mockValue{list: list}
+	return list, nil
+}
+
+// popLocked removes up to count items from the head or tail of the list at key
+// The caller must hold m.mu for writing
+func (m *MockRedis) popLocked(key string, count int, head bool) ([]string, error) {
+	list, err := m.listLocked(key, false)
+	if err != nil || list == nil {
+		return nil, err
+	}
+	count = min(count, len(list.items))
+	popped := make([]string, count)
+	if head {
+		copy(popped, list.items[:count])
+		list.items = list.items[count:]
+	} else {
+		for i := range popped {
+			popped[i] = list.items[len(list.items)-1-i]
+		}
+		list.items = list.items[:len(list.items)-count]
+	}
+	m.dropIfEmpty(key)
+	return popped, nil
+}
+
+// handlePush implements LPUSH (head) and RPUSH
+func (m *MockRedis) handlePush(args []string, w *bufio.Writer, head bool) error {
+	if len(args) < 3 {
+		return writeError(w, "wrong number of arguments for '"+strings.ToLower(args[0])+"' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list, err := m.listLocked(args[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	for _, item := range args[2:] {
+		if head {
+			list.items = append([]string{item}, list.items...)
+		} else {
+			list.items = append(list.items, item)
+		}
+	}
+	return writeInt(w, int64(len(list.items)))
+}
+
+// handlePop implements LPOP (head) and RPOP, with the optional count argument
+func (m *MockRedis) handlePop(args []string, w *bufio.Writer, head bool) error {
+	if len(args) != 2 && len(args) != 3 {
+		return writeError(w, "wrong number of arguments for '"+strings.ToLower(args[0])+"' command")
+	}
+	count := 1
+	if len(args) == 3 {
+		n, err := strconv.Atoi(args[2])
+		if err != nil || n < 0 {
+			return writeError(w, "value is out of range, must be positive")
+		}
+		count = n
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	popped, err := m.popLocked(args[1], count, head)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if len(args) == 3 {
+		if len(popped) == 0 {
+			return writeNilArray(w)
+		}
+		return writeArrayBulk(w, popped)
+	}
+	if len(popped) == 0 {
+		return writeNil(w)
+	}
+	return writeBulkString(w, popped[0])
+}
+
+// handleBPop implements BLPOP (head) and BRPOP by polling the keys until the timeout
+func (m *MockRedis) handleBPop(args []string, w *bufio.Writer, head bool) error {
+	if len(args) < 3 {
+		return writeError(w, "wrong number of arguments for '"+strings.ToLower(args[0])+"' command")
+	}
+	seconds, err := strconv.ParseFloat(args[len(args)-1], 64)
+	if err != nil || seconds < 0 {
+		return writeError(w, "timeout is not a float or out of range")
+	}
+	block := time.Duration(seconds * float64(time.Second))
+	if block == 0 {
+		block = maxBlockWait
+	}
+	keys := args[1 : len(args)-1]
+
+	deadline := time.Now().Add(block)
+	for {
+		key, item, err := m.bpopOnce(keys, head)
+		if err != nil {
+			return writeRawError(w, err.Error())
+		}
+		if key != "" {
+			return writeArrayBulk(w, []string{key, item})
+		}
+		if time.Now().After(deadline) {
+			return writeNilArray(w)
+		}
+		time.Sleep(blockPollInterval)
+	}
+}
+
+// bpopOnce pops from the first non-empty list of keys; key is empty if all are empty
+func (m *MockRedis) bpopOnce(keys []string, head bool) (key, item string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		popped, err := m.popLocked(key, 1, head)
+		if err != nil {
+			return "", "", err
+		}
+		if len(popped) > 0 {
+			return key, popped[0], nil
+		}
+	}
+	return "", "", nil
+}
+
+func (m *MockRedis) handleLLen(args []string, w *bufio.Writer) error {
+	if len(args) != 2 {
+		return writeError(w, "wrong number of arguments for 'llen' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list, err := m.listLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if list == nil {
+		return writeInt(w, 0)
+	}
+	return writeInt(w, int64(len(list.items)))
+}
+
+func (m *MockRedis) handleLRange(args []string, w *bufio.Writer) error {
+	if len(args) != 4 {
+		return writeError(w, "wrong number of arguments for 'lrange' command")
+	}
+	start, err1 := strconv.Atoi(args[2])
+	stop, err2 := strconv.Atoi(args[3])
+	if err1 != nil || err2 != nil {
+		return writeError(w, "value is not an integer or out of range")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list, err := m.listLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if list == nil {
+		return writeArrayBulk(w, nil)
+	}
+	lo, hi, ok := normalizeIndexRange(start, stop, len(list.items))
+	if !ok {
+		return writeArrayBulk(w, nil)
+	}
+	return writeArrayBulk(w, list.items[lo:hi])
+}
+
+func (m *MockRedis) evalNotifyRecord(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 3 || len(argv) < 6 {
+		return writeError(w, "invalid args")
+	}
+	now, err1 := strconv.ParseInt(argv[0], 10, 64)
+	quiet, err2 := strconv.ParseInt(argv[1], 10, 64)
+	maxDelay, err3 := strconv.ParseInt(argv[2], 10, 64)
+	maxEvents, err4 := strconv.Atoi(argv[3])
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return writeError(w, "invalid args")
+	}
+	user := argv[4]
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	due, err := m.zsetLocked(keys[0], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	meta, err := m.hashLocked(keys[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	list, err := m.listLocked(keys[2], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+
+	first, count := now, int64(0)
+	if raw, ok := meta[user]; ok {
+		firstPart, countPart, _ := strings.Cut(raw, ":")
+		first, _ = strconv.ParseInt(firstPart, 10, 64)
+		count, _ = strconv.ParseInt(countPart, 10, 64)
+	}
+	count++
+	meta[user] = strconv.FormatInt(first, 10) + ":" + strconv.FormatInt(count, 10)
+	list.items = append(list.items, argv[5])
+	if maxEvents > 0 && len(list.items) > maxEvents {
+		list.items = list.items[len(list.items)-maxEvents:]
+	}
+	due[user] = float64(min(now+quiet, first+maxDelay))
+	return writeInt(w, count)
+}
+
+func (m *MockRedis) evalNotifyDrain(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 2 || len(argv) < 3 {
+		return writeError(w, "invalid args")
+	}
+	limit, err := strconv.Atoi(argv[1])
+	if err != nil {
+		return writeError(w, "invalid limit")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	due, err := m.zsetLocked(keys[0], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	ready, err := selectZRange(due, zrangeQuery{start: "-inf", stop: argv[0], byScore: true, count: limit})
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	meta, err := m.hashLocked(keys[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+
+	var out []string
+	for _, zm := range ready {
+		delete(due, zm.member)
+		raw, ok := meta[zm.member]
+		delete(meta, zm.member)
+		key := argv[2] + zm.member
+		list, err := m.listLocked(key, false)
+		if err != nil {
+			return writeRawError(w, err.Error())
+		}
+		var events []string
+		if list != nil {
+			events = list.items
+		}
+		delete(m.data, key)
+		if ok {
+			out = append(out, zm.member, raw, strconv.Itoa(len(events)))
+			out = append(out, events...)
+		}
+	}
+	m.dropIfEmpty(keys[0])
+	m.dropIfEmpty(keys[1])
+	return writeArrayBulk(w, out)
+}
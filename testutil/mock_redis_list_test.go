@@ -0,0 +1,77 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMockRedis_List(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if n, err := client.RPush(ctx, "l", "b", "c").Result(); err != nil || n != 2 {
+		t.Fatalf("RPush() = %d, %v, want 2", n, err)
+	}
+	if n, _ := client.LPush(ctx, "l", "a").Result(); n != 3 {
+		t.Errorf("LPush() = %d, want 3", n)
+	}
+	if items, _ := client.LRange(ctx, "l", 0, -1).Result(); !slices.Equal(items, []string{"a", "b", "c"}) {
+		t.Errorf("LRange() = %v, want [a b c]", items)
+	}
+	if n, _ := client.LLen(ctx, "l").Result(); n != 3 {
+		t.Errorf("LLen() = %d, want 3", n)
+	}
+	if typ, _ := client.Type(ctx, "l").Result(); typ != "list" {
+		t.Errorf("Type() = %q, want list", typ)
+	}
+
+	if v, err := client.LPop(ctx, "l").Result(); err != nil || v != "a" {
+		t.Errorf("LPop() = %q, %v, want a", v, err)
+	}
+	if v, _ := client.RPop(ctx, "l").Result(); v != "c" {
+		t.Errorf("RPop() = %q, want c", v)
+	}
+	if items, _ := client.LPopCount(ctx, "l", 5).Result(); !slices.Equal(items, []string{"b"}) {
+		t.Errorf("LPopCount() = %v, want [b]", items)
+	}
+	if n, _ := client.Exists(ctx, "l").Result(); n != 0 {
+		t.Error("list still exists after popping its last item")
+	}
+	if err := client.LPop(ctx, "l").Err(); !errors.Is(err, redis.Nil) {
+		t.Errorf("LPop() on empty list error = %v, want redis.Nil", err)
+	}
+
+	_ = client.Set(ctx, "str", "v", 0).Err()
+	if err := client.LPush(ctx, "str", "a").Err(); err == nil {
+		t.Error("LPush() on a string error = nil, want WRONGTYPE")
+	}
+}
+
+func TestMockRedis_BlockingPop(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	_ = client.RPush(ctx, "b", "1", "2").Err()
+	if kv, err := client.BRPop(ctx, time.Second, "a", "b").Result(); err != nil || !slices.Equal(kv, []string{"b", "2"}) {
+		t.Errorf("BRPop() = %v, %v, want [b 2]", kv, err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = client.RPush(ctx, "a", "late").Err()
+	}()
+	if kv, err := client.BLPop(ctx, time.Second, "a").Result(); err != nil || !slices.Equal(kv, []string{"a", "late"}) {
+		t.Errorf("BLPop() = %v, %v, want [a late]", kv, err)
+	}
+
+	if err := client.BLPop(ctx, time.Second, "empty").Err(); !errors.Is(err, redis.Nil) {
+		t.Errorf("BLPop() timeout error = %v, want redis.Nil", err)
+	}
+}
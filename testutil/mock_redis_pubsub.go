@@ -0,0 +1,156 @@
+package testutil
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// mockSubscriber is the pub/sub state of one connection
+// Pub/sub replies bypass the connection's buffered writer and go straight to its reply
+// queue under m.psMu, so messages are never delivered ahead of a subscribe confirmation
+type mockSubscriber struct {
+	queue    *replyQueue
+	channels map[string]struct{}
+	patterns map[string]struct{}
+}
+
+func newMockSubscriber(queue *replyQueue) *mockSubscriber {
+	return &mockSubscriber{
+		queue:    queue,
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+	}
+}
+
+func (s *mockSubscriber) count() int {
+	return len(s.channels) + len(s.patterns)
+}
+
+// isPubSubCommand reports whether cmd changes the subscriptions of a connection
+func isPubSubCommand(cmd string) bool {
+	switch cmd {
+	case "SUBSCRIBE", "UNSUBSCRIBE", "PSUBSCRIBE", "PUNSUBSCRIBE":
+		return true
+	}
+	return false
+}
+
+// handlePubSub serves SUBSCRIBE, UNSUBSCRIBE, PSUBSCRIBE, and PUNSUBSCRIBE
+func (m *MockRedis) handlePubSub(sub *mockSubscriber, args []string) error {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+
+	m.mu.RLock()
+	shouldFail := m.shouldFail
+	m.mu.RUnlock()
+
+	m.psMu.Lock()
+	defer m.psMu.Unlock()
+
+	cmd := strings.ToUpper(args[0])
+	switch {
+	case shouldFail:
+		_ = writeError(w, "mock redis failure")
+	case cmd == "SUBSCRIBE" || cmd == "PSUBSCRIBE":
+		if len(args) < 2 {
+			_ = writeError(w, "wrong number of arguments for '"+strings.ToLower(cmd)+"' command")
+			break
+		}
+		set, kind := sub.channels, "subscribe"
+		if cmd == "PSUBSCRIBE" {
+			set, kind = sub.patterns, "psubscribe"
+		}
+		m.subscribers[sub] = struct{}{}
+		for _, name := range args[1:] {
+			set[name] = struct{}{}
+			writeSubscription(w, kind, name, sub.count())
+		}
+	default:
+		set, kind := sub.channels, "unsubscribe"
+		if cmd == "PUNSUBSCRIBE" {
+			set, kind = sub.patterns, "punsubscribe"
+		}
+		names := args[1:]
+		if len(names) == 0 {
+			for name := range set {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+		if len(names) == 0 {
+			_ = writeArrayLen(w, 3)
+			_ = writeBulkString(w, kind)
+			_ = writeNil(w)
+			_ = writeInt(w, int64(sub.count()))
+		}
+		for _, name := range names {
+			delete(set, name)
+			writeSubscription(w, kind, name, sub.count())
+		}
+		if sub.count() == 0 {
+			delete(m.subscribers, sub)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := sub.queue.Write(buf.Bytes())
+	return err
+}
+
+func writeSubscription(w *bufio.Writer, kind, name string, count int) {
+	_ = writeArrayLen(w, 3)
+	_ = writeBulkString(w, kind)
+	_ = writeBulkString(w, name)
+	_ = writeInt(w, int64(count))
+}
+
+// removeSubscriber drops the subscriptions of a closed connection
+func (m *MockRedis) removeSubscriber(sub *mockSubscriber) {
+	m.psMu.Lock()
+	delete(m.subscribers, sub)
+	m.psMu.Unlock()
+}
+
+// Publish delivers message to the subscribers of channel and returns how many received it
+func (m *MockRedis) Publish(channel, message string) int {
+	m.psMu.Lock()
+	defer m.psMu.Unlock()
+
+	received := 0
+	for sub := range m.subscribers {
+		var buf bytes.Buffer
+		w := bufio.NewWriter(&buf)
+		if _, ok := sub.channels[channel]; ok {
+			_ = writeArrayLen(w, 3)
+			_ = writeBulkString(w, "message")
+			_ = writeBulkString(w, channel)
+			_ = writeBulkString(w, message)
+			received++
+		}
+		for pattern := range sub.patterns {
+			if matchPattern(pattern, channel) {
+				_ = writeArrayLen(w, 4)
+				_ = writeBulkString(w, "pmessage")
+				_ = writeBulkString(w, pattern)
+				_ = writeBulkString(w, channel)
+				_ = writeBulkString(w, message)
+				received++
+			}
+		}
+		if err := w.Flush(); err == nil && buf.Len() > 0 {
+			_, _ = sub.queue.Write(buf.Bytes())
+		}
+	}
+	return received
+}
+
+func (m *MockRedis) handlePublish(args []string, w *bufio.Writer) error {
+	if len(args) != 3 {
+		return writeError(w, "wrong number of arguments for 'publish' command")
+	}
+	return writeInt(w, int64(m.Publish(args[1], args[2])))
+}
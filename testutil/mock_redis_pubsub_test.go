@@ -0,0 +1,70 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMockRedis_PubSub(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sub := client.Subscribe(ctx, "news")
+	defer func() { _ = sub.Close() }()
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatalf("Receive() subscription error = %v, want nil", err)
+	}
+	psub := client.PSubscribe(ctx, "news.*")
+	defer func() { _ = psub.Close() }()
+	if _, err := psub.Receive(ctx); err != nil {
+		t.Fatalf("Receive() psubscription error = %v, want nil", err)
+	}
+
+	if n, err := client.Publish(ctx, "news", "hello").Result(); err != nil || n != 1 {
+		t.Fatalf("Publish() = %d, %v, want 1", n, err)
+	}
+	msg, err := sub.ReceiveMessage(ctx)
+	if err != nil || msg.Channel != "news" || msg.Payload != "hello" {
+		t.Errorf("ReceiveMessage() = %+v, %v, want news/hello", msg, err)
+	}
+
+	if n, _ := client.Publish(ctx, "news.sports", "goal").Result(); n != 1 {
+		t.Errorf("Publish() to a pattern = %d, want 1", n)
+	}
+	msg, err = psub.ReceiveMessage(ctx)
+	if err != nil || msg.Pattern != "news.*" || msg.Channel != "news.sports" || msg.Payload != "goal" {
+		t.Errorf("ReceiveMessage() pattern = %+v, %v, want news.*/news.sports/goal", msg, err)
+	}
+
+	if err := sub.Ping(ctx); err != nil {
+		t.Errorf("Ping() on a subscribed connection error = %v, want nil", err)
+	}
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Errorf("Receive() pong error = %v, want nil", err)
+	}
+
+	if err := sub.Unsubscribe(ctx, "news"); err != nil {
+		t.Fatalf("Unsubscribe() error = %v, want nil", err)
+	}
+	if _, ok := mustReceive(ctx, t, sub).(*redis.Subscription); !ok {
+		t.Error("Receive() after Unsubscribe should return the unsubscribe confirmation")
+	}
+	if n, _ := client.Publish(ctx, "news", "again").Result(); n != 0 {
+		t.Errorf("Publish() after Unsubscribe = %d, want 0", n)
+	}
+}
+
+func mustReceive(ctx context.Context, t *testing.T, sub *redis.PubSub) interface{} {
+	t.Helper()
+	msg, err := sub.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Receive() error = %v, want nil", err)
+	}
+	return msg
+}
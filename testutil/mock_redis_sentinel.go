@@ -0,0 +1,46 @@
+package testutil
+
+import (
+	"bufio"
+	"net"
+	"strings"
+)
+
+// SetSentinelMaster makes the mock answer as a Redis Sentinel monitoring a master called
+// name at addr; dial addr with Dialer, which ignores it, to reach the mock itself
+func (m *MockRedis) SetSentinelMaster(name, addr string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sentinelMasters[name] = addr
+}
+
+// handleSentinel answers the SENTINEL subcommands a failover client issues: the master
+// address, and empty lists of other sentinels and replicas
+func (m *MockRedis) handleSentinel(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "wrong number of arguments for 'sentinel' command")
+	}
+
+	m.mu.RLock()
+	addr, ok := m.sentinelMasters[args[2]]
+	m.mu.RUnlock()
+
+	switch strings.ToUpper(args[1]) {
+	case "GET-MASTER-ADDR-BY-NAME":
+		if !ok {
+			return writeNilArray(w)
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return writeError(w, err.Error())
+		}
+		return writeArrayBulk(w, []string{host, port})
+	case "SENTINELS", "REPLICAS", "SLAVES":
+		if !ok {
+			return writeRawError(w, "ERR No such master with that name")
+		}
+		return writeArrayBulk(w, nil)
+	default:
+		return writeError(w, "unknown sentinel subcommand: "+args[1])
+	}
+}
@@ -0,0 +1,34 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMockRedis_Sentinel(t *testing.T) {
+	mock := NewMockRedis()
+	mock.SetSentinelMaster("mymaster", "10.0.0.1:6379")
+	sentinel := redis.NewSentinelClient(&redis.Options{Addr: "sentinel:26379", Dialer: mock.Dialer()})
+	defer func() { _ = sentinel.Close() }()
+
+	ctx := context.Background()
+	addr, err := sentinel.GetMasterAddrByName(ctx, "mymaster").Result()
+	if err != nil || len(addr) != 2 || addr[0] != "10.0.0.1" || addr[1] != "6379" {
+		t.Errorf("GetMasterAddrByName() = %v, %v, want [10.0.0.1 6379]", addr, err)
+	}
+	if err := sentinel.GetMasterAddrByName(ctx, "other").Err(); !errors.Is(err, redis.Nil) {
+		t.Errorf("GetMasterAddrByName() unknown master error = %v, want redis.Nil", err)
+	}
+	if sentinels, err := sentinel.Sentinels(ctx, "mymaster").Result(); err != nil || len(sentinels) != 0 {
+		t.Errorf("Sentinels() = %v, %v, want none", sentinels, err)
+	}
+	if replicas, err := sentinel.Replicas(ctx, "mymaster").Result(); err != nil || len(replicas) != 0 {
+		t.Errorf("Replicas() = %v, %v, want none", replicas, err)
+	}
+	if err := sentinel.Sentinels(ctx, "other").Err(); err == nil {
+		t.Error("Sentinels() unknown master error = nil, want error")
+	}
+}
@@ -0,0 +1,308 @@
+package testutil
+
+import (
+	"bufio"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setLocked returns the set at key; create makes it if missing
+// The caller must hold m.mu for writing
+func (m *MockRedis) setLocked(key string, create bool) (map[string]struct{}, error) {
+	val, ok := m.lookup(key)
+	if ok {
+		if val.set == nil {
+			return nil, errors.New(wrongTypeError)
+		}
+		return val.set, nil
+	}
+	if !create {
+		return nil, nil
+	}
+	set := make(map[string]struct{})
+	m.data[key] = mockValue{set: set}
+	return set, nil
+}
+
+func (m *MockRedis) handleSAdd(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "wrong number of arguments for 'sadd' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	set, err := m.setLocked(args[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	added := int64(0)
+	for _, member := range args[2:] {
+		if _, ok := set[member]; !ok {
+			set[member] = struct{}{}
+			added++
+		}
+	}
+	return writeInt(w, added)
+}
+
+func (m *MockRedis) handleSRem(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "wrong number of arguments for 'srem' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	set, err := m.setLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	removed := int64(0)
+	for _, member := range args[2:] {
+		if _, ok := set[member]; ok {
+			delete(set, member)
+			removed++
+		}
+	}
+	m.dropIfEmpty(args[1])
+	return writeInt(w, removed)
+}
+
+// handleSMembers returns members sorted, so results in tests are deterministic
+func (m *MockRedis) handleSMembers(args []string, w *bufio.Writer) error {
+	if len(args) != 2 {
+		return writeError(w, "wrong number of arguments for 'smembers' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	set, err := m.setLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return writeArrayBulk(w, members)
+}
+
+func (m *MockRedis) handleSIsMember(args []string, w *bufio.Writer) error {
+	if len(args) != 3 {
+		return writeError(w, "wrong number of arguments for 'sismember' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	set, err := m.setLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if _, ok := set[args[2]]; ok {
+		return writeInt(w, 1)
+	}
+	return writeInt(w, 0)
+}
+
+func (m *MockRedis) handleSMIsMember(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "wrong number of arguments for 'smismember' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	set, err := m.setLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	found := make([]int64, len(args)-2)
+	for i, member := range args[2:] {
+		if _, ok := set[member]; ok {
+			found[i] = 1
+		}
+	}
+	return writeArrayInt(w, found)
+}
+
+func (m *MockRedis) handleSCard(args []string, w *bufio.Writer) error {
+	if len(args) != 2 {
+		return writeError(w, "wrong number of arguments for 'scard' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	set, err := m.setLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	return writeInt(w, int64(len(set)))
+}
+
+func (m *MockRedis) handleSScan(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	set, err := m.setLocked(args[1], false)
+	if err != nil {
+		m.mu.Unlock()
+		return writeRawError(w, err.Error())
+	}
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	m.mu.Unlock()
+	sort.Strings(members)
+
+	page, next, err := scanPage(members, args[2:])
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	if err := writeArrayLen(w, 2); err != nil {
+		return err
+	}
+	if err := writeBulkString(w, strconv.Itoa(next)); err != nil {
+		return err
+	}
+	return writeArrayBulk(w, page)
+}
+
+// handleSStore implements SUNIONSTORE, SINTERSTORE, and SDIFFSTORE, selected by op
+// The destination is replaced (dropping any TTL), or deleted when the result is empty
+func (m *MockRedis) handleSStore(args []string, w *bufio.Writer, op string) error {
+	if len(args) < 3 {
+		return writeError(w, "wrong number of arguments for '"+strings.ToLower(args[0])+"' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sources := make([]map[string]struct{}, 0, len(args)-2)
+	for _, key := range args[2:] {
+		set, err := m.setLocked(key, false)
+		if err != nil {
+			return writeRawError(w, err.Error())
+		}
+		sources = append(sources, set)
+	}
+
+	result := make(map[string]struct{})
+	switch op {
+	case "union":
+		for _, set := range sources {
+			for member := range set {
+				result[member] = struct{}{}
+			}
+		}
+	case "inter":
+		for member := range sources[0] {
+			in := true
+			for _, set := range sources[1:] {
+				if _, ok := set[member]; !ok {
+					in = false
+					break
+				}
+			}
+			if in {
+				result[member] = struct{}{}
+			}
+		}
+	case "diff":
+		for member := range sources[0] {
+			in := false
+			for _, set := range sources[1:] {
+				if _, ok := set[member]; ok {
+					in = true
+					break
+				}
+			}
+			if !in {
+				result[member] = struct{}{}
+			}
+		}
+	}
+
+	delete(m.data, args[1])
+	if len(result) > 0 {
+		m.data[args[1]] = mockValue{set: result}
+	}
+	return writeInt(w, int64(len(result)))
+}
+
+// evalCacheTagAdd serves the cache's tag script: add ARGV[1] to the set KEYS[1], keeping the
+// set at least ARGV[2] milliseconds, or without expiry for 0
+func (m *MockRedis) evalCacheTagAdd(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 1 || len(argv) < 2 {
+		return writeError(w, "invalid args")
+	}
+	want, err := strconv.ParseInt(argv[1], 10, 64)
+	if err != nil {
+		return writeError(w, "value is not an integer or out of range")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, existed := m.lookup(keys[0])
+	set, err := m.setLocked(keys[0], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	set[argv[0]] = struct{}{}
+	val := m.data[keys[0]]
+	switch {
+	case want <= 0:
+		val.expiresAt = nil
+	case !existed || (val.expiresAt != nil && time.Until(*val.expiresAt) < time.Duration(want)*time.Millisecond):
+		expiresAt := time.Now().Add(time.Duration(want) * time.Millisecond)
+		val.expiresAt = &expiresAt
+	}
+	m.data[keys[0]] = val
+	return writeInt(w, 1)
+}
+
+// evalVotes serves the vote and unvote scripts: add or remove ARGV[1] from the voter set
+// KEYS[1] and move the counter KEYS[2] along, replying with the changed flag and the count
+func (m *MockRedis) evalVotes(keys, argv []string, w *bufio.Writer, vote bool) error {
+	if len(keys) < 2 || len(argv) < 1 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	set, err := m.setLocked(keys[0], vote)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	_, member := set[argv[0]]
+	if vote == member {
+		var count int64
+		if val, ok := m.lookup(keys[1]); ok {
+			count, _ = strconv.ParseInt(val.value, 10, 64)
+		}
+		m.dropIfEmpty(keys[0])
+		return writeArrayInt(w, []int64{0, count})
+	}
+
+	delta := int64(1)
+	if vote {
+		set[argv[0]] = struct{}{}
+	} else {
+		delete(set, argv[0])
+		m.dropIfEmpty(keys[0])
+		delta = -1
+	}
+	count, err := m.incrByLocked(keys[1], delta)
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	if count <= 0 {
+		delete(m.data, keys[1])
+		count = 0
+	}
+	return writeArrayInt(w, []int64{1, count})
+}
@@ -0,0 +1,105 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMockRedis_Set(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if n, err := client.SAdd(ctx, "s", "b", "a", "b").Result(); err != nil || n != 2 {
+		t.Fatalf("SAdd() = %d, %v, want 2", n, err)
+	}
+	if n, _ := client.SAdd(ctx, "s", "a", "c").Result(); n != 1 {
+		t.Errorf("SAdd() existing = %d, want 1", n)
+	}
+	if members, _ := client.SMembers(ctx, "s").Result(); len(members) != 3 || members[0] != "a" || members[2] != "c" {
+		t.Errorf("SMembers() = %v, want [a b c]", members)
+	}
+	if n, _ := client.SCard(ctx, "s").Result(); n != 3 {
+		t.Errorf("SCard() = %d, want 3", n)
+	}
+	if ok, _ := client.SIsMember(ctx, "s", "a").Result(); !ok {
+		t.Error("SIsMember(a) = false, want true")
+	}
+	if found, _ := client.SMIsMember(ctx, "s", "a", "x").Result(); len(found) != 2 || !found[0] || found[1] {
+		t.Errorf("SMIsMember() = %v, want [true false]", found)
+	}
+	if typ, _ := client.Type(ctx, "s").Result(); typ != "set" {
+		t.Errorf("Type() = %q, want set", typ)
+	}
+
+	if n, _ := client.SRem(ctx, "s", "a", "b", "c", "x").Result(); n != 3 {
+		t.Errorf("SRem() = %d, want 3", n)
+	}
+	if n, _ := client.Exists(ctx, "s").Result(); n != 0 {
+		t.Error("set still exists after removing its last member")
+	}
+
+	_ = client.Set(ctx, "str", "v", 0).Err()
+	if err := client.SAdd(ctx, "str", "a").Err(); err == nil {
+		t.Error("SAdd() on a string error = nil, want WRONGTYPE")
+	}
+}
+
+func TestMockRedis_SetStore(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	_ = client.SAdd(ctx, "a", "1", "2", "3").Err()
+	_ = client.SAdd(ctx, "b", "2", "3", "4").Err()
+
+	if n, err := client.SUnionStore(ctx, "u", "a", "b", "missing").Result(); err != nil || n != 4 {
+		t.Errorf("SUnionStore() = %d, %v, want 4", n, err)
+	}
+	if n, _ := client.SInterStore(ctx, "i", "a", "b").Result(); n != 2 {
+		t.Errorf("SInterStore() = %d, want 2", n)
+	}
+	if members, _ := client.SMembers(ctx, "i").Result(); len(members) != 2 || members[0] != "2" || members[1] != "3" {
+		t.Errorf("SInterStore() stored %v, want [2 3]", members)
+	}
+	if n, _ := client.SDiffStore(ctx, "d", "a", "b").Result(); n != 1 {
+		t.Errorf("SDiffStore() = %d, want 1", n)
+	}
+
+	_ = client.Expire(ctx, "u", time.Minute).Err()
+	if n, _ := client.SInterStore(ctx, "u", "a", "missing").Result(); n != 0 {
+		t.Errorf("SInterStore() with missing source = %d, want 0", n)
+	}
+	if n, _ := client.Exists(ctx, "u").Result(); n != 0 {
+		t.Error("empty store result left the destination in place")
+	}
+
+	for i := 0; i < 25; i++ {
+		_ = client.SAdd(ctx, "big", fmt.Sprintf("m%02d", i)).Err()
+	}
+	seen := map[string]bool{}
+	var cursor uint64
+	for {
+		members, next, err := client.SScan(ctx, "big", cursor, "", 10).Result()
+		if err != nil {
+			t.Fatalf("SScan() error = %v", err)
+		}
+		for _, member := range members {
+			seen[member] = true
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != 25 {
+		t.Errorf("SScan() collected %d members, want 25", len(seen))
+	}
+
+	_ = client.Set(ctx, "str", "v", 0).Err()
+	if err := client.SUnionStore(ctx, "u", "a", "str").Err(); err == nil {
+		t.Error("SUnionStore() with a string source error = nil, want WRONGTYPE")
+	}
+}
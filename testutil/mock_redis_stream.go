@@ -0,0 +1,980 @@
+package testutil
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBlockWait caps BLOCK 0 (wait forever) so abandoned readers do not leak goroutines
+const maxBlockWait = time.Second
+
+// blockPollInterval is how often blocking commands re-check for data
+const blockPollInterval = 2 * time.Millisecond
+
+type streamID struct {
+	ms  uint64
+	seq uint64
+}
+
+func (id streamID) String() string {
+	return strconv.FormatUint(id.ms, 10) + "-" + strconv.FormatUint(id.seq, 10)
+}
+
+func (id streamID) less(other streamID) bool {
+	return id.ms < other.ms || (id.ms == other.ms && id.seq < other.seq)
+}
+
+// parseStreamID parses "ms-seq" or "ms"; a missing sequence defaults to defSeq
+func parseStreamID(s string, defSeq uint64) (streamID, error) {
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return streamID{}, fmt.Errorf("invalid stream ID")
+	}
+	seq := defSeq
+	if hasSeq {
+		if seq, err = strconv.ParseUint(seqPart, 10, 64); err != nil {
+			return streamID{}, fmt.Errorf("invalid stream ID")
+		}
+	}
+	return streamID{ms: ms, seq: seq}, nil
+}
+
+// parseRangeID parses XRANGE bounds, including the special "-" and "+" IDs
+func parseRangeID(s string, isEnd bool) (streamID, bool, error) {
+	exclusive := strings.HasPrefix(s, "(")
+	s = strings.TrimPrefix(s, "(")
+	switch s {
+	case "-":
+		return streamID{}, exclusive, nil
+	case "+":
+		return streamID{ms: ^uint64(0), seq: ^uint64(0)}, exclusive, nil
+	}
+	defSeq := uint64(0)
+	if isEnd {
+		defSeq = ^uint64(0)
+	}
+	id, err := parseStreamID(s, defSeq)
+	return id, exclusive, err
+}
+
+type mockStreamEntry struct {
+	id     streamID
+	fields []string
+}
+
+type mockPending struct {
+	consumer    string
+	deliveredAt time.Time
+	count       int64
+}
+
+type mockGroup struct {
+	lastDelivered streamID
+	pending       map[streamID]*mockPending
+}
+
+// sortedPending returns the group's pending IDs in ascending order
+func (g *mockGroup) sortedPending() []streamID {
+	ids := make([]streamID, 0, len(g.pending))
+	for id := range g.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].less(ids[j]) })
+	return ids
+}
+
+type mockStream struct {
+	entries []mockStreamEntry
+	lastID  streamID
+	groups  map[string]*mockGroup
+}
+
+func newMockStream() *mockStream {
+	return &mockStream{groups: make(map[string]*mockGroup)}
+}
+
+// nextID returns the auto-generated ID for the next entry, as XADD * does
+func (s *mockStream) nextID() streamID {
+	now := uint64(time.Now().UnixMilli())
+	if now > s.lastID.ms {
+		return streamID{ms: now}
+	}
+	return streamID{ms: s.lastID.ms, seq: s.lastID.seq + 1}
+}
+
+func (s *mockStream) find(id streamID) (mockStreamEntry, bool) {
+	i := sort.Search(len(s.entries), func(i int) bool { return !s.entries[i].id.less(id) })
+	if i < len(s.entries) && s.entries[i].id == id {
+		return s.entries[i], true
+	}
+	return mockStreamEntry{}, false
+}
+
+func (s *mockStream) after(id streamID, count int) []mockStreamEntry {
+	var result []mockStreamEntry
+	for _, entry := range s.entries {
+		if id.less(entry.id) {
+			result = append(result, entry)
+			if count > 0 && len(result) >= count {
+				break
+			}
+		}
+	}
+	return result
+}
+
+func (s *mockStream) trimMaxLen(maxLen int) {
+	if maxLen >= 0 && len(s.entries) > maxLen {
+		s.entries = append([]mockStreamEntry(nil), s.entries[len(s.entries)-maxLen:]...)
+	}
+}
+
+func (s *mockStream) trimMinID(minID streamID) {
+	i := 0
+	for i < len(s.entries) && s.entries[i].id.less(minID) {
+		i++
+	}
+	s.entries = append([]mockStreamEntry(nil), s.entries[i:]...)
+}
+
+// streamLocked returns the stream at key; create makes it if missing
+// The caller must hold m.mu for writing
+func (m *MockRedis) streamLocked(key string, create bool) (*mockStream, error) {
+	val, ok := m.lookup(key)
+	if ok {
+		if val.stream == nil {
+			return nil, errors.New(wrongTypeError)
+		}
+		return val.stream, nil
+	}
+	if !create {
+		return nil, nil
+	}
+	stream := newMockStream()
+	m.data[key] = mockValue{stream: stream}
+	return stream, nil
+}
+
+func writeStreamEntry(w *bufio.Writer, id streamID, fields []string) error {
+	if err := writeArrayLen(w, 2); err != nil {
+		return err
+	}
+	if err := writeBulkString(w, id.String()); err != nil {
+		return err
+	}
+	if fields == nil {
+		return writeNilArray(w)
+	}
+	return writeArrayBulk(w, fields)
+}
+
+func writeStreamEntries(w *bufio.Writer, entries []mockStreamEntry) error {
+	if err := writeArrayLen(w, len(entries)); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := writeStreamEntry(w, entry.id, entry.fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTrim parses MAXLEN/MINID [=|~] threshold [LIMIT n] starting at args[i]
+// It returns the index after the trim arguments and a function applying the trim
+func parseTrim(args []string, i int) (int, func(*mockStream), error) {
+	strategy := strings.ToUpper(args[i])
+	i++
+	if i < len(args) && (args[i] == "~" || args[i] == "=") {
+		i++
+	}
+	if i >= len(args) {
+		return i, nil, fmt.Errorf("invalid args")
+	}
+	threshold := args[i]
+	i++
+	if i+1 < len(args) && strings.ToUpper(args[i]) == "LIMIT" {
+		i += 2
+	}
+
+	if strategy == "MAXLEN" {
+		maxLen, err := strconv.Atoi(threshold)
+		if err != nil || maxLen < 0 {
+			return i, nil, fmt.Errorf("invalid MAXLEN")
+		}
+		return i, func(s *mockStream) { s.trimMaxLen(maxLen) }, nil
+	}
+	minID, err := parseStreamID(threshold, 0)
+	if err != nil {
+		return i, nil, err
+	}
+	return i, func(s *mockStream) { s.trimMinID(minID) }, nil
+}
+
+func (m *MockRedis) handleXAdd(args []string, w *bufio.Writer) error {
+	if len(args) < 5 {
+		return writeError(w, "invalid args")
+	}
+
+	key := args[1]
+	i := 2
+	noMkStream := false
+	var trim func(*mockStream)
+	for i < len(args) {
+		opt := strings.ToUpper(args[i])
+		if opt == "NOMKSTREAM" {
+			noMkStream = true
+			i++
+		} else if opt == "MAXLEN" || opt == "MINID" {
+			var err error
+			if i, trim, err = parseTrim(args, i); err != nil {
+				return writeError(w, err.Error())
+			}
+		} else {
+			break
+		}
+	}
+	if i >= len(args) {
+		return writeError(w, "invalid args")
+	}
+	idArg := args[i]
+	fields := args[i+1:]
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		return writeError(w, "wrong number of arguments for 'xadd' command")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stream, err := m.streamLocked(key, !noMkStream)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if stream == nil {
+		return writeNil(w)
+	}
+
+	var id streamID
+	if idArg == "*" {
+		id = stream.nextID()
+	} else {
+		if id, err = parseStreamID(idArg, 0); err != nil {
+			return writeError(w, err.Error())
+		}
+		if !stream.lastID.less(id) {
+			return writeError(w, "The ID specified in XADD is equal or smaller than the target stream top item")
+		}
+	}
+
+	stream.entries = append(stream.entries, mockStreamEntry{id: id, fields: append([]string(nil), fields...)})
+	stream.lastID = id
+	if trim != nil {
+		trim(stream)
+	}
+	return writeBulkString(w, id.String())
+}
+
+func (m *MockRedis) handleXLen(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stream, err := m.streamLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if stream == nil {
+		return writeInt(w, 0)
+	}
+	return writeInt(w, int64(len(stream.entries)))
+}
+
+func (m *MockRedis) handleXRange(args []string, w *bufio.Writer, reverse bool) error {
+	if len(args) < 4 {
+		return writeError(w, "invalid args")
+	}
+
+	startArg, endArg := args[2], args[3]
+	if reverse {
+		startArg, endArg = endArg, startArg
+	}
+	start, startExcl, err := parseRangeID(startArg, false)
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	end, endExcl, err := parseRangeID(endArg, true)
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	count := -1
+	if len(args) >= 6 && strings.ToUpper(args[4]) == "COUNT" {
+		if count, err = strconv.Atoi(args[5]); err != nil {
+			return writeError(w, "invalid count")
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stream, err := m.streamLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if stream == nil {
+		return writeArrayLen(w, 0)
+	}
+
+	var matched []mockStreamEntry
+	for _, entry := range stream.entries {
+		if entry.id.less(start) || (startExcl && entry.id == start) {
+			continue
+		}
+		if end.less(entry.id) || (endExcl && entry.id == end) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	if reverse {
+		for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+			matched[i], matched[j] = matched[j], matched[i]
+		}
+	}
+	if count >= 0 && len(matched) > count {
+		matched = matched[:count]
+	}
+	return writeStreamEntries(w, matched)
+}
+
+func (m *MockRedis) handleXDel(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stream, err := m.streamLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if stream == nil {
+		return writeInt(w, 0)
+	}
+
+	deleted := 0
+	for _, arg := range args[2:] {
+		id, err := parseStreamID(arg, 0)
+		if err != nil {
+			return writeError(w, err.Error())
+		}
+		for i, entry := range stream.entries {
+			if entry.id == id {
+				stream.entries = append(stream.entries[:i], stream.entries[i+1:]...)
+				deleted++
+				break
+			}
+		}
+	}
+	return writeInt(w, int64(deleted))
+}
+
+func (m *MockRedis) handleXTrim(args []string, w *bufio.Writer) error {
+	if len(args) < 4 {
+		return writeError(w, "invalid args")
+	}
+
+	_, trim, err := parseTrim(args, 2)
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stream, err := m.streamLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if stream == nil {
+		return writeInt(w, 0)
+	}
+	before := len(stream.entries)
+	trim(stream)
+	return writeInt(w, int64(before-len(stream.entries)))
+}
+
+func (m *MockRedis) handleXGroup(args []string, w *bufio.Writer) error {
+	if len(args) < 4 {
+		return writeError(w, "invalid args")
+	}
+
+	sub := strings.ToUpper(args[1])
+	key, group := args[2], args[3]
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch sub {
+	case "CREATE":
+		if len(args) < 5 {
+			return writeError(w, "invalid args")
+		}
+		mkStream := len(args) > 5 && strings.ToUpper(args[5]) == "MKSTREAM"
+		stream, err := m.streamLocked(key, mkStream)
+		if err != nil {
+			return writeRawError(w, err.Error())
+		}
+		if stream == nil {
+			return writeError(w, "The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+		}
+		if _, ok := stream.groups[group]; ok {
+			return writeRawError(w, "BUSYGROUP Consumer Group name already exists")
+		}
+		start := stream.lastID
+		if args[4] != "$" {
+			if start, err = parseStreamID(args[4], 0); err != nil {
+				return writeError(w, err.Error())
+			}
+		}
+		stream.groups[group] = &mockGroup{lastDelivered: start, pending: make(map[streamID]*mockPending)}
+		return writeSimpleString(w, "OK")
+	case "DESTROY":
+		stream, err := m.streamLocked(key, false)
+		if err != nil {
+			return writeRawError(w, err.Error())
+		}
+		if stream == nil {
+			return writeInt(w, 0)
+		}
+		if _, ok := stream.groups[group]; !ok {
+			return writeInt(w, 0)
+		}
+		delete(stream.groups, group)
+		return writeInt(w, 1)
+	default:
+		return writeError(w, fmt.Sprintf("unknown XGROUP subcommand: %s", sub))
+	}
+}
+
+// groupLocked returns the consumer group or a NOGROUP error
+func (m *MockRedis) groupLocked(key, group string) (*mockStream, *mockGroup, error) {
+	stream, err := m.streamLocked(key, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if stream != nil {
+		if g, ok := stream.groups[group]; ok {
+			return stream, g, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("NOGROUP No such key '%s' or consumer group '%s'", key, group)
+}
+
+type xreadResult struct {
+	key     string
+	entries []mockStreamEntry
+}
+
+func (m *MockRedis) handleXReadGroup(args []string, w *bufio.Writer) error {
+	if len(args) < 7 || strings.ToUpper(args[1]) != "GROUP" {
+		return writeError(w, "invalid args")
+	}
+
+	group, consumer := args[2], args[3]
+	count := 0
+	block := time.Duration(-1)
+	noAck := false
+	i := 4
+	for ; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		if opt == "STREAMS" {
+			i++
+			break
+		}
+		switch opt {
+		case "COUNT":
+			if i+1 >= len(args) {
+				return writeError(w, "invalid args")
+			}
+			count, _ = strconv.Atoi(args[i+1])
+			i++
+		case "BLOCK":
+			if i+1 >= len(args) {
+				return writeError(w, "invalid args")
+			}
+			ms, _ := strconv.Atoi(args[i+1])
+			block = time.Duration(ms) * time.Millisecond
+			if ms == 0 {
+				block = maxBlockWait
+			}
+			i++
+		case "NOACK":
+			noAck = true
+		}
+	}
+
+	rest := args[i:]
+	if len(rest) == 0 || len(rest)%2 != 0 {
+		return writeError(w, "Unbalanced 'xreadgroup' list of streams")
+	}
+	keys, ids := rest[:len(rest)/2], rest[len(rest)/2:]
+
+	deadline := time.Now().Add(block)
+	for {
+		results, err := m.readGroupOnce(keys, ids, group, consumer, count, noAck)
+		if err != nil {
+			return writeRawError(w, err.Error())
+		}
+		if len(results) > 0 || block < 0 || time.Now().After(deadline) {
+			return writeXReadResults(w, results)
+		}
+		time.Sleep(blockPollInterval)
+	}
+}
+
+// writeXReadResults writes the reply of XREAD and XREADGROUP, a nil array when nothing was read
+func writeXReadResults(w *bufio.Writer, results []xreadResult) error {
+	if len(results) == 0 {
+		return writeNilArray(w)
+	}
+	if err := writeArrayLen(w, len(results)); err != nil {
+		return err
+	}
+	for _, res := range results {
+		if err := writeArrayLen(w, 2); err != nil {
+			return err
+		}
+		if err := writeBulkString(w, res.key); err != nil {
+			return err
+		}
+		if err := writeStreamEntries(w, res.entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleXRead serves XREAD [COUNT n] [BLOCK ms] STREAMS key... id..., where the ID "$"
+// stands for the last entry of the stream when the command arrives
+func (m *MockRedis) handleXRead(args []string, w *bufio.Writer) error {
+	count := 0
+	block := time.Duration(-1)
+	i := 1
+	for ; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		if opt == "STREAMS" {
+			i++
+			break
+		}
+		if i+1 >= len(args) {
+			return writeError(w, "invalid args")
+		}
+		switch opt {
+		case "COUNT":
+			count, _ = strconv.Atoi(args[i+1])
+		case "BLOCK":
+			ms, _ := strconv.Atoi(args[i+1])
+			block = time.Duration(ms) * time.Millisecond
+			if ms == 0 {
+				block = maxBlockWait
+			}
+		default:
+			return writeError(w, "syntax error")
+		}
+		i++
+	}
+
+	rest := args[min(i, len(args)):]
+	if len(rest) == 0 || len(rest)%2 != 0 {
+		return writeError(w, "Unbalanced 'xread' list of streams")
+	}
+	keys := rest[:len(rest)/2]
+	from := make([]streamID, len(keys))
+	m.mu.Lock()
+	for j, key := range keys {
+		idArg := rest[len(keys)+j]
+		if idArg != "$" {
+			id, err := parseStreamID(idArg, 0)
+			if err != nil {
+				m.mu.Unlock()
+				return writeError(w, err.Error())
+			}
+			from[j] = id
+			continue
+		}
+		stream, err := m.streamLocked(key, false)
+		if err != nil {
+			m.mu.Unlock()
+			return writeRawError(w, err.Error())
+		}
+		if stream != nil {
+			from[j] = stream.lastID
+		}
+	}
+	m.mu.Unlock()
+
+	deadline := time.Now().Add(block)
+	for {
+		results, err := m.readOnce(keys, from, count)
+		if err != nil {
+			return writeRawError(w, err.Error())
+		}
+		if len(results) > 0 || block < 0 || time.Now().After(deadline) {
+			return writeXReadResults(w, results)
+		}
+		time.Sleep(blockPollInterval)
+	}
+}
+
+func (m *MockRedis) readOnce(keys []string, from []streamID, count int) ([]xreadResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var results []xreadResult
+	for i, key := range keys {
+		stream, err := m.streamLocked(key, false)
+		if err != nil {
+			return nil, err
+		}
+		if stream == nil {
+			continue
+		}
+		if entries := stream.after(from[i], count); len(entries) > 0 {
+			results = append(results, xreadResult{key: key, entries: entries})
+		}
+	}
+	return results, nil
+}
+
+func (m *MockRedis) readGroupOnce(keys, ids []string, group, consumer string, count int, noAck bool) ([]xreadResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var results []xreadResult
+	for i, key := range keys {
+		stream, g, err := m.groupLocked(key, group)
+		if err != nil {
+			return nil, err
+		}
+
+		if ids[i] == ">" {
+			entries := stream.after(g.lastDelivered, count)
+			if len(entries) == 0 {
+				continue
+			}
+			g.lastDelivered = entries[len(entries)-1].id
+			if !noAck {
+				now := time.Now()
+				for _, entry := range entries {
+					g.pending[entry.id] = &mockPending{consumer: consumer, deliveredAt: now, count: 1}
+				}
+			}
+			results = append(results, xreadResult{key: key, entries: entries})
+			continue
+		}
+
+		// Reading history returns this consumer's pending entries, even when empty
+		from, err := parseStreamID(ids[i], 0)
+		if err != nil {
+			return nil, err
+		}
+		res := xreadResult{key: key, entries: []mockStreamEntry{}}
+		for _, id := range g.sortedPending() {
+			p := g.pending[id]
+			if p.consumer != consumer || !from.less(id) {
+				continue
+			}
+			entry, ok := stream.find(id)
+			if !ok {
+				entry = mockStreamEntry{id: id}
+			}
+			res.entries = append(res.entries, entry)
+			if count > 0 && len(res.entries) >= count {
+				break
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (m *MockRedis) handleXAck(args []string, w *bufio.Writer) error {
+	if len(args) < 4 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, g, err := m.groupLocked(args[1], args[2])
+	if err != nil {
+		return writeInt(w, 0)
+	}
+
+	acked := 0
+	for _, arg := range args[3:] {
+		id, err := parseStreamID(arg, 0)
+		if err != nil {
+			return writeError(w, err.Error())
+		}
+		if _, ok := g.pending[id]; ok {
+			delete(g.pending, id)
+			acked++
+		}
+	}
+	return writeInt(w, int64(acked))
+}
+
+func (m *MockRedis) handleXPending(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, g, err := m.groupLocked(args[1], args[2])
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	ids := g.sortedPending()
+
+	// Summary form: XPENDING key group
+	if len(args) == 3 {
+		if err := writeArrayLen(w, 4); err != nil {
+			return err
+		}
+		if err := writeInt(w, int64(len(ids))); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			for i := 0; i < 3; i++ {
+				if err := writeNil(w); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err := writeBulkString(w, ids[0].String()); err != nil {
+			return err
+		}
+		if err := writeBulkString(w, ids[len(ids)-1].String()); err != nil {
+			return err
+		}
+		perConsumer := make(map[string]int)
+		for _, id := range ids {
+			perConsumer[g.pending[id].consumer]++
+		}
+		consumers := make([]string, 0, len(perConsumer))
+		for c := range perConsumer {
+			consumers = append(consumers, c)
+		}
+		sort.Strings(consumers)
+		if err := writeArrayLen(w, len(consumers)); err != nil {
+			return err
+		}
+		for _, c := range consumers {
+			if err := writeArrayBulk(w, []string{c, strconv.Itoa(perConsumer[c])}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Extended form: XPENDING key group [IDLE ms] start end count [consumer]
+	rest := args[3:]
+	minIdle := time.Duration(0)
+	if len(rest) >= 2 && strings.ToUpper(rest[0]) == "IDLE" {
+		ms, _ := strconv.Atoi(rest[1])
+		minIdle = time.Duration(ms) * time.Millisecond
+		rest = rest[2:]
+	}
+	if len(rest) < 3 {
+		return writeError(w, "invalid args")
+	}
+	start, startExcl, err := parseRangeID(rest[0], false)
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	end, endExcl, err := parseRangeID(rest[1], true)
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	count, err := strconv.Atoi(rest[2])
+	if err != nil {
+		return writeError(w, "invalid count")
+	}
+	consumer := ""
+	if len(rest) > 3 {
+		consumer = rest[3]
+	}
+
+	now := time.Now()
+	type row struct {
+		id streamID
+		p  *mockPending
+	}
+	var rows []row
+	for _, id := range ids {
+		p := g.pending[id]
+		if id.less(start) || (startExcl && id == start) || end.less(id) || (endExcl && id == end) {
+			continue
+		}
+		if consumer != "" && p.consumer != consumer {
+			continue
+		}
+		if now.Sub(p.deliveredAt) < minIdle {
+			continue
+		}
+		rows = append(rows, row{id: id, p: p})
+		if len(rows) >= count {
+			break
+		}
+	}
+
+	if err := writeArrayLen(w, len(rows)); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := writeArrayLen(w, 4); err != nil {
+			return err
+		}
+		if err := writeBulkString(w, r.id.String()); err != nil {
+			return err
+		}
+		if err := writeBulkString(w, r.p.consumer); err != nil {
+			return err
+		}
+		if err := writeInt(w, now.Sub(r.p.deliveredAt).Milliseconds()); err != nil {
+			return err
+		}
+		if err := writeInt(w, r.p.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockRedis) handleXAutoClaim(args []string, w *bufio.Writer) error {
+	if len(args) < 6 {
+		return writeError(w, "invalid args")
+	}
+
+	key, group, consumer := args[1], args[2], args[3]
+	minIdleMs, err := strconv.Atoi(args[4])
+	if err != nil {
+		return writeError(w, "invalid min-idle-time")
+	}
+	start, err := parseStreamID(args[5], 0)
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	count := 100
+	justID := false
+	for i := 6; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "COUNT":
+			if i+1 < len(args) {
+				count, _ = strconv.Atoi(args[i+1])
+				i++
+			}
+		case "JUSTID":
+			justID = true
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stream, g, err := m.groupLocked(key, group)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+
+	now := time.Now()
+	minIdle := time.Duration(minIdleMs) * time.Millisecond
+	var claimed []mockStreamEntry
+	var deleted []string
+	next := streamID{}
+	for _, id := range g.sortedPending() {
+		if id.less(start) {
+			continue
+		}
+		if len(claimed)+len(deleted) >= count {
+			next = id
+			break
+		}
+		p := g.pending[id]
+		if now.Sub(p.deliveredAt) < minIdle {
+			continue
+		}
+		entry, ok := stream.find(id)
+		if !ok {
+			delete(g.pending, id)
+			deleted = append(deleted, id.String())
+			continue
+		}
+		p.consumer = consumer
+		p.deliveredAt = now
+		if !justID {
+			p.count++
+		}
+		claimed = append(claimed, entry)
+	}
+
+	if err := writeArrayLen(w, 3); err != nil {
+		return err
+	}
+	if err := writeBulkString(w, next.String()); err != nil {
+		return err
+	}
+	if justID {
+		ids := make([]string, len(claimed))
+		for i, entry := range claimed {
+			ids[i] = entry.id.String()
+		}
+		if err := writeArrayBulk(w, ids); err != nil {
+			return err
+		}
+	} else if err := writeStreamEntries(w, claimed); err != nil {
+		return err
+	}
+	return writeArrayBulk(w, deleted)
+}
+
+// evalQueueDeadLetter emulates the queue package's dead-letter script:
+// append the job to the dead-letter stream and ack it in the source group
+func (m *MockRedis) evalQueueDeadLetter(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 2 || len(argv) < 6 {
+		return writeError(w, "invalid args")
+	}
+	sourceID, err := parseStreamID(argv[0], 0)
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dead, err := m.streamLocked(keys[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	id := dead.nextID()
+	dead.entries = append(dead.entries, mockStreamEntry{id: id, fields: []string{
+		"payload", argv[1], "error", argv[2], "source_id", argv[0], "attempts", argv[3], "failed_at", argv[4],
+	}})
+	dead.lastID = id
+
+	_, g, err := m.groupLocked(keys[0], argv[5])
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if _, ok := g.pending[sourceID]; !ok {
+		return writeInt(w, 0)
+	}
+	delete(g.pending, sourceID)
+	return writeInt(w, 1)
+}
@@ -0,0 +1,181 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMockRedis_XAddRange(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := client.XAdd(ctx, &redis.XAddArgs{Stream: "s", Values: []string{"n", "v"}}).Err(); err != nil {
+			t.Fatalf("XAdd() error = %v, want nil", err)
+		}
+	}
+
+	if n, err := client.XLen(ctx, "s").Result(); err != nil || n != 3 {
+		t.Errorf("XLen() = %d, %v, want 3", n, err)
+	}
+	msgs, err := client.XRange(ctx, "s", "-", "+").Result()
+	if err != nil || len(msgs) != 3 {
+		t.Fatalf("XRange() = %v, %v, want 3 messages", msgs, err)
+	}
+	if msgs[0].Values["n"] != "v" {
+		t.Errorf("XRange() values = %v, want n=v", msgs[0].Values)
+	}
+	rev, err := client.XRevRangeN(ctx, "s", "+", "-", 1).Result()
+	if err != nil || len(rev) != 1 || rev[0].ID != msgs[2].ID {
+		t.Errorf("XRevRangeN() = %v, %v, want last message", rev, err)
+	}
+
+	if typ, _ := client.Type(ctx, "s").Result(); typ != "stream" {
+		t.Errorf("Type() = %q, want stream", typ)
+	}
+
+	if err := client.XAdd(ctx, &redis.XAddArgs{Stream: "s", ID: "1-1", Values: []string{"n", "v"}}).Err(); err == nil {
+		t.Error("XAdd() with smaller ID should return error")
+	}
+
+	if err := client.XAdd(ctx, &redis.XAddArgs{Stream: "s", MaxLen: 2, Values: []string{"n", "v"}}).Err(); err != nil {
+		t.Fatalf("XAdd() with MaxLen error = %v, want nil", err)
+	}
+	if n, _ := client.XLen(ctx, "s").Result(); n != 2 {
+		t.Errorf("XLen() after MaxLen = %d, want 2", n)
+	}
+	if n, err := client.XDel(ctx, "s", msgs[0].ID, "0-0").Result(); err != nil || n != 0 {
+		t.Errorf("XDel() trimmed IDs = %d, %v, want 0", n, err)
+	}
+	if n, err := client.XTrimMaxLen(ctx, "s", 1).Result(); err != nil || n != 1 {
+		t.Errorf("XTrimMaxLen() = %d, %v, want 1", n, err)
+	}
+}
+
+func TestMockRedis_XRead(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	first, _ := client.XAdd(ctx, &redis.XAddArgs{Stream: "s", Values: []string{"n", "1"}}).Result()
+	_ = client.XAdd(ctx, &redis.XAddArgs{Stream: "s", Values: []string{"n", "2"}}).Err()
+
+	res, err := client.XRead(ctx, &redis.XReadArgs{Streams: []string{"s", "0"}, Block: -1}).Result()
+	if err != nil || len(res) != 1 || len(res[0].Messages) != 2 {
+		t.Fatalf("XRead() from 0 = %v, %v, want 2 messages", res, err)
+	}
+	res, _ = client.XRead(ctx, &redis.XReadArgs{Streams: []string{"s", first}, Count: 5, Block: -1}).Result()
+	if len(res) != 1 || len(res[0].Messages) != 1 || res[0].Messages[0].Values["n"] != "2" {
+		t.Errorf("XRead() after the first ID = %v, want the second message", res)
+	}
+
+	// "$" only returns entries added while blocking
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = client.XAdd(ctx, &redis.XAddArgs{Stream: "s", Values: []string{"n", "3"}}).Err()
+	}()
+	res, err = client.XRead(ctx, &redis.XReadArgs{Streams: []string{"s", "$"}, Block: time.Second}).Result()
+	if err != nil || len(res) != 1 || len(res[0].Messages) != 1 || res[0].Messages[0].Values["n"] != "3" {
+		t.Errorf("XRead() from $ = %v, %v, want the new message", res, err)
+	}
+
+	if err := client.XRead(ctx, &redis.XReadArgs{Streams: []string{"s", "$"}, Block: 10 * time.Millisecond}).Err(); err != redis.Nil {
+		t.Errorf("XRead() timeout error = %v, want redis.Nil", err)
+	}
+	if err := client.XRead(ctx, &redis.XReadArgs{Streams: []string{"missing", "0"}, Block: -1}).Err(); err != redis.Nil {
+		t.Errorf("XRead() of a missing stream error = %v, want redis.Nil", err)
+	}
+}
+
+func TestMockRedis_ConsumerGroups(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	if err := client.XGroupCreateMkStream(ctx, "jobs", "g", "$").Err(); err != nil {
+		t.Fatalf("XGroupCreateMkStream() error = %v, want nil", err)
+	}
+	if err := client.XGroupCreateMkStream(ctx, "jobs", "g", "$").Err(); err == nil || !redis.HasErrorPrefix(err, "BUSYGROUP") {
+		t.Errorf("XGroupCreateMkStream() twice error = %v, want BUSYGROUP", err)
+	}
+
+	id, _ := client.XAdd(ctx, &redis.XAddArgs{Stream: "jobs", Values: []string{"payload", "1"}}).Result()
+
+	streams, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "g", Consumer: "c1", Streams: []string{"jobs", ">"}, Count: 10, Block: -1,
+	}).Result()
+	if err != nil || len(streams) != 1 || len(streams[0].Messages) != 1 {
+		t.Fatalf("XReadGroup() = %v, %v, want one message", streams, err)
+	}
+
+	// Nothing new: a non-blocking read returns redis.Nil
+	_, err = client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "g", Consumer: "c1", Streams: []string{"jobs", ">"}, Block: -1,
+	}).Result()
+	if err != redis.Nil {
+		t.Errorf("XReadGroup() on empty stream error = %v, want redis.Nil", err)
+	}
+
+	// A blocking read times out with redis.Nil
+	start := time.Now()
+	_, err = client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "g", Consumer: "c1", Streams: []string{"jobs", ">"}, Block: 20 * time.Millisecond,
+	}).Result()
+	if err != redis.Nil || time.Since(start) < 20*time.Millisecond {
+		t.Errorf("XReadGroup() with BLOCK error = %v after %v, want redis.Nil after 20ms", err, time.Since(start))
+	}
+
+	summary, err := client.XPending(ctx, "jobs", "g").Result()
+	if err != nil || summary.Count != 1 || summary.Consumers["c1"] != 1 {
+		t.Errorf("XPending() = %+v, %v, want 1 pending for c1", summary, err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	claimed, _, err := client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream: "jobs", Group: "g", Consumer: "c2", MinIdle: time.Millisecond, Start: "0-0", Count: 10,
+	}).Result()
+	if err != nil || len(claimed) != 1 || claimed[0].ID != id {
+		t.Fatalf("XAutoClaim() = %v, %v, want %s", claimed, err, id)
+	}
+
+	ext, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: "jobs", Group: "g", Start: "-", End: "+", Count: 10,
+	}).Result()
+	if err != nil || len(ext) != 1 || ext[0].Consumer != "c2" || ext[0].RetryCount != 2 {
+		t.Errorf("XPendingExt() = %+v, %v, want c2 with 2 deliveries", ext, err)
+	}
+
+	if n, err := client.XAck(ctx, "jobs", "g", id).Result(); err != nil || n != 1 {
+		t.Errorf("XAck() = %d, %v, want 1", n, err)
+	}
+	if summary, _ := client.XPending(ctx, "jobs", "g").Result(); summary.Count != 0 {
+		t.Errorf("XPending() after ack count = %d, want 0", summary.Count)
+	}
+
+	err = client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "missing", Consumer: "c1", Streams: []string{"jobs", ">"}, Block: -1,
+	}).Err()
+	if err == nil || !redis.HasErrorPrefix(err, "NOGROUP") {
+		t.Errorf("XReadGroup() unknown group error = %v, want NOGROUP", err)
+	}
+
+	if n, err := client.XGroupDestroy(ctx, "jobs", "g").Result(); err != nil || n != 1 {
+		t.Errorf("XGroupDestroy() = %d, %v, want 1", n, err)
+	}
+}
+
+func TestMockRedis_StreamWrongType(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	_ = client.Set(ctx, "str", "v", 0).Err()
+	err := client.XAdd(ctx, &redis.XAddArgs{Stream: "str", Values: []string{"a", "b"}}).Err()
+	if err == nil || !redis.HasErrorPrefix(err, "WRONGTYPE") {
+		t.Errorf("XAdd() on string error = %v, want WRONGTYPE", err)
+	}
+}
@@ -3,6 +3,7 @@ package testutil
 import (
 	"bufio"
 	"context"
+	"fmt"
 	"net"
 	"strings"
 	"testing"
@@ -69,6 +70,44 @@ func TestNewMockRedisClient(t *testing.T) {
 	}
 }
 
+func TestNewMockRingClient(t *testing.T) {
+	ring, _ := NewMockRingClient()
+	defer func() { _ = ring.Close() }()
+
+	ctx := context.Background()
+	if err := ring.Set(ctx, "k", "v", 0).Err(); err != nil {
+		t.Fatalf("Set() through ring error = %v, want nil", err)
+	}
+	if got, err := ring.Get(ctx, "k").Result(); err != nil || got != "v" {
+		t.Errorf("Get() through ring = %q, %v, want v", got, err)
+	}
+}
+
+func TestNewMockClusterClient(t *testing.T) {
+	cluster, mocks := NewMockClusterClient(3)
+	defer func() { _ = cluster.Close() }()
+
+	ctx := context.Background()
+	for i := 0; i < 30; i++ {
+		if err := cluster.Set(ctx, fmt.Sprintf("k%d", i), "v", 0).Err(); err != nil {
+			t.Fatalf("Set() through cluster error = %v, want nil", err)
+		}
+	}
+	if got, err := cluster.Get(ctx, "k7").Result(); err != nil || got != "v" {
+		t.Errorf("Get() through cluster = %q, %v, want v", got, err)
+	}
+	total := 0
+	for i, mock := range mocks {
+		if len(mock.data) == 0 {
+			t.Errorf("mock %d holds no keys, want the slots spread over every node", i)
+		}
+		total += len(mock.data)
+	}
+	if total != 30 {
+		t.Errorf("mocks hold %d keys, want 30", total)
+	}
+}
+
 func TestMockRedis_SET_GET(t *testing.T) {
 	client, _ := NewMockRedisClient()
 	defer func() { _ = client.Close() }()
@@ -772,8 +811,8 @@ func TestMockRedis_UnknownCommand(t *testing.T) {
 
 	ctx := context.Background()
 
-	// Try to use HSET which is not supported
-	err := client.HSet(ctx, "hashkey", "field", "value").Err()
+	// Try to use a command the mock does not implement
+	err := client.Do(ctx, "OBJECT", "ENCODING", "hashkey").Err()
 	if err == nil {
 		t.Error("Unsupported command should return error")
 	}
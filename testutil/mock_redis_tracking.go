@@ -0,0 +1,172 @@
+package testutil
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// trackingChannel is the channel RESP2 clients subscribe to for redirected invalidations
+const trackingChannel = "__redis__:invalidate"
+
+// handleTracking serves CLIENT TRACKING ON|OFF [REDIRECT id] [BCAST] [PREFIX prefix]...
+// Only broadcasting mode is supported: the redirect target receives an invalidation for every
+// written key that starts with one of the prefixes, or for every written key without prefixes
+func (m *MockRedis) handleTracking(c *mockClient, args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "wrong number of arguments for 'client|tracking' command")
+	}
+
+	switch strings.ToUpper(args[2]) {
+	case "OFF":
+		m.mu.Lock()
+		c.tracking, c.redirect, c.prefixes = false, 0, nil
+		m.mu.Unlock()
+		return writeSimpleString(w, "OK")
+	case "ON":
+	default:
+		return writeError(w, "syntax error")
+	}
+
+	var redirect int64
+	var prefixes []string
+	bcast := false
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "REDIRECT":
+			if i+1 >= len(args) {
+				return writeError(w, "syntax error")
+			}
+			id, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return writeError(w, "value is not an integer or out of range")
+			}
+			redirect = id
+			i++
+		case "PREFIX":
+			if i+1 >= len(args) {
+				return writeError(w, "syntax error")
+			}
+			prefixes = append(prefixes, args[i+1])
+			i++
+		case "BCAST":
+			bcast = true
+		case "NOLOOP", "OPTIN", "OPTOUT":
+		default:
+			return writeError(w, "syntax error")
+		}
+	}
+	if !bcast {
+		return writeError(w, "mock redis supports only BCAST tracking")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[redirect]; redirect != 0 && !ok {
+		return writeError(w, "The client ID you want redirect to does not exist")
+	}
+	c.tracking, c.redirect, c.prefixes = true, redirect, prefixes
+	return writeSimpleString(w, "OK")
+}
+
+// writtenKeys returns the keys a write command modifies, and whether it flushes every key;
+// reads and commands the mock does not track return nothing
+func writtenKeys(args []string) (keys []string, flush bool) {
+	if len(args) < 2 {
+		if len(args) == 1 && strings.EqualFold(args[0], "FLUSHDB") {
+			return nil, true
+		}
+		return nil, false
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "SET", "GETEX", "GETDEL", "INCR", "INCRBY", "DECRBY", "EXPIRE", "PEXPIRE", "PERSIST",
+		"XADD", "XDEL", "XTRIM", "XACK", "HSET", "HSETNX", "HDEL", "HINCRBY", "HINCRBYFLOAT",
+		"ZADD", "ZINCRBY", "ZREM", "ZREMRANGEBYSCORE", "ZREMRANGEBYRANK",
+		"LPUSH", "RPUSH", "LPOP", "RPOP", "SADD", "SREM", "SETBIT", "PFADD", "GEOADD",
+		"SUNIONSTORE", "SINTERSTORE", "SDIFFSTORE", "PFMERGE":
+		return args[1:2], false
+	case "DEL", "UNLINK":
+		return args[1:], false
+	case "BLPOP", "BRPOP":
+		return args[1 : len(args)-1], false
+	case "EVAL", "EVALSHA":
+		// Scripts are assumed to write every key they declare
+		if len(args) < 3 {
+			return nil, false
+		}
+		n, err := strconv.Atoi(args[2])
+		if err != nil || n < 0 || 3+n > len(args) {
+			return nil, false
+		}
+		return args[3 : 3+n], false
+	case "FLUSHDB":
+		return nil, true
+	}
+	return nil, false
+}
+
+// invalidate sends the redirect targets of tracking clients the written keys matching their
+// prefixes, or a flush of every key
+func (m *MockRedis) invalidate(keys []string, flush bool) {
+	if len(keys) == 0 && !flush {
+		return
+	}
+
+	type delivery struct {
+		sub  *mockSubscriber
+		keys []string
+	}
+	var deliveries []delivery
+	m.mu.RLock()
+	for _, c := range m.clients {
+		if !c.tracking {
+			continue
+		}
+		target, ok := m.clients[c.redirect]
+		if !ok {
+			continue
+		}
+		var matched []string
+		for _, key := range keys {
+			if len(c.prefixes) == 0 || hasAnyPrefix(key, c.prefixes) {
+				matched = append(matched, key)
+			}
+		}
+		if flush || len(matched) > 0 {
+			deliveries = append(deliveries, delivery{sub: target.sub, keys: matched})
+		}
+	}
+	m.mu.RUnlock()
+
+	m.psMu.Lock()
+	defer m.psMu.Unlock()
+	for _, d := range deliveries {
+		if _, ok := d.sub.channels[trackingChannel]; !ok {
+			continue
+		}
+		var buf bytes.Buffer
+		w := bufio.NewWriter(&buf)
+		_ = writeArrayLen(w, 3)
+		_ = writeBulkString(w, "message")
+		_ = writeBulkString(w, trackingChannel)
+		if flush {
+			_ = writeNil(w)
+		} else {
+			_ = writeArrayBulk(w, d.keys)
+		}
+		if err := w.Flush(); err == nil {
+			_, _ = d.sub.queue.Write(buf.Bytes())
+		}
+	}
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package testutil
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const wrongTypeError = "WRONGTYPE Operation against a key holding the wrong kind of value"
+
+// typeName returns the Redis type of the value as reported by TYPE
+func (v mockValue) typeName() string {
+	switch {
+	case v.stream != nil:
+		return "stream"
+	case v.hash != nil:
+		return "hash"
+	case v.zset != nil:
+		return "zset"
+	case v.set != nil:
+		return "set"
+	case v.list != nil:
+		return "list"
+	default:
+		return "string"
+	}
+}
+
+// lookup returns the live value stored at key, deleting it if expired
+// The caller must hold m.mu for writing
+func (m *MockRedis) lookup(key string) (mockValue, bool) {
+	val, ok := m.data[key]
+	if !ok {
+		return mockValue{}, false
+	}
+	if val.expiresAt != nil && time.Now().After(*val.expiresAt) {
+		delete(m.data, key)
+		return mockValue{}, false
+	}
+	return val, true
+}
+
+func (m *MockRedis) handleType(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	val, ok := m.lookup(args[1])
+	m.mu.Unlock()
+	if !ok {
+		return writeSimpleString(w, "none")
+	}
+	return writeSimpleString(w, val.typeName())
+}
+
+// writeRawError writes an error reply without the generic ERR prefix
+func writeRawError(w *bufio.Writer, msg string) error {
+	_, err := w.WriteString("-" + msg + "\r\n")
+	return err
+}
+
+func writeNilArray(w *bufio.Writer) error {
+	_, err := w.WriteString("*-1\r\n")
+	return err
+}
+
+func writeArrayLen(w *bufio.Writer, n int) error {
+	_, err := w.WriteString("*" + strconv.Itoa(n) + "\r\n")
+	return err
+}
+
+// mockKeyOverhead approximates the per-key bookkeeping MEMORY USAGE includes
+const mockKeyOverhead = 48
+
+// memoryUsage estimates the bytes used by key and its value, like MEMORY USAGE
+// The numbers are not Redis's, but grow with the size of the key and its contents
+func (v mockValue) memoryUsage(key string) int64 {
+	n := int64(mockKeyOverhead + len(key) + len(v.value))
+	switch {
+	case v.stream != nil:
+		for _, e := range v.stream.entries {
+			for _, f := range e.fields {
+				n += int64(len(f))
+			}
+		}
+	case v.hash != nil:
+		for f, val := range v.hash {
+			n += int64(len(f) + len(val))
+		}
+	case v.zset != nil:
+		for member := range v.zset {
+			n += int64(len(member) + 8)
+		}
+	case v.set != nil:
+		for member := range v.set {
+			n += int64(len(member))
+		}
+	case v.list != nil:
+		for _, item := range v.list.items {
+			n += int64(len(item))
+		}
+	case v.hll != nil:
+		for member := range v.hll {
+			n += int64(len(member))
+		}
+	}
+	return n
+}
+
+// handleMemory serves MEMORY USAGE key [SAMPLES count]
+func (m *MockRedis) handleMemory(args []string, w *bufio.Writer) error {
+	if len(args) < 3 || strings.ToUpper(args[1]) != "USAGE" {
+		return writeError(w, "unknown MEMORY subcommand")
+	}
+
+	m.mu.Lock()
+	val, ok := m.lookup(args[2])
+	m.mu.Unlock()
+	if !ok {
+		return writeNil(w)
+	}
+	return writeInt(w, val.memoryUsage(args[2]))
+}
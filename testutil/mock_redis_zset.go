@@ -0,0 +1,859 @@
+package testutil
+
+import (
+	"bufio"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type zmember struct {
+	member string
+	score  float64
+}
+
+// zsetLocked returns the sorted set at key; create makes it if missing
+// The caller must hold m.mu for writing
+func (m *MockRedis) zsetLocked(key string, create bool) (map[string]float64, error) {
+	val, ok := m.lookup(key)
+	if ok {
+		if val.zset == nil {
+			return nil, errors.New(wrongTypeError)
+		}
+		return val.zset, nil
+	}
+	if !create {
+		return nil, nil
+	}
+	zset := make(map[string]float64)
+	m.data[key] = mockValue{zset: zset}
+	return zset, nil
+}
+
+// sortedZSet returns the members ordered by score, then lexicographically
+func sortedZSet(zset map[string]float64) []zmember {
+	members := make([]zmember, 0, len(zset))
+	for member, score := range zset {
+		members = append(members, zmember{member: member, score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].score != members[j].score {
+			return members[i].score < members[j].score
+		}
+		return members[i].member < members[j].member
+	})
+	return members
+}
+
+func parseScore(s string) (float64, error) {
+	switch strings.ToLower(s) {
+	case "+inf", "inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, errors.New("value is not a valid float")
+	}
+	return f, nil
+}
+
+// parseScoreBound parses a ZRANGEBYSCORE bound such as "5", "(5", or "-inf"
+func parseScoreBound(s string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(s, "(")
+	f, err := parseScore(strings.TrimPrefix(s, "("))
+	if err != nil {
+		return 0, false, errors.New("min or max is not a float")
+	}
+	return f, exclusive, nil
+}
+
+func formatScore(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func inScoreRange(score, lo float64, loExcl bool, hi float64, hiExcl bool) bool {
+	if score < lo || (loExcl && score == lo) {
+		return false
+	}
+	if score > hi || (hiExcl && score == hi) {
+		return false
+	}
+	return true
+}
+
+// normalizeIndexRange converts Redis start/stop indexes (negative from the end) into a slice range
+func normalizeIndexRange(start, stop, n int) (int, int, bool) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop || start >= n {
+		return 0, 0, false
+	}
+	return start, stop + 1, true
+}
+
+func writeZMembers(w *bufio.Writer, members []zmember, withScores bool) error {
+	flat := make([]string, 0, 2*len(members))
+	for _, zm := range members {
+		flat = append(flat, zm.member)
+		if withScores {
+			flat = append(flat, formatScore(zm.score))
+		}
+	}
+	return writeArrayBulk(w, flat)
+}
+
+func (m *MockRedis) handleZAdd(args []string, w *bufio.Writer) error {
+	if len(args) < 4 {
+		return writeError(w, "invalid args")
+	}
+
+	var nx, xx, gt, lt, ch, incr bool
+	i := 2
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GT":
+			gt = true
+		case "LT":
+			lt = true
+		case "CH":
+			ch = true
+		case "INCR":
+			incr = true
+		default:
+			break options
+		}
+	}
+	rest := args[i:]
+	if len(rest) == 0 || len(rest)%2 != 0 || (nx && xx) || (incr && len(rest) != 2) {
+		return writeError(w, "syntax error")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(args[1], !xx)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if zset == nil {
+		if incr {
+			return writeNil(w)
+		}
+		return writeInt(w, 0)
+	}
+
+	changed := 0
+	added := 0
+	for j := 0; j < len(rest); j += 2 {
+		score, err := parseScore(rest[j])
+		if err != nil {
+			return writeError(w, err.Error())
+		}
+		member := rest[j+1]
+		old, exists := zset[member]
+		if (nx && exists) || (xx && !exists) {
+			if incr {
+				m.dropIfEmpty(args[1])
+				return writeNil(w)
+			}
+			continue
+		}
+		if incr && exists {
+			score += old
+		}
+		if exists && ((gt && score <= old) || (lt && score >= old)) {
+			if incr {
+				return writeNil(w)
+			}
+			continue
+		}
+		zset[member] = score
+		if !exists {
+			added++
+			changed++
+		} else if old != score {
+			changed++
+		}
+		if incr {
+			return writeBulkString(w, formatScore(score))
+		}
+	}
+	m.dropIfEmpty(args[1])
+	if ch {
+		return writeInt(w, int64(changed))
+	}
+	return writeInt(w, int64(added))
+}
+
+func (m *MockRedis) handleZIncrBy(args []string, w *bufio.Writer) error {
+	if len(args) < 4 {
+		return writeError(w, "invalid args")
+	}
+	delta, err := parseScore(args[2])
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(args[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	zset[args[3]] += delta
+	return writeBulkString(w, formatScore(zset[args[3]]))
+}
+
+func (m *MockRedis) handleZRem(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	removed := 0
+	for _, member := range args[2:] {
+		if _, ok := zset[member]; ok {
+			delete(zset, member)
+			removed++
+		}
+	}
+	m.dropIfEmpty(args[1])
+	return writeInt(w, int64(removed))
+}
+
+func (m *MockRedis) handleZScore(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	score, ok := zset[args[2]]
+	if !ok {
+		return writeNil(w)
+	}
+	return writeBulkString(w, formatScore(score))
+}
+
+func (m *MockRedis) handleZMScore(args []string, w *bufio.Writer) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if err := writeArrayLen(w, len(args)-2); err != nil {
+		return err
+	}
+	for _, member := range args[2:] {
+		score, ok := zset[member]
+		if !ok {
+			if err := writeNil(w); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeBulkString(w, formatScore(score)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockRedis) handleZCard(args []string, w *bufio.Writer) error {
+	if len(args) < 2 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	return writeInt(w, int64(len(zset)))
+}
+
+func (m *MockRedis) handleZCount(args []string, w *bufio.Writer) error {
+	if len(args) < 4 {
+		return writeError(w, "invalid args")
+	}
+	lo, loExcl, err := parseScoreBound(args[2])
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	hi, hiExcl, err := parseScoreBound(args[3])
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	count := 0
+	for _, score := range zset {
+		if inScoreRange(score, lo, loExcl, hi, hiExcl) {
+			count++
+		}
+	}
+	return writeInt(w, int64(count))
+}
+
+func (m *MockRedis) handleZRank(args []string, w *bufio.Writer, reverse bool) error {
+	if len(args) < 3 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	if _, ok := zset[args[2]]; !ok {
+		return writeNil(w)
+	}
+	members := sortedZSet(zset)
+	for i, zm := range members {
+		if zm.member == args[2] {
+			if reverse {
+				return writeInt(w, int64(len(members)-1-i))
+			}
+			return writeInt(w, int64(i))
+		}
+	}
+	return writeNil(w)
+}
+
+// zrangeQuery is the normalized form of the ZRANGE family of commands
+type zrangeQuery struct {
+	key        string
+	start      string
+	stop       string
+	byScore    bool
+	reverse    bool
+	withScores bool
+	offset     int
+	count      int
+}
+
+// parseZRangeOptions reads [BYSCORE] [REV] [LIMIT offset count] [WITHSCORES] from args
+func parseZRangeOptions(q *zrangeQuery, args []string) error {
+	for i := 0; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "BYSCORE":
+			q.byScore = true
+		case "REV":
+			q.reverse = true
+		case "WITHSCORES":
+			q.withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				return errors.New("syntax error")
+			}
+			offset, err1 := strconv.Atoi(args[i+1])
+			count, err2 := strconv.Atoi(args[i+2])
+			if err1 != nil || err2 != nil {
+				return errors.New("value is not an integer or out of range")
+			}
+			q.offset, q.count = offset, count
+			i += 2
+		default:
+			return errors.New("syntax error")
+		}
+	}
+	return nil
+}
+
+// selectZRange returns the members matched by q in reply order
+func selectZRange(zset map[string]float64, q zrangeQuery) ([]zmember, error) {
+	members := sortedZSet(zset)
+
+	if !q.byScore {
+		start, err1 := strconv.Atoi(q.start)
+		stop, err2 := strconv.Atoi(q.stop)
+		if err1 != nil || err2 != nil {
+			return nil, errors.New("value is not an integer or out of range")
+		}
+		if q.reverse {
+			for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+				members[i], members[j] = members[j], members[i]
+			}
+		}
+		from, to, ok := normalizeIndexRange(start, stop, len(members))
+		if !ok {
+			return nil, nil
+		}
+		return members[from:to], nil
+	}
+
+	minArg, maxArg := q.start, q.stop
+	if q.reverse {
+		minArg, maxArg = maxArg, minArg
+	}
+	lo, loExcl, err := parseScoreBound(minArg)
+	if err != nil {
+		return nil, err
+	}
+	hi, hiExcl, err := parseScoreBound(maxArg)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []zmember
+	for _, zm := range members {
+		if inScoreRange(zm.score, lo, loExcl, hi, hiExcl) {
+			matched = append(matched, zm)
+		}
+	}
+	if q.reverse {
+		for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+			matched[i], matched[j] = matched[j], matched[i]
+		}
+	}
+	if q.offset > 0 {
+		if q.offset >= len(matched) {
+			return nil, nil
+		}
+		matched = matched[q.offset:]
+	}
+	if q.count >= 0 && q.count < len(matched) {
+		matched = matched[:q.count]
+	}
+	return matched, nil
+}
+
+// handleZRange serves ZRANGE, ZREVRANGE, ZRANGEBYSCORE, and ZREVRANGEBYSCORE
+func (m *MockRedis) handleZRange(args []string, w *bufio.Writer, byScore, reverse bool) error {
+	if len(args) < 4 {
+		return writeError(w, "invalid args")
+	}
+
+	q := zrangeQuery{key: args[1], start: args[2], stop: args[3], byScore: byScore, reverse: reverse, count: -1}
+	if err := parseZRangeOptions(&q, args[4:]); err != nil {
+		return writeError(w, err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(q.key, false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	members, err := selectZRange(zset, q)
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	return writeZMembers(w, members, q.withScores)
+}
+
+// handleZRemRange serves ZREMRANGEBYSCORE and ZREMRANGEBYRANK
+func (m *MockRedis) handleZRemRange(args []string, w *bufio.Writer, byScore bool) error {
+	if len(args) < 4 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(args[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	members, err := selectZRange(zset, zrangeQuery{start: args[2], stop: args[3], byScore: byScore, count: -1})
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	for _, zm := range members {
+		delete(zset, zm.member)
+	}
+	m.dropIfEmpty(args[1])
+	return writeInt(w, int64(len(members)))
+}
+
+// evalSchedulePop emulates the schedule package's pop script: remove up to ARGV[2]
+// members due at or before ARGV[1] and return id, payload, due triples
+func (m *MockRedis) evalSchedulePop(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 2 || len(argv) < 2 {
+		return writeError(w, "invalid args")
+	}
+	limit, err := strconv.Atoi(argv[1])
+	if err != nil {
+		return writeError(w, "invalid limit")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(keys[0], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	due, err := selectZRange(zset, zrangeQuery{start: "-inf", stop: argv[0], byScore: true, count: limit})
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+	hash, err := m.hashLocked(keys[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+
+	var out []string
+	for _, zm := range due {
+		delete(zset, zm.member)
+		payload, ok := hash[zm.member]
+		if !ok {
+			continue
+		}
+		delete(hash, zm.member)
+		out = append(out, zm.member, payload, formatScore(zm.score))
+	}
+	m.dropIfEmpty(keys[0])
+	m.dropIfEmpty(keys[1])
+	return writeArrayBulk(w, out)
+}
+
+// workerpoolState holds the containers a workerpool script works on:
+// KEYS = pending, leases, tasks, owners, attempts
+// The caller must hold m.mu for writing
+type workerpoolState struct {
+	keys                    []string
+	pending, leases         map[string]float64
+	tasks, owners, attempts map[string]string
+}
+
+func (m *MockRedis) workerpoolLocked(keys []string) (*workerpoolState, error) {
+	if len(keys) < 5 {
+		return nil, errors.New("invalid args")
+	}
+	s := &workerpoolState{keys: keys}
+	var err error
+	if s.pending, err = m.zsetLocked(keys[0], true); err != nil {
+		return nil, err
+	}
+	if s.leases, err = m.zsetLocked(keys[1], true); err != nil {
+		return nil, err
+	}
+	if s.tasks, err = m.hashLocked(keys[2], true); err != nil {
+		return nil, err
+	}
+	if s.owners, err = m.hashLocked(keys[3], true); err != nil {
+		return nil, err
+	}
+	if s.attempts, err = m.hashLocked(keys[4], true); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (m *MockRedis) dropWorkerpoolEmpty(s *workerpoolState) {
+	for _, key := range s.keys[:5] {
+		m.dropIfEmpty(key)
+	}
+}
+
+// requeueExpired mirrors the requeue part of the workerpool scripts
+func (s *workerpoolState) requeueExpired(now float64) int64 {
+	var n int64
+	for id, expiry := range s.leases {
+		if expiry <= now {
+			delete(s.leases, id)
+			delete(s.owners, id)
+			s.pending[id] = now
+			n++
+		}
+	}
+	return n
+}
+
+func (m *MockRedis) evalWorkerpoolRequeue(keys, argv []string, w *bufio.Writer) error {
+	if len(argv) < 1 {
+		return writeError(w, "invalid args")
+	}
+	now, err := parseScore(argv[0])
+	if err != nil {
+		return writeError(w, "invalid now")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, err := m.workerpoolLocked(keys)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	n := s.requeueExpired(now)
+	m.dropWorkerpoolEmpty(s)
+	return writeInt(w, n)
+}
+
+func (m *MockRedis) evalWorkerpoolClaim(keys, argv []string, w *bufio.Writer) error {
+	if len(argv) < 4 {
+		return writeError(w, "invalid args")
+	}
+	now, err1 := parseScore(argv[0])
+	limit, err2 := strconv.Atoi(argv[1])
+	leaseMs, err3 := parseScore(argv[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, err := m.workerpoolLocked(keys)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	s.requeueExpired(now)
+
+	var out []string
+	members := sortedZSet(s.pending)
+	for i := 0; i < limit && i < len(members); i++ {
+		id := members[i].member
+		delete(s.pending, id)
+		payload, ok := s.tasks[id]
+		if !ok {
+			continue
+		}
+		s.leases[id] = now + leaseMs
+		s.owners[id] = argv[3]
+		attempts, _ := strconv.ParseInt(s.attempts[id], 10, 64)
+		attempts++
+		s.attempts[id] = strconv.FormatInt(attempts, 10)
+		out = append(out, id, payload, strconv.FormatInt(attempts, 10))
+	}
+	m.dropWorkerpoolEmpty(s)
+	return writeArrayBulk(w, out)
+}
+
+// evalWorkerpoolSettle serves the extend, complete, and release scripts, which all check
+// ARGV[2] against the lease owner of task ARGV[1] first
+func (m *MockRedis) evalWorkerpoolSettle(script string, keys, argv []string, w *bufio.Writer) error {
+	if len(argv) < 2 {
+		return writeError(w, "invalid args")
+	}
+	id := argv[0]
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, err := m.workerpoolLocked(keys)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	defer m.dropWorkerpoolEmpty(s)
+	if owner, ok := s.owners[id]; !ok || owner != argv[1] {
+		return writeInt(w, 0)
+	}
+
+	if strings.Contains(script, "redis-kit:workerpool-complete") {
+		delete(s.leases, id)
+		delete(s.tasks, id)
+		delete(s.owners, id)
+		delete(s.attempts, id)
+		return writeInt(w, 1)
+	}
+
+	if len(argv) < 3 {
+		return writeError(w, "invalid args")
+	}
+	score, err := parseScore(argv[2])
+	if err != nil {
+		return writeError(w, "invalid score")
+	}
+	if strings.Contains(script, "redis-kit:workerpool-extend") {
+		s.leases[id] = score
+	} else {
+		delete(s.leases, id)
+		delete(s.owners, id)
+		s.pending[id] = score
+	}
+	return writeInt(w, 1)
+}
+
+// evalPQueuePop emulates the pqueue package's pop script: remove the member with the
+// highest or lowest score, chosen by ARGV[1], and return it with its score
+func (m *MockRedis) evalPQueuePop(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 1 || len(argv) < 1 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(keys[0], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	members := sortedZSet(zset)
+	if len(members) == 0 {
+		return writeArrayBulk(w, nil)
+	}
+
+	zm := members[0]
+	if argv[0] == "highest" {
+		zm = members[len(members)-1]
+	}
+	delete(zset, zm.member)
+	m.dropIfEmpty(keys[0])
+	return writeArrayBulk(w, []string{zm.member, formatScore(zm.score)})
+}
+
+// lruTouchLocked scores member in the index zset like the cache package's LRU scripts:
+// the current time in microseconds, kept strictly above the highest score
+// The caller must hold m.mu for writing
+func lruTouchLocked(index map[string]float64, member string) {
+	now := float64(time.Now().UnixMicro())
+	for _, score := range index {
+		if score >= now {
+			now = score + 1
+		}
+	}
+	index[member] = now
+}
+
+// evalLRUSet emulates the cache package's LRU set script: store KEYS[1], mark it most
+// recently used in KEYS[2], and evict the oldest entries beyond ARGV[3]; with ARGV[4] set
+// to 1, an existing KEYS[1] is left as is and -1 returned, as it is with ARGV[4] set to 2
+// when KEYS[1] holds the tombstone ARGV[5]
+func (m *MockRedis) evalLRUSet(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 2 || len(argv) < 3 {
+		return writeError(w, "invalid args")
+	}
+	ttl, err1 := strconv.ParseInt(argv[1], 10, 64)
+	maxEntries, err2 := strconv.Atoi(argv[2])
+	if err1 != nil || err2 != nil {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(argv) > 3 {
+		val, ok := m.lookup(keys[0])
+		if ok && (argv[3] == "1" || argv[3] == "2" && len(argv) > 4 && val.value == argv[4]) {
+			return writeInt(w, -1)
+		}
+	}
+	index, err := m.zsetLocked(keys[1], true)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+
+	var expiresAt *time.Time
+	if ttl > 0 {
+		exp := time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		expiresAt = &exp
+	}
+	m.data[keys[0]] = mockValue{value: argv[0], expiresAt: expiresAt}
+	lruTouchLocked(index, keys[0])
+
+	evicted := int64(0)
+	members := sortedZSet(index)
+	for _, zm := range members[:max(0, len(members)-maxEntries)] {
+		if _, ok := m.lookup(zm.member); ok {
+			delete(m.data, zm.member)
+			evicted++
+		}
+		delete(index, zm.member)
+	}
+	return writeInt(w, evicted)
+}
+
+// evalLRUGet emulates the cache package's LRU get script: return KEYS[1] and mark it
+// most recently used in KEYS[2], or drop it from the index if it is gone
+func (m *MockRedis) evalLRUGet(keys []string, w *bufio.Writer) error {
+	if len(keys) < 2 {
+		return writeError(w, "invalid args")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	index, err := m.zsetLocked(keys[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	val, ok := m.lookup(keys[0])
+	if !ok {
+		delete(index, keys[0])
+		m.dropIfEmpty(keys[1])
+		return writeNil(w)
+	}
+	if val.typeName() != "string" {
+		return writeRawError(w, wrongTypeError)
+	}
+	if _, tracked := index[keys[0]]; tracked {
+		lruTouchLocked(index, keys[0])
+	}
+	return writeBulkString(w, val.value)
+}
+
+// evalRegistryPrune emulates the registry package's prune script: remove the members of
+// KEYS[1] scored at or before ARGV[1] and their fields in KEYS[2], and return them
+func (m *MockRedis) evalRegistryPrune(keys, argv []string, w *bufio.Writer) error {
+	if len(keys) < 2 || len(argv) < 1 {
+		return writeError(w, "invalid args")
+	}
+	now, err := parseScore(argv[0])
+	if err != nil {
+		return writeError(w, err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zset, err := m.zsetLocked(keys[0], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	info, err := m.hashLocked(keys[1], false)
+	if err != nil {
+		return writeRawError(w, err.Error())
+	}
+	expired := []string{}
+	for _, zm := range sortedZSet(zset) {
+		if zm.score > now {
+			break
+		}
+		delete(zset, zm.member)
+		delete(info, zm.member)
+		expired = append(expired, zm.member)
+	}
+	m.dropIfEmpty(keys[0])
+	m.dropIfEmpty(keys[1])
+	return writeArrayBulk(w, expired)
+}
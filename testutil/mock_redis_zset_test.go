@@ -0,0 +1,84 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMockRedis_ZSet(t *testing.T) {
+	client, _ := NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	n, err := client.ZAdd(ctx, "z",
+		redis.Z{Score: 3, Member: "c"},
+		redis.Z{Score: 1, Member: "a"},
+		redis.Z{Score: 2, Member: "b"},
+	).Result()
+	if err != nil || n != 3 {
+		t.Fatalf("ZAdd() = %d, %v, want 3", n, err)
+	}
+
+	if got, _ := client.ZRange(ctx, "z", 0, -1).Result(); len(got) != 3 || got[0] != "a" || got[2] != "c" {
+		t.Errorf("ZRange() = %v, want [a b c]", got)
+	}
+	if got, _ := client.ZRevRange(ctx, "z", 0, 0).Result(); len(got) != 1 || got[0] != "c" {
+		t.Errorf("ZRevRange() = %v, want [c]", got)
+	}
+	got, _ := client.ZRangeByScoreWithScores(ctx, "z", &redis.ZRangeBy{Min: "(1", Max: "+inf"}).Result()
+	if len(got) != 2 || got[0].Member != "b" || got[0].Score != 2 {
+		t.Errorf("ZRangeByScoreWithScores() = %v, want b and c", got)
+	}
+	if got, _ := client.ZRevRangeByScore(ctx, "z", &redis.ZRangeBy{Min: "-inf", Max: "3", Count: 1}).Result(); len(got) != 1 || got[0] != "c" {
+		t.Errorf("ZRevRangeByScore() = %v, want [c]", got)
+	}
+	if got, _ := client.ZRangeArgs(ctx, redis.ZRangeArgs{Key: "z", Start: "2", Stop: "3", ByScore: true}).Result(); len(got) != 2 {
+		t.Errorf("ZRangeArgs() BYSCORE = %v, want [b c]", got)
+	}
+
+	if s, _ := client.ZScore(ctx, "z", "b").Result(); s != 2 {
+		t.Errorf("ZScore() = %v, want 2", s)
+	}
+	if err := client.ZScore(ctx, "z", "missing").Err(); err != redis.Nil {
+		t.Errorf("ZScore() missing member error = %v, want redis.Nil", err)
+	}
+	if s, _ := client.ZIncrBy(ctx, "z", 10, "a").Result(); s != 11 {
+		t.Errorf("ZIncrBy() = %v, want 11", s)
+	}
+	if r, _ := client.ZRank(ctx, "z", "a").Result(); r != 2 {
+		t.Errorf("ZRank() = %d, want 2", r)
+	}
+	if r, _ := client.ZRevRank(ctx, "z", "a").Result(); r != 0 {
+		t.Errorf("ZRevRank() = %d, want 0", r)
+	}
+	if c, _ := client.ZCount(ctx, "z", "2", "3").Result(); c != 2 {
+		t.Errorf("ZCount() = %d, want 2", c)
+	}
+	if c, _ := client.ZCard(ctx, "z").Result(); c != 3 {
+		t.Errorf("ZCard() = %d, want 3", c)
+	}
+
+	// GT only raises scores
+	_ = client.ZAddGT(ctx, "z", redis.Z{Score: 1, Member: "c"}).Err()
+	if s, _ := client.ZScore(ctx, "z", "c").Result(); s != 3 {
+		t.Errorf("ZAddGT() lowered score to %v, want 3", s)
+	}
+	if n, _ := client.ZAddNX(ctx, "z", redis.Z{Score: 0, Member: "a"}).Result(); n != 0 {
+		t.Errorf("ZAddNX() existing member = %d, want 0", n)
+	}
+
+	if n, _ := client.ZRemRangeByScore(ctx, "z", "-inf", "2").Result(); n != 1 {
+		t.Errorf("ZRemRangeByScore() = %d, want 1", n)
+	}
+	if n, _ := client.ZRemRangeByRank(ctx, "z", 0, 0).Result(); n != 1 {
+		t.Errorf("ZRemRangeByRank() = %d, want 1", n)
+	}
+	if n, _ := client.ZRem(ctx, "z", "a").Result(); n != 1 {
+		t.Errorf("ZRem() = %d, want 1", n)
+	}
+	if n, _ := client.Exists(ctx, "z").Result(); n != 0 {
+		t.Errorf("Exists() after removing all members = %d, want 0", n)
+	}
+}
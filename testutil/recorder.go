@@ -0,0 +1,46 @@
+package testutil
+
+import (
+	"sync"
+	"time"
+
+	"github.com/soulteary/redis-kit/metrics"
+)
+
+// Recorder is a metrics.Recorder that counts observations per label set, for tests
+type Recorder struct {
+	mu     sync.Mutex
+	counts map[[3]string]int
+}
+
+var _ metrics.Recorder = (*Recorder)(nil)
+
+// NewRecorder creates an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{counts: make(map[[3]string]int)}
+}
+
+// ObserveOperation implements metrics.Recorder
+func (r *Recorder) ObserveOperation(subsystem, operation, outcome string, _ time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[[3]string{subsystem, operation, outcome}]++
+}
+
+// Count returns how often the given label set was observed
+func (r *Recorder) Count(subsystem, operation, outcome string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counts[[3]string{subsystem, operation, outcome}]
+}
+
+// Total returns the number of observations across all label sets
+func (r *Recorder) Total() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, c := range r.counts {
+		n += c
+	}
+	return n
+}
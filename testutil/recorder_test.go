@@ -0,0 +1,20 @@
+package testutil
+
+import "testing"
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	r.ObserveOperation("cache", "get", "hit", 0)
+	r.ObserveOperation("cache", "get", "hit", 0)
+	r.ObserveOperation("cache", "get", "miss", 0)
+
+	if n := r.Count("cache", "get", "hit"); n != 2 {
+		t.Errorf("Count(hit) = %d, want 2", n)
+	}
+	if n := r.Count("cache", "set", "success"); n != 0 {
+		t.Errorf("Count(unseen) = %d, want 0", n)
+	}
+	if n := r.Total(); n != 3 {
+		t.Errorf("Total() = %d, want 3", n)
+	}
+}
@@ -0,0 +1,11 @@
+package timeseries
+
+import "errors"
+
+var (
+	// ErrInvalidBucket indicates an aggregation bucket that is not positive.
+	ErrInvalidBucket = errors.New("aggregation bucket must be positive")
+
+	// ErrInvalidAggregation indicates an unknown aggregation.
+	ErrInvalidAggregation = errors.New("invalid aggregation")
+)
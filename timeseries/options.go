@@ -0,0 +1,64 @@
+package timeseries
+
+import "time"
+
+// Mode selects the series implementation
+type Mode int
+
+const (
+	// ModeAuto uses RedisTimeSeries when the server provides it and a sorted set otherwise
+	ModeAuto Mode = iota
+	// ModeRedisTimeSeries always uses the RedisTimeSeries TS.* commands
+	ModeRedisTimeSeries
+	// ModeSortedSet always uses a sorted set scored by timestamp
+	ModeSortedSet
+)
+
+// String returns the name of the mode
+func (m Mode) String() string {
+	switch m {
+	case ModeRedisTimeSeries:
+		return "redistimeseries"
+	case ModeSortedSet:
+		return "sortedset"
+	default:
+		return "auto"
+	}
+}
+
+// Option configures a Series
+type Option func(*Series)
+
+// WithKeyPrefix sets the prefix of the series key (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(s *Series) {
+		s.keyPrefix = prefix
+	}
+}
+
+// WithMode selects the implementation (default: ModeAuto)
+func WithMode(mode Mode) Option {
+	return func(s *Series) {
+		s.mode.Store(int32(mode))
+	}
+}
+
+// WithRetention drops samples older than d before the latest added sample (default: 0, keep all)
+// RedisTimeSeries applies it when the series is created by the first Add
+func WithRetention(d time.Duration) Option {
+	return func(s *Series) {
+		if d > 0 {
+			s.retention = d
+		}
+	}
+}
+
+// WithMaxSamples caps the number of samples kept by the sorted set implementation,
+// dropping the oldest first (default: DefaultMaxSamples; 0 disables the cap)
+func WithMaxSamples(n int64) Option {
+	return func(s *Series) {
+		if n >= 0 {
+			s.maxSamples = n
+		}
+	}
+}
@@ -0,0 +1,407 @@
+// Package timeseries stores (timestamp, value) samples on Redis
+// It uses RedisTimeSeries when available and falls back to a capped sorted set scored by
+// timestamp, with range queries, per-bucket aggregation, and retention trimming
+package timeseries
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for series keys
+	DefaultKeyPrefix = "ts:"
+
+	// DefaultMaxSamples is the default sample cap of the sorted set implementation
+	DefaultMaxSamples = 100000
+)
+
+// Aggregation combines the samples of a bucket into one value
+type Aggregation int
+
+const (
+	// Avg is the mean of the samples
+	Avg Aggregation = iota
+	// Min is the smallest sample
+	Min
+	// Max is the largest sample
+	Max
+	// Sum is the total of the samples
+	Sum
+	// Count is the number of samples
+	Count
+)
+
+// String returns the name of the aggregation
+func (a Aggregation) String() string {
+	switch a {
+	case Avg:
+		return "avg"
+	case Min:
+		return "min"
+	case Max:
+		return "max"
+	case Sum:
+		return "sum"
+	case Count:
+		return "count"
+	default:
+		return "invalid"
+	}
+}
+
+// aggregator returns the RedisTimeSeries equivalent of a
+func (a Aggregation) aggregator() redis.Aggregator {
+	switch a {
+	case Avg:
+		return redis.Avg
+	case Min:
+		return redis.Min
+	case Max:
+		return redis.Max
+	case Sum:
+		return redis.Sum
+	case Count:
+		return redis.Count
+	default:
+		return redis.Invalid
+	}
+}
+
+// Sample is a value recorded at a point in time, with millisecond precision
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series is a named time series
+// A timestamp holds one sample: adding another at the same millisecond replaces it
+type Series struct {
+	client     *redis.Client
+	name       string
+	keyPrefix  string
+	retention  time.Duration
+	maxSamples int64
+	mode       atomic.Int32
+}
+
+// NewSeries creates a handle to the series name
+func NewSeries(client *redis.Client, name string, opts ...Option) *Series {
+	s := &Series{
+		client:     client,
+		name:       name,
+		keyPrefix:  DefaultKeyPrefix,
+		maxSamples: DefaultMaxSamples,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Key returns the Redis key of the series
+func (s *Series) Key() string {
+	return s.keyPrefix + s.name
+}
+
+// Mode returns the implementation in use; ModeAuto until the first command resolves it
+func (s *Series) Mode() Mode {
+	return Mode(s.mode.Load())
+}
+
+// Add records value at t
+func (s *Series) Add(ctx context.Context, t time.Time, value float64) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	ts := t.UnixMilli()
+	err := s.dispatch(ctx,
+		func() error {
+			return s.client.TSAddWithArgs(ctx, s.Key(), ts, value, &redis.TSOptions{
+				Retention:       int(s.retention.Milliseconds()),
+				DuplicatePolicy: "LAST",
+			}).Err()
+		},
+		func() error {
+			return s.zsetAdd(ctx, ts, value)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add sample: %w", err)
+	}
+	return nil
+}
+
+// Range returns the samples between from and to, both inclusive, oldest first
+func (s *Series) Range(ctx context.Context, from, to time.Time) ([]Sample, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	var samples []Sample
+	err := s.dispatch(ctx,
+		func() error {
+			res, err := s.client.TSRange(ctx, s.Key(), int(from.UnixMilli()), int(to.UnixMilli())).Result()
+			if err != nil {
+				return err
+			}
+			samples = fromTimestampValues(res)
+			return nil
+		},
+		func() (err error) {
+			samples, err = s.zsetRange(ctx, from.UnixMilli(), to.UnixMilli())
+			return err
+		},
+	)
+	if isMissingSeries(err) {
+		return []Sample{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read samples: %w", err)
+	}
+	return samples, nil
+}
+
+// Aggregate downsamples the samples between from and to into buckets of the given width,
+// aligned to the Unix epoch, and returns one sample per non-empty bucket stamped with the
+// bucket's start
+func (s *Series) Aggregate(ctx context.Context, from, to time.Time, bucket time.Duration, agg Aggregation) ([]Sample, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	width := bucket.Milliseconds()
+	if width <= 0 {
+		return nil, ErrInvalidBucket
+	}
+	if agg.aggregator() == redis.Invalid {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidAggregation, agg)
+	}
+
+	var samples []Sample
+	err := s.dispatch(ctx,
+		func() error {
+			res, err := s.client.TSRangeWithArgs(ctx, s.Key(), int(from.UnixMilli()), int(to.UnixMilli()), &redis.TSRangeOptions{
+				Aggregator:     agg.aggregator(),
+				BucketDuration: int(width),
+			}).Result()
+			if err != nil {
+				return err
+			}
+			samples = fromTimestampValues(res)
+			return nil
+		},
+		func() error {
+			raw, err := s.zsetRange(ctx, from.UnixMilli(), to.UnixMilli())
+			if err != nil {
+				return err
+			}
+			samples = aggregate(raw, width, agg)
+			return nil
+		},
+	)
+	if isMissingSeries(err) {
+		return []Sample{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate samples: %w", err)
+	}
+	return samples, nil
+}
+
+// Trim deletes the samples older than before and returns how many were removed
+func (s *Series) Trim(ctx context.Context, before time.Time) (int64, error) {
+	if s.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	var removed int64
+	err := s.dispatch(ctx,
+		func() (err error) {
+			removed, err = s.client.TSDel(ctx, s.Key(), 0, int(before.UnixMilli()-1)).Result()
+			return err
+		},
+		func() (err error) {
+			removed, err = s.client.ZRemRangeByScore(ctx, s.Key(), "-inf", "("+strconv.FormatInt(before.UnixMilli(), 10)).Result()
+			return err
+		},
+	)
+	if isMissingSeries(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to trim series: %w", err)
+	}
+	return removed, nil
+}
+
+// Clear deletes the series
+func (s *Series) Clear(ctx context.Context) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := s.client.Del(ctx, s.Key()).Err(); err != nil {
+		return fmt.Errorf("failed to clear series: %w", err)
+	}
+	return nil
+}
+
+// zsetAdd stores a sample as the member "<timestamp>:<value>" scored by its timestamp,
+// replacing any sample at the same timestamp, then applies retention and the sample cap
+// Retention is measured from the sample being added
+func (s *Series) zsetAdd(ctx context.Context, ts int64, value float64) error {
+	score := strconv.FormatInt(ts, 10)
+	pipe := s.client.Pipeline()
+	pipe.ZRemRangeByScore(ctx, s.Key(), score, score)
+	pipe.ZAdd(ctx, s.Key(), redis.Z{
+		Score:  float64(ts),
+		Member: score + ":" + strconv.FormatFloat(value, 'g', -1, 64),
+	})
+	if s.retention > 0 {
+		cutoff := strconv.FormatInt(ts-s.retention.Milliseconds(), 10)
+		pipe.ZRemRangeByScore(ctx, s.Key(), "-inf", "("+cutoff)
+	}
+	if s.maxSamples > 0 {
+		pipe.ZRemRangeByRank(ctx, s.Key(), 0, -s.maxSamples-1)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// zsetRange reads the samples scored between from and to
+func (s *Series) zsetRange(ctx context.Context, from, to int64) ([]Sample, error) {
+	members, err := s.client.ZRangeByScore(ctx, s.Key(), &redis.ZRangeBy{
+		Min: strconv.FormatInt(from, 10),
+		Max: strconv.FormatInt(to, 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]Sample, 0, len(members))
+	for _, member := range members {
+		sample, err := parseMember(member)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+// parseMember decodes a sorted set member written by zsetAdd
+func parseMember(member string) (Sample, error) {
+	tsPart, valuePart, ok := strings.Cut(member, ":")
+	if !ok {
+		return Sample{}, fmt.Errorf("malformed sample %q", member)
+	}
+	ts, err := strconv.ParseInt(tsPart, 10, 64)
+	if err != nil {
+		return Sample{}, fmt.Errorf("malformed sample %q: %w", member, err)
+	}
+	value, err := strconv.ParseFloat(valuePart, 64)
+	if err != nil {
+		return Sample{}, fmt.Errorf("malformed sample %q: %w", member, err)
+	}
+	return Sample{Time: time.UnixMilli(ts), Value: value}, nil
+}
+
+// aggregate buckets samples, which must be ordered by time, the way TS.RANGE AGGREGATION does
+func aggregate(samples []Sample, width int64, agg Aggregation) []Sample {
+	out := make([]Sample, 0)
+	var start int64
+	var values []float64
+	flush := func() {
+		if len(values) > 0 {
+			out = append(out, Sample{Time: time.UnixMilli(start), Value: combine(values, agg)})
+		}
+	}
+	for _, sample := range samples {
+		ts := sample.Time.UnixMilli()
+		bucketStart := ts - ((ts%width)+width)%width
+		if len(values) == 0 || bucketStart != start {
+			flush()
+			start = bucketStart
+			values = values[:0]
+		}
+		values = append(values, sample.Value)
+	}
+	flush()
+	return out
+}
+
+// combine applies agg to a non-empty bucket
+func combine(values []float64, agg Aggregation) float64 {
+	switch agg {
+	case Min:
+		result := math.Inf(1)
+		for _, v := range values {
+			result = math.Min(result, v)
+		}
+		return result
+	case Max:
+		result := math.Inf(-1)
+		for _, v := range values {
+			result = math.Max(result, v)
+		}
+		return result
+	case Count:
+		return float64(len(values))
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	if agg == Avg {
+		return sum / float64(len(values))
+	}
+	return sum
+}
+
+func fromTimestampValues(values []redis.TSTimestampValue) []Sample {
+	samples := make([]Sample, len(values))
+	for i, v := range values {
+		samples[i] = Sample{Time: time.UnixMilli(v.Timestamp), Value: v.Value}
+	}
+	return samples
+}
+
+// dispatch runs the RedisTimeSeries or sorted set variant of a command
+// In ModeAuto the first "unknown command" reply switches the series to the sorted set for good
+func (s *Series) dispatch(ctx context.Context, timeseries, zset func() error) error {
+	switch s.Mode() {
+	case ModeRedisTimeSeries:
+		return timeseries()
+	case ModeSortedSet:
+		return zset()
+	}
+
+	err := timeseries()
+	if err == nil || isMissingSeries(err) {
+		s.mode.CompareAndSwap(int32(ModeAuto), int32(ModeRedisTimeSeries))
+		return err
+	}
+	if !isUnknownCommand(err) || ctx.Err() != nil {
+		return err
+	}
+	s.mode.CompareAndSwap(int32(ModeAuto), int32(ModeSortedSet))
+	return zset()
+}
+
+func isUnknownCommand(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "unknown command")
+}
+
+// isMissingSeries reports whether RedisTimeSeries rejected a read of a series never written
+func isMissingSeries(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "key does not exist")
+}
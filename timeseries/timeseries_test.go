@@ -0,0 +1,259 @@
+package timeseries
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+var epoch = time.UnixMilli(1_700_000_000_000)
+
+func at(offset time.Duration) time.Time {
+	return epoch.Add(offset)
+}
+
+func TestNewSeries(t *testing.T) {
+	s := NewSeries(nil, "cpu")
+	if s.Key() != "ts:cpu" {
+		t.Errorf("Key() = %q, want ts:cpu", s.Key())
+	}
+	if s.Mode() != ModeAuto || s.maxSamples != DefaultMaxSamples || s.retention != 0 {
+		t.Errorf("NewSeries() = %s/%d/%v, want defaults", s.Mode(), s.maxSamples, s.retention)
+	}
+
+	s = NewSeries(nil, "cpu", WithKeyPrefix("metrics:"), WithMode(ModeSortedSet),
+		WithRetention(time.Hour), WithMaxSamples(10))
+	if s.Key() != "metrics:cpu" || s.Mode() != ModeSortedSet || s.retention != time.Hour || s.maxSamples != 10 {
+		t.Errorf("NewSeries() with options = %q/%s/%v/%d", s.Key(), s.Mode(), s.retention, s.maxSamples)
+	}
+
+	s = NewSeries(nil, "cpu", WithRetention(-time.Hour), WithMaxSamples(-1))
+	if s.retention != 0 || s.maxSamples != DefaultMaxSamples {
+		t.Errorf("NewSeries() with invalid options = %v/%d, want defaults", s.retention, s.maxSamples)
+	}
+}
+
+func TestMode_String(t *testing.T) {
+	for mode, want := range map[Mode]string{ModeAuto: "auto", ModeRedisTimeSeries: "redistimeseries", ModeSortedSet: "sortedset"} {
+		if got := mode.String(); got != want {
+			t.Errorf("Mode(%d).String() = %q, want %q", mode, got, want)
+		}
+	}
+	if got := Aggregation(42).String(); got != "invalid" {
+		t.Errorf("Aggregation(42).String() = %q, want invalid", got)
+	}
+}
+
+func TestSeries_AutoFallsBackToSortedSet(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewSeries(client, "cpu")
+
+	// The mock has no RedisTimeSeries module, so the first command switches to the sorted set
+	if err := s.Add(ctx, at(0), 1.5); err != nil {
+		t.Fatalf("Add() error = %v, want nil", err)
+	}
+	if s.Mode() != ModeSortedSet {
+		t.Errorf("Mode() = %s, want sortedset", s.Mode())
+	}
+	if typ, _ := client.Type(ctx, s.Key()).Result(); typ != "zset" {
+		t.Errorf("Type() of the series key = %q, want zset", typ)
+	}
+}
+
+func TestSeries_Range(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewSeries(client, "cpu", WithMode(ModeSortedSet))
+	for i, v := range []float64{1, 2, 3, 4} {
+		if err := s.Add(ctx, at(time.Duration(i)*time.Second), v); err != nil {
+			t.Fatalf("Add() error = %v, want nil", err)
+		}
+	}
+	// A second sample at the same timestamp replaces the first
+	_ = s.Add(ctx, at(time.Second), 20)
+
+	samples, err := s.Range(ctx, at(time.Second), at(2*time.Second))
+	if err != nil {
+		t.Fatalf("Range() error = %v, want nil", err)
+	}
+	want := []Sample{{at(time.Second), 20}, {at(2 * time.Second), 3}}
+	if len(samples) != len(want) {
+		t.Fatalf("Range() = %v, want %v", samples, want)
+	}
+	for i := range want {
+		if !samples[i].Time.Equal(want[i].Time) || samples[i].Value != want[i].Value {
+			t.Errorf("Range()[%d] = %v, want %v", i, samples[i], want[i])
+		}
+	}
+
+	if samples, err := NewSeries(client, "empty", WithMode(ModeSortedSet)).Range(ctx, at(0), at(time.Hour)); err != nil || len(samples) != 0 {
+		t.Errorf("Range() of a missing series = %v, %v, want empty", samples, err)
+	}
+}
+
+func TestSeries_Aggregate(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewSeries(client, "cpu", WithMode(ModeSortedSet))
+	// Two samples in the first 10s bucket, none in the second, three in the third
+	for _, sample := range []Sample{
+		{at(1 * time.Second), 2}, {at(4 * time.Second), 6},
+		{at(21 * time.Second), 1}, {at(25 * time.Second), 5}, {at(29 * time.Second), 3},
+	} {
+		_ = s.Add(ctx, sample.Time, sample.Value)
+	}
+
+	tests := []struct {
+		agg  Aggregation
+		want []float64
+	}{
+		{Avg, []float64{4, 3}},
+		{Min, []float64{2, 1}},
+		{Max, []float64{6, 5}},
+		{Sum, []float64{8, 9}},
+		{Count, []float64{2, 3}},
+	}
+	for _, tt := range tests {
+		got, err := s.Aggregate(ctx, at(0), at(time.Minute), 10*time.Second, tt.agg)
+		if err != nil {
+			t.Fatalf("Aggregate(%s) error = %v, want nil", tt.agg, err)
+		}
+		if len(got) != 2 || got[0].Value != tt.want[0] || got[1].Value != tt.want[1] {
+			t.Errorf("Aggregate(%s) = %v, want values %v", tt.agg, got, tt.want)
+			continue
+		}
+		if !got[0].Time.Equal(at(0)) || !got[1].Time.Equal(at(20*time.Second)) {
+			t.Errorf("Aggregate(%s) bucket times = %v, %v, want the bucket starts", tt.agg, got[0].Time, got[1].Time)
+		}
+	}
+
+	if _, err := s.Aggregate(ctx, at(0), at(time.Minute), 0, Avg); !errors.Is(err, ErrInvalidBucket) {
+		t.Errorf("Aggregate() with zero bucket error = %v, want ErrInvalidBucket", err)
+	}
+	if _, err := s.Aggregate(ctx, at(0), at(time.Minute), time.Second, Aggregation(42)); !errors.Is(err, ErrInvalidAggregation) {
+		t.Errorf("Aggregate() with unknown aggregation error = %v, want ErrInvalidAggregation", err)
+	}
+}
+
+func TestAggregate_NegativeTimestamps(t *testing.T) {
+	samples := []Sample{{time.UnixMilli(-15), 1}, {time.UnixMilli(-5), 2}, {time.UnixMilli(5), 3}}
+	got := aggregate(samples, 10, Sum)
+	if len(got) != 3 || got[0].Time.UnixMilli() != -20 || got[1].Time.UnixMilli() != -10 || got[2].Time.UnixMilli() != 0 {
+		t.Errorf("aggregate() = %v, want buckets at -20, -10 and 0", got)
+	}
+}
+
+func TestSeries_Retention(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewSeries(client, "cpu", WithMode(ModeSortedSet), WithRetention(10*time.Second), WithMaxSamples(3))
+	for i := 0; i < 12; i++ {
+		_ = s.Add(ctx, at(time.Duration(i)*time.Second), float64(i))
+	}
+	samples, _ := s.Range(ctx, at(0), at(time.Minute))
+	if len(samples) != 3 || samples[0].Value != 9 {
+		t.Errorf("Range() after the cap = %v, want the 3 newest samples", samples)
+	}
+
+	s = NewSeries(client, "mem", WithMode(ModeSortedSet), WithRetention(10*time.Second))
+	for i := 0; i < 30; i += 5 {
+		_ = s.Add(ctx, at(time.Duration(i)*time.Second), float64(i))
+	}
+	samples, _ = s.Range(ctx, at(0), at(time.Minute))
+	if len(samples) != 3 || samples[0].Value != 15 {
+		t.Errorf("Range() after retention = %v, want samples from 15s on", samples)
+	}
+}
+
+func TestSeries_Trim(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewSeries(client, "cpu", WithMode(ModeSortedSet))
+	for i := 0; i < 5; i++ {
+		_ = s.Add(ctx, at(time.Duration(i)*time.Second), float64(i))
+	}
+
+	removed, err := s.Trim(ctx, at(2*time.Second))
+	if err != nil || removed != 2 {
+		t.Errorf("Trim() = %d, %v, want 2", removed, err)
+	}
+	samples, _ := s.Range(ctx, at(0), at(time.Minute))
+	if len(samples) != 3 || samples[0].Value != 2 {
+		t.Errorf("Range() after Trim = %v, want samples from 2s on", samples)
+	}
+
+	if err := s.Clear(ctx); err != nil {
+		t.Errorf("Clear() error = %v, want nil", err)
+	}
+	if n, _ := client.Exists(ctx, s.Key()).Result(); n != 0 {
+		t.Error("series key exists after Clear()")
+	}
+}
+
+func TestSeries_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	s := NewSeries(nil, "cpu")
+	if err := s.Add(ctx, epoch, 1); err == nil {
+		t.Error("Add() with nil client error = nil, want error")
+	}
+	if _, err := s.Range(ctx, epoch, epoch); err == nil {
+		t.Error("Range() with nil client error = nil, want error")
+	}
+	if _, err := s.Aggregate(ctx, epoch, epoch, time.Second, Avg); err == nil {
+		t.Error("Aggregate() with nil client error = nil, want error")
+	}
+	if _, err := s.Trim(ctx, epoch); err == nil {
+		t.Error("Trim() with nil client error = nil, want error")
+	}
+	if err := s.Clear(ctx); err == nil {
+		t.Error("Clear() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	// Forcing RedisTimeSeries on a server without it surfaces the error
+	s = NewSeries(client, "cpu", WithMode(ModeRedisTimeSeries))
+	if err := s.Add(ctx, epoch, 1); err == nil {
+		t.Error("Add() without RedisTimeSeries error = nil, want error")
+	}
+
+	_ = client.ZAdd(ctx, "ts:bad", redis.Z{Score: 1, Member: "garbage"}).Err()
+	if _, err := NewSeries(client, "bad", WithMode(ModeSortedSet)).Range(ctx, time.UnixMilli(0), time.UnixMilli(10)); err == nil {
+		t.Error("Range() over a malformed member error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	s = NewSeries(client, "cpu")
+	if err := s.Add(ctx, epoch, 1); err == nil {
+		t.Error("Add() error = nil, want error")
+	}
+	if s.Mode() != ModeAuto {
+		t.Errorf("Mode() after a failure = %s, want auto", s.Mode())
+	}
+	if _, err := s.Range(ctx, epoch, epoch); err == nil {
+		t.Error("Range() error = nil, want error")
+	}
+	if _, err := s.Trim(ctx, epoch); err == nil {
+		t.Error("Trim() error = nil, want error")
+	}
+	if err := s.Clear(ctx); err == nil {
+		t.Error("Clear() error = nil, want error")
+	}
+}
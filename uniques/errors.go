@@ -0,0 +1,8 @@
+package uniques
+
+import "errors"
+
+var (
+	// ErrRangeTooLarge indicates a range query spans more than MaxRangeBuckets buckets.
+	ErrRangeTooLarge = errors.New("range spans too many buckets")
+)
@@ -0,0 +1,60 @@
+package uniques
+
+import "time"
+
+// Bucket is the time granularity of a counter
+type Bucket int
+
+const (
+	// BucketNone keeps a single all-time counter
+	BucketNone Bucket = iota
+	// BucketHour counts uniques per hour
+	BucketHour
+	// BucketDay counts uniques per calendar day
+	BucketDay
+)
+
+// String returns the name of the bucket
+func (b Bucket) String() string {
+	switch b {
+	case BucketHour:
+		return "hour"
+	case BucketDay:
+		return "day"
+	default:
+		return "none"
+	}
+}
+
+// Option configures a Counter
+type Option func(*Counter)
+
+// WithKeyPrefix sets the prefix of the counter keys (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(c *Counter) {
+		c.keyPrefix = prefix
+	}
+}
+
+// WithBucket counts uniques per time bucket (default: BucketNone)
+func WithBucket(b Bucket) Option {
+	return func(c *Counter) {
+		c.bucket = b
+	}
+}
+
+// WithLocation sets the time zone used for bucket boundaries (default: UTC)
+func WithLocation(loc *time.Location) Option {
+	return func(c *Counter) {
+		c.loc = loc
+	}
+}
+
+// WithRetention makes bucket keys expire this long after their bucket ends (default: never)
+func WithRetention(d time.Duration) Option {
+	return func(c *Counter) {
+		if d >= 0 {
+			c.retention = d
+		}
+	}
+}
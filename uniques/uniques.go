@@ -0,0 +1,215 @@
+// Package uniques provides approximate unique counting with HyperLogLog
+// Each counter uses about 12 KB regardless of how many items it sees, with a standard error of 0.81%
+package uniques
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for counter keys
+	DefaultKeyPrefix = "uniques:"
+
+	// MaxRangeBuckets is the largest number of buckets a range query may span (a year of hours)
+	MaxRangeBuckets = 366 * 24
+)
+
+// Counter counts distinct items, optionally per hour or day
+type Counter struct {
+	client    *redis.Client
+	name      string
+	keyPrefix string
+	bucket    Bucket
+	loc       *time.Location
+	retention time.Duration
+}
+
+// NewCounter creates a unique counter with the given name
+func NewCounter(client *redis.Client, name string, opts ...Option) *Counter {
+	c := &Counter{
+		client:    client,
+		name:      name,
+		keyPrefix: DefaultKeyPrefix,
+		bucket:    BucketNone,
+		loc:       time.UTC,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.loc == nil {
+		c.loc = time.UTC
+	}
+	return c
+}
+
+// Bucket returns the time granularity of the counter
+func (c *Counter) Bucket() Bucket {
+	return c.bucket
+}
+
+// bounds returns the start of the bucket containing t and the start of the next one
+func (c *Counter) bounds(t time.Time) (time.Time, time.Time) {
+	switch c.bucket {
+	case BucketHour:
+		local := t.In(c.loc)
+		start := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, c.loc)
+		return start, start.Add(time.Hour)
+	case BucketDay:
+		return utils.DayStart(t, c.loc), utils.NextDayStart(t, c.loc)
+	default:
+		return time.Time{}, time.Time{}
+	}
+}
+
+// Key returns the key of the bucket containing t; t is ignored with BucketNone
+func (c *Counter) Key(t time.Time) string {
+	key := c.keyPrefix + c.name
+	start, _ := c.bounds(t)
+	switch c.bucket {
+	case BucketHour:
+		key += ":" + start.Format("2006-01-02T15")
+	case BucketDay:
+		key += ":" + start.Format("2006-01-02")
+	}
+	return key
+}
+
+// Keys returns the keys of all buckets overlapping [from, to], oldest first
+// Returns ErrRangeTooLarge if the range spans more than MaxRangeBuckets buckets
+func (c *Counter) Keys(from, to time.Time) ([]string, error) {
+	if c.bucket == BucketNone {
+		return []string{c.Key(from)}, nil
+	}
+	if to.Before(from) {
+		return []string{}, nil
+	}
+
+	var keys []string
+	for start, _ := c.bounds(from); !start.After(to); _, start = c.bounds(start) {
+		if len(keys) == MaxRangeBuckets {
+			return nil, ErrRangeTooLarge
+		}
+		keys = append(keys, c.Key(start))
+	}
+	return keys, nil
+}
+
+// Add records items in the current bucket
+// Returns true if the estimated count changed
+func (c *Counter) Add(ctx context.Context, items ...string) (bool, error) {
+	return c.AddAt(ctx, time.Now(), items...)
+}
+
+// AddAt records items in the bucket containing t, e.g. when replaying events
+// Returns true if the estimated count changed; a bucket already past the retention window is
+// left alone, as it would have expired by now
+func (c *Counter) AddAt(ctx context.Context, t time.Time, items ...string) (bool, error) {
+	if c.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+	ttl, live := c.ttl(t)
+	if len(items) == 0 || !live {
+		return false, nil
+	}
+
+	args := make([]interface{}, len(items))
+	for i, item := range items {
+		args[i] = item
+	}
+
+	key := c.Key(t)
+	pipe := c.client.Pipeline()
+	add := pipe.PFAdd(ctx, key, args...)
+	if ttl > 0 {
+		pipe.PExpire(ctx, key, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to add items: %w", err)
+	}
+	return add.Val() == 1, nil
+}
+
+// ttl returns how long the bucket containing t must live to cover the retention window
+// Returns 0 for keys that never expire, and false for a bucket already past the window
+func (c *Counter) ttl(t time.Time) (time.Duration, bool) {
+	if c.bucket == BucketNone || c.retention <= 0 {
+		return 0, true
+	}
+	_, end := c.bounds(t)
+	ttl := time.Until(end.Add(c.retention))
+	return ttl, ttl > 0
+}
+
+// Count returns the estimated number of distinct items in the current bucket
+func (c *Counter) Count(ctx context.Context) (int64, error) {
+	return c.CountAt(ctx, time.Now())
+}
+
+// CountAt returns the estimated number of distinct items in the bucket containing t
+func (c *Counter) CountAt(ctx context.Context, t time.Time) (int64, error) {
+	if c.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	n, err := c.client.PFCount(ctx, c.Key(t)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count uniques: %w", err)
+	}
+	return n, nil
+}
+
+// CountRange returns the estimated number of distinct items across all buckets
+// overlapping [from, to]; items seen in several buckets are counted once
+// In Redis Cluster the bucket keys must share a hash slot, e.g. via a {tag} in the name
+func (c *Counter) CountRange(ctx context.Context, from, to time.Time) (int64, error) {
+	if c.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	keys, err := c.Keys(from, to)
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	n, err := c.client.PFCount(ctx, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count uniques: %w", err)
+	}
+	return n, nil
+}
+
+// MergeRange stores the union of all buckets overlapping [from, to] in dest, e.g. to
+// precompute a weekly rollup; existing contents of dest are kept and merged in
+func (c *Counter) MergeRange(ctx context.Context, dest string, from, to time.Time) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	keys, err := c.Keys(from, to)
+	if err != nil {
+		return err
+	}
+	if err := c.client.PFMerge(ctx, dest, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to merge uniques: %w", err)
+	}
+	return nil
+}
+
+// Reset deletes the bucket containing t
+func (c *Counter) Reset(ctx context.Context, t time.Time) error {
+	if c.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := c.client.Del(ctx, c.Key(t)).Err(); err != nil {
+		return fmt.Errorf("failed to reset uniques: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package uniques
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestNewCounter(t *testing.T) {
+	ts := time.Date(2024, 5, 17, 13, 45, 0, 0, time.UTC)
+
+	c := NewCounter(nil, "visitors")
+	if c.Key(ts) != "uniques:visitors" {
+		t.Errorf("Key() = %q, want %q", c.Key(ts), "uniques:visitors")
+	}
+
+	c = NewCounter(nil, "visitors", WithKeyPrefix("u:"), WithBucket(BucketHour), WithLocation(nil))
+	if c.Key(ts) != "u:visitors:2024-05-17T13" {
+		t.Errorf("hourly Key() = %q, want %q", c.Key(ts), "u:visitors:2024-05-17T13")
+	}
+
+	tokyo := time.FixedZone("JST", 9*3600)
+	c = NewCounter(nil, "visitors", WithBucket(BucketDay), WithLocation(tokyo))
+	if c.Key(ts) != "uniques:visitors:2024-05-17" {
+		t.Errorf("daily Key() = %q, want %q", c.Key(ts), "uniques:visitors:2024-05-17")
+	}
+	if c.Key(ts.Add(11*time.Hour)) != "uniques:visitors:2024-05-18" {
+		t.Errorf("daily Key() after midnight JST = %q, want 2024-05-18", c.Key(ts.Add(11*time.Hour)))
+	}
+}
+
+func TestCounter_Keys(t *testing.T) {
+	c := NewCounter(nil, "v", WithBucket(BucketDay))
+	from := time.Date(2024, 5, 30, 18, 0, 0, 0, time.UTC)
+	keys, err := c.Keys(from, from.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("Keys() error = %v, want nil", err)
+	}
+	want := []string{"uniques:v:2024-05-30", "uniques:v:2024-05-31", "uniques:v:2024-06-01"}
+	if len(keys) != len(want) {
+		t.Fatalf("Keys() = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("Keys()[%d] = %q, want %q", i, keys[i], want[i])
+		}
+	}
+
+	if keys, _ := c.Keys(from, from.Add(-time.Hour)); len(keys) != 0 {
+		t.Errorf("Keys() with to before from = %v, want empty", keys)
+	}
+	hourly := NewCounter(nil, "v", WithBucket(BucketHour))
+	if _, err := hourly.Keys(from, from.AddDate(2, 0, 0)); !errors.Is(err, ErrRangeTooLarge) {
+		t.Errorf("Keys() over two years of hours error = %v, want ErrRangeTooLarge", err)
+	}
+	if keys, _ := NewCounter(nil, "v").Keys(from, from.AddDate(1, 0, 0)); len(keys) != 1 {
+		t.Errorf("Keys() without buckets = %v, want one key", keys)
+	}
+}
+
+func TestCounter_AddCount(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	c := NewCounter(client, "visitors")
+
+	if changed, err := c.Add(ctx, "alice", "bob", "alice"); err != nil || !changed {
+		t.Fatalf("Add() = %v, %v, want true", changed, err)
+	}
+	if changed, _ := c.Add(ctx, "bob"); changed {
+		t.Error("Add() of a seen item = true, want false")
+	}
+	if changed, err := c.Add(ctx); err != nil || changed {
+		t.Errorf("Add() with no items = %v, %v, want false", changed, err)
+	}
+	if n, err := c.Count(ctx); err != nil || n != 2 {
+		t.Errorf("Count() = %d, %v, want 2", n, err)
+	}
+	if err := c.Reset(ctx, time.Now()); err != nil {
+		t.Fatalf("Reset() error = %v, want nil", err)
+	}
+	if n, _ := c.Count(ctx); n != 0 {
+		t.Errorf("Count() after Reset = %d, want 0", n)
+	}
+}
+
+func TestCounter_Buckets(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	c := NewCounter(client, "dau", WithBucket(BucketDay), WithRetention(24*time.Hour))
+
+	today := time.Now()
+	yesterday := today.AddDate(0, 0, -1)
+	if _, err := c.AddAt(ctx, yesterday, "alice", "bob"); err != nil {
+		t.Fatalf("AddAt() error = %v, want nil", err)
+	}
+	if _, err := c.AddAt(ctx, today, "bob", "carol", "dave"); err != nil {
+		t.Fatalf("AddAt() error = %v, want nil", err)
+	}
+
+	if n, _ := c.CountAt(ctx, yesterday); n != 2 {
+		t.Errorf("CountAt(yesterday) = %d, want 2", n)
+	}
+	if n, _ := c.Count(ctx); n != 3 {
+		t.Errorf("Count() = %d, want 3", n)
+	}
+	if n, err := c.CountRange(ctx, yesterday, today); err != nil || n != 4 {
+		t.Errorf("CountRange() = %d, %v, want 4", n, err)
+	}
+	if n, err := c.CountRange(ctx, today, yesterday); err != nil || n != 0 {
+		t.Errorf("CountRange() with an empty range = %d, %v, want 0", n, err)
+	}
+
+	if err := c.MergeRange(ctx, "uniques:dau:2d", yesterday, today); err != nil {
+		t.Fatalf("MergeRange() error = %v, want nil", err)
+	}
+	if n, _ := client.PFCount(ctx, "uniques:dau:2d").Result(); n != 4 {
+		t.Errorf("PFCount() of the merged key = %d, want 4", n)
+	}
+
+	if ttl := client.PTTL(ctx, c.Key(today)).Val(); ttl <= 24*time.Hour || ttl > 48*time.Hour {
+		t.Errorf("PTTL() of today's bucket = %v, want between 24h and 48h", ttl)
+	}
+
+	// A bucket past the retention window is not recreated without a TTL
+	old := today.AddDate(0, 0, -3)
+	if changed, err := c.AddAt(ctx, old, "eve"); err != nil || changed {
+		t.Errorf("AddAt() of an expired bucket = %v, %v, want a no-op", changed, err)
+	}
+	if n, _ := client.Exists(ctx, c.Key(old)).Result(); n != 0 {
+		t.Error("AddAt() of an expired bucket created its key")
+	}
+}
+
+func TestCounter_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	c := NewCounter(nil, "x")
+	if _, err := c.Add(ctx, "a"); err == nil {
+		t.Error("Add() with nil client error = nil, want error")
+	}
+	if _, err := c.Count(ctx); err == nil {
+		t.Error("Count() with nil client error = nil, want error")
+	}
+	if err := c.MergeRange(ctx, "d", time.Now(), time.Now()); err == nil {
+		t.Error("MergeRange() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	mock.SetShouldFail(true)
+
+	c = NewCounter(client, "x", WithBucket(BucketHour))
+	if _, err := c.Add(ctx, "a"); err == nil {
+		t.Error("Add() error = nil, want error")
+	}
+	if _, err := c.CountRange(ctx, time.Now().Add(-time.Hour), time.Now()); err == nil {
+		t.Error("CountRange() error = nil, want error")
+	}
+	if err := c.Reset(ctx, time.Now()); err == nil {
+		t.Error("Reset() error = nil, want error")
+	}
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultChunkSize is the default number of keys sent in a single pipeline or command
+const DefaultChunkSize = 500
+
+// Chunk splits items into consecutive slices of at most size elements
+// The returned slices share the backing array of items; size <= 0 uses DefaultChunkSize
+func Chunk[T any](items []T, size int) [][]T {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	chunks := make([][]T, 0, (len(items)+size-1)/size)
+	for start := 0; start < len(items); start += size {
+		end := min(start+size, len(items))
+		chunks = append(chunks, items[start:end:end])
+	}
+	return chunks
+}
+
+// PipelineEach queues fn for every key into pipelines of at most size keys
+// Each chunk is executed before the next one is built, so memory and command size stay bounded
+// redis.Nil replies are not treated as failures; the first failing chunk stops the iteration
+func PipelineEach(ctx context.Context, client redis.Cmdable, keys []string, size int, fn func(pipe redis.Pipeliner, key string)) error {
+	if client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	for i, chunk := range Chunk(keys, size) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		pipe := client.Pipeline()
+		for _, key := range chunk {
+			fn(pipe, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return fmt.Errorf("pipeline chunk %d failed: %w", i, err)
+		}
+	}
+
+	return nil
+}
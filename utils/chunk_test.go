@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestChunk(t *testing.T) {
+	t.Run("even split", func(t *testing.T) {
+		chunks := Chunk([]string{"a", "b", "c", "d"}, 2)
+		if len(chunks) != 2 {
+			t.Fatalf("Chunk() returned %d chunks, want 2", len(chunks))
+		}
+		if chunks[0][0] != "a" || chunks[1][1] != "d" {
+			t.Errorf("Chunk() = %v, want [[a b] [c d]]", chunks)
+		}
+	})
+
+	t.Run("remainder chunk", func(t *testing.T) {
+		chunks := Chunk([]int{1, 2, 3, 4, 5}, 2)
+		if len(chunks) != 3 {
+			t.Fatalf("Chunk() returned %d chunks, want 3", len(chunks))
+		}
+		if len(chunks[2]) != 1 || chunks[2][0] != 5 {
+			t.Errorf("Chunk() last chunk = %v, want [5]", chunks[2])
+		}
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		if chunks := Chunk([]string{}, 10); chunks != nil {
+			t.Errorf("Chunk() on empty input = %v, want nil", chunks)
+		}
+	})
+
+	t.Run("non-positive size uses default", func(t *testing.T) {
+		items := make([]int, DefaultChunkSize+1)
+		chunks := Chunk(items, 0)
+		if len(chunks) != 2 {
+			t.Errorf("Chunk() with size 0 returned %d chunks, want 2", len(chunks))
+		}
+	})
+
+	t.Run("appending to a chunk does not clobber the next one", func(t *testing.T) {
+		items := []int{1, 2, 3, 4}
+		chunks := Chunk(items, 2)
+		_ = append(chunks[0], 99)
+		if items[2] != 3 {
+			t.Errorf("append to chunk modified source: %v", items)
+		}
+	})
+}
+
+func TestPipelineEach(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("executes fn for every key", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		keys := []string{"k1", "k2", "k3", "k4", "k5"}
+		err := PipelineEach(ctx, client, keys, 2, func(pipe redis.Pipeliner, key string) {
+			pipe.Set(ctx, key, "v-"+key, 0)
+		})
+		if err != nil {
+			t.Fatalf("PipelineEach() error = %v, want nil", err)
+		}
+
+		for _, key := range keys {
+			val, err := client.Get(ctx, key).Result()
+			if err != nil || val != "v-"+key {
+				t.Errorf("Get(%q) = (%q, %v), want (%q, nil)", key, val, err, "v-"+key)
+			}
+		}
+	})
+
+	t.Run("ignores redis.Nil replies", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		var cmds []*redis.StringCmd
+		err := PipelineEach(ctx, client, []string{"missing1", "missing2"}, 10, func(pipe redis.Pipeliner, key string) {
+			cmds = append(cmds, pipe.Get(ctx, key))
+		})
+		if err != nil {
+			t.Fatalf("PipelineEach() error = %v, want nil", err)
+		}
+		if len(cmds) != 2 || cmds[0].Err() != redis.Nil {
+			t.Errorf("PipelineEach() did not surface per-command redis.Nil")
+		}
+	})
+
+	t.Run("returns chunk failure", func(t *testing.T) {
+		client, mock := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		mock.SetShouldFail(true)
+		err := PipelineEach(ctx, client, []string{"k1"}, 10, func(pipe redis.Pipeliner, key string) {
+			pipe.Set(ctx, key, "v", 0)
+		})
+		if err == nil {
+			t.Error("PipelineEach() with Redis failure should return error")
+		}
+	})
+
+	t.Run("stops on cancelled context", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		calls := 0
+		err := PipelineEach(ctx, client, []string{"k1"}, 10, func(pipe redis.Pipeliner, key string) {
+			calls++
+		})
+		if err != context.Canceled {
+			t.Errorf("PipelineEach() error = %v, want %v", err, context.Canceled)
+		}
+		if calls != 0 {
+			t.Errorf("PipelineEach() called fn %d times after cancellation", calls)
+		}
+	})
+
+	t.Run("nil client", func(t *testing.T) {
+		err := PipelineEach(ctx, nil, []string{"k1"}, 10, func(redis.Pipeliner, string) {})
+		if err == nil || err.Error() != "redis client is nil" {
+			t.Errorf("PipelineEach() error = %v, want %q", err, "redis client is nil")
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package utils
+
+import "github.com/redis/go-redis/v9"
+
+// NormalizeClient returns nil for a nil client stored in the interface, such as a nil
+// *redis.Client, so constructors accepting a redis.UniversalClient can keep comparing it
+// with nil to detect a missing client
+func NormalizeClient(client redis.UniversalClient) redis.UniversalClient {
+	switch c := client.(type) {
+	case *redis.Client:
+		if c == nil {
+			return nil
+		}
+	case *redis.ClusterClient:
+		if c == nil {
+			return nil
+		}
+	case *redis.Ring:
+		if c == nil {
+			return nil
+		}
+	}
+	return client
+}
+
+// DegradedReporter is implemented by clients that track whether Redis is available, such as
+// client.ResilientClient. The cache, HybridLocker, and rate limiter skip Redis while the client
+// they were given reports itself degraded
+type DegradedReporter interface {
+	Degraded() bool
+}
+
+// Degraded reports whether client is a DegradedReporter that currently reports Redis as
+// unavailable
+func Degraded(client redis.UniversalClient) bool {
+	d, ok := client.(DegradedReporter)
+	return ok && d.Degraded()
+}
+
+// Unwrapper is implemented by clients wrapping another, such as client.ResilientClient, so
+// keyspace-wide helpers such as ScanKeys can reach every node of a cluster or ring underneath
+type Unwrapper interface {
+	Unwrap() redis.UniversalClient
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestNormalizeClient(t *testing.T) {
+	var client *redis.Client
+	var cluster *redis.ClusterClient
+	var ring *redis.Ring
+	for name, c := range map[string]redis.UniversalClient{"untyped": nil, "client": client, "cluster": cluster, "ring": ring} {
+		if got := NormalizeClient(c); got != nil {
+			t.Errorf("NormalizeClient(nil %s) = %v, want nil", name, got)
+		}
+	}
+
+	mockClient, _ := testutil.NewMockRedisClient()
+	defer func() { _ = mockClient.Close() }()
+	if got := NormalizeClient(mockClient); got != mockClient {
+		t.Errorf("NormalizeClient() = %v, want the client unchanged", got)
+	}
+}
+
+// degradedClient reports a settable degraded state
+type degradedClient struct {
+	redis.UniversalClient
+	degraded bool
+}
+
+func (c *degradedClient) Degraded() bool { return c.degraded }
+
+func TestDegraded(t *testing.T) {
+	mockClient, _ := testutil.NewMockRedisClient()
+	defer func() { _ = mockClient.Close() }()
+
+	if Degraded(mockClient) || Degraded(nil) {
+		t.Error("Degraded() = true for a client that does not report it")
+	}
+	client := &degradedClient{UniversalClient: mockClient}
+	if Degraded(client) {
+		t.Error("Degraded() = true, want false")
+	}
+	client.degraded = true
+	if !Degraded(client) {
+		t.Error("Degraded() = false, want true")
+	}
+}
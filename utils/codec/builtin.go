@@ -0,0 +1,91 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var (
+	// JSON encodes values with encoding/json
+	JSON Codec = jsonCodec{}
+	// Msgpack encodes values with MessagePack, which is more compact than JSON
+	Msgpack Codec = msgpackCodec{}
+	// Gob encodes values with encoding/gob, preserving Go types JSON cannot round-trip
+	Gob Codec = gobCodec{}
+	// Raw passes []byte and string values through unchanged
+	Raw Codec = rawCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return NameJSON }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string { return NameMsgpack }
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) { return msgpack.Marshal(v) }
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+
+type gobCodec struct{}
+
+func (gobCodec) Name() string { return NameGob }
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type rawCodec struct{}
+
+func (rawCodec) Name() string { return NameRaw }
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	switch val := v.(type) {
+	case []byte:
+		return val, nil
+	case string:
+		return []byte(val), nil
+	case *[]byte:
+		if val != nil {
+			return *val, nil
+		}
+	case *string:
+		if val != nil {
+			return []byte(*val), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: raw cannot marshal %T", ErrUnsupportedType, v)
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	switch val := v.(type) {
+	case *[]byte:
+		if val != nil {
+			*val = append((*val)[:0], data...)
+			return nil
+		}
+	case *string:
+		if val != nil {
+			*val = string(data)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: raw cannot unmarshal into %T", ErrUnsupportedType, v)
+}
@@ -0,0 +1,91 @@
+package codec
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type sample struct {
+	ID      string
+	Count   int
+	Created time.Time
+	Tags    []string
+}
+
+func TestStructuredCodecs_RoundTrip(t *testing.T) {
+	in := sample{ID: "42", Count: 7, Created: time.Unix(1700000000, 0).UTC(), Tags: []string{"a", "b"}}
+
+	for _, c := range []Codec{JSON, Msgpack, Gob} {
+		t.Run(c.Name(), func(t *testing.T) {
+			data, err := c.Marshal(in)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var out sample
+			if err := c.Unmarshal(data, &out); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if out.ID != in.ID || out.Count != in.Count || !out.Created.Equal(in.Created) || len(out.Tags) != 2 {
+				t.Errorf("round trip = %+v, want %+v", out, in)
+			}
+		})
+	}
+}
+
+func TestStructuredCodecs_Errors(t *testing.T) {
+	for _, c := range []Codec{JSON, Msgpack, Gob} {
+		t.Run(c.Name(), func(t *testing.T) {
+			var out sample
+			if err := c.Unmarshal([]byte{0xc1, 0xff, 0x00}, &out); err == nil {
+				t.Error("Unmarshal() of garbage should return error")
+			}
+		})
+	}
+
+	if _, err := JSON.Marshal(make(chan int)); err == nil {
+		t.Error("JSON.Marshal(chan) should return error")
+	}
+	if _, err := Gob.Marshal(make(chan int)); err == nil {
+		t.Error("Gob.Marshal(chan) should return error")
+	}
+}
+
+func TestRawCodec(t *testing.T) {
+	t.Run("bytes and strings", func(t *testing.T) {
+		b := []byte("blob")
+		s := "text"
+		for _, v := range []interface{}{b, s, &b, &s} {
+			data, err := Raw.Marshal(v)
+			if err != nil {
+				t.Errorf("Raw.Marshal(%T) error = %v", v, err)
+			}
+			if len(data) != 4 {
+				t.Errorf("Raw.Marshal(%T) = %q", v, data)
+			}
+		}
+
+		var outBytes []byte
+		if err := Raw.Unmarshal([]byte("blob"), &outBytes); err != nil || string(outBytes) != "blob" {
+			t.Errorf("Raw.Unmarshal(*[]byte) = (%q, %v)", outBytes, err)
+		}
+		var outString string
+		if err := Raw.Unmarshal([]byte("text"), &outString); err != nil || outString != "text" {
+			t.Errorf("Raw.Unmarshal(*string) = (%q, %v)", outString, err)
+		}
+	})
+
+	t.Run("unsupported types", func(t *testing.T) {
+		if _, err := Raw.Marshal(42); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("Raw.Marshal(int) error = %v, want %v", err, ErrUnsupportedType)
+		}
+		var n int
+		if err := Raw.Unmarshal([]byte("1"), &n); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("Raw.Unmarshal(*int) error = %v, want %v", err, ErrUnsupportedType)
+		}
+		var nilBytes *[]byte
+		if err := Raw.Unmarshal([]byte("1"), nilBytes); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("Raw.Unmarshal(nil *[]byte) error = %v, want %v", err, ErrUnsupportedType)
+		}
+	})
+}
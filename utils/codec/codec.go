@@ -0,0 +1,107 @@
+// Package codec provides the pluggable value encoding shared by the kit's packages
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Built-in codec names
+const (
+	NameJSON    = "json"
+	NameMsgpack = "msgpack"
+	NameGob     = "gob"
+	NameRaw     = "raw"
+)
+
+var (
+	// ErrUnknownCodec indicates no codec is registered under the requested name.
+	ErrUnknownCodec = errors.New("unknown codec")
+	// ErrUnsupportedType indicates the codec cannot encode or decode the given type.
+	ErrUnsupportedType = errors.New("unsupported type for codec")
+	// ErrDecrypt indicates an encrypted value cannot be decrypted.
+	ErrDecrypt = errors.New("failed to decrypt value")
+)
+
+// Codec converts values to and from their stored byte representation
+type Codec interface {
+	// Name returns the registry name of the codec
+	Name() string
+
+	// Marshal encodes v
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal decodes data into v, which should be a pointer
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Sealer is implemented by codecs that transform their encoded output, e.g. EncryptedCodec;
+// callers that store []byte or string values without the codec still seal them with it
+type Sealer interface {
+	// Seal transforms encoded data for storage
+	Seal(data []byte) ([]byte, error)
+
+	// Open reverses Seal
+	Open(data []byte) ([]byte, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Codec{}
+)
+
+func init() {
+	Register(JSON)
+	Register(Msgpack)
+	Register(Gob)
+	Register(Raw)
+}
+
+// Register adds a codec to the registry, replacing any codec with the same name
+func Register(c Codec) {
+	if c == nil {
+		return
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[c.Name()] = c
+}
+
+// Get returns the codec registered under name
+func Get(name string) (Codec, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	c, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCodec, name)
+	}
+	return c, nil
+}
+
+// MustGet returns the codec registered under name and panics if there is none
+func MustGet(name string) Codec {
+	c, err := Get(name)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Names returns the sorted names of all registered codecs
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Default returns the codec used when none is configured (JSON)
+func Default() Codec {
+	return JSON
+}
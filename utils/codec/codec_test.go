@@ -0,0 +1,71 @@
+package codec
+
+import (
+	"errors"
+	"testing"
+)
+
+type upperCodec struct{ rawCodec }
+
+func (upperCodec) Name() string { return "upper" }
+
+func TestRegistry(t *testing.T) {
+	t.Run("built-in codecs registered", func(t *testing.T) {
+		for _, name := range []string{NameJSON, NameMsgpack, NameGob, NameRaw} {
+			c, err := Get(name)
+			if err != nil {
+				t.Errorf("Get(%q) error = %v", name, err)
+				continue
+			}
+			if c.Name() != name {
+				t.Errorf("Get(%q).Name() = %q", name, c.Name())
+			}
+		}
+	})
+
+	t.Run("unknown codec", func(t *testing.T) {
+		_, err := Get("nope")
+		if !errors.Is(err, ErrUnknownCodec) {
+			t.Errorf("Get(nope) error = %v, want %v", err, ErrUnknownCodec)
+		}
+	})
+
+	t.Run("register custom codec", func(t *testing.T) {
+		Register(upperCodec{})
+		if c := MustGet("upper"); c.Name() != "upper" {
+			t.Errorf("MustGet(upper).Name() = %q", c.Name())
+		}
+		found := false
+		for _, name := range Names() {
+			if name == "upper" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Names() = %v, missing upper", Names())
+		}
+	})
+
+	t.Run("register nil is ignored", func(t *testing.T) {
+		before := len(Names())
+		Register(nil)
+		if len(Names()) != before {
+			t.Error("Register(nil) changed the registry")
+		}
+	})
+
+	t.Run("MustGet panics on unknown codec", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("MustGet(nope) should panic")
+			}
+		}()
+		MustGet("nope")
+	})
+
+	t.Run("default is JSON", func(t *testing.T) {
+		if Default().Name() != NameJSON {
+			t.Errorf("Default().Name() = %q, want %q", Default().Name(), NameJSON)
+		}
+	})
+}
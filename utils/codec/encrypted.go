@@ -0,0 +1,119 @@
+package codec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+// encryptedVersion starts every payload of an EncryptedCodec, ahead of the key ID length, the
+// key ID, the nonce, and the sealed data
+const encryptedVersion = 1
+
+// EncryptedCodec encrypts the output of another codec with AES-GCM, so values such as PII
+// stay encrypted at rest in a shared Redis. Each payload names the key it was sealed with, so
+// keys can be rotated: new values use the primary key while values sealed with any other key
+// of the keyring still decrypt until they expire
+type EncryptedCodec struct {
+	inner   Codec
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// Encrypted returns a codec encoding values with inner and encrypting the result with the
+// key named primary; keys maps key IDs of 1 to 255 bytes to AES keys of 16, 24, or 32 bytes,
+// and must hold primary. Keep retired keys in it as long as values sealed with them may be read
+func Encrypted(inner Codec, primary string, keys map[string][]byte) (*EncryptedCodec, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("inner codec is nil")
+	}
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary key %q is not in the keyring", primary)
+	}
+	c := &EncryptedCodec{inner: inner, primary: primary, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if len(id) == 0 || len(id) > 255 {
+			return nil, fmt.Errorf("key ID %q must be 1 to 255 bytes", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher for key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher for key %q: %w", id, err)
+		}
+		c.aeads[id] = aead
+	}
+	return c, nil
+}
+
+// Name returns the inner codec's name with "+aes-gcm" appended
+func (c *EncryptedCodec) Name() string {
+	return c.inner.Name() + "+aes-gcm"
+}
+
+// Marshal encodes v with the inner codec and encrypts the result
+func (c *EncryptedCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.Seal(data)
+}
+
+// Unmarshal decrypts data and decodes it into v with the inner codec
+func (c *EncryptedCodec) Unmarshal(data []byte, v interface{}) error {
+	plain, err := c.Open(data)
+	if err != nil {
+		return err
+	}
+	return c.inner.Unmarshal(plain, v)
+}
+
+// Seal encrypts data with the primary key
+func (c *EncryptedCodec) Seal(data []byte) ([]byte, error) {
+	aead := c.aeads[c.primary]
+	out := make([]byte, 0, 2+len(c.primary)+aead.NonceSize()+len(data)+aead.Overhead())
+	out = append(out, encryptedVersion, byte(len(c.primary)))
+	out = append(out, c.primary...)
+	nonce := out[len(out) : len(out)+aead.NonceSize()]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out = out[:len(out)+len(nonce)]
+	return aead.Seal(out, nonce, data, out[:2+len(c.primary)]), nil
+}
+
+// Open decrypts data sealed with any key of the keyring; it fails with ErrDecrypt when data
+// is not such a payload, names an unknown key, or was tampered with
+func (c *EncryptedCodec) Open(data []byte) ([]byte, error) {
+	id, err := c.KeyID(data)
+	if err != nil {
+		return nil, err
+	}
+	aead, ok := c.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrDecrypt, id)
+	}
+	header := 2 + len(id)
+	if len(data) < header+aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("%w: payload too short", ErrDecrypt)
+	}
+	nonce := data[header : header+aead.NonceSize()]
+	plain, err := aead.Open(nil, nonce, data[header+aead.NonceSize():], data[:header])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	return plain, nil
+}
+
+// KeyID returns the ID of the key data was sealed with, e.g. to find values still sealed with
+// a key being retired
+func (c *EncryptedCodec) KeyID(data []byte) (string, error) {
+	if len(data) < 2 || data[0] != encryptedVersion || len(data) < 2+int(data[1]) || data[1] == 0 {
+		return "", fmt.Errorf("%w: not an encrypted payload", ErrDecrypt)
+	}
+	return string(data[2 : 2+int(data[1])]), nil
+}
@@ -0,0 +1,108 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestEncrypted(t *testing.T) {
+	c, err := Encrypted(JSON, "k1", map[string][]byte{"k1": testKey(1)})
+	if err != nil {
+		t.Fatalf("Encrypted() error = %v, want nil", err)
+	}
+	var _ Sealer = c
+	if c.Name() != "json+aes-gcm" {
+		t.Errorf("Name() = %q, want json+aes-gcm", c.Name())
+	}
+
+	in := sample{ID: "42", Tags: []string{"pii"}}
+	data, err := c.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v, want nil", err)
+	}
+	if bytes.Contains(data, []byte("pii")) {
+		t.Errorf("Marshal() = %q, want the value encrypted", data)
+	}
+	if id, err := c.KeyID(data); err != nil || id != "k1" {
+		t.Errorf("KeyID() = %q, %v, want k1", id, err)
+	}
+	var out sample
+	if err := c.Unmarshal(data, &out); err != nil || out.ID != "42" || len(out.Tags) != 1 {
+		t.Errorf("Unmarshal() = %+v, %v, want %+v", out, err, in)
+	}
+
+	// Each payload gets its own nonce
+	again, _ := c.Marshal(in)
+	if bytes.Equal(data, again) {
+		t.Error("Marshal() twice returned the same payload")
+	}
+
+	// Tampering is detected
+	data[len(data)-1] ^= 1
+	if err := c.Unmarshal(data, &out); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Unmarshal() of a tampered payload error = %v, want ErrDecrypt", err)
+	}
+	for _, bad := range [][]byte{nil, []byte(`{"ID":"42"}`), {1, 2, 'k', '1'}} {
+		if _, err := c.Open(bad); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("Open(%q) error = %v, want ErrDecrypt", bad, err)
+		}
+	}
+}
+
+func TestEncrypted_Rotation(t *testing.T) {
+	old, _ := Encrypted(Raw, "k1", map[string][]byte{"k1": testKey(1)})
+	sealed, err := old.Marshal("secret")
+	if err != nil {
+		t.Fatalf("Marshal() error = %v, want nil", err)
+	}
+
+	// New values use the new primary key and old ones still decrypt
+	rotated, err := Encrypted(Raw, "k2", map[string][]byte{"k1": testKey(1), "k2": testKey(2)})
+	if err != nil {
+		t.Fatalf("Encrypted() error = %v, want nil", err)
+	}
+	var s string
+	if err := rotated.Unmarshal(sealed, &s); err != nil || s != "secret" {
+		t.Errorf("Unmarshal() of a value sealed with a retired key = %q, %v, want secret", s, err)
+	}
+	fresh, _ := rotated.Marshal("secret")
+	if id, _ := rotated.KeyID(fresh); id != "k2" {
+		t.Errorf("KeyID() of a new value = %q, want k2", id)
+	}
+
+	// Once a key is dropped, its values no longer decrypt
+	if err := old.Unmarshal(fresh, &s); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Unmarshal() with an unknown key error = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestEncrypted_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		inner   Codec
+		primary string
+		keys    map[string][]byte
+	}{
+		{"nil inner", nil, "k", map[string][]byte{"k": testKey(1)}},
+		{"missing primary", JSON, "k", map[string][]byte{"other": testKey(1)}},
+		{"bad key size", JSON, "k", map[string][]byte{"k": []byte("short")}},
+		{"empty key ID", JSON, "k", map[string][]byte{"k": testKey(1), "": testKey(2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Encrypted(tt.inner, tt.primary, tt.keys); err == nil {
+				t.Error("Encrypted() error = nil, want error")
+			}
+		})
+	}
+
+	c, _ := Encrypted(JSON, "k", map[string][]byte{"k": testKey(1)})
+	if _, err := c.Marshal(make(chan int)); err == nil {
+		t.Error("Marshal(chan) error = nil, want error")
+	}
+}
@@ -10,6 +10,32 @@ const (
 	DefaultOperationTimeout = 5 * time.Second
 )
 
+// ReadPreference selects which node serves read commands
+type ReadPreference int
+
+const (
+	// ReadPrimary routes reads to the primary (default)
+	ReadPrimary ReadPreference = iota
+	// ReadPreferReplica routes reads to a replica when one is healthy, falling back to the primary
+	ReadPreferReplica
+)
+
+// String returns the name of the read preference
+func (p ReadPreference) String() string {
+	switch p {
+	case ReadPrimary:
+		return "primary"
+	case ReadPreferReplica:
+		return "prefer-replica"
+	default:
+		return "unknown"
+	}
+}
+
+type timeoutOverrideKey struct{}
+
+type readPreferenceKey struct{}
+
 // WithTimeout creates a context with the given timeout
 func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
 	if ctx == nil {
@@ -19,6 +45,55 @@ func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, c
 }
 
 // WithDefaultTimeout creates a context with the default operation timeout
+// A timeout attached with ContextWithTimeoutOverride takes precedence over the default
 func WithDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
-	return WithTimeout(ctx, DefaultOperationTimeout)
+	return WithOperationTimeout(ctx, DefaultOperationTimeout)
+}
+
+// WithOperationTimeout creates a context with the per-call timeout override if present, otherwise fallback
+func WithOperationTimeout(ctx context.Context, fallback time.Duration) (context.Context, context.CancelFunc) {
+	if timeout, ok := TimeoutOverride(ctx); ok {
+		return WithTimeout(ctx, timeout)
+	}
+	return WithTimeout(ctx, fallback)
+}
+
+// ContextWithTimeoutOverride attaches a per-call operation timeout to ctx
+// Kit operations that apply a default timeout use this value instead; non-positive values are ignored
+func ContextWithTimeoutOverride(ctx context.Context, timeout time.Duration) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if timeout <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, timeoutOverrideKey{}, timeout)
+}
+
+// TimeoutOverride returns the per-call operation timeout attached to ctx, if any
+func TimeoutOverride(ctx context.Context) (time.Duration, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	timeout, ok := ctx.Value(timeoutOverrideKey{}).(time.Duration)
+	return timeout, ok
+}
+
+// WithReadPreference attaches a read preference to ctx, honored by read/write splitting clients
+func WithReadPreference(ctx context.Context, pref ReadPreference) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, readPreferenceKey{}, pref)
+}
+
+// ReadPreferenceFromContext returns the read preference attached to ctx (default: ReadPrimary)
+func ReadPreferenceFromContext(ctx context.Context) ReadPreference {
+	if ctx == nil {
+		return ReadPrimary
+	}
+	if pref, ok := ctx.Value(readPreferenceKey{}).(ReadPreference); ok {
+		return pref
+	}
+	return ReadPrimary
 }
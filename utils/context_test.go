@@ -179,3 +179,91 @@ func TestWithDefaultTimeout(t *testing.T) {
 		}
 	})
 }
+
+func TestContextWithTimeoutOverride(t *testing.T) {
+	t.Run("override is returned", func(t *testing.T) {
+		ctx := ContextWithTimeoutOverride(context.Background(), 50*time.Millisecond)
+		timeout, ok := TimeoutOverride(ctx)
+		if !ok || timeout != 50*time.Millisecond {
+			t.Errorf("TimeoutOverride() = (%v, %v), want (50ms, true)", timeout, ok)
+		}
+	})
+
+	t.Run("no override", func(t *testing.T) {
+		if _, ok := TimeoutOverride(context.Background()); ok {
+			t.Error("TimeoutOverride() on plain context should report false")
+		}
+		//nolint:staticcheck // SA1012: intentionally passing nil to test nil handling
+		if _, ok := TimeoutOverride(nil); ok {
+			t.Error("TimeoutOverride(nil) should report false")
+		}
+	})
+
+	t.Run("non-positive timeout ignored", func(t *testing.T) {
+		ctx := ContextWithTimeoutOverride(context.Background(), 0)
+		if _, ok := TimeoutOverride(ctx); ok {
+			t.Error("ContextWithTimeoutOverride(0) should not attach an override")
+		}
+	})
+
+	t.Run("handles nil context", func(t *testing.T) {
+		//nolint:staticcheck // SA1012: intentionally passing nil to test nil handling
+		ctx := ContextWithTimeoutOverride(nil, time.Second)
+		if _, ok := TimeoutOverride(ctx); !ok {
+			t.Error("ContextWithTimeoutOverride(nil) should attach to Background")
+		}
+	})
+
+	t.Run("WithDefaultTimeout honors override", func(t *testing.T) {
+		start := time.Now()
+		ctx, cancel := WithDefaultTimeout(ContextWithTimeoutOverride(context.Background(), 30*time.Millisecond))
+		defer cancel()
+
+		<-ctx.Done()
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("WithDefaultTimeout() ignored override, took %v", elapsed)
+		}
+	})
+
+	t.Run("WithOperationTimeout uses fallback", func(t *testing.T) {
+		ctx, cancel := WithOperationTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		deadline, ok := ctx.Deadline()
+		if !ok || time.Until(deadline) < 59*time.Second {
+			t.Errorf("WithOperationTimeout() deadline = %v, want ~1m from now", deadline)
+		}
+	})
+}
+
+func TestWithReadPreference(t *testing.T) {
+	if got := ReadPreferenceFromContext(context.Background()); got != ReadPrimary {
+		t.Errorf("ReadPreferenceFromContext() default = %v, want %v", got, ReadPrimary)
+	}
+	//nolint:staticcheck // SA1012: intentionally passing nil to test nil handling
+	if got := ReadPreferenceFromContext(nil); got != ReadPrimary {
+		t.Errorf("ReadPreferenceFromContext(nil) = %v, want %v", got, ReadPrimary)
+	}
+
+	ctx := WithReadPreference(context.Background(), ReadPreferReplica)
+	if got := ReadPreferenceFromContext(ctx); got != ReadPreferReplica {
+		t.Errorf("ReadPreferenceFromContext() = %v, want %v", got, ReadPreferReplica)
+	}
+
+	//nolint:staticcheck // SA1012: intentionally passing nil to test nil handling
+	if got := ReadPreferenceFromContext(WithReadPreference(nil, ReadPreferReplica)); got != ReadPreferReplica {
+		t.Errorf("WithReadPreference(nil) = %v, want %v", got, ReadPreferReplica)
+	}
+}
+
+func TestReadPreference_String(t *testing.T) {
+	for pref, want := range map[ReadPreference]string{
+		ReadPrimary:        "primary",
+		ReadPreferReplica:  "prefer-replica",
+		ReadPreference(99): "unknown",
+	} {
+		if got := pref.String(); got != want {
+			t.Errorf("ReadPreference(%d).String() = %q, want %q", pref, got, want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultDeleteBatchSize is the default number of keys removed per round trip
+const DefaultDeleteBatchSize = 100
+
+// DeleteOptions controls how DeleteByPattern removes keys
+type DeleteOptions struct {
+	// BatchSize is the maximum number of keys removed per round trip (default: 100)
+	BatchSize int
+
+	// ScanCount is the COUNT hint passed to SCAN (default: 100)
+	ScanCount int64
+
+	// Interval is the pause between batches, limiting the load put on the server (default: 0)
+	Interval time.Duration
+
+	// DryRun only counts matching keys without deleting anything
+	DryRun bool
+
+	// OnProgress is called after every batch with the running totals and a copy of the batch's
+	// keys, which it may keep
+	OnProgress func(progress DeleteResult, batch []string)
+}
+
+// DeleteResult reports the outcome of DeleteByPattern
+type DeleteResult struct {
+	// Matched is the number of keys found by SCAN
+	Matched int64
+
+	// Deleted is the number of keys actually removed (always 0 in dry-run mode)
+	Deleted int64
+}
+
+// DefaultDeleteOptions returns DeleteOptions with default values
+func DefaultDeleteOptions() DeleteOptions {
+	return DeleteOptions{
+		BatchSize: DefaultDeleteBatchSize,
+		ScanCount: DefaultScanCount,
+	}
+}
+
+// WithBatchSize sets the number of keys removed per round trip
+func (o DeleteOptions) WithBatchSize(size int) DeleteOptions {
+	o.BatchSize = size
+	return o
+}
+
+// WithInterval sets the pause between batches
+func (o DeleteOptions) WithInterval(interval time.Duration) DeleteOptions {
+	o.Interval = interval
+	return o
+}
+
+// WithDryRun enables or disables dry-run mode
+func (o DeleteOptions) WithDryRun(dryRun bool) DeleteOptions {
+	o.DryRun = dryRun
+	return o
+}
+
+// WithProgress sets the progress callback
+func (o DeleteOptions) WithProgress(fn func(progress DeleteResult, batch []string)) DeleteOptions {
+	o.OnProgress = fn
+	return o
+}
+
+// DeleteByPattern removes every key matching pattern using SCAN and batched UNLINK
+// UNLINK frees memory in the background, and batches are paced by opts.Interval,
+// so large cleanups do not block the server; the partial result is returned on error
+// On a cluster client every master is scanned, and each batch is one pipeline of
+// single-key UNLINKs, as a multi-key UNLINK fails across slots
+func DeleteByPattern(ctx context.Context, client redis.Cmdable, pattern string, opts DeleteOptions) (DeleteResult, error) {
+	var result DeleteResult
+	if client == nil {
+		return result, fmt.Errorf("redis client is nil")
+	}
+	if pattern == "" {
+		return result, fmt.Errorf("pattern is required")
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultDeleteBatchSize
+	}
+
+	batch := make([]string, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		result.Matched += int64(len(batch))
+		if !opts.DryRun {
+			n, err := unlink(ctx, client, batch)
+			if err != nil {
+				return fmt.Errorf("failed to unlink keys: %w", err)
+			}
+			result.Deleted += n
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(result, slices.Clone(batch))
+		}
+		batch = batch[:0]
+		return sleepContext(ctx, opts.Interval)
+	}
+
+	for key, err := range ScanKeys(ctx, client, pattern, opts.ScanCount) {
+		if err != nil {
+			return result, err
+		}
+		batch = append(batch, key)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// unlink deletes keys in one round trip with one UNLINK per key, as the keys may live in
+// different cluster slots, and returns the number of keys deleted
+func unlink(ctx context.Context, client redis.Cmdable, keys []string) (int64, error) {
+	pipe := client.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Unlink(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	var deleted int64
+	for _, cmd := range cmds {
+		deleted += cmd.Val()
+	}
+	return deleted, nil
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
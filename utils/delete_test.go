@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestDefaultDeleteOptions(t *testing.T) {
+	opts := DefaultDeleteOptions()
+	if opts.BatchSize != DefaultDeleteBatchSize {
+		t.Errorf("DefaultDeleteOptions().BatchSize = %d, want %d", opts.BatchSize, DefaultDeleteBatchSize)
+	}
+	if opts.ScanCount != DefaultScanCount {
+		t.Errorf("DefaultDeleteOptions().ScanCount = %d, want %d", opts.ScanCount, DefaultScanCount)
+	}
+	if opts.DryRun {
+		t.Error("DefaultDeleteOptions().DryRun = true, want false")
+	}
+
+	opts = opts.WithBatchSize(5).WithInterval(time.Millisecond).WithDryRun(true).WithProgress(func(DeleteResult, []string) {})
+	if opts.BatchSize != 5 || opts.Interval != time.Millisecond || !opts.DryRun || opts.OnProgress == nil {
+		t.Errorf("DeleteOptions builders did not apply: %+v", opts)
+	}
+}
+
+func TestDeleteByPattern(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("deletes matching keys in batches", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+		for i := 0; i < 12; i++ {
+			_ = client.Set(ctx, fmt.Sprintf("tmp:%02d", i), "v", 0).Err()
+		}
+		_ = client.Set(ctx, "keep:1", "v", 0).Err()
+
+		var batches [][]string
+		opts := DefaultDeleteOptions().WithBatchSize(5).WithProgress(func(_ DeleteResult, batch []string) {
+			batches = append(batches, batch)
+		})
+		res, err := DeleteByPattern(ctx, client, "tmp:*", opts)
+		if err != nil {
+			t.Fatalf("DeleteByPattern() error = %v", err)
+		}
+		if res.Matched != 12 || res.Deleted != 12 {
+			t.Errorf("DeleteByPattern() = %+v, want Matched=12 Deleted=12", res)
+		}
+		if len(batches) != 3 || len(batches[0]) != 5 || len(batches[2]) != 2 {
+			t.Errorf("DeleteByPattern() batches = %v, want 5, 5, and 2 keys", batches)
+		}
+		// Batches kept by the callback are not reused for the next ones
+		seen := map[string]bool{}
+		for _, batch := range batches {
+			for _, key := range batch {
+				seen[key] = true
+			}
+		}
+		if len(seen) != 12 {
+			t.Errorf("DeleteByPattern() batches = %v, want 12 distinct keys", batches)
+		}
+
+		if n, _ := client.Exists(ctx, "tmp:00", "tmp:11").Result(); n != 0 {
+			t.Errorf("matching keys still exist: %d", n)
+		}
+		if n, _ := client.Exists(ctx, "keep:1").Result(); n != 1 {
+			t.Error("non-matching key was deleted")
+		}
+	})
+
+	t.Run("deletes across cluster masters", func(t *testing.T) {
+		cluster, _ := testutil.NewMockClusterClient(3)
+		defer func() { _ = cluster.Close() }()
+		for i := 0; i < 30; i++ {
+			_ = cluster.Set(ctx, fmt.Sprintf("tmp:%02d", i), "v", 0).Err()
+		}
+
+		res, err := DeleteByPattern(ctx, cluster, "tmp:*", DefaultDeleteOptions().WithBatchSize(7))
+		if err != nil || res.Matched != 30 || res.Deleted != 30 {
+			t.Errorf("DeleteByPattern() on a cluster = %+v, %v, want Matched=30 Deleted=30", res, err)
+		}
+		if keys, _ := ScanAll(ctx, cluster, "tmp:*", 10); len(keys) != 0 {
+			t.Errorf("keys left on the cluster = %v, want none", keys)
+		}
+	})
+
+	t.Run("dry run keeps keys", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+		for i := 0; i < 3; i++ {
+			_ = client.Set(ctx, fmt.Sprintf("tmp:%d", i), "v", 0).Err()
+		}
+
+		res, err := DeleteByPattern(ctx, client, "tmp:*", DefaultDeleteOptions().WithDryRun(true))
+		if err != nil {
+			t.Fatalf("DeleteByPattern() error = %v", err)
+		}
+		if res.Matched != 3 || res.Deleted != 0 {
+			t.Errorf("DeleteByPattern() dry run = %+v, want Matched=3 Deleted=0", res)
+		}
+		if n, _ := client.Exists(ctx, "tmp:0", "tmp:1", "tmp:2").Result(); n != 3 {
+			t.Errorf("dry run deleted keys, %d remain", n)
+		}
+	})
+
+	t.Run("interval respects cancellation", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+		for i := 0; i < 3; i++ {
+			_ = client.Set(ctx, fmt.Sprintf("tmp:%d", i), "v", 0).Err()
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		opts := DefaultDeleteOptions().WithBatchSize(1).WithInterval(time.Hour).WithProgress(func(DeleteResult, []string) {
+			cancel()
+		})
+		res, err := DeleteByPattern(ctx, client, "tmp:*", opts)
+		if err != context.Canceled {
+			t.Errorf("DeleteByPattern() error = %v, want %v", err, context.Canceled)
+		}
+		if res.Deleted != 1 {
+			t.Errorf("DeleteByPattern() deleted %d keys before cancellation, want 1", res.Deleted)
+		}
+	})
+
+	t.Run("redis failure", func(t *testing.T) {
+		client, mock := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		mock.SetShouldFail(true)
+		if _, err := DeleteByPattern(ctx, client, "*", DefaultDeleteOptions()); err == nil {
+			t.Error("DeleteByPattern() with Redis failure should return error")
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		if _, err := DeleteByPattern(ctx, client, "", DefaultDeleteOptions()); err == nil {
+			t.Error("DeleteByPattern() with empty pattern should return error")
+		}
+		if _, err := DeleteByPattern(ctx, nil, "*", DefaultDeleteOptions()); err == nil {
+			t.Error("DeleteByPattern() with nil client should return error")
+		}
+	})
+}
@@ -1,5 +1,14 @@
 package utils
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrKeyPrefixMismatch indicates a key does not start with the expected prefix.
+var ErrKeyPrefixMismatch = errors.New("key prefix mismatch")
+
 // BuildKey constructs a key with the given prefix
 func BuildKey(prefix, key string) string {
 	if prefix == "" {
@@ -16,3 +25,12 @@ func BuildKeys(prefix string, keys ...string) []string {
 	}
 	return result
 }
+
+// ParseKey strips prefix from a raw Redis key, the inverse of BuildKey
+// Returns ErrKeyPrefixMismatch if the key does not start with prefix
+func ParseKey(prefix, key string) (string, error) {
+	if !strings.HasPrefix(key, prefix) {
+		return "", fmt.Errorf("%w: %q does not start with %q", ErrKeyPrefixMismatch, key, prefix)
+	}
+	return key[len(prefix):], nil
+}
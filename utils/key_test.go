@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -95,3 +96,28 @@ func TestBuildKeys(t *testing.T) {
 		}
 	})
 }
+
+func TestParseKey(t *testing.T) {
+	t.Run("strips prefix", func(t *testing.T) {
+		got, err := ParseKey("test:", "test:mykey")
+		if err != nil || got != "mykey" {
+			t.Errorf("ParseKey() = (%q, %v), want (%q, nil)", got, err, "mykey")
+		}
+	})
+
+	t.Run("inverse of BuildKey", func(t *testing.T) {
+		for _, prefix := range []string{"", "app:", "a:b:"} {
+			got, err := ParseKey(prefix, BuildKey(prefix, "user:1"))
+			if err != nil || got != "user:1" {
+				t.Errorf("ParseKey(%q, BuildKey()) = (%q, %v), want (%q, nil)", prefix, got, err, "user:1")
+			}
+		}
+	})
+
+	t.Run("prefix mismatch", func(t *testing.T) {
+		_, err := ParseKey("test:", "other:mykey")
+		if !errors.Is(err, ErrKeyPrefixMismatch) {
+			t.Errorf("ParseKey() error = %v, want %v", err, ErrKeyPrefixMismatch)
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// retryableErrorPrefixes lists server replies that indicate a transient condition
+var retryableErrorPrefixes = []string{"LOADING", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN", "READONLY", "BUSY"}
+
+// RetryPolicy describes how an operation is retried on transient failures
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one (<= 1 disables retries)
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts (0 means no cap)
+	MaxBackoff time.Duration
+
+	// Multiplier is applied to the delay after every attempt (values below 1 are treated as 1)
+	Multiplier float64
+
+	// Jitter is the fraction (0-1) by which each delay is randomly shortened or extended
+	Jitter float64
+
+	// Classifier reports whether an error is worth retrying (default: IsRetryableError)
+	Classifier func(error) bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy with default values
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		Classifier:     IsRetryableError,
+	}
+}
+
+// NoRetry returns a RetryPolicy that runs the operation exactly once
+func NoRetry() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// WithMaxAttempts sets the total number of attempts
+func (p RetryPolicy) WithMaxAttempts(attempts int) RetryPolicy {
+	p.MaxAttempts = attempts
+	return p
+}
+
+// WithBackoff sets the initial and maximum delay between attempts
+func (p RetryPolicy) WithBackoff(initial, maxBackoff time.Duration) RetryPolicy {
+	p.InitialBackoff = initial
+	p.MaxBackoff = maxBackoff
+	return p
+}
+
+// WithJitter sets the random jitter fraction
+func (p RetryPolicy) WithJitter(fraction float64) RetryPolicy {
+	p.Jitter = fraction
+	return p
+}
+
+// WithClassifier sets the function deciding which errors are retried
+func (p RetryPolicy) WithClassifier(classifier func(error) bool) RetryPolicy {
+	p.Classifier = classifier
+	return p
+}
+
+// Backoff returns the delay to wait after the given attempt (1-based) failed
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if p.InitialBackoff <= 0 || attempt < 1 {
+		return 0
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		delay += delay * jitter * (2*rand.Float64() - 1)
+	}
+
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// Retry runs fn until it succeeds, returns a non-retryable error, or the policy's attempts are exhausted
+// The last error is returned; if ctx is done while waiting, the result also wraps ctx.Err()
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	classifier := policy.Classifier
+	if classifier == nil {
+		classifier = IsRetryableError
+	}
+
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if attempt == attempts || !classifier(err) {
+			return err
+		}
+
+		timer := time.NewTimer(policy.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	return err
+}
+
+// IsRetryableError reports whether err looks like a transient Redis failure
+// Network errors and server replies such as LOADING or TRYAGAIN are retryable;
+// redis.Nil, context errors, and other server errors are not
+func IsRetryableError(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	for _, prefix := range retryableErrorPrefixes {
+		if redis.HasErrorPrefix(err, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsUnsentError reports whether err shows that a command was never executed, so retrying a
+// write cannot apply it twice: no connection could be made, or the server rejected the
+// command with a reply such as LOADING or TRYAGAIN. Errors after a command was sent, such as
+// a lost reply, are not unsent
+func IsUnsentError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, redis.ErrPoolTimeout) || errors.Is(err, redis.ErrPoolExhausted) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	for _, prefix := range retryableErrorPrefixes {
+		if redis.HasErrorPrefix(err, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
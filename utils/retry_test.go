@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var errTransient = errors.New("transient")
+
+func alwaysRetry(error) bool { return true }
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	p := DefaultRetryPolicy()
+	if p.MaxAttempts != 3 {
+		t.Errorf("DefaultRetryPolicy().MaxAttempts = %d, want 3", p.MaxAttempts)
+	}
+	if p.InitialBackoff != 50*time.Millisecond {
+		t.Errorf("DefaultRetryPolicy().InitialBackoff = %v, want %v", p.InitialBackoff, 50*time.Millisecond)
+	}
+	if p.MaxBackoff != time.Second {
+		t.Errorf("DefaultRetryPolicy().MaxBackoff = %v, want %v", p.MaxBackoff, time.Second)
+	}
+	if p.Classifier == nil {
+		t.Error("DefaultRetryPolicy().Classifier should be set")
+	}
+}
+
+func TestRetryPolicy_With(t *testing.T) {
+	p := NoRetry().
+		WithMaxAttempts(5).
+		WithBackoff(time.Millisecond, 10*time.Millisecond).
+		WithJitter(0.5).
+		WithClassifier(alwaysRetry)
+
+	if p.MaxAttempts != 5 {
+		t.Errorf("WithMaxAttempts() = %d, want 5", p.MaxAttempts)
+	}
+	if p.InitialBackoff != time.Millisecond || p.MaxBackoff != 10*time.Millisecond {
+		t.Errorf("WithBackoff() = (%v, %v), want (1ms, 10ms)", p.InitialBackoff, p.MaxBackoff)
+	}
+	if p.Jitter != 0.5 {
+		t.Errorf("WithJitter() = %v, want 0.5", p.Jitter)
+	}
+	if p.Classifier == nil {
+		t.Error("WithClassifier() should set classifier")
+	}
+
+	// Verify immutability
+	base := NoRetry()
+	_ = base.WithMaxAttempts(10)
+	if base.MaxAttempts != 1 {
+		t.Error("WithMaxAttempts() should not modify original policy")
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	t.Run("exponential growth", func(t *testing.T) {
+		p := RetryPolicy{InitialBackoff: 10 * time.Millisecond, Multiplier: 2}
+		want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond}
+		for i, w := range want {
+			if got := p.Backoff(i + 1); got != w {
+				t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+			}
+		}
+	})
+
+	t.Run("capped by max backoff", func(t *testing.T) {
+		p := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 15 * time.Millisecond, Multiplier: 2}
+		if got := p.Backoff(5); got != 15*time.Millisecond {
+			t.Errorf("Backoff(5) = %v, want %v", got, 15*time.Millisecond)
+		}
+	})
+
+	t.Run("multiplier below one is constant", func(t *testing.T) {
+		p := RetryPolicy{InitialBackoff: 10 * time.Millisecond}
+		if got := p.Backoff(4); got != 10*time.Millisecond {
+			t.Errorf("Backoff(4) = %v, want %v", got, 10*time.Millisecond)
+		}
+	})
+
+	t.Run("jitter stays within bounds", func(t *testing.T) {
+		p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 1, Jitter: 0.2}
+		for i := 0; i < 100; i++ {
+			got := p.Backoff(1)
+			if got < 80*time.Millisecond || got > 120*time.Millisecond {
+				t.Fatalf("Backoff(1) with jitter = %v, want within [80ms, 120ms]", got)
+			}
+		}
+	})
+
+	t.Run("zero backoff", func(t *testing.T) {
+		if got := NoRetry().Backoff(1); got != 0 {
+			t.Errorf("Backoff(1) without initial backoff = %v, want 0", got)
+		}
+		p := RetryPolicy{InitialBackoff: time.Millisecond}
+		if got := p.Backoff(0); got != 0 {
+			t.Errorf("Backoff(0) = %v, want 0", got)
+		}
+	})
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Classifier: alwaysRetry}
+
+	t.Run("succeeds first time", func(t *testing.T) {
+		calls := 0
+		err := Retry(ctx, policy, func(context.Context) error {
+			calls++
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Retry() error = %v, want nil", err)
+		}
+		if calls != 1 {
+			t.Errorf("Retry() calls = %d, want 1", calls)
+		}
+	})
+
+	t.Run("succeeds after transient failures", func(t *testing.T) {
+		calls := 0
+		err := Retry(ctx, policy, func(context.Context) error {
+			calls++
+			if calls < 3 {
+				return errTransient
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Retry() error = %v, want nil", err)
+		}
+		if calls != 3 {
+			t.Errorf("Retry() calls = %d, want 3", calls)
+		}
+	})
+
+	t.Run("returns last error after max attempts", func(t *testing.T) {
+		calls := 0
+		err := Retry(ctx, policy, func(context.Context) error {
+			calls++
+			return errTransient
+		})
+		if !errors.Is(err, errTransient) {
+			t.Errorf("Retry() error = %v, want %v", err, errTransient)
+		}
+		if calls != 3 {
+			t.Errorf("Retry() calls = %d, want 3", calls)
+		}
+	})
+
+	t.Run("stops on non-retryable error", func(t *testing.T) {
+		calls := 0
+		p := policy.WithClassifier(func(error) bool { return false })
+		err := Retry(ctx, p, func(context.Context) error {
+			calls++
+			return errTransient
+		})
+		if !errors.Is(err, errTransient) {
+			t.Errorf("Retry() error = %v, want %v", err, errTransient)
+		}
+		if calls != 1 {
+			t.Errorf("Retry() calls = %d, want 1", calls)
+		}
+	})
+
+	t.Run("zero policy runs once", func(t *testing.T) {
+		calls := 0
+		_ = Retry(ctx, RetryPolicy{}, func(context.Context) error {
+			calls++
+			return io.EOF
+		})
+		if calls != 1 {
+			t.Errorf("Retry() calls = %d, want 1", calls)
+		}
+	})
+
+	t.Run("context cancelled while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		p := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour, Classifier: alwaysRetry}
+		err := Retry(ctx, p, func(context.Context) error {
+			cancel()
+			return errTransient
+		})
+		if !errors.Is(err, errTransient) {
+			t.Errorf("Retry() error = %v, want to wrap %v", err, errTransient)
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Retry() error = %v, want to wrap %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("handles nil context", func(t *testing.T) {
+		//nolint:staticcheck // SA1012: intentionally passing nil to test nil handling
+		err := Retry(nil, policy, func(ctx context.Context) error {
+			if ctx == nil {
+				return errors.New("nil context passed to fn")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Retry() error = %v, want nil", err)
+		}
+	})
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"redis nil", redis.Nil, false},
+		{"context canceled", context.Canceled, false},
+		{"deadline exceeded", context.DeadlineExceeded, false},
+		{"EOF", io.EOF, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"closed connection", net.ErrClosed, true},
+		{"net error", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"loading", redisError("LOADING Redis is loading the dataset in memory"), true},
+		{"tryagain", redisError("TRYAGAIN Multiple keys request during rehashing of slot"), true},
+		{"readonly", redisError("READONLY You can't write against a read only replica."), true},
+		{"wrong type", redisError("WRONGTYPE Operation against a key holding the wrong kind of value"), false},
+		{"plain error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUnsentError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"pool timeout", redis.ErrPoolTimeout, true},
+		{"dial error", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"read error", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}, false},
+		{"EOF", io.EOF, false},
+		{"loading", redisError("LOADING Redis is loading the dataset in memory"), true},
+		{"wrapped tryagain", fmt.Errorf("eval: %w", redisError("TRYAGAIN Multiple keys request during rehashing of slot")), true},
+		{"wrong type", redisError("WRONGTYPE Operation against a key holding the wrong kind of value"), false},
+		{"deadline exceeded", context.DeadlineExceeded, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUnsentError(tt.err); got != tt.want {
+				t.Errorf("IsUnsentError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// redisError mimics a server error reply
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+func (redisError) RedisError() {}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultScanCount is the default COUNT hint passed to SCAN
+const DefaultScanCount int64 = 100
+
+// ScanKeys returns an iterator over keys matching pattern, driving the SCAN cursor until the keyspace is exhausted
+// SCAN only covers the node it runs on, so on a *redis.ClusterClient every master is scanned in turn,
+// and on a *redis.Ring every shard
+// The iteration stops after yielding an error, including ctx.Err() when the context is cancelled between pages
+// As with SCAN itself, a key may be yielded more than once if the keyspace is resized during iteration
+func ScanKeys(ctx context.Context, client redis.Cmdable, pattern string, count int64) iter.Seq2[string, error] {
+	if count <= 0 {
+		count = DefaultScanCount
+	}
+
+	return func(yield func(string, error) bool) {
+		if client == nil {
+			yield("", fmt.Errorf("redis client is nil"))
+			return
+		}
+
+		nodes, err := scanNodes(ctx, client)
+		if err != nil {
+			yield("", err)
+			return
+		}
+		for _, node := range nodes {
+			if !scanNode(ctx, node, pattern, count, yield) {
+				return
+			}
+		}
+	}
+}
+
+// scanNodes returns the clients a keyspace-wide command such as SCAN must run on to cover
+// client's keys: the masters of a *redis.ClusterClient, the shards of a *redis.Ring, also behind
+// an Unwrapper, or client itself otherwise
+func scanNodes(ctx context.Context, client redis.Cmdable) ([]redis.Cmdable, error) {
+	inner := client
+	for {
+		w, ok := inner.(Unwrapper)
+		if !ok {
+			break
+		}
+		inner = w.Unwrap()
+	}
+
+	var forEach func(ctx context.Context, fn func(ctx context.Context, node *redis.Client) error) error
+	switch c := inner.(type) {
+	case *redis.ClusterClient:
+		forEach = c.ForEachMaster
+	case *redis.Ring:
+		forEach = c.ForEachShard
+	default:
+		return []redis.Cmdable{client}, nil
+	}
+
+	// The callbacks run concurrently, one per node
+	var mu sync.Mutex
+	var nodes []redis.Cmdable
+	err := forEach(ctx, func(ctx context.Context, node *redis.Client) error {
+		mu.Lock()
+		defer mu.Unlock()
+		nodes = append(nodes, node)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	return nodes, nil
+}
+
+// scanNode drives the SCAN cursor of one node, passing keys to yield; it reports whether the
+// iteration should go on
+func scanNode(ctx context.Context, node redis.Cmdable, pattern string, count int64, yield func(string, error) bool) bool {
+	var cursor uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			yield("", err)
+			return false
+		}
+
+		keys, next, err := node.Scan(ctx, cursor, pattern, count).Result()
+		if err != nil {
+			yield("", fmt.Errorf("failed to scan keys: %w", err))
+			return false
+		}
+
+		for _, key := range keys {
+			if !yield(key, nil) {
+				return false
+			}
+		}
+
+		if next == 0 {
+			return true
+		}
+		cursor = next
+	}
+}
+
+// ScanAll collects every key matching pattern, removing duplicates returned by SCAN
+func ScanAll(ctx context.Context, client redis.Cmdable, pattern string, count int64) ([]string, error) {
+	seen := make(map[string]struct{})
+	var keys []string
+	for key, err := range ScanKeys(ctx, client, pattern, count) {
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// EscapeGlob quotes the SCAN MATCH metacharacters in s, so a prefix is matched literally
+func EscapeGlob(s string) string {
+	return globEscaper.Replace(s)
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestScanKeys(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("iterates across pages", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		for i := 0; i < 25; i++ {
+			_ = client.Set(ctx, fmt.Sprintf("scan:%02d", i), "v", 0).Err()
+		}
+		_ = client.Set(ctx, "other:1", "v", 0).Err()
+
+		var keys []string
+		for key, err := range ScanKeys(ctx, client, "scan:*", 4) {
+			if err != nil {
+				t.Fatalf("ScanKeys() error = %v", err)
+			}
+			keys = append(keys, key)
+		}
+		if len(keys) != 25 {
+			t.Errorf("ScanKeys() returned %d keys, want 25", len(keys))
+		}
+	})
+
+	t.Run("early break", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		for i := 0; i < 10; i++ {
+			_ = client.Set(ctx, fmt.Sprintf("k%d", i), "v", 0).Err()
+		}
+
+		n := 0
+		for range ScanKeys(ctx, client, "*", 2) {
+			n++
+			if n == 3 {
+				break
+			}
+		}
+		if n != 3 {
+			t.Errorf("ScanKeys() iterated %d times after break, want 3", n)
+		}
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		for _, err := range ScanKeys(ctx, client, "*", 10) {
+			if err != context.Canceled {
+				t.Errorf("ScanKeys() error = %v, want %v", err, context.Canceled)
+			}
+		}
+	})
+
+	t.Run("redis failure", func(t *testing.T) {
+		client, mock := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		mock.SetShouldFail(true)
+		var gotErr error
+		for _, err := range ScanKeys(ctx, client, "*", 0) {
+			gotErr = err
+		}
+		if gotErr == nil {
+			t.Error("ScanKeys() with Redis failure should yield error")
+		}
+	})
+
+	t.Run("covers every cluster master", func(t *testing.T) {
+		cluster, mocks := testutil.NewMockClusterClient(3)
+		defer func() { _ = cluster.Close() }()
+
+		for i := 0; i < 30; i++ {
+			_ = cluster.Set(ctx, fmt.Sprintf("scan:%02d", i), "v", 0).Err()
+		}
+		keys, err := ScanAll(ctx, cluster, "scan:*", 10)
+		if err != nil || len(keys) != 30 {
+			t.Errorf("ScanAll() on a cluster = %d keys, %v, want 30", len(keys), err)
+		}
+
+		mocks[1].SetShouldFail(true)
+		if _, err := ScanAll(ctx, cluster, "scan:*", 10); err == nil {
+			t.Error("ScanAll() with a failing master error = nil, want error")
+		}
+	})
+
+	t.Run("nil client", func(t *testing.T) {
+		for _, err := range ScanKeys(ctx, nil, "*", 10) {
+			if err == nil || err.Error() != "redis client is nil" {
+				t.Errorf("ScanKeys() error = %v, want %q", err, "redis client is nil")
+			}
+		}
+	})
+}
+
+func TestScanAll(t *testing.T) {
+	ctx := context.Background()
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	_ = client.Set(ctx, "user:1", "a", 0).Err()
+	_ = client.Set(ctx, "user:2", "b", 0).Err()
+	_ = client.Set(ctx, "session:1", "c", 0).Err()
+
+	keys, err := ScanAll(ctx, client, "user:*", 1)
+	if err != nil {
+		t.Fatalf("ScanAll() error = %v", err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "user:1" || keys[1] != "user:2" {
+		t.Errorf("ScanAll() = %v, want [user:1 user:2]", keys)
+	}
+
+	mock.SetShouldFail(true)
+	if _, err := ScanAll(ctx, client, "*", 10); err == nil {
+		t.Error("ScanAll() with Redis failure should return error")
+	}
+}
+
+func TestEscapeGlob(t *testing.T) {
+	if got := EscapeGlob(`a*b?[c]\`); got != `a\*b\?\[c\]\\` {
+		t.Errorf("EscapeGlob() = %q", got)
+	}
+}
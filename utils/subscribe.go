@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// subscribeRetryDelay is how long WatchChannel waits before receiving again after a failure
+const subscribeRetryDelay = time.Second
+
+// ChannelHandler receives the events of a channel watched with WatchChannel
+type ChannelHandler struct {
+	// OnSubscribe is called each time the subscription is (re)established, so state changed
+	// while disconnected can be reloaded
+	OnSubscribe func()
+
+	// OnMessage is called with the payload of each message
+	OnMessage func(payload string)
+
+	// OnError is called when receiving fails, before the subscription is retried
+	OnError func(err error)
+
+	// OnPoll, if set, is called every PollInterval and after every receive failure, so state
+	// is still re-read while the subscription is down
+	OnPoll func()
+
+	// PollInterval is how often OnPoll is called; OnPoll is only called after failures if zero
+	PollInterval time.Duration
+}
+
+type channelEvent struct {
+	msg interface{}
+	err error
+}
+
+// WatchChannel subscribes to channel and passes its events to h until ctx is cancelled
+// The handlers are called one at a time on the calling goroutine. Receive failures are
+// retried after a second, while go-redis re-establishes the subscription. WatchChannel
+// returns nil on cancellation
+func WatchChannel(ctx context.Context, client redis.UniversalClient, channel string, h ChannelHandler) error {
+	if NormalizeClient(client) == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	var receiver sync.WaitGroup
+	defer receiver.Wait()
+
+	sub := client.Subscribe(ctx, channel)
+	defer func() { _ = sub.Close() }()
+
+	// Receive does not return on cancellation by itself, so closing the subscription unblocks it
+	stop := context.AfterFunc(ctx, func() { _ = sub.Close() })
+	defer stop()
+
+	events := make(chan channelEvent)
+	receiver.Add(1)
+	go func() {
+		defer receiver.Done()
+		for {
+			msg, err := sub.Receive(ctx)
+			if err != nil && ctx.Err() != nil {
+				return
+			}
+			select {
+			case events <- channelEvent{msg: msg, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(subscribeRetryDelay):
+				}
+			}
+		}
+	}()
+
+	var poll <-chan time.Time
+	if h.OnPoll != nil && h.PollInterval > 0 {
+		ticker := time.NewTicker(h.PollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll:
+			h.OnPoll()
+		case ev := <-events:
+			switch msg := ev.msg.(type) {
+			case *redis.Subscription:
+				if h.OnSubscribe != nil {
+					h.OnSubscribe()
+				}
+			case *redis.Message:
+				if h.OnMessage != nil {
+					h.OnMessage(msg.Payload)
+				}
+			}
+			if ev.err != nil {
+				if h.OnError != nil {
+					h.OnError(ev.err)
+				}
+				if h.OnPoll != nil {
+					h.OnPoll()
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestWatchChannel(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	subscribed := make(chan struct{}, 1)
+	payloads := make(chan string, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchChannel(ctx, client, "events", ChannelHandler{
+			OnSubscribe: func() { subscribed <- struct{}{} },
+			OnMessage:   func(payload string) { payloads <- payload },
+		})
+	}()
+
+	select {
+	case <-subscribed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnSubscribe was not called")
+	}
+	_ = client.Publish(ctx, "events", "hello").Err()
+	select {
+	case got := <-payloads:
+		if got != "hello" {
+			t.Errorf("OnMessage() payload = %q, want hello", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnMessage was not called")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("WatchChannel() error = %v, want nil on cancellation", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("WatchChannel() did not return after cancellation")
+	}
+}
+
+func TestWatchChannel_Poll(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var polls atomic.Int64
+	err := WatchChannel(ctx, client, "events", ChannelHandler{
+		PollInterval: 5 * time.Millisecond,
+		OnPoll: func() {
+			if polls.Add(1) == 3 {
+				cancel()
+			}
+		},
+	})
+	if err != nil || polls.Load() < 3 {
+		t.Errorf("WatchChannel() = %v after %d polls, want nil after 3", err, polls.Load())
+	}
+}
+
+func TestWatchChannel_ReceiveFailure(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr: "unreachable:6379",
+		Dialer: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		},
+		MaxRetries: -1,
+	})
+	defer func() { _ = client.Close() }()
+
+	// Without a subscription, every failure is followed by a poll so state is still re-read
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var failures, polls int
+	err := WatchChannel(ctx, client, "events", ChannelHandler{
+		OnError: func(error) { failures++ },
+		OnPoll: func() {
+			polls++
+			cancel()
+		},
+	})
+	if err != nil || failures != 1 || polls != 1 {
+		t.Errorf("WatchChannel() = %v with %d failures and %d polls, want nil, 1 and 1", err, failures, polls)
+	}
+}
+
+func TestWatchChannel_NilClient(t *testing.T) {
+	var client *redis.Client
+	if err := WatchChannel(context.Background(), client, "events", ChannelHandler{}); err == nil {
+		t.Error("WatchChannel() with nil client error = nil, want error")
+	}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// minJitteredTTL is the lower bound of a jittered TTL; a zero TTL would mean "no expiry" to Redis
+const minJitteredTTL = time.Millisecond
+
+// JitterTTL randomizes ttl by up to ±fraction (0-1) so keys written together do not expire together
+// Non-positive TTLs (no expiry or keep TTL) are returned unchanged
+func JitterTTL(ttl time.Duration, fraction float64) time.Duration {
+	if ttl <= 0 || fraction <= 0 {
+		return ttl
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+
+	delta := float64(ttl) * fraction * (2*rand.Float64() - 1)
+	jittered := ttl + time.Duration(delta)
+	if jittered < minJitteredTTL {
+		return minJitteredTTL
+	}
+	return jittered
+}
+
+// FloorToWindow returns the start of the fixed window of length w containing t
+// Windows are aligned to the Unix epoch, so every process computes the same boundaries
+func FloorToWindow(t time.Time, w time.Duration) time.Time {
+	if w <= 0 {
+		return t
+	}
+	return t.Truncate(w)
+}
+
+// NextReset returns the end of the fixed window of length w containing t
+func NextReset(t time.Time, w time.Duration) time.Time {
+	if w <= 0 {
+		return t
+	}
+	return FloorToWindow(t, w).Add(w)
+}
+
+// DayStart returns midnight at the beginning of t's calendar day in loc (UTC if nil)
+func DayStart(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+}
+
+// NextDayStart returns midnight at the beginning of the calendar day after t in loc (UTC if nil)
+// Days are computed on the calendar, so DST transitions yield 23 or 25 hour days
+func NextDayStart(t time.Time, loc *time.Location) time.Time {
+	start := DayStart(t, loc)
+	return time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, start.Location())
+}
+
+// UntilNextDay returns the time remaining from t until the next calendar day in loc
+func UntilNextDay(t time.Time, loc *time.Location) time.Duration {
+	return NextDayStart(t, loc).Sub(t)
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJitterTTL(t *testing.T) {
+	t.Run("stays within fraction", func(t *testing.T) {
+		ttl := time.Minute
+		for i := 0; i < 200; i++ {
+			got := JitterTTL(ttl, 0.1)
+			if got < 54*time.Second || got > 66*time.Second {
+				t.Fatalf("JitterTTL(1m, 0.1) = %v, want within [54s, 66s]", got)
+			}
+		}
+	})
+
+	t.Run("produces different values", func(t *testing.T) {
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 20; i++ {
+			seen[JitterTTL(time.Hour, 0.5)] = true
+		}
+		if len(seen) < 2 {
+			t.Error("JitterTTL() should randomize the TTL")
+		}
+	})
+
+	t.Run("unchanged without jitter", func(t *testing.T) {
+		if got := JitterTTL(time.Minute, 0); got != time.Minute {
+			t.Errorf("JitterTTL(1m, 0) = %v, want 1m", got)
+		}
+		if got := JitterTTL(0, 0.5); got != 0 {
+			t.Errorf("JitterTTL(0, 0.5) = %v, want 0", got)
+		}
+		if got := JitterTTL(-1, 0.5); got != -1 {
+			t.Errorf("JitterTTL(-1, 0.5) = %v, want -1", got)
+		}
+	})
+
+	t.Run("never reaches zero", func(t *testing.T) {
+		for i := 0; i < 200; i++ {
+			if got := JitterTTL(time.Millisecond, 5); got < time.Millisecond {
+				t.Fatalf("JitterTTL(1ms, 5) = %v, want >= 1ms", got)
+			}
+		}
+	})
+}
+
+func TestFloorToWindow(t *testing.T) {
+	ts := time.Date(2024, 3, 15, 10, 37, 42, 0, time.UTC)
+
+	tests := []struct {
+		window time.Duration
+		want   time.Time
+	}{
+		{time.Minute, time.Date(2024, 3, 15, 10, 37, 0, 0, time.UTC)},
+		{15 * time.Minute, time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)},
+		{time.Hour, time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)},
+		{24 * time.Hour, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{0, ts},
+	}
+	for _, tt := range tests {
+		if got := FloorToWindow(ts, tt.window); !got.Equal(tt.want) {
+			t.Errorf("FloorToWindow(%v, %v) = %v, want %v", ts, tt.window, got, tt.want)
+		}
+	}
+}
+
+func TestNextReset(t *testing.T) {
+	ts := time.Date(2024, 3, 15, 10, 37, 42, 0, time.UTC)
+	if got, want := NextReset(ts, time.Hour), time.Date(2024, 3, 15, 11, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("NextReset(%v, 1h) = %v, want %v", ts, got, want)
+	}
+	if got := NextReset(ts, 0); !got.Equal(ts) {
+		t.Errorf("NextReset(%v, 0) = %v, want %v", ts, got, ts)
+	}
+
+	// A timestamp on a boundary starts a new window
+	boundary := time.Date(2024, 3, 15, 11, 0, 0, 0, time.UTC)
+	if got, want := NextReset(boundary, time.Hour), time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("NextReset(%v, 1h) = %v, want %v", boundary, got, want)
+	}
+}
+
+func TestDayBoundaries(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+
+	t.Run("day start in zone", func(t *testing.T) {
+		// 2024-03-15 20:00 UTC is already 2024-03-16 04:00 in UTC+8
+		ts := time.Date(2024, 3, 15, 20, 0, 0, 0, time.UTC)
+		got := DayStart(ts, shanghai)
+		want := time.Date(2024, 3, 16, 0, 0, 0, 0, shanghai)
+		if !got.Equal(want) {
+			t.Errorf("DayStart() = %v, want %v", got, want)
+		}
+		if got := NextDayStart(ts, shanghai); !got.Equal(want.AddDate(0, 0, 1)) {
+			t.Errorf("NextDayStart() = %v, want %v", got, want.AddDate(0, 0, 1))
+		}
+		if got := UntilNextDay(ts, shanghai); got != 20*time.Hour {
+			t.Errorf("UntilNextDay() = %v, want 20h", got)
+		}
+	})
+
+	t.Run("nil location uses UTC", func(t *testing.T) {
+		ts := time.Date(2024, 3, 15, 20, 0, 0, 0, time.UTC)
+		if got := DayStart(ts, nil); !got.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("DayStart(nil) = %v", got)
+		}
+	})
+
+	t.Run("DST transition", func(t *testing.T) {
+		ny, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			t.Skip("timezone database not available")
+		}
+		// 2024-03-10 is a 23 hour day in New York
+		ts := time.Date(2024, 3, 10, 0, 0, 0, 0, ny)
+		if got := UntilNextDay(ts, ny); got != 23*time.Hour {
+			t.Errorf("UntilNextDay() across DST = %v, want 23h", got)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// TTLNoExpiry is reported for keys that exist without an expiration
+	TTLNoExpiry time.Duration = -1
+	// TTLKeyNotFound is reported for keys that do not exist
+	TTLKeyNotFound time.Duration = -2
+)
+
+// TTLs fetches the remaining time-to-live of many keys using chunked PTTL pipelines
+// Keys without expiration map to TTLNoExpiry and missing keys map to TTLKeyNotFound
+func TTLs(ctx context.Context, client redis.Cmdable, keys []string) (map[string]time.Duration, error) {
+	cmds := make(map[string]*redis.DurationCmd, len(keys))
+	err := PipelineEach(ctx, client, keys, DefaultChunkSize, func(pipe redis.Pipeliner, key string) {
+		cmds[key] = pipe.PTTL(ctx, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]time.Duration, len(cmds))
+	for key, cmd := range cmds {
+		result[key] = cmd.Val()
+	}
+	return result, nil
+}
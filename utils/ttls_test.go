@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestTTLs(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("mixed keys", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		_ = client.Set(ctx, "short", "v", time.Minute).Err()
+		_ = client.Set(ctx, "forever", "v", 0).Err()
+
+		ttls, err := TTLs(ctx, client, []string{"short", "forever", "missing"})
+		if err != nil {
+			t.Fatalf("TTLs() error = %v", err)
+		}
+		if len(ttls) != 3 {
+			t.Fatalf("TTLs() returned %d entries, want 3", len(ttls))
+		}
+		if ttl := ttls["short"]; ttl <= 59*time.Second || ttl > time.Minute {
+			t.Errorf("TTLs()[short] = %v, want ~1m", ttl)
+		}
+		if ttls["forever"] != TTLNoExpiry {
+			t.Errorf("TTLs()[forever] = %v, want %v", ttls["forever"], TTLNoExpiry)
+		}
+		if ttls["missing"] != TTLKeyNotFound {
+			t.Errorf("TTLs()[missing] = %v, want %v", ttls["missing"], TTLKeyNotFound)
+		}
+	})
+
+	t.Run("more keys than one chunk", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		keys := make([]string, DefaultChunkSize+10)
+		for i := range keys {
+			keys[i] = fmt.Sprintf("k%d", i)
+		}
+		ttls, err := TTLs(ctx, client, keys)
+		if err != nil {
+			t.Fatalf("TTLs() error = %v", err)
+		}
+		if len(ttls) != len(keys) {
+			t.Errorf("TTLs() returned %d entries, want %d", len(ttls), len(keys))
+		}
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		client, _ := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		ttls, err := TTLs(ctx, client, nil)
+		if err != nil || len(ttls) != 0 {
+			t.Errorf("TTLs(nil) = (%v, %v), want empty map", ttls, err)
+		}
+	})
+
+	t.Run("redis failure", func(t *testing.T) {
+		client, mock := testutil.NewMockRedisClient()
+		defer func() { _ = client.Close() }()
+
+		mock.SetShouldFail(true)
+		if _, err := TTLs(ctx, client, []string{"k"}); err == nil {
+			t.Error("TTLs() with Redis failure should return error")
+		}
+	})
+}
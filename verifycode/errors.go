@@ -0,0 +1,41 @@
+package verifycode
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrCodeNotFound indicates no code is pending for the key, or it expired or was used.
+	ErrCodeNotFound = errors.New("verification code not found or expired")
+
+	// ErrInvalidCode indicates the submitted code does not match.
+	ErrInvalidCode = errors.New("invalid verification code")
+
+	// ErrTooManyAttempts indicates the code was invalidated after too many wrong attempts.
+	ErrTooManyAttempts = errors.New("too many verification attempts")
+
+	// ErrLocked indicates the key is locked out after too many wrong attempts.
+	ErrLocked = errors.New("verification locked")
+
+	// ErrCooldown indicates a code was sent too recently to send another.
+	ErrCooldown = errors.New("verification code sent too recently")
+
+	// ErrRateLimited indicates the destination reached its send limit.
+	ErrRateLimited = errors.New("verification send limit reached")
+)
+
+// RetryError reports when a refused operation may be attempted again
+// It wraps ErrLocked, ErrCooldown, or ErrRateLimited
+type RetryError struct {
+	Err     error
+	RetryAt time.Time
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error() + ", retry at " + e.RetryAt.Format(time.RFC3339)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
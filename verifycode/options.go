@@ -0,0 +1,89 @@
+package verifycode
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/ratelimit"
+)
+
+// Option configures a Manager
+type Option func(*Manager)
+
+// WithKeyPrefix sets the prefix of code and lockout keys (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(m *Manager) {
+		m.keyPrefix = prefix
+	}
+}
+
+// WithTTL sets how long a code stays valid (default: DefaultTTL)
+func WithTTL(ttl time.Duration) Option {
+	return func(m *Manager) {
+		if ttl > 0 {
+			m.ttl = ttl
+		}
+	}
+}
+
+// WithLength sets the number of characters in a code (default: DefaultLength)
+func WithLength(n int) Option {
+	return func(m *Manager) {
+		if n > 0 {
+			m.length = n
+		}
+	}
+}
+
+// WithAlphabet sets the characters codes are drawn from (default: DefaultAlphabet)
+func WithAlphabet(alphabet string) Option {
+	return func(m *Manager) {
+		if len(alphabet) > 1 {
+			m.alphabet = alphabet
+		}
+	}
+}
+
+// WithMaxAttempts sets how many wrong codes are accepted before the code is invalidated (default: DefaultMaxAttempts)
+func WithMaxAttempts(n int) Option {
+	return func(m *Manager) {
+		if n > 0 {
+			m.maxAttempts = n
+		}
+	}
+}
+
+// WithLockout sets how long a key is locked after too many wrong codes; 0 disables the lockout (default: DefaultLockout)
+func WithLockout(d time.Duration) Option {
+	return func(m *Manager) {
+		if d >= 0 {
+			m.lockout = d
+		}
+	}
+}
+
+// WithSecret sets a server-side secret mixed into code digests, so a leaked digest cannot be brute-forced offline
+func WithSecret(secret []byte) Option {
+	return func(m *Manager) {
+		m.secret = secret
+	}
+}
+
+// SendPolicy limits how often codes are sent to a destination
+type SendPolicy struct {
+	// Cooldown is the minimum time between two codes for the same key (0 disables it)
+	Cooldown time.Duration
+
+	// Limit is the number of codes a key may receive per Window (0 disables it)
+	Limit int
+
+	// Window is the period Limit applies to
+	Window time.Duration
+}
+
+// WithRateLimiter checks the limiter's cooldown and destination limits before issuing codes
+func WithRateLimiter(limiter *ratelimit.RateLimiter, policy SendPolicy) Option {
+	return func(m *Manager) {
+		m.limiter = limiter
+		m.sendPolicy = policy
+	}
+}
@@ -0,0 +1,272 @@
+// Package verifycode provides one-time verification codes for SMS and email flows
+// Codes are stored as salted HMAC digests, compared in constant time, and invalidated
+// after a number of wrong attempts
+package verifycode
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/ratelimit"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for code keys
+	DefaultKeyPrefix = "verifycode:"
+
+	// DefaultTTL is how long codes stay valid by default
+	DefaultTTL = 5 * time.Minute
+
+	// DefaultLength is the default number of characters in a code
+	DefaultLength = 6
+
+	// DefaultAlphabet is the default set of code characters
+	DefaultAlphabet = "0123456789"
+
+	// DefaultMaxAttempts is the default number of wrong codes before a code is invalidated
+	DefaultMaxAttempts = 5
+
+	// DefaultLockout is how long a key is locked after too many wrong codes by default
+	DefaultLockout = 15 * time.Minute
+)
+
+const attemptScript = `
+-- redis-kit:verifycode-attempt
+if redis.call("exists", KEYS[1]) == 0 then
+	return false
+end
+local attempts = redis.call("hincrby", KEYS[1], "a", 1)
+return {redis.call("hget", KEYS[1], "h"), redis.call("hget", KEYS[1], "s"), attempts}
+`
+
+// Manager issues and verifies one-time codes
+type Manager struct {
+	client      *redis.Client
+	keyPrefix   string
+	ttl         time.Duration
+	length      int
+	alphabet    string
+	maxAttempts int
+	lockout     time.Duration
+	secret      []byte
+	limiter     *ratelimit.RateLimiter
+	sendPolicy  SendPolicy
+}
+
+// NewManager creates a verification code manager
+func NewManager(client *redis.Client, opts ...Option) *Manager {
+	m := &Manager{
+		client:      client,
+		keyPrefix:   DefaultKeyPrefix,
+		ttl:         DefaultTTL,
+		length:      DefaultLength,
+		alphabet:    DefaultAlphabet,
+		maxAttempts: DefaultMaxAttempts,
+		lockout:     DefaultLockout,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// TTL returns how long codes stay valid
+func (m *Manager) TTL() time.Duration {
+	return m.ttl
+}
+
+func (m *Manager) codeKey(key string) string {
+	return m.keyPrefix + key
+}
+
+func (m *Manager) lockKey(key string) string {
+	return m.keyPrefix + "lock:" + key
+}
+
+// generateCode draws a uniformly random code from the alphabet
+func (m *Manager) generateCode() (string, error) {
+	code := make([]byte, m.length)
+	n := big.NewInt(int64(len(m.alphabet)))
+	for i := range code {
+		idx, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random code: %w", err)
+		}
+		code[i] = m.alphabet[idx.Int64()]
+	}
+	return string(code), nil
+}
+
+// digest returns the salted HMAC of code
+func (m *Manager) digest(key, salt, code string) []byte {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(salt))
+	mac.Write([]byte{0})
+	mac.Write([]byte(key))
+	mac.Write([]byte{0})
+	mac.Write([]byte(code))
+	return mac.Sum(nil)
+}
+
+// Issue generates a code for key (e.g. "login:+15551234567"), replacing any pending one
+// The caller delivers the returned code; only its digest is stored. With a rate limiter
+// configured, a RetryError wrapping ErrCooldown or ErrRateLimited is returned when the
+// destination was sent a code too recently or too often, and ErrLocked while locked out
+func (m *Manager) Issue(ctx context.Context, key string) (string, error) {
+	if m.client == nil {
+		return "", fmt.Errorf("redis client is nil")
+	}
+	if err := m.checkLock(ctx, key); err != nil {
+		return "", err
+	}
+	if err := m.checkSendPolicy(ctx, key); err != nil {
+		return "", err
+	}
+
+	code, err := m.generateCode()
+	if err != nil {
+		return "", err
+	}
+	saltBytes := make([]byte, 16)
+	if _, err := rand.Read(saltBytes); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	salt := hex.EncodeToString(saltBytes)
+
+	codeKey := m.codeKey(key)
+	pipe := m.client.Pipeline()
+	pipe.Del(ctx, codeKey)
+	pipe.HSet(ctx, codeKey, "h", hex.EncodeToString(m.digest(key, salt, code)), "s", salt, "a", 0)
+	pipe.PExpire(ctx, codeKey, m.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to store verification code: %w", err)
+	}
+	return code, nil
+}
+
+func (m *Manager) checkLock(ctx context.Context, key string) error {
+	if m.lockout <= 0 {
+		return nil
+	}
+	ttl, err := m.client.PTTL(ctx, m.lockKey(key)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check lockout: %w", err)
+	}
+	if ttl > 0 {
+		return &RetryError{Err: ErrLocked, RetryAt: time.Now().Add(ttl)}
+	}
+	return nil
+}
+
+func (m *Manager) checkSendPolicy(ctx context.Context, key string) error {
+	if m.limiter == nil {
+		return nil
+	}
+	if m.sendPolicy.Cooldown > 0 {
+		allowed, resetAt, err := m.limiter.CheckCooldown(ctx, key, m.sendPolicy.Cooldown)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return &RetryError{Err: ErrCooldown, RetryAt: resetAt}
+		}
+	}
+	if m.sendPolicy.Limit > 0 {
+		allowed, _, resetAt, err := m.limiter.CheckDestinationLimit(ctx, key, m.sendPolicy.Limit, m.sendPolicy.Window)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return &RetryError{Err: ErrRateLimited, RetryAt: resetAt}
+		}
+	}
+	return nil
+}
+
+// Verify checks code against the pending code for key and consumes it on success
+// Returns ErrInvalidCode for a wrong code, ErrTooManyAttempts when the wrong code used up
+// the last attempt, ErrCodeNotFound when no code is pending, and a RetryError wrapping
+// ErrLocked while the key is locked out. A code can be verified successfully only once
+func (m *Manager) Verify(ctx context.Context, key, code string) error {
+	if m.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if err := m.checkLock(ctx, key); err != nil {
+		return err
+	}
+
+	codeKey := m.codeKey(key)
+	res, err := m.client.Eval(ctx, attemptScript, []string{codeKey}).Slice()
+	if err == redis.Nil {
+		return ErrCodeNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check verification code: %w", err)
+	}
+	if len(res) != 3 {
+		return fmt.Errorf("unexpected verification attempt response")
+	}
+	stored, _ := res[0].(string)
+	salt, _ := res[1].(string)
+	attempts, _ := res[2].(int64)
+
+	want, err := hex.DecodeString(stored)
+	if err != nil {
+		return fmt.Errorf("failed to decode stored digest: %w", err)
+	}
+	if hmac.Equal(m.digest(key, salt, code), want) {
+		// Only the caller that deletes the code wins when the same code is submitted concurrently
+		n, err := m.client.Del(ctx, codeKey).Result()
+		if err != nil {
+			return fmt.Errorf("failed to consume verification code: %w", err)
+		}
+		if n == 0 {
+			return ErrCodeNotFound
+		}
+		return nil
+	}
+
+	if attempts < int64(m.maxAttempts) {
+		return fmt.Errorf("%w (%d attempts left)", ErrInvalidCode, int64(m.maxAttempts)-attempts)
+	}
+	pipe := m.client.Pipeline()
+	pipe.Del(ctx, codeKey)
+	if m.lockout > 0 {
+		pipe.Set(ctx, m.lockKey(key), "1", m.lockout)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to invalidate verification code: %w", err)
+	}
+	return ErrTooManyAttempts
+}
+
+// Revoke deletes the pending code for key
+func (m *Manager) Revoke(ctx context.Context, key string) error {
+	if m.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := m.client.Del(ctx, m.codeKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to revoke verification code: %w", err)
+	}
+	return nil
+}
+
+// Unlock lifts a lockout for key, e.g. after support verified the user another way
+func (m *Manager) Unlock(ctx context.Context, key string) error {
+	if m.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := m.client.Del(ctx, m.lockKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to unlock verification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package verifycode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/ratelimit"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestManager_IssueVerify(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	m := NewManager(client, WithSecret([]byte("pepper")))
+
+	code, err := m.Issue(ctx, "login:alice@example.com")
+	if err != nil {
+		t.Fatalf("Issue() error = %v, want nil", err)
+	}
+	if len(code) != DefaultLength || strings.Trim(code, DefaultAlphabet) != "" {
+		t.Errorf("Issue() = %q, want %d digits", code, DefaultLength)
+	}
+
+	stored, _ := client.HGetAll(ctx, "verifycode:login:alice@example.com").Result()
+	if stored["h"] == "" || stored["s"] == "" || strings.Contains(stored["h"], code) {
+		t.Errorf("stored code = %v, want a salted digest", stored)
+	}
+	if ttl, _ := client.PTTL(ctx, "verifycode:login:alice@example.com").Result(); ttl <= 0 || ttl > DefaultTTL {
+		t.Errorf("PTTL() = %v, want up to %v", ttl, DefaultTTL)
+	}
+
+	if err := m.Verify(ctx, "login:bob@example.com", code); !errors.Is(err, ErrCodeNotFound) {
+		t.Errorf("Verify() another key error = %v, want ErrCodeNotFound", err)
+	}
+	if err := m.Verify(ctx, "login:alice@example.com", code); err != nil {
+		t.Fatalf("Verify() error = %v, want nil", err)
+	}
+	if err := m.Verify(ctx, "login:alice@example.com", code); !errors.Is(err, ErrCodeNotFound) {
+		t.Errorf("Verify() reused code error = %v, want ErrCodeNotFound", err)
+	}
+}
+
+func TestManager_ReissueReplacesCode(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	m := NewManager(client, WithLength(8), WithAlphabet("ABCDEFGHJKLMNPQRSTUVWXYZ23456789"))
+
+	first, _ := m.Issue(ctx, "k")
+	second, err := m.Issue(ctx, "k")
+	if err != nil || len(second) != 8 {
+		t.Fatalf("Issue() = %q, %v, want an 8 character code", second, err)
+	}
+	if first != second {
+		if err := m.Verify(ctx, "k", first); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("Verify() replaced code error = %v, want ErrInvalidCode", err)
+		}
+	}
+	if err := m.Verify(ctx, "k", second); err != nil {
+		t.Errorf("Verify() error = %v, want nil", err)
+	}
+
+	code, _ := m.Issue(ctx, "k")
+	if err := m.Revoke(ctx, "k"); err != nil {
+		t.Fatalf("Revoke() error = %v, want nil", err)
+	}
+	if err := m.Verify(ctx, "k", code); !errors.Is(err, ErrCodeNotFound) {
+		t.Errorf("Verify() revoked code error = %v, want ErrCodeNotFound", err)
+	}
+}
+
+func TestManager_MaxAttempts(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	m := NewManager(client, WithMaxAttempts(3), WithLockout(time.Minute))
+
+	code, _ := m.Issue(ctx, "k")
+	wrong := "x" + code[1:]
+	for i := 0; i < 2; i++ {
+		if err := m.Verify(ctx, "k", wrong); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("Verify() attempt %d error = %v, want ErrInvalidCode", i+1, err)
+		}
+	}
+	if err := m.Verify(ctx, "k", wrong); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Verify() last attempt error = %v, want ErrTooManyAttempts", err)
+	}
+
+	err := m.Verify(ctx, "k", code)
+	var retry *RetryError
+	if !errors.Is(err, ErrLocked) || !errors.As(err, &retry) || time.Until(retry.RetryAt) <= 0 {
+		t.Errorf("Verify() while locked error = %v, want ErrLocked with RetryAt", err)
+	}
+	if _, err := m.Issue(ctx, "k"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Issue() while locked error = %v, want ErrLocked", err)
+	}
+
+	if err := m.Unlock(ctx, "k"); err != nil {
+		t.Fatalf("Unlock() error = %v, want nil", err)
+	}
+	if err := m.Verify(ctx, "k", code); !errors.Is(err, ErrCodeNotFound) {
+		t.Errorf("Verify() invalidated code error = %v, want ErrCodeNotFound", err)
+	}
+	code, _ = m.Issue(ctx, "k")
+	if err := m.Verify(ctx, "k", code); err != nil {
+		t.Errorf("Verify() after unlock error = %v, want nil", err)
+	}
+}
+
+func TestManager_NoLockout(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	m := NewManager(client, WithMaxAttempts(1), WithLockout(0))
+
+	code, _ := m.Issue(ctx, "k")
+	if err := m.Verify(ctx, "k", "wrong"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Verify() error = %v, want ErrTooManyAttempts", err)
+	}
+	if err := m.Verify(ctx, "k", code); !errors.Is(err, ErrCodeNotFound) {
+		t.Errorf("Verify() invalidated code error = %v, want ErrCodeNotFound", err)
+	}
+	if _, err := m.Issue(ctx, "k"); err != nil {
+		t.Errorf("Issue() without lockout error = %v, want nil", err)
+	}
+}
+
+func TestManager_RateLimiter(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	limiter := ratelimit.NewRateLimiter(client)
+	m := NewManager(client, WithRateLimiter(limiter, SendPolicy{Cooldown: time.Minute}))
+
+	if _, err := m.Issue(ctx, "k"); err != nil {
+		t.Fatalf("Issue() error = %v, want nil", err)
+	}
+	_, err := m.Issue(ctx, "k")
+	var retry *RetryError
+	if !errors.Is(err, ErrCooldown) || !errors.As(err, &retry) || retry.RetryAt.IsZero() {
+		t.Errorf("Issue() during cooldown error = %v, want ErrCooldown with RetryAt", err)
+	}
+
+	m = NewManager(client, WithRateLimiter(limiter, SendPolicy{Limit: 2, Window: time.Hour}))
+	for i := 0; i < 2; i++ {
+		if _, err := m.Issue(ctx, "other"); err != nil {
+			t.Fatalf("Issue() %d error = %v, want nil", i+1, err)
+		}
+	}
+	if _, err := m.Issue(ctx, "other"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Issue() over limit error = %v, want ErrRateLimited", err)
+	}
+}
+
+func TestManager_Errors(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(nil)
+	if _, err := m.Issue(ctx, "k"); err == nil {
+		t.Error("Issue() with nil client error = nil, want error")
+	}
+	if err := m.Verify(ctx, "k", "123456"); err == nil {
+		t.Error("Verify() with nil client error = nil, want error")
+	}
+	if err := m.Revoke(ctx, "k"); err == nil {
+		t.Error("Revoke() with nil client error = nil, want error")
+	}
+	if err := m.Unlock(ctx, "k"); err == nil {
+		t.Error("Unlock() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	m = NewManager(client)
+	mock.SetShouldFail(true)
+	if _, err := m.Issue(ctx, "k"); err == nil {
+		t.Error("Issue() with failing redis error = nil, want error")
+	}
+	if err := m.Verify(ctx, "k", "123456"); err == nil {
+		t.Error("Verify() with failing redis error = nil, want error")
+	}
+}
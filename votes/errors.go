@@ -0,0 +1,8 @@
+package votes
+
+import "errors"
+
+var (
+	// ErrUnvoteUnsupported indicates Unvote was called on a store tracking voters in Bloom filters.
+	ErrUnvoteUnsupported = errors.New("unvote is not supported with bloom filters")
+)
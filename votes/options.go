@@ -0,0 +1,22 @@
+package votes
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithKeyPrefix sets the prefix of count and voter keys (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(s *Store) {
+		s.keyPrefix = prefix
+	}
+}
+
+// WithBloomFilter tracks the voters of each item in a Bloom filter sized for capacity voters
+// at errorRate instead of a set, for items with too many voters to keep exactly
+// A voter may then be wrongly taken for having voted already, and Unvote is not supported
+func WithBloomFilter(capacity uint64, errorRate float64) Option {
+	return func(s *Store) {
+		s.bloom = true
+		s.bloomCapacity = capacity
+		s.bloomErrorRate = errorRate
+	}
+}
@@ -0,0 +1,235 @@
+// Package votes counts votes or likes per item, at most one per user
+// Each item has a counter and a set of its voters, or a Bloom filter for items with huge
+// numbers of voters, so voting twice is idempotent and counts can be read in one round trip
+package votes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/bloomfilter"
+)
+
+// DefaultKeyPrefix is the default prefix for count and voter keys
+const DefaultKeyPrefix = "votes:"
+
+const (
+	countSuffix  = ":count"
+	votersSuffix = ":voters"
+)
+
+const voteScript = `
+-- redis-kit:votes-vote
+if redis.call("sadd", KEYS[1], ARGV[1]) == 1 then
+	return {1, redis.call("incr", KEYS[2])}
+end
+return {0, tonumber(redis.call("get", KEYS[2]) or "0")}
+`
+
+const unvoteScript = `
+-- redis-kit:votes-unvote
+if redis.call("srem", KEYS[1], ARGV[1]) == 1 then
+	local count = redis.call("decr", KEYS[2])
+	if count <= 0 then
+		redis.call("del", KEYS[2])
+		count = 0
+	end
+	return {1, count}
+end
+return {0, tonumber(redis.call("get", KEYS[2]) or "0")}
+`
+
+// Store records votes on items
+type Store struct {
+	client    *redis.Client
+	keyPrefix string
+
+	bloom          bool
+	bloomCapacity  uint64
+	bloomErrorRate float64
+	bloomMode      atomic.Int32 // implementation resolved by the first filter, shared by the next ones
+}
+
+// NewStore creates a vote store
+func NewStore(client *redis.Client, opts ...Option) *Store {
+	s := &Store{
+		client:    client,
+		keyPrefix: DefaultKeyPrefix,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CountKey returns the Redis key holding the vote count of item
+func (s *Store) CountKey(item string) string {
+	return s.keyPrefix + item + countSuffix
+}
+
+// VotersKey returns the Redis key holding the voters of item
+func (s *Store) VotersKey(item string) string {
+	return s.keyPrefix + item + votersSuffix
+}
+
+// Vote records user's vote on item
+// Returns whether the vote was counted, false if user had voted already, and the item's count
+func (s *Store) Vote(ctx context.Context, item, user string) (bool, int64, error) {
+	if s.client == nil {
+		return false, 0, fmt.Errorf("redis client is nil")
+	}
+
+	if s.bloom {
+		return s.bloomVote(ctx, item, user)
+	}
+	counted, count, err := s.eval(ctx, voteScript, item, user)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to vote: %w", err)
+	}
+	return counted, count, nil
+}
+
+// Unvote withdraws user's vote on item
+// Returns whether a vote was withdrawn and the item's count; with Bloom filters it returns
+// ErrUnvoteUnsupported, as a filter cannot forget a voter
+func (s *Store) Unvote(ctx context.Context, item, user string) (bool, int64, error) {
+	if s.client == nil {
+		return false, 0, fmt.Errorf("redis client is nil")
+	}
+	if s.bloom {
+		return false, 0, ErrUnvoteUnsupported
+	}
+
+	withdrawn, count, err := s.eval(ctx, unvoteScript, item, user)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to unvote: %w", err)
+	}
+	return withdrawn, count, nil
+}
+
+// HasVoted reports whether user has voted on item; with Bloom filters it may report users
+// who have not, at roughly the configured error rate
+func (s *Store) HasVoted(ctx context.Context, item, user string) (bool, error) {
+	if s.client == nil {
+		return false, fmt.Errorf("redis client is nil")
+	}
+
+	var voted bool
+	var err error
+	if s.bloom {
+		f := s.filter(item)
+		voted, err = f.Exists(ctx, user)
+		s.resolveMode(f)
+	} else {
+		voted, err = s.client.SIsMember(ctx, s.VotersKey(item), user).Result()
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check vote: %w", err)
+	}
+	return voted, nil
+}
+
+// Count returns the number of votes on item
+func (s *Store) Count(ctx context.Context, item string) (int64, error) {
+	counts, err := s.Counts(ctx, item)
+	if err != nil {
+		return 0, err
+	}
+	return counts[0], nil
+}
+
+// Counts returns the number of votes on each item in one round trip
+func (s *Store) Counts(ctx context.Context, items ...string) ([]int64, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if len(items) == 0 {
+		return []int64{}, nil
+	}
+
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = s.CountKey(item)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vote counts: %w", err)
+	}
+
+	counts := make([]int64, len(items))
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+		counts[i], err = strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed vote count of %s: %w", items[i], err)
+		}
+	}
+	return counts, nil
+}
+
+// Clear deletes the votes on item
+func (s *Store) Clear(ctx context.Context, item string) error {
+	if s.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := s.client.Del(ctx, s.CountKey(item), s.VotersKey(item)).Err(); err != nil {
+		return fmt.Errorf("failed to clear votes: %w", err)
+	}
+	return nil
+}
+
+// eval runs a vote script and returns its changed flag and count
+func (s *Store) eval(ctx context.Context, script, item, user string) (bool, int64, error) {
+	res, err := s.client.Eval(ctx, script, []string{s.VotersKey(item), s.CountKey(item)}, user).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, errors.New("unexpected vote script reply")
+	}
+	return res[0] == 1, res[1], nil
+}
+
+// bloomVote adds user to item's filter and counts the vote if the filter did not know user
+func (s *Store) bloomVote(ctx context.Context, item, user string) (bool, int64, error) {
+	f := s.filter(item)
+	added, err := f.Add(ctx, user)
+	s.resolveMode(f)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to vote: %w", err)
+	}
+
+	var count int64
+	if added {
+		count, err = s.client.Incr(ctx, s.CountKey(item)).Result()
+	} else {
+		count, err = s.client.Get(ctx, s.CountKey(item)).Int64()
+		if errors.Is(err, redis.Nil) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to count vote: %w", err)
+	}
+	return added, count, nil
+}
+
+// filter returns the Bloom filter of item's voters
+func (s *Store) filter(item string) *bloomfilter.Filter {
+	return bloomfilter.NewFilter(s.client, item+votersSuffix, s.bloomCapacity, s.bloomErrorRate,
+		bloomfilter.WithKeyPrefix(s.keyPrefix),
+		bloomfilter.WithMode(bloomfilter.Mode(s.bloomMode.Load())))
+}
+
+// resolveMode remembers the implementation f settled on, so later filters skip the probe
+func (s *Store) resolveMode(f *bloomfilter.Filter) {
+	s.bloomMode.CompareAndSwap(int32(bloomfilter.ModeAuto), int32(f.Mode()))
+}
@@ -0,0 +1,192 @@
+package votes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/soulteary/redis-kit/bloomfilter"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestNewStore(t *testing.T) {
+	s := NewStore(nil)
+	if s.CountKey("post:1") != "votes:post:1:count" || s.VotersKey("post:1") != "votes:post:1:voters" || s.bloom {
+		t.Errorf("NewStore() keys = %q/%q, want defaults", s.CountKey("post:1"), s.VotersKey("post:1"))
+	}
+
+	s = NewStore(nil, WithKeyPrefix("likes:"), WithBloomFilter(1000, 0.01))
+	if s.CountKey("p") != "likes:p:count" || !s.bloom || s.bloomCapacity != 1000 || s.bloomErrorRate != 0.01 {
+		t.Errorf("NewStore() with options = %+v", s)
+	}
+}
+
+func TestStore_Vote(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client)
+
+	if counted, count, err := s.Vote(ctx, "post", "alice"); err != nil || !counted || count != 1 {
+		t.Fatalf("Vote() = %v, %d, %v, want true, 1", counted, count, err)
+	}
+	if counted, count, _ := s.Vote(ctx, "post", "alice"); counted || count != 1 {
+		t.Errorf("Vote() twice = %v, %d, want false, 1", counted, count)
+	}
+	if _, count, _ := s.Vote(ctx, "post", "bob"); count != 2 {
+		t.Errorf("Vote() by another user count = %d, want 2", count)
+	}
+
+	if voted, err := s.HasVoted(ctx, "post", "alice"); err != nil || !voted {
+		t.Errorf("HasVoted(alice) = %v, %v, want true", voted, err)
+	}
+	if voted, _ := s.HasVoted(ctx, "post", "carol"); voted {
+		t.Error("HasVoted(carol) = true, want false")
+	}
+
+	if withdrawn, count, err := s.Unvote(ctx, "post", "alice"); err != nil || !withdrawn || count != 1 {
+		t.Errorf("Unvote() = %v, %d, %v, want true, 1", withdrawn, count, err)
+	}
+	if withdrawn, count, _ := s.Unvote(ctx, "post", "alice"); withdrawn || count != 1 {
+		t.Errorf("Unvote() twice = %v, %d, want false, 1", withdrawn, count)
+	}
+	if _, count, _ := s.Unvote(ctx, "post", "bob"); count != 0 {
+		t.Errorf("Unvote() of the last vote count = %d, want 0", count)
+	}
+	if keys, _ := client.Keys(ctx, "votes:*").Result(); len(keys) != 0 {
+		t.Errorf("keys left after withdrawing every vote: %v", keys)
+	}
+}
+
+func TestStore_Counts(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client)
+	_, _, _ = s.Vote(ctx, "a", "alice")
+	_, _, _ = s.Vote(ctx, "a", "bob")
+	_, _, _ = s.Vote(ctx, "c", "alice")
+
+	counts, err := s.Counts(ctx, "a", "b", "c")
+	if err != nil || len(counts) != 3 || counts[0] != 2 || counts[1] != 0 || counts[2] != 1 {
+		t.Errorf("Counts() = %v, %v, want [2 0 1]", counts, err)
+	}
+	if counts, err := s.Counts(ctx); err != nil || len(counts) != 0 {
+		t.Errorf("Counts() without items = %v, %v, want empty", counts, err)
+	}
+
+	if err := s.Clear(ctx, "a"); err != nil {
+		t.Fatalf("Clear() error = %v, want nil", err)
+	}
+	if count, _ := s.Count(ctx, "a"); count != 0 {
+		t.Errorf("Count() after Clear = %d, want 0", count)
+	}
+	if counted, _, _ := s.Vote(ctx, "a", "alice"); !counted {
+		t.Error("Vote() after Clear = false, want true")
+	}
+}
+
+func TestStore_ConcurrentVotes(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, _ = s.Vote(ctx, "post", []string{"alice", "bob"}[i%2])
+		}(i)
+	}
+	wg.Wait()
+	if count, _ := s.Count(ctx, "post"); count != 2 {
+		t.Errorf("Count() after concurrent duplicate votes = %d, want 2", count)
+	}
+}
+
+func TestStore_BloomFilter(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	s := NewStore(client, WithBloomFilter(1000, 0.001))
+
+	if counted, count, err := s.Vote(ctx, "video", "alice"); err != nil || !counted || count != 1 {
+		t.Fatalf("Vote() = %v, %d, %v, want true, 1", counted, count, err)
+	}
+	// The mock has no RedisBloom module, so the store settles on the bitmap fallback
+	if bloomfilter.Mode(s.bloomMode.Load()) != bloomfilter.ModeBitmap {
+		t.Errorf("resolved mode = %s, want bitmap", bloomfilter.Mode(s.bloomMode.Load()))
+	}
+	if counted, count, _ := s.Vote(ctx, "video", "alice"); counted || count != 1 {
+		t.Errorf("Vote() twice = %v, %d, want false, 1", counted, count)
+	}
+	if _, count, _ := s.Vote(ctx, "video", "bob"); count != 2 {
+		t.Errorf("Vote() by another user count = %d, want 2", count)
+	}
+	if voted, err := s.HasVoted(ctx, "video", "alice"); err != nil || !voted {
+		t.Errorf("HasVoted() = %v, %v, want true", voted, err)
+	}
+	if _, _, err := s.Unvote(ctx, "video", "alice"); !errors.Is(err, ErrUnvoteUnsupported) {
+		t.Errorf("Unvote() error = %v, want ErrUnvoteUnsupported", err)
+	}
+	if typ, _ := client.Type(ctx, s.VotersKey("video")).Result(); typ != "string" {
+		t.Errorf("Type() of the voters key = %q, want the bitmap string", typ)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	s := NewStore(nil)
+	if _, _, err := s.Vote(ctx, "i", "u"); err == nil {
+		t.Error("Vote() with nil client error = nil, want error")
+	}
+	if _, _, err := s.Unvote(ctx, "i", "u"); err == nil {
+		t.Error("Unvote() with nil client error = nil, want error")
+	}
+	if _, err := s.HasVoted(ctx, "i", "u"); err == nil {
+		t.Error("HasVoted() with nil client error = nil, want error")
+	}
+	if _, err := s.Count(ctx, "i"); err == nil {
+		t.Error("Count() with nil client error = nil, want error")
+	}
+	if err := s.Clear(ctx, "i"); err == nil {
+		t.Error("Clear() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	s = NewStore(client)
+	_ = client.Set(ctx, s.CountKey("bad"), "garbage", 0).Err()
+	if _, err := s.Count(ctx, "bad"); err == nil {
+		t.Error("Count() of a malformed counter error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if _, _, err := s.Vote(ctx, "i", "u"); err == nil {
+		t.Error("Vote() error = nil, want error")
+	}
+	if _, _, err := s.Unvote(ctx, "i", "u"); err == nil {
+		t.Error("Unvote() error = nil, want error")
+	}
+	if _, err := s.HasVoted(ctx, "i", "u"); err == nil {
+		t.Error("HasVoted() error = nil, want error")
+	}
+	if _, err := s.Count(ctx, "i"); err == nil {
+		t.Error("Count() error = nil, want error")
+	}
+	if err := s.Clear(ctx, "i"); err == nil {
+		t.Error("Clear() error = nil, want error")
+	}
+	if _, _, err := NewStore(client, WithBloomFilter(100, 0.01)).Vote(ctx, "i", "u"); err == nil {
+		t.Error("Vote() with bloom filter error = nil, want error")
+	}
+}
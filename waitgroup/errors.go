@@ -0,0 +1,8 @@
+package waitgroup
+
+import "errors"
+
+var (
+	// ErrNegativeCounter indicates Done or Add took the counter below zero.
+	ErrNegativeCounter = errors.New("wait group counter is negative")
+)
@@ -0,0 +1,33 @@
+package waitgroup
+
+import "time"
+
+// Option configures a WaitGroup
+type Option func(*WaitGroup)
+
+// WithKeyPrefix sets the prefix of the counter key and completion channel (default: DefaultKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(wg *WaitGroup) {
+		wg.keyPrefix = prefix
+	}
+}
+
+// WithTTL sets how long the counter lives after its last update, so abandoned groups
+// are cleaned up (default: DefaultTTL)
+func WithTTL(ttl time.Duration) Option {
+	return func(wg *WaitGroup) {
+		if ttl > 0 {
+			wg.ttl = ttl
+		}
+	}
+}
+
+// WithPollInterval sets how often Wait re-reads the counter in case a completion
+// notification was missed (default: DefaultPollInterval)
+func WithPollInterval(interval time.Duration) Option {
+	return func(wg *WaitGroup) {
+		if interval > 0 {
+			wg.pollInterval = interval
+		}
+	}
+}
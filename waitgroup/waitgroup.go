@@ -0,0 +1,161 @@
+// Package waitgroup provides a wait group shared across processes
+// A coordinator adds the number of tasks it fans out, workers anywhere call Done, and
+// Wait returns once the counter is back at zero
+package waitgroup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix for counter keys and completion channels
+	DefaultKeyPrefix = "waitgroup:"
+
+	// DefaultTTL is how long a counter lives after its last update by default
+	DefaultTTL = 24 * time.Hour
+
+	// DefaultPollInterval is how often Wait re-reads the counter by default
+	DefaultPollInterval = time.Second
+)
+
+// WaitGroup is a counter in Redis that processes can wait on
+type WaitGroup struct {
+	client       *redis.Client
+	name         string
+	keyPrefix    string
+	ttl          time.Duration
+	pollInterval time.Duration
+}
+
+// NewWaitGroup creates a handle to the wait group name (e.g. "import:2024-06-01")
+// Every process using the same name shares the counter
+func NewWaitGroup(client *redis.Client, name string, opts ...Option) *WaitGroup {
+	wg := &WaitGroup{
+		client:       client,
+		name:         name,
+		keyPrefix:    DefaultKeyPrefix,
+		ttl:          DefaultTTL,
+		pollInterval: DefaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(wg)
+	}
+	return wg
+}
+
+// Key returns the Redis key of the counter
+func (wg *WaitGroup) Key() string {
+	return wg.keyPrefix + wg.name
+}
+
+// Channel returns the pub/sub channel notified when the counter reaches zero
+func (wg *WaitGroup) Channel() string {
+	return wg.keyPrefix + wg.name + ":done"
+}
+
+// Add adds delta, which may be negative, to the counter and returns the new value
+// Returns ErrNegativeCounter if the counter drops below zero
+func (wg *WaitGroup) Add(ctx context.Context, delta int64) (int64, error) {
+	if wg.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	pipe := wg.client.Pipeline()
+	incr := pipe.IncrBy(ctx, wg.Key(), delta)
+	pipe.PExpire(ctx, wg.Key(), wg.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to update wait group: %w", err)
+	}
+
+	n := incr.Val()
+	if n < 0 {
+		return n, ErrNegativeCounter
+	}
+	if n == 0 && delta != 0 {
+		if err := wg.client.Publish(ctx, wg.Channel(), "0").Err(); err != nil {
+			return n, fmt.Errorf("failed to notify waiters: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// Done decrements the counter by one and returns the new value
+func (wg *WaitGroup) Done(ctx context.Context) (int64, error) {
+	return wg.Add(ctx, -1)
+}
+
+// Count returns the current counter value; an expired or never used group counts as zero
+func (wg *WaitGroup) Count(ctx context.Context) (int64, error) {
+	if wg.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	n, err := wg.client.Get(ctx, wg.Key()).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read wait group: %w", err)
+	}
+	return n, nil
+}
+
+// Wait blocks until the counter is zero or below, or ctx is done
+// Like sync.WaitGroup, call Add before starting the work so Wait does not return early.
+// Completion arrives over pub/sub, and the counter is also re-read every poll interval; a
+// read failing with a retryable error is skipped until the next one.
+// Returns ctx.Err() if ctx is done first
+func (wg *WaitGroup) Wait(ctx context.Context) error {
+	if wg.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	watchCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var failed error
+	check := func() {
+		n, err := wg.Count(watchCtx)
+		switch {
+		case err != nil && (watchCtx.Err() != nil || utils.IsRetryableError(err)):
+			// The next completion or poll reads the counter again
+		case err != nil:
+			failed = err
+			stop()
+		case n <= 0:
+			stop()
+		}
+	}
+
+	// The counter is read once subscribed, so a completion in between is not missed
+	err := utils.WatchChannel(watchCtx, wg.client, wg.Channel(), utils.ChannelHandler{
+		OnSubscribe:  check,
+		OnMessage:    func(string) { check() },
+		OnPoll:       check,
+		PollInterval: wg.pollInterval,
+	})
+	if err != nil {
+		return err
+	}
+	if failed != nil {
+		return failed
+	}
+	return ctx.Err()
+}
+
+// Reset deletes the counter
+func (wg *WaitGroup) Reset(ctx context.Context) error {
+	if wg.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	if err := wg.client.Del(ctx, wg.Key()).Err(); err != nil {
+		return fmt.Errorf("failed to reset wait group: %w", err)
+	}
+	return nil
+}
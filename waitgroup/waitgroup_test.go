@@ -0,0 +1,187 @@
+package waitgroup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+)
+
+func TestWaitGroup_AddDone(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	wg := NewWaitGroup(client, "job", WithTTL(time.Hour))
+	if wg.Key() != "waitgroup:job" || wg.Channel() != "waitgroup:job:done" {
+		t.Errorf("Key(), Channel() = %q, %q", wg.Key(), wg.Channel())
+	}
+
+	if n, err := wg.Count(ctx); err != nil || n != 0 {
+		t.Errorf("Count() unused = %d, %v, want 0", n, err)
+	}
+	if n, err := wg.Add(ctx, 3); err != nil || n != 3 {
+		t.Fatalf("Add(3) = %d, %v, want 3", n, err)
+	}
+	if ttl, _ := client.PTTL(ctx, wg.Key()).Result(); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("PTTL() = %v, want up to 1h", ttl)
+	}
+	if n, err := wg.Done(ctx); err != nil || n != 2 {
+		t.Errorf("Done() = %d, %v, want 2", n, err)
+	}
+	if n, _ := wg.Count(ctx); n != 2 {
+		t.Errorf("Count() = %d, want 2", n)
+	}
+
+	_, _ = wg.Done(ctx)
+	_, _ = wg.Done(ctx)
+	if n, err := wg.Done(ctx); !errors.Is(err, ErrNegativeCounter) || n != -1 {
+		t.Errorf("Done() past zero = %d, %v, want -1, ErrNegativeCounter", n, err)
+	}
+
+	if err := wg.Reset(ctx); err != nil {
+		t.Fatalf("Reset() error = %v, want nil", err)
+	}
+	if n, _ := wg.Count(ctx); n != 0 {
+		t.Errorf("Count() after Reset() = %d, want 0", n)
+	}
+}
+
+func TestWaitGroup_Wait(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	coordinator := NewWaitGroup(client, "fan", WithPollInterval(time.Hour))
+	worker := NewWaitGroup(client, "fan")
+
+	// Nothing added yet
+	if err := coordinator.Wait(ctx); err != nil {
+		t.Fatalf("Wait() on empty group error = %v, want nil", err)
+	}
+
+	_, _ = coordinator.Add(ctx, 2)
+	done := make(chan error, 1)
+	go func() { done <- coordinator.Wait(ctx) }()
+
+	_, _ = worker.Done(ctx)
+	select {
+	case err := <-done:
+		t.Fatalf("Wait() returned early with %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_, _ = worker.Done(ctx)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Wait() error = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait() did not return after the counter reached zero")
+	}
+}
+
+func TestWaitGroup_WaitCanceled(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	wg := NewWaitGroup(client, "slow", WithPollInterval(10*time.Millisecond))
+	_, _ = wg.Add(context.Background(), 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := wg.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+// flakyReads fails the first reads of the counter with a connection error
+type flakyReads struct {
+	failures atomic.Int64
+}
+
+func (h *flakyReads) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *flakyReads) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "get" && h.failures.Add(-1) >= 0 {
+			cmd.SetErr(io.EOF)
+			return io.EOF
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *flakyReads) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestWaitGroup_WaitTransientError(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	wg := NewWaitGroup(client, "flaky", WithPollInterval(10*time.Millisecond))
+	_, _ = wg.Add(ctx, 1)
+
+	hook := &flakyReads{}
+	hook.failures.Store(2)
+	client.AddHook(hook)
+
+	done := make(chan error, 1)
+	go func() { done <- wg.Wait(ctx) }()
+	select {
+	case err := <-done:
+		t.Fatalf("Wait() returned %v after a transient error, want it to keep waiting", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_, _ = wg.Done(ctx)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Wait() error = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait() did not return after the counter reached zero")
+	}
+}
+
+func TestWaitGroup_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	wg := NewWaitGroup(nil, "x")
+	if _, err := wg.Add(ctx, 1); err == nil {
+		t.Error("Add() with nil client error = nil, want error")
+	}
+	if _, err := wg.Count(ctx); err == nil {
+		t.Error("Count() with nil client error = nil, want error")
+	}
+	if err := wg.Wait(ctx); err == nil {
+		t.Error("Wait() with nil client error = nil, want error")
+	}
+	if err := wg.Reset(ctx); err == nil {
+		t.Error("Reset() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	mock.SetShouldFail(true)
+
+	wg = NewWaitGroup(client, "x")
+	if _, err := wg.Add(ctx, 1); err == nil {
+		t.Error("Add() with failing Redis error = nil, want error")
+	}
+	if _, err := wg.Count(ctx); err == nil {
+		t.Error("Count() with failing Redis error = nil, want error")
+	}
+	if err := wg.Wait(ctx); err == nil {
+		t.Error("Wait() with failing Redis error = nil, want error")
+	}
+}
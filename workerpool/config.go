@@ -0,0 +1,78 @@
+package workerpool
+
+import (
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+// DefaultKeyPrefix is the default prefix for pool keys
+const DefaultKeyPrefix = "workerpool:"
+
+// Config represents worker pool configuration
+type Config struct {
+	// Key is the base key of the pool; the pending set, leases and payloads live under it
+	// (default: "workerpool:" + name)
+	Key string
+
+	// Codec encodes task payloads (default: codec.Default())
+	Codec codec.Codec
+
+	// LeaseTime is how long a claimed task stays with its worker without a heartbeat (default: 30s)
+	LeaseTime time.Duration
+
+	// HeartbeatInterval is how often Run extends the lease of a task in progress (default: LeaseTime / 3)
+	HeartbeatInterval time.Duration
+
+	// PollInterval is how long an idle worker waits before claiming again (default: 1s)
+	PollInterval time.Duration
+
+	// Concurrency is the number of tasks handled in parallel by Run (default: 4)
+	Concurrency int
+
+	// OnError is called with errors from Run's claim loop, heartbeats and handler (optional)
+	OnError func(error)
+}
+
+// DefaultConfig returns a Config with default values for the pool with the given name
+func DefaultConfig(name string) Config {
+	return Config{
+		Key:               DefaultKeyPrefix + name,
+		Codec:             codec.Default(),
+		LeaseTime:         30 * time.Second,
+		HeartbeatInterval: 10 * time.Second,
+		PollInterval:      time.Second,
+		Concurrency:       4,
+	}
+}
+
+// WithCodec sets the payload codec
+func (c Config) WithCodec(cd codec.Codec) Config {
+	c.Codec = cd
+	return c
+}
+
+// WithLease sets the lease time and how often running tasks renew it
+func (c Config) WithLease(leaseTime, heartbeatInterval time.Duration) Config {
+	c.LeaseTime = leaseTime
+	c.HeartbeatInterval = heartbeatInterval
+	return c
+}
+
+// WithPollInterval sets how long an idle worker waits before claiming again
+func (c Config) WithPollInterval(interval time.Duration) Config {
+	c.PollInterval = interval
+	return c
+}
+
+// WithConcurrency sets the number of parallel workers
+func (c Config) WithConcurrency(n int) Config {
+	c.Concurrency = n
+	return c
+}
+
+// WithOnError sets the callback for claim loop, heartbeat and handler errors
+func (c Config) WithOnError(fn func(error)) Config {
+	c.OnError = fn
+	return c
+}
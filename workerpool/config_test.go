@@ -0,0 +1,57 @@
+package workerpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig("thumbnails")
+
+	if cfg.Key != "workerpool:thumbnails" {
+		t.Errorf("DefaultConfig().Key = %q, want %q", cfg.Key, "workerpool:thumbnails")
+	}
+	if cfg.Codec == nil || cfg.Codec.Name() != codec.NameJSON {
+		t.Errorf("DefaultConfig().Codec = %v, want json", cfg.Codec)
+	}
+	if cfg.LeaseTime != 30*time.Second || cfg.HeartbeatInterval != 10*time.Second {
+		t.Errorf("DefaultConfig() lease = %v/%v, want 30s/10s", cfg.LeaseTime, cfg.HeartbeatInterval)
+	}
+	if cfg.PollInterval != time.Second || cfg.Concurrency != 4 {
+		t.Errorf("DefaultConfig() poll/concurrency = %v/%d, want 1s/4", cfg.PollInterval, cfg.Concurrency)
+	}
+}
+
+func TestConfig_With(t *testing.T) {
+	var called bool
+	cfg := DefaultConfig("p").
+		WithCodec(codec.Gob).
+		WithLease(time.Minute, 5*time.Second).
+		WithPollInterval(time.Millisecond).
+		WithConcurrency(2).
+		WithOnError(func(error) { called = true })
+
+	if cfg.Codec.Name() != codec.NameGob {
+		t.Errorf("WithCodec() = %q, want gob", cfg.Codec.Name())
+	}
+	if cfg.LeaseTime != time.Minute || cfg.HeartbeatInterval != 5*time.Second {
+		t.Errorf("WithLease() = %v/%v, want 1m/5s", cfg.LeaseTime, cfg.HeartbeatInterval)
+	}
+	if cfg.PollInterval != time.Millisecond || cfg.Concurrency != 2 {
+		t.Errorf("WithPollInterval/WithConcurrency = %v/%d, want 1ms/2", cfg.PollInterval, cfg.Concurrency)
+	}
+	cfg.OnError(nil)
+	if !called {
+		t.Error("WithOnError() callback not set")
+	}
+}
+
+func TestNewPool_Defaults(t *testing.T) {
+	p := NewPool(nil, Config{Key: "k", LeaseTime: 9 * time.Second})
+	cfg := p.Config()
+	if cfg.Codec == nil || cfg.HeartbeatInterval != 3*time.Second || cfg.PollInterval != time.Second || cfg.Concurrency != 1 {
+		t.Errorf("NewPool() config = %+v, want codec, 3s heartbeat, 1s poll, 1 worker", cfg)
+	}
+}
@@ -0,0 +1,10 @@
+package workerpool
+
+import "errors"
+
+var (
+	// ErrLeaseLost indicates the task lease expired and the task was re-queued or claimed by another worker.
+	ErrLeaseLost = errors.New("task lease lost")
+	// ErrHandlerPanic indicates the task handler panicked while processing a task.
+	ErrHandlerPanic = errors.New("task handler panicked")
+)
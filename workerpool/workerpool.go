@@ -0,0 +1,445 @@
+// Package workerpool provides fault-tolerant task claiming with leases on Redis
+// Workers claim tasks from a pending set, renew their lease while processing, and
+// tasks whose lease expires, e.g. because the worker crashed, are re-queued for others
+package workerpool
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/utils"
+	"github.com/soulteary/redis-kit/utils/codec"
+)
+
+const (
+	suffixPending  = ":pending"
+	suffixLeases   = ":leases"
+	suffixTasks    = ":tasks"
+	suffixOwners   = ":owners"
+	suffixAttempts = ":attempts"
+)
+
+// requeueExpiredLua moves tasks whose lease expired by ARGV[1] back to the pending set
+const requeueExpiredLua = `
+local now = tonumber(ARGV[1])
+local expired = redis.call("zrangebyscore", KEYS[2], "-inf", now)
+for _, id in ipairs(expired) do
+	redis.call("zrem", KEYS[2], id)
+	redis.call("hdel", KEYS[4], id)
+	redis.call("zadd", KEYS[1], now, id)
+end
+`
+
+const requeueScript = `
+-- redis-kit:workerpool-requeue` + requeueExpiredLua + `
+return #expired
+`
+
+const claimScript = `
+-- redis-kit:workerpool-claim` + requeueExpiredLua + `
+local ids = redis.call("zrange", KEYS[1], 0, tonumber(ARGV[2]) - 1)
+local out = {}
+for _, id in ipairs(ids) do
+	redis.call("zrem", KEYS[1], id)
+	local payload = redis.call("hget", KEYS[3], id)
+	if payload then
+		redis.call("zadd", KEYS[2], now + tonumber(ARGV[3]), id)
+		redis.call("hset", KEYS[4], id, ARGV[4])
+		local attempts = redis.call("hincrby", KEYS[5], id, 1)
+		table.insert(out, id)
+		table.insert(out, payload)
+		table.insert(out, tostring(attempts))
+	end
+end
+return out
+`
+
+const extendScript = `
+-- redis-kit:workerpool-extend
+if redis.call("hget", KEYS[4], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call("zadd", KEYS[2], ARGV[3], ARGV[1])
+return 1
+`
+
+const completeScript = `
+-- redis-kit:workerpool-complete
+if redis.call("hget", KEYS[4], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call("zrem", KEYS[2], ARGV[1])
+redis.call("hdel", KEYS[3], ARGV[1])
+redis.call("hdel", KEYS[4], ARGV[1])
+redis.call("hdel", KEYS[5], ARGV[1])
+return 1
+`
+
+const releaseScript = `
+-- redis-kit:workerpool-release
+if redis.call("hget", KEYS[4], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call("zrem", KEYS[2], ARGV[1])
+redis.call("hdel", KEYS[4], ARGV[1])
+redis.call("zadd", KEYS[1], ARGV[3], ARGV[1])
+return 1
+`
+
+// Handler processes a claimed task; returning an error re-queues it
+// ctx is cancelled when Run stops or the lease is lost to another worker
+type Handler func(ctx context.Context, task *Task) error
+
+// Task is a unit of work claimed under a lease
+type Task struct {
+	// ID identifies the task within the pool
+	ID string
+
+	// Payload is the encoded task payload
+	Payload []byte
+
+	// Attempts is the number of times the task has been claimed, including this claim
+	Attempts int64
+
+	token string
+	codec codec.Codec
+}
+
+// Decode unmarshals the task payload into v using the pool's codec
+func (t *Task) Decode(v interface{}) error {
+	if err := t.codec.Unmarshal(t.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode task payload: %w", err)
+	}
+	return nil
+}
+
+// Pool hands out tasks to workers under renewable leases
+// Lease deadlines are computed from the clocks of the workers, which should be roughly in sync
+type Pool struct {
+	client *redis.Client
+	cfg    Config
+}
+
+// NewPool creates a new worker pool; zero config values fall back to sane minimums
+// Key has no default, and operations on a pool without one return an error
+func NewPool(client *redis.Client, cfg Config) *Pool {
+	if cfg.Codec == nil {
+		cfg.Codec = codec.Default()
+	}
+	if cfg.LeaseTime <= 0 {
+		cfg.LeaseTime = 30 * time.Second
+	}
+	if cfg.HeartbeatInterval <= 0 || cfg.HeartbeatInterval >= cfg.LeaseTime {
+		cfg.HeartbeatInterval = cfg.LeaseTime / 3
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	return &Pool{client: client, cfg: cfg}
+}
+
+// Config returns the pool configuration
+func (p *Pool) Config() Config {
+	return p.cfg
+}
+
+func (p *Pool) keys() []string {
+	return []string{
+		p.cfg.Key + suffixPending,
+		p.cfg.Key + suffixLeases,
+		p.cfg.Key + suffixTasks,
+		p.cfg.Key + suffixOwners,
+		p.cfg.Key + suffixAttempts,
+	}
+}
+
+// generateID generates a random task ID or lease token
+func generateID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// Submit encodes payload, adds it to the pending set, and returns the new task ID
+func (p *Pool) Submit(ctx context.Context, payload interface{}) (string, error) {
+	if err := p.check(); err != nil {
+		return "", err
+	}
+
+	data, err := p.cfg.Codec.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode task payload: %w", err)
+	}
+	id, err := generateID()
+	if err != nil {
+		return "", err
+	}
+
+	// The payload is written first so a concurrent claim never sees an ID without it
+	pipe := p.client.Pipeline()
+	pipe.HSet(ctx, p.cfg.Key+suffixTasks, id, data)
+	pipe.ZAdd(ctx, p.cfg.Key+suffixPending, redis.Z{Score: float64(time.Now().UnixMilli()), Member: id})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to submit task: %w", err)
+	}
+	return id, nil
+}
+
+// Claim leases up to limit pending tasks, oldest first, to the caller
+// Tasks whose lease has expired are re-queued first, so they can be claimed again
+func (p *Pool) Claim(ctx context.Context, limit int64) ([]*Task, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	if limit < 1 {
+		limit = 1
+	}
+
+	token, err := generateID()
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.client.Eval(ctx, claimScript, p.keys(),
+		time.Now().UnixMilli(), limit, p.cfg.LeaseTime.Milliseconds(), token,
+	).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim tasks: %w", err)
+	}
+
+	tasks := make([]*Task, 0, len(res)/3)
+	for i := 0; i+2 < len(res); i += 3 {
+		attempts, _ := strconv.ParseInt(res[i+2], 10, 64)
+		tasks = append(tasks, &Task{
+			ID:       res[i],
+			Payload:  []byte(res[i+1]),
+			Attempts: attempts,
+			token:    token,
+			codec:    p.cfg.Codec,
+		})
+	}
+	return tasks, nil
+}
+
+// settle runs one of the lease-checked scripts for task
+// Returns ErrLeaseLost if the task is no longer leased under the task's claim
+func (p *Pool) settle(ctx context.Context, script string, task *Task, args ...interface{}) error {
+	if err := p.check(); err != nil {
+		return err
+	}
+
+	argv := append([]interface{}{task.ID, task.token}, args...)
+	ok, err := p.client.Eval(ctx, script, p.keys(), argv...).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Extend renews the lease of task for another LeaseTime
+func (p *Pool) Extend(ctx context.Context, task *Task) error {
+	err := p.settle(ctx, extendScript, task, time.Now().Add(p.cfg.LeaseTime).UnixMilli())
+	if err != nil && !errors.Is(err, ErrLeaseLost) {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+	return err
+}
+
+// Complete removes a finished task from the pool
+func (p *Pool) Complete(ctx context.Context, task *Task) error {
+	err := p.settle(ctx, completeScript, task)
+	if err != nil && !errors.Is(err, ErrLeaseLost) {
+		return fmt.Errorf("failed to complete task: %w", err)
+	}
+	return err
+}
+
+// Release gives up the lease and puts task back into the pending set for another attempt
+func (p *Pool) Release(ctx context.Context, task *Task) error {
+	err := p.settle(ctx, releaseScript, task, time.Now().UnixMilli())
+	if err != nil && !errors.Is(err, ErrLeaseLost) {
+		return fmt.Errorf("failed to release task: %w", err)
+	}
+	return err
+}
+
+// RequeueExpired moves tasks whose lease has expired back to the pending set and returns how many
+// Claim does this on every call, so calling it is only needed to keep Pending accurate
+func (p *Pool) RequeueExpired(ctx context.Context) (int64, error) {
+	if err := p.check(); err != nil {
+		return 0, err
+	}
+
+	n, err := p.client.Eval(ctx, requeueScript, p.keys(), time.Now().UnixMilli()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue expired tasks: %w", err)
+	}
+	return n, nil
+}
+
+// Pending returns the number of tasks waiting to be claimed
+func (p *Pool) Pending(ctx context.Context) (int64, error) {
+	if err := p.check(); err != nil {
+		return 0, err
+	}
+
+	n, err := p.client.ZCard(ctx, p.cfg.Key+suffixPending).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending tasks: %w", err)
+	}
+	return n, nil
+}
+
+// Leased returns the number of tasks currently leased, including expired leases not yet re-queued
+func (p *Pool) Leased(ctx context.Context) (int64, error) {
+	if err := p.check(); err != nil {
+		return 0, err
+	}
+
+	n, err := p.client.ZCard(ctx, p.cfg.Key+suffixLeases).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get leased tasks: %w", err)
+	}
+	return n, nil
+}
+
+// Run starts Concurrency workers that claim and process tasks until ctx is cancelled
+// Leases are renewed every HeartbeatInterval while the handler runs. Successful tasks are
+// completed, failed ones released for another attempt. Run returns nil once in-flight tasks finish
+func (p *Pool) Run(ctx context.Context, handler Handler) error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	if handler == nil {
+		return fmt.Errorf("task handler is nil")
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < p.cfg.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			p.work(ctx, handler)
+		}()
+	}
+	workers.Wait()
+	return nil
+}
+
+func (p *Pool) work(ctx context.Context, handler Handler) {
+	for ctx.Err() == nil {
+		tasks, err := p.Claim(ctx, 1)
+		if err != nil {
+			p.reportError(err)
+		}
+		if len(tasks) == 0 {
+			if !wait(ctx, p.cfg.PollInterval) {
+				return
+			}
+			continue
+		}
+		p.process(ctx, handler, tasks[0])
+	}
+}
+
+// process runs the handler under a renewed lease and settles the task even if ctx was cancelled meanwhile
+func (p *Pool) process(ctx context.Context, handler Handler, task *Task) {
+	taskCtx, cancel := context.WithCancel(ctx)
+	lost := make(chan bool, 1)
+	go func() { lost <- p.heartbeat(taskCtx, cancel, task) }()
+
+	err := runHandler(taskCtx, handler, task)
+	cancel()
+	if <-lost {
+		p.reportError(fmt.Errorf("task %s: %w", task.ID, ErrLeaseLost))
+		return
+	}
+
+	opCtx, opCancel := utils.WithDefaultTimeout(context.WithoutCancel(ctx))
+	defer opCancel()
+
+	if err == nil {
+		err = p.Complete(opCtx, task)
+	} else {
+		p.reportError(fmt.Errorf("task %s failed: %w", task.ID, err))
+		err = p.Release(opCtx, task)
+	}
+	if err != nil {
+		p.reportError(fmt.Errorf("task %s: %w", task.ID, err))
+	}
+}
+
+// heartbeat extends the lease of task until ctx is done
+// If the lease is lost it cancels the handler and returns true
+func (p *Pool) heartbeat(ctx context.Context, cancel context.CancelFunc, task *Task) bool {
+	ticker := time.NewTicker(p.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+
+		err := p.Extend(ctx, task)
+		if errors.Is(err, ErrLeaseLost) {
+			cancel()
+			return true
+		}
+		if err != nil && ctx.Err() == nil {
+			p.reportError(err)
+		}
+	}
+}
+
+func runHandler(ctx context.Context, handler Handler, task *Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+		}
+	}()
+	return handler(ctx, task)
+}
+
+func (p *Pool) check() error {
+	if p.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if p.cfg.Key == "" {
+		return fmt.Errorf("worker pool key is required")
+	}
+	return nil
+}
+
+func (p *Pool) reportError(err error) {
+	if p.cfg.OnError != nil && err != nil && !errors.Is(err, context.Canceled) {
+		p.cfg.OnError(err)
+	}
+}
+
+// wait sleeps for d, returning false if ctx is cancelled first
+func wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,393 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/soulteary/redis-kit/testutil"
+	"github.com/soulteary/redis-kit/utils"
+)
+
+type thumb struct {
+	Path string `json:"path"`
+}
+
+func TestPool_ClaimComplete(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	p := NewPool(client, DefaultConfig("thumbs"))
+
+	first, err := p.Submit(ctx, thumb{Path: "a.png"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v, want nil", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	_, _ = p.Submit(ctx, thumb{Path: "b.png"})
+	if n, _ := p.Pending(ctx); n != 2 {
+		t.Errorf("Pending() = %d, want 2", n)
+	}
+
+	tasks, err := p.Claim(ctx, 1)
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("Claim() = %v, %v, want one task", tasks, err)
+	}
+	task := tasks[0]
+	var got thumb
+	if task.ID != first || task.Attempts != 1 || task.Decode(&got) != nil || got.Path != "a.png" {
+		t.Errorf("Claim() task = %+v (%+v), want oldest task on its first attempt", task, got)
+	}
+	if n, _ := p.Leased(ctx); n != 1 {
+		t.Errorf("Leased() = %d, want 1", n)
+	}
+
+	if err := p.Extend(ctx, task); err != nil {
+		t.Errorf("Extend() error = %v, want nil", err)
+	}
+	if err := p.Complete(ctx, task); err != nil {
+		t.Fatalf("Complete() error = %v, want nil", err)
+	}
+	if err := p.Complete(ctx, task); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Complete() twice error = %v, want ErrLeaseLost", err)
+	}
+	if n, _ := p.Leased(ctx); n != 0 {
+		t.Errorf("Leased() after Complete() = %d, want 0", n)
+	}
+	if n, _ := p.Pending(ctx); n != 1 {
+		t.Errorf("Pending() after Complete() = %d, want 1", n)
+	}
+}
+
+func TestPool_Release(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	p := NewPool(client, DefaultConfig("retry"))
+	_, _ = p.Submit(ctx, thumb{Path: "a.png"})
+
+	tasks, _ := p.Claim(ctx, 10)
+	if len(tasks) != 1 {
+		t.Fatalf("Claim() = %d tasks, want 1", len(tasks))
+	}
+	if err := p.Release(ctx, tasks[0]); err != nil {
+		t.Fatalf("Release() error = %v, want nil", err)
+	}
+	if err := p.Extend(ctx, tasks[0]); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Extend() after Release() error = %v, want ErrLeaseLost", err)
+	}
+
+	again, _ := p.Claim(ctx, 10)
+	if len(again) != 1 || again[0].ID != tasks[0].ID || again[0].Attempts != 2 {
+		t.Fatalf("Claim() after Release() = %+v, want the same task on attempt 2", again)
+	}
+	if err := p.Complete(ctx, tasks[0]); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Complete() with a stale claim error = %v, want ErrLeaseLost", err)
+	}
+}
+
+func TestPool_ExpiredLease(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	p := NewPool(client, DefaultConfig("crash").WithLease(20*time.Millisecond, 0))
+	_, _ = p.Submit(ctx, thumb{Path: "a.png"})
+
+	crashed, _ := p.Claim(ctx, 1)
+	if len(crashed) != 1 {
+		t.Fatalf("Claim() = %d tasks, want 1", len(crashed))
+	}
+	if tasks, _ := p.Claim(ctx, 1); len(tasks) != 0 {
+		t.Errorf("Claim() while leased = %d tasks, want 0", len(tasks))
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if n, err := p.RequeueExpired(ctx); err != nil || n != 1 {
+		t.Errorf("RequeueExpired() = %d, %v, want 1", n, err)
+	}
+	if n, _ := p.Pending(ctx); n != 1 {
+		t.Errorf("Pending() after RequeueExpired() = %d, want 1", n)
+	}
+
+	tasks, _ := p.Claim(ctx, 1)
+	if len(tasks) != 1 || tasks[0].Attempts != 2 {
+		t.Fatalf("Claim() after expiry = %+v, want the task on attempt 2", tasks)
+	}
+	if err := p.Complete(ctx, crashed[0]); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Complete() by the crashed worker error = %v, want ErrLeaseLost", err)
+	}
+}
+
+func TestPool_Run(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var errs []error
+	cfg := DefaultConfig("run").
+		WithLease(60*time.Millisecond, 10*time.Millisecond).
+		WithPollInterval(5 * time.Millisecond).
+		WithConcurrency(2).
+		WithOnError(func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		})
+	p := NewPool(client, cfg)
+
+	for _, path := range []string{"ok.png", "flaky.png", "panic.png", "slow.png"} {
+		_, _ = p.Submit(ctx, thumb{Path: path})
+	}
+
+	var done atomic.Int64
+	handler := func(ctx context.Context, task *Task) error {
+		var th thumb
+		if err := task.Decode(&th); err != nil {
+			return err
+		}
+		switch th.Path {
+		case "flaky.png":
+			if task.Attempts < 2 {
+				return errors.New("temporary failure")
+			}
+		case "panic.png":
+			if task.Attempts < 2 {
+				panic("boom")
+			}
+		case "slow.png":
+			// Outlives the lease several times, kept alive by heartbeats
+			time.Sleep(150 * time.Millisecond)
+		}
+		done.Add(1)
+		return nil
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- p.Run(ctx, handler) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for done.Load() < 4 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-result; err != nil {
+		t.Errorf("Run() error = %v, want nil on cancellation", err)
+	}
+
+	if done.Load() != 4 {
+		t.Fatalf("handled %d tasks, want 4", done.Load())
+	}
+	bg := context.Background()
+	if n, _ := p.Pending(bg); n != 0 {
+		t.Errorf("Pending() after Run() = %d, want 0", n)
+	}
+	if n, _ := p.Leased(bg); n != 0 {
+		t.Errorf("Leased() after Run() = %d, want 0", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var panicked bool
+	for _, err := range errs {
+		if errors.Is(err, ErrHandlerPanic) {
+			panicked = true
+		}
+		if errors.Is(err, ErrLeaseLost) {
+			t.Errorf("OnError() got %v, want heartbeats to keep the lease", err)
+		}
+	}
+	if !panicked {
+		t.Errorf("OnError() errors = %v, want a handler panic", errs)
+	}
+}
+
+func TestPool_RunLeaseLost(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lost := make(chan error, 1)
+	cfg := DefaultConfig("lost").
+		WithLease(time.Second, 10*time.Millisecond).
+		WithPollInterval(5 * time.Millisecond).
+		WithConcurrency(1).
+		WithOnError(func(err error) {
+			if errors.Is(err, ErrLeaseLost) {
+				select {
+				case lost <- err:
+				default:
+				}
+			}
+		})
+	p := NewPool(client, cfg)
+	_, _ = p.Submit(ctx, thumb{Path: "a.png"})
+
+	// Another party takes the task away while the handler is running
+	handler := func(hctx context.Context, task *Task) error {
+		_ = client.HDel(context.Background(), "workerpool:lost:owners", task.ID).Err()
+		<-hctx.Done()
+		return hctx.Err()
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- p.Run(ctx, handler) }()
+
+	select {
+	case <-lost:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not report the lost lease")
+	}
+	cancel()
+	if err := <-result; err != nil {
+		t.Errorf("Run() error = %v, want nil on cancellation", err)
+	}
+}
+
+func TestPool_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewPool(nil, DefaultConfig("x"))
+	if _, err := p.Submit(ctx, 1); err == nil {
+		t.Error("Submit() with nil client error = nil, want error")
+	}
+	if _, err := p.Claim(ctx, 1); err == nil {
+		t.Error("Claim() with nil client error = nil, want error")
+	}
+	if err := p.Complete(ctx, &Task{ID: "a"}); err == nil {
+		t.Error("Complete() with nil client error = nil, want error")
+	}
+	if _, err := p.RequeueExpired(ctx); err == nil {
+		t.Error("RequeueExpired() with nil client error = nil, want error")
+	}
+	if _, err := p.Pending(ctx); err == nil {
+		t.Error("Pending() with nil client error = nil, want error")
+	}
+	if err := p.Run(ctx, func(context.Context, *Task) error { return nil }); err == nil {
+		t.Error("Run() with nil client error = nil, want error")
+	}
+
+	client, mock := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+
+	p = NewPool(client, DefaultConfig("x"))
+	if err := p.Run(ctx, nil); err == nil {
+		t.Error("Run() with nil handler error = nil, want error")
+	}
+	if _, err := p.Submit(ctx, make(chan int)); err == nil {
+		t.Error("Submit() with unencodable payload error = nil, want error")
+	}
+
+	mock.SetShouldFail(true)
+	if _, err := p.Submit(ctx, 1); err == nil {
+		t.Error("Submit() with failing Redis error = nil, want error")
+	}
+	if _, err := p.Claim(ctx, 1); err == nil {
+		t.Error("Claim() with failing Redis error = nil, want error")
+	}
+	if err := p.Extend(ctx, &Task{ID: "a"}); err == nil || errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Extend() with failing Redis error = %v, want a Redis error", err)
+	}
+	if err := p.Release(ctx, &Task{ID: "a"}); err == nil || errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Release() with failing Redis error = %v, want a Redis error", err)
+	}
+	if _, err := p.Leased(ctx); err == nil {
+		t.Error("Leased() with failing Redis error = nil, want error")
+	}
+}
+
+func TestPool_EmptyKey(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	p := NewPool(client, Config{})
+	if _, err := p.Submit(ctx, 1); err == nil {
+		t.Error("Submit() without a key error = nil, want error")
+	}
+	if _, err := p.Claim(ctx, 1); err == nil {
+		t.Error("Claim() without a key error = nil, want error")
+	}
+	if err := p.Complete(ctx, &Task{ID: "a"}); err == nil {
+		t.Error("Complete() without a key error = nil, want error")
+	}
+	if _, err := p.Pending(ctx); err == nil {
+		t.Error("Pending() without a key error = nil, want error")
+	}
+	if err := p.Run(ctx, func(context.Context, *Task) error { return nil }); err == nil {
+		t.Error("Run() without a key error = nil, want error")
+	}
+	if n, err := client.Exists(ctx, suffixPending, suffixTasks).Result(); err != nil || n != 0 {
+		t.Errorf("Exists() on the shared keys = %d, %v, want nothing written", n, err)
+	}
+}
+
+// deadlineRecorder records the deadline of the context completing a task
+type deadlineRecorder struct {
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+func (h *deadlineRecorder) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *deadlineRecorder) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if args := cmd.Args(); cmd.Name() == "eval" && len(args) > 1 {
+			if script, _ := args[1].(string); strings.Contains(script, "redis-kit:workerpool-complete") {
+				h.mu.Lock()
+				h.deadline, _ = ctx.Deadline()
+				h.mu.Unlock()
+			}
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *deadlineRecorder) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestPool_RunTimeoutOverride(t *testing.T) {
+	client, _ := testutil.NewMockRedisClient()
+	defer func() { _ = client.Close() }()
+	hook := &deadlineRecorder{}
+	client.AddHook(hook)
+
+	ctx, cancel := context.WithCancel(utils.ContextWithTimeoutOverride(context.Background(), time.Minute))
+	defer cancel()
+	p := NewPool(client, DefaultConfig("override").WithPollInterval(5*time.Millisecond))
+	_, _ = p.Submit(ctx, 1)
+
+	result := make(chan error, 1)
+	go func() { result <- p.Run(ctx, func(context.Context, *Task) error { return nil }) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		hook.mu.Lock()
+		got := hook.deadline
+		hook.mu.Unlock()
+		if !got.IsZero() {
+			// The override replaces utils.DefaultOperationTimeout for the settling call
+			if remaining := time.Until(got); remaining <= utils.DefaultOperationTimeout {
+				t.Errorf("Complete() deadline in %v, want the 1m override", remaining)
+			}
+			cancel()
+			<-result
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("task was not completed")
+}